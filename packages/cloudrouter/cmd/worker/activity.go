@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interaction channels reported by /_cmux/activity. Idle detection must consider
+// all of them: a user watching VNC or driving DevTools never hits /exec.
+const (
+	activityExec = "exec"
+	activityPTY  = "pty"
+	activitySSH  = "ssh"
+	activityVNC  = "vnc"
+	activityCDP  = "cdp"
+	// activityBrowser is native /_cmux/browser commands; cdp is other clients
+	// connected to Chrome's debugging port.
+	activityBrowser = "browser"
+)
+
+// activityTracker records the last interaction and open connection count per
+// channel so callers can decide whether the sandbox is safe to pause.
+type activityTracker struct {
+	mu       sync.Mutex
+	channels map[string]*channelActivity
+}
+
+type channelActivity struct {
+	active int
+	last   time.Time
+}
+
+var activity = &activityTracker{channels: make(map[string]*channelActivity)}
+
+func (t *activityTracker) channel(name string) *channelActivity {
+	ch, ok := t.channels[name]
+	if !ok {
+		ch = &channelActivity{}
+		t.channels[name] = ch
+	}
+	return ch
+}
+
+// touch records a one-off interaction on the given channel.
+func (t *activityTracker) touch(name string) {
+	t.mu.Lock()
+	t.channel(name).last = time.Now()
+	t.mu.Unlock()
+}
+
+// begin marks a long-lived connection as open. The returned func must be
+// called when the connection closes.
+func (t *activityTracker) begin(name string) func() {
+	t.mu.Lock()
+	ch := t.channel(name)
+	ch.active++
+	ch.last = time.Now()
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		ch.active--
+		ch.last = time.Now()
+		t.mu.Unlock()
+	}
+}
+
+func (t *activityTracker) snapshot() map[string]map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	out := make(map[string]map[string]interface{}, len(t.channels))
+	for name, ch := range t.channels {
+		last := ch.last
+		if ch.active > 0 {
+			last = now
+		}
+		out[name] = map[string]interface{}{
+			"activeConnections": ch.active,
+			"lastActivityAt":    last.UnixMilli(),
+		}
+	}
+	return out
+}
+
+// tcpSocket is a TCP connection from /proc/net/tcp.
+type tcpSocket struct {
+	localPort   int
+	remotePort  int
+	established bool
+	inode       string
+}
+
+// tcpEstablishedState is TCP_ESTABLISHED in /proc/net/tcp.
+const tcpEstablishedState = "01"
+
+// parseTCPSockets parses /proc/net/tcp or /proc/net/tcp6 into its sockets.
+func parseTCPSockets(r io.Reader, ipv6 bool) []tcpSocket {
+	var sockets []tcpSocket
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		_, local, ok1 := parseHexAddr(fields[1], ipv6)
+		_, remote, ok2 := parseHexAddr(fields[2], ipv6)
+		if !ok1 || !ok2 {
+			continue
+		}
+		sockets = append(sockets, tcpSocket{
+			localPort:   local,
+			remotePort:  remote,
+			established: fields[3] == tcpEstablishedState,
+			inode:       fields[9],
+		})
+	}
+	return sockets
+}
+
+// countCDPSessions counts established connections into Chrome's debugging
+// port: DevTools clients through nginx's chrome_cdp upstream, agent-browser
+// and the like. Chrome serves CDP on that port itself, so there is no proxy
+// to ask. Connections from sockets in own (the worker's native /_cmux/browser
+// sessions) are left out; those are reported as the browser channel.
+func countCDPSessions(sockets []tcpSocket, port int, own map[string]bool) int {
+	ownPorts := make(map[int]bool)
+	for _, s := range sockets {
+		if own[s.inode] && s.remotePort == port {
+			ownPorts[s.localPort] = true
+		}
+	}
+	n := 0
+	for _, s := range sockets {
+		if s.established && s.localPort == port && !ownPorts[s.remotePort] {
+			n++
+		}
+	}
+	return n
+}
+
+// readCDPSessions counts the DevTools sessions open on cdpPort right now.
+func readCDPSessions() (int, error) {
+	var sockets []tcpSocket
+	read := 0
+	for _, source := range []struct {
+		path string
+		ipv6 bool
+	}{{"/proc/net/tcp", false}, {"/proc/net/tcp6", true}} {
+		f, err := os.Open(source.path)
+		if err != nil {
+			continue
+		}
+		sockets = append(sockets, parseTCPSockets(f, source.ipv6)...)
+		f.Close()
+		read++
+	}
+	if read == 0 {
+		return 0, fmt.Errorf("cannot read /proc/net/tcp")
+	}
+
+	own := make(map[string]bool)
+	self := os.Getpid()
+	for inode, pid := range socketOwners() {
+		if pid == self {
+			own[inode] = true
+		}
+	}
+	return countCDPSessions(sockets, cdpPort, own), nil
+}
+
+func handleActivity(w http.ResponseWriter, r *http.Request) {
+	cdpSessions, cdpErr := readCDPSessions()
+	if cdpSessions > 0 {
+		activity.touch(activityCDP)
+	}
+	channels := activity.snapshot()
+	switch {
+	case cdpErr != nil:
+		channels[activityCDP] = map[string]interface{}{"error": cdpErr.Error()}
+	case channels[activityCDP] != nil:
+		channels[activityCDP]["activeConnections"] = cdpSessions
+	default:
+		channels[activityCDP] = map[string]interface{}{"activeConnections": cdpSessions}
+	}
+
+	var lastActivityAt int64
+	activeConnections := 0
+	for _, ch := range channels {
+		if ts, ok := ch["lastActivityAt"].(int64); ok && ts > lastActivityAt {
+			lastActivityAt = ts
+		}
+		if n, ok := ch["activeConnections"].(int); ok {
+			activeConnections += n
+		}
+	}
+
+	result := map[string]interface{}{
+		"activeConnections": activeConnections,
+		"lastActivityAt":    lastActivityAt,
+		"channels":          channels,
+	}
+	if lastActivityAt > 0 {
+		result["idleMs"] = time.Now().UnixMilli() - lastActivityAt
+	}
+	sendJSON(w, result)
+}
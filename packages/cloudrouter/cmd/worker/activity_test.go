@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCountCDPSessions(t *testing.T) {
+	// Chrome listens on 9222 (0x2406). 0xD2F0 is a DevTools client through
+	// nginx; 0xD2F1 is the worker's own /_cmux/browser connection (inode 222).
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:2406 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 100 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:2406 0100007F:D2F0 01 00000000:00000000 00:00000000 00000000  1000        0 111 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:D2F0 0100007F:2406 01 00000000:00000000 00:00000000 00000000     0        0 112 1 0000000000000000 20 4 30 10 -1
+   3: 0100007F:2406 0100007F:D2F1 01 00000000:00000000 00:00000000 00000000  1000        0 221 1 0000000000000000 20 4 30 10 -1
+   4: 0100007F:D2F1 0100007F:2406 01 00000000:00000000 00:00000000 00000000     0        0 222 1 0000000000000000 20 4 30 10 -1
+   5: 0100007F:2406 0100007F:D2F2 06 00000000:00000000 00:00000000 00000000  1000        0 0 1 0000000000000000 20 4 30 10 -1
+`
+	sockets := parseTCPSockets(strings.NewReader(tcp), false)
+	if len(sockets) != 6 {
+		t.Fatalf("expected 6 sockets, got %+v", sockets)
+	}
+	if got := countCDPSessions(sockets, 9222, nil); got != 2 {
+		t.Errorf("without own sockets = %d, want 2", got)
+	}
+	if got := countCDPSessions(sockets, 9222, map[string]bool{"222": true}); got != 1 {
+		t.Errorf("excluding the worker's connection = %d, want 1", got)
+	}
+}
+
+// TestCountCDPSessionsLive counts a real loopback connection the same way
+// handleActivity does, so the /proc parsing is checked against the kernel.
+func TestCountCDPSessionsLive(t *testing.T) {
+	if _, err := os.Stat("/proc/net/tcp"); err != nil {
+		t.Skip("no /proc/net/tcp")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	f, err := os.Open("/proc/net/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sockets := parseTCPSockets(f, false)
+	f.Close()
+
+	if got := countCDPSessions(sockets, port, nil); got != 1 {
+		t.Errorf("sessions on %d = %d, want 1", port, got)
+	}
+	own := make(map[string]bool)
+	for inode, pid := range socketOwners() {
+		if pid == os.Getpid() {
+			own[inode] = true
+		}
+	}
+	if got := countCDPSessions(sockets, port, own); got != 0 {
+		t.Errorf("sessions on %d excluding this process = %d, want 0", port, got)
+	}
+}
+
+// TestCDPPortMatchesImage guards against counting a port nothing serves
+// CDP on: Chrome must be started with cdpPort and nginx must proxy to it.
+func TestCDPPortMatchesImage(t *testing.T) {
+	for _, tt := range []struct {
+		file    string
+		pattern string
+	}{
+		{"../../worker/xstartup", `--remote-debugging-port=(\d+)`},
+		{"../../worker/nginx.conf", `upstream chrome_cdp \{\s*server 127\.0\.0\.1:(\d+);`},
+	} {
+		data, err := os.ReadFile(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := regexp.MustCompile(tt.pattern).FindSubmatch(data)
+		if m == nil {
+			t.Fatalf("%s: no match for %s", tt.file, tt.pattern)
+		}
+		if port, _ := strconv.Atoi(string(m[1])); port != cdpPort {
+			t.Errorf("%s uses CDP port %d, worker counts %d", tt.file, port, cdpPort)
+		}
+	}
+}
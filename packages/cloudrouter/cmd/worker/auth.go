@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The control plane mints worker tokens and installs them with
+// POST /_cmux/auth/rotate. The token being replaced stays valid for a grace
+// period so clients holding it can switch over without failing requests.
+const (
+	defaultTokenRotationGrace = 10 * time.Minute
+	maxTokenRotationGrace     = 24 * time.Hour
+	minAuthTokenLength        = 32
+)
+
+var (
+	// Guarded by authTokenMu.
+	previousAuthToken       string
+	previousAuthTokenExpiry time.Time
+)
+
+// unauthenticatedPaths are served without a token. /health is kept as an
+// alias of /healthz for existing health checks.
+var unauthenticatedPaths = map[string]struct{}{
+	"/healthz": {},
+	"/health":  {},
+}
+
+// authMiddleware rejects requests without a valid worker token, except for
+// health checks and loopback token bootstrap via /auth-token.
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := unauthenticatedPaths[r.URL.Path]; ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.Path == "/auth-token" && isLoopbackRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !verifyAuth(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			sendJSON(w, map[string]string{"error": "Unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// presentedTokens returns the tokens a request carries, in the order
+// Authorization header, token query parameter, auth cookie.
+func presentedTokens(r *http.Request) []string {
+	var tokens []string
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		tokens = append(tokens, strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		tokens = append(tokens, token)
+	}
+	if cookie, err := r.Cookie(authCookieName); err == nil && cookie.Value != "" {
+		tokens = append(tokens, cookie.Value)
+	}
+	return tokens
+}
+
+// isValidToken reports whether token is the current worker token or the
+// previous one within its rotation grace period.
+func isValidToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	current := ensureValidToken()
+	if tokensEqual(token, current) {
+		return true
+	}
+
+	authTokenMu.RLock()
+	previous, expiry := previousAuthToken, previousAuthTokenExpiry
+	authTokenMu.RUnlock()
+	return previous != "" && time.Now().Before(expiry) && tokensEqual(token, previous)
+}
+
+func tokensEqual(a, b string) bool {
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// rotateAuthToken makes token the current worker token and keeps the
+// replaced one valid for grace.
+func rotateAuthToken(token string, grace time.Duration) time.Time {
+	authTokenMu.Lock()
+	defer authTokenMu.Unlock()
+
+	previousAuthToken = authToken
+	previousAuthTokenExpiry = time.Now().Add(grace)
+	authToken = token
+	return previousAuthTokenExpiry
+}
+
+// handleAuthRotate installs a control-plane minted token. Only the current
+// token may rotate, so a token in its grace period cannot take control back.
+// The VS Code token file is left alone; that server reads it only at start.
+func handleAuthRotate(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		sendJSON(w, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	current := ensureValidToken()
+	authorized := false
+	for _, token := range presentedTokens(r) {
+		if tokensEqual(token, current) {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		w.WriteHeader(http.StatusForbidden)
+		sendJSON(w, map[string]string{"error": "Rotation requires the current token"})
+		return
+	}
+
+	token, _ := body["token"].(string)
+	if len(token) < minAuthTokenLength || strings.ContainsAny(token, " \t\r\n") {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": "token must be at least 32 characters without whitespace"})
+		return
+	}
+	if tokensEqual(token, current) {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": "token is already current"})
+		return
+	}
+
+	grace := defaultTokenRotationGrace
+	if v, ok := body["graceSeconds"].(float64); ok {
+		if v < 0 || time.Duration(v)*time.Second > maxTokenRotationGrace {
+			w.WriteHeader(http.StatusBadRequest)
+			sendJSON(w, map[string]string{"error": "graceSeconds must be between 0 and 86400"})
+			return
+		}
+		grace = time.Duration(v) * time.Second
+	}
+
+	expiry := rotateAuthToken(token, grace)
+	writeToCandidateFiles(
+		candidateReadOrder(authTokenPath, authTokenPathCandidates),
+		[]byte(token),
+		"auth token",
+	)
+	log.Printf("[worker] Auth token rotated, previous valid until %s", expiry.Format(time.RFC3339))
+
+	sendJSON(w, map[string]interface{}{
+		"rotated":            true,
+		"previousValidUntil": expiry.UTC().Format(time.RFC3339),
+	})
+}
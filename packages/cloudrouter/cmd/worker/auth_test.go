@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testTokenA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	testTokenB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// useTestAuthToken points the token files at a temp dir and sets the current
+// worker token, restoring the globals after the test.
+func useTestAuthToken(t *testing.T, token string) string {
+	t.Helper()
+	bootID := getCurrentBootID()
+	if bootID == "" {
+		t.Skip("boot ID unavailable")
+	}
+
+	dir := t.TempDir()
+	saved := []*string{&authTokenPath, &vscodeTokenPath, &bootIDPath, &authToken, &previousAuthToken}
+	savedValues := make([]string, len(saved))
+	for i, p := range saved {
+		savedValues[i] = *p
+	}
+	savedCandidates := [][]string{authTokenPathCandidates, vscodeTokenPathCandidates, bootIDPathCandidates}
+	savedExpiry := previousAuthTokenExpiry
+	t.Cleanup(func() {
+		for i, p := range saved {
+			*p = savedValues[i]
+		}
+		authTokenPathCandidates, vscodeTokenPathCandidates, bootIDPathCandidates = savedCandidates[0], savedCandidates[1], savedCandidates[2]
+		previousAuthTokenExpiry = savedExpiry
+	})
+
+	authTokenPath = filepath.Join(dir, ".worker-auth-token")
+	vscodeTokenPath = filepath.Join(dir, ".vscode-token")
+	bootIDPath = filepath.Join(dir, ".token-boot-id")
+	authTokenPathCandidates = []string{authTokenPath}
+	vscodeTokenPathCandidates = []string{vscodeTokenPath}
+	bootIDPathCandidates = []string{bootIDPath}
+	if err := os.WriteFile(bootIDPath, []byte(bootID), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	authToken = token
+	previousAuthToken = ""
+	previousAuthTokenExpiry = time.Time{}
+	return dir
+}
+
+func authTestHandler() http.Handler {
+	return authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	useTestAuthToken(t, testTokenA)
+	handler := authTestHandler()
+
+	tests := []struct {
+		name   string
+		path   string
+		remote string
+		header string
+		want   int
+	}{
+		{"healthz open", "/healthz", "203.0.113.5:1234", "", http.StatusNoContent},
+		{"legacy health open", "/health", "203.0.113.5:1234", "", http.StatusNoContent},
+		{"missing token", "/exec", "203.0.113.5:1234", "", http.StatusUnauthorized},
+		{"wrong token", "/pty", "203.0.113.5:1234", "Bearer " + testTokenB, http.StatusUnauthorized},
+		{"bearer token", "/exec", "203.0.113.5:1234", "Bearer " + testTokenA, http.StatusNoContent},
+		{"raw token", "/_cmux/browser", "203.0.113.5:1234", testTokenA, http.StatusNoContent},
+		{"auth-token from loopback", "/auth-token", "127.0.0.1:1234", "", http.StatusNoContent},
+		{"auth-token from remote", "/auth-token", "203.0.113.5:1234", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = tt.remote
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddlewareAcceptsQueryAndCookie(t *testing.T) {
+	useTestAuthToken(t, testTokenA)
+	handler := authTestHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/status?token="+testTokenA, nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("query token: status = %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.AddCookie(&http.Cookie{Name: authCookieName, Value: testTokenA})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("cookie token: status = %d", rec.Code)
+	}
+}
+
+func TestRotateAuthTokenKeepsPreviousDuringGrace(t *testing.T) {
+	useTestAuthToken(t, testTokenA)
+
+	rotateAuthToken(testTokenB, time.Minute)
+	if !isValidToken(testTokenA) || !isValidToken(testTokenB) {
+		t.Fatal("expected both tokens valid during grace period")
+	}
+
+	rotateAuthToken(testTokenA, 0)
+	if isValidToken(testTokenB) {
+		t.Error("expected previous token invalid once grace has elapsed")
+	}
+	if !isValidToken(testTokenA) {
+		t.Error("expected current token valid")
+	}
+}
+
+func TestHandleAuthRotate(t *testing.T) {
+	dir := useTestAuthToken(t, testTokenA)
+
+	rotate := func(bearer string, body map[string]interface{}) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/_cmux/auth/rotate", nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		handleAuthRotate(rec, req, body)
+		return rec
+	}
+
+	if rec := rotate(testTokenA, map[string]interface{}{"token": "short"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("short token: status = %d", rec.Code)
+	}
+	if rec := rotate(testTokenA, map[string]interface{}{"token": testTokenB, "graceSeconds": float64(-1)}); rec.Code != http.StatusBadRequest {
+		t.Errorf("negative grace: status = %d", rec.Code)
+	}
+
+	rec := rotate(testTokenA, map[string]interface{}{"token": testTokenB, "graceSeconds": float64(60)})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rotate: status = %d body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Rotated            bool   `json:"rotated"`
+		PreviousValidUntil string `json:"previousValidUntil"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Rotated || resp.PreviousValidUntil == "" {
+		t.Errorf("unexpected response %s (%v)", rec.Body.String(), err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".worker-auth-token")); strings.TrimSpace(string(data)) != testTokenB {
+		t.Errorf("persisted token = %q", data)
+	}
+	if !isValidToken(testTokenA) {
+		t.Error("expected previous token valid during grace period")
+	}
+
+	// A token in its grace period must not be able to rotate.
+	if rec := rotate(testTokenA, map[string]interface{}{"token": strings.Repeat("c", 48)}); rec.Code != http.StatusForbidden {
+		t.Errorf("rotate with previous token: status = %d", rec.Code)
+	}
+}
@@ -3,26 +3,297 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
-// browserManager wraps the agent-browser CLI for screenshot and agent mode.
-type browserManager struct{}
+// browserManager wraps the agent-browser CLI for screenshot and agent mode,
+// and holds direct CDP sessions for the native /_cmux/browser commands.
+type browserManager struct {
+	mu       sync.Mutex
+	sessions map[string]*pageSession
+}
+
+var browser = &browserManager{sessions: make(map[string]*pageSession)}
+
+// pageSession is a CDP connection to one page target plus the per-page
+// state built on top of it.
+type pageSession struct {
+	targetID string
+	conn     *cdpConn
+	refs     *refTable
+	network  *networkRecorder
+	console  *consoleBuffer
+	nav      *navTracker
+	queue    *commandQueue
+}
+
+// Close is called on shutdown and drops all CDP sessions.
+func (bm *browserManager) Close() {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	for id, s := range bm.sessions {
+		s.conn.Close()
+		delete(bm.sessions, id)
+	}
+}
+
+// session returns the CDP session for targetID, or for the first page
+// target when targetID is empty, connecting if needed.
+func (bm *browserManager) session(ctx context.Context, targetID string) (*pageSession, error) {
+	targets, err := listCDPTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *cdpTarget
+	for i := range targets {
+		t := &targets[i]
+		if t.Type != "page" || t.WebSocketDebuggerURL == "" {
+			continue
+		}
+		if targetID == "" || t.ID == targetID {
+			target = t
+			break
+		}
+	}
+	if target == nil {
+		if targetID != "" {
+			return nil, &browserError{status: http.StatusNotFound, code: "target_not_found", msg: fmt.Sprintf("no page target %s", targetID)}
+		}
+		return nil, &browserError{status: http.StatusServiceUnavailable, code: "no_page", msg: "no page target available"}
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if s, ok := bm.sessions[target.ID]; ok && s.conn.Alive() {
+		return s, nil
+	}
+
+	conn, err := dialCDP(ctx, target.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, err
+	}
+	s := &pageSession{
+		targetID: target.ID,
+		conn:     conn,
+		refs:     newRefTable(),
+		network:  newNetworkRecorder(),
+		console:  newConsoleBuffer(),
+		nav:      newNavTracker(),
+		queue:    newCommandQueue(browserMaxPending),
+	}
+	if err := enableConsoleCapture(ctx, s); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := enableNavigationTracking(ctx, s); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Downloads are best-effort: a failure here must not block other commands.
+	if err := enableDownloads(ctx, s); err != nil {
+		logCDPError("Browser.setDownloadBehavior", err)
+	}
+	bm.sessions[target.ID] = s
+	return s, nil
+}
+
+// browserError is a command failure with an HTTP status and a stable code
+// callers can branch on. details, if set, is added to the response.
+type browserError struct {
+	status  int
+	code    string
+	msg     string
+	details map[string]interface{}
+}
+
+func (e *browserError) Error() string {
+	return e.msg
+}
+
+// browserCommand is one native /_cmux/browser command. readOnly commands are
+// allowed while a human holds browser control. idempotent commands are
+// replayed on a fresh session if Chrome drops the old one mid-command.
+type browserCommand struct {
+	readOnly   bool
+	idempotent bool
+	run        func(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error)
+}
+
+var browserCommands map[string]browserCommand
+
+func init() {
+	browserCommands = map[string]browserCommand{
+		"open":              {run: cmdOpen},
+		"reload":            {run: cmdReload},
+		"back":              {run: cmdBack},
+		"forward":           {run: cmdForward},
+		"waitForNavigation": {readOnly: true, run: cmdWaitForNavigation},
+
+		"url":   {readOnly: true, idempotent: true, run: cmdURL},
+		"title": {readOnly: true, idempotent: true, run: cmdTitle},
+
+		"snapshot": {readOnly: true, idempotent: true, run: cmdSnapshot},
+		"click":    {run: cmdClick},
+		"hover":    {run: cmdHover},
+		"focus":    {run: cmdFocus},
+		"fill":     {run: cmdFill},
+		"wait":     {readOnly: true, run: cmdWait},
+
+		"assert.visible": {readOnly: true, idempotent: true, run: cmdAssertVisible},
+		"assert.text":    {readOnly: true, idempotent: true, run: cmdAssertText},
+		"assert.url":     {readOnly: true, idempotent: true, run: cmdAssertURL},
+		"assert.count":   {readOnly: true, idempotent: true, run: cmdAssertCount},
+
+		"network.start":    {run: cmdNetworkStart},
+		"network.stop":     {run: cmdNetworkStop},
+		"network.requests": {readOnly: true, run: cmdNetworkRequests},
 
-var browser = &browserManager{}
+		"console": {readOnly: true, run: cmdConsole},
+		"eval":    {run: cmdEval},
 
-// Close is called on shutdown. No-op for CLI-based approach.
-func (bm *browserManager) Close() {}
+		"setFiles":  {run: cmdSetFiles},
+		"downloads": {readOnly: true, run: cmdDownloads},
 
-// Screenshot takes a screenshot via agent-browser and returns base64-encoded PNG.
-func (bm *browserManager) Screenshot() (map[string]interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		"screenshot":  {readOnly: true, idempotent: true, run: cmdScreenshot},
+		"setViewport": {run: cmdSetViewport},
+		"pdf":         {readOnly: true, idempotent: true, run: cmdPDF},
+	}
+}
+
+// handleBrowserCommand runs a native CDP command:
+// POST /_cmux/browser {"command": "click", "selector": "@e3", "target": "<id>"}.
+func handleBrowserCommand(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	name, _ := body["command"].(string)
+	command, ok := browserCommands[name]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": fmt.Sprintf("unknown browser command %q", name)})
+		return
+	}
+	if !command.readOnly && !requireAgentControl(w) {
+		return
+	}
+	activity.touch(activityBrowser)
+
+	timeout, err := commandTimeout(body)
+	if err != nil {
+		writeBrowserError(w, name, err)
+		return
+	}
+	// The request context cancels in-flight CDP calls if the client goes
+	// away; the timeout stops a hung page from holding the handler.
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
 	defer cancel()
 
+	targetID, _ := body["target"].(string)
+	ran := false
+	result, err := browser.run(ctx, targetID, command.idempotent, func(s *pageSession) (map[string]interface{}, error) {
+		ran = true
+		result, err := command.run(ctx, s, body)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
+			return nil, commandTimeoutError(name, timeout, s)
+		}
+		return result, err
+	})
+	if errors.Is(err, context.DeadlineExceeded) && !ran {
+		err = commandTimeoutError(name, timeout, nil)
+	}
+	if err != nil {
+		writeBrowserError(w, name, err)
+		return
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	result["success"] = true
+	sendJSON(w, result)
+}
+
+// run executes fn on the target's session once earlier commands on that
+// target have finished. If the session is lost mid-command it reconnects
+// and, when replay is set, runs fn again on the new session; otherwise the
+// caller gets a browser_restarted error.
+func (bm *browserManager) run(ctx context.Context, targetID string, replay bool, fn func(s *pageSession) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	s, err := bm.session(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return bm.runOn(ctx, s, replay, fn)
+}
+
+func (bm *browserManager) runOn(ctx context.Context, s *pageSession, replay bool, fn func(s *pageSession) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	result, err := s.runQueued(ctx, fn)
+	if err == nil || !isSessionLost(err) || ctx.Err() != nil {
+		return result, err
+	}
+
+	log.Printf("[browser] session for target %s lost: %v", s.targetID, err)
+	next, err := bm.reconnect(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	restarted := next.targetID != s.targetID
+	if !replay {
+		return nil, errBrowserRestarted(restarted)
+	}
+
+	result, err = next.runQueued(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	// Replayed results come from the new session, which after a restart
+	// is a fresh page; flag it so callers don't mistake it for the old one.
+	result["reconnected"] = true
+	if restarted {
+		result["browserRestarted"] = true
+	}
+	return result, nil
+}
+
+// runQueued runs fn once earlier commands on the session have finished.
+func (s *pageSession) runQueued(ctx context.Context, fn func(s *pageSession) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	release, err := s.queue.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return fn(s)
+}
+
+// writeBrowserError maps browserErrors to their status and code; anything
+// else is logged and reported as a 500.
+func writeBrowserError(w http.ResponseWriter, name string, err error) {
+	var be *browserError
+	if errors.As(err, &be) {
+		resp := map[string]interface{}{"error": be.msg, "code": be.code}
+		for k, v := range be.details {
+			resp[k] = v
+		}
+		w.WriteHeader(be.status)
+		sendJSON(w, resp)
+		return
+	}
+	log.Printf("[browser] %s failed: %v", name, err)
+	w.WriteHeader(http.StatusInternalServerError)
+	sendJSON(w, map[string]string{"error": err.Error()})
+}
+
+// screenshotViaCLI takes a viewport PNG via agent-browser. It is the
+// fallback when no CDP page session can be opened.
+func (bm *browserManager) screenshotViaCLI(ctx context.Context) (map[string]interface{}, error) {
 	targetPath := "/tmp/screenshot.png"
 	cmd := exec.CommandContext(ctx, "agent-browser", "screenshot", targetPath)
 	cmd.Dir = workspaceDir
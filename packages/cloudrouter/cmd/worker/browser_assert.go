@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Assertion commands report a failed check as {"pass": false} with the
+// expected and actual values rather than as an error, so agents can record
+// verification steps without telling failures apart from broken requests.
+// Malformed parameters and selectors, stale refs and CDP failures are still
+// errors.
+
+const visibleFunction = `function() {
+  const el = this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement;
+  if (!el || !el.isConnected) return false;
+  const style = getComputedStyle(el);
+  if (style.display === "none" || style.visibility === "hidden" || Number(style.opacity) === 0) return false;
+  const rect = el.getBoundingClientRect();
+  return rect.width > 0 && rect.height > 0;
+}`
+
+const textFunction = `function() {
+  if (this.tagName === "INPUT" || this.tagName === "TEXTAREA" || this.tagName === "SELECT") return this.value;
+  return this.innerText ?? this.textContent ?? "";
+}`
+
+func assertionResult(pass bool, expected, actual interface{}, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"pass":     pass,
+		"expected": expected,
+		"actual":   actual,
+		"message":  message,
+	}
+}
+
+func invalidAssertion(msg string) error {
+	return &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: msg}
+}
+
+// findForAssert resolves selector, reporting a missing element as found ==
+// false instead of an error.
+func findForAssert(ctx context.Context, s *pageSession, selector string) (int64, bool, error) {
+	nodeID, err := resolveSelector(ctx, s, selector)
+	var be *browserError
+	if errors.As(err, &be) && be.code == "element_not_found" {
+		return 0, false, nil
+	}
+	return nodeID, err == nil, err
+}
+
+// callOnNode runs fn with the node as this and returns its value.
+func callOnNode(ctx context.Context, s *pageSession, backendNodeID int64, fn string, out interface{}) error {
+	var resolved struct {
+		Object struct {
+			ObjectID string `json:"objectId"`
+		} `json:"object"`
+	}
+	if err := s.conn.Call(ctx, "DOM.resolveNode", map[string]interface{}{"backendNodeId": backendNodeID}, &resolved); err != nil {
+		return err
+	}
+	defer s.conn.Call(ctx, "Runtime.releaseObject", map[string]interface{}{"objectId": resolved.Object.ObjectID}, nil)
+
+	var res struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+	}
+	if err := s.conn.Call(ctx, "Runtime.callFunctionOn", map[string]interface{}{
+		"objectId":            resolved.Object.ObjectID,
+		"functionDeclaration": fn,
+		"returnByValue":       true,
+	}, &res); err != nil {
+		return err
+	}
+	return json.Unmarshal(res.Result.Value, out)
+}
+
+// cmdAssertVisible checks that "selector" is (or with "visible": false, is
+// not) a rendered element with a non-empty box.
+func cmdAssertVisible(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	want := true
+	if v, ok := params["visible"].(bool); ok {
+		want = v
+	}
+
+	nodeID, found, err := findForAssert(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	visible := false
+	if found {
+		if err := callOnNode(ctx, s, nodeID, visibleFunction, &visible); err != nil {
+			return nil, err
+		}
+	}
+
+	actual := map[string]interface{}{"found": found, "visible": visible}
+	expected := map[string]interface{}{"visible": want}
+	switch {
+	case visible == want:
+		return assertionResult(true, expected, actual, ""), nil
+	case !found:
+		return assertionResult(false, expected, actual, fmt.Sprintf("no element matches %q", selector)), nil
+	case want:
+		return assertionResult(false, expected, actual, fmt.Sprintf("%q matches an element that is not visible", selector)), nil
+	default:
+		return assertionResult(false, expected, actual, fmt.Sprintf("%q is visible", selector)), nil
+	}
+}
+
+// cmdAssertText checks the rendered text of "selector", or the value of a
+// form field, against "text". Whitespace is normalized; the match is a
+// substring unless "exact" is set.
+func cmdAssertText(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	want, ok := params["text"].(string)
+	if !ok {
+		return nil, invalidAssertion("text required")
+	}
+	exact, _ := params["exact"].(bool)
+
+	nodeID, found, err := findForAssert(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	expected := map[string]interface{}{"text": want, "exact": exact}
+	if !found {
+		return assertionResult(false, expected, map[string]interface{}{"found": false}, fmt.Sprintf("no element matches %q", selector)), nil
+	}
+
+	var text string
+	if err := callOnNode(ctx, s, nodeID, textFunction, &text); err != nil {
+		return nil, err
+	}
+	pass, diff, message := compareText(want, text, exact)
+	result := assertionResult(pass, expected, map[string]interface{}{"found": true, "text": normalizeText(text)}, message)
+	if diff != nil {
+		result["diff"] = diff
+	}
+	return result, nil
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// textDiff locates the first difference between an expected and an actual
+// text, with a little context on either side.
+type textDiff struct {
+	Offset   int    `json:"offset"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+const textDiffContext = 20
+
+// compareText reports whether got satisfies want and, for a failed exact
+// match, where they diverge.
+func compareText(want, got string, exact bool) (bool, *textDiff, string) {
+	want, got = normalizeText(want), normalizeText(got)
+	if !exact {
+		if strings.Contains(got, want) {
+			return true, nil, ""
+		}
+		return false, nil, fmt.Sprintf("text does not contain %q", want)
+	}
+	if want == got {
+		return true, nil, ""
+	}
+
+	w, g := []rune(want), []rune(got)
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	return false, &textDiff{Offset: i, Expected: excerpt(want, i), Actual: excerpt(got, i)}, fmt.Sprintf("text differs at character %d", i)
+}
+
+// excerpt returns the text around rune offset i, marking elisions.
+func excerpt(s string, i int) string {
+	r := []rune(s)
+	start, end := i-textDiffContext, i+textDiffContext
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(r) {
+		end, suffix = len(r), ""
+	}
+	return prefix + string(r[start:end]) + suffix
+}
+
+// cmdAssertURL checks the page URL against exactly one of "url" (equal),
+// "contains" (substring) or "pattern" (regular expression).
+func cmdAssertURL(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	check, err := parseURLCheck(params)
+	if err != nil {
+		return nil, err
+	}
+	info, err := targetInfo(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	pass, message := check.match(info.URL)
+	return assertionResult(pass, check.expected(), map[string]interface{}{"url": info.URL}, message), nil
+}
+
+type urlCheck struct {
+	mode  string // "url", "contains" or "pattern"
+	value string
+	re    *regexp.Regexp
+}
+
+func parseURLCheck(params map[string]interface{}) (urlCheck, error) {
+	var check urlCheck
+	for _, mode := range []string{"url", "contains", "pattern"} {
+		v, ok := params[mode].(string)
+		if !ok {
+			continue
+		}
+		if check.mode != "" {
+			return urlCheck{}, invalidAssertion("set only one of url, contains or pattern")
+		}
+		check.mode, check.value = mode, v
+	}
+	if check.mode == "" {
+		return urlCheck{}, invalidAssertion("url, contains or pattern required")
+	}
+	if check.mode == "pattern" {
+		re, err := regexp.Compile(check.value)
+		if err != nil {
+			return urlCheck{}, invalidAssertion(fmt.Sprintf("invalid pattern: %v", err))
+		}
+		check.re = re
+	}
+	return check, nil
+}
+
+func (c urlCheck) expected() map[string]interface{} {
+	return map[string]interface{}{c.mode: c.value}
+}
+
+func (c urlCheck) match(url string) (bool, string) {
+	switch c.mode {
+	case "contains":
+		if strings.Contains(url, c.value) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("URL does not contain %q", c.value)
+	case "pattern":
+		if c.re.MatchString(url) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("URL does not match /%s/", c.value)
+	}
+	if url == c.value {
+		return true, ""
+	}
+	return false, fmt.Sprintf("URL is %q, want %q", url, c.value)
+}
+
+// cmdAssertCount checks how many elements "selector" matches against
+// "count", or against "min" and/or "max". An @eN ref counts as one element
+// or none.
+func cmdAssertCount(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	bounds, err := parseCountBounds(params)
+	if err != nil {
+		return nil, err
+	}
+	n, err := countSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	pass, message := bounds.match(n)
+	return assertionResult(pass, bounds.expected(), map[string]interface{}{"count": n}, message), nil
+}
+
+type countBounds struct {
+	min, max int // max < 0 means unbounded
+}
+
+func parseCountBounds(params map[string]interface{}) (countBounds, error) {
+	number := func(key string) (int, bool, error) {
+		raw, ok := params[key]
+		if !ok {
+			return 0, false, nil
+		}
+		f, ok := raw.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			return 0, false, invalidAssertion(key + " must be a non-negative integer")
+		}
+		return int(f), true, nil
+	}
+	count, hasCount, err := number("count")
+	if err != nil {
+		return countBounds{}, err
+	}
+	min, hasMin, err := number("min")
+	if err != nil {
+		return countBounds{}, err
+	}
+	max, hasMax, err := number("max")
+	if err != nil {
+		return countBounds{}, err
+	}
+	switch {
+	case hasCount && (hasMin || hasMax):
+		return countBounds{}, invalidAssertion("set count or min/max, not both")
+	case hasCount:
+		return countBounds{min: count, max: count}, nil
+	case !hasMin && !hasMax:
+		return countBounds{}, invalidAssertion("count, min or max required")
+	case !hasMax:
+		max = -1
+	case max < min:
+		return countBounds{}, invalidAssertion("max is less than min")
+	}
+	return countBounds{min: min, max: max}, nil
+}
+
+func (b countBounds) expected() map[string]interface{} {
+	if b.min == b.max {
+		return map[string]interface{}{"count": b.min}
+	}
+	expected := map[string]interface{}{"min": b.min}
+	if b.max >= 0 {
+		expected["max"] = b.max
+	}
+	return expected
+}
+
+func (b countBounds) match(n int) (bool, string) {
+	switch {
+	case b.min == b.max && n != b.min:
+		return false, fmt.Sprintf("found %d elements, want %d", n, b.min)
+	case n < b.min:
+		return false, fmt.Sprintf("found %d elements, want at least %d", n, b.min)
+	case b.max >= 0 && n > b.max:
+		return false, fmt.Sprintf("found %d elements, want at most %d", n, b.max)
+	}
+	return true, ""
+}
+
+// countSelector counts the elements a selector matches.
+func countSelector(ctx context.Context, s *pageSession, selector string) (int, error) {
+	if _, ok := parseRef(selector); ok {
+		_, found, err := findForAssert(ctx, s, selector)
+		if found {
+			return 1, err
+		}
+		return 0, err
+	}
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return 0, err
+	}
+	if sel.value == "" {
+		return 0, invalidSelector("selector required")
+	}
+
+	if sel.kind != selectorCSS {
+		var res struct {
+			Result struct {
+				Value int `json:"value"`
+			} `json:"result"`
+			ExceptionDetails *struct {
+				Text      string `json:"text"`
+				Exception *struct {
+					Description string `json:"description"`
+				} `json:"exception"`
+			} `json:"exceptionDetails"`
+		}
+		if err := s.conn.Call(ctx, "Runtime.evaluate", map[string]interface{}{
+			"expression":    selectorExpression(sel) + ".length",
+			"returnByValue": true,
+		}, &res); err != nil {
+			return 0, err
+		}
+		if ex := res.ExceptionDetails; ex != nil {
+			msg := ex.Text
+			if ex.Exception != nil && ex.Exception.Description != "" {
+				msg = ex.Exception.Description
+			}
+			return 0, invalidSelector(msg)
+		}
+		return res.Result.Value, nil
+	}
+
+	var doc struct {
+		Root struct {
+			NodeID int64 `json:"nodeId"`
+		} `json:"root"`
+	}
+	if err := s.conn.Call(ctx, "DOM.getDocument", map[string]interface{}{"depth": 0}, &doc); err != nil {
+		return 0, err
+	}
+	var found struct {
+		NodeIDs []int64 `json:"nodeIds"`
+	}
+	if err := s.conn.Call(ctx, "DOM.querySelectorAll", map[string]interface{}{
+		"nodeId":   doc.Root.NodeID,
+		"selector": sel.value,
+	}, &found); err != nil {
+		return 0, invalidSelector(err.Error())
+	}
+	return len(found.NodeIDs), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareText(t *testing.T) {
+	if pass, _, _ := compareText("Welcome back", "  Welcome\n  back, Ada ", false); !pass {
+		t.Error("contains match with different whitespace should pass")
+	}
+	if pass, diff, msg := compareText("Goodbye", "Welcome back", false); pass || diff != nil || msg == "" {
+		t.Errorf("contains mismatch = %v, %+v, %q; want a failure message and no diff", pass, diff, msg)
+	}
+	if pass, _, _ := compareText("Welcome", "Welcome back", true); pass {
+		t.Error("exact match should not accept a prefix")
+	}
+
+	long := strings.Repeat("x", 30)
+	pass, diff, msg := compareText(long+"abc", long+"abd and more", true)
+	want := &textDiff{Offset: 32, Expected: "…" + strings.Repeat("x", 18) + "abc", Actual: "…" + strings.Repeat("x", 18) + "abd and more"}
+	if pass || !reflect.DeepEqual(diff, want) || msg != "text differs at character 32" {
+		t.Errorf("exact mismatch = %v, %+v, %q; want %+v", pass, diff, msg, want)
+	}
+}
+
+func TestURLCheck(t *testing.T) {
+	tests := []struct {
+		params map[string]interface{}
+		url    string
+		pass   bool
+	}{
+		{map[string]interface{}{"url": "https://example.com/"}, "https://example.com/", true},
+		{map[string]interface{}{"url": "https://example.com/"}, "https://example.com/login", false},
+		{map[string]interface{}{"contains": "/dashboard"}, "https://example.com/dashboard?tab=1", true},
+		{map[string]interface{}{"contains": "/dashboard"}, "https://example.com/login", false},
+		{map[string]interface{}{"pattern": `/orders/\d+$`}, "https://example.com/orders/42", true},
+		{map[string]interface{}{"pattern": `/orders/\d+$`}, "https://example.com/orders/new", false},
+	}
+	for _, tt := range tests {
+		check, err := parseURLCheck(tt.params)
+		if err != nil {
+			t.Fatalf("parseURLCheck(%v): %v", tt.params, err)
+		}
+		if pass, msg := check.match(tt.url); pass != tt.pass || (!pass && msg == "") {
+			t.Errorf("%v.match(%q) = %v, %q; want %v", tt.params, tt.url, pass, msg, tt.pass)
+		}
+	}
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"url": "a", "contains": "b"},
+		{"pattern": "("},
+	} {
+		_, err := parseURLCheck(params)
+		var be *browserError
+		if !errors.As(err, &be) || be.code != "invalid_params" {
+			t.Errorf("parseURLCheck(%v) error = %v, want invalid_params", params, err)
+		}
+	}
+}
+
+func TestCountBounds(t *testing.T) {
+	tests := []struct {
+		params   map[string]interface{}
+		n        int
+		pass     bool
+		expected map[string]interface{}
+	}{
+		{map[string]interface{}{"count": float64(3)}, 3, true, map[string]interface{}{"count": 3}},
+		{map[string]interface{}{"count": float64(3)}, 2, false, map[string]interface{}{"count": 3}},
+		{map[string]interface{}{"count": float64(0)}, 0, true, map[string]interface{}{"count": 0}},
+		{map[string]interface{}{"min": float64(1)}, 5, true, map[string]interface{}{"min": 1}},
+		{map[string]interface{}{"min": float64(1)}, 0, false, map[string]interface{}{"min": 1}},
+		{map[string]interface{}{"max": float64(2)}, 3, false, map[string]interface{}{"min": 0, "max": 2}},
+		{map[string]interface{}{"min": float64(1), "max": float64(2)}, 2, true, map[string]interface{}{"min": 1, "max": 2}},
+	}
+	for _, tt := range tests {
+		b, err := parseCountBounds(tt.params)
+		if err != nil {
+			t.Fatalf("parseCountBounds(%v): %v", tt.params, err)
+		}
+		if pass, msg := b.match(tt.n); pass != tt.pass || (!pass && msg == "") {
+			t.Errorf("%v.match(%d) = %v, %q; want %v", tt.params, tt.n, pass, msg, tt.pass)
+		}
+		if got := b.expected(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%v.expected() = %v, want %v", tt.params, got, tt.expected)
+		}
+	}
+
+	for _, params := range []map[string]interface{}{
+		{},
+		{"count": float64(1), "min": float64(0)},
+		{"count": 1.5},
+		{"min": float64(-1)},
+		{"min": float64(3), "max": float64(2)},
+	} {
+		if _, err := parseCountBounds(params); err == nil {
+			t.Errorf("parseCountBounds(%v) should fail", params)
+		}
+	}
+}
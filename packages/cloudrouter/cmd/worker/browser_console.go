@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const consoleMaxEntries = 500
+
+// consoleEntry is one console message, browser log entry, or uncaught
+// exception.
+type consoleEntry struct {
+	Level     string  `json:"level"`
+	Source    string  `json:"source"`
+	Text      string  `json:"text"`
+	URL       string  `json:"url,omitempty"`
+	Line      int     `json:"line,omitempty"`
+	Timestamp float64 `json:"timestamp"`
+}
+
+// consoleBuffer is a fixed-size ring of the most recent console entries for
+// one page. Capture starts as soon as the session connects so errors thrown
+// before the agent asks are not lost.
+type consoleBuffer struct {
+	mu      sync.Mutex
+	entries []consoleEntry
+	next    int
+	full    bool
+}
+
+func newConsoleBuffer() *consoleBuffer {
+	return &consoleBuffer{entries: make([]consoleEntry, consoleMaxEntries)}
+}
+
+func (b *consoleBuffer) add(e consoleEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// list returns entries oldest first.
+func (b *consoleBuffer) list() []consoleEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]consoleEntry(nil), b.entries[:b.next]...)
+	}
+	out := make([]consoleEntry, 0, len(b.entries))
+	out = append(out, b.entries[b.next:]...)
+	return append(out, b.entries[:b.next]...)
+}
+
+func (b *consoleBuffer) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next = 0
+	b.full = false
+}
+
+// remoteObjectText renders a Runtime.RemoteObject the way DevTools would
+// print it inline.
+func remoteObjectText(raw json.RawMessage) string {
+	var obj struct {
+		Type        string          `json:"type"`
+		Value       json.RawMessage `json:"value"`
+		Description string          `json:"description"`
+	}
+	if json.Unmarshal(raw, &obj) != nil {
+		return ""
+	}
+	if obj.Type == "string" {
+		var s string
+		if json.Unmarshal(obj.Value, &s) == nil {
+			return s
+		}
+	}
+	if len(obj.Value) > 0 {
+		return string(obj.Value)
+	}
+	if obj.Description != "" {
+		return obj.Description
+	}
+	return obj.Type
+}
+
+func (b *consoleBuffer) handleEvent(method string, params json.RawMessage) {
+	switch method {
+	case "Runtime.consoleAPICalled":
+		var ev struct {
+			Type       string            `json:"type"`
+			Args       []json.RawMessage `json:"args"`
+			Timestamp  float64           `json:"timestamp"`
+			StackTrace *struct {
+				CallFrames []struct {
+					URL        string `json:"url"`
+					LineNumber int    `json:"lineNumber"`
+				} `json:"callFrames"`
+			} `json:"stackTrace"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		parts := make([]string, 0, len(ev.Args))
+		for _, arg := range ev.Args {
+			parts = append(parts, remoteObjectText(arg))
+		}
+		entry := consoleEntry{
+			Level:     consoleLevel(ev.Type),
+			Source:    "console",
+			Text:      strings.Join(parts, " "),
+			Timestamp: ev.Timestamp,
+		}
+		if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+			entry.URL = ev.StackTrace.CallFrames[0].URL
+			entry.Line = ev.StackTrace.CallFrames[0].LineNumber + 1
+		}
+		b.add(entry)
+
+	case "Runtime.exceptionThrown":
+		var ev struct {
+			Timestamp        float64 `json:"timestamp"`
+			ExceptionDetails struct {
+				Text       string          `json:"text"`
+				URL        string          `json:"url"`
+				LineNumber int             `json:"lineNumber"`
+				Exception  json.RawMessage `json:"exception"`
+			} `json:"exceptionDetails"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		d := ev.ExceptionDetails
+		text := d.Text
+		if len(d.Exception) > 0 {
+			if desc := remoteObjectText(d.Exception); desc != "" {
+				text = desc
+			}
+		}
+		b.add(consoleEntry{
+			Level:     "error",
+			Source:    "exception",
+			Text:      text,
+			URL:       d.URL,
+			Line:      d.LineNumber + 1,
+			Timestamp: ev.Timestamp,
+		})
+
+	case "Log.entryAdded":
+		var ev struct {
+			Entry struct {
+				Source     string  `json:"source"`
+				Level      string  `json:"level"`
+				Text       string  `json:"text"`
+				URL        string  `json:"url"`
+				LineNumber int     `json:"lineNumber"`
+				Timestamp  float64 `json:"timestamp"`
+			} `json:"entry"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		e := ev.Entry
+		entry := consoleEntry{
+			Level:     consoleLevel(e.Level),
+			Source:    e.Source,
+			Text:      e.Text,
+			URL:       e.URL,
+			Timestamp: e.Timestamp,
+		}
+		if e.LineNumber > 0 {
+			entry.Line = e.LineNumber + 1
+		}
+		b.add(entry)
+	}
+}
+
+// consoleLevel normalizes console API types and Log levels to
+// debug/info/warning/error.
+func consoleLevel(t string) string {
+	switch t {
+	case "error", "assert":
+		return "error"
+	case "warning", "warn":
+		return "warning"
+	case "debug", "verbose", "trace":
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+var consoleLevelRank = map[string]int{"debug": 0, "info": 1, "warning": 2, "error": 3}
+
+// enableConsoleCapture turns on the Runtime and Log domains for a freshly
+// connected session and starts feeding its console buffer.
+func enableConsoleCapture(ctx context.Context, s *pageSession) error {
+	s.conn.On(s.console.handleEvent)
+	if err := s.conn.Call(ctx, "Runtime.enable", nil, nil); err != nil {
+		return err
+	}
+	return s.conn.Call(ctx, "Log.enable", nil, nil)
+}
+
+// cmdConsole returns recent console entries, optionally filtered by minimum
+// "level" and capped to the last "limit" entries. "clear": true empties the
+// buffer after reading.
+func cmdConsole(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	minRank := 0
+	if level, _ := params["level"].(string); level != "" {
+		switch level {
+		case "debug", "info", "log", "warn", "warning", "error":
+			minRank = consoleLevelRank[consoleLevel(level)]
+		default:
+			return nil, &browserError{status: http.StatusBadRequest, code: "invalid_level", msg: fmt.Sprintf("unknown console level %q", level)}
+		}
+	}
+
+	entries := s.console.list()
+	filtered := make([]consoleEntry, 0, len(entries))
+	for _, e := range entries {
+		if consoleLevelRank[e.Level] >= minRank {
+			filtered = append(filtered, e)
+		}
+	}
+	if n, ok := params["limit"].(float64); ok && n > 0 && int(n) < len(filtered) {
+		filtered = filtered[len(filtered)-int(n):]
+	}
+
+	if clear, _ := params["clear"].(bool); clear {
+		s.console.clear()
+	}
+	return map[string]interface{}{
+		"count":   len(filtered),
+		"entries": filtered,
+	}, nil
+}
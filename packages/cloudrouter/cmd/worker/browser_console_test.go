@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestConsoleBufferCollectsEvents(t *testing.T) {
+	b := newConsoleBuffer()
+	b.handleEvent("Runtime.consoleAPICalled", json.RawMessage(`{"type":"warning","timestamp":1,"args":[{"type":"string","value":"slow"},{"type":"number","value":42}],"stackTrace":{"callFrames":[{"url":"http://localhost:3000/app.js","lineNumber":9}]}}`))
+	b.handleEvent("Runtime.exceptionThrown", json.RawMessage(`{"timestamp":2,"exceptionDetails":{"text":"Uncaught","url":"http://localhost:3000/app.js","lineNumber":4,"exception":{"type":"object","description":"TypeError: x is undefined"}}}`))
+	b.handleEvent("Log.entryAdded", json.RawMessage(`{"entry":{"source":"network","level":"error","text":"Failed to load resource","url":"http://localhost:3000/missing.css","timestamp":3}}`))
+
+	entries := b.list()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Level != "warning" || e.Text != "slow 42" || e.Line != 10 {
+		t.Errorf("console entry = %+v", e)
+	}
+	if e := entries[1]; e.Level != "error" || e.Source != "exception" || e.Text != "TypeError: x is undefined" {
+		t.Errorf("exception entry = %+v", e)
+	}
+	if e := entries[2]; e.Source != "network" || e.URL != "http://localhost:3000/missing.css" {
+		t.Errorf("log entry = %+v", e)
+	}
+}
+
+func TestConsoleBufferWrapsOldestFirst(t *testing.T) {
+	b := newConsoleBuffer()
+	for i := 0; i < consoleMaxEntries+3; i++ {
+		b.add(consoleEntry{Text: fmt.Sprint(i)})
+	}
+	entries := b.list()
+	if len(entries) != consoleMaxEntries {
+		t.Fatalf("expected %d entries, got %d", consoleMaxEntries, len(entries))
+	}
+	if entries[0].Text != "3" || entries[len(entries)-1].Text != fmt.Sprint(consoleMaxEntries+2) {
+		t.Errorf("unexpected order: first %q last %q", entries[0].Text, entries[len(entries)-1].Text)
+	}
+
+	b.clear()
+	if got := len(b.list()); got != 0 {
+		t.Errorf("expected empty buffer after clear, got %d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	evalDefaultMaxBytes = 256 * 1024
+	evalMaxBytes        = 4 * 1024 * 1024
+)
+
+// cmdEval evaluates "expression" in the page's main world. The result is
+// returned by value, so it must be JSON-serializable; "awaitPromise": true
+// resolves a returned promise first. Results larger than "maxBytes" once
+// serialized are rejected rather than truncated, since half a JSON value is
+// useless to the caller.
+func cmdEval(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	expression, _ := params["expression"].(string)
+	if expression == "" {
+		return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "expression required"}
+	}
+	awaitPromise, _ := params["awaitPromise"].(bool)
+	maxBytes := evalDefaultMaxBytes
+	if v, ok := params["maxBytes"].(float64); ok && v > 0 {
+		maxBytes = int(v)
+	}
+	if maxBytes > evalMaxBytes {
+		maxBytes = evalMaxBytes
+	}
+
+	var res struct {
+		Result struct {
+			Type                string          `json:"type"`
+			Subtype             string          `json:"subtype"`
+			Value               json.RawMessage `json:"value"`
+			UnserializableValue string          `json:"unserializableValue"`
+			Description         string          `json:"description"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	if err := s.conn.Call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  awaitPromise,
+		"userGesture":   true,
+		"replMode":      true,
+	}, &res); err != nil {
+		return nil, err
+	}
+
+	if ex := res.ExceptionDetails; ex != nil {
+		msg := ex.Text
+		if ex.Exception != nil && ex.Exception.Description != "" {
+			msg = ex.Exception.Description
+		}
+		return nil, &browserError{status: http.StatusBadRequest, code: "eval_error", msg: msg}
+	}
+
+	r := res.Result
+	result := map[string]interface{}{"type": r.Type}
+	if r.Subtype != "" {
+		result["subtype"] = r.Subtype
+	}
+	switch {
+	case r.UnserializableValue != "":
+		// NaN, Infinity, -0 and bigints have no JSON form.
+		result["value"] = r.UnserializableValue
+		result["unserializable"] = true
+	case len(r.Value) > 0:
+		if len(r.Value) > maxBytes {
+			return nil, &browserError{
+				status: http.StatusRequestEntityTooLarge,
+				code:   "result_too_large",
+				msg:    fmt.Sprintf("result is %d bytes, limit is %d", len(r.Value), maxBytes),
+			}
+		}
+		result["value"] = r.Value
+	default:
+		result["value"] = nil
+		if r.Type != "undefined" && r.Description != "" {
+			result["description"] = r.Description
+		}
+	}
+	return result, nil
+}
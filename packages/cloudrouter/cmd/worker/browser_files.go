@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// browserDownloadDir is where Chrome saves downloads triggered by the page.
+var browserDownloadDir = filepath.Join(homeDir, "Downloads")
+
+// download is one file download reported by the Browser domain.
+type download struct {
+	GUID      string  `json:"guid"`
+	URL       string  `json:"url"`
+	Filename  string  `json:"filename"`
+	Path      string  `json:"path"`
+	State     string  `json:"state"`
+	Received  float64 `json:"receivedBytes"`
+	Total     float64 `json:"totalBytes,omitempty"`
+	StartedAt int64   `json:"startedAt"`
+}
+
+// downloadTracker records downloads across all page sessions. Download
+// behavior is browser-wide, so every session feeds the same tracker.
+type downloadTracker struct {
+	mu    sync.Mutex
+	order []string
+	byID  map[string]*download
+}
+
+var downloads = &downloadTracker{byID: make(map[string]*download)}
+
+func (d *downloadTracker) handleEvent(method string, params json.RawMessage) {
+	switch method {
+	case "Browser.downloadWillBegin":
+		var ev struct {
+			GUID              string `json:"guid"`
+			URL               string `json:"url"`
+			SuggestedFilename string `json:"suggestedFilename"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if _, ok := d.byID[ev.GUID]; ok {
+			return
+		}
+		d.byID[ev.GUID] = &download{
+			GUID:      ev.GUID,
+			URL:       ev.URL,
+			Filename:  ev.SuggestedFilename,
+			Path:      filepath.Join(browserDownloadDir, ev.SuggestedFilename),
+			State:     "inProgress",
+			StartedAt: time.Now().UnixMilli(),
+		}
+		d.order = append(d.order, ev.GUID)
+
+	case "Browser.downloadProgress":
+		var ev struct {
+			GUID          string  `json:"guid"`
+			State         string  `json:"state"`
+			ReceivedBytes float64 `json:"receivedBytes"`
+			TotalBytes    float64 `json:"totalBytes"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if dl, ok := d.byID[ev.GUID]; ok {
+			dl.State = ev.State
+			dl.Received = ev.ReceivedBytes
+			dl.Total = ev.TotalBytes
+		}
+	}
+}
+
+func (d *downloadTracker) list(includePending bool) []download {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]download, 0, len(d.order))
+	for _, id := range d.order {
+		dl := d.byID[id]
+		if dl.State != "completed" && !includePending {
+			continue
+		}
+		out = append(out, *dl)
+	}
+	return out
+}
+
+// enableDownloads points Chrome's downloads at browserDownloadDir and
+// subscribes the session to download events.
+func enableDownloads(ctx context.Context, s *pageSession) error {
+	if err := os.MkdirAll(browserDownloadDir, 0755); err != nil {
+		return fmt.Errorf("failed to create download dir: %w", err)
+	}
+	s.conn.On(downloads.handleEvent)
+	return s.conn.Call(ctx, "Browser.setDownloadBehavior", map[string]interface{}{
+		"behavior":      "allow",
+		"downloadPath":  browserDownloadDir,
+		"eventsEnabled": true,
+	}, nil)
+}
+
+// cmdDownloads lists completed downloads; "all": true includes in-progress
+// and canceled ones.
+func cmdDownloads(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	all, _ := params["all"].(bool)
+	items := downloads.list(all)
+
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, dl := range items {
+		item := map[string]interface{}{
+			"guid":          dl.GUID,
+			"url":           dl.URL,
+			"filename":      dl.Filename,
+			"path":          dl.Path,
+			"state":         dl.State,
+			"receivedBytes": dl.Received,
+			"startedAt":     dl.StartedAt,
+		}
+		if dl.Total > 0 {
+			item["totalBytes"] = dl.Total
+		}
+		if info, err := os.Stat(dl.Path); err == nil {
+			item["size"] = info.Size()
+		}
+		result = append(result, item)
+	}
+	return map[string]interface{}{
+		"dir":       browserDownloadDir,
+		"count":     len(result),
+		"downloads": result,
+	}, nil
+}
+
+// cmdSetFiles sets the files of an <input type=file>. Relative paths are
+// resolved against the workspace; every file must exist in the VM.
+func cmdSetFiles(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	files := stringList(params["files"])
+	if len(files) == 0 {
+		return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "files required"}
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(workspaceDir, f)
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, &browserError{status: http.StatusBadRequest, code: "file_not_found", msg: fmt.Sprintf("file not found: %s", f)}
+		}
+		if info.IsDir() {
+			return nil, &browserError{status: http.StatusBadRequest, code: "file_not_found", msg: fmt.Sprintf("not a file: %s", f)}
+		}
+		paths = append(paths, f)
+	}
+
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "DOM.setFileInputFiles", map[string]interface{}{
+		"files":         paths,
+		"backendNodeId": nodeID,
+	}, nil); err != nil {
+		var ce *cdpError
+		if errors.As(err, &ce) {
+			return nil, &browserError{status: http.StatusBadRequest, code: "not_file_input", msg: ce.Message}
+		}
+		return nil, err
+	}
+	return map[string]interface{}{"files": paths}, nil
+}
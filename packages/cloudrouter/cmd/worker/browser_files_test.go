@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadTrackerListsCompleted(t *testing.T) {
+	d := &downloadTracker{byID: make(map[string]*download)}
+	d.handleEvent("Browser.downloadWillBegin", json.RawMessage(`{"guid":"a","url":"http://localhost:3000/report.csv","suggestedFilename":"report.csv"}`))
+	d.handleEvent("Browser.downloadWillBegin", json.RawMessage(`{"guid":"b","url":"http://localhost:3000/big.zip","suggestedFilename":"big.zip"}`))
+	// A second session reporting the same download must not duplicate it.
+	d.handleEvent("Browser.downloadWillBegin", json.RawMessage(`{"guid":"a","url":"http://localhost:3000/report.csv","suggestedFilename":"report.csv"}`))
+	d.handleEvent("Browser.downloadProgress", json.RawMessage(`{"guid":"a","state":"completed","receivedBytes":12,"totalBytes":12}`))
+	d.handleEvent("Browser.downloadProgress", json.RawMessage(`{"guid":"b","state":"inProgress","receivedBytes":5,"totalBytes":100}`))
+
+	done := d.list(false)
+	if len(done) != 1 || done[0].GUID != "a" {
+		t.Fatalf("completed downloads = %+v", done)
+	}
+	if done[0].Path != filepath.Join(browserDownloadDir, "report.csv") || done[0].Received != 12 {
+		t.Errorf("unexpected download: %+v", done[0])
+	}
+	if all := d.list(true); len(all) != 2 {
+		t.Errorf("expected 2 downloads including pending, got %d", len(all))
+	}
+}
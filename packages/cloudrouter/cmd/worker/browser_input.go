@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// elementCenter scrolls the node into view and returns the center of its
+// first content quad in viewport coordinates.
+func elementCenter(ctx context.Context, s *pageSession, backendNodeID int64) (float64, float64, error) {
+	_ = s.conn.Call(ctx, "DOM.scrollIntoViewIfNeeded", map[string]interface{}{"backendNodeId": backendNodeID}, nil)
+
+	var quads struct {
+		Quads [][]float64 `json:"quads"`
+	}
+	if err := s.conn.Call(ctx, "DOM.getContentQuads", map[string]interface{}{"backendNodeId": backendNodeID}, &quads); err != nil {
+		return 0, 0, err
+	}
+	if len(quads.Quads) == 0 || len(quads.Quads[0]) < 8 {
+		return 0, 0, &browserError{status: http.StatusConflict, code: "not_visible", msg: "element has no visible box"}
+	}
+
+	q := quads.Quads[0]
+	x := (q[0] + q[2] + q[4] + q[6]) / 4
+	y := (q[1] + q[3] + q[5] + q[7]) / 4
+	return x, y, nil
+}
+
+func dispatchMouse(ctx context.Context, s *pageSession, eventType string, x, y float64, clickCount int) error {
+	params := map[string]interface{}{
+		"type": eventType,
+		"x":    x,
+		"y":    y,
+	}
+	if eventType != "mouseMoved" {
+		params["button"] = "left"
+		params["clickCount"] = clickCount
+	}
+	return s.conn.Call(ctx, "Input.dispatchMouseEvent", params, nil)
+}
+
+func cmdClick(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	x, y, err := elementCenter(ctx, s, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	clickCount := 1
+	if n, ok := params["clickCount"].(float64); ok && n > 0 {
+		clickCount = int(n)
+	}
+
+	if err := dispatchMouse(ctx, s, "mouseMoved", x, y, 0); err != nil {
+		return nil, err
+	}
+	if err := dispatchMouse(ctx, s, "mousePressed", x, y, clickCount); err != nil {
+		return nil, err
+	}
+	if err := dispatchMouse(ctx, s, "mouseReleased", x, y, clickCount); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"x": x, "y": y}, nil
+}
+
+func cmdHover(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	x, y, err := elementCenter(ctx, s, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := dispatchMouse(ctx, s, "mouseMoved", x, y, 0); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"x": x, "y": y}, nil
+}
+
+func cmdFocus(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "DOM.focus", map[string]interface{}{"backendNodeId": nodeID}, nil); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// cmdFill clears an input and types value into it as a single insertion,
+// firing the input events frameworks listen for.
+func cmdFill(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	value, _ := params["value"].(string)
+
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "DOM.focus", map[string]interface{}{"backendNodeId": nodeID}, nil); err != nil {
+		return nil, err
+	}
+
+	var resolved struct {
+		Object struct {
+			ObjectID string `json:"objectId"`
+		} `json:"object"`
+	}
+	if err := s.conn.Call(ctx, "DOM.resolveNode", map[string]interface{}{"backendNodeId": nodeID}, &resolved); err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "Runtime.callFunctionOn", map[string]interface{}{
+		"objectId":            resolved.Object.ObjectID,
+		"functionDeclaration": `function() { if ("value" in this) { this.value = ""; } else if (this.isContentEditable) { this.textContent = ""; } this.dispatchEvent(new Event("input", { bubbles: true })); }`,
+	}, nil); err != nil {
+		return nil, err
+	}
+
+	if value != "" {
+		if err := s.conn.Call(ctx, "Input.insertText", map[string]interface{}{"text": value}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
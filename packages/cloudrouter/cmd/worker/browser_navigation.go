@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// waitUntil values accepted by navigation commands, mapped to the Page
+// lifecycle event that satisfies them. "commit" only waits for the new
+// document to be committed.
+var waitUntilEvents = map[string]string{
+	"commit":           "",
+	"domcontentloaded": "DOMContentLoaded",
+	"load":             "load",
+	"networkidle":      "networkIdle",
+}
+
+// navTracker follows main-frame navigations and the lifecycle events fired
+// for the current document so commands can wait on real page state instead
+// of fixed sleeps.
+type navTracker struct {
+	mu        sync.Mutex
+	mainFrame string
+	loaderID  string
+	url       string
+	seq       int
+	events    map[string]bool
+	changed   chan struct{}
+}
+
+func newNavTracker() *navTracker {
+	return &navTracker{events: make(map[string]bool), changed: make(chan struct{})}
+}
+
+// notifyLocked wakes all waiters. Callers hold n.mu.
+func (n *navTracker) notifyLocked() {
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// commitLocked records a new main-frame document.
+func (n *navTracker) commitLocked(loaderID string) {
+	if loaderID == n.loaderID {
+		return
+	}
+	n.loaderID = loaderID
+	n.seq++
+	n.events = make(map[string]bool)
+}
+
+func (n *navTracker) handleEvent(method string, params json.RawMessage) {
+	switch method {
+	case "Page.frameNavigated":
+		var ev struct {
+			Frame struct {
+				ID       string `json:"id"`
+				ParentID string `json:"parentId"`
+				LoaderID string `json:"loaderId"`
+				URL      string `json:"url"`
+			} `json:"frame"`
+		}
+		if json.Unmarshal(params, &ev) != nil || ev.Frame.ParentID != "" {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.mainFrame = ev.Frame.ID
+		n.url = ev.Frame.URL
+		n.commitLocked(ev.Frame.LoaderID)
+		n.notifyLocked()
+
+	case "Page.lifecycleEvent":
+		var ev struct {
+			FrameID  string `json:"frameId"`
+			LoaderID string `json:"loaderId"`
+			Name     string `json:"name"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.mainFrame != "" && ev.FrameID != n.mainFrame {
+			return
+		}
+		if ev.Name == "init" {
+			n.commitLocked(ev.LoaderID)
+		}
+		if ev.LoaderID == n.loaderID {
+			n.events[ev.Name] = true
+		}
+		n.notifyLocked()
+
+	case "Page.navigatedWithinDocument":
+		var ev struct {
+			FrameID string `json:"frameId"`
+			URL     string `json:"url"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if ev.FrameID != n.mainFrame {
+			return
+		}
+		// Same-document navigations (pushState, hash changes) never fire
+		// lifecycle events; the document is already fully loaded.
+		n.url = ev.URL
+		n.seq++
+		for _, name := range waitUntilEvents {
+			if name != "" {
+				n.events[name] = true
+			}
+		}
+		n.notifyLocked()
+	}
+}
+
+// state returns the current navigation sequence number and URL.
+func (n *navTracker) state() (int, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.seq, n.url
+}
+
+// wait blocks until a navigation newer than afterSeq (or, if loaderID is
+// set, the document with that loader) has fired event. An empty event
+// only requires the navigation to have committed.
+func (n *navTracker) wait(ctx context.Context, afterSeq int, loaderID, event string) error {
+	for {
+		n.mu.Lock()
+		committed := n.seq > afterSeq
+		if loaderID != "" {
+			committed = n.loaderID == loaderID
+		}
+		done := committed && (event == "" || n.events[event])
+		changed := n.changed
+		n.mu.Unlock()
+
+		if done {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return &browserError{status: http.StatusGatewayTimeout, code: "navigation_timeout", msg: fmt.Sprintf("timed out waiting for %s", waitLabel(event))}
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+func waitLabel(event string) string {
+	if event == "" {
+		return "navigation"
+	}
+	return event
+}
+
+// enableNavigationTracking turns on Page lifecycle events and seeds the
+// tracker with the current main frame.
+func enableNavigationTracking(ctx context.Context, s *pageSession) error {
+	s.conn.On(s.nav.handleEvent)
+	if err := s.conn.Call(ctx, "Page.enable", nil, nil); err != nil {
+		return err
+	}
+	if err := s.conn.Call(ctx, "Page.setLifecycleEventsEnabled", map[string]interface{}{"enabled": true}, nil); err != nil {
+		return err
+	}
+
+	var tree struct {
+		FrameTree struct {
+			Frame struct {
+				ID       string `json:"id"`
+				LoaderID string `json:"loaderId"`
+				URL      string `json:"url"`
+			} `json:"frame"`
+		} `json:"frameTree"`
+	}
+	if err := s.conn.Call(ctx, "Page.getFrameTree", nil, &tree); err != nil {
+		return err
+	}
+
+	n := s.nav
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.mainFrame == "" {
+		n.mainFrame = tree.FrameTree.Frame.ID
+		n.url = tree.FrameTree.Frame.URL
+		n.loaderID = tree.FrameTree.Frame.LoaderID
+	}
+	return nil
+}
+
+// navigationOptions reads "waitUntil" (default load) and an optional
+// "timeout" in milliseconds, returning the lifecycle event to wait for and
+// a context bounded by the timeout. The same "timeout" also bounds the
+// whole command (see commandTimeout), capped server-side.
+func navigationOptions(ctx context.Context, params map[string]interface{}) (string, context.Context, context.CancelFunc, error) {
+	waitUntil, _ := params["waitUntil"].(string)
+	if waitUntil == "" {
+		waitUntil = "load"
+	}
+	event, ok := waitUntilEvents[waitUntil]
+	if !ok {
+		return "", nil, nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: fmt.Sprintf("unknown waitUntil %q (use commit, domcontentloaded, load, networkidle)", waitUntil)}
+	}
+
+	if t, ok := params["timeout"].(float64); ok && t > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, time.Duration(t)*time.Millisecond)
+		return event, waitCtx, cancel, nil
+	}
+	waitCtx, cancel := context.WithCancel(ctx)
+	return event, waitCtx, cancel, nil
+}
+
+func navigationResult(s *pageSession) map[string]interface{} {
+	_, url := s.nav.state()
+	return map[string]interface{}{"url": url}
+}
+
+// cmdURL returns the page's current URL.
+func cmdURL(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	info, err := targetInfo(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"url": info.URL}, nil
+}
+
+// cmdTitle returns the page's current title.
+func cmdTitle(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	info, err := targetInfo(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"title": info.Title, "url": info.URL}, nil
+}
+
+type pageTargetInfo struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+func targetInfo(ctx context.Context, s *pageSession) (*pageTargetInfo, error) {
+	var res struct {
+		TargetInfo pageTargetInfo `json:"targetInfo"`
+	}
+	if err := s.conn.Call(ctx, "Target.getTargetInfo", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res.TargetInfo, nil
+}
+
+// cmdOpen navigates the page to "url" and waits for "waitUntil".
+func cmdOpen(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	url, _ := params["url"].(string)
+	if url == "" {
+		return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "url required"}
+	}
+	event, waitCtx, cancel, err := navigationOptions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	seq, _ := s.nav.state()
+	var res struct {
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := s.conn.Call(ctx, "Page.navigate", map[string]interface{}{"url": url}, &res); err != nil {
+		return nil, err
+	}
+	if res.ErrorText != "" {
+		return nil, &browserError{status: http.StatusBadGateway, code: "navigation_failed", msg: fmt.Sprintf("navigation to %s failed: %s", url, res.ErrorText)}
+	}
+
+	// No loader means a same-document navigation, which has already
+	// completed.
+	if res.LoaderID != "" {
+		if err := s.nav.wait(waitCtx, seq, res.LoaderID, event); err != nil {
+			return nil, err
+		}
+	}
+	return navigationResult(s), nil
+}
+
+// cmdReload reloads the page and waits for "waitUntil".
+func cmdReload(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	event, waitCtx, cancel, err := navigationOptions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	ignoreCache, _ := params["ignoreCache"].(bool)
+	seq, _ := s.nav.state()
+	if err := s.conn.Call(ctx, "Page.reload", map[string]interface{}{"ignoreCache": ignoreCache}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.nav.wait(waitCtx, seq, "", event); err != nil {
+		return nil, err
+	}
+	return navigationResult(s), nil
+}
+
+func cmdBack(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	return navigateHistory(ctx, s, params, -1)
+}
+
+func cmdForward(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	return navigateHistory(ctx, s, params, 1)
+}
+
+func navigateHistory(ctx context.Context, s *pageSession, params map[string]interface{}, delta int) (map[string]interface{}, error) {
+	event, waitCtx, cancel, err := navigationOptions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var history struct {
+		CurrentIndex int `json:"currentIndex"`
+		Entries      []struct {
+			ID int `json:"id"`
+		} `json:"entries"`
+	}
+	if err := s.conn.Call(ctx, "Page.getNavigationHistory", nil, &history); err != nil {
+		return nil, err
+	}
+	index := history.CurrentIndex + delta
+	if index < 0 || index >= len(history.Entries) {
+		return nil, &browserError{status: http.StatusConflict, code: "no_history", msg: "no history entry to navigate to"}
+	}
+
+	seq, _ := s.nav.state()
+	if err := s.conn.Call(ctx, "Page.navigateToHistoryEntry", map[string]interface{}{"entryId": history.Entries[index].ID}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.nav.wait(waitCtx, seq, "", event); err != nil {
+		return nil, err
+	}
+	return navigationResult(s), nil
+}
+
+// cmdWaitForNavigation waits for the next main-frame navigation, e.g. one
+// triggered by a click, to reach "waitUntil".
+func cmdWaitForNavigation(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	event, waitCtx, cancel, err := navigationOptions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	seq, _ := s.nav.state()
+	if err := s.nav.wait(waitCtx, seq, "", event); err != nil {
+		return nil, err
+	}
+	return navigationResult(s), nil
+}
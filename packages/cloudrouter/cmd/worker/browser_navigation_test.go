@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNavTrackerWaitsForLifecycleEvent(t *testing.T) {
+	n := newNavTracker()
+	n.mainFrame = "F"
+	n.loaderID = "L1"
+	seq, _ := n.state()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- n.wait(context.Background(), seq, "L2", "load")
+	}()
+
+	n.handleEvent("Page.lifecycleEvent", json.RawMessage(`{"frameId":"F","loaderId":"L2","name":"init"}`))
+	n.handleEvent("Page.frameNavigated", json.RawMessage(`{"frame":{"id":"F","loaderId":"L2","url":"http://localhost:3000/"}}`))
+	n.handleEvent("Page.lifecycleEvent", json.RawMessage(`{"frameId":"F","loaderId":"L2","name":"DOMContentLoaded"}`))
+
+	select {
+	case err := <-done:
+		t.Fatalf("wait returned before load: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Subframe events must not satisfy the main frame.
+	n.handleEvent("Page.lifecycleEvent", json.RawMessage(`{"frameId":"child","loaderId":"L2","name":"load"}`))
+	n.handleEvent("Page.lifecycleEvent", json.RawMessage(`{"frameId":"F","loaderId":"L2","name":"load"}`))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait did not return after load")
+	}
+	if _, url := n.state(); url != "http://localhost:3000/" {
+		t.Errorf("url = %q", url)
+	}
+}
+
+func TestNavTrackerSameDocumentNavigation(t *testing.T) {
+	n := newNavTracker()
+	n.mainFrame = "F"
+	seq, _ := n.state()
+
+	n.handleEvent("Page.navigatedWithinDocument", json.RawMessage(`{"frameId":"F","url":"http://localhost:3000/#settings"}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := n.wait(ctx, seq, "", "networkIdle"); err != nil {
+		t.Fatalf("same-document navigation should satisfy waits: %v", err)
+	}
+}
+
+func TestNavTrackerTimeout(t *testing.T) {
+	n := newNavTracker()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := n.wait(ctx, 0, "", "load")
+	var be *browserError
+	if !errors.As(err, &be) || be.code != "navigation_timeout" {
+		t.Fatalf("expected navigation_timeout, got %v", err)
+	}
+}
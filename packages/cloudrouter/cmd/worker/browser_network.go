@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	networkMaxEntries      = 1000
+	networkDefaultBodySize = 64 * 1024
+	networkMaxBodySize     = 1024 * 1024
+)
+
+// networkEntry is one captured request/response pair.
+type networkEntry struct {
+	RequestID    string            `json:"requestId"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	ResourceType string            `json:"resourceType,omitempty"`
+	Status       int               `json:"status,omitempty"`
+	StatusText   string            `json:"statusText,omitempty"`
+	MimeType     string            `json:"mimeType,omitempty"`
+	Headers      map[string]string `json:"responseHeaders,omitempty"`
+	Failed       bool              `json:"failed,omitempty"`
+	ErrorText    string            `json:"errorText,omitempty"`
+	Blocked      bool              `json:"blocked,omitempty"`
+	Finished     bool              `json:"finished"`
+	EncodedSize  float64           `json:"encodedDataLength,omitempty"`
+	StartedAt    int64             `json:"startedAt"`
+	DurationMs   int64             `json:"durationMs,omitempty"`
+}
+
+// networkRecorder captures Network domain events for one page session.
+// Entries are kept in arrival order and trimmed to networkMaxEntries.
+type networkRecorder struct {
+	mu       sync.Mutex
+	active   bool
+	entries  []*networkEntry
+	byID     map[string]*networkEntry
+	blocked  []string
+	stopFunc func()
+}
+
+func newNetworkRecorder() *networkRecorder {
+	return &networkRecorder{byID: make(map[string]*networkEntry)}
+}
+
+func (n *networkRecorder) handleEvent(method string, params json.RawMessage) {
+	switch method {
+	case "Network.requestWillBeSent":
+		var ev struct {
+			RequestID string `json:"requestId"`
+			Type      string `json:"type"`
+			Request   struct {
+				URL    string `json:"url"`
+				Method string `json:"method"`
+			} `json:"request"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		entry := &networkEntry{
+			RequestID:    ev.RequestID,
+			URL:          ev.Request.URL,
+			Method:       ev.Request.Method,
+			ResourceType: ev.Type,
+			StartedAt:    time.Now().UnixMilli(),
+		}
+		// Redirects reuse the request ID; keep the latest hop.
+		n.byID[ev.RequestID] = entry
+		n.entries = append(n.entries, entry)
+		if len(n.entries) > networkMaxEntries {
+			dropped := n.entries[0]
+			n.entries = n.entries[1:]
+			if n.byID[dropped.RequestID] == dropped {
+				delete(n.byID, dropped.RequestID)
+			}
+		}
+
+	case "Network.responseReceived":
+		var ev struct {
+			RequestID string `json:"requestId"`
+			Response  struct {
+				Status     int                    `json:"status"`
+				StatusText string                 `json:"statusText"`
+				MimeType   string                 `json:"mimeType"`
+				Headers    map[string]interface{} `json:"headers"`
+			} `json:"response"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if entry, ok := n.byID[ev.RequestID]; ok {
+			entry.Status = ev.Response.Status
+			entry.StatusText = ev.Response.StatusText
+			entry.MimeType = ev.Response.MimeType
+			entry.Headers = make(map[string]string, len(ev.Response.Headers))
+			for k, v := range ev.Response.Headers {
+				if s, ok := v.(string); ok {
+					entry.Headers[k] = s
+				}
+			}
+		}
+
+	case "Network.loadingFinished":
+		var ev struct {
+			RequestID         string  `json:"requestId"`
+			EncodedDataLength float64 `json:"encodedDataLength"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if entry, ok := n.byID[ev.RequestID]; ok {
+			entry.Finished = true
+			entry.EncodedSize = ev.EncodedDataLength
+			entry.DurationMs = time.Now().UnixMilli() - entry.StartedAt
+		}
+
+	case "Network.loadingFailed":
+		var ev struct {
+			RequestID     string `json:"requestId"`
+			ErrorText     string `json:"errorText"`
+			BlockedReason string `json:"blockedReason"`
+		}
+		if json.Unmarshal(params, &ev) != nil {
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if entry, ok := n.byID[ev.RequestID]; ok {
+			entry.Finished = true
+			entry.Failed = true
+			entry.ErrorText = ev.ErrorText
+			entry.Blocked = ev.BlockedReason != ""
+			entry.DurationMs = time.Now().UnixMilli() - entry.StartedAt
+		}
+	}
+}
+
+// snapshot returns copies of entries whose URL contains filter.
+func (n *networkRecorder) snapshot(filter string) []networkEntry {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := make([]networkEntry, 0, len(n.entries))
+	for _, e := range n.entries {
+		if filter != "" && !strings.Contains(e.URL, filter) {
+			continue
+		}
+		out = append(out, *e)
+	}
+	return out
+}
+
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// cmdNetworkStart begins capturing requests for the page, clearing any
+// previous capture. "block" is a list of URL patterns (with * wildcards)
+// that Chrome fails instead of fetching.
+func cmdNetworkStart(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	rec := s.network
+	blocked := stringList(params["block"])
+
+	if err := s.conn.Call(ctx, "Network.enable", map[string]interface{}{
+		"maxResourceBufferSize": networkMaxBodySize,
+		"maxTotalBufferSize":    50 * networkMaxBodySize,
+	}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "Network.setBlockedURLs", map[string]interface{}{"urls": blocked}, nil); err != nil {
+		return nil, err
+	}
+
+	rec.mu.Lock()
+	if rec.stopFunc != nil {
+		rec.stopFunc()
+	}
+	rec.entries = nil
+	rec.byID = make(map[string]*networkEntry)
+	rec.blocked = blocked
+	rec.active = true
+	rec.stopFunc = s.conn.On(rec.handleEvent)
+	rec.mu.Unlock()
+
+	return map[string]interface{}{"capturing": true, "blocked": blocked}, nil
+}
+
+// cmdNetworkStop stops capturing and clears blocked patterns. Captured
+// entries remain readable via network.requests.
+func cmdNetworkStop(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	rec := s.network
+
+	rec.mu.Lock()
+	if rec.stopFunc != nil {
+		rec.stopFunc()
+		rec.stopFunc = nil
+	}
+	rec.active = false
+	rec.blocked = nil
+	count := len(rec.entries)
+	rec.mu.Unlock()
+
+	if err := s.conn.Call(ctx, "Network.setBlockedURLs", map[string]interface{}{"urls": []string{}}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "Network.disable", nil, nil); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"capturing": false, "count": count}, nil
+}
+
+// cmdNetworkRequests lists captured requests. With "bodies": true, response
+// bodies of finished requests are included, truncated to "maxBodyBytes".
+func cmdNetworkRequests(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	filter, _ := params["filter"].(string)
+	withBodies, _ := params["bodies"].(bool)
+	maxBody := networkDefaultBodySize
+	if v, ok := params["maxBodyBytes"].(float64); ok && v > 0 {
+		maxBody = int(v)
+	}
+	if maxBody > networkMaxBodySize {
+		maxBody = networkMaxBodySize
+	}
+
+	entries := s.network.snapshot(filter)
+	requests := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		raw, _ := json.Marshal(e)
+		var item map[string]interface{}
+		_ = json.Unmarshal(raw, &item)
+
+		if withBodies && e.Finished && !e.Failed {
+			if body, truncated, err := responseBody(ctx, s, e.RequestID, maxBody); err == nil {
+				item["body"] = body
+				item["bodyTruncated"] = truncated
+			} else {
+				item["bodyError"] = err.Error()
+			}
+		}
+		requests = append(requests, item)
+	}
+
+	s.network.mu.Lock()
+	capturing := s.network.active
+	s.network.mu.Unlock()
+
+	return map[string]interface{}{
+		"capturing": capturing,
+		"count":     len(requests),
+		"requests":  requests,
+	}, nil
+}
+
+func responseBody(ctx context.Context, s *pageSession, requestID string, limit int) (string, bool, error) {
+	var res struct {
+		Body          string `json:"body"`
+		Base64Encoded bool   `json:"base64Encoded"`
+	}
+	if err := s.conn.Call(ctx, "Network.getResponseBody", map[string]interface{}{"requestId": requestID}, &res); err != nil {
+		return "", false, err
+	}
+
+	body := res.Body
+	if res.Base64Encoded {
+		raw, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return "", false, err
+		}
+		if len(raw) > limit {
+			return base64.StdEncoding.EncodeToString(raw[:limit]), true, nil
+		}
+		return body, false, nil
+	}
+	if len(body) > limit {
+		return body[:limit], true, nil
+	}
+	return body, false, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestNetworkRecorderTracksRequestLifecycle(t *testing.T) {
+	n := newNetworkRecorder()
+	n.handleEvent("Network.requestWillBeSent", json.RawMessage(`{"requestId":"1","type":"Fetch","request":{"url":"http://localhost:3000/api/items","method":"POST"}}`))
+	n.handleEvent("Network.requestWillBeSent", json.RawMessage(`{"requestId":"2","type":"Script","request":{"url":"http://cdn.example.com/ads.js","method":"GET"}}`))
+	n.handleEvent("Network.responseReceived", json.RawMessage(`{"requestId":"1","response":{"status":201,"statusText":"Created","mimeType":"application/json","headers":{"content-type":"application/json"}}}`))
+	n.handleEvent("Network.loadingFinished", json.RawMessage(`{"requestId":"1","encodedDataLength":42}`))
+	n.handleEvent("Network.loadingFailed", json.RawMessage(`{"requestId":"2","errorText":"net::ERR_BLOCKED_BY_CLIENT","blockedReason":"inspector"}`))
+
+	entries := n.snapshot("")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	api := entries[0]
+	if api.Status != 201 || !api.Finished || api.Failed || api.Headers["content-type"] != "application/json" {
+		t.Errorf("unexpected api entry: %+v", api)
+	}
+	ads := entries[1]
+	if !ads.Failed || !ads.Blocked {
+		t.Errorf("expected blocked failure, got %+v", ads)
+	}
+
+	if got := n.snapshot("/api/"); len(got) != 1 || got[0].RequestID != "1" {
+		t.Errorf("filter returned %+v", got)
+	}
+}
+
+func TestNetworkRecorderTrimsOldEntries(t *testing.T) {
+	n := newNetworkRecorder()
+	for i := 0; i < networkMaxEntries+5; i++ {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"requestId": fmt.Sprintf("r%d", i),
+			"request":   map[string]string{"url": "http://x/", "method": "GET"},
+		})
+		n.handleEvent("Network.requestWillBeSent", raw)
+	}
+	if got := len(n.snapshot("")); got != networkMaxEntries {
+		t.Errorf("expected %d entries, got %d", networkMaxEntries, got)
+	}
+}
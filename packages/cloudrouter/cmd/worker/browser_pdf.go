@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	pdfDefaultPath = "/tmp/page.pdf"
+	// pdfInlineMaxBytes is the largest PDF returned inline as base64;
+	// bigger files are only written to disk.
+	pdfInlineMaxBytes = 5 * 1024 * 1024
+)
+
+// cmdPDF prints the page with Page.printToPDF and writes it to "path"
+// (relative paths resolve against the workspace). Options: "landscape",
+// "scale", "pageRanges" (e.g. "1-3, 5"), and "printBackground".
+func cmdPDF(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	path, _ := params["path"].(string)
+	if path == "" {
+		path = pdfDefaultPath
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(workspaceDir, path)
+	}
+
+	opts := map[string]interface{}{"printBackground": true}
+	if v, ok := params["landscape"].(bool); ok {
+		opts["landscape"] = v
+	}
+	if v, ok := params["printBackground"].(bool); ok {
+		opts["printBackground"] = v
+	}
+	if v, ok := params["scale"].(float64); ok {
+		if v < 0.1 || v > 2 {
+			return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "scale must be between 0.1 and 2"}
+		}
+		opts["scale"] = v
+	}
+	if v, _ := params["pageRanges"].(string); v != "" {
+		opts["pageRanges"] = v
+	}
+
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := s.conn.Call(ctx, "Page.printToPDF", opts, &res); err != nil {
+		var ce *cdpError
+		if errors.As(err, &ce) {
+			if strings.Contains(ce.Message, "not implemented") {
+				return nil, &browserError{status: http.StatusNotImplemented, code: "unsupported", msg: "PDF export is not supported by this browser"}
+			}
+			return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: ce.Message}
+		}
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(res.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pdf: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write pdf: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"path": path,
+		"size": len(raw),
+	}
+	if len(raw) <= pdfInlineMaxBytes {
+		result["base64"] = res.Data
+	} else {
+		result["inline"] = false
+	}
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// defaultBrowserMaxPending is how many commands may wait behind the running
+// one on a single target before new ones are rejected. Override with
+// CMUX_BROWSER_MAX_PENDING.
+const defaultBrowserMaxPending = 8
+
+var browserMaxPending = loadBrowserMaxPending()
+
+func loadBrowserMaxPending() int {
+	if v := os.Getenv("CMUX_BROWSER_MAX_PENDING"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultBrowserMaxPending
+}
+
+// commandQueue serializes commands on one page target. Multi-step commands
+// (scroll then read quads then dispatch input) must not interleave with
+// another caller's, or each acts on state the other just changed.
+type commandQueue struct {
+	slot       chan struct{}
+	mu         sync.Mutex
+	pending    int
+	maxPending int
+}
+
+func newCommandQueue(maxPending int) *commandQueue {
+	return &commandQueue{slot: make(chan struct{}, 1), maxPending: maxPending}
+}
+
+// acquire waits for the target to be free. It fails fast with a 409
+// queue_full error when maxPending callers are already waiting. The
+// returned func must be called once the command finishes.
+func (q *commandQueue) acquire(ctx context.Context) (func(), error) {
+	q.mu.Lock()
+	if q.pending > q.maxPending {
+		q.mu.Unlock()
+		return nil, &browserError{
+			status: http.StatusConflict,
+			code:   "queue_full",
+			msg:    fmt.Sprintf("browser target busy: %d commands already queued", q.maxPending),
+		}
+	}
+	q.pending++
+	q.mu.Unlock()
+
+	select {
+	case q.slot <- struct{}{}:
+		return func() {
+			<-q.slot
+			q.done()
+		}, nil
+	case <-ctx.Done():
+		q.done()
+		return nil, ctx.Err()
+	}
+}
+
+func (q *commandQueue) done() {
+	q.mu.Lock()
+	q.pending--
+	q.mu.Unlock()
+}
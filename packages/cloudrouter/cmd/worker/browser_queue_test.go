@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCommandQueueSerializesAndRejectsWhenFull(t *testing.T) {
+	q := newCommandQueue(1)
+	ctx := context.Background()
+
+	release, err := q.acquire(ctx)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	acquired := make(chan func(), 1)
+	go func() {
+		r, err := q.acquire(ctx)
+		if err != nil {
+			t.Errorf("queued acquire: %v", err)
+			return
+		}
+		acquired <- r
+	}()
+
+	// Wait until the second caller is queued, then a third must be rejected.
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		pending := q.pending
+		q.mu.Unlock()
+		if pending == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("second caller never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err = q.acquire(ctx)
+	var be *browserError
+	if !errors.As(err, &be) || be.code != "queue_full" || be.status != 409 {
+		t.Fatalf("expected queue_full 409, got %v", err)
+	}
+
+	select {
+	case <-acquired:
+		t.Fatal("queued caller ran while the target was busy")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("queued caller did not run after release")
+	}
+}
+
+func TestCommandQueueHonorsContext(t *testing.T) {
+	q := newCommandQueue(4)
+	release, _ := q.acquire(context.Background())
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending != 1 {
+		t.Errorf("pending = %d after timeout, want 1", q.pending)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// browserReconnectAttempts bounds how many times a command whose CDP
+// session dropped tries to reconnect, waiting browserReconnectBackoff
+// (doubling) between attempts while Chrome comes back.
+const (
+	browserReconnectAttempts   = 5
+	browserReconnectBackoff    = 500 * time.Millisecond
+	browserReconnectMaxBackoff = 4 * time.Second
+)
+
+// cdpSessionLostMessages are protocol errors Chrome returns when the page
+// or browser went away under an in-flight command.
+var cdpSessionLostMessages = []string{
+	"target closed",
+	"session closed",
+	"inspected target navigated or closed",
+	"no target with given id",
+}
+
+// isSessionLost reports whether err means the CDP session died (Chrome
+// crashed, was OOM killed, or the tab was closed) rather than the command
+// itself failing.
+func isSessionLost(err error) bool {
+	if errors.Is(err, errCDPClosed) {
+		return true
+	}
+	var ce *cdpError
+	if errors.As(err, &ce) {
+		msg := strings.ToLower(ce.Message)
+		for _, m := range cdpSessionLostMessages {
+			if strings.Contains(msg, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// drop forgets a dead session so the next lookup dials a fresh one.
+func (bm *browserManager) drop(s *pageSession) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if bm.sessions[s.targetID] == s {
+		delete(bm.sessions, s.targetID)
+	}
+	s.conn.Close()
+}
+
+// reconnect opens a session after s was lost. The same target is preferred
+// if it survived (only the websocket dropped); after a Chrome restart the
+// old target is gone and the first page target is used instead.
+func (bm *browserManager) reconnect(ctx context.Context, s *pageSession) (*pageSession, error) {
+	bm.drop(s)
+
+	delay := browserReconnectBackoff
+	var lastErr error
+	for attempt := 1; attempt <= browserReconnectAttempts; attempt++ {
+		next, err := bm.session(ctx, s.targetID)
+		var be *browserError
+		if errors.As(err, &be) && be.code == "target_not_found" {
+			next, err = bm.session(ctx, "")
+		}
+		if err == nil {
+			if next.targetID != s.targetID {
+				log.Printf("[browser] target %s gone, reconnected to %s", s.targetID, next.targetID)
+			}
+			return next, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("[browser] reconnect attempt %d/%d failed: %v", attempt, browserReconnectAttempts, err)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > browserReconnectMaxBackoff {
+			delay = browserReconnectMaxBackoff
+		}
+	}
+	return nil, &browserError{
+		status: http.StatusServiceUnavailable,
+		code:   "browser_unavailable",
+		msg:    fmt.Sprintf("browser session lost and reconnect failed: %v", lastErr),
+	}
+}
+
+// errBrowserRestarted is returned for a non-idempotent command whose session
+// dropped mid-command: it may or may not have taken effect, so it is not
+// replayed.
+func errBrowserRestarted(restarted bool) error {
+	msg := "browser session lost mid-command; it may not have completed, so check page state before retrying"
+	if restarted {
+		msg = "browser restarted mid-command; the page was reset, so navigate again before retrying"
+	}
+	return &browserError{status: http.StatusServiceUnavailable, code: "browser_restarted", msg: msg}
+}
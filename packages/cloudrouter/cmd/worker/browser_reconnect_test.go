@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsSessionLost(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"closed connection", fmt.Errorf("DOM.getDocument: %w", errCDPClosed), true},
+		{"target closed", fmt.Errorf("Runtime.evaluate: %w", &cdpError{Code: -32000, Message: "Target closed"}), true},
+		{"session closed", &cdpError{Code: -32001, Message: "Session closed. Most likely the page has been closed."}, true},
+		{"navigated away", &cdpError{Code: -32000, Message: "Inspected target navigated or closed"}, true},
+		{"command error", &cdpError{Code: -32000, Message: "Could not find node with given id"}, false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"browser error", &browserError{code: "invalid_params", msg: "selector required"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSessionLost(tt.err); got != tt.want {
+				t.Errorf("isSessionLost(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnlyReadOnlyCommandsReplay(t *testing.T) {
+	for name, command := range browserCommands {
+		if command.idempotent && !command.readOnly {
+			t.Errorf("%s is replayed after reconnect but is not read-only", name)
+		}
+	}
+	for _, name := range []string{"url", "title", "snapshot"} {
+		if !browserCommands[name].idempotent {
+			t.Errorf("%s should be replayed after reconnect", name)
+		}
+	}
+}
+
+func TestErrBrowserRestarted(t *testing.T) {
+	var be *browserError
+	if err := errBrowserRestarted(true); !errors.As(err, &be) || be.code != "browser_restarted" || be.status != 503 {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// deviceProfile is a named viewport preset for setViewport.
+type deviceProfile struct {
+	width             int
+	height            int
+	deviceScaleFactor float64
+	mobile            bool
+	userAgent         string
+}
+
+var deviceProfiles = map[string]deviceProfile{
+	"desktop": {width: 1280, height: 800, deviceScaleFactor: 1},
+	"iphone-14": {
+		width: 390, height: 844, deviceScaleFactor: 3, mobile: true,
+		userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		width: 412, height: 915, deviceScaleFactor: 2.625, mobile: true,
+		userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+	},
+	"ipad": {
+		width: 820, height: 1180, deviceScaleFactor: 2, mobile: true,
+		userAgent: "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	},
+}
+
+// Screenshot captures the page over CDP. Options: "fullPage", "selector"
+// (CSS or @ref) for an element-only capture, "format" png|jpeg, "quality"
+// (jpeg only) and "target". Without options it falls back to agent-browser
+// when no page session is available.
+func (bm *browserManager) Screenshot(body map[string]interface{}) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	targetID, _ := body["target"].(string)
+	s, err := bm.session(ctx, targetID)
+	if err != nil {
+		if !hasScreenshotOptions(body) {
+			log.Printf("[browser] cdp session unavailable, using agent-browser: %v", err)
+			return bm.screenshotViaCLI(ctx)
+		}
+		return nil, err
+	}
+	return bm.runOn(ctx, s, true, func(s *pageSession) (map[string]interface{}, error) {
+		return cmdScreenshot(ctx, s, body)
+	})
+}
+
+func hasScreenshotOptions(body map[string]interface{}) bool {
+	for _, key := range []string{"target", "selector", "fullPage", "format", "quality"} {
+		if _, ok := body[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func cmdScreenshot(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	format, _ := params["format"].(string)
+	switch format {
+	case "", "png":
+		format = "png"
+	case "jpeg", "jpg":
+		format = "jpeg"
+	default:
+		return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: fmt.Sprintf("unsupported format %q", format)}
+	}
+
+	capture := map[string]interface{}{"format": format}
+	if format == "jpeg" {
+		quality := 80
+		if q, ok := params["quality"].(float64); ok && q > 0 && q <= 100 {
+			quality = int(q)
+		}
+		capture["quality"] = quality
+	}
+
+	fullPage, _ := params["fullPage"].(bool)
+	selector, _ := params["selector"].(string)
+	switch {
+	case selector != "":
+		clip, err := elementClip(ctx, s, selector)
+		if err != nil {
+			return nil, err
+		}
+		capture["clip"] = clip
+		capture["captureBeyondViewport"] = true
+	case fullPage:
+		var metrics struct {
+			CSSContentSize struct {
+				Width  float64 `json:"width"`
+				Height float64 `json:"height"`
+			} `json:"cssContentSize"`
+		}
+		if err := s.conn.Call(ctx, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+			return nil, err
+		}
+		capture["clip"] = map[string]interface{}{
+			"x": 0, "y": 0,
+			"width":  metrics.CSSContentSize.Width,
+			"height": metrics.CSSContentSize.Height,
+			"scale":  1,
+		}
+		capture["captureBeyondViewport"] = true
+	}
+
+	var shot struct {
+		Data string `json:"data"`
+	}
+	if err := s.conn.Call(ctx, "Page.captureScreenshot", capture, &shot); err != nil {
+		return nil, err
+	}
+
+	targetPath := "/tmp/screenshot.png"
+	if format == "jpeg" {
+		targetPath = "/tmp/screenshot.jpg"
+	}
+	if raw, err := base64.StdEncoding.DecodeString(shot.Data); err == nil {
+		if err := os.WriteFile(targetPath, raw, 0644); err != nil {
+			log.Printf("[browser] failed to write %s: %v", targetPath, err)
+		}
+	}
+
+	return map[string]interface{}{
+		"success":  true,
+		"path":     targetPath,
+		"format":   format,
+		"base64":   shot.Data,
+		"data":     map[string]interface{}{"base64": shot.Data},
+		"mimeType": "image/" + format,
+	}, nil
+}
+
+// elementClip returns the element's border box in page coordinates.
+func elementClip(ctx context.Context, s *pageSession, selector string) (map[string]interface{}, error) {
+	nodeID, err := resolveSelector(ctx, s, selector)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.conn.Call(ctx, "DOM.scrollIntoViewIfNeeded", map[string]interface{}{"backendNodeId": nodeID}, nil)
+
+	var box struct {
+		Model struct {
+			Border []float64 `json:"border"`
+		} `json:"model"`
+	}
+	if err := s.conn.Call(ctx, "DOM.getBoxModel", map[string]interface{}{"backendNodeId": nodeID}, &box); err != nil || len(box.Model.Border) < 8 {
+		return nil, &browserError{status: http.StatusConflict, code: "not_visible", msg: "element has no visible box"}
+	}
+
+	var metrics struct {
+		CSSLayoutViewport struct {
+			PageX float64 `json:"pageX"`
+			PageY float64 `json:"pageY"`
+		} `json:"cssLayoutViewport"`
+	}
+	if err := s.conn.Call(ctx, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+		return nil, err
+	}
+
+	q := box.Model.Border
+	minX, maxX, minY, maxY := q[0], q[0], q[1], q[1]
+	for i := 2; i < 8; i += 2 {
+		minX, maxX = min(minX, q[i]), max(maxX, q[i])
+		minY, maxY = min(minY, q[i+1]), max(maxY, q[i+1])
+	}
+	if maxX-minX <= 0 || maxY-minY <= 0 {
+		return nil, &browserError{status: http.StatusConflict, code: "not_visible", msg: "element has an empty box"}
+	}
+	return map[string]interface{}{
+		"x":      minX + metrics.CSSLayoutViewport.PageX,
+		"y":      minY + metrics.CSSLayoutViewport.PageY,
+		"width":  maxX - minX,
+		"height": maxY - minY,
+		"scale":  1,
+	}, nil
+}
+
+// cmdSetViewport overrides the page's device metrics. Pass "device" for a
+// preset (see deviceProfiles) or explicit "width"/"height" with optional
+// "deviceScaleFactor" and "mobile". "reset": true restores the real window.
+func cmdSetViewport(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	if reset, _ := params["reset"].(bool); reset {
+		if err := s.conn.Call(ctx, "Emulation.clearDeviceMetricsOverride", nil, nil); err != nil {
+			return nil, err
+		}
+		_ = s.conn.Call(ctx, "Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": false}, nil)
+		_ = s.conn.Call(ctx, "Emulation.setUserAgentOverride", map[string]interface{}{"userAgent": ""}, nil)
+		return map[string]interface{}{"reset": true}, nil
+	}
+
+	var profile deviceProfile
+	if name, _ := params["device"].(string); name != "" {
+		p, ok := deviceProfiles[name]
+		if !ok {
+			return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: fmt.Sprintf("unknown device %q", name)}
+		}
+		profile = p
+	} else {
+		profile.deviceScaleFactor = 1
+	}
+	if v, ok := params["width"].(float64); ok && v > 0 {
+		profile.width = int(v)
+	}
+	if v, ok := params["height"].(float64); ok && v > 0 {
+		profile.height = int(v)
+	}
+	if v, ok := params["deviceScaleFactor"].(float64); ok && v > 0 {
+		profile.deviceScaleFactor = v
+	}
+	if v, ok := params["mobile"].(bool); ok {
+		profile.mobile = v
+	}
+	if profile.width <= 0 || profile.height <= 0 {
+		return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "width and height required"}
+	}
+
+	if err := s.conn.Call(ctx, "Emulation.setDeviceMetricsOverride", map[string]interface{}{
+		"width":             profile.width,
+		"height":            profile.height,
+		"deviceScaleFactor": profile.deviceScaleFactor,
+		"mobile":            profile.mobile,
+	}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.conn.Call(ctx, "Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": profile.mobile}, nil); err != nil {
+		return nil, err
+	}
+	if profile.userAgent != "" {
+		if err := s.conn.Call(ctx, "Emulation.setUserAgentOverride", map[string]interface{}{"userAgent": profile.userAgent}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"width":             profile.width,
+		"height":            profile.height,
+		"deviceScaleFactor": profile.deviceScaleFactor,
+		"mobile":            profile.mobile,
+	}, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectorKind is how a selector string is matched against the page.
+type selectorKind int
+
+const (
+	selectorCSS selectorKind = iota
+	selectorXPath
+	selectorText
+)
+
+// waitPollInterval is how often "wait" re-checks for its element.
+const waitPollInterval = 100 * time.Millisecond
+
+// parsedSelector is a selector with its prefix stripped. Selectors are CSS
+// unless prefixed with "css=", "xpath=" or "text=". text= matches elements
+// whose visible text contains the value, case-insensitively; a quoted value
+// (text="Sign in") must match the whole text exactly.
+type parsedSelector struct {
+	kind  selectorKind
+	value string
+	exact bool
+}
+
+func parseSelector(selector string) (parsedSelector, error) {
+	s := strings.TrimSpace(selector)
+	switch {
+	case strings.HasPrefix(s, "css="):
+		s = strings.TrimSpace(strings.TrimPrefix(s, "css="))
+		if s == "" {
+			return parsedSelector{}, invalidSelector("css= selector is empty")
+		}
+		return parsedSelector{kind: selectorCSS, value: s}, nil
+	case strings.HasPrefix(s, "xpath="):
+		s = strings.TrimSpace(strings.TrimPrefix(s, "xpath="))
+		if s == "" {
+			return parsedSelector{}, invalidSelector("xpath= selector is empty")
+		}
+		return parsedSelector{kind: selectorXPath, value: s}, nil
+	case strings.HasPrefix(s, "text="):
+		s = strings.TrimSpace(strings.TrimPrefix(s, "text="))
+		exact := false
+		if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+			if s[0] == '"' {
+				unquoted, err := strconv.Unquote(s)
+				if err != nil {
+					return parsedSelector{}, invalidSelector(fmt.Sprintf("text= selector: %v", err))
+				}
+				s = unquoted
+			} else {
+				s = s[1 : len(s)-1]
+			}
+			exact = true
+		}
+		if strings.TrimSpace(s) == "" {
+			return parsedSelector{}, invalidSelector("text= selector is empty")
+		}
+		return parsedSelector{kind: selectorText, value: s, exact: exact}, nil
+	}
+	return parsedSelector{kind: selectorCSS, value: s}, nil
+}
+
+func invalidSelector(msg string) error {
+	return &browserError{status: http.StatusBadRequest, code: "invalid_selector", msg: msg}
+}
+
+// textSelectorScript lists the innermost visible elements whose normalized
+// text matches, in document order. Inputs match on their value or
+// placeholder, so buttons written as <input type=submit> can be found by
+// their label.
+const textSelectorScript = `((want, exact) => {
+  const norm = (s) => (s || "").replace(/\s+/g, " ").trim();
+  const needle = exact ? norm(want) : norm(want).toLowerCase();
+  const skip = new Set(["SCRIPT", "STYLE", "NOSCRIPT", "TEMPLATE", "HEAD"]);
+  const matches = [];
+  for (const el of document.querySelectorAll("*")) {
+    if (skip.has(el.tagName) || el.getClientRects().length === 0) continue;
+    let text = el.tagName === "INPUT" ? (el.value || el.placeholder) : el.textContent;
+    text = norm(text);
+    if (exact ? text === needle : text.toLowerCase().includes(needle)) matches.push(el);
+  }
+  return matches.filter((m) => !matches.some((o) => o !== m && m.contains(o)));
+})(%s, %t)`
+
+const xpathSelectorScript = `((expr) => {
+  const found = document.evaluate(expr, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+  return Array.from({ length: found.snapshotLength }, (_, i) => found.snapshotItem(i));
+})(%s)`
+
+// selectorExpression is the JavaScript that evaluates to the array of nodes
+// an xpath= or text= selector matches.
+func selectorExpression(sel parsedSelector) string {
+	quoted, _ := json.Marshal(sel.value)
+	if sel.kind == selectorXPath {
+		return fmt.Sprintf(xpathSelectorScript, quoted)
+	}
+	return fmt.Sprintf(textSelectorScript, quoted, sel.exact)
+}
+
+// resolveScriptSelector evaluates an xpath= or text= selector in the page
+// and returns the backend node ID of the element it finds.
+func resolveScriptSelector(ctx context.Context, s *pageSession, sel parsedSelector, selector string) (int64, error) {
+	var res struct {
+		Result struct {
+			Subtype  string `json:"subtype"`
+			ObjectID string `json:"objectId"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	if err := s.conn.Call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":  selectorExpression(sel) + "[0] || null",
+		"objectGroup": "selector",
+	}, &res); err != nil {
+		return 0, err
+	}
+	defer s.conn.Call(ctx, "Runtime.releaseObjectGroup", map[string]interface{}{"objectGroup": "selector"}, nil)
+	if ex := res.ExceptionDetails; ex != nil {
+		msg := ex.Text
+		if ex.Exception != nil && ex.Exception.Description != "" {
+			msg = ex.Exception.Description
+		}
+		return 0, invalidSelector(msg)
+	}
+	if res.Result.ObjectID == "" || res.Result.Subtype == "null" {
+		return 0, &browserError{status: http.StatusNotFound, code: "element_not_found", msg: fmt.Sprintf("no element matches %q", selector)}
+	}
+	if res.Result.Subtype != "node" {
+		return 0, invalidSelector(fmt.Sprintf("%q does not select an element", selector))
+	}
+
+	var described struct {
+		Node struct {
+			BackendNodeID int64 `json:"backendNodeId"`
+		} `json:"node"`
+	}
+	if err := s.conn.Call(ctx, "DOM.describeNode", map[string]interface{}{"objectId": res.Result.ObjectID}, &described); err != nil {
+		return 0, err
+	}
+	return described.Node.BackendNodeID, nil
+}
+
+// cmdWait polls until "selector" matches an element or the command times
+// out. Only element_not_found is retried: a malformed selector or a stale
+// ref will not fix itself by waiting.
+func cmdWait(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	selector, _ := params["selector"].(string)
+	start := time.Now()
+	for {
+		nodeID, err := resolveSelector(ctx, s, selector)
+		if err == nil {
+			return map[string]interface{}{
+				"backendNodeId": nodeID,
+				"waitedMs":      time.Since(start).Milliseconds(),
+			}, nil
+		}
+		var be *browserError
+		if !errors.As(err, &be) || be.code != "element_not_found" {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
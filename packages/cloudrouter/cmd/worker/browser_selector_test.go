@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		in   string
+		want parsedSelector
+	}{
+		{"#email", parsedSelector{kind: selectorCSS, value: "#email"}},
+		{"css=button.primary", parsedSelector{kind: selectorCSS, value: "button.primary"}},
+		{"xpath=//a[@href='/about']", parsedSelector{kind: selectorXPath, value: "//a[@href='/about']"}},
+		{"text=sign in", parsedSelector{kind: selectorText, value: "sign in"}},
+		{`text="Sign in"`, parsedSelector{kind: selectorText, value: "Sign in", exact: true}},
+		{`text='Say "hi"'`, parsedSelector{kind: selectorText, value: `Say "hi"`, exact: true}},
+		{`text="a \"b\""`, parsedSelector{kind: selectorText, value: `a "b"`, exact: true}},
+		{`text="Sign in`, parsedSelector{kind: selectorText, value: `"Sign in`}},
+	}
+	for _, tt := range tests {
+		got, err := parseSelector(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSelector(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"xpath=", "text=", `text=""`, "css= "} {
+		_, err := parseSelector(in)
+		var be *browserError
+		if !errors.As(err, &be) || be.code != "invalid_selector" {
+			t.Errorf("parseSelector(%q) error = %v, want invalid_selector", in, err)
+		}
+	}
+}
+
+func TestSelectorExpressionQuotesValue(t *testing.T) {
+	xpath := selectorExpression(parsedSelector{kind: selectorXPath, value: `//button[text()="Go"]`})
+	if !strings.HasSuffix(xpath, `})("//button[text()=\"Go\"]")`) {
+		t.Errorf("xpath expression = %s", xpath)
+	}
+
+	text := selectorExpression(parsedSelector{kind: selectorText, value: "it's </script>", exact: true})
+	if !strings.HasSuffix(text, `})("it's \u003c/script\u003e", true)`) {
+		t.Errorf("text expression ends with %q", text[strings.LastIndex(text, "})"):])
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// axValue is a CDP Accessibility.AXValue; only string-ish values are used.
+type axValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (v *axValue) String() string {
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	if s, ok := v.Value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v.Value)
+}
+
+type axNode struct {
+	NodeID           string   `json:"nodeId"`
+	Ignored          bool     `json:"ignored"`
+	Role             *axValue `json:"role,omitempty"`
+	Name             *axValue `json:"name,omitempty"`
+	Value            *axValue `json:"value,omitempty"`
+	ParentID         string   `json:"parentId,omitempty"`
+	ChildIDs         []string `json:"childIds,omitempty"`
+	BackendDOMNodeID int64    `json:"backendDOMNodeId,omitempty"`
+}
+
+// interactiveRoles get a ref even when they have no accessible name.
+var interactiveRoles = map[string]bool{
+	"button": true, "link": true, "textbox": true, "searchbox": true,
+	"checkbox": true, "radio": true, "combobox": true, "listbox": true,
+	"option": true, "menuitem": true, "menuitemcheckbox": true,
+	"menuitemradio": true, "tab": true, "switch": true, "slider": true,
+	"spinbutton": true, "treeitem": true,
+}
+
+// structuralRoles are flattened out of the snapshot; their children are
+// printed at the parent's depth.
+var structuralRoles = map[string]bool{
+	"none": true, "generic": true, "InlineTextBox": true,
+	"LineBreak": true, "presentation": true,
+}
+
+// refEntry is what a ref pointed at when the snapshot was taken.
+type refEntry struct {
+	BackendNodeID int64
+	Role          string
+	Name          string
+}
+
+// refTable maps @eN refs from the latest snapshot to DOM nodes, so resolving
+// a ref does not depend on re-walking a tree that may have changed since.
+// It also keeps the rendered lines of that snapshot so later pages can be
+// served without re-numbering refs.
+type refTable struct {
+	mu         sync.Mutex
+	entries    map[int]refEntry
+	lines      []string
+	generation int
+}
+
+func newRefTable() *refTable {
+	return &refTable{entries: make(map[int]refEntry)}
+}
+
+// replace installs a new snapshot and returns its generation.
+func (t *refTable) replace(entries map[int]refEntry, lines []string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = entries
+	t.lines = lines
+	t.generation++
+	return t.generation
+}
+
+// page returns up to limit lines of the snapshot with the given generation,
+// starting at offset. ok is false if a newer snapshot has replaced it.
+func (t *refTable) page(generation, offset, limit int) ([]string, int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if generation != t.generation {
+		return nil, 0, false
+	}
+	return pageLines(t.lines, offset, limit), len(t.lines), true
+}
+
+func (t *refTable) get(n int) (refEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[n]
+	return e, ok
+}
+
+// parseRef accepts "@e3", "e3", and "ref=e3".
+func parseRef(selector string) (int, bool) {
+	s := strings.TrimSpace(selector)
+	s = strings.TrimPrefix(s, "ref=")
+	s = strings.TrimPrefix(s, "@")
+	if !strings.HasPrefix(s, "e") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func staleRefError(ref int, reason string) error {
+	return &browserError{
+		status: http.StatusConflict,
+		code:   "stale_ref",
+		msg:    fmt.Sprintf("ref @e%d is stale (%s); take a new snapshot", ref, reason),
+	}
+}
+
+// snapshotOptions scope and filter a snapshot.
+type snapshotOptions struct {
+	// rootBackendID limits the snapshot to the subtree of this DOM node.
+	rootBackendID int64
+	// interactiveOnly prints only nodes with interactive roles.
+	interactiveOnly bool
+	// maxDepth stops descending past this many printed levels (0 = no limit).
+	maxDepth int
+}
+
+// buildSnapshot renders the whole AX tree as an indented outline and
+// assigns refs in document order.
+func buildSnapshot(nodes []axNode) (string, map[int]refEntry) {
+	lines, refs, _ := renderSnapshot(nodes, snapshotOptions{})
+	return strings.Join(lines, "\n"), refs
+}
+
+// renderSnapshot renders the AX tree, or the subtree selected by opts, one
+// line per printed node. found is false if the requested root is not in
+// the tree.
+func renderSnapshot(nodes []axNode, opts snapshotOptions) (lines []string, refs map[int]refEntry, found bool) {
+	byID := make(map[string]*axNode, len(nodes))
+	for i := range nodes {
+		byID[nodes[i].NodeID] = &nodes[i]
+	}
+
+	refs = make(map[int]refEntry)
+
+	var walk func(id string, depth int)
+	walk = func(id string, depth int) {
+		node, ok := byID[id]
+		if !ok {
+			return
+		}
+		if opts.maxDepth > 0 && depth >= opts.maxDepth {
+			return
+		}
+
+		role := node.Role.String()
+		name := strings.TrimSpace(node.Name.String())
+		childDepth := depth
+
+		printable := !node.Ignored && !structuralRoles[role] && role != "RootWebArea"
+		if opts.interactiveOnly && !interactiveRoles[role] {
+			printable = false
+		}
+		if printable {
+			if role == "StaticText" {
+				if name != "" {
+					lines = append(lines, fmt.Sprintf("%s- text: %q", strings.Repeat("  ", depth), name))
+				}
+			} else {
+				line := strings.Repeat("  ", depth) + "- " + role
+				if name != "" {
+					line += fmt.Sprintf(" %q", name)
+				}
+				if node.BackendDOMNodeID != 0 && (interactiveRoles[role] || name != "") {
+					n := len(refs) + 1
+					refs[n] = refEntry{BackendNodeID: node.BackendDOMNodeID, Role: role, Name: name}
+					line += fmt.Sprintf(" [ref=e%d]", n)
+				}
+				if v := node.Value.String(); v != "" && v != name {
+					line += fmt.Sprintf(": %q", v)
+				}
+				lines = append(lines, line)
+				childDepth = depth + 1
+			}
+		}
+
+		for _, child := range node.ChildIDs {
+			walk(child, childDepth)
+		}
+	}
+
+	for i := range nodes {
+		if opts.rootBackendID != 0 {
+			if nodes[i].BackendDOMNodeID == opts.rootBackendID {
+				walk(nodes[i].NodeID, 0)
+				return lines, refs, true
+			}
+			continue
+		}
+		if nodes[i].ParentID == "" {
+			walk(nodes[i].NodeID, 0)
+			return lines, refs, true
+		}
+	}
+	return lines, refs, false
+}
+
+func pageLines(lines []string, offset, limit int) []string {
+	if offset >= len(lines) {
+		return nil
+	}
+	end := len(lines)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return lines[offset:end]
+}
+
+// Snapshot cursors are "<generation>:<offset>" and are only valid until the
+// next snapshot replaces the ref table.
+func formatSnapshotCursor(generation, offset int) string {
+	return fmt.Sprintf("%d:%d", generation, offset)
+}
+
+func parseSnapshotCursor(cursor string) (int, int, bool) {
+	gen, off, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	g, err1 := strconv.Atoi(gen)
+	o, err2 := strconv.Atoi(off)
+	if err1 != nil || err2 != nil || g <= 0 || o < 0 {
+		return 0, 0, false
+	}
+	return g, o, true
+}
+
+const snapshotDefaultLimit = 2000
+
+// cmdSnapshot renders the accessibility tree with refs. Options:
+// "selector" (CSS or @ref) to snapshot a subtree, "interactive" to keep
+// only interactive nodes, "depth" to cap nesting, and "limit" lines per
+// page. When output is cut, "cursor" is returned; pass it back to fetch the
+// next page of the same snapshot without re-numbering refs.
+func cmdSnapshot(ctx context.Context, s *pageSession, params map[string]interface{}) (map[string]interface{}, error) {
+	limit := snapshotDefaultLimit
+	if v, ok := params["limit"].(float64); ok && v > 0 {
+		limit = int(v)
+	}
+
+	if cursor, _ := params["cursor"].(string); cursor != "" {
+		generation, offset, ok := parseSnapshotCursor(cursor)
+		if !ok {
+			return nil, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: fmt.Sprintf("invalid cursor %q", cursor)}
+		}
+		lines, total, ok := s.refs.page(generation, offset, limit)
+		if !ok {
+			return nil, &browserError{status: http.StatusConflict, code: "stale_cursor", msg: "snapshot was replaced; take a new snapshot"}
+		}
+		return snapshotPage(s, lines, generation, offset, total), nil
+	}
+
+	var opts snapshotOptions
+	opts.interactiveOnly, _ = params["interactive"].(bool)
+	if v, ok := params["depth"].(float64); ok && v > 0 {
+		opts.maxDepth = int(v)
+	}
+	if selector, _ := params["selector"].(string); selector != "" {
+		nodeID, err := resolveSelector(ctx, s, selector)
+		if err != nil {
+			return nil, err
+		}
+		opts.rootBackendID = nodeID
+	}
+
+	var tree struct {
+		Nodes []axNode `json:"nodes"`
+	}
+	if err := s.conn.Call(ctx, "Accessibility.getFullAXTree", nil, &tree); err != nil {
+		return nil, err
+	}
+
+	lines, refs, found := renderSnapshot(tree.Nodes, opts)
+	if !found {
+		return nil, &browserError{status: http.StatusNotFound, code: "element_not_found", msg: "element is not in the accessibility tree"}
+	}
+	generation := s.refs.replace(refs, lines)
+
+	result := snapshotPage(s, pageLines(lines, 0, limit), generation, 0, len(lines))
+	result["refs"] = len(refs)
+	return result, nil
+}
+
+func snapshotPage(s *pageSession, lines []string, generation, offset, total int) map[string]interface{} {
+	result := map[string]interface{}{
+		"snapshot":   strings.Join(lines, "\n"),
+		"target":     s.targetID,
+		"offset":     offset,
+		"totalLines": total,
+	}
+	if next := offset + len(lines); next < total {
+		result["cursor"] = formatSnapshotCursor(generation, next)
+		result["truncated"] = true
+	}
+	return result
+}
+
+// resolveSelector returns the backend node ID for an @eN ref, a CSS
+// selector, or an xpath= or text= selector (see parseSelector). Refs are
+// validated against the live DOM: if the node is gone or no longer has the
+// role and name it had at snapshot time, a stale_ref error is returned
+// instead of acting on the wrong element.
+func resolveSelector(ctx context.Context, s *pageSession, selector string) (int64, error) {
+	if strings.TrimSpace(selector) == "" {
+		return 0, &browserError{status: http.StatusBadRequest, code: "invalid_selector", msg: "selector required"}
+	}
+
+	if n, ok := parseRef(selector); ok {
+		return resolveRef(ctx, s, n)
+	}
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return 0, err
+	}
+	if sel.kind != selectorCSS {
+		return resolveScriptSelector(ctx, s, sel, selector)
+	}
+	selector = sel.value
+
+	var doc struct {
+		Root struct {
+			NodeID int64 `json:"nodeId"`
+		} `json:"root"`
+	}
+	if err := s.conn.Call(ctx, "DOM.getDocument", map[string]interface{}{"depth": 0}, &doc); err != nil {
+		return 0, err
+	}
+
+	var found struct {
+		NodeID int64 `json:"nodeId"`
+	}
+	if err := s.conn.Call(ctx, "DOM.querySelector", map[string]interface{}{
+		"nodeId":   doc.Root.NodeID,
+		"selector": selector,
+	}, &found); err != nil {
+		return 0, &browserError{status: http.StatusBadRequest, code: "invalid_selector", msg: err.Error()}
+	}
+	if found.NodeID == 0 {
+		return 0, &browserError{status: http.StatusNotFound, code: "element_not_found", msg: fmt.Sprintf("no element matches %q", selector)}
+	}
+
+	var described struct {
+		Node struct {
+			BackendNodeID int64 `json:"backendNodeId"`
+		} `json:"node"`
+	}
+	if err := s.conn.Call(ctx, "DOM.describeNode", map[string]interface{}{"nodeId": found.NodeID}, &described); err != nil {
+		return 0, err
+	}
+	return described.Node.BackendNodeID, nil
+}
+
+func resolveRef(ctx context.Context, s *pageSession, n int) (int64, error) {
+	entry, ok := s.refs.get(n)
+	if !ok {
+		return 0, staleRefError(n, "not in the latest snapshot")
+	}
+
+	var partial struct {
+		Nodes []axNode `json:"nodes"`
+	}
+	if err := s.conn.Call(ctx, "Accessibility.getPartialAXTree", map[string]interface{}{
+		"backendNodeId":  entry.BackendNodeID,
+		"fetchRelatives": false,
+	}, &partial); err != nil {
+		return 0, staleRefError(n, "element was removed")
+	}
+
+	for _, node := range partial.Nodes {
+		if node.BackendDOMNodeID != entry.BackendNodeID {
+			continue
+		}
+		if role := node.Role.String(); role != entry.Role {
+			return 0, staleRefError(n, fmt.Sprintf("role changed from %s to %s", entry.Role, role))
+		}
+		if name := strings.TrimSpace(node.Name.String()); name != entry.Name {
+			return 0, staleRefError(n, fmt.Sprintf("name changed from %q to %q", entry.Name, name))
+		}
+		return entry.BackendNodeID, nil
+	}
+	return 0, staleRefError(n, "element is no longer in the accessibility tree")
+}
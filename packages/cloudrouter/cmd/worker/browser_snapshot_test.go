@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"@e3", 3, true},
+		{"e12", 12, true},
+		{"ref=e7", 7, true},
+		{"#submit", 0, false},
+		{"@e0", 0, false},
+		{"@ex", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRef(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRef(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBuildSnapshotAssignsRefsWithBackendIDs(t *testing.T) {
+	nodes := []axNode{
+		{NodeID: "1", Role: &axValue{Value: "RootWebArea"}, Name: &axValue{Value: "Page"}, ChildIDs: []string{"2", "3"}, BackendDOMNodeID: 1},
+		{NodeID: "2", ParentID: "1", Role: &axValue{Value: "generic"}, ChildIDs: []string{"4"}, BackendDOMNodeID: 10},
+		{NodeID: "3", ParentID: "1", Role: &axValue{Value: "link"}, Name: &axValue{Value: "Docs"}, BackendDOMNodeID: 30},
+		{NodeID: "4", ParentID: "2", Role: &axValue{Value: "button"}, BackendDOMNodeID: 40},
+	}
+
+	text, refs := buildSnapshot(nodes)
+
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 refs, got %d: %v", len(refs), refs)
+	}
+	if refs[1].BackendNodeID != 40 || refs[1].Role != "button" {
+		t.Errorf("ref e1 = %+v, want button backend 40", refs[1])
+	}
+	if refs[2].BackendNodeID != 30 || refs[2].Name != "Docs" {
+		t.Errorf("ref e2 = %+v, want link Docs backend 30", refs[2])
+	}
+	if !strings.Contains(text, `- link "Docs" [ref=e2]`) {
+		t.Errorf("snapshot missing link line:\n%s", text)
+	}
+	if strings.Contains(text, "generic") {
+		t.Errorf("structural roles should be flattened:\n%s", text)
+	}
+}
+
+func snapshotFixture() []axNode {
+	return []axNode{
+		{NodeID: "1", Role: &axValue{Value: "RootWebArea"}, ChildIDs: []string{"2", "5"}, BackendDOMNodeID: 1},
+		{NodeID: "2", ParentID: "1", Role: &axValue{Value: "navigation"}, Name: &axValue{Value: "Main"}, ChildIDs: []string{"3", "4"}, BackendDOMNodeID: 20},
+		{NodeID: "3", ParentID: "2", Role: &axValue{Value: "link"}, Name: &axValue{Value: "Home"}, BackendDOMNodeID: 30},
+		{NodeID: "4", ParentID: "2", Role: &axValue{Value: "StaticText"}, Name: &axValue{Value: "|"}, BackendDOMNodeID: 40},
+		{NodeID: "5", ParentID: "1", Role: &axValue{Value: "form"}, Name: &axValue{Value: "Login"}, ChildIDs: []string{"6"}, BackendDOMNodeID: 50},
+		{NodeID: "6", ParentID: "5", Role: &axValue{Value: "button"}, Name: &axValue{Value: "Sign in"}, BackendDOMNodeID: 60},
+	}
+}
+
+func TestRenderSnapshotSubtree(t *testing.T) {
+	lines, refs, found := renderSnapshot(snapshotFixture(), snapshotOptions{rootBackendID: 50})
+	if !found {
+		t.Fatal("subtree root not found")
+	}
+	want := []string{`- form "Login" [ref=e1]`, `  - button "Sign in" [ref=e2]`}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("subtree snapshot:\n%s", strings.Join(lines, "\n"))
+	}
+	if refs[2].BackendNodeID != 60 {
+		t.Errorf("ref e2 = %+v", refs[2])
+	}
+
+	if _, _, found := renderSnapshot(snapshotFixture(), snapshotOptions{rootBackendID: 999}); found {
+		t.Error("expected missing root to be reported")
+	}
+}
+
+func TestRenderSnapshotInteractiveAndDepth(t *testing.T) {
+	lines, _, _ := renderSnapshot(snapshotFixture(), snapshotOptions{interactiveOnly: true})
+	want := []string{`- link "Home" [ref=e1]`, `- button "Sign in" [ref=e2]`}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("interactive snapshot:\n%s", strings.Join(lines, "\n"))
+	}
+
+	lines, _, _ = renderSnapshot(snapshotFixture(), snapshotOptions{maxDepth: 1})
+	for _, line := range lines {
+		if strings.HasPrefix(line, " ") {
+			t.Errorf("depth 1 snapshot has nested line %q", line)
+		}
+	}
+}
+
+func TestRefTablePaging(t *testing.T) {
+	table := newRefTable()
+	gen := table.replace(map[int]refEntry{}, []string{"a", "b", "c"})
+
+	lines, total, ok := table.page(gen, 1, 1)
+	if !ok || total != 3 || len(lines) != 1 || lines[0] != "b" {
+		t.Errorf("page = %v, %d, %v", lines, total, ok)
+	}
+
+	g, off, ok := parseSnapshotCursor(formatSnapshotCursor(gen, 2))
+	if !ok || g != gen || off != 2 {
+		t.Errorf("cursor round trip = %d, %d, %v", g, off, ok)
+	}
+
+	table.replace(map[int]refEntry{}, nil)
+	if _, _, ok := table.page(gen, 0, 10); ok {
+		t.Error("expected old generation to be stale")
+	}
+}
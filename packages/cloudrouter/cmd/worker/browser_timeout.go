@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultBrowserCommandTimeout bounds a /_cmux/browser command that does not set
+// "timeout". defaultBrowserMaxCommandTimeout caps what a caller may ask
+// for; override it with CMUX_BROWSER_MAX_COMMAND_TIMEOUT.
+const (
+	defaultBrowserCommandTimeout    = 30 * time.Second
+	defaultBrowserMaxCommandTimeout = 2 * time.Minute
+
+	// readyStateProbeTimeout bounds the diagnostic probe after a timeout;
+	// a page stuck in a script loop will not answer at all.
+	readyStateProbeTimeout = 2 * time.Second
+)
+
+var browserMaxCommandTimeout = loadBrowserMaxCommandTimeout()
+
+func loadBrowserMaxCommandTimeout() time.Duration {
+	if v := os.Getenv("CMUX_BROWSER_MAX_COMMAND_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultBrowserMaxCommandTimeout
+}
+
+// commandTimeout reads the optional "timeout" in milliseconds, capped at
+// browserMaxCommandTimeout. It covers queueing behind earlier commands as
+// well as the command itself.
+func commandTimeout(params map[string]interface{}) (time.Duration, error) {
+	raw, ok := params["timeout"]
+	if !ok {
+		return defaultBrowserCommandTimeout, nil
+	}
+	ms, ok := raw.(float64)
+	if !ok || ms <= 0 {
+		return 0, &browserError{status: http.StatusBadRequest, code: "invalid_params", msg: "timeout must be a positive number of milliseconds"}
+	}
+	timeout := time.Duration(ms * float64(time.Millisecond))
+	if timeout > browserMaxCommandTimeout {
+		timeout = browserMaxCommandTimeout
+	}
+	return timeout, nil
+}
+
+// commandTimeoutError reports a command that ran out of time. s is the
+// session it ran on, or nil if it timed out waiting in the queue; the
+// page's readyState is included to tell a slow load from a hung page.
+func commandTimeoutError(name string, timeout time.Duration, s *pageSession) error {
+	be := &browserError{
+		status:  http.StatusGatewayTimeout,
+		code:    "timeout",
+		msg:     fmt.Sprintf("%s timed out after %s", name, timeout),
+		details: map[string]interface{}{"timeoutMs": timeout.Milliseconds()},
+	}
+	if s == nil {
+		be.msg += " waiting for earlier commands on the target"
+		return be
+	}
+
+	state := pageReadyState(s)
+	be.details["readyState"] = state
+	be.msg += fmt.Sprintf(" (page readyState: %s)", state)
+	return be
+}
+
+// pageReadyState returns document.readyState, or "unresponsive" if the
+// page does not answer within readyStateProbeTimeout.
+func pageReadyState(s *pageSession) string {
+	ctx, cancel := context.WithTimeout(context.Background(), readyStateProbeTimeout)
+	defer cancel()
+
+	var res struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+	}
+	err := s.conn.Call(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    "document.readyState",
+		"returnByValue": true,
+	}, &res)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "unresponsive"
+	case err != nil || res.Result.Value == "":
+		return "unknown"
+	}
+	return res.Result.Value
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", map[string]interface{}{}, defaultBrowserCommandTimeout, false},
+		{"explicit", map[string]interface{}{"timeout": float64(5000)}, 5 * time.Second, false},
+		{"capped", map[string]interface{}{"timeout": float64(24 * time.Hour / time.Millisecond)}, browserMaxCommandTimeout, false},
+		{"zero", map[string]interface{}{"timeout": float64(0)}, 0, true},
+		{"string", map[string]interface{}{"timeout": "5s"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commandTimeout(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandTimeoutErrorWhileQueued(t *testing.T) {
+	err := commandTimeoutError("click", 5*time.Second, nil)
+	var be *browserError
+	if !errors.As(err, &be) || be.status != 504 || be.code != "timeout" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if be.details["timeoutMs"] != int64(5000) {
+		t.Errorf("details = %v", be.details)
+	}
+	if _, ok := be.details["readyState"]; ok {
+		t.Error("readyState should not be probed for a queued command")
+	}
+}
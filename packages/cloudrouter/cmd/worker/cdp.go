@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// cdpConn is a minimal Chrome DevTools Protocol client for a single target.
+// Calls are matched to responses by ID; events are fanned out to listeners.
+type cdpConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	nextID    int64
+	pending   map[int64]chan cdpMessage
+	listeners map[int]func(method string, params json.RawMessage)
+	nextLn    int
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type cdpMessage struct {
+	ID     int64           `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *cdpError       `json:"error,omitempty"`
+}
+
+// cdpError is a protocol-level error returned by Chrome.
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *cdpError) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("cdp error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+var errCDPClosed = errors.New("cdp connection closed")
+
+// cdpTarget is an entry from Chrome's /json/list.
+type cdpTarget struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Title                string `json:"title"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+func listCDPTargets(ctx context.Context) ([]cdpTarget, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%d/json/list", cdpPort), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Chrome CDP not available: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var targets []cdpTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("failed to decode CDP targets: %w", err)
+	}
+	return targets, nil
+}
+
+func dialCDP(ctx context.Context, wsURL string) (*cdpConn, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	ws, _, err := dialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to CDP target: %w", err)
+	}
+	ws.SetReadLimit(64 << 20)
+
+	c := &cdpConn{
+		ws:        ws,
+		pending:   make(map[int64]chan cdpMessage),
+		listeners: make(map[int]func(string, json.RawMessage)),
+		closed:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *cdpConn) readLoop() {
+	for {
+		var msg cdpMessage
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		if msg.ID != 0 {
+			c.mu.Lock()
+			ch, ok := c.pending[msg.ID]
+			delete(c.pending, msg.ID)
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+			continue
+		}
+
+		if msg.Method != "" {
+			c.mu.Lock()
+			listeners := make([]func(string, json.RawMessage), 0, len(c.listeners))
+			for _, fn := range c.listeners {
+				listeners = append(listeners, fn)
+			}
+			c.mu.Unlock()
+			for _, fn := range listeners {
+				fn(msg.Method, msg.Params)
+			}
+		}
+	}
+}
+
+func (c *cdpConn) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.closeErr = err
+		close(c.closed)
+		_ = c.ws.Close()
+	})
+}
+
+// Close terminates the connection. Pending calls fail with errCDPClosed.
+func (c *cdpConn) Close() {
+	c.shutdown(errCDPClosed)
+}
+
+// Alive reports whether the underlying websocket is still open.
+func (c *cdpConn) Alive() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// On registers an event listener and returns a func that removes it.
+// Listeners run on the read loop and must not block or call back into
+// the connection synchronously.
+func (c *cdpConn) On(fn func(method string, params json.RawMessage)) func() {
+	c.mu.Lock()
+	id := c.nextLn
+	c.nextLn++
+	c.listeners[id] = fn
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		delete(c.listeners, id)
+		c.mu.Unlock()
+	}
+}
+
+// Call sends a CDP command and decodes its result into out (if non-nil).
+func (c *cdpConn) Call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan cdpMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	req := map[string]interface{}{"id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+
+	c.writeMu.Lock()
+	err := c.ws.WriteJSON(req)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.shutdown(err)
+		return fmt.Errorf("%s: %w", method, errCDPClosed)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return fmt.Errorf("%s: %w", method, errCDPClosed)
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		if out != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, out); err != nil {
+				return fmt.Errorf("%s: failed to decode result: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+func logCDPError(method string, err error) {
+	if err != nil && !errors.Is(err, errCDPClosed) {
+		log.Printf("[browser] %s failed: %v", method, err)
+	}
+}
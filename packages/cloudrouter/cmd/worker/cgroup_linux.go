@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startInCgroup makes cmd's process start inside the cgroup open as fd
+// (clone3 CLONE_INTO_CGROUP), rather than joining it after exec.
+func startInCgroup(cmd *exec.Cmd, fd int) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// startInCgroup fails outside Linux, which has no cgroups.
+func startInCgroup(cmd *exec.Cmd, fd int) error {
+	return errors.New("cgroup limits need Linux")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Browser drivers. Only one party drives the browser at a time so a human
+// on VNC and an agent on CDP don't fight over the mouse.
+const (
+	driverNone  = ""
+	driverAgent = "agent"
+	driverHuman = "human"
+)
+
+// browserControl tracks who currently drives the browser. While a human
+// holds control the agent is paused: agent-driven browser endpoints return
+// 409 until the human gives control back.
+type browserControl struct {
+	mu     sync.Mutex
+	driver string
+	since  time.Time
+	reason string
+}
+
+var control = &browserControl{}
+
+// errBrowserPaused is returned to agent callers while a human drives.
+type errBrowserPaused struct {
+	since time.Time
+}
+
+func (e *errBrowserPaused) Error() string {
+	return fmt.Sprintf("browser is under human control since %s; wait for control to be given back", e.since.Format(time.RFC3339))
+}
+
+func (c *browserControl) stateLocked() map[string]interface{} {
+	state := map[string]interface{}{
+		"driver": c.driver,
+		"paused": c.driver == driverHuman,
+	}
+	if !c.since.IsZero() {
+		state["since"] = c.since.UnixMilli()
+	}
+	if c.reason != "" {
+		state["reason"] = c.reason
+	}
+	return state
+}
+
+func (c *browserControl) state() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateLocked()
+}
+
+// take makes actor the driver. A human always preempts the agent; the agent
+// cannot take control away from a human.
+func (c *browserControl) take(actor, reason string) (map[string]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if actor == driverAgent && c.driver == driverHuman {
+		return nil, &errBrowserPaused{since: c.since}
+	}
+	if c.driver == actor {
+		return c.stateLocked(), nil
+	}
+
+	c.driver = actor
+	c.since = time.Now()
+	c.reason = reason
+	state := c.stateLocked()
+	events.publish("browser.control", state)
+	return state, nil
+}
+
+// give releases control held by actor. Releasing control you don't hold is
+// a no-op so callers can release unconditionally on exit.
+func (c *browserControl) give(actor string) map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.driver != actor {
+		return c.stateLocked()
+	}
+
+	c.driver = driverNone
+	c.since = time.Now()
+	c.reason = ""
+	state := c.stateLocked()
+	events.publish("browser.control", state)
+	return state
+}
+
+// checkAgent returns an error if the agent must not drive the browser now.
+func (c *browserControl) checkAgent() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.driver == driverHuman {
+		return &errBrowserPaused{since: c.since}
+	}
+	return nil
+}
+
+// requireAgentControl writes a 409 and returns false when a human drives.
+func requireAgentControl(w http.ResponseWriter) bool {
+	if err := control.checkAgent(); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		sendJSON(w, map[string]interface{}{
+			"error":   err.Error(),
+			"control": control.state(),
+		})
+		return false
+	}
+	return true
+}
+
+// isAgentBrowserCommand reports whether an /exec command drives the browser
+// through the agent-browser CLI.
+func isAgentBrowserCommand(command string) bool {
+	return strings.HasPrefix(strings.TrimSpace(command), "agent-browser")
+}
+
+func handleBrowserControl(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	if r.Method == http.MethodGet {
+		sendJSON(w, control.state())
+		return
+	}
+
+	action, _ := body["action"].(string)
+	actor, _ := body["actor"].(string)
+	reason, _ := body["reason"].(string)
+	if actor == "" {
+		actor = driverHuman
+	}
+	if actor != driverHuman && actor != driverAgent {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": "actor must be human or agent"})
+		return
+	}
+
+	switch action {
+	case "", "status":
+		sendJSON(w, control.state())
+	case "take":
+		state, err := control.take(actor, reason)
+		if err != nil {
+			w.WriteHeader(http.StatusConflict)
+			sendJSON(w, map[string]interface{}{"error": err.Error(), "control": control.state()})
+			return
+		}
+		sendJSON(w, state)
+	case "give":
+		sendJSON(w, control.give(actor))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": "action must be take, give, or status"})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDiskGuardInterval is how often the guard checks free space.
+	// Override with CMUX_DISK_GUARD_INTERVAL.
+	defaultDiskGuardInterval = 30 * time.Second
+	// diskCleanupCooldown stops the guard from re-running cleanup on every
+	// tick while space stays low.
+	diskCleanupCooldown = 10 * time.Minute
+	// diskCleanupHookTimeout bounds each cleanup hook.
+	diskCleanupHookTimeout = 5 * time.Minute
+	// buildCacheMaxDepth bounds how deep build-caches searches the workspace.
+	buildCacheMaxDepth = 4
+	// defaultDiskCleanupDir holds extra cleanup executables. Override with
+	// CMUX_DISK_CLEANUP_DIR.
+	defaultDiskCleanupDir = "/etc/cmux/disk-cleanup.d"
+)
+
+// buildCacheDirs are removed wherever build-caches finds them in the
+// workspace, along with node_modules/.cache. All are rebuilt on demand.
+var buildCacheDirs = []string{
+	filepath.Join(".next", "cache"),
+	".turbo",
+	".parcel-cache",
+}
+
+// diskUsage is free space on the filesystem holding Path.
+type diskUsage struct {
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"freeBytes"`
+	TotalBytes uint64 `json:"totalBytes"`
+}
+
+// statDisk is overridden in tests.
+var statDisk = statfs
+
+// diskGuardPaths are the filesystems agents fill up.
+func diskGuardPaths() []string {
+	return []string{workspaceDir, os.TempDir()}
+}
+
+// cleanupHook frees space when the disk runs low.
+type cleanupHook struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// cleanupRun is the outcome of one hook in the last cleanup.
+type cleanupRun struct {
+	Hook       string `json:"hook"`
+	FreedBytes int64  `json:"freedBytes"` // Change in workspace free space, approximate
+	Error      string `json:"error,omitempty"`
+}
+
+type cleanupReport struct {
+	At         time.Time    `json:"at"`
+	Trigger    string       `json:"trigger"`
+	Hooks      []cleanupRun `json:"hooks"`
+	FreedBytes int64        `json:"freedBytes"`
+}
+
+// diskGuard watches free space and runs cleanup hooks when any watched
+// filesystem drops below the /healthz minimum. /healthz stays degraded
+// until space recovers, so callers see why agents fail with ENOSPC.
+type diskGuard struct {
+	interval time.Duration
+	cooldown time.Duration
+	hooks    func() []cleanupHook
+
+	mu          sync.Mutex
+	lastCleanup *cleanupReport
+}
+
+var disks = &diskGuard{
+	interval: durationEnv("CMUX_DISK_GUARD_INTERVAL", defaultDiskGuardInterval),
+	cooldown: diskCleanupCooldown,
+	hooks:    configuredCleanupHooks,
+}
+
+// run checks free space every interval until ctx is done.
+func (g *diskGuard) run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		g.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs cleanup if a watched path is low on space and the last
+// cleanup is older than the cooldown. It returns the new report, if any.
+func (g *diskGuard) check(ctx context.Context) *cleanupReport {
+	minFree := minFreeDiskBytes()
+	var low []string
+	for _, path := range diskGuardPaths() {
+		usage, err := statDisk(path)
+		if err != nil {
+			continue
+		}
+		if usage.FreeBytes < minFree {
+			low = append(low, fmt.Sprintf("%s has %d bytes free", path, usage.FreeBytes))
+		}
+	}
+	if len(low) == 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	recent := g.lastCleanup != nil && time.Since(g.lastCleanup.At) < g.cooldown
+	g.mu.Unlock()
+	if recent {
+		return nil
+	}
+
+	trigger := strings.Join(low, ", ")
+	log.Printf("[worker] Disk low (%s, want %d), running cleanup hooks", trigger, minFree)
+	report := &cleanupReport{At: time.Now(), Trigger: trigger}
+	for _, hook := range g.hooks() {
+		before, _ := statDisk(workspaceDir)
+		hookCtx, cancel := context.WithTimeout(ctx, diskCleanupHookTimeout)
+		err := hook.run(hookCtx)
+		cancel()
+		after, _ := statDisk(workspaceDir)
+
+		result := cleanupRun{Hook: hook.name, FreedBytes: int64(after.FreeBytes) - int64(before.FreeBytes)}
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("[worker] Disk cleanup hook %s failed: %v", hook.name, err)
+		}
+		report.Hooks = append(report.Hooks, result)
+		report.FreedBytes += result.FreedBytes
+	}
+	log.Printf("[worker] Disk cleanup freed about %d bytes", report.FreedBytes)
+
+	g.mu.Lock()
+	g.lastCleanup = report
+	g.mu.Unlock()
+	return report
+}
+
+func (g *diskGuard) lastReport() *cleanupReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastCleanup
+}
+
+// builtinCleanupHooks are selected by name with CMUX_DISK_CLEANUP_HOOKS.
+func builtinCleanupHooks() map[string]cleanupHook {
+	return map[string]cleanupHook{
+		"npm-cache":    removeDirsHook("npm-cache", filepath.Join(homeDir, ".npm", "_cacache")),
+		"bun-cache":    removeDirsHook("bun-cache", filepath.Join(homeDir, ".bun", "install", "cache")),
+		"pnpm-store":   removeDirsHook("pnpm-store", filepath.Join(homeDir, ".local", "share", "pnpm", "store"), filepath.Join(homeDir, ".cache", "pnpm")),
+		"yarn-cache":   removeDirsHook("yarn-cache", filepath.Join(homeDir, ".cache", "yarn"), filepath.Join(homeDir, ".yarn", "berry", "cache")),
+		"build-caches": {name: "build-caches", run: func(ctx context.Context) error { return removeBuildCaches(ctx, workspaceDir) }},
+	}
+}
+
+// configuredCleanupHooks returns the built-in hooks named in
+// CMUX_DISK_CLEANUP_HOOKS (default all, "none" for none), then every
+// executable in CMUX_DISK_CLEANUP_DIR in name order.
+func configuredCleanupHooks() []cleanupHook {
+	builtins := builtinCleanupHooks()
+	names := make([]string, 0, len(builtins))
+	if v := strings.TrimSpace(os.Getenv("CMUX_DISK_CLEANUP_HOOKS")); v != "" {
+		if v != "none" {
+			for _, name := range strings.Split(v, ",") {
+				names = append(names, strings.TrimSpace(name))
+			}
+		}
+	} else {
+		for name := range builtins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var hooks []cleanupHook
+	for _, name := range names {
+		hook, ok := builtins[name]
+		if !ok {
+			log.Printf("[worker] Unknown disk cleanup hook %q", name)
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+
+	dir := os.Getenv("CMUX_DISK_CLEANUP_DIR")
+	if dir == "" {
+		dir = defaultDiskCleanupDir
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		hooks = append(hooks, cleanupHook{name: entry.Name(), run: func(ctx context.Context) error {
+			cmd := exec.CommandContext(ctx, path)
+			cmd.Dir = workspaceDir
+			cmd.Env = append(os.Environ(), "CMUX_WORKSPACE="+workspaceDir)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+			}
+			return nil
+		}})
+	}
+	return hooks
+}
+
+func removeDirsHook(name string, dirs ...string) cleanupHook {
+	return cleanupHook{name: name, run: func(ctx context.Context) error {
+		for _, dir := range dirs {
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// removeBuildCaches deletes buildCacheDirs and node_modules/.cache found
+// within buildCacheMaxDepth levels of root. It does not descend into .git
+// or node_modules.
+func removeBuildCaches(ctx context.Context, root string) error {
+	var targets []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if d.Name() == ".git" || strings.Count(rel, string(filepath.Separator)) >= buildCacheMaxDepth {
+			return filepath.SkipDir
+		}
+		for _, cache := range buildCacheDirs {
+			if rel == cache || strings.HasSuffix(rel, string(filepath.Separator)+cache) {
+				targets = append(targets, path)
+				return filepath.SkipDir
+			}
+		}
+		if d.Name() == "node_modules" {
+			if info, err := os.Stat(filepath.Join(path, ".cache")); err == nil && info.IsDir() {
+				targets = append(targets, filepath.Join(path, ".cache"))
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskGuardRunsHooksOnceUntilCooldown(t *testing.T) {
+	savedStat, savedWorkspace := statDisk, workspaceDir
+	t.Cleanup(func() { statDisk, workspaceDir = savedStat, savedWorkspace })
+	workspaceDir = t.TempDir()
+	t.Setenv("CMUX_HEALTH_MIN_FREE_BYTES", "1000")
+
+	free := uint64(10)
+	statDisk = func(path string) (diskUsage, error) {
+		return diskUsage{Path: path, FreeBytes: free, TotalBytes: 5000}, nil
+	}
+	runs := 0
+	guard := &diskGuard{
+		interval: time.Minute,
+		cooldown: time.Hour,
+		hooks: func() []cleanupHook {
+			return []cleanupHook{{name: "fake", run: func(context.Context) error {
+				runs++
+				free += 500
+				return nil
+			}}}
+		},
+	}
+
+	report := guard.check(context.Background())
+	if report == nil || runs != 1 {
+		t.Fatalf("expected one cleanup run, got report=%v runs=%d", report, runs)
+	}
+	if report.FreedBytes != 500 || len(report.Hooks) != 1 || report.Hooks[0].Hook != "fake" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if guard.check(context.Background()) != nil || runs != 1 {
+		t.Errorf("cleanup re-ran within cooldown (runs=%d)", runs)
+	}
+	if guard.lastReport() != report {
+		t.Error("lastReport should return the first cleanup")
+	}
+}
+
+func TestDiskGuardSkipsWhenSpaceIsFine(t *testing.T) {
+	saved := statDisk
+	t.Cleanup(func() { statDisk = saved })
+	t.Setenv("CMUX_HEALTH_MIN_FREE_BYTES", "1000")
+	statDisk = func(path string) (diskUsage, error) {
+		return diskUsage{Path: path, FreeBytes: 2000, TotalBytes: 5000}, nil
+	}
+	guard := &diskGuard{cooldown: time.Hour, hooks: func() []cleanupHook {
+		t.Fatal("hooks should not be loaded")
+		return nil
+	}}
+	if guard.check(context.Background()) != nil {
+		t.Error("expected no cleanup")
+	}
+}
+
+func TestRemoveBuildCaches(t *testing.T) {
+	root := t.TempDir()
+	keep := []string{
+		filepath.Join(root, "app", "node_modules", "react"),
+		filepath.Join(root, "app", "src"),
+	}
+	remove := []string{
+		filepath.Join(root, "app", "node_modules", ".cache"),
+		filepath.Join(root, "app", ".next", "cache"),
+		filepath.Join(root, ".turbo"),
+	}
+	for _, dir := range append(keep, remove...) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := removeBuildCaches(context.Background(), root); err != nil {
+		t.Fatalf("removeBuildCaches: %v", err)
+	}
+	for _, dir := range keep {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s should remain: %v", dir, err)
+		}
+	}
+	for _, dir := range remove {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", dir)
+		}
+	}
+}
+
+func TestConfiguredCleanupHooks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "10-prune"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a hook"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CMUX_DISK_CLEANUP_DIR", dir)
+
+	t.Setenv("CMUX_DISK_CLEANUP_HOOKS", "none")
+	hooks := configuredCleanupHooks()
+	if len(hooks) != 1 || hooks[0].name != "10-prune" {
+		t.Fatalf("expected only the script hook, got %v", hookNames(hooks))
+	}
+
+	t.Setenv("CMUX_DISK_CLEANUP_HOOKS", "npm-cache, bogus")
+	hooks = configuredCleanupHooks()
+	if names := hookNames(hooks); len(names) != 2 || names[0] != "npm-cache" || names[1] != "10-prune" {
+		t.Errorf("unexpected hooks %v", names)
+	}
+}
+
+func hookNames(hooks []cleanupHook) []string {
+	names := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		names = append(names, hook.name)
+	}
+	return names
+}
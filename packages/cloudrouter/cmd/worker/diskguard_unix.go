@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func statfs(path string) (diskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return diskUsage{}, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	return diskUsage{
+		Path:       path,
+		FreeBytes:  uint64(st.Bavail) * uint64(st.Bsize),
+		TotalBytes: uint64(st.Blocks) * uint64(st.Bsize),
+	}, nil
+}
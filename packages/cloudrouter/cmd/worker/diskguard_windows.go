@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "errors"
+
+// statfs is not implemented on Windows, so the disk guard never runs
+// cleanup there.
+func statfs(path string) (diskUsage, error) {
+	return diskUsage{}, errors.New("disk usage is not supported on windows")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// workerEvent is a single notification pushed to /_cmux/events subscribers.
+type workerEvent struct {
+	Type string                 `json:"type"`
+	Time int64                  `json:"time"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// eventHub fans out worker events to Server-Sent Events subscribers. Slow
+// subscribers drop events rather than block publishers.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan workerEvent]struct{}
+}
+
+var events = &eventHub{subs: make(map[chan workerEvent]struct{})}
+
+func (h *eventHub) subscribe() chan workerEvent {
+	ch := make(chan workerEvent, 32)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan workerEvent) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+func (h *eventHub) publish(eventType string, data map[string]interface{}) {
+	ev := workerEvent{Type: eventType, Time: time.Now().UnixMilli(), Data: data}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("[worker] dropping %s event for slow subscriber", eventType)
+		}
+	}
+}
+
+// handleEvents streams worker events as Server-Sent Events until the client
+// disconnects.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": "streaming not supported"})
+		return
+	}
+
+	ch := events.subscribe()
+	defer events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev := <-ch:
+			payload, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultExecTimeout = 60 * time.Second
+
+// execRequest is the body of POST /exec.
+type execRequest struct {
+	command string
+	env     map[string]string
+	cwd     string
+	timeout time.Duration
+	stdin   string
+	stream  bool
+	sandbox *execSandbox
+	confirm bool // Run despite an exec policy "ask" rule
+
+	// cpuExceeded is set when the sandbox's cpu_seconds killed the command.
+	cpuExceeded atomic.Bool
+}
+
+// parseExecRequest validates an /exec body. "env" is a map of extra
+// variables, "cwd" is resolved against the workspace when relative,
+// "timeout" is in milliseconds, "stdin" is written to the command's stdin,
+// "stream": true switches the response to NDJSON chunks, and "sandbox"
+// restricts the command (see parseExecSandbox). "confirm": true approves a
+// command the exec policy asks about.
+func parseExecRequest(body map[string]interface{}) (*execRequest, error) {
+	req := &execRequest{timeout: defaultExecTimeout, cwd: workspaceDir}
+
+	req.command, _ = body["command"].(string)
+	if req.command == "" {
+		return nil, errors.New("command required")
+	}
+
+	if t, ok := body["timeout"].(float64); ok && t > 0 {
+		req.timeout = time.Duration(t) * time.Millisecond
+	}
+
+	if raw, ok := body["env"]; ok && raw != nil {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("env must be an object of strings")
+		}
+		req.env = make(map[string]string, len(m))
+		for k, v := range m {
+			s, ok := v.(string)
+			if !ok || k == "" || strings.ContainsAny(k, "=\x00") {
+				return nil, fmt.Errorf("invalid env entry %q", k)
+			}
+			req.env[k] = s
+		}
+	}
+
+	if cwd, _ := body["cwd"].(string); cwd != "" {
+		if !filepath.IsAbs(cwd) {
+			cwd = filepath.Join(workspaceDir, cwd)
+		}
+		info, err := os.Stat(cwd)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("cwd is not a directory: %s", cwd)
+		}
+		req.cwd = cwd
+	}
+
+	req.stdin, _ = body["stdin"].(string)
+	req.stream, _ = body["stream"].(bool)
+	req.confirm, _ = body["confirm"].(bool)
+
+	if raw, ok := body["sandbox"]; ok && raw != nil {
+		sandbox, err := parseExecSandbox(raw)
+		if err != nil {
+			return nil, err
+		}
+		req.sandbox = sandbox
+	}
+	return req, nil
+}
+
+// cmd builds the process. It runs in its own process group so a
+// timeout kills background children too, not just bash. A sandboxed
+// command is started inside its cgroup, so it is limited from its first
+// instruction and the sandbox never needs write access to cgroupfs.
+func (req *execRequest) cmd(ctx context.Context) (*exec.Cmd, error) {
+	argv := []string{"bash", "-c", req.command}
+	var cgroupFD *os.File
+	if req.sandbox != nil {
+		cgroup, err := req.sandbox.prepareCgroup()
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: %w", err)
+		}
+		if cgroup != "" {
+			if cgroupFD, err = os.Open(cgroup); err != nil {
+				return nil, fmt.Errorf("sandbox: open cgroup: %w", err)
+			}
+			if req.sandbox.cpuSeconds > 0 {
+				limit := time.Duration(req.sandbox.cpuSeconds) * time.Second
+				go watchCPUTime(ctx, cgroup, limit, func() { req.cpuExceeded.Store(true) })
+			}
+		}
+		argv = req.sandbox.argv(req.command)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = req.cwd
+	cmd.Env = append(os.Environ(), "FORCE_COLOR=0")
+	keys := make([]string, 0, len(req.env))
+	for k := range req.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, k+"="+req.env[k])
+	}
+	if req.stdin != "" {
+		cmd.Stdin = strings.NewReader(req.stdin)
+	}
+	setProcessGroup(cmd)
+	if cgroupFD != nil {
+		if err := startInCgroup(cmd, int(cgroupFD.Fd())); err != nil {
+			cgroupFD.Close()
+			return nil, fmt.Errorf("sandbox: %w", err)
+		}
+		// The fd only has to live until the child is cloned into the group.
+		go func() {
+			<-ctx.Done()
+			cgroupFD.Close()
+		}()
+	}
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 2 * time.Second
+	return cmd, nil
+}
+
+// exitStatus returns the exit code for a finished command, or an error if
+// it could not be started at all.
+func exitStatus(cmd *exec.Cmd, err error) (int, error) {
+	if cmd.ProcessState != nil {
+		return cmd.ProcessState.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+func handleExec(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	req, err := parseExecRequest(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if isAgentBrowserCommand(req.command) && !requireAgentControl(w) {
+		return
+	}
+	if !enforceExecPolicy(w, req) {
+		return
+	}
+	activity.touch(activityExec)
+
+	ctx, cancel := context.WithTimeout(r.Context(), req.timeout)
+	defer cancel()
+
+	if req.stream {
+		streamExec(ctx, w, req)
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd, err := req.cmd(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	exitCode, err := exitStatus(cmd, cmd.Run())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result := map[string]interface{}{
+		"stdout":    strings.TrimSpace(stdout.String()),
+		"stderr":    strings.TrimSpace(stderr.String()),
+		"exit_code": exitCode,
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result["timed_out"] = true
+	}
+	if req.cpuExceeded.Load() {
+		result["cpu_limit_exceeded"] = true
+	}
+	sendJSON(w, result)
+}
+
+// ndjsonWriter serializes chunks from concurrent stdout/stderr copies onto
+// one response, flushing after each line.
+type ndjsonWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (n *ndjsonWriter) send(v map[string]interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := json.NewEncoder(n.w).Encode(v); err != nil {
+		return
+	}
+	n.flusher.Flush()
+}
+
+// streamChunk is an io.Writer that forwards each write as one NDJSON chunk.
+type streamChunk struct {
+	out    *ndjsonWriter
+	stream string
+}
+
+func (c *streamChunk) Write(p []byte) (int, error) {
+	c.out.send(map[string]interface{}{"type": c.stream, "data": string(p)})
+	return len(p), nil
+}
+
+// streamExec runs the command and writes {"type":"stdout"|"stderr","data":...}
+// lines as output arrives, then a final {"type":"exit","exit_code":N}.
+func streamExec(ctx context.Context, w http.ResponseWriter, req *execRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": "streaming not supported"})
+		return
+	}
+
+	cmd, err := req.cmd(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	out := &ndjsonWriter{w: w, flusher: flusher}
+
+	cmd.Stdout = &streamChunk{out: out, stream: "stdout"}
+	cmd.Stderr = &streamChunk{out: out, stream: "stderr"}
+
+	exitCode, err := exitStatus(cmd, cmd.Run())
+	if err != nil {
+		out.send(map[string]interface{}{"type": "error", "error": err.Error()})
+		return
+	}
+	final := map[string]interface{}{"type": "exit", "exit_code": exitCode}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		final["timed_out"] = true
+	}
+	if req.cpuExceeded.Load() {
+		final["cpu_limit_exceeded"] = true
+	}
+	out.send(final)
+}
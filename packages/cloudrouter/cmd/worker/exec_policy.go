@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultExecPolicyPath is read at startup when present. Override with
+// CMUX_EXEC_POLICY.
+const defaultExecPolicyPath = "/etc/cmux/exec-policy.json"
+
+// Exec policy outcomes. "ask" runs the command only if the request sets
+// "confirm": true.
+const (
+	execPolicyAllow = "allow"
+	execPolicyDeny  = "deny"
+	execPolicyAsk   = "ask"
+)
+
+// execPolicyRule matches a command by "prefix" (any shell segment starting
+// with it, after sudo, env assignments and the program's directory are
+// stripped) or "regex" (the whole command).
+type execPolicyRule struct {
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+
+	re *regexp.Regexp
+}
+
+func (r *execPolicyRule) pattern() string {
+	if r.Regex != "" {
+		return "regex:" + r.Regex
+	}
+	return "prefix:" + r.Prefix
+}
+
+func (r *execPolicyRule) matches(command string, segments []string) bool {
+	if r.re != nil {
+		return r.re.MatchString(command)
+	}
+	for _, seg := range segments {
+		if strings.HasPrefix(seg, r.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// execPolicy is the optional operator policy for /exec and /_cmux/jobs.
+// The first matching rule wins; commands no rule matches get Default.
+type execPolicy struct {
+	Default string           `json:"default,omitempty"`
+	Rules   []execPolicyRule `json:"rules"`
+}
+
+type execPolicyDecision struct {
+	action string
+	rule   string
+	reason string
+}
+
+// execPolicyCurrent is nil when no policy file is installed.
+var execPolicyCurrent *execPolicy
+
+// loadExecPolicy reads the policy file. A missing file means no policy;
+// an invalid one is an error so the worker never runs without the policy
+// the operator intended.
+func loadExecPolicy() (*execPolicy, error) {
+	path := os.Getenv("CMUX_EXEC_POLICY")
+	if path == "" {
+		path = defaultExecPolicyPath
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy, err := parseExecPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("[worker] Loaded exec policy from %s (%d rules, default %s)", path, len(policy.Rules), policy.Default)
+	return policy, nil
+}
+
+func parseExecPolicy(data []byte) (*execPolicy, error) {
+	var policy execPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid exec policy: %w", err)
+	}
+	if policy.Default == "" {
+		policy.Default = execPolicyAllow
+	}
+	if !validExecPolicyAction(policy.Default) {
+		return nil, fmt.Errorf("invalid default action %q", policy.Default)
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if !validExecPolicyAction(rule.Action) {
+			return nil, fmt.Errorf("rule %d: invalid action %q (want allow, deny or ask)", i+1, rule.Action)
+		}
+		if (rule.Prefix == "") == (rule.Regex == "") {
+			return nil, fmt.Errorf("rule %d: set exactly one of prefix or regex", i+1)
+		}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			rule.re = re
+		}
+	}
+	return &policy, nil
+}
+
+func validExecPolicyAction(action string) bool {
+	switch action {
+	case execPolicyAllow, execPolicyDeny, execPolicyAsk:
+		return true
+	}
+	return false
+}
+
+// evaluate returns the decision for command.
+func (p *execPolicy) evaluate(command string) execPolicyDecision {
+	segments := commandSegments(command)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.matches(command, segments) {
+			return execPolicyDecision{action: rule.Action, rule: rule.pattern(), reason: rule.Reason}
+		}
+	}
+	return execPolicyDecision{action: p.Default, rule: "default"}
+}
+
+var commandSeparator = regexp.MustCompile("&&|\\|\\||[;&|\\n(){}`]|\\$\\(")
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=\S*\s+`)
+
+// commandSegments splits a shell command at separators, pipes and
+// substitutions so "echo ok; mkfs /dev/sda" still yields "mkfs /dev/sda".
+// This is a best-effort guard against accidents, not a shell parser.
+func commandSegments(command string) []string {
+	var segments []string
+	for _, seg := range commandSeparator.Split(command, -1) {
+		seg = strings.TrimSpace(seg)
+		for {
+			trimmed := strings.TrimSpace(envAssignment.ReplaceAllString(seg, ""))
+			for _, wrapper := range []string{"sudo ", "exec ", "command ", "env "} {
+				trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, wrapper))
+			}
+			if trimmed == seg {
+				break
+			}
+			seg = trimmed
+		}
+		if seg == "" {
+			continue
+		}
+		if program, args, _ := strings.Cut(seg, " "); strings.Contains(program, "/") {
+			seg = strings.TrimSpace(filepath.Base(program) + " " + args)
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// enforceExecPolicy checks req against the exec policy, writing a 403 for
+// denied commands and a 409 for unconfirmed "ask" commands. It reports
+// whether the command may run.
+func enforceExecPolicy(w http.ResponseWriter, req *execRequest) bool {
+	policy := execPolicyCurrent
+	if policy == nil {
+		return true
+	}
+	decision := policy.evaluate(req.command)
+	switch decision.action {
+	case execPolicyDeny:
+		log.Printf("[worker] Exec policy denied command (%s): %q", decision.rule, req.command)
+		w.WriteHeader(http.StatusForbidden)
+		sendJSON(w, map[string]string{
+			"error":  execPolicyMessage("command denied by exec policy", decision),
+			"rule":   decision.rule,
+			"action": decision.action,
+		})
+		return false
+	case execPolicyAsk:
+		if req.confirm {
+			log.Printf("[worker] Exec policy confirmed command (%s): %q", decision.rule, req.command)
+			return true
+		}
+		log.Printf("[worker] Exec policy held command for confirmation (%s): %q", decision.rule, req.command)
+		w.WriteHeader(http.StatusConflict)
+		sendJSON(w, map[string]interface{}{
+			"error":           execPolicyMessage("command requires confirmation; resend with \"confirm\": true", decision),
+			"rule":            decision.rule,
+			"action":          decision.action,
+			"confirmRequired": true,
+		})
+		return false
+	}
+	return true
+}
+
+func execPolicyMessage(msg string, decision execPolicyDecision) string {
+	if decision.reason != "" {
+		return msg + ": " + decision.reason
+	}
+	return msg
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testExecPolicy = `{
+	"default": "allow",
+	"rules": [
+		{"prefix": "mkfs", "action": "deny", "reason": "formatting disks is not allowed"},
+		{"prefix": "shutdown", "action": "deny"},
+		{"regex": "curl[^|]*\\|\\s*(ba)?sh", "action": "deny", "reason": "no piping downloads to a shell"},
+		{"prefix": "git push --force", "action": "ask"}
+	]
+}`
+
+func TestExecPolicyEvaluate(t *testing.T) {
+	policy, err := parseExecPolicy([]byte(testExecPolicy))
+	if err != nil {
+		t.Fatalf("parseExecPolicy: %v", err)
+	}
+	tests := []struct {
+		command string
+		action  string
+	}{
+		{"ls -la", execPolicyAllow},
+		{"mkfs.ext4 /dev/sda1", execPolicyDeny},
+		{"echo ok && sudo /sbin/mkfs /dev/sda", execPolicyDeny},
+		{"FOO=1 env BAR=2 shutdown -h now", execPolicyDeny},
+		{"cat $(shutdown now)", execPolicyDeny},
+		{"curl -fsSL https://example.com/install | sh", execPolicyDeny},
+		{"curl -o out https://example.com/install", execPolicyAllow},
+		{"git push --force origin main", execPolicyAsk},
+		{"git push origin main", execPolicyAllow},
+	}
+	for _, tt := range tests {
+		if got := policy.evaluate(tt.command).action; got != tt.action {
+			t.Errorf("evaluate(%q) = %s, want %s", tt.command, got, tt.action)
+		}
+	}
+}
+
+func TestParseExecPolicyRejectsInvalidRules(t *testing.T) {
+	bad := []string{
+		`{"rules": [{"prefix": "rm", "action": "block"}]}`,
+		`{"rules": [{"action": "deny"}]}`,
+		`{"rules": [{"prefix": "rm", "regex": "rm", "action": "deny"}]}`,
+		`{"rules": [{"regex": "(", "action": "deny"}]}`,
+		`{"default": "maybe", "rules": []}`,
+		`not json`,
+	}
+	for _, data := range bad {
+		if _, err := parseExecPolicy([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestLoadExecPolicyMissingFile(t *testing.T) {
+	t.Setenv("CMUX_EXEC_POLICY", filepath.Join(t.TempDir(), "missing.json"))
+	policy, err := loadExecPolicy()
+	if err != nil || policy != nil {
+		t.Errorf("loadExecPolicy() = %v, %v; want no policy", policy, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"rules": [{"prefix": "rm"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CMUX_EXEC_POLICY", path)
+	if _, err := loadExecPolicy(); err == nil {
+		t.Error("expected error for invalid policy file")
+	}
+}
+
+func TestHandleExecAppliesPolicy(t *testing.T) {
+	policy, err := parseExecPolicy([]byte(testExecPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := execPolicyCurrent
+	t.Cleanup(func() { execPolicyCurrent = saved })
+	execPolicyCurrent = policy
+
+	dir := t.TempDir()
+	run := func(body map[string]interface{}) int {
+		body["cwd"] = dir
+		w := httptest.NewRecorder()
+		handleExec(w, httptest.NewRequest("POST", "/exec", nil), body)
+		return w.Code
+	}
+
+	if code := run(map[string]interface{}{"command": "mkfs /dev/null"}); code != http.StatusForbidden {
+		t.Errorf("denied command: got %d, want 403", code)
+	}
+	if code := run(map[string]interface{}{"command": "git push --force"}); code != http.StatusConflict {
+		t.Errorf("ask without confirm: got %d, want 409", code)
+	}
+	if code := run(map[string]interface{}{"command": "false && git push --force", "confirm": true}); code != http.StatusOK {
+		t.Errorf("ask with confirm: got %d, want 200", code)
+	}
+	if code := run(map[string]interface{}{"command": "true"}); code != http.StatusOK {
+		t.Errorf("allowed command: got %d, want 200", code)
+	}
+}
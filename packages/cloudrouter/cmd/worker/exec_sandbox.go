@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// execSandbox restricts what an /exec command can touch. The command runs
+// under unshare in fresh user, mount and PID namespaces (plus a network
+// namespace with network off) and execs with every capability dropped and
+// no_new_privs set, so it cannot undo the read-only remounts, enter the
+// host's namespaces or move itself out of its cgroup. Memory, process
+// count and total CPU time are capped by a cgroup v2 group the process is
+// started in. Setup failures abort the command rather than running it
+// unrestricted.
+type execSandbox struct {
+	readOnly   bool     // remount every filesystem read-only except writable
+	writable   []string // absolute paths left writable when readOnly
+	noNetwork  bool     // empty network namespace (loopback only)
+	cpuSeconds int      // CPU time for the whole command, from cgroup cpu.stat
+	memoryMB   int      // cgroup memory.max
+	maxProcs   int      // cgroup pids.max
+}
+
+// execSandboxProfiles are the named presets accepted by "sandbox".
+var execSandboxProfiles = map[string]execSandbox{
+	"read-only":   {readOnly: true},
+	"network-off": {noNetwork: true},
+	"strict":      {readOnly: true, noNetwork: true, cpuSeconds: 600, memoryMB: 4096, maxProcs: 512},
+}
+
+// execCgroupRoot holds one cgroup per limited command.
+var execCgroupRoot = "/sys/fs/cgroup/cmux-exec"
+
+// parseExecSandbox reads the "sandbox" field of an /exec body: either a
+// profile name or an object with an optional "profile" plus overrides
+// ("read_only", "network", "writable", "cpu_seconds", "memory_mb",
+// "max_procs"). Relative writable paths resolve against the workspace.
+func parseExecSandbox(raw interface{}) (*execSandbox, error) {
+	if name, ok := raw.(string); ok {
+		raw = map[string]interface{}{"profile": name}
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("sandbox must be a profile name or an object")
+	}
+
+	var s execSandbox
+	if name, ok := m["profile"]; ok {
+		profile, ok := execSandboxProfiles[fmt.Sprint(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown sandbox profile %q (valid: %s)", fmt.Sprint(name), strings.Join(execSandboxProfileNames(), ", "))
+		}
+		s = profile
+	}
+
+	if v, ok := m["read_only"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("sandbox.read_only must be a boolean")
+		}
+		s.readOnly = b
+	}
+	if v, ok := m["network"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("sandbox.network must be a boolean")
+		}
+		s.noNetwork = !b
+	}
+
+	for key, dst := range map[string]*int{"cpu_seconds": &s.cpuSeconds, "memory_mb": &s.memoryMB, "max_procs": &s.maxProcs} {
+		v, ok := m[key]
+		if !ok {
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok || n < 0 || n != float64(int(n)) {
+			return nil, fmt.Errorf("sandbox.%s must be a non-negative integer", key)
+		}
+		*dst = int(n)
+	}
+
+	if v, ok := m["writable"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.New("sandbox.writable must be an array of paths")
+		}
+		for _, item := range list {
+			p, ok := item.(string)
+			if !ok || p == "" {
+				return nil, errors.New("sandbox.writable must be an array of paths")
+			}
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(workspaceDir, p)
+			}
+			p = filepath.Clean(p)
+			if _, err := os.Stat(p); err != nil {
+				return nil, fmt.Errorf("sandbox.writable path does not exist: %s", p)
+			}
+			s.writable = append(s.writable, p)
+		}
+		if len(s.writable) > 0 && !s.readOnly {
+			return nil, errors.New("sandbox.writable requires read_only")
+		}
+	}
+
+	return &s, nil
+}
+
+func execSandboxProfileNames() []string {
+	names := make([]string, 0, len(execSandboxProfiles))
+	for name := range execSandboxProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *execSandbox) usesCgroup() bool {
+	return s.memoryMB > 0 || s.maxProcs > 0 || s.cpuSeconds > 0
+}
+
+// argv returns the process to run in place of `bash -c command`. The
+// command and writable paths are passed as positional arguments so no
+// request value is ever interpolated into the script. Root inside the user
+// namespace keeps the capabilities the setup script needs; the command
+// itself gets none.
+func (s *execSandbox) argv(command string) []string {
+	argv := []string{"unshare", "--user", "--map-root-user", "--mount", "--propagation", "private",
+		"--pid", "--mount-proc", "--fork", "--kill-child"}
+	if s.noNetwork {
+		argv = append(argv, "--net")
+	}
+	argv = append(argv, "--", "bash", "-c", s.script(), "cmux-sandbox", command)
+	return append(argv, s.writable...)
+}
+
+// sandboxRemountFunc defines remount_ro, which makes one mount read-only.
+// Inside a user namespace the mount's nosuid/nodev/noexec and atime flags
+// are locked, so they are carried over or the remount fails.
+const sandboxRemountFunc = `remount_ro() { local flags=ro; for o in ${2//,/ }; do case "$o" in nosuid|nodev|noexec|noatime|nodiratime|relatime|strictatime) flags=$flags,$o ;; esac; done; mount -o "remount,bind,$flags" "$1"; }`
+
+// sandboxCgroupScript remounts every cgroup filesystem read-only so the
+// command cannot write another cgroup's cgroup.procs to escape its limits.
+const sandboxCgroupScript = `mounts=$(cat /proc/self/mounts)
+while read -r _ mp fstype opts _; do
+  case "$fstype" in cgroup|cgroup2) remount_ro "$(printf '%b' "$mp")" "$opts" ;; esac
+done <<< "$mounts"`
+
+// sandboxReadOnlyScript binds the writable paths onto themselves, remounts
+// every other real filesystem read-only, and gives the command private /tmp
+// and /dev/shm unless a writable path lives there. "$@" holds the writable
+// paths.
+const sandboxReadOnlyScript = `writable() { local m=$1; shift; for w in "$@"; do if [ "$m" = "$w" ] || [ "${m#"$w"/}" != "$m" ]; then return 0; fi; done; return 1; }
+for w in "$@"; do mount --bind "$w" "$w"; done
+mounts=$(cat /proc/self/mounts)
+while read -r _ mp fstype opts _; do
+  mp=$(printf '%b' "$mp")
+  case "$fstype" in proc|sysfs|devtmpfs|devpts|mqueue|cgroup|cgroup2|securityfs|debugfs|tracefs|pstore|bpf|fusectl|configfs|hugetlbfs|binfmt_misc|autofs) continue ;; esac
+  if writable "$mp" "$@"; then continue; fi
+  remount_ro "$mp" "$opts"
+done <<< "$mounts"
+holds() { local t=$1; shift; for w in "$@"; do if [ "${w#"$t"/}" != "$w" ]; then return 0; fi; done; return 1; }
+for t in /tmp /dev/shm; do
+  if [ -d "$t" ] && ! writable "$t" "$@" && ! holds "$t" "$@"; then mount -t tmpfs -o nosuid,nodev,mode=1777 tmpfs "$t"; fi
+done`
+
+// script is the bash setup that runs inside the namespaces before the
+// command replaces it. The command is exec'd with an empty bounding set
+// and no_new_privs, so neither it nor anything it runs regains the
+// capabilities the setup used.
+func (s *execSandbox) script() string {
+	lines := []string{"set -e", "cmd=$1", "shift", sandboxRemountFunc}
+	if s.noNetwork {
+		lines = append(lines, "ip link set lo up 2>/dev/null || true")
+	}
+	if s.readOnly {
+		lines = append(lines, sandboxReadOnlyScript)
+	}
+	lines = append(lines, sandboxCgroupScript,
+		`exec setpriv --inh-caps=-all --ambient-caps=-all --bounding-set=-all --no-new-privs -- bash -c "$cmd"`)
+	return strings.Join(lines, "\n")
+}
+
+// prepareCgroup creates a cgroup v2 group with the memory and process
+// limits, returning "" when no cgroup limit is set. The command is started
+// directly in it (see execRequest.cmd), never moved there by the sandbox.
+// Groups left behind by finished commands are empty, so they are swept here
+// rather than tracked.
+func (s *execSandbox) prepareCgroup() (string, error) {
+	if !s.usesCgroup() {
+		return "", nil
+	}
+	parent := filepath.Dir(execCgroupRoot)
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.controllers")); err != nil {
+		return "", errors.New("memory_mb, max_procs and cpu_seconds require cgroup v2")
+	}
+	if err := os.MkdirAll(execCgroupRoot, 0755); err != nil {
+		return "", fmt.Errorf("create cgroup: %w", err)
+	}
+	// cpu.stat needs no controller; memory and pids limits do.
+	var controllers []string
+	if s.memoryMB > 0 {
+		controllers = append(controllers, "+memory")
+	}
+	if s.maxProcs > 0 {
+		controllers = append(controllers, "+pids")
+	}
+	if len(controllers) > 0 {
+		for _, dir := range []string{parent, execCgroupRoot} {
+			if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(strings.Join(controllers, " ")), 0644); err != nil {
+				return "", fmt.Errorf("enable cgroup controllers: %w", err)
+			}
+		}
+	}
+
+	if stale, err := os.ReadDir(execCgroupRoot); err == nil {
+		for _, e := range stale {
+			if e.IsDir() {
+				os.Remove(filepath.Join(execCgroupRoot, e.Name())) // fails while in use
+			}
+		}
+	}
+
+	b := make([]byte, 6)
+	rand.Read(b)
+	dir := filepath.Join(execCgroupRoot, hex.EncodeToString(b))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("create cgroup: %w", err)
+	}
+
+	limits := map[string]string{}
+	if s.memoryMB > 0 {
+		limits["memory.max"] = strconv.Itoa(s.memoryMB * 1024 * 1024)
+		limits["memory.swap.max"] = "0"
+	}
+	if s.maxProcs > 0 {
+		limits["pids.max"] = strconv.Itoa(s.maxProcs)
+	}
+	for file, value := range limits {
+		err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+		if err != nil && !(file == "memory.swap.max" && os.IsNotExist(err)) {
+			os.Remove(dir)
+			return "", fmt.Errorf("set %s: %w", file, err)
+		}
+	}
+	return dir, nil
+}
+
+// cpuUsage reads the CPU time used by every process ever in cgroup.
+func cpuUsage(cgroup string) (time.Duration, error) {
+	data, err := os.ReadFile(filepath.Join(cgroup, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			usec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(usec) * time.Microsecond, nil
+		}
+	}
+	return 0, errors.New("cpu.stat has no usage_usec")
+}
+
+// cpuPollInterval is how often watchCPUTime samples cpu.stat.
+var cpuPollInterval = 250 * time.Millisecond
+
+// watchCPUTime kills everything in cgroup once the processes in it have
+// used limit of CPU time between them, calling exceeded first. Unlike
+// RLIMIT_CPU this counts the whole command, not each process. It returns
+// when ctx is done.
+func watchCPUTime(ctx context.Context, cgroup string, limit time.Duration, exceeded func()) {
+	ticker := time.NewTicker(cpuPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if used, err := cpuUsage(cgroup); err == nil && used >= limit {
+			exceeded()
+			killCgroup(cgroup)
+			return
+		}
+	}
+}
+
+// killCgroup SIGKILLs every process in cgroup, through cgroup.kill where
+// the kernel has it.
+func killCgroup(cgroup string) {
+	if os.WriteFile(filepath.Join(cgroup, "cgroup.kill"), []byte("1"), 0644) == nil {
+		return
+	}
+	data, _ := os.ReadFile(filepath.Join(cgroup, "cgroup.procs"))
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			signalProcess(pid, syscall.SIGKILL)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecSandbox(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := parseExecSandbox("strict")
+	if err != nil {
+		t.Fatalf("strict: %v", err)
+	}
+	if !s.readOnly || !s.noNetwork || s.memoryMB == 0 || s.cpuSeconds == 0 {
+		t.Errorf("strict = %+v", s)
+	}
+
+	s, err = parseExecSandbox(map[string]interface{}{
+		"profile":     "strict",
+		"network":     true,
+		"writable":    []interface{}{dir},
+		"cpu_seconds": float64(5),
+		"memory_mb":   float64(0),
+	})
+	if err != nil {
+		t.Fatalf("overrides: %v", err)
+	}
+	if s.noNetwork || s.cpuSeconds != 5 || s.memoryMB != 0 || s.maxProcs != 512 || len(s.writable) != 1 || s.writable[0] != dir {
+		t.Errorf("overrides = %+v", s)
+	}
+
+	bad := []interface{}{
+		"sandboxed",
+		float64(1),
+		map[string]interface{}{"read_only": "yes"},
+		map[string]interface{}{"cpu_seconds": float64(-1)},
+		map[string]interface{}{"memory_mb": float64(1.5)},
+		map[string]interface{}{"read_only": true, "writable": []interface{}{dir + "/missing"}},
+		map[string]interface{}{"writable": []interface{}{dir}},
+	}
+	for _, raw := range bad {
+		if _, err := parseExecSandbox(raw); err == nil {
+			t.Errorf("expected error for %v", raw)
+		}
+	}
+}
+
+func TestParseExecRequestSandbox(t *testing.T) {
+	req, err := parseExecRequest(map[string]interface{}{"command": "ls", "sandbox": "network-off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.sandbox == nil || !req.sandbox.noNetwork {
+		t.Errorf("sandbox = %+v", req.sandbox)
+	}
+	if _, err := parseExecRequest(map[string]interface{}{"command": "ls", "sandbox": "nope"}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func runSandboxedExec(t *testing.T, command string, env map[string]interface{}, sandbox interface{}) map[string]interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handleExec(w, httptest.NewRequest("POST", "/exec", nil), map[string]interface{}{
+		"command": command,
+		"cwd":     t.TempDir(),
+		"env":     env,
+		"sandbox": sandbox,
+	})
+	var result map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v (%s)", err, w.Body.String())
+	}
+	return result
+}
+
+// requireNamespaces skips tests that need root and a working unshare.
+func requireNamespaces(t *testing.T) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("sandbox namespaces need root")
+	}
+	if err := exec.Command("unshare", "--user", "--map-root-user", "--mount", "--pid", "--mount-proc", "--net", "--fork", "true").Run(); err != nil {
+		t.Skipf("unshare unavailable: %v", err)
+	}
+}
+
+func TestHandleExecSandboxReadOnly(t *testing.T) {
+	requireNamespaces(t)
+	writable, other := t.TempDir(), t.TempDir()
+
+	result := runSandboxedExec(t,
+		`touch "$W/ok" && if touch "$O/no" 2>/dev/null; then echo escaped; fi; echo done`,
+		map[string]interface{}{"W": writable, "O": other},
+		map[string]interface{}{"profile": "read-only", "writable": []interface{}{writable}},
+	)
+	if result["exit_code"] != float64(0) || result["stdout"] != "done" {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	if _, err := os.Stat(filepath.Join(writable, "ok")); err != nil {
+		t.Errorf("writable path was not writable: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(other, "no")); !os.IsNotExist(err) {
+		t.Errorf("write escaped the read-only sandbox: %v", err)
+	}
+}
+
+func TestHandleExecSandboxNetworkOff(t *testing.T) {
+	requireNamespaces(t)
+
+	result := runSandboxedExec(t, `tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' '`, nil, "network-off")
+	if result["exit_code"] != float64(0) || result["stdout"] != "lo" {
+		t.Errorf("expected only loopback, got %v", result)
+	}
+}
+
+// useTestCgroupRoot points execCgroupRoot at a fresh group under the
+// cgroup v2 hierarchy and returns the hierarchy's mount point, skipping
+// the test without a writable one.
+func useTestCgroupRoot(t *testing.T) string {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("cgroups need root")
+	}
+	mountinfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		t.Skip(err)
+	}
+	var mount string
+	for _, line := range strings.Split(string(mountinfo), "\n") {
+		if fields := strings.Fields(line); len(fields) > 8 && fields[len(fields)-3] == "cgroup2" {
+			mount = fields[4]
+			break
+		}
+	}
+	if mount == "" {
+		t.Skip("no cgroup v2 hierarchy")
+	}
+
+	saved := execCgroupRoot
+	execCgroupRoot = filepath.Join(mount, fmt.Sprintf("cmux-exec-test-%d", time.Now().UnixNano()))
+	if err := os.Mkdir(execCgroupRoot, 0755); err != nil {
+		execCgroupRoot = saved
+		t.Skipf("cgroup v2 not writable: %v", err)
+	}
+	root := execCgroupRoot
+	t.Cleanup(func() {
+		execCgroupRoot = saved
+		// Killed processes can take a moment to leave their group.
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			entries, _ := os.ReadDir(root)
+			for _, e := range entries {
+				if e.IsDir() {
+					os.Remove(filepath.Join(root, e.Name()))
+				}
+			}
+			if os.Remove(root) == nil {
+				return
+			}
+		}
+		t.Logf("left test cgroup %s behind", root)
+	})
+	return mount
+}
+
+func TestHandleExecSandboxCPULimitCoversWholeCommand(t *testing.T) {
+	requireNamespaces(t)
+	useTestCgroupRoot(t)
+
+	// Two busy children each stay under the limit on their own, but not
+	// together; RLIMIT_CPU would never fire here.
+	start := time.Now()
+	result := runSandboxedExec(t, `(while :; do :; done) & (while :; do :; done) & wait`, nil,
+		map[string]interface{}{"cpu_seconds": float64(1)})
+	if result["cpu_limit_exceeded"] != true || result["timed_out"] == true {
+		t.Fatalf("expected the CPU limit to kill the command, got %v", result)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("CPU limit took %s to fire", elapsed)
+	}
+}
+
+func TestWatchCPUTime(t *testing.T) {
+	saved := cpuPollInterval
+	t.Cleanup(func() { cpuPollInterval = saved })
+	cpuPollInterval = time.Millisecond
+
+	dir := t.TempDir()
+	stat := filepath.Join(dir, "cpu.stat")
+	os.WriteFile(stat, []byte("usage_usec 500000\nuser_usec 400000\n"), 0644)
+
+	fired := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go watchCPUTime(ctx, dir, time.Second, func() { close(fired) })
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-fired:
+		t.Fatal("fired below the limit")
+	default:
+	}
+	os.WriteFile(stat, []byte("usage_usec 1000000\nuser_usec 900000\n"), 0644)
+	select {
+	case <-fired:
+	case <-ctx.Done():
+		t.Fatal("did not fire at the limit")
+	}
+}
+
+func TestHandleExecSandboxCannotEscape(t *testing.T) {
+	requireNamespaces(t)
+	mount := useTestCgroupRoot(t)
+	other := t.TempDir()
+
+	// The command is root inside its user namespace, so each of these would
+	// work if it still had capabilities or a writable cgroupfs.
+	script := `if mount -o remount,rw / 2>/dev/null; then echo remounted /; fi
+if mount -o remount,rw "$O" 2>/dev/null; then echo remounted "$O"; fi
+if touch "$O/escaped" 2>/dev/null; then echo wrote "$O"; fi
+if echo $$ > "$HOSTCG/cgroup.procs" 2>/dev/null; then echo left cgroup; fi
+if mkdir "$HOSTCG/cmux-escape" 2>/dev/null; then echo made cgroup; fi
+if nsenter -t 1 -m -n true 2>/dev/null; then echo entered pid 1; fi
+grep -q cmux-exec-test /proc/self/cgroup || echo "not in the sandbox cgroup: $(cat /proc/self/cgroup)"
+echo done`
+	result := runSandboxedExec(t, script,
+		map[string]interface{}{"O": other, "HOSTCG": mount},
+		map[string]interface{}{"profile": "read-only", "cpu_seconds": float64(600)},
+	)
+	if result["exit_code"] != float64(0) || result["stdout"] != "done" {
+		t.Fatalf("sandbox escape: %v", result)
+	}
+	if _, err := os.Stat(filepath.Join(mount, "cmux-escape")); err == nil {
+		os.Remove(filepath.Join(mount, "cmux-escape"))
+		t.Error("sandboxed command created a host cgroup")
+	}
+}
+
+func TestPrepareCgroupRequiresCgroupV2(t *testing.T) {
+	saved := execCgroupRoot
+	t.Cleanup(func() { execCgroupRoot = saved })
+	execCgroupRoot = filepath.Join(t.TempDir(), "cmux-exec")
+
+	s := &execSandbox{memoryMB: 256}
+	if _, err := s.prepareCgroup(); err == nil || !strings.Contains(err.Error(), "cgroup v2") {
+		t.Errorf("expected cgroup v2 error, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handleExec(w, httptest.NewRequest("POST", "/exec", nil), map[string]interface{}{
+		"command": "echo ran",
+		"sandbox": map[string]interface{}{"memory_mb": float64(256)},
+	})
+	if w.Code != 500 || strings.Contains(w.Body.String(), "ran") {
+		t.Errorf("command should not run without its limits: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseExecRequestValidates(t *testing.T) {
+	dir := t.TempDir()
+	req, err := parseExecRequest(map[string]interface{}{
+		"command": "pwd",
+		"cwd":     dir,
+		"env":     map[string]interface{}{"FOO": "bar"},
+		"timeout": float64(1500),
+		"stream":  true,
+	})
+	if err != nil {
+		t.Fatalf("parseExecRequest: %v", err)
+	}
+	if req.cwd != dir || req.env["FOO"] != "bar" || req.timeout.Milliseconds() != 1500 || !req.stream {
+		t.Errorf("unexpected request: %+v", req)
+	}
+
+	bad := []map[string]interface{}{
+		{},
+		{"command": "ls", "cwd": dir + "/missing"},
+		{"command": "ls", "env": map[string]interface{}{"A=B": "x"}},
+		{"command": "ls", "env": map[string]interface{}{"N": float64(1)}},
+		{"command": "ls", "env": "FOO=bar"},
+	}
+	for _, body := range bad {
+		if _, err := parseExecRequest(body); err == nil {
+			t.Errorf("expected error for %v", body)
+		}
+	}
+}
+
+func TestHandleExecBuffered(t *testing.T) {
+	dir := t.TempDir()
+	w := httptest.NewRecorder()
+	handleExec(w, httptest.NewRequest("POST", "/exec", nil), map[string]interface{}{
+		"command": `read line; echo "$line $GREETING $(basename "$PWD")"; echo oops >&2; exit 3`,
+		"cwd":     dir,
+		"env":     map[string]interface{}{"GREETING": "hello"},
+		"stdin":   "input\n",
+	})
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v (%s)", err, w.Body.String())
+	}
+	want := "input hello " + dir[strings.LastIndex(dir, "/")+1:]
+	if result["stdout"] != want || result["stderr"] != "oops" || result["exit_code"] != float64(3) {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestHandleExecStreamTimeout(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleExec(w, httptest.NewRequest("POST", "/exec", nil), map[string]interface{}{
+		"command": "echo started; sleep 5",
+		"cwd":     t.TempDir(),
+		"timeout": float64(200),
+		"stream":  true,
+	})
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("content type = %q", ct)
+	}
+	var chunks []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		var chunk map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("bad ndjson line %q: %v", scanner.Text(), err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("expected output and exit chunks, got %v", chunks)
+	}
+	if chunks[0]["type"] != "stdout" || chunks[0]["data"] != "started\n" {
+		t.Errorf("first chunk = %v", chunks[0])
+	}
+	last := chunks[len(chunks)-1]
+	if last["type"] != "exit" || last["timed_out"] != true {
+		t.Errorf("last chunk = %v", last)
+	}
+}
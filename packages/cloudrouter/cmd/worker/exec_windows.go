@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on Windows; stopping a command only stops
+// the command itself.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup kills pid. Windows has no POSIX process groups or
+// signals, so every sig terminates the process.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	return signalProcess(pid, sig)
+}
+
+// signalProcess kills pid whatever sig is.
+func signalProcess(pid int, sig syscall.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTailLines is how many existing lines are sent before following,
+	// matching tail(1).
+	defaultTailLines = 10
+	// maxTailLineBytes splits longer lines so one runaway line cannot grow
+	// the pending buffer without bound.
+	maxTailLineBytes = 64 * 1024
+	// tailPollInterval is how often a followed file is checked for growth,
+	// truncation, and rotation.
+	tailPollInterval = 250 * time.Millisecond
+	// tailKeepAlive keeps idle follows open through proxies.
+	tailKeepAlive = 15 * time.Second
+)
+
+// tailStream writes tail events as NDJSON lines or, when the client accepts
+// text/event-stream, as Server-Sent Events.
+type tailStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	sse     bool
+}
+
+func (t *tailStream) send(v map[string]interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if t.sse {
+		_, err = fmt.Fprintf(t.w, "event: %s\ndata: %s\n\n", v["type"], payload)
+	} else {
+		_, err = fmt.Fprintf(t.w, "%s\n", payload)
+	}
+	if err == nil {
+		t.flusher.Flush()
+	}
+	return err
+}
+
+func (t *tailStream) keepAlive() error {
+	if t.sse {
+		if _, err := fmt.Fprint(t.w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		t.flusher.Flush()
+		return nil
+	}
+	return t.send(map[string]interface{}{"type": "keepalive"})
+}
+
+// fileTailer reads complete lines appended to a file, reopening it when it
+// is rotated (replaced by a new file at the same path) or truncated.
+type fileTailer struct {
+	path    string
+	f       *os.File
+	info    os.FileInfo
+	offset  int64
+	pending []byte // Partial line awaiting its newline
+}
+
+func openFileTailer(path string, lines int) (*fileTailer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	offset, err := lastLinesOffset(f, info.Size(), lines)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileTailer{path: path, f: f, info: info, offset: offset}, nil
+}
+
+func (t *fileTailer) Close() {
+	if t.f != nil {
+		t.f.Close()
+	}
+}
+
+// lastLinesOffset returns where the last n lines of a file of the given size
+// start, scanning backwards in chunks.
+func lastLinesOffset(r io.ReaderAt, size int64, n int) (int64, error) {
+	if n <= 0 {
+		return size, nil
+	}
+	// A trailing newline ends the last line rather than starting an empty one.
+	end := size
+	if end > 0 {
+		last := make([]byte, 1)
+		if _, err := r.ReadAt(last, end-1); err != nil && err != io.EOF {
+			return 0, err
+		}
+		if last[0] == '\n' {
+			end--
+		}
+	}
+
+	const chunk = 8 * 1024
+	buf := make([]byte, chunk)
+	count := 0
+	for end > 0 {
+		start := end - chunk
+		if start < 0 {
+			start = 0
+		}
+		b := buf[:end-start]
+		if _, err := r.ReadAt(b, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(b) - 1; i >= 0; i-- {
+			if b[i] == '\n' {
+				if count++; count == n {
+					return start + int64(i) + 1, nil
+				}
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// read returns the complete lines appended since the last call.
+func (t *fileTailer) read() ([]string, error) {
+	if _, err := t.f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(t.f)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(data))
+	t.pending = append(t.pending, data...)
+
+	var lines []string
+	for {
+		i := bytes.IndexByte(t.pending, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, strings.TrimSuffix(string(t.pending[:i]), "\r"))
+		t.pending = t.pending[i+1:]
+	}
+	for len(t.pending) >= maxTailLineBytes {
+		lines = append(lines, string(t.pending[:maxTailLineBytes]))
+		t.pending = t.pending[maxTailLineBytes:]
+	}
+	t.pending = append([]byte(nil), t.pending...)
+	return lines, nil
+}
+
+// check detects rotation and truncation. It returns "rotated" after
+// switching to the new file at path, along with the old file's unterminated
+// last line, or "truncated" after rewinding. While a rotated file has not
+// been recreated yet, the old one keeps being read.
+func (t *fileTailer) check() (string, []string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, nil
+		}
+		return "", nil, err
+	}
+	if !os.SameFile(info, t.info) {
+		f, err := os.Open(t.path)
+		if err != nil {
+			return "", nil, nil
+		}
+		var partial []string
+		if len(t.pending) > 0 {
+			partial = []string{string(t.pending)}
+		}
+		t.f.Close()
+		t.f, t.info, t.offset, t.pending = f, info, 0, nil
+		return "rotated", partial, nil
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+		t.pending = nil
+		return "truncated", nil, nil
+	}
+	return "", nil, nil
+}
+
+// handleFileTail streams a file's last lines and, with follow=1, lines
+// appended afterwards until the client disconnects:
+//
+//	GET /_cmux/files/tail?path=/var/log/app.log&lines=10&follow=1
+//
+// Events are {"type":"line","data":...}, {"type":"rotated"} and
+// {"type":"truncated"}; without follow the stream ends with {"type":"eof"}.
+// Relative paths resolve against the workspace directory.
+func handleFileTail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		sendJSON(w, map[string]string{"error": "method not allowed"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": "streaming not supported"})
+		return
+	}
+
+	q := r.URL.Query()
+	path := q.Get("path")
+	if path == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		sendJSON(w, map[string]string{"error": "path required"})
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspaceDir, path)
+	}
+	lines := defaultTailLines
+	if v := q.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			sendJSON(w, map[string]string{"error": "lines must be a non-negative integer"})
+			return
+		}
+		lines = n
+	}
+	follow := q.Get("follow") == "1" || q.Get("follow") == "true"
+
+	tailer, err := openFileTailer(path, lines)
+	if err != nil {
+		status := http.StatusBadRequest
+		if os.IsNotExist(err) {
+			status = http.StatusNotFound
+		} else if os.IsPermission(err) {
+			status = http.StatusForbidden
+		}
+		w.WriteHeader(status)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	defer tailer.Close()
+
+	out := &tailStream{w: w, flusher: flusher, sse: strings.Contains(r.Header.Get("Accept"), "text/event-stream")}
+	if out.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sendLines := func(lines []string) error {
+		for _, line := range lines {
+			if err := out.send(map[string]interface{}{"type": "line", "data": line}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	initial, err := tailer.read()
+	if err == nil {
+		err = sendLines(initial)
+	}
+	if err != nil {
+		out.send(map[string]interface{}{"type": "error", "error": err.Error()})
+		return
+	}
+	if !follow {
+		if len(tailer.pending) > 0 {
+			sendLines([]string{string(tailer.pending)})
+		}
+		out.send(map[string]interface{}{"type": "eof"})
+		return
+	}
+
+	poll := time.NewTicker(tailPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(tailKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if out.keepAlive() != nil {
+				return
+			}
+		case <-poll.C:
+			// Drain the current file before checking for rotation so lines
+			// written just before the rename are not lost.
+			lines, err := tailer.read()
+			if err == nil {
+				err = sendLines(lines)
+			}
+			if err != nil {
+				out.send(map[string]interface{}{"type": "error", "error": err.Error()})
+				return
+			}
+			event, partial, err := tailer.check()
+			if err != nil {
+				out.send(map[string]interface{}{"type": "error", "error": err.Error()})
+				return
+			}
+			if sendLines(partial) != nil {
+				return
+			}
+			if event != "" && out.send(map[string]interface{}{"type": event}) != nil {
+				return
+			}
+		}
+	}
+}
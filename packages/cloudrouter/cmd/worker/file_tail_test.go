@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastLinesOffset(t *testing.T) {
+	cases := []struct {
+		content string
+		n       int
+		want    string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\nc\n", 10, "a\nb\nc\n"},
+		{"a\nb\nc\n", 0, ""},
+		{"", 3, ""},
+		{strings.Repeat("x\n", 10000) + "last\n", 1, "last\n"},
+	}
+	for _, tc := range cases {
+		r := strings.NewReader(tc.content)
+		off, err := lastLinesOffset(r, int64(len(tc.content)), tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tc.content[off:]; got != tc.want {
+			t.Errorf("last %d lines of %.20q = %q, want %q", tc.n, tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestFileTailNoFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("one\ntwo\nthree\npartial"), 0644)
+
+	w := httptest.NewRecorder()
+	handleFileTail(w, httptest.NewRequest("GET", "/_cmux/files/tail?lines=2&path="+url.QueryEscape(path), nil))
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var ev map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		got = append(got, ev["type"].(string)+":"+stringValue(ev["data"]))
+	}
+	want := []string{"line:three", "line:partial", "eof:"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	handleFileTail(w, httptest.NewRequest("GET", "/_cmux/files/tail?path="+url.QueryEscape(path+".missing"), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing file status = %d", w.Code)
+	}
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func TestFileTailFollowRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	os.WriteFile(path, []byte("old\n"), 0644)
+
+	srv := httptest.NewServer(http.HandlerFunc(handleFileTail))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "?follow=1&path=" + url.QueryEscape(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var ev map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &ev) == nil && ev["type"] != "keepalive" {
+				events <- ev["type"].(string) + ":" + stringValue(ev["data"])
+			}
+		}
+		close(events)
+	}()
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("event = %q, want %q", got, want)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	expect("line:old")
+	appendFile(t, path, "appended\n")
+	expect("line:appended")
+
+	// Rotate: rename away, write a final line to the old file, recreate.
+	os.Rename(path, path+".1")
+	appendFile(t, path+".1", "before rotate\n")
+	os.WriteFile(path, []byte("fresh\n"), 0644)
+	expect("line:before rotate")
+	expect("rotated:")
+	expect("line:fresh")
+
+	os.WriteFile(path, []byte("x\n"), 0644)
+	expect("truncated:")
+	expect("line:x")
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString(data)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthProbeTimeout bounds each dependency probe in /healthz.
+const healthProbeTimeout = 2 * time.Second
+
+// defaultMinFreeDiskBytes is the workspace free space below which the disk
+// probe fails. Override with CMUX_HEALTH_MIN_FREE_BYTES.
+const defaultMinFreeDiskBytes = 1 << 30
+
+var workerStartedAt = time.Now()
+
+// browserAgentRunnerCandidates are checked in order; CMUX_BROWSER_AGENT_RUNNER
+// takes precedence when set.
+var browserAgentRunnerCandidates = []string{
+	"/usr/local/lib/cmux/browser-agent-runner.js",
+	"/opt/cmux/browser-agent-runner.js",
+	filepath.Join(homeDir, ".cmux", "browser-agent-runner.js"),
+}
+
+// healthCheck is the result of one dependency probe.
+type healthCheck struct {
+	Status    string                 `json:"status"` // ok or fail
+	LatencyMs int64                  `json:"latencyMs"`
+	Error     string                 `json:"error,omitempty"`
+	Detail    map[string]interface{} `json:"detail,omitempty"`
+}
+
+type healthProbe func(ctx context.Context) (map[string]interface{}, error)
+
+// healthProbes are the dependencies reported by /healthz, keyed by check name.
+var healthProbes = map[string]healthProbe{
+	"chromeCdp":          probeChromeCDP,
+	"workspace":          probeWorkspaceWritable,
+	"disk":               probeDiskFree,
+	"browserAgentRunner": probeBrowserAgentRunner,
+}
+
+// handleHealthz reports worker liveness plus each dependency probe. The
+// worker answering at all means the process is up; a failed dependency makes
+// the overall status "degraded" and the response 503, so callers can tell
+// "worker down" (no response) from "worker up, Chrome dead".
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	checks := runHealthProbes(r.Context(), healthProbes)
+	status := "ok"
+	for _, c := range checks {
+		if c.Status != "ok" {
+			status = "degraded"
+			break
+		}
+	}
+	checks["worker"] = healthCheck{Status: "ok"}
+
+	if status != "ok" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":   status,
+		"provider": "e2b",
+		"uptimeMs": time.Since(workerStartedAt).Milliseconds(),
+		"checks":   checks,
+	})
+}
+
+// runHealthProbes runs probes concurrently, each with its own timeout.
+func runHealthProbes(ctx context.Context, probes map[string]healthProbe) map[string]healthCheck {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		checks = make(map[string]healthCheck, len(probes)+1)
+	)
+	for name, probe := range probes {
+		wg.Add(1)
+		go func(name string, probe healthProbe) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := probe(probeCtx)
+			check := healthCheck{Status: "ok", LatencyMs: time.Since(start).Milliseconds(), Detail: detail}
+			if err != nil {
+				check.Status = "fail"
+				check.Error = err.Error()
+			}
+
+			mu.Lock()
+			checks[name] = check
+			mu.Unlock()
+		}(name, probe)
+	}
+	wg.Wait()
+	return checks
+}
+
+func probeChromeCDP(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%d/json/version", cdpPort), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Chrome CDP not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Chrome CDP returned %s", resp.Status)
+	}
+
+	var version struct {
+		Browser              string `json:"Browser"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, fmt.Errorf("failed to decode CDP version: %w", err)
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return nil, errors.New("Chrome CDP reported no debugger URL")
+	}
+	return map[string]interface{}{"browser": version.Browser, "port": cdpPort}, nil
+}
+
+// probeWorkspaceWritable creates and removes a scratch file in the workspace.
+func probeWorkspaceWritable(ctx context.Context) (map[string]interface{}, error) {
+	detail := map[string]interface{}{"path": workspaceDir}
+	f, err := os.CreateTemp(workspaceDir, ".cmux-healthz-*")
+	if err != nil {
+		return detail, fmt.Errorf("workspace not writable: %w", err)
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+		return detail, fmt.Errorf("workspace not writable: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return detail, fmt.Errorf("workspace not writable: %w", err)
+	}
+	return detail, nil
+}
+
+// probeDiskFree checks every disk guard path, keeping the workspace's
+// numbers at the top level, and reports the guard's last cleanup.
+func probeDiskFree(ctx context.Context) (map[string]interface{}, error) {
+	minFree := minFreeDiskBytes()
+	detail := map[string]interface{}{"minFreeBytes": minFree}
+	if report := disks.lastReport(); report != nil {
+		detail["lastCleanup"] = report
+	}
+
+	var usages []diskUsage
+	var low []string
+	for _, path := range diskGuardPaths() {
+		usage, err := statDisk(path)
+		if err != nil {
+			return detail, err
+		}
+		usages = append(usages, usage)
+		if usage.FreeBytes < minFree {
+			low = append(low, fmt.Sprintf("%s has only %d bytes free", path, usage.FreeBytes))
+		}
+	}
+	detail["path"] = usages[0].Path
+	detail["freeBytes"] = usages[0].FreeBytes
+	detail["totalBytes"] = usages[0].TotalBytes
+	detail["paths"] = usages
+
+	if len(low) > 0 {
+		return detail, fmt.Errorf("%s, want at least %d; writes may fail with ENOSPC", strings.Join(low, ", "), minFree)
+	}
+	return detail, nil
+}
+
+func minFreeDiskBytes() uint64 {
+	if v := os.Getenv("CMUX_HEALTH_MIN_FREE_BYTES"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultMinFreeDiskBytes
+}
+
+func probeBrowserAgentRunner(ctx context.Context) (map[string]interface{}, error) {
+	candidates := browserAgentRunnerCandidates
+	if v := os.Getenv("CMUX_BROWSER_AGENT_RUNNER"); v != "" {
+		candidates = []string{v}
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return map[string]interface{}{"path": path}, nil
+		}
+	}
+	return map[string]interface{}{"searched": candidates}, errors.New("browser-agent-runner.js not found")
+}
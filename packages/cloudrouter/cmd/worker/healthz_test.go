@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleHealthzReportsDegradedDependency(t *testing.T) {
+	saved := healthProbes
+	t.Cleanup(func() { healthProbes = saved })
+	healthProbes = map[string]healthProbe{
+		"chromeCdp": func(ctx context.Context) (map[string]interface{}, error) {
+			return nil, errors.New("Chrome CDP not reachable")
+		},
+		"disk": func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"freeBytes": 1}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code = %d, want 503", rec.Code)
+	}
+
+	var resp struct {
+		Status string                 `json:"status"`
+		Checks map[string]healthCheck `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v (%s)", err, rec.Body.String())
+	}
+	if resp.Status != "degraded" {
+		t.Errorf("status = %q, want degraded", resp.Status)
+	}
+	if resp.Checks["worker"].Status != "ok" || resp.Checks["disk"].Status != "ok" {
+		t.Errorf("unexpected checks: %+v", resp.Checks)
+	}
+	if c := resp.Checks["chromeCdp"]; c.Status != "fail" || c.Error == "" {
+		t.Errorf("chromeCdp = %+v", c)
+	}
+}
+
+func TestHandleHealthzOK(t *testing.T) {
+	saved := healthProbes
+	t.Cleanup(func() { healthProbes = saved })
+	healthProbes = map[string]healthProbe{
+		"disk": func(ctx context.Context) (map[string]interface{}, error) { return nil, nil },
+	}
+
+	rec := httptest.NewRecorder()
+	handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %d, want 200", rec.Code)
+	}
+}
+
+func TestProbeWorkspaceWritable(t *testing.T) {
+	saved := workspaceDir
+	t.Cleanup(func() { workspaceDir = saved })
+
+	workspaceDir = t.TempDir()
+	if _, err := probeWorkspaceWritable(context.Background()); err != nil {
+		t.Fatalf("writable workspace: %v", err)
+	}
+	if entries, _ := os.ReadDir(workspaceDir); len(entries) != 0 {
+		t.Errorf("probe left files behind: %v", entries)
+	}
+
+	workspaceDir = filepath.Join(workspaceDir, "missing")
+	if _, err := probeWorkspaceWritable(context.Background()); err == nil {
+		t.Error("expected error for missing workspace")
+	}
+}
+
+func TestProbeDiskFreeThreshold(t *testing.T) {
+	saved := workspaceDir
+	t.Cleanup(func() { workspaceDir = saved })
+	workspaceDir = t.TempDir()
+
+	t.Setenv("CMUX_HEALTH_MIN_FREE_BYTES", "0")
+	if _, err := probeDiskFree(context.Background()); err != nil {
+		t.Fatalf("probeDiskFree: %v", err)
+	}
+	t.Setenv("CMUX_HEALTH_MIN_FREE_BYTES", "18446744073709551615")
+	if _, err := probeDiskFree(context.Background()); err == nil {
+		t.Error("expected error below minimum free space")
+	}
+}
+
+func TestProbeBrowserAgentRunner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "browser-agent-runner.js")
+	t.Setenv("CMUX_BROWSER_AGENT_RUNNER", path)
+	if _, err := probeBrowserAgentRunner(context.Background()); err == nil {
+		t.Error("expected error for missing runner")
+	}
+
+	if err := os.WriteFile(path, []byte("// runner"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	detail, err := probeBrowserAgentRunner(context.Background())
+	if err != nil || detail["path"] != path {
+		t.Errorf("probeBrowserAgentRunner() = %v, %v", detail, err)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	jobOutputMax      = 256 * 1024
+	jobDefaultTail    = 4096
+	jobMaxFinished    = 100
+	jobKillGraceDelay = 5 * time.Second
+)
+
+const (
+	jobRunning = "running"
+	jobExited  = "exited"
+	jobKilled  = "killed"
+	jobFailed  = "failed"
+)
+
+// tailBuffer keeps the last max bytes written to it, plus a running total.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	max   int
+	total int64
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total += int64(len(p))
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// tail returns up to n trailing bytes and the total written so far.
+func (b *tailBuffer) tail(n int) (string, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n <= 0 || n > len(b.buf) {
+		n = len(b.buf)
+	}
+	return string(b.buf[len(b.buf)-n:]), b.total
+}
+
+// job is a background command started via POST /_cmux/jobs. Output from
+// stdout and stderr is interleaved into one tail buffer.
+type job struct {
+	id        string
+	command   string
+	cwd       string
+	pid       int
+	startedAt time.Time
+	output    *tailBuffer
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu       sync.Mutex
+	status   string
+	exitCode int
+	endedAt  time.Time
+	killed   bool
+}
+
+func (j *job) state(tailBytes int) map[string]interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	out, total := j.output.tail(tailBytes)
+	state := map[string]interface{}{
+		"id":          j.id,
+		"command":     j.command,
+		"cwd":         j.cwd,
+		"pid":         j.pid,
+		"status":      j.status,
+		"startedAt":   j.startedAt.UnixMilli(),
+		"output":      out,
+		"outputBytes": total,
+	}
+	if j.status != jobRunning {
+		state["exit_code"] = j.exitCode
+		state["endedAt"] = j.endedAt.UnixMilli()
+	}
+	return state
+}
+
+// jobManager owns all background jobs. Finished jobs are kept for
+// inspection until jobMaxFinished newer ones have finished.
+type jobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+var jobs = &jobManager{jobs: make(map[string]*job)}
+
+func newJobID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "job_" + hex.EncodeToString(b)
+}
+
+// start launches req in the background. Jobs only time out when the
+// request sets an explicit timeout.
+func (m *jobManager) start(req *execRequest, hasTimeout bool) (*job, error) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if hasTimeout {
+		ctx, cancel = context.WithTimeout(context.Background(), req.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	j := &job{
+		id:        newJobID(),
+		command:   req.command,
+		cwd:       req.cwd,
+		output:    &tailBuffer{max: jobOutputMax},
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    jobRunning,
+		startedAt: time.Now(),
+	}
+
+	cmd, err := req.cmd(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	cmd.Stdout = j.output
+	cmd.Stderr = j.output
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	j.pid = cmd.Process.Pid
+
+	m.mu.Lock()
+	m.jobs[j.id] = j
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := cmd.Wait()
+
+		j.mu.Lock()
+		j.endedAt = time.Now()
+		j.exitCode = -1
+		if cmd.ProcessState != nil {
+			j.exitCode = cmd.ProcessState.ExitCode()
+		}
+		switch {
+		case j.killed:
+			j.status = jobKilled
+		case err != nil && cmd.ProcessState == nil:
+			j.status = jobFailed
+		default:
+			j.status = jobExited
+		}
+		status, exitCode := j.status, j.exitCode
+		j.mu.Unlock()
+		close(j.done)
+
+		events.publish("job.exit", map[string]interface{}{
+			"id":        j.id,
+			"status":    status,
+			"exit_code": exitCode,
+		})
+		m.prune()
+	}()
+
+	return j, nil
+}
+
+func (m *jobManager) get(id string) (*job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+func (m *jobManager) list() []*job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].startedAt.Before(out[b].startedAt) })
+	return out
+}
+
+// prune drops the oldest finished jobs beyond jobMaxFinished.
+func (m *jobManager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var finished []*job
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		if j.status != jobRunning {
+			finished = append(finished, j)
+		}
+		j.mu.Unlock()
+	}
+	if len(finished) <= jobMaxFinished {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].endedAt.Before(finished[b].endedAt) })
+	for _, j := range finished[:len(finished)-jobMaxFinished] {
+		delete(m.jobs, j.id)
+	}
+}
+
+// kill sends SIGTERM to the job's process group and escalates to SIGKILL
+// if it has not exited after jobKillGraceDelay.
+func (j *job) kill() {
+	j.mu.Lock()
+	if j.status != jobRunning {
+		j.mu.Unlock()
+		return
+	}
+	j.killed = true
+	j.mu.Unlock()
+
+	signalProcessGroup(j.pid, syscall.SIGTERM)
+	select {
+	case <-j.done:
+	case <-time.After(jobKillGraceDelay):
+		j.cancel()
+		<-j.done
+	}
+}
+
+// handleJobs serves the job API:
+//
+//	POST   /_cmux/jobs       start a job (same body as /exec)
+//	GET    /_cmux/jobs       list jobs
+//	GET    /_cmux/jobs/{id}  status and output tail (?tail=bytes, 0 for all)
+//	DELETE /_cmux/jobs/{id}  kill the job
+func handleJobs(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/_cmux/jobs"), "/")
+
+	if id == "" {
+		switch r.Method {
+		case http.MethodPost:
+			req, err := parseExecRequest(body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				sendJSON(w, map[string]string{"error": err.Error()})
+				return
+			}
+			if isAgentBrowserCommand(req.command) && !requireAgentControl(w) {
+				return
+			}
+			if !enforceExecPolicy(w, req) {
+				return
+			}
+			activity.touch(activityExec)
+
+			_, hasTimeout := body["timeout"].(float64)
+			j, err := jobs.start(req, hasTimeout)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				sendJSON(w, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Printf("[worker] started job %s (pid %d): %s", j.id, j.pid, j.command)
+			w.WriteHeader(http.StatusCreated)
+			sendJSON(w, j.state(0))
+		case http.MethodGet:
+			list := jobs.list()
+			out := make([]map[string]interface{}, 0, len(list))
+			for _, j := range list {
+				state := j.state(0)
+				delete(state, "output")
+				out = append(out, state)
+			}
+			sendJSON(w, map[string]interface{}{"jobs": out})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			sendJSON(w, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	j, ok := jobs.get(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		sendJSON(w, map[string]string{"error": "job not found"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tail := jobDefaultTail
+		if v := r.URL.Query().Get("tail"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				tail = n
+			}
+		}
+		sendJSON(w, j.state(tail))
+	case http.MethodDelete:
+		j.kill()
+		sendJSON(w, j.state(jobDefaultTail))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		sendJSON(w, map[string]string{"error": "Method not allowed"})
+	}
+}
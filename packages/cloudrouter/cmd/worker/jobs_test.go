@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jobRequest(t *testing.T, method, path string, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handleJobs(w, httptest.NewRequest(method, path, nil), body)
+	var out map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: decode %q: %v", method, path, w.Body.String(), err)
+	}
+	return out
+}
+
+func TestJobLifecycle(t *testing.T) {
+	started := jobRequest(t, "POST", "/_cmux/jobs", map[string]interface{}{
+		"command": "echo ready; sleep 30",
+		"cwd":     t.TempDir(),
+	})
+	id, _ := started["id"].(string)
+	if id == "" || started["status"] != jobRunning {
+		t.Fatalf("unexpected start response: %v", started)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		state := jobRequest(t, "GET", "/_cmux/jobs/"+id, nil)
+		if strings.Contains(state["output"].(string), "ready") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job output never appeared: %v", state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	killed := jobRequest(t, "DELETE", "/_cmux/jobs/"+id, nil)
+	if killed["status"] != jobKilled {
+		t.Errorf("status after DELETE = %v", killed["status"])
+	}
+
+	list := jobRequest(t, "GET", "/_cmux/jobs", nil)
+	found := false
+	for _, item := range list["jobs"].([]interface{}) {
+		if item.(map[string]interface{})["id"] == id {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("job %s missing from list", id)
+	}
+
+	if missing := jobRequest(t, "GET", "/_cmux/jobs/job_nope", nil); missing["error"] == nil {
+		t.Errorf("expected error for unknown job, got %v", missing)
+	}
+}
+
+func TestTailBufferKeepsEnd(t *testing.T) {
+	b := &tailBuffer{max: 8}
+	b.Write([]byte("hello "))
+	b.Write([]byte("world!"))
+	out, total := b.tail(0)
+	if out != "o world!" || total != 12 {
+		t.Errorf("tail = %q, %d", out, total)
+	}
+	if out, _ := b.tail(3); out != "ld!" {
+		t.Errorf("tail(3) = %q", out)
+	}
+}
@@ -65,17 +65,6 @@ var (
 	}
 )
 
-type ptySession struct {
-	ID        string
-	PTY       *os.File
-	Cmd       *exec.Cmd
-	CreatedAt time.Time
-	Shell     string
-	Cwd       string
-	Cols      uint16
-	Rows      uint16
-}
-
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Printf("[worker] Starting cmux worker daemon...")
@@ -90,6 +79,12 @@ func main() {
 	// Initialize auth token
 	initAuthToken()
 
+	policy, err := loadExecPolicy()
+	if err != nil {
+		log.Fatalf("[worker] Exec policy: %v", err)
+	}
+	execPolicyCurrent = policy
+
 	// Start SSH server in goroutine
 	go startSSHServer()
 
@@ -97,6 +92,9 @@ func main() {
 	vncProxySrv := newVNCProxy()
 	go vncProxySrv.Start()
 
+	// Watch free space and run cleanup hooks before agents hit ENOSPC
+	go disks.run(context.Background())
+
 	// Start HTTP server (browser manager is cleaned up on shutdown)
 	startHTTPServer(vncProxySrv)
 }
@@ -196,29 +194,11 @@ func ensureValidToken() string {
 }
 
 func verifyAuth(r *http.Request) bool {
-	token := ensureValidToken()
-
-	// Check Authorization header
-	if auth := r.Header.Get("Authorization"); auth != "" {
-		if strings.HasPrefix(auth, "Bearer ") {
-			if auth[7:] == token {
-				return true
-			}
-		} else if auth == token {
+	for _, token := range presentedTokens(r) {
+		if isValidToken(token) {
 			return true
 		}
 	}
-
-	// Check query parameter
-	if r.URL.Query().Get("token") == token {
-		return true
-	}
-
-	// Check cookie
-	if cookie, err := r.Cookie(authCookieName); err == nil && cookie.Value == token {
-		return true
-	}
-
 	return false
 }
 
@@ -229,7 +209,8 @@ func verifyAuth(r *http.Request) bool {
 func startHTTPServer(vncProxySrv *vncProxy) {
 	mux := http.NewServeMux()
 
-	// Health check - no auth
+	// Health checks - no auth. /healthz probes dependencies; /health is liveness only
+	mux.HandleFunc("/healthz", handleHealthz)
 	mux.HandleFunc("/health", handleHealth)
 
 	// Auth token endpoint - localhost only
@@ -238,12 +219,12 @@ func startHTTPServer(vncProxySrv *vncProxy) {
 	// Auth cookie setter
 	mux.HandleFunc("/_cmux/auth", handleAuthCookie)
 
-	// All other endpoints require auth
+	// All other endpoints require auth (enforced by authMiddleware)
 	mux.HandleFunc("/", handleAPI)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf("0.0.0.0:%d", httpPort),
-		Handler: corsMiddleware(mux),
+		Handler: corsMiddleware(authMiddleware(mux)),
 	}
 
 	// Graceful shutdown
@@ -291,8 +272,7 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 
 func handleAuthToken(w http.ResponseWriter, r *http.Request) {
 	// Only allow from localhost
-	host, _, _ := net.SplitHostPort(r.RemoteAddr)
-	if host != "127.0.0.1" && host != "::1" && host != "::ffff:127.0.0.1" {
+	if !isLoopbackRequest(r) {
 		sendJSON(w, map[string]string{"error": "Forbidden"})
 		return
 	}
@@ -306,8 +286,7 @@ func handleAuthCookie(w http.ResponseWriter, r *http.Request) {
 		returnPath = "/"
 	}
 
-	currentToken := ensureValidToken()
-	if token == "" || token != currentToken {
+	if !isValidToken(token) {
 		w.WriteHeader(http.StatusUnauthorized)
 		sendJSON(w, map[string]string{"error": "Invalid token"})
 		return
@@ -328,29 +307,14 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 
 	// WebSocket endpoints
 	if path == "/pty" {
-		if !verifyAuth(r) {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
 		handlePTYWebSocket(w, r)
 		return
 	}
 	if path == "/ssh" {
-		if !verifyAuth(r) {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
 		handleSSHWebSocket(w, r)
 		return
 	}
 
-	// Require auth for all other endpoints
-	if !verifyAuth(r) {
-		w.WriteHeader(http.StatusUnauthorized)
-		sendJSON(w, map[string]string{"error": "Unauthorized"})
-		return
-	}
-
 	// Parse body for POST requests
 	var body map[string]interface{}
 	if r.Method == "POST" {
@@ -364,7 +328,14 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if path == "/_cmux/jobs" || strings.HasPrefix(path, "/_cmux/jobs/") {
+		handleJobs(w, r, body)
+		return
+	}
+
 	switch path {
+	case "/_cmux/auth/rotate":
+		handleAuthRotate(w, r, body)
 	case "/exec":
 		handleExec(w, r, body)
 	case "/read-file":
@@ -379,16 +350,28 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 		handleStatus(w, r)
 	case "/services":
 		handleServices(w, r)
+	case "/_cmux/activity":
+		handleActivity(w, r)
+	case "/_cmux/ports":
+		handlePorts(w, r)
+	case "/_cmux/files/tail":
+		handleFileTail(w, r)
+	case "/_cmux/events":
+		handleEvents(w, r)
 	case "/pty-sessions":
 		handlePTYSessions(w, r)
 	case "/cdp-info":
 		handleCDPInfo(w, r)
 	case "/screenshot":
 		handleScreenshot(w, r, body)
-	// Browser automation (screenshot returns base64 data, browser-agent runs in agent mode)
-	// All other browser commands go through /exec with "agent-browser <command>"
+	// Browser automation: browser-agent runs agent-browser in agent mode,
+	// single commands run natively over CDP through /_cmux/browser
 	case "/browser-agent":
 		handleBrowserAgent(w, r, body)
+	case "/_cmux/browser/control":
+		handleBrowserControl(w, r, body)
+	case "/_cmux/browser":
+		handleBrowserCommand(w, r, body)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 		sendJSON(w, map[string]string{"error": "Not found"})
@@ -399,46 +382,6 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 // API Handlers
 // =============================================================================
 
-func handleExec(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
-	command, _ := body["command"].(string)
-	if command == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		sendJSON(w, map[string]string{"error": "command required"})
-		return
-	}
-
-	timeout := 60 * time.Second
-	if t, ok := body["timeout"].(float64); ok {
-		timeout = time.Duration(t) * time.Millisecond
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
-	cmd.Dir = workspaceDir
-	cmd.Env = append(os.Environ(), "FORCE_COLOR=0")
-
-	stdout, _ := cmd.Output()
-	var stderr []byte
-	if exitErr, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && exitErr.ExitStatus() != 0 {
-		if ee, ok := cmd.ProcessState.Sys().(interface{ Stderr() []byte }); ok {
-			stderr = ee.Stderr()
-		}
-	}
-
-	exitCode := 0
-	if cmd.ProcessState != nil {
-		exitCode = cmd.ProcessState.ExitCode()
-	}
-
-	sendJSON(w, map[string]interface{}{
-		"stdout":    strings.TrimSpace(string(stdout)),
-		"stderr":    strings.TrimSpace(string(stderr)),
-		"exit_code": exitCode,
-	})
-}
-
 func handleReadFile(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
 	path, _ := body["path"].(string)
 	if path == "" {
@@ -579,17 +522,15 @@ func handleServices(w http.ResponseWriter, r *http.Request) {
 
 func handlePTYSessions(w http.ResponseWriter, r *http.Request) {
 	ptySessionsMu.RLock()
-	defer ptySessionsMu.RUnlock()
+	list := make([]*ptySession, 0, len(ptySessions))
+	for _, s := range ptySessions {
+		list = append(list, s)
+	}
+	ptySessionsMu.RUnlock()
 
-	sessions := make([]map[string]interface{}, 0, len(ptySessions))
-	for id, s := range ptySessions {
-		sessions = append(sessions, map[string]interface{}{
-			"id":        id,
-			"createdAt": s.CreatedAt.UnixMilli(),
-			"shell":     s.Shell,
-			"cwd":       s.Cwd,
-			"connected": s.PTY != nil,
-		})
+	sessions := make([]map[string]interface{}, 0, len(list))
+	for _, s := range list {
+		sessions = append(sessions, s.info())
 	}
 
 	sendJSON(w, map[string]interface{}{
@@ -613,10 +554,9 @@ func handleCDPInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleScreenshot(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
-	result, err := browser.Screenshot()
+	result, err := browser.Screenshot(body)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		sendJSON(w, map[string]string{"error": err.Error()})
+		writeBrowserError(w, "screenshot", err)
 		return
 	}
 
@@ -637,6 +577,9 @@ func handleScreenshot(w http.ResponseWriter, r *http.Request, body map[string]in
 }
 
 func handleBrowserAgent(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	if !requireAgentControl(w) {
+		return
+	}
 	result, err := browser.RunBrowserAgent(body)
 	if err != nil {
 		log.Printf("[worker] browser-agent failed: %v", err)
@@ -650,7 +593,20 @@ func handleBrowserAgent(w http.ResponseWriter, r *http.Request, body map[string]
 // PTY WebSocket Handler
 // =============================================================================
 
+// handlePTYWebSocket opens a new shell, or reattaches to a running session
+// with ?session=<id>. Disconnecting detaches; the shell keeps running until
+// it exits or the detach timeout passes.
 func handlePTYWebSocket(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sessionID := q.Get("session")
+	var session *ptySession
+	if sessionID != "" {
+		if session = getPTYSession(sessionID); session == nil {
+			http.Error(w, "PTY session not found", http.StatusNotFound)
+			return
+		}
+	}
+
 	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[worker] Failed to accept WebSocket: %v", err)
@@ -659,79 +615,38 @@ func handlePTYWebSocket(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	// Parse options from query
-	q := r.URL.Query()
 	cols := parseUint16(q.Get("cols"), 80)
 	rows := parseUint16(q.Get("rows"), 24)
-	shell := q.Get("shell")
-	if shell == "" {
-		shell = os.Getenv("SHELL")
+	if session == nil {
+		shell := q.Get("shell")
 		if shell == "" {
-			shell = "/bin/bash"
+			shell = os.Getenv("SHELL")
+			if shell == "" {
+				shell = "/bin/bash"
+			}
+		}
+		cwd := q.Get("cwd")
+		if cwd == "" {
+			cwd = workspaceDir
 		}
-	}
-	cwd := q.Get("cwd")
-	if cwd == "" {
-		cwd = workspaceDir
-	}
 
-	// Spawn PTY
-	cmd := exec.Command(shell)
-	cmd.Dir = cwd
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+		session, err = startPTYSession(shell, cwd, cols, rows)
+		if err != nil {
+			log.Printf("[worker] Failed to start PTY: %v", err)
+			return
+		}
+	} else if q.Get("cols") != "" && q.Get("rows") != "" {
+		session.resize(cols, rows)
+	}
+	defer activity.begin(activityPTY)()
 
-	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
-	if err != nil {
-		log.Printf("[worker] Failed to start PTY: %v", err)
-		conn.Close()
+	if !session.attach(conn, sessionID != "") {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"exit","code":0}`))
 		return
 	}
-	defer ptmx.Close()
-
-	// Create session
-	sessionID := generateSessionID()
-	session := &ptySession{
-		ID:        sessionID,
-		PTY:       ptmx,
-		Cmd:       cmd,
-		CreatedAt: time.Now(),
-		Shell:     shell,
-		Cwd:       cwd,
-		Cols:      cols,
-		Rows:      rows,
-	}
-
-	ptySessionsMu.Lock()
-	ptySessions[sessionID] = session
-	ptySessionsMu.Unlock()
-
-	defer func() {
-		ptySessionsMu.Lock()
-		delete(ptySessions, sessionID)
-		ptySessionsMu.Unlock()
-	}()
-
-	// Send session info
-	conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"session","id":"%s"}`, sessionID)))
-
-	// Read from PTY, write to WebSocket
-	go func() {
-		buf := make([]byte, 4096)
-		for {
-			n, err := ptmx.Read(buf)
-			if err != nil {
-				return
-			}
-			msg, _ := json.Marshal(map[string]string{
-				"type": "data",
-				"data": string(buf[:n]),
-			})
-			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				return
-			}
-		}
-	}()
+	defer session.detach(conn)
 
-	// Read from WebSocket, write to PTY
+	// Read from WebSocket, write to PTY; the session pumps output back.
 	for {
 		_, data, err := conn.ReadMessage()
 		if err != nil {
@@ -750,24 +665,16 @@ func handlePTYWebSocket(w http.ResponseWriter, r *http.Request) {
 
 		switch msg.Type {
 		case "data":
-			ptmx.Write([]byte(msg.Data))
+			activity.touch(activityPTY)
+			session.PTY.Write([]byte(msg.Data))
 		case "resize":
 			if msg.Cols > 0 && msg.Rows > 0 {
-				pty.Setsize(ptmx, &pty.Winsize{Cols: uint16(msg.Cols), Rows: uint16(msg.Rows)})
+				session.resize(uint16(msg.Cols), uint16(msg.Rows))
 			}
+		case "kill":
+			session.kill()
 		}
 	}
-
-	// Cleanup
-	cmd.Process.Kill()
-	cmd.Wait()
-
-	// Send exit message
-	exitCode := 0
-	if cmd.ProcessState != nil {
-		exitCode = cmd.ProcessState.ExitCode()
-	}
-	conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"exit","code":%d}`, exitCode)))
 }
 
 // =============================================================================
@@ -790,6 +697,7 @@ func handleSSHWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer sshConn.Close()
+	defer activity.begin(activitySSH)()
 
 	// Bridge WebSocket <-> SSH
 	done := make(chan struct{})
@@ -890,6 +798,7 @@ func handleSSHConn(conn net.Conn, config *cryptossh.ServerConfig) {
 		return
 	}
 	defer sshConn.Close()
+	defer activity.begin(activitySSH)()
 
 	go cryptossh.DiscardRequests(reqs)
 
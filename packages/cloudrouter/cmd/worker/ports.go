@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listeningPort is one TCP socket in LISTEN state.
+type listeningPort struct {
+	Port    int    `json:"port"`
+	Address string `json:"address"`
+	PID     int    `json:"pid,omitempty"`
+	Process string `json:"process,omitempty"`
+	inode   string
+}
+
+// dockerPort is a published container port.
+type dockerPort struct {
+	Container     string `json:"container"`
+	HostIP        string `json:"hostIp"`
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// tcpListenState is TCP_LISTEN in /proc/net/tcp.
+const tcpListenState = "0A"
+
+// parseProcNetTCP parses /proc/net/tcp or /proc/net/tcp6 and returns the
+// listening sockets.
+func parseProcNetTCP(r io.Reader, ipv6 bool) []listeningPort {
+	var ports []listeningPort
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		addr, port, ok := parseHexAddr(fields[1], ipv6)
+		if !ok {
+			continue
+		}
+		ports = append(ports, listeningPort{Port: port, Address: addr, inode: fields[9]})
+	}
+	return ports
+}
+
+// parseHexAddr decodes "0100007F:0BB8" style local addresses. The kernel
+// prints each 32-bit word of the address in host (little-endian) order.
+func parseHexAddr(s string, ipv6 bool) (string, int, bool) {
+	host, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, false
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	raw, err := hex.DecodeString(host)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) || (len(raw) == 16) != ipv6 {
+		return "", 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip.String(), int(port), true
+}
+
+// socketOwners maps socket inodes to the owning PID by scanning
+// /proc/*/fd. Processes we cannot inspect are skipped.
+func socketOwners() map[string]int {
+	owners := make(map[string]int)
+	procs, _ := filepath.Glob("/proc/[0-9]*/fd")
+	for _, fdDir := range procs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(fdDir)))
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			owners[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = pid
+		}
+	}
+	return owners
+}
+
+func processName(pid int) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// discoverPorts lists TCP listeners with their owning processes, one entry
+// per port and address.
+func discoverPorts() ([]listeningPort, error) {
+	var ports []listeningPort
+	for _, source := range []struct {
+		path string
+		ipv6 bool
+	}{{"/proc/net/tcp", false}, {"/proc/net/tcp6", true}} {
+		f, err := os.Open(source.path)
+		if err != nil {
+			if source.ipv6 && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		ports = append(ports, parseProcNetTCP(f, source.ipv6)...)
+		f.Close()
+	}
+
+	owners := socketOwners()
+	seen := make(map[string]bool)
+	out := make([]listeningPort, 0, len(ports))
+	for _, p := range ports {
+		key := fmt.Sprintf("%s:%d", p.Address, p.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if pid, ok := owners[p.inode]; ok {
+			p.PID = pid
+			p.Process = processName(pid)
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out, nil
+}
+
+// parseDockerPorts parses the Ports column of `docker ps`, e.g.
+// "0.0.0.0:3000->3000/tcp, :::3000->3000/tcp, 5432/tcp". Unpublished
+// ports are skipped.
+func parseDockerPorts(container, column string) []dockerPort {
+	var out []dockerPort
+	for _, item := range strings.Split(column, ",") {
+		item = strings.TrimSpace(item)
+		hostPart, containerPart, ok := strings.Cut(item, "->")
+		if !ok {
+			continue
+		}
+		idx := strings.LastIndex(hostPart, ":")
+		if idx < 0 {
+			continue
+		}
+		hostIP := hostPart[:idx]
+		hostPort, err := strconv.Atoi(hostPart[idx+1:])
+		if err != nil {
+			continue
+		}
+		portStr, proto, _ := strings.Cut(containerPart, "/")
+		containerPort, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		out = append(out, dockerPort{
+			Container:     container,
+			HostIP:        hostIP,
+			HostPort:      hostPort,
+			ContainerPort: containerPort,
+			Protocol:      proto,
+		})
+	}
+	return out
+}
+
+// discoverDockerPorts returns published ports of running containers. A
+// missing docker binary is not an error: the sandbox may simply not use
+// docker.
+func discoverDockerPorts(ctx context.Context) ([]dockerPort, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "docker", "ps", "--format", "{{.Names}}\t{{.Ports}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker ps failed: %w", err)
+	}
+
+	var ports []dockerPort
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		name, column, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		ports = append(ports, parseDockerPorts(name, column)...)
+	}
+	return ports, nil
+}
+
+// handlePorts serves GET /_cmux/ports: TCP listeners with owning processes
+// and, when docker is available, published container ports.
+func handlePorts(w http.ResponseWriter, r *http.Request) {
+	ports, err := discoverPorts()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result := map[string]interface{}{"ports": ports}
+	docker, err := discoverDockerPorts(r.Context())
+	if err != nil {
+		result["dockerError"] = err.Error()
+	}
+	if docker == nil {
+		docker = []dockerPort{}
+	}
+	result["docker"] = docker
+	sendJSON(w, result)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProcNetTCP(t *testing.T) {
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0BB8 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 12345 1 0000000000000000 100 0 0 10 0
+   1: 00000000:9991 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 23456 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0BB8 0100007F:D2F0 01 00000000:00000000 00:00000000 00000000  1000        0 34567 1 0000000000000000 20 4 30 10 -1
+`
+	ports := parseProcNetTCP(strings.NewReader(tcp), false)
+	if len(ports) != 2 {
+		t.Fatalf("expected 2 listeners, got %+v", ports)
+	}
+	if ports[0].Address != "127.0.0.1" || ports[0].Port != 3000 || ports[0].inode != "12345" {
+		t.Errorf("first listener = %+v", ports[0])
+	}
+	if ports[1].Address != "0.0.0.0" || ports[1].Port != 39313 {
+		t.Errorf("second listener = %+v", ports[1])
+	}
+
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 45678 1 0000000000000000 100 0 0 10 0
+`
+	ports = parseProcNetTCP(strings.NewReader(tcp6), true)
+	if len(ports) != 1 || ports[0].Address != "::1" || ports[0].Port != 8080 {
+		t.Errorf("tcp6 listeners = %+v", ports)
+	}
+}
+
+func TestParseDockerPorts(t *testing.T) {
+	ports := parseDockerPorts("db", "0.0.0.0:5433->5432/tcp, :::5433->5432/tcp, 6379/tcp")
+	if len(ports) != 2 {
+		t.Fatalf("expected 2 published ports, got %+v", ports)
+	}
+	if p := ports[0]; p.HostIP != "0.0.0.0" || p.HostPort != 5433 || p.ContainerPort != 5432 || p.Protocol != "tcp" || p.Container != "db" {
+		t.Errorf("ipv4 mapping = %+v", p)
+	}
+	if p := ports[1]; p.HostIP != "::" || p.HostPort != 5433 {
+		t.Errorf("ipv6 mapping = %+v", p)
+	}
+}
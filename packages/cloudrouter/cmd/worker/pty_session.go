@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+)
+
+// defaultPTYScrollbackBytes is how much recent output each PTY session keeps
+// for replay on reattach. Override with CMUX_PTY_SCROLLBACK_BYTES.
+const defaultPTYScrollbackBytes = 64 * 1024
+
+// defaultPTYDetachTimeout is how long a session keeps running with no client
+// attached before its shell is killed. Override with CMUX_PTY_DETACH_TIMEOUT
+// (a Go duration); 0 kills the shell as soon as the client disconnects.
+const defaultPTYDetachTimeout = 30 * time.Minute
+
+// ptyWriteTimeout bounds each WebSocket write so a stalled client cannot
+// block the session's output pump.
+const ptyWriteTimeout = 10 * time.Second
+
+var (
+	ptyScrollbackBytes = loadPTYScrollbackBytes()
+	ptyDetachTimeout   = loadPTYDetachTimeout()
+)
+
+func loadPTYScrollbackBytes() int {
+	if v := os.Getenv("CMUX_PTY_SCROLLBACK_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultPTYScrollbackBytes
+}
+
+func loadPTYDetachTimeout() time.Duration {
+	if v := os.Getenv("CMUX_PTY_DETACH_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultPTYDetachTimeout
+}
+
+// scrollbackBuffer keeps the last size bytes written to it.
+type scrollbackBuffer struct {
+	size int
+	buf  []byte
+}
+
+func newScrollbackBuffer(size int) *scrollbackBuffer {
+	return &scrollbackBuffer{size: size}
+}
+
+func (b *scrollbackBuffer) Write(p []byte) {
+	if b.size <= 0 {
+		return
+	}
+	b.buf = append(b.buf, p...)
+	// Compact only once the slice is twice the limit so writes stay amortized O(n).
+	if len(b.buf) > 2*b.size {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.size:]...)
+	}
+}
+
+// Len returns how many bytes Bytes would return, before rune alignment.
+func (b *scrollbackBuffer) Len() int {
+	if len(b.buf) > b.size {
+		return b.size
+	}
+	return len(b.buf)
+}
+
+// Bytes returns a copy of the retained output, starting at a UTF-8 rune
+// boundary so replay never begins mid-character.
+func (b *scrollbackBuffer) Bytes() []byte {
+	out := b.buf
+	if len(out) > b.size {
+		out = out[len(out)-b.size:]
+	}
+	for i := 0; i < len(out) && i < utf8.UTFMax; i++ {
+		if utf8.RuneStart(out[i]) {
+			out = out[i:]
+			break
+		}
+	}
+	return append([]byte(nil), out...)
+}
+
+// ptySession is a shell on a PTY that outlives its WebSocket connections.
+// Output is pumped into the scrollback buffer and to the attached client, if
+// any. A session ends when its shell exits or it stays detached longer than
+// ptyDetachTimeout.
+type ptySession struct {
+	ID        string
+	PTY       *os.File
+	Cmd       *exec.Cmd
+	CreatedAt time.Time
+	Shell     string
+	Cwd       string
+	Cols      uint16
+	Rows      uint16
+
+	// mu guards the fields below and serializes every write to client, so
+	// replay on attach cannot interleave with live output.
+	mu          sync.Mutex
+	scrollback  *scrollbackBuffer
+	client      *websocket.Conn
+	detachedAt  time.Time
+	detachTimer *time.Timer
+	exited      bool
+}
+
+// startPTYSession spawns shell in cwd and registers the session.
+func startPTYSession(shell, cwd string, cols, rows uint16) (*ptySession, error) {
+	cmd := exec.Command(shell)
+	cmd.Dir = cwd
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ptySession{
+		ID:         generateSessionID(),
+		PTY:        ptmx,
+		Cmd:        cmd,
+		CreatedAt:  time.Now(),
+		Shell:      shell,
+		Cwd:        cwd,
+		Cols:       cols,
+		Rows:       rows,
+		scrollback: newScrollbackBuffer(ptyScrollbackBytes),
+	}
+
+	ptySessionsMu.Lock()
+	ptySessions[s.ID] = s
+	ptySessionsMu.Unlock()
+
+	go s.pump()
+	return s, nil
+}
+
+func getPTYSession(id string) *ptySession {
+	ptySessionsMu.RLock()
+	defer ptySessionsMu.RUnlock()
+	return ptySessions[id]
+}
+
+// pump copies PTY output to the scrollback and the attached client until the
+// shell exits, then reports the exit code and unregisters the session.
+func (s *ptySession) pump() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.PTY.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			s.scrollback.Write(buf[:n])
+			s.sendLocked(map[string]interface{}{"type": "data", "data": string(buf[:n])})
+			s.mu.Unlock()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	s.Cmd.Wait()
+	exitCode := 0
+	if s.Cmd.ProcessState != nil {
+		exitCode = s.Cmd.ProcessState.ExitCode()
+	}
+
+	s.mu.Lock()
+	s.exited = true
+	if s.detachTimer != nil {
+		s.detachTimer.Stop()
+	}
+	s.sendLocked(map[string]interface{}{"type": "exit", "code": exitCode})
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+	s.mu.Unlock()
+
+	ptySessionsMu.Lock()
+	delete(ptySessions, s.ID)
+	ptySessionsMu.Unlock()
+	s.PTY.Close()
+	log.Printf("[worker] PTY session %s exited with code %d", s.ID, exitCode)
+}
+
+// sendLocked writes msg to the attached client. A client that cannot keep
+// up is dropped and the session detaches. Callers hold s.mu.
+func (s *ptySession) sendLocked(msg interface{}) {
+	if s.client == nil {
+		return
+	}
+	data, _ := json.Marshal(msg)
+	s.client.SetWriteDeadline(time.Now().Add(ptyWriteTimeout))
+	if err := s.client.WriteMessage(websocket.TextMessage, data); err != nil {
+		s.client.Close()
+		s.detachLocked()
+	}
+}
+
+// attach makes conn the session's client and replays the scrollback, which
+// for a new session is whatever the shell printed before the client was
+// ready. A previously attached client is told it was taken over and
+// disconnected. It returns false if the session is gone or conn failed.
+func (s *ptySession) attach(conn *websocket.Conn, reattach bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited {
+		return false
+	}
+	if old := s.client; old != nil {
+		data, _ := json.Marshal(map[string]interface{}{"type": "detached", "reason": "attached elsewhere"})
+		old.SetWriteDeadline(time.Now().Add(ptyWriteTimeout))
+		old.WriteMessage(websocket.TextMessage, data)
+		old.Close()
+	}
+	if s.detachTimer != nil {
+		s.detachTimer.Stop()
+		s.detachTimer = nil
+	}
+
+	s.client = conn
+	s.sendLocked(map[string]interface{}{"type": "session", "id": s.ID, "reattached": reattach})
+	if replay := s.scrollback.Bytes(); len(replay) > 0 {
+		s.sendLocked(map[string]interface{}{"type": "data", "data": string(replay)})
+	}
+	return s.client == conn
+}
+
+// detach releases conn if it is still the attached client and starts the
+// detach timeout.
+func (s *ptySession) detach(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == conn {
+		s.detachLocked()
+	}
+}
+
+func (s *ptySession) detachLocked() {
+	s.client = nil
+	s.detachedAt = time.Now()
+	if s.exited {
+		return
+	}
+	if ptyDetachTimeout == 0 {
+		s.killLocked()
+		return
+	}
+	s.detachTimer = time.AfterFunc(ptyDetachTimeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.client == nil && !s.exited {
+			log.Printf("[worker] PTY session %s detached for %s, closing", s.ID, ptyDetachTimeout)
+			s.killLocked()
+		}
+	})
+}
+
+// kill ends the session's shell; pump then cleans up.
+func (s *ptySession) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.killLocked()
+}
+
+func (s *ptySession) killLocked() {
+	if s.exited || s.Cmd.Process == nil {
+		return
+	}
+	s.Cmd.Process.Kill()
+	// Background jobs may still hold the terminal open; closing the master
+	// unblocks pump's read.
+	s.PTY.Close()
+}
+
+func (s *ptySession) resize(cols, rows uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Cols, s.Rows = cols, rows
+	pty.Setsize(s.PTY, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+// info describes the session for /pty-sessions.
+func (s *ptySession) info() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]interface{}{
+		"id":              s.ID,
+		"createdAt":       s.CreatedAt.UnixMilli(),
+		"shell":           s.Shell,
+		"cwd":             s.Cwd,
+		"connected":       s.client != nil,
+		"scrollbackBytes": s.scrollback.Len(),
+	}
+	if s.client == nil {
+		out["detachedAt"] = s.detachedAt.UnixMilli()
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrollbackBufferKeepsTail(t *testing.T) {
+	b := newScrollbackBuffer(8)
+	for _, chunk := range []string{"hello ", "world", "!", strings.Repeat("x", 20), "tail1234"} {
+		b.Write([]byte(chunk))
+		if got := len(b.Bytes()); got > 8 {
+			t.Fatalf("retained %d bytes, limit 8", got)
+		}
+	}
+	if got := string(b.Bytes()); got != "tail1234" {
+		t.Errorf("Bytes() = %q, want %q", got, "tail1234")
+	}
+	if b.Len() != 8 {
+		t.Errorf("Len() = %d, want 8", b.Len())
+	}
+}
+
+func TestScrollbackBufferShortOutput(t *testing.T) {
+	b := newScrollbackBuffer(64)
+	b.Write([]byte("$ "))
+	if got := string(b.Bytes()); got != "$ " {
+		t.Errorf("Bytes() = %q", got)
+	}
+}
+
+func TestScrollbackBufferStartsOnRuneBoundary(t *testing.T) {
+	b := newScrollbackBuffer(4)
+	b.Write([]byte("ab€cd")) // € is 3 bytes; the last 4 bytes start inside it
+	if got := string(b.Bytes()); got != "cd" {
+		t.Errorf("Bytes() = %q, want %q", got, "cd")
+	}
+}
+
+func TestScrollbackBufferDisabled(t *testing.T) {
+	b := newScrollbackBuffer(0)
+	b.Write([]byte("anything"))
+	if got := b.Bytes(); len(got) != 0 {
+		t.Errorf("Bytes() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleAPIServesWorkerRoutesUnderCmuxPrefix(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/_cmux/activity", "", http.StatusOK},
+		{http.MethodGet, "/_cmux/events", "", http.StatusOK},
+		{http.MethodGet, "/_cmux/browser/control", "", http.StatusOK},
+		{http.MethodPost, "/_cmux/browser", `{"command":"no-such-command"}`, http.StatusBadRequest},
+
+		{http.MethodGet, "/activity", "", http.StatusNotFound},
+		{http.MethodGet, "/events", "", http.StatusNotFound},
+		{http.MethodGet, "/browser/control", "", http.StatusNotFound},
+		{http.MethodPost, "/browser", `{"command":"no-such-command"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		// A cancelled context ends the /_cmux/events stream right after its
+		// headers are written.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handleAPI(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
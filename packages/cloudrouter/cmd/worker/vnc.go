@@ -194,6 +194,7 @@ func (vp *vncProxy) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	vncConn.(*net.TCPConn).SetNoDelay(true)
 	log.Printf("[vnc-proxy] WebSocket connected, bridging to VNC")
+	defer activity.begin(activityVNC)()
 
 	done := make(chan struct{})
 
@@ -220,6 +221,8 @@ func (vp *vncProxy) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				vncConn.Close()
 				return
 			}
+			// Client -> server traffic is keyboard/mouse input.
+			activity.touch(activityVNC)
 			if _, err := vncConn.Write(data); err != nil {
 				return
 			}
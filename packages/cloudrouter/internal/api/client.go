@@ -22,14 +22,18 @@ func NewClient() *Client {
 	cfg := auth.GetConfig()
 	return &Client{
 		baseURL:    cfg.ConvexSiteURL,
-		httpClient: &http.Client{Timeout: 600 * time.Second},
+		httpClient: NewHTTPClient(600 * time.Second),
 	}
 }
 
 func (c *Client) doRequest(method, path string, body interface{}) ([]byte, error) {
-	token, err := auth.GetAccessToken()
-	if err != nil {
-		return nil, err
+	// Dry runs are offline, so they must not need a login.
+	token := "dry-run"
+	if !DryRun() {
+		var err error
+		if token, err = auth.GetAccessToken(); err != nil {
+			return nil, err
+		}
 	}
 
 	var reqBody io.Reader
@@ -131,6 +135,11 @@ func (c *Client) CreateInstance(req CreateInstanceRequest) (*CreateInstanceRespo
 		return nil, fmt.Errorf("failed to parse response: %w (body: %s)", err, string(respBody))
 	}
 	resp.WorkerURL = normalizeWorkerURL(resp.Provider, resp.WorkerURL)
+	if DryRun() {
+		resp.DevboxID = "dry-run-instance"
+		resp.Status = "running"
+		resp.WorkerURL = dryRunPlaceholderWorkerURL
+	}
 
 	return &resp, nil
 }
@@ -163,6 +172,10 @@ func (c *Client) GetInstance(teamSlug, id string) (*Instance, error) {
 		return nil, err
 	}
 
+	if DryRun() {
+		return dryRunInstance(id), nil
+	}
+
 	var inst Instance
 	if err := json.Unmarshal(respBody, &inst); err != nil {
 		return nil, err
@@ -291,6 +304,10 @@ func (c *Client) GetAuthToken(teamSlug, id string) (string, error) {
 		return "", err
 	}
 
+	if DryRun() {
+		return "dry-run", nil
+	}
+
 	var resp AuthTokenResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
 		return "", err
@@ -353,6 +370,17 @@ func (c *Client) GetConfig() (*ConfigResponse, error) {
 // 	return respBody, nil
 // }
 
+// WorkerError is an error response from the worker daemon. Body is the raw
+// response, usually JSON with "error" and often a "code".
+type WorkerError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("worker error (%d): %s", e.StatusCode, string(e.Body))
+}
+
 // DoWorkerRequest makes a direct request to the worker daemon
 func DoWorkerRequest(workerURL, path, token string, body []byte) ([]byte, error) {
 	return DoWorkerRequestWithTimeout(workerURL, path, token, body, 60)
@@ -360,7 +388,7 @@ func DoWorkerRequest(workerURL, path, token string, body []byte) ([]byte, error)
 
 // DoWorkerRequestWithTimeout makes a direct request to the worker daemon with custom timeout
 func DoWorkerRequestWithTimeout(workerURL, path, token string, body []byte, timeoutSecs int) ([]byte, error) {
-	client := &http.Client{Timeout: time.Duration(timeoutSecs) * time.Second}
+	client := NewHTTPClient(time.Duration(timeoutSecs) * time.Second)
 
 	req, err := http.NewRequest("POST", workerURL+path, bytes.NewReader(body))
 	if err != nil {
@@ -382,7 +410,7 @@ func DoWorkerRequestWithTimeout(workerURL, path, token string, body []byte, time
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("worker error (%d): %s", resp.StatusCode, string(respBody))
+		return nil, &WorkerError{StatusCode: resp.StatusCode, Body: respBody}
 	}
 
 	return respBody, nil
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDryRunStopped is returned when a dry run reaches a step that is not
+// an HTTP call (SSH, rsync, a websocket session). The step is printed and
+// the command stops there, since nothing after it can be planned.
+var ErrDryRunStopped = errors.New("dry run stopped")
+
+// dryRunPlaceholderWorkerURL stands in for a sandbox's worker so commands
+// can plan the worker calls that follow a lookup.
+const dryRunPlaceholderWorkerURL = "https://worker.dry-run.invalid"
+
+var dryRunOut io.Writer
+
+// SetDryRun makes every HTTP call print to w instead of being sent. A nil
+// w turns dry-run mode off.
+func SetDryRun(w io.Writer) {
+	dryRunOut = w
+}
+
+// DryRun reports whether dry-run mode is on.
+func DryRun() bool {
+	return dryRunOut != nil
+}
+
+// PrintDryRunStep prints a step that is not an HTTP call, such as opening
+// a URL in the browser. detail, if set, is printed below it.
+func PrintDryRunStep(kind, target, detail string) {
+	fmt.Fprintf(dryRunOut, "%-6s %s\n", kind, redactURL(target))
+	if detail != "" {
+		fmt.Fprintf(dryRunOut, "       %s\n", detail)
+	}
+}
+
+// DryRunStep prints a step the command cannot continue past and returns
+// ErrDryRunStopped.
+func DryRunStep(kind, target, detail string) error {
+	PrintDryRunStep(kind, target, detail)
+	return ErrDryRunStopped
+}
+
+// NewHTTPClient returns an HTTP client with the given timeout that honors
+// dry-run mode.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if DryRun() {
+		client.Transport = dryRunTransport{}
+	}
+	return client
+}
+
+// dryRunTransport prints each request and answers it with an empty JSON
+// object.
+type dryRunTransport struct{}
+
+func (dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(dryRunOut, "%-6s %s\n", req.Method, redactURL(req.URL.String()))
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if body := redactBody(data); body != "" {
+			fmt.Fprintf(dryRunOut, "       %s\n", body)
+		}
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+// isSecretKey reports whether a JSON key or query parameter holds a
+// credential or user-supplied environment.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "tkn", "password", "secret", "apikey", "api_key", "envs"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	for key := range q {
+		if isSecretKey(key) {
+			q.Set(key, "REDACTED")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactBody returns a JSON body as one line with secret values replaced.
+// Non-JSON bodies are summarized by size.
+func redactBody(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Sprintf("<%d bytes>", len(data))
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v)); err != nil {
+		return fmt.Sprintf("<%d bytes>", len(data))
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			if isSecretKey(key) {
+				t[key] = "REDACTED"
+			} else {
+				t[key] = redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+// dryRunInstance is the placeholder a dry run returns for instance lookups.
+func dryRunInstance(id string) *Instance {
+	return &Instance{
+		ID:         id,
+		Status:     "running",
+		WorkerURL:  dryRunPlaceholderWorkerURL,
+		VSCodeURL:  dryRunPlaceholderWorkerURL + "/vscode",
+		VNCURL:     dryRunPlaceholderWorkerURL + "/vnc",
+		JupyterURL: dryRunPlaceholderWorkerURL + "/jupyter",
+	}
+}
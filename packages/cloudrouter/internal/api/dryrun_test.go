@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRunTransportPrintsRedactedRequests(t *testing.T) {
+	var out bytes.Buffer
+	SetDryRun(&out)
+	defer SetDryRun(nil)
+
+	c := &Client{baseURL: "https://api.example.com", httpClient: NewHTTPClient(time.Second)}
+	if _, err := c.CreateInstance(CreateInstanceRequest{
+		TeamSlugOrID: "acme",
+		Name:         "a&b",
+		Envs:         map[string]string{"OPENAI_API_KEY": "sk-live"},
+	}); err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	inst, err := c.GetInstance("acme", "cr_1")
+	if err != nil || inst.WorkerURL != dryRunPlaceholderWorkerURL {
+		t.Fatalf("GetInstance = %+v, %v", inst, err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"POST   https://api.example.com/api/v2/devbox/instances\n",
+		`"envs":"REDACTED"`,
+		`"name":"a&b"`,
+		"GET    https://api.example.com/api/v2/devbox/instances/cr_1?teamSlugOrId=acme\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "sk-live") {
+		t.Errorf("secret leaked:\n%s", got)
+	}
+}
+
+func TestDryRunStep(t *testing.T) {
+	var out bytes.Buffer
+	SetDryRun(&out)
+	defer SetDryRun(nil)
+
+	err := DryRunStep("WS", "wss://worker.example.com/pty?token=abc&cols=80", "")
+	if !errors.Is(err, ErrDryRunStopped) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := out.String(); got != "WS     wss://worker.example.com/pty?cols=80&token=REDACTED\n" {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -177,13 +177,6 @@ type Credentials struct {
 	StackRefreshToken string `json:"stack_refresh_token,omitempty"`
 }
 
-func StoreRefreshToken(token string) error {
-	if runtime.GOOS == "darwin" {
-		return storeInKeychain(token)
-	}
-	return storeInFile(token)
-}
-
 // getRefreshTokenFromEnv checks .env file first, then falls back to env var
 func getRefreshTokenFromEnv() string {
 	// Try loading from .env file first (same pattern as GetConfig)
@@ -209,46 +202,7 @@ func GetRefreshToken() (string, error) {
 	if token := getRefreshTokenFromEnv(); token != "" {
 		return token, nil
 	}
-	if runtime.GOOS == "darwin" {
-		return getFromKeychain()
-	}
-	return getFromFile()
-}
-
-func DeleteRefreshToken() error {
-	if runtime.GOOS == "darwin" {
-		return deleteFromKeychain()
-	}
-	return deleteFromFile()
-}
-
-func storeInKeychain(token string) error {
-	cfg := GetConfig()
-	account := fmt.Sprintf("STACK_REFRESH_TOKEN_%s", cfg.ProjectID)
-	_ = exec.Command("security", "delete-generic-password", "-s", KeychainService, "-a", account).Run()
-	cmd := exec.Command("security", "add-generic-password", "-s", KeychainService, "-a", account, "-w", token)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to store token in keychain: %w", err)
-	}
-	return nil
-}
-
-func getFromKeychain() (string, error) {
-	cfg := GetConfig()
-	account := fmt.Sprintf("STACK_REFRESH_TOKEN_%s", cfg.ProjectID)
-	cmd := exec.Command("security", "find-generic-password", "-s", KeychainService, "-a", account, "-w")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("token not found in keychain")
-	}
-	return strings.TrimSpace(string(output)), nil
-}
-
-func deleteFromKeychain() error {
-	cfg := GetConfig()
-	account := fmt.Sprintf("STACK_REFRESH_TOKEN_%s", cfg.ProjectID)
-	_ = exec.Command("security", "delete-generic-password", "-s", KeychainService, "-a", account).Run()
-	return nil
+	return getStoredRefreshToken()
 }
 
 func storeInFile(token string) error {
@@ -461,11 +415,10 @@ func Logout() error {
 	return nil
 }
 
-func GetAccessToken() (string, error) {
-	if token, err := GetCachedAccessToken(60); err == nil {
-		return token, nil
-	}
-
+// requestAccessToken exchanges the refresh token for a new access token
+// and caches it. Callers should go through refreshAccessToken so that
+// concurrent refreshes are coalesced.
+func requestAccessToken() (string, error) {
 	refreshToken, err := GetRefreshToken()
 	if err != nil {
 		return "", fmt.Errorf("not logged in. Run 'cloudrouter login' first")
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Credential store selection via CLOUDROUTER_CREDENTIAL_STORE:
+//
+//	auto (default)  OS store if available, else the credentials file
+//	keychain        OS store, warning and using the file if unavailable
+//	file            plaintext credentials file only
+const (
+	credentialStoreAuto     = "auto"
+	credentialStoreKeychain = "keychain"
+	credentialStoreFile     = "file"
+)
+
+// credentialStore persists the refresh token.
+type credentialStore interface {
+	name() string
+	get(account string) (string, error)
+	set(account, token string) error
+	delete(account string) error
+}
+
+// systemStore returns the OS credential store (macOS Keychain, Windows
+// Credential Manager or Linux secret-service), or nil if none is usable.
+// It is a variable so tests can substitute a fake.
+var systemStore = platformStore
+
+func refreshTokenAccount() string {
+	return fmt.Sprintf("STACK_REFRESH_TOKEN_%s", GetConfig().ProjectID)
+}
+
+// selectedStore returns the configured credential store.
+func selectedStore() credentialStore {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("CLOUDROUTER_CREDENTIAL_STORE")))
+	switch mode {
+	case "", credentialStoreAuto, credentialStoreKeychain:
+		if s := systemStore(); s != nil {
+			return s
+		}
+		if mode == credentialStoreKeychain {
+			fmt.Fprintln(os.Stderr, "Warning: no OS credential store available, storing credentials in a file")
+		}
+	case credentialStoreFile:
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: unknown CLOUDROUTER_CREDENTIAL_STORE %q, using auto\n", mode)
+		if s := systemStore(); s != nil {
+			return s
+		}
+	}
+	return fileStore{}
+}
+
+// StoreRefreshToken stores the refresh token in the selected store. If the
+// OS store fails, the token is written to the credentials file instead;
+// otherwise any plaintext copy is removed.
+func StoreRefreshToken(token string) error {
+	account := refreshTokenAccount()
+	store := selectedStore()
+	if _, isFile := store.(fileStore); isFile {
+		return storeInFile(token)
+	}
+	if err := store.set(account, token); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to store token in %s (%v), using credentials file\n", store.name(), err)
+		return storeInFile(token)
+	}
+	_ = deleteFromFile()
+	return nil
+}
+
+// getStoredRefreshToken reads the refresh token from the selected store.
+// A token found only in the credentials file is migrated into the OS
+// store on first use.
+func getStoredRefreshToken() (string, error) {
+	account := refreshTokenAccount()
+	store := selectedStore()
+	if _, isFile := store.(fileStore); isFile {
+		return getFromFile()
+	}
+	if token, err := store.get(account); err == nil && token != "" {
+		return token, nil
+	}
+
+	token, err := getFromFile()
+	if err != nil {
+		return "", err
+	}
+	if err := store.set(account, token); err == nil {
+		_ = deleteFromFile()
+	}
+	return token, nil
+}
+
+// DeleteRefreshToken removes the refresh token from every store.
+func DeleteRefreshToken() error {
+	if store := systemStore(); store != nil {
+		_ = store.delete(refreshTokenAccount())
+	}
+	return deleteFromFile()
+}
+
+// fileStore keeps the token in credentials.json.
+type fileStore struct{}
+
+func (fileStore) name() string                     { return "credentials file" }
+func (fileStore) get(string) (string, error)       { return getFromFile() }
+func (fileStore) set(_ string, token string) error { return storeInFile(token) }
+func (fileStore) delete(string) error              { return deleteFromFile() }
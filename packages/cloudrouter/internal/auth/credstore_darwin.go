@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore uses the macOS Keychain via the security tool.
+type keychainStore struct{}
+
+func platformStore() credentialStore {
+	return keychainStore{}
+}
+
+func (keychainStore) name() string { return "macOS Keychain" }
+
+func (keychainStore) set(account, token string) error {
+	_ = exec.Command("security", "delete-generic-password", "-s", KeychainService, "-a", account).Run()
+	cmd := exec.Command("security", "add-generic-password", "-s", KeychainService, "-a", account, "-w", token)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to store token in keychain: %w", err)
+	}
+	return nil
+}
+
+func (keychainStore) get(account string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", KeychainService, "-a", account, "-w")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token not found in keychain")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (keychainStore) delete(account string) error {
+	_ = exec.Command("security", "delete-generic-password", "-s", KeychainService, "-a", account).Run()
+	return nil
+}
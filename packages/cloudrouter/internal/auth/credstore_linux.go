@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// secretServiceStore uses the freedesktop secret-service (GNOME Keyring,
+// KWallet) via secret-tool.
+type secretServiceStore struct{}
+
+// platformStore returns the secret-service store when secret-tool is
+// installed and a session bus is running; headless hosts use the file.
+func platformStore() credentialStore {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return secretServiceStore{}
+}
+
+func (secretServiceStore) name() string { return "secret-service" }
+
+func (secretServiceStore) set(account, token string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "cloudrouter refresh token",
+		"service", KeychainService, "account", account)
+	cmd.Stdin = strings.NewReader(token)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (secretServiceStore) get(account string) (string, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", KeychainService, "account", account).Output()
+	token := strings.TrimSpace(string(output))
+	if err != nil || token == "" {
+		return "", fmt.Errorf("token not found in secret-service")
+	}
+	return token, nil
+}
+
+func (secretServiceStore) delete(account string) error {
+	_ = exec.Command("secret-tool", "clear", "service", KeychainService, "account", account).Run()
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !darwin && !linux && !windows
+
+package auth
+
+func platformStore() credentialStore {
+	return nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+// memStore is an in-memory credentialStore.
+type memStore struct {
+	tokens  map[string]string
+	failSet bool
+}
+
+func (m *memStore) name() string { return "memory" }
+
+func (m *memStore) get(account string) (string, error) {
+	if token, ok := m.tokens[account]; ok {
+		return token, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (m *memStore) set(account, token string) error {
+	if m.failSet {
+		return errors.New("store unavailable")
+	}
+	m.tokens[account] = token
+	return nil
+}
+
+func (m *memStore) delete(account string) error {
+	delete(m.tokens, account)
+	return nil
+}
+
+func useMemStore(t *testing.T) *memStore {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CLOUDROUTER_REFRESH_TOKEN", "")
+	t.Setenv("CLOUDROUTER_CREDENTIAL_STORE", "")
+	store := &memStore{tokens: map[string]string{}}
+	orig := systemStore
+	systemStore = func() credentialStore { return store }
+	t.Cleanup(func() { systemStore = orig })
+	return store
+}
+
+func TestStoreRefreshTokenUsesSystemStore(t *testing.T) {
+	store := useMemStore(t)
+
+	if err := StoreRefreshToken("tok-1"); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+	if store.tokens[refreshTokenAccount()] != "tok-1" {
+		t.Errorf("token not in system store: %v", store.tokens)
+	}
+	if _, err := getFromFile(); err == nil {
+		t.Error("token should not be written to the credentials file")
+	}
+
+	if err := DeleteRefreshToken(); err != nil {
+		t.Fatalf("DeleteRefreshToken: %v", err)
+	}
+	if _, err := GetRefreshToken(); err == nil {
+		t.Error("expected no token after delete")
+	}
+}
+
+func TestGetRefreshTokenMigratesFileToken(t *testing.T) {
+	store := useMemStore(t)
+	if err := storeInFile("legacy"); err != nil {
+		t.Fatalf("storeInFile: %v", err)
+	}
+
+	token, err := GetRefreshToken()
+	if err != nil || token != "legacy" {
+		t.Fatalf("GetRefreshToken = %q, %v", token, err)
+	}
+	if store.tokens[refreshTokenAccount()] != "legacy" {
+		t.Errorf("token was not migrated: %v", store.tokens)
+	}
+	if _, err := getFromFile(); err == nil {
+		t.Error("plaintext token should be removed after migration")
+	}
+}
+
+func TestStoreRefreshTokenFallsBackToFile(t *testing.T) {
+	store := useMemStore(t)
+	store.failSet = true
+
+	if err := StoreRefreshToken("tok-2"); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+	if token, err := getFromFile(); err != nil || token != "tok-2" {
+		t.Errorf("expected file fallback, got %q, %v", token, err)
+	}
+}
+
+func TestFileCredentialStoreSelected(t *testing.T) {
+	store := useMemStore(t)
+	t.Setenv("CLOUDROUTER_CREDENTIAL_STORE", "file")
+
+	if err := StoreRefreshToken("tok-3"); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+	if len(store.tokens) != 0 {
+		t.Errorf("system store used despite file mode: %v", store.tokens)
+	}
+	if token, err := GetRefreshToken(); err != nil || token != "tok-3" {
+		t.Errorf("GetRefreshToken = %q, %v", token, err)
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+// winCredential mirrors the Win32 CREDENTIALW struct.
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// wincredStore uses the Windows Credential Manager.
+type wincredStore struct{}
+
+func platformStore() credentialStore {
+	if advapi32.Load() != nil {
+		return nil
+	}
+	return wincredStore{}
+}
+
+func credentialTarget(account string) string {
+	return KeychainService + ":" + account
+}
+
+func (wincredStore) name() string { return "Windows Credential Manager" }
+
+func (wincredStore) set(account, token string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(token)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("failed to store token in credential manager: %w", err)
+	}
+	return nil
+}
+
+func (wincredStore) get(account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	if r, _, _ := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); r == 0 {
+		return "", fmt.Errorf("token not found in credential manager")
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", fmt.Errorf("token not found in credential manager")
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (wincredStore) delete(account string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTarget(account))
+	if err != nil {
+		return err
+	}
+	_, _, _ = procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	return nil
+}
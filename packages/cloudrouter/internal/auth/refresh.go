@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultRefreshWindow is how long before expiry an access token is
+// refreshed. Override with CLOUDROUTER_TOKEN_REFRESH_WINDOW (e.g. "10m").
+const defaultRefreshWindow = 5 * time.Minute
+
+// refreshLockTimeout bounds how long we wait for another process to
+// finish refreshing before refreshing anyway.
+const refreshLockTimeout = 30 * time.Second
+
+var (
+	// refreshMu coalesces refreshes within this process.
+	refreshMu sync.Mutex
+	// fetchAccessToken performs the network refresh; replaced in tests.
+	fetchAccessToken = requestAccessToken
+)
+
+func refreshWindow() time.Duration {
+	if v := os.Getenv("CLOUDROUTER_TOKEN_REFRESH_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultRefreshWindow
+}
+
+func getRefreshLockPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "refresh.lock"), nil
+}
+
+// GetAccessToken returns a cached access token, refreshing it first if it
+// expires within the refresh window.
+func GetAccessToken() (string, error) {
+	window := int64(refreshWindow().Seconds())
+	if token, err := GetCachedAccessToken(window); err == nil {
+		return token, nil
+	}
+	return refreshAccessToken(window)
+}
+
+// refreshAccessToken refreshes the access token at most once at a time
+// across goroutines and processes. Whoever waits re-reads the cache after
+// acquiring the lock, so a refresh done by another caller is reused
+// instead of hitting the refresh endpoint again.
+func refreshAccessToken(window int64) (string, error) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	if path, err := getRefreshLockPath(); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+			if unlock, err := lockFile(path, refreshLockTimeout); err == nil {
+				defer unlock()
+			}
+		}
+	}
+
+	if token, err := GetCachedAccessToken(window); err == nil {
+		return token, nil
+	}
+
+	token, err := fetchAccessToken()
+	if err != nil {
+		// A proactive refresh failed but the old token still works.
+		if cached, cacheErr := GetCachedAccessToken(60); cacheErr == nil {
+			return cached, nil
+		}
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubFetchAccessToken(t *testing.T, fn func() (string, error)) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CLOUDROUTER_TOKEN_REFRESH_WINDOW", "")
+	orig := fetchAccessToken
+	fetchAccessToken = fn
+	t.Cleanup(func() { fetchAccessToken = orig })
+}
+
+func TestGetAccessTokenSingleFlight(t *testing.T) {
+	var calls atomic.Int32
+	stubFetchAccessToken(t, func() (string, error) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		token := "fresh"
+		return token, CacheAccessToken(token, time.Now().Add(time.Hour).Unix())
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := GetAccessToken(); err != nil || token != "fresh" {
+				t.Errorf("GetAccessToken = %q, %v", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected a single refresh, got %d", n)
+	}
+}
+
+func TestGetAccessTokenRefreshesBeforeExpiry(t *testing.T) {
+	stubFetchAccessToken(t, func() (string, error) {
+		return "fresh", CacheAccessToken("fresh", time.Now().Add(time.Hour).Unix())
+	})
+
+	// Valid for two more minutes: inside the default five minute window.
+	if err := CacheAccessToken("old", time.Now().Add(2*time.Minute).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := GetAccessToken(); err != nil || token != "fresh" {
+		t.Errorf("GetAccessToken = %q, %v", token, err)
+	}
+}
+
+func TestGetAccessTokenKeepsValidTokenWhenRefreshFails(t *testing.T) {
+	stubFetchAccessToken(t, func() (string, error) {
+		return "", errors.New("refresh endpoint down")
+	})
+
+	if err := CacheAccessToken("old", time.Now().Add(2*time.Minute).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := GetAccessToken(); err != nil || token != "old" {
+		t.Errorf("GetAccessToken = %q, %v", token, err)
+	}
+
+	if err := ClearCachedAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetAccessToken(); err == nil {
+		t.Error("expected an error with no usable token")
+	}
+}
+
+func TestLockFileExcludes(t *testing.T) {
+	path := t.TempDir() + "/refresh.lock"
+	unlock, err := lockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("lockFile: %v", err)
+	}
+	if _, err := lockFile(path, 100*time.Millisecond); err == nil {
+		t.Error("second lock should time out while the first is held")
+	}
+	unlock()
+
+	unlock, err = lockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("lockFile after unlock: %v", err)
+	}
+	unlock()
+}
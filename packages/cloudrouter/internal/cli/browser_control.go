@@ -0,0 +1,143 @@
+// internal/cli/browser_control.go
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karlorz/cloudrouter/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// browserControlState mirrors the worker's /_cmux/browser/control response.
+type browserControlState struct {
+	Driver string `json:"driver"`
+	Paused bool   `json:"paused"`
+	Since  int64  `json:"since,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// resolveWorker returns the worker URL and auth token for a sandbox.
+func resolveWorker(sandboxID string) (string, string, error) {
+	teamSlug, err := getTeamSlug()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get team: %w", err)
+	}
+
+	client := api.NewClient()
+	inst, err := client.GetInstance(teamSlug, sandboxID)
+	if err != nil {
+		return "", "", fmt.Errorf("sandbox not found: %w", err)
+	}
+
+	if inst.WorkerURL == "" {
+		return "", "", fmt.Errorf("worker URL not available")
+	}
+
+	token, err := client.GetAuthToken(teamSlug, sandboxID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	return inst.WorkerURL, token, nil
+}
+
+// browserControlRequest posts a control action to the worker.
+func browserControlRequest(workerURL, token string, body map[string]string) (*browserControlState, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := api.DoWorkerRequestWithTimeout(workerURL, "/_cmux/browser/control", token, payload, 15)
+	if err != nil {
+		return nil, err
+	}
+
+	var state browserControlState
+	if err := json.Unmarshal(respBody, &state); err != nil {
+		return nil, fmt.Errorf("invalid control response: %w", err)
+	}
+	return &state, nil
+}
+
+// checkBrowserNotPaused refuses to drive the browser while a human holds
+// control. Workers without the control endpoint are treated as unpaused.
+func checkBrowserNotPaused(workerURL, token string) error {
+	state, err := browserControlRequest(workerURL, token, map[string]string{"action": "status"})
+	if err != nil || !state.Paused {
+		return nil
+	}
+	return fmt.Errorf("browser is under human control%s; run 'cloudrouter browser control <id> give' to hand it back", formatControlSince(state.Since))
+}
+
+func formatControlSince(since int64) string {
+	if since <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" since %s", time.UnixMilli(since).Format(time.Kitchen))
+}
+
+func printBrowserControlState(state *browserControlState) {
+	driver := state.Driver
+	if driver == "" {
+		driver = "none"
+	}
+	fmt.Printf("Driver: %s%s\n", driver, formatControlSince(state.Since))
+	if state.Paused {
+		fmt.Println("Agent:  paused (human has control)")
+	} else {
+		fmt.Println("Agent:  allowed")
+	}
+	if state.Reason != "" {
+		fmt.Printf("Reason: %s\n", state.Reason)
+	}
+}
+
+var browserControlCmd = &cobra.Command{
+	Use:   "control <id> <take|give|status>",
+	Short: "Take or give back control of the browser",
+	Long: `Coordinate browser control between you (via VNC) and an agent.
+
+'take' makes you the driver: agent browser commands are paused and fail
+until you 'give' control back. 'status' shows the current driver.
+
+Examples:
+  cloudrouter browser control cr_abc123 take
+  cloudrouter browser control cr_abc123 take --reason "fixing login"
+  cloudrouter browser control cr_abc123 give
+  cloudrouter browser control cr_abc123 status`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := strings.ToLower(args[1])
+		if action != "take" && action != "give" && action != "status" {
+			return fmt.Errorf("unknown action %q (expected take, give, or status)", args[1])
+		}
+
+		workerURL, token, err := resolveWorker(args[0])
+		if err != nil {
+			return err
+		}
+
+		reason, _ := cmd.Flags().GetString("reason")
+		state, err := browserControlRequest(workerURL, token, map[string]string{
+			"action": action,
+			"actor":  "human",
+			"reason": reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		printBrowserControlState(state)
+		return nil
+	},
+}
+
+func init() {
+	browserControlCmd.Flags().String("reason", "", "Why you are taking control (shown to the agent)")
+	browserCmd.AddCommand(browserControlCmd)
+}
@@ -0,0 +1,360 @@
+// internal/cli/browser_native.go
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlorz/cloudrouter/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// browserCommandPath is the worker's native browser endpoint. It drives
+// Chrome over CDP directly, so element refs survive between commands and
+// failures come back with a stable code instead of agent-browser's stderr.
+const browserCommandPath = "/_cmux/browser"
+
+// browserHTTPSlack is added to the command timeout for the HTTP request, so
+// the worker's own timeout error (with page diagnostics) arrives first.
+const browserHTTPSlack = 15 * time.Second
+
+// defaultBrowserTimeout matches the worker's default per-command timeout.
+const defaultBrowserTimeout = 30 * time.Second
+
+var (
+	flagBrowserTimeout time.Duration
+	flagBrowserTarget  string
+)
+
+// browserCommandError is a failed /_cmux/browser command. Code is the worker's
+// error code, e.g. "stale_ref", "element_not_found" or "timeout".
+type browserCommandError struct {
+	Command string
+	Code    string
+	Message string
+}
+
+func (e *browserCommandError) Error() string {
+	switch e.Code {
+	case "":
+		return fmt.Sprintf("browser %s failed: %s", e.Command, e.Message)
+	case "stale_ref":
+		return fmt.Sprintf("stale ref: %s (run 'cloudrouter browser snapshot' for fresh refs)", e.Message)
+	}
+	return fmt.Sprintf("browser %s failed (%s): %s", e.Command, e.Code, e.Message)
+}
+
+// runBrowserCommand runs a native browser command in a sandbox. The worker
+// refuses commands that change the page while a human holds control.
+func runBrowserCommand(sandboxID, command string, params map[string]interface{}) (map[string]interface{}, error) {
+	workerURL, token, err := resolveWorker(sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	return postBrowserCommand(workerURL, token, command, params)
+}
+
+// postBrowserCommand sends one command to the worker's /_cmux/browser endpoint,
+// adding the --timeout and --target flags.
+func postBrowserCommand(workerURL, token, command string, params map[string]interface{}) (map[string]interface{}, error) {
+	body := map[string]interface{}{"command": command}
+	for k, v := range params {
+		body[k] = v
+	}
+	timeout := defaultBrowserTimeout
+	if flagBrowserTimeout > 0 {
+		timeout = flagBrowserTimeout
+		body["timeout"] = flagBrowserTimeout.Milliseconds()
+	}
+	if flagBrowserTarget != "" {
+		body["target"] = flagBrowserTarget
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if flagVerbose {
+		fmt.Fprintf(os.Stderr, "[debug] POST %s %s\n", browserCommandPath, payload)
+	}
+	respBody, err := api.DoWorkerRequestWithTimeout(workerURL, browserCommandPath, token, payload, int((timeout + browserHTTPSlack).Seconds()))
+	if err != nil {
+		var we *api.WorkerError
+		if errors.As(err, &we) {
+			return nil, parseBrowserError(command, we)
+		}
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid browser response: %w", err)
+	}
+	return result, nil
+}
+
+// parseBrowserError turns the worker's {"error", "code"} body into a
+// browserCommandError, keeping the raw error for anything else.
+func parseBrowserError(command string, we *api.WorkerError) error {
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.Unmarshal(we.Body, &body); err != nil || body.Error == "" {
+		return we
+	}
+	return &browserCommandError{Command: command, Code: body.Code, Message: body.Error}
+}
+
+// resultString returns a string field of a command result.
+func resultString(result map[string]interface{}, key string) string {
+	s, _ := result[key].(string)
+	return s
+}
+
+// printJSONValue prints a JSON value from a command result: strings as-is,
+// anything else as indented JSON.
+func printJSONValue(v interface{}) {
+	if s, ok := v.(string); ok {
+		fmt.Println(s)
+		return
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Println(v)
+		return
+	}
+	fmt.Println(string(out))
+}
+
+// =============================================================================
+// Assertions
+// =============================================================================
+
+var browserAssertCmd = &cobra.Command{
+	Use:   "assert",
+	Short: "Check the page and fail if the check does not hold",
+	Long: `Assert on the page's state. A failed assertion prints what was expected
+and what was found, and exits non-zero.
+
+Examples:
+  cloudrouter browser assert visible cr_abc123 "text=Welcome"
+  cloudrouter browser assert visible cr_abc123 "#spinner" --hidden
+  cloudrouter browser assert text cr_abc123 h1 "Dashboard"
+  cloudrouter browser assert url cr_abc123 --contains /dashboard
+  cloudrouter browser assert count cr_abc123 ".row" --min 1`,
+}
+
+// runBrowserAssert runs an assert.* command and reports its outcome.
+func runBrowserAssert(sandboxID, command string, params map[string]interface{}) error {
+	result, err := runBrowserCommand(sandboxID, command, params)
+	if err != nil {
+		return err
+	}
+	if pass, _ := result["pass"].(bool); pass {
+		fmt.Println("PASS")
+		return nil
+	}
+	expected, _ := json.Marshal(result["expected"])
+	actual, _ := json.Marshal(result["actual"])
+	fmt.Printf("Expected: %s\nActual:   %s\n", expected, actual)
+	if diff, ok := result["diff"]; ok {
+		out, _ := json.Marshal(diff)
+		fmt.Printf("Diff:     %s\n", out)
+	}
+	return fmt.Errorf("assertion failed: %s", resultString(result, "message"))
+}
+
+var browserAssertVisibleCmd = &cobra.Command{
+	Use:   "visible <id> <selector>",
+	Short: "Assert an element is visible (or hidden with --hidden)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hidden, _ := cmd.Flags().GetBool("hidden")
+		return runBrowserAssert(args[0], "assert.visible", map[string]interface{}{"selector": args[1], "visible": !hidden})
+	},
+}
+
+var browserAssertTextCmd = &cobra.Command{
+	Use:   "text <id> <selector> <text>",
+	Short: "Assert an element's text contains (or with --exact equals) text",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exact, _ := cmd.Flags().GetBool("exact")
+		return runBrowserAssert(args[0], "assert.text", map[string]interface{}{"selector": args[1], "text": args[2], "exact": exact})
+	},
+}
+
+var browserAssertURLCmd = &cobra.Command{
+	Use:   "url <id> [url]",
+	Short: "Assert the page URL equals url, or matches --contains or --pattern",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := map[string]interface{}{}
+		if len(args) > 1 {
+			params["url"] = args[1]
+		}
+		if v, _ := cmd.Flags().GetString("contains"); v != "" {
+			params["contains"] = v
+		}
+		if v, _ := cmd.Flags().GetString("pattern"); v != "" {
+			params["pattern"] = v
+		}
+		return runBrowserAssert(args[0], "assert.url", params)
+	},
+}
+
+var browserAssertCountCmd = &cobra.Command{
+	Use:   "count <id> <selector> [count]",
+	Short: "Assert how many elements match, exactly or with --min/--max",
+	Args:  cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := map[string]interface{}{"selector": args[1]}
+		if len(args) > 2 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid count %q", args[2])
+			}
+			params["count"] = n
+		}
+		if cmd.Flags().Changed("min") {
+			v, _ := cmd.Flags().GetInt("min")
+			params["min"] = v
+		}
+		if cmd.Flags().Changed("max") {
+			v, _ := cmd.Flags().GetInt("max")
+			params["max"] = v
+		}
+		return runBrowserAssert(args[0], "assert.count", params)
+	},
+}
+
+// =============================================================================
+// Network capture and downloads
+// =============================================================================
+
+var browserNetworkStartCmd = &cobra.Command{
+	Use:   "network-start <id>",
+	Short: "Start capturing network requests",
+	Long: `Start capturing the page's network requests for network-requests.
+--block fails requests whose URL matches a pattern (* wildcards, repeatable).
+
+Examples:
+  cloudrouter browser network-start cr_abc123
+  cloudrouter browser network-start cr_abc123 --block "*.doubleclick.net/*"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := map[string]interface{}{}
+		if block, _ := cmd.Flags().GetStringArray("block"); len(block) > 0 {
+			params["block"] = block
+		}
+		if _, err := runBrowserCommand(args[0], "network.start", params); err != nil {
+			return err
+		}
+		fmt.Println("Network capture started")
+		return nil
+	},
+}
+
+var browserNetworkStopCmd = &cobra.Command{
+	Use:   "network-stop <id>",
+	Short: "Stop capturing network requests and unblock URLs",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := runBrowserCommand(args[0], "network.stop", nil); err != nil {
+			return err
+		}
+		fmt.Println("Network capture stopped")
+		return nil
+	},
+}
+
+var browserDownloadsCmd = &cobra.Command{
+	Use:   "downloads <id>",
+	Short: "List files the browser finished downloading",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		result, err := runBrowserCommand(args[0], "downloads", map[string]interface{}{"all": all})
+		if err != nil {
+			return err
+		}
+		items, _ := result["downloads"].([]interface{})
+		if len(items) == 0 {
+			fmt.Println("No downloads")
+			return nil
+		}
+		for _, item := range items {
+			dl, _ := item.(map[string]interface{})
+			fmt.Printf("%-11s %s  (%s)\n", resultString(dl, "state"), resultString(dl, "path"), resultString(dl, "url"))
+		}
+		return nil
+	},
+}
+
+// printBrowserRequests prints network.requests entries one per line.
+func printBrowserRequests(result map[string]interface{}) {
+	items, _ := result["requests"].([]interface{})
+	if len(items) == 0 {
+		if capturing, _ := result["capturing"].(bool); !capturing {
+			fmt.Println("Not capturing; run 'cloudrouter browser network-start' first")
+		} else {
+			fmt.Println("No requests")
+		}
+		return
+	}
+	for _, item := range items {
+		req, _ := item.(map[string]interface{})
+		status := "..."
+		switch {
+		case req["blocked"] == true:
+			status = "BLOCKED"
+		case req["failed"] == true:
+			status = "FAILED"
+		case req["status"] != nil:
+			status = fmt.Sprint(req["status"])
+		}
+		fmt.Printf("%-6s %-7s %s\n", resultString(req, "method"), status, resultString(req, "url"))
+	}
+}
+
+// printBrowserConsole prints console entries as "[level] text".
+func printBrowserConsole(result map[string]interface{}) {
+	items, _ := result["entries"].([]interface{})
+	for _, item := range items {
+		e, _ := item.(map[string]interface{})
+		line := fmt.Sprintf("[%s] %s", resultString(e, "level"), resultString(e, "text"))
+		if url := resultString(e, "url"); url != "" {
+			line += fmt.Sprintf(" (%s:%v)", url, e["line"])
+		}
+		fmt.Println(strings.TrimRight(line, "\n"))
+	}
+}
+
+func init() {
+	browserCmd.PersistentFlags().DurationVar(&flagBrowserTimeout, "timeout", 0, "Time limit for the browser command (default 30s)")
+	browserCmd.PersistentFlags().StringVar(&flagBrowserTarget, "target", "", "Page target ID to act on (default: the first page)")
+
+	browserAssertVisibleCmd.Flags().Bool("hidden", false, "Assert the element is hidden or absent instead")
+	browserAssertTextCmd.Flags().Bool("exact", false, "Require the whole text to match")
+	browserAssertURLCmd.Flags().String("contains", "", "Substring the URL must contain")
+	browserAssertURLCmd.Flags().String("pattern", "", "Regular expression the URL must match")
+	browserAssertCountCmd.Flags().Int("min", 0, "Minimum number of matches")
+	browserAssertCountCmd.Flags().Int("max", 0, "Maximum number of matches")
+	browserNetworkStartCmd.Flags().StringArray("block", nil, "URL pattern to block (repeatable)")
+	browserDownloadsCmd.Flags().Bool("all", false, "Include in-progress and canceled downloads")
+
+	browserAssertCmd.AddCommand(browserAssertVisibleCmd)
+	browserAssertCmd.AddCommand(browserAssertTextCmd)
+	browserAssertCmd.AddCommand(browserAssertURLCmd)
+	browserAssertCmd.AddCommand(browserAssertCountCmd)
+	browserCmd.AddCommand(browserAssertCmd)
+	browserCmd.AddCommand(browserNetworkStartCmd)
+	browserCmd.AddCommand(browserNetworkStopCmd)
+	browserCmd.AddCommand(browserDownloadsCmd)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostBrowserCommand(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != browserCommandPath || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"success": true, "x": 10, "y": 20}`))
+	}))
+	defer server.Close()
+
+	flagBrowserTimeout, flagBrowserTarget = 5*time.Second, "T1"
+	defer func() { flagBrowserTimeout, flagBrowserTarget = 0, "" }()
+
+	result, err := postBrowserCommand(server.URL, "tok", "click", map[string]interface{}{"selector": "@e3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["x"] != float64(10) {
+		t.Errorf("result = %v", result)
+	}
+	want := map[string]interface{}{"command": "click", "selector": "@e3", "timeout": float64(5000), "target": "T1"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("request %s = %v, want %v (body %v)", k, got[k], v, got)
+		}
+	}
+}
+
+func TestPostBrowserCommandStaleRef(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error": "ref @e3 is stale (node was removed); take a new snapshot", "code": "stale_ref"}`))
+	}))
+	defer server.Close()
+
+	_, err := postBrowserCommand(server.URL, "tok", "click", map[string]interface{}{"selector": "@e3"})
+	var be *browserCommandError
+	if !errors.As(err, &be) || be.Code != "stale_ref" {
+		t.Fatalf("error = %v, want a stale_ref browserCommandError", err)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "stale ref: ref @e3 is stale") || !strings.Contains(msg, "browser snapshot") {
+		t.Errorf("error message = %q", msg)
+	}
+}
+
+func TestPostBrowserCommandPausedByHuman(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error": "browser is under human control", "control": {"driver": "human"}}`))
+	}))
+	defer server.Close()
+
+	_, err := postBrowserCommand(server.URL, "tok", "open", map[string]interface{}{"url": "https://example.com"})
+	if err == nil || err.Error() != "browser open failed: browser is under human control" {
+		t.Errorf("error = %v", err)
+	}
+}
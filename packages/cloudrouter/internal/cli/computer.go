@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,11 +19,18 @@ import (
 
 var browserCmd = &cobra.Command{
 	Use:   "browser",
-	Short: "Browser automation commands (wraps agent-browser)",
-	Long: `Control the browser in a sandbox via agent-browser CLI.
+	Short: "Browser automation commands",
+	Long: `Control the Chrome browser running in a sandbox's VNC desktop.
 
-These commands allow you to automate the Chrome browser running in the VNC desktop.
-Under the hood, each command runs "agent-browser <command>" inside the sandbox via SSH.
+Navigation, snapshots, clicks, fills, waits, evals, screenshots, PDFs,
+assertions, console and network capture go through the worker's native
+/_cmux/browser endpoint. Refs from a snapshot (@e1, @e2) stay valid until the
+element changes; a ref whose element changed fails with "stale ref" rather
+than acting on the wrong element, so take a new snapshot. Commands without
+a native equivalent run "agent-browser <command>" inside the sandbox via SSH.
+
+Selectors are refs, CSS, or prefixed with xpath= or text= (text="Sign in"
+matches the whole text exactly). --timeout bounds each command.
 
 Examples:
   cloudrouter browser snapshot cr_abc123              # Get accessibility tree
@@ -42,6 +50,9 @@ func shellQuote(s string) string {
 // runSSHCommand runs a command inside the sandbox via SSH over WebSocket tunnel.
 // It prefers websocat (bidirectional), then curl with WebSocket, then Go WebSocket bridge.
 func runSSHCommand(workerURL, token, command string) (string, string, int, error) {
+	if api.DryRun() {
+		return "", "", -1, dryRunWorkerStep("SSH", workerURL, "$ "+command)
+	}
 	wsURL := strings.Replace(workerURL, "https://", "wss://", 1)
 	wsURL = strings.Replace(wsURL, "http://", "ws://", 1)
 	wsURL = wsURL + "/ssh?token=" + url.QueryEscape(token)
@@ -347,24 +358,13 @@ func filterSSHWarnings(stderr string) string {
 
 // execAgentBrowser runs "agent-browser --cdp 9222 <args...>" inside the sandbox via SSH.
 func execAgentBrowser(sandboxID string, args ...string) (string, error) {
-	teamSlug, err := getTeamSlug()
-	if err != nil {
-		return "", fmt.Errorf("failed to get team: %w", err)
-	}
-
-	client := api.NewClient()
-	inst, err := client.GetInstance(teamSlug, sandboxID)
+	workerURL, token, err := resolveWorker(sandboxID)
 	if err != nil {
-		return "", fmt.Errorf("sandbox not found: %w", err)
-	}
-
-	if inst.WorkerURL == "" {
-		return "", fmt.Errorf("worker URL not available")
+		return "", err
 	}
 
-	token, err := client.GetAuthToken(teamSlug, sandboxID)
-	if err != nil {
-		return "", fmt.Errorf("failed to get auth token: %w", err)
+	if err := checkBrowserNotPaused(workerURL, token); err != nil {
+		return "", err
 	}
 
 	// Build shell command: agent-browser --cdp 9222 arg1 arg2 ...
@@ -379,7 +379,7 @@ func execAgentBrowser(sandboxID string, args ...string) (string, error) {
 		fmt.Fprintf(os.Stderr, "[debug] SSH command: %s\n", cmdStr)
 	}
 
-	stdout, stderr, exitCode, err := runSSHCommand(inst.WorkerURL, token, cmdStr)
+	stdout, stderr, exitCode, err := runSSHCommand(workerURL, token, cmdStr)
 	if err != nil {
 		return "", err
 	}
@@ -398,57 +398,6 @@ func execAgentBrowser(sandboxID string, args ...string) (string, error) {
 	return strings.TrimSpace(stdout), nil
 }
 
-// execScreenshotCommand takes a screenshot via SSH and returns base64-encoded PNG.
-func execScreenshotCommand(sandboxID string) (string, error) {
-	teamSlug, err := getTeamSlug()
-	if err != nil {
-		return "", fmt.Errorf("failed to get team: %w", err)
-	}
-
-	client := api.NewClient()
-	inst, err := client.GetInstance(teamSlug, sandboxID)
-	if err != nil {
-		return "", fmt.Errorf("sandbox not found: %w", err)
-	}
-
-	if inst.WorkerURL == "" {
-		return "", fmt.Errorf("worker URL not available")
-	}
-
-	token, err := client.GetAuthToken(teamSlug, sandboxID)
-	if err != nil {
-		return "", fmt.Errorf("failed to get auth token: %w", err)
-	}
-
-	// Take screenshot and base64 encode it in one SSH command
-	cmdStr := "agent-browser --cdp 9222 screenshot /tmp/screenshot.png && base64 /tmp/screenshot.png"
-
-	stdout, stderr, exitCode, err := runSSHCommand(inst.WorkerURL, token, cmdStr)
-	if err != nil {
-		return "", err
-	}
-
-	if exitCode != 0 {
-		msg := strings.TrimSpace(stderr)
-		if msg == "" {
-			msg = strings.TrimSpace(stdout)
-		}
-		return "", fmt.Errorf("screenshot failed: %s", msg)
-	}
-
-	// The output contains agent-browser status line(s) followed by base64 data from the base64 command.
-	// Find where the base64 data starts (it begins with the PNG base64 prefix "iVBOR").
-	b64 := strings.TrimSpace(stdout)
-	if idx := strings.Index(b64, "iVBOR"); idx > 0 {
-		b64 = b64[idx:]
-	}
-	// Remove any embedded newlines from the base64 command output
-	b64 = strings.ReplaceAll(b64, "\n", "")
-	b64 = strings.ReplaceAll(b64, "\r", "")
-
-	return b64, nil
-}
-
 // =============================================================================
 // Navigation Commands
 // =============================================================================
@@ -461,21 +410,34 @@ Each element is assigned a ref (e.g., @e1, @e2) that can be used with click, typ
 
 Examples:
   cloudrouter browser snapshot cr_abc123
-  cloudrouter browser snapshot -i cr_abc123        # Interactive elements only`,
+  cloudrouter browser snapshot -i cr_abc123        # Interactive elements only
+  cloudrouter browser snapshot cr_abc123 --selector "#main" --depth 3`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		abArgs := []string{"snapshot"}
+		params := map[string]interface{}{}
 		if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
-			abArgs = append(abArgs, "-i")
+			params["interactive"] = true
 		}
-		if compact, _ := cmd.Flags().GetBool("compact"); compact {
-			abArgs = append(abArgs, "-C")
+		if selector, _ := cmd.Flags().GetString("selector"); selector != "" {
+			params["selector"] = selector
 		}
-		out, err := execAgentBrowser(args[0], abArgs...)
+		if depth, _ := cmd.Flags().GetInt("depth"); depth > 0 {
+			params["depth"] = depth
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
+			params["limit"] = limit
+		}
+		if cursor, _ := cmd.Flags().GetString("cursor"); cursor != "" {
+			params["cursor"] = cursor
+		}
+		result, err := runBrowserCommand(args[0], "snapshot", params)
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		fmt.Println(resultString(result, "snapshot"))
+		if cursor := resultString(result, "cursor"); cursor != "" {
+			fmt.Fprintf(os.Stderr, "Snapshot truncated; continue with --cursor %s\n", cursor)
+		}
 		return nil
 	},
 }
@@ -485,11 +447,11 @@ var browserOpenCmd = &cobra.Command{
 	Short: "Navigate browser to URL",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "open", args[1])
+		result, err := runBrowserCommand(args[0], "open", map[string]interface{}{"url": args[1]})
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Navigated to: %s\n", args[1])
+		fmt.Printf("Navigated to: %s\n", resultString(result, "url"))
 		return nil
 	},
 }
@@ -499,7 +461,7 @@ var browserBackCmd = &cobra.Command{
 	Short: "Navigate back in history",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "back")
+		_, err := runBrowserCommand(args[0], "back", nil)
 		if err != nil {
 			return err
 		}
@@ -513,7 +475,7 @@ var browserForwardCmd = &cobra.Command{
 	Short: "Navigate forward in history",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "forward")
+		_, err := runBrowserCommand(args[0], "forward", nil)
 		if err != nil {
 			return err
 		}
@@ -527,7 +489,7 @@ var browserReloadCmd = &cobra.Command{
 	Short: "Reload the current page",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "reload")
+		_, err := runBrowserCommand(args[0], "reload", nil)
 		if err != nil {
 			return err
 		}
@@ -564,7 +526,7 @@ Examples:
   cloudrouter browser click cr_abc123 "#submit"`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "click", args[1])
+		_, err := runBrowserCommand(args[0], "click", map[string]interface{}{"selector": args[1]})
 		if err != nil {
 			return err
 		}
@@ -578,7 +540,7 @@ var browserDblclickCmd = &cobra.Command{
 	Short: "Double-click an element",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "dblclick", args[1])
+		_, err := runBrowserCommand(args[0], "click", map[string]interface{}{"selector": args[1], "clickCount": 2})
 		if err != nil {
 			return err
 		}
@@ -611,7 +573,7 @@ var browserFillCmd = &cobra.Command{
 	Short: "Clear and fill an input field",
 	Args:  cobra.ExactArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "fill", args[1], args[2])
+		_, err := runBrowserCommand(args[0], "fill", map[string]interface{}{"selector": args[1], "value": args[2]})
 		if err != nil {
 			return err
 		}
@@ -672,7 +634,7 @@ var browserHoverCmd = &cobra.Command{
 	Short: "Hover over an element",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "hover", args[1])
+		_, err := runBrowserCommand(args[0], "hover", map[string]interface{}{"selector": args[1]})
 		if err != nil {
 			return err
 		}
@@ -686,7 +648,7 @@ var browserFocusCmd = &cobra.Command{
 	Short: "Focus an element",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "focus", args[1])
+		_, err := runBrowserCommand(args[0], "focus", map[string]interface{}{"selector": args[1]})
 		if err != nil {
 			return err
 		}
@@ -785,11 +747,13 @@ var browserDragCmd = &cobra.Command{
 }
 
 var browserUploadCmd = &cobra.Command{
-	Use:   "upload <id> <selector> <files>",
-	Short: "Upload files to a file input",
-	Args:  cobra.ExactArgs(3),
+	Use:   "upload <id> <selector> <file>...",
+	Short: "Set the files of a file input",
+	Long: `Set the files of a file input. Files are paths inside the sandbox,
+relative to the workspace unless absolute.`,
+	Args: cobra.MinimumNArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "upload", args[1], args[2])
+		_, err := runBrowserCommand(args[0], "setFiles", map[string]interface{}{"selector": args[1], "files": args[2:]})
 		if err != nil {
 			return err
 		}
@@ -807,11 +771,11 @@ var browserURLCmd = &cobra.Command{
 	Short: "Get current page URL",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		out, err := execAgentBrowser(args[0], "get", "url")
+		result, err := runBrowserCommand(args[0], "url", nil)
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		fmt.Println(resultString(result, "url"))
 		return nil
 	},
 }
@@ -821,11 +785,11 @@ var browserTitleCmd = &cobra.Command{
 	Short: "Get current page title",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		out, err := execAgentBrowser(args[0], "get", "title")
+		result, err := runBrowserCommand(args[0], "title", nil)
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		fmt.Println(resultString(result, "title"))
 		return nil
 	},
 }
@@ -923,11 +887,12 @@ var browserIsVisibleCmd = &cobra.Command{
 	Short: "Check if element is visible",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		out, err := execAgentBrowser(args[0], "is", "visible", args[1])
+		result, err := runBrowserCommand(args[0], "assert.visible", map[string]interface{}{"selector": args[1]})
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		actual, _ := result["actual"].(map[string]interface{})
+		fmt.Println(actual["visible"] == true)
 		return nil
 	},
 }
@@ -968,13 +933,23 @@ var browserScreenshotCmd = &cobra.Command{
 	Use:   "screenshot <id> [output-file]",
 	Short: "Take a screenshot",
 	Long: `Take a screenshot of the current browser state.
-If output file is not specified, outputs base64-encoded PNG to stdout.`,
+If output file is not specified, outputs base64-encoded PNG to stdout.
+--full captures the whole page; --selector captures one element.`,
 	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		b64Data, err := execScreenshotCommand(args[0])
+		params := map[string]interface{}{}
+		if full, _ := cmd.Flags().GetBool("full"); full {
+			params["fullPage"] = true
+		}
+		if selector, _ := cmd.Flags().GetString("selector"); selector != "" {
+			params["selector"] = selector
+		}
+		result, err := runBrowserCommand(args[0], "screenshot", params)
 		if err != nil {
 			return err
 		}
+		data, _ := result["data"].(map[string]interface{})
+		b64Data, _ := data["base64"].(string)
 
 		if len(args) > 1 {
 			data, err := base64.StdEncoding.DecodeString(b64Data)
@@ -993,17 +968,22 @@ If output file is not specified, outputs base64-encoded PNG to stdout.`,
 }
 
 var browserPDFCmd = &cobra.Command{
-	Use:   "pdf <id> [output-file]",
-	Short: "Save page as PDF",
+	Use:   "pdf <id> [remote-path]",
+	Short: "Save page as PDF inside the sandbox",
 	Args:  cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Generate PDF on remote
-		remotePath := "/tmp/page.pdf"
-		_, err := execAgentBrowser(args[0], "pdf", remotePath)
+		params := map[string]interface{}{}
+		if len(args) > 1 {
+			params["path"] = args[1]
+		}
+		if landscape, _ := cmd.Flags().GetBool("landscape"); landscape {
+			params["landscape"] = true
+		}
+		result, err := runBrowserCommand(args[0], "pdf", params)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("PDF saved on remote: %s\n", remotePath)
+		fmt.Printf("PDF saved on remote: %s\n", resultString(result, "path"))
 		return nil
 	},
 }
@@ -1029,23 +1009,26 @@ var browserHighlightCmd = &cobra.Command{
 var browserWaitCmd = &cobra.Command{
 	Use:   "wait <id> <selector-or-ms>",
 	Short: "Wait for an element or time",
-	Long: `Wait for an element to be visible, or wait a number of milliseconds.
+	Long: `Wait for an element to appear, or wait a number of milliseconds.
+Waiting for an element gives up after --timeout (default 30s).
 
 Examples:
   cloudrouter browser wait cr_abc123 @e5
   cloudrouter browser wait cr_abc123 "#content"
+  cloudrouter browser wait cr_abc123 "text=Order placed" --timeout 1m
   cloudrouter browser wait cr_abc123 2000`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		out, err := execAgentBrowser(args[0], "wait", args[1])
+		if ms, err := strconv.Atoi(args[1]); err == nil {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+			fmt.Printf("Waited %dms\n", ms)
+			return nil
+		}
+		result, err := runBrowserCommand(args[0], "wait", map[string]interface{}{"selector": args[1]})
 		if err != nil {
 			return err
 		}
-		if out != "" {
-			fmt.Println(out)
-		} else {
-			fmt.Printf("Wait complete: %s\n", args[1])
-		}
+		fmt.Printf("Found %s after %vms\n", args[1], result["waitedMs"])
 		return nil
 	},
 }
@@ -1064,11 +1047,17 @@ Examples:
   cloudrouter browser eval cr_abc123 "document.querySelectorAll('a').length"`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		out, err := execAgentBrowser(args[0], "eval", args[1])
+		result, err := runBrowserCommand(args[0], "eval", map[string]interface{}{"expression": args[1], "awaitPromise": true})
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		if v, ok := result["value"]; ok && v != nil {
+			printJSONValue(v)
+		} else if desc := resultString(result, "description"); desc != "" {
+			fmt.Println(desc)
+		} else {
+			fmt.Println(resultString(result, "type"))
+		}
 		return nil
 	},
 }
@@ -1190,7 +1179,15 @@ var browserSetViewportCmd = &cobra.Command{
 	Short: "Set browser viewport size",
 	Args:  cobra.ExactArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := execAgentBrowser(args[0], "set", "viewport", args[1], args[2])
+		width, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid width %q", args[1])
+		}
+		height, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid height %q", args[2])
+		}
+		_, err = runBrowserCommand(args[0], "setViewport", map[string]interface{}{"width": width, "height": height})
 		if err != nil {
 			return err
 		}
@@ -1531,18 +1528,18 @@ var browserStorageSessionClearCmd = &cobra.Command{
 
 var browserNetworkRequestsCmd = &cobra.Command{
 	Use:   "network-requests <id> [--filter pattern]",
-	Short: "List network requests",
+	Short: "List network requests captured since network-start",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		abArgs := []string{"network", "requests"}
+		params := map[string]interface{}{}
 		if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
-			abArgs = append(abArgs, "--filter", filter)
+			params["filter"] = filter
 		}
-		out, err := execAgentBrowser(args[0], abArgs...)
+		result, err := runBrowserCommand(args[0], "network.requests", params)
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		printBrowserRequests(result)
 		return nil
 	},
 }
@@ -1595,15 +1592,18 @@ var browserConsoleCmd = &cobra.Command{
 	Short: "Get console log output",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		abArgs := []string{"console"}
+		params := map[string]interface{}{}
 		if clear, _ := cmd.Flags().GetBool("clear"); clear {
-			abArgs = append(abArgs, "--clear")
+			params["clear"] = true
 		}
-		out, err := execAgentBrowser(args[0], abArgs...)
+		if level, _ := cmd.Flags().GetString("level"); level != "" {
+			params["level"] = level
+		}
+		result, err := runBrowserCommand(args[0], "console", params)
 		if err != nil {
 			return err
 		}
-		fmt.Println(out)
+		printBrowserConsole(result)
 		return nil
 	},
 }
@@ -1738,11 +1738,19 @@ func init() {
 	// Flags
 	browserSnapshotCmd.Flags().BoolP("interactive", "i", false, "Show only interactive elements")
 	browserSnapshotCmd.Flags().BoolP("compact", "c", false, "Compact output")
+	_ = browserSnapshotCmd.Flags().MarkDeprecated("compact", "snapshots are compact already; use --interactive or --depth")
+	browserSnapshotCmd.Flags().String("selector", "", "Only snapshot the subtree of this element")
+	browserSnapshotCmd.Flags().Int("depth", 0, "Stop after this many levels (0 = no limit)")
+	browserSnapshotCmd.Flags().Int("limit", 0, "Maximum lines per page of output")
+	browserSnapshotCmd.Flags().String("cursor", "", "Continue a truncated snapshot")
 	browserScreenshotCmd.Flags().Bool("full", false, "Full page screenshot")
+	browserScreenshotCmd.Flags().String("selector", "", "Capture only this element")
+	browserPDFCmd.Flags().Bool("landscape", false, "Landscape orientation")
 	browserNetworkRequestsCmd.Flags().String("filter", "", "Filter pattern")
 	browserNetworkRouteCmd.Flags().Bool("abort", false, "Abort matching requests")
 	browserNetworkRouteCmd.Flags().String("body", "", "Response body for mocked requests")
 	browserConsoleCmd.Flags().Bool("clear", false, "Clear console after reading")
+	browserConsoleCmd.Flags().String("level", "", "Minimum level: debug, info, log, warn or error")
 	browserErrorsCmd.Flags().Bool("clear", false, "Clear errors after reading")
 
 	// Allow negative numbers as positional args (e.g., longitude -122.4194)
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/karlorz/cloudrouter/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// dryRunEnvVar enables dry-run mode like --dry-run. sync and upload have
+// their own --dry-run (rsync's trial run), so this is the way to plan them.
+const dryRunEnvVar = "CLOUDROUTER_DRY_RUN"
+
+var flagDryRun bool
+
+func dryRunRequested() bool {
+	if flagDryRun {
+		return true
+	}
+	switch strings.ToLower(os.Getenv(dryRunEnvVar)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// dryRunUnsupported lists commands that manage local state (credentials,
+// installed skills) rather than call the team's API, so there is nothing
+// to plan.
+var dryRunUnsupported = []string{"auth", "login", "logout", "whoami", "skills"}
+
+func supportsDryRun(cmd *cobra.Command) bool {
+	path := strings.Fields(cmd.CommandPath())
+	if len(path) < 2 {
+		return true
+	}
+	for _, name := range dryRunUnsupported {
+		if path[1] == name {
+			return false
+		}
+	}
+	return true
+}
+
+// dryRunWorkerStep stops a dry run before an SSH, rsync or websocket step
+// against the sandbox worker, printing what it would have done.
+func dryRunWorkerStep(kind, workerURL, detail string) error {
+	wsURL := strings.Replace(strings.TrimRight(workerURL, "/"), "https://", "wss://", 1)
+	wsURL = strings.Replace(wsURL, "http://", "ws://", 1)
+	return api.DryRunStep(kind, wsURL+"/ssh", detail)
+}
@@ -252,6 +252,10 @@ Examples:
 }
 
 func openBrowser(url string) error {
+	if api.DryRun() {
+		api.PrintDryRunStep("OPEN", url, "")
+		return nil
+	}
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
 	case "darwin":
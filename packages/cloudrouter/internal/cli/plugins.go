@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/karlorz/cloudrouter/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefixes name external subcommands: `cloudrouter foo` runs the first
+// cloudrouter-foo or cmux-foo found on PATH, unless foo is a built-in.
+var pluginPrefixes = []string{"cloudrouter-", "cmux-"}
+
+// pluginContextEnvVar carries pluginContext as JSON to the plugin.
+const pluginContextEnvVar = "CLOUDROUTER_PLUGIN_CONTEXT"
+
+// pluginContext is what a plugin needs to act like a built-in command. The
+// same values are also set as individual CLOUDROUTER_* variables.
+type pluginContext struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Binary        string `json:"binary,omitempty"` // This CLI, for plugins that call back into it
+	Team          string `json:"team,omitempty"`   // Only set with --team; plugins look up the default themselves
+	Verbose       bool   `json:"verbose"`
+	DryRun        bool   `json:"dryRun"`
+	APIURL        string `json:"apiUrl"`
+	ConvexSiteURL string `json:"convexSiteUrl"`
+	LoggedIn      bool   `json:"loggedIn"`
+}
+
+// ExitError reports a plugin's non-zero exit status so main can exit with
+// the same code without printing anything more.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// builtinOnly are added by cobra at Execute time, so rootCmd.Find can't see
+// them yet.
+var builtinOnly = map[string]bool{
+	"help":                          true,
+	"completion":                    true,
+	cobra.ShellCompRequestCmd:       true,
+	cobra.ShellCompNoDescRequestCmd: true,
+}
+
+// splitPluginArgs returns the leading global flags, the subcommand name and
+// the arguments after it. ok is false if there is no subcommand.
+func splitPluginArgs(args []string) (globals []string, name string, rest []string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return nil, "", nil, false
+		case arg == "-t" || arg == "--team":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return args[:i], arg, args[i+1:], true
+		}
+	}
+	return nil, "", nil, false
+}
+
+// isBuiltinCommand reports whether name is a command or alias of the CLI.
+func isBuiltinCommand(name string) bool {
+	if builtinOnly[name] {
+		return true
+	}
+	cmd, _, err := rootCmd.Find([]string{name})
+	return err == nil && cmd != rootCmd
+}
+
+// findPlugin returns the path of the plugin for name, or "" if name is a
+// built-in command or no plugin is installed.
+func findPlugin(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) || isBuiltinCommand(name) {
+		return ""
+	}
+	for _, prefix := range pluginPrefixes {
+		if path, err := exec.LookPath(prefix + name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// runPlugin parses the global flags and runs path with args, passing
+// global flags and auth through the environment.
+func runPlugin(path, name string, globals, args []string) error {
+	if err := rootCmd.PersistentFlags().Parse(globals); err != nil {
+		return err
+	}
+	auth.SetConfigOverrides("", "", "", "")
+
+	env, err := pluginEnv(name)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("run plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// pluginEnv builds the plugin's environment. The access token is only
+// included when already logged in; plugins must not trigger a login.
+func pluginEnv(name string) ([]string, error) {
+	cfg := auth.GetConfig()
+	ctx := pluginContext{
+		Name:          name,
+		Version:       versionStr,
+		Team:          flagTeam,
+		Verbose:       flagVerbose,
+		DryRun:        dryRunRequested(),
+		APIURL:        cfg.CmuxURL,
+		ConvexSiteURL: cfg.ConvexSiteURL,
+	}
+	if self, err := os.Executable(); err == nil {
+		ctx.Binary = self
+	}
+
+	env := os.Environ()
+	if !ctx.DryRun && auth.IsLoggedIn() {
+		if token, err := auth.GetAccessToken(); err == nil {
+			ctx.LoggedIn = true
+			env = append(env, "CLOUDROUTER_ACCESS_TOKEN="+token)
+		}
+	}
+
+	data, err := json.Marshal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env,
+		pluginContextEnvVar+"="+string(data),
+		"CLOUDROUTER_PLUGIN_NAME="+ctx.Name,
+		"CLOUDROUTER_VERSION="+ctx.Version,
+		"CLOUDROUTER_BIN="+ctx.Binary,
+		"CLOUDROUTER_TEAM="+ctx.Team,
+		"CLOUDROUTER_VERBOSE="+strconv.FormatBool(ctx.Verbose),
+		"CLOUDROUTER_API_URL="+ctx.APIURL,
+		"CLOUDROUTER_CONVEX_SITE_URL="+ctx.ConvexSiteURL,
+	)
+	if ctx.DryRun {
+		env = append(env, dryRunEnvVar+"=1")
+	}
+	return env, nil
+}
+
+// listPlugins returns installed plugins by name, keeping the first one on
+// PATH for each name and skipping any shadowed by a built-in.
+func listPlugins() map[string]string {
+	plugins := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			for _, prefix := range pluginPrefixes {
+				base := entry.Name()
+				if runtime.GOOS == "windows" {
+					base = strings.TrimSuffix(base, filepath.Ext(base))
+				}
+				name := strings.TrimPrefix(base, prefix)
+				if name == base || name == "" {
+					continue
+				}
+				if _, seen := plugins[name]; seen || isBuiltinCommand(name) {
+					continue
+				}
+				plugins[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return plugins
+}
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List plugins installed on PATH",
+	Long: `List external subcommands found on PATH.
+
+Any executable named cloudrouter-<name> or cmux-<name> on PATH runs as
+'cloudrouter <name> [args...]'. Built-in commands always take precedence.
+
+Plugins receive the global flags and login through the environment:
+  CLOUDROUTER_PLUGIN_CONTEXT   JSON with the values below, except the token
+  CLOUDROUTER_ACCESS_TOKEN     Bearer token, when logged in
+  CLOUDROUTER_TEAM             --team, if given
+  CLOUDROUTER_VERBOSE          true with --verbose
+  CLOUDROUTER_DRY_RUN          1 with --dry-run
+  CLOUDROUTER_API_URL          cmux API base URL
+  CLOUDROUTER_CONVEX_SITE_URL  Convex HTTP routes base URL
+  CLOUDROUTER_BIN              Path to this CLI`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins := listPlugins()
+		if len(plugins) == 0 {
+			fmt.Println("No plugins found on PATH.")
+			return nil
+		}
+		names := make([]string, 0, len(plugins))
+		for name := range plugins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%-20s %s\n", name, plugins[name])
+		}
+		return nil
+	},
+}
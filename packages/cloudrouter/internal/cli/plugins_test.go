@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSplitPluginArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		globals []string
+		name    string
+		rest    []string
+		ok      bool
+	}{
+		{[]string{"deploy", "--prod"}, []string{}, "deploy", []string{"--prod"}, true},
+		{[]string{"-t", "acme", "-v", "deploy", "x"}, []string{"-t", "acme", "-v"}, "deploy", []string{"x"}, true},
+		{[]string{"--team=acme", "deploy"}, []string{"--team=acme"}, "deploy", []string{}, true},
+		{[]string{"--verbose"}, nil, "", nil, false},
+		{[]string{"--", "deploy"}, nil, "", nil, false},
+	}
+	for _, tt := range tests {
+		globals, name, rest, ok := splitPluginArgs(tt.args)
+		if ok != tt.ok || name != tt.name || !reflect.DeepEqual(globals, tt.globals) || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("splitPluginArgs(%q) = %q, %q, %q, %v", tt.args, globals, name, rest, ok)
+		}
+	}
+}
+
+func TestFindPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin fixtures are shell scripts")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"cloudrouter-deploy", "cmux-deploy", "cmux-audit", "cloudrouter-ls"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	if got := findPlugin("deploy"); got != filepath.Join(dir, "cloudrouter-deploy") {
+		t.Errorf("findPlugin(deploy) = %q, want the cloudrouter- prefixed plugin", got)
+	}
+	if got := findPlugin("audit"); got != filepath.Join(dir, "cmux-audit") {
+		t.Errorf("findPlugin(audit) = %q", got)
+	}
+	if got := findPlugin("ls"); got != "" {
+		t.Errorf("built-in ls should shadow plugin, got %q", got)
+	}
+	if got := findPlugin("missing"); got != "" {
+		t.Errorf("findPlugin(missing) = %q", got)
+	}
+
+	plugins := listPlugins()
+	if len(plugins) != 2 || plugins["deploy"] != filepath.Join(dir, "cloudrouter-deploy") || plugins["audit"] == "" {
+		t.Errorf("listPlugins() = %v", plugins)
+	}
+}
@@ -18,15 +18,23 @@ import (
 	"golang.org/x/term"
 )
 
+var ptyAttach string
+
 var ptyCmd = &cobra.Command{
-	Use:   "pty <id>",
-	Short: "Open a terminal session in the sandbox",
+	Use:     "pty <id>",
+	Aliases: []string{"shell"},
+	Short:   "Open a terminal session in the sandbox",
 	Long: `Open an interactive terminal session in a sandbox.
 
-This provides a terminal experience via WebSocket.
+This provides a terminal experience via WebSocket. Sessions survive
+disconnects: the shell keeps running in the sandbox, and --attach
+reconnects to it and replays recent output. Detached sessions are closed
+after 30 minutes by default.
 
 Examples:
-  cloudrouter pty cr_abc123`,
+  cloudrouter pty cr_abc123
+  cloudrouter pty cr_abc123 --attach 3f9c2a7d1e4b5c60   # Reattach to a session
+  cloudrouter pty-list cr_abc123                        # Find session IDs`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sandboxID := args[0]
@@ -52,16 +60,18 @@ Examples:
 		}
 
 		// Build WebSocket URL
-		wsURL, err := buildPtyWebSocketURL(inst.WorkerURL, token)
+		wsURL, err := buildPtyWebSocketURL(inst.WorkerURL, token, ptyAttach)
 		if err != nil {
 			return fmt.Errorf("failed to build WebSocket URL: %w", err)
 		}
 
-		return runPtySession(wsURL)
+		return runPtySession(wsURL, sandboxID)
 	},
 }
 
-func buildPtyWebSocketURL(workerURL, token string) (string, error) {
+// buildPtyWebSocketURL returns the worker /pty URL. A non-empty sessionID
+// reattaches to that session instead of starting a new shell.
+func buildPtyWebSocketURL(workerURL, token, sessionID string) (string, error) {
 	parsed, err := url.Parse(workerURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid worker URL: %w", err)
@@ -80,6 +90,9 @@ func buildPtyWebSocketURL(workerURL, token string) (string, error) {
 	// Add query parameters
 	query := parsed.Query()
 	query.Set("token", token)
+	if sessionID != "" {
+		query.Set("session", sessionID)
+	}
 	// Get terminal size
 	width, height, _ := term.GetSize(int(os.Stdin.Fd()))
 	if width > 0 {
@@ -93,7 +106,10 @@ func buildPtyWebSocketURL(workerURL, token string) (string, error) {
 	return parsed.String(), nil
 }
 
-func runPtySession(wsURL string) error {
+func runPtySession(wsURL, sandboxID string) error {
+	if api.DryRun() {
+		return api.DryRunStep("WS", wsURL, "interactive terminal session")
+	}
 	// Connect to WebSocket
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
@@ -145,17 +161,23 @@ func runPtySession(wsURL string) error {
 
 	// Read from WebSocket and write to stdout
 	done := make(chan struct{})
+	var sessionID string
 	go func() {
 		defer close(done)
 		for {
 			_, message, err := conn.ReadMessage()
 			if err != nil {
+				if sessionID != "" {
+					fmt.Printf("\r\nDetached from session %s. Reattach with: cloudrouter pty %s --attach %s\r\n", sessionID, sandboxID, sessionID)
+				}
 				return
 			}
 
 			var msg struct {
 				Type     string `json:"type"`
 				Data     string `json:"data"`
+				ID       string `json:"id"`
+				Reason   string `json:"reason"`
 				ExitCode int    `json:"exitCode"`
 				Code     int    `json:"code"`
 			}
@@ -171,7 +193,10 @@ func runPtySession(wsURL string) error {
 			case "output":
 				os.Stdout.Write([]byte(msg.Data))
 			case "session":
-				// Session connected, ready to use
+				sessionID = msg.ID
+			case "detached":
+				fmt.Printf("\r\nSession %s detached: %s\r\n", sessionID, msg.Reason)
+				return
 			case "exit":
 				exitCode := msg.ExitCode
 				if exitCode == 0 {
@@ -250,7 +275,7 @@ Examples:
 		}
 		req.Header.Set("Authorization", "Bearer "+token)
 
-		httpClient := &http.Client{Timeout: 30 * time.Second}
+		httpClient := api.NewHTTPClient(30 * time.Second)
 		resp, err := httpClient.Do(req)
 		if err != nil {
 			return fmt.Errorf("failed to list sessions: %w", err)
@@ -305,5 +330,5 @@ Examples:
 }
 
 func init() {
-	// No flags needed for pty
+	ptyCmd.Flags().StringVar(&ptyAttach, "attach", "", "Reattach to a running PTY session by ID")
 }
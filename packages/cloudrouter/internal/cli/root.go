@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/karlorz/cloudrouter/internal/api"
 	"github.com/karlorz/cloudrouter/internal/auth"
 	"github.com/karlorz/cloudrouter/internal/version"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -37,6 +42,7 @@ Quick start:
   cloudrouter ssh <id> "ls -la"          # Run a command via SSH
   cloudrouter upload <id> ./my-dir       # Upload files to sandbox
   cloudrouter download <id> ./output     # Download files from sandbox
+  cloudrouter tail <id> /var/log/app.log # Follow a file in the sandbox
   cloudrouter browser snapshot <id>      # Get browser accessibility tree
   cloudrouter browser open <id> <url>    # Navigate browser to URL
   cloudrouter stop <id>                  # Pause sandbox
@@ -62,45 +68,67 @@ GPU options (--gpu, auto-selects Modal provider):
   B200        192GB VRAM - latest gen, frontier models`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		auth.SetConfigOverrides("", "", "", "")
 
-		// Start version check in background for long-running commands
-		cmdName := cmd.Name()
-		if version.IsLongRunningCommand(cmdName) {
+		if dryRunRequested() {
+			if !supportsDryRun(cmd) {
+				return fmt.Errorf("%s does not support --dry-run", cmd.CommandPath())
+			}
+			api.SetDryRun(os.Stdout)
+		}
+
+		// Start version check in background; it only hits the registry once a day
+		if shouldCheckForUpdates(cmd) {
 			versionCheckDone = make(chan struct{})
 			go func() {
 				defer close(versionCheckDone)
 				versionCheckResult = version.CheckForUpdates()
 			}()
 		}
+		return nil
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
-		// Show version update warning after long-running commands complete
-		cmdName := cmd.Name()
-		if version.IsLongRunningCommand(cmdName) && versionCheckDone != nil {
-			// Wait for version check to complete (with timeout)
-			select {
-			case <-versionCheckDone:
-				// Version check completed
-			case <-time.After(5 * time.Second):
-				// Timeout - don't block user
-				return
-			}
+		// Show a one-line update hint once the command completes
+		if versionCheckDone == nil {
+			return
+		}
 
-			if versionCheckResult != nil {
-				if version.PrintUpdateWarning(versionCheckResult) {
-					// Auto-update skills when CLI update is available
-					_ = AutoUpdateSkillsIfNeeded()
-				}
-			}
+		// Wait for the version check, but never hold up a quick command for long
+		wait := time.Second
+		if version.IsLongRunningCommand(cmd.Name()) {
+			wait = 5 * time.Second
+		}
+		select {
+		case <-versionCheckDone:
+		case <-time.After(wait):
+			return
+		}
+
+		if version.PrintUpdateWarning(versionCheckResult) {
+			// Auto-update skills when CLI update is available
+			_ = AutoUpdateSkillsIfNeeded()
 		}
 	},
 }
 
+// shouldCheckForUpdates skips the update check for shell completion and help,
+// and when stderr is not a terminal so scripts never see the hint.
+func shouldCheckForUpdates(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion", "help":
+		return false
+	}
+	if api.DryRun() {
+		return false
+	}
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().StringVarP(&flagTeam, "team", "t", "", "Team slug (overrides default)")
+	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "Print the HTTP calls a command would make (redacted) without sending them; also "+dryRunEnvVar+"=1")
 
 	// Version command
 	rootCmd.AddCommand(versionCmd)
@@ -135,6 +163,7 @@ func init() {
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(uploadCmd)
 	rootCmd.AddCommand(downloadCmd)
+	rootCmd.AddCommand(tailCmd)
 
 	// PTY commands (terminal session)
 	rootCmd.AddCommand(ptyCmd)
@@ -148,10 +177,22 @@ func init() {
 
 	// Skills management
 	rootCmd.AddCommand(skillsCmd)
+
+	// External cloudrouter-<name> commands
+	rootCmd.AddCommand(pluginsCmd)
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	if globals, name, args, ok := splitPluginArgs(os.Args[1:]); ok {
+		if path := findPlugin(name); path != "" {
+			return runPlugin(path, name, globals, args)
+		}
+	}
+	err := rootCmd.Execute()
+	if errors.Is(err, api.ErrDryRunStopped) {
+		return nil
+	}
+	return err
 }
 
 var (
@@ -177,5 +218,10 @@ func getTeamSlug() (string, error) {
 	if flagTeam != "" {
 		return flagTeam, nil
 	}
+	if api.DryRun() {
+		// Looking up the default team needs the network; plan with a
+		// placeholder instead.
+		return "TEAM", nil
+	}
 	return auth.GetTeamSlug()
 }
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/karlorz/cloudrouter/internal/api"
 )
 
 // Rsync flags - set by sync.go before calling runRsyncOverWebSocket
@@ -145,6 +146,9 @@ func getCurlWithWebSocket() string {
 
 // runRsyncOverWebSocket syncs files using rsync over a WebSocket SSH tunnel
 func runRsyncOverWebSocket(workerURL, token, localPath, remotePath string) error {
+	if api.DryRun() {
+		return dryRunWorkerStep("RSYNC", workerURL, localPath+"/ -> "+remotePath)
+	}
 	// Check for rsync
 	if _, err := exec.LookPath("rsync"); err != nil {
 		return fmt.Errorf("rsync not found. Install with: brew install rsync (macOS) or apt install rsync (Linux)")
@@ -280,6 +284,9 @@ func runRsyncOverWebSocket(workerURL, token, localPath, remotePath string) error
 
 // runRsyncSingleFile syncs a single file using rsync over WebSocket SSH
 func runRsyncSingleFile(workerURL, token, localFile, remotePath string) error {
+	if api.DryRun() {
+		return dryRunWorkerStep("RSYNC", workerURL, localFile+" -> "+remotePath)
+	}
 	// Check for rsync
 	if _, err := exec.LookPath("rsync"); err != nil {
 		return fmt.Errorf("rsync not found. Install with: brew install rsync (macOS) or apt install rsync (Linux)")
@@ -453,6 +460,9 @@ func runRsyncSingleFileWithBridge(wsURL, token, localFile, remotePath string) (*
 
 // runRsyncDownload downloads files from remote sandbox to local using rsync over WebSocket SSH
 func runRsyncDownload(workerURL, token, remotePath, localPath string) error {
+	if api.DryRun() {
+		return dryRunWorkerStep("RSYNC", workerURL, remotePath+" -> "+localPath)
+	}
 	// Check for rsync
 	if _, err := exec.LookPath("rsync"); err != nil {
 		return fmt.Errorf("rsync not found. Install with: brew install rsync (macOS) or apt install rsync (Linux)")
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -121,6 +122,9 @@ func watchAndRsync(workerURL, token, localPath, remotePath string) error {
 	// Initial sync
 	fmt.Println("Initial sync...")
 	if err := runRsyncOverWebSocket(workerURL, token, localPath, remotePath); err != nil {
+		if errors.Is(err, api.ErrDryRunStopped) {
+			return err
+		}
 		fmt.Printf("Initial sync error: %v\n", err)
 	}
 
@@ -0,0 +1,110 @@
+// internal/cli/tail.go
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/karlorz/cloudrouter/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tailLines    int
+	tailNoFollow bool
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail <id> <path>",
+	Short: "Follow a file in the sandbox",
+	Long: `Print the last lines of a file in the sandbox and follow new ones.
+
+Following survives log rotation and truncation. Relative paths resolve
+against the workspace directory. Press Ctrl+C to stop.
+
+Examples:
+  cloudrouter tail cr_abc123 /var/log/devserver.log
+  cloudrouter tail cr_abc123 logs/app.log -n 100
+  cloudrouter tail cr_abc123 /tmp/build.log --no-follow`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workerURL, token, err := resolveWorker(args[0])
+		if err != nil {
+			return err
+		}
+		return streamTail(workerURL, token, args[1], tailLines, !tailNoFollow)
+	},
+}
+
+// streamTail reads NDJSON events from the worker's tail endpoint and prints
+// lines to stdout until the stream ends.
+func streamTail(workerURL, token, path string, lines int, follow bool) error {
+	query := url.Values{}
+	query.Set("path", path)
+	query.Set("lines", strconv.Itoa(lines))
+	if follow {
+		query.Set("follow", "1")
+	}
+	req, err := http.NewRequest("GET", strings.TrimRight(workerURL, "/")+"/_cmux/files/tail?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// No client timeout: a follow runs until interrupted.
+	resp, err := api.NewHTTPClient(0).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to tail %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("failed to tail %s: %s", path, apiErr.Error)
+		}
+		return fmt.Errorf("failed to tail %s: %s", path, strings.TrimSpace(string(body)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev struct {
+			Type  string `json:"type"`
+			Data  string `json:"data"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "line":
+			fmt.Println(ev.Data)
+		case "rotated", "truncated":
+			fmt.Fprintf(os.Stderr, "==> %s %s <==\n", path, ev.Type)
+		case "error":
+			return fmt.Errorf("tail %s: %s", path, ev.Error)
+		case "eof":
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("tail %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	tailCmd.Flags().IntVarP(&tailLines, "lines", "n", 10, "Number of existing lines to print first")
+	tailCmd.Flags().BoolVar(&tailNoFollow, "no-follow", false, "Print the last lines and exit")
+}
@@ -8,18 +8,24 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	npmRegistryURL     = "https://registry.npmjs.org/@karlorz/cloudrouter"
-	checkIntervalHours = 6 // Only check every 6 hours
-	configDirName      = "cloudrouter"
+	checkInterval = 24 * time.Hour // Check the registry at most once per day
+	configDirName = "cloudrouter"
+
+	// NoUpdateCheckEnv disables update checks when set to anything but "0" or "false"
+	NoUpdateCheckEnv = "CMUX_NO_UPDATE_CHECK"
 )
 
 var (
 	currentVersion string
+
+	// npmRegistryURL is a var so tests can point it at a local server
+	npmRegistryURL = "https://registry.npmjs.org/@karlorz/cloudrouter"
 )
 
 // SetCurrentVersion sets the current CLI version (called from main)
@@ -38,12 +44,51 @@ type VersionCache struct {
 	CheckedAt     int64  `json:"checked_at"`
 }
 
-func getCachePath() (string, error) {
+func getConfigDir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".config", configDirName, "version_cache.json"), nil
+	return filepath.Join(home, ".config", configDirName), nil
+}
+
+func getCachePath() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "version_cache.json"), nil
+}
+
+// Config holds the user settings in ~/.config/cloudrouter/config.json that
+// the version check reads
+type Config struct {
+	// UpdateCheck set to false disables update checks and hints
+	UpdateCheck *bool `json:"update_check,omitempty"`
+}
+
+func loadConfig() Config {
+	var cfg Config
+	dir, err := getConfigDir()
+	if err != nil {
+		return cfg
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return cfg
+	}
+	_ = json.Unmarshal(data, &cfg)
+	return cfg
+}
+
+// UpdateCheckDisabled reports whether update checks are turned off via
+// CMUX_NO_UPDATE_CHECK or "update_check": false in config.json.
+func UpdateCheckDisabled() bool {
+	if v := strings.TrimSpace(os.Getenv(NoUpdateCheckEnv)); v != "" && v != "0" && !strings.EqualFold(v, "false") {
+		return true
+	}
+	cfg := loadConfig()
+	return cfg.UpdateCheck != nil && !*cfg.UpdateCheck
 }
 
 func loadCache() (*VersionCache, error) {
@@ -119,36 +164,36 @@ type CheckResult struct {
 }
 
 // CheckForUpdates checks if there's a newer version available.
-// It uses a cache to avoid checking too frequently.
+// The registry is queried at most once per checkInterval; failed queries are
+// cached too so an unreachable registry does not slow every command.
 func CheckForUpdates() *CheckResult {
 	result := &CheckResult{
 		CurrentVersion: currentVersion,
 	}
 
-	// Skip check for dev builds
-	if currentVersion == "" || currentVersion == "dev" {
+	// Skip check for dev builds and when the user opted out
+	if currentVersion == "" || currentVersion == "dev" || UpdateCheckDisabled() {
 		return result
 	}
 
 	// Check cache first
 	cache, err := loadCache()
-	if err == nil && cache != nil {
-		// Use cached result if checked recently
-		if time.Now().Unix()-cache.CheckedAt < checkIntervalHours*3600 {
-			result.LatestVersion = cache.LatestVersion
-			result.IsOutdated = isNewer(cache.LatestVersion, currentVersion)
-			return result
-		}
+	if err != nil {
+		cache = &VersionCache{}
+	}
+	if time.Since(time.Unix(cache.CheckedAt, 0)) < checkInterval {
+		result.LatestVersion = cache.LatestVersion
+		result.IsOutdated = isNewer(cache.LatestVersion, currentVersion)
+		return result
 	}
 
 	// Fetch latest version from npm (non-blocking, with short timeout)
 	latestVersion, err := fetchLatestVersion()
 	if err != nil {
 		result.Error = err
-		return result
+		latestVersion = cache.LatestVersion // Keep the last known release
 	}
 
-	// Save to cache
 	_ = saveCache(&VersionCache{
 		LatestVersion: latestVersion,
 		CheckedAt:     time.Now().Unix(),
@@ -159,64 +204,116 @@ func CheckForUpdates() *CheckResult {
 	return result
 }
 
-// isNewer returns true if latest is newer than current
-// Supports semver-like versions (e.g., "0.7.6" vs "0.7.5")
+// isNewer returns true if latest is a higher semver than current
 func isNewer(latest, current string) bool {
-	// Clean up versions (remove 'v' prefix if present)
-	latest = strings.TrimPrefix(latest, "v")
-	current = strings.TrimPrefix(current, "v")
-
-	latestParts := strings.Split(latest, ".")
-	currentParts := strings.Split(current, ".")
-
-	for i := 0; i < len(latestParts) && i < len(currentParts); i++ {
-		// Parse as integers for comparison
-		var latestNum, currentNum int
-		fmt.Sscanf(latestParts[i], "%d", &latestNum)
-		fmt.Sscanf(currentParts[i], "%d", &currentNum)
+	if latest == "" || current == "" {
+		return false
+	}
+	return compareVersions(latest, current) > 0
+}
 
-		if latestNum > currentNum {
-			return true
+// compareVersions compares two semver strings (an optional "v" prefix is
+// allowed) and returns -1, 0 or 1. Missing numeric parts count as 0, a
+// prerelease sorts before its release, and build metadata is ignored.
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := 0; i < len(aCore) || i < len(bCore); i++ {
+		var x, y int
+		if i < len(aCore) {
+			x = aCore[i]
+		}
+		if i < len(bCore) {
+			y = bCore[i]
 		}
-		if latestNum < currentNum {
-			return false
+		if x != y {
+			return cmpInt(x, y)
 		}
 	}
 
-	// If all compared parts are equal, newer if latest has more parts
-	return len(latestParts) > len(currentParts)
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePrerelease(aPre, bPre)
 }
 
-// PrintUpdateWarning prints a warning message if an update is available.
-// Returns true if an update is available.
-func PrintUpdateWarning(result *CheckResult) bool {
-	if result == nil || !result.IsOutdated {
-		return false
+func splitVersion(v string) ([]int, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, _ := strings.Cut(v, "-")
+
+	parts := strings.Split(core, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		nums[i], _ = strconv.Atoi(p)
+	}
+	return nums, pre
+}
+
+// comparePrerelease orders dot-separated prerelease identifiers: numeric
+// identifiers compare numerically and sort before alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	aIDs := strings.Split(a, ".")
+	bIDs := strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, xErr := strconv.Atoi(aIDs[i])
+		y, yErr := strconv.Atoi(bIDs[i])
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				return cmpInt(x, y)
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aIDs[i], bIDs[i]); c != 0 {
+				return c
+			}
+		}
 	}
+	return cmpInt(len(aIDs), len(bIDs))
+}
 
-	fmt.Fprintf(os.Stderr, "\n")
-	fmt.Fprintf(os.Stderr, "╭─────────────────────────────────────────────────────────────╮\n")
-	fmt.Fprintf(os.Stderr, "│  A new version of cloudrouter is available: %s → %s    │\n",
-		padVersion(result.CurrentVersion), padVersion(result.LatestVersion))
-	fmt.Fprintf(os.Stderr, "│                                                             │\n")
-	fmt.Fprintf(os.Stderr, "│  To update: npm i -g @karlorz/cloudrouter               │\n")
-	fmt.Fprintf(os.Stderr, "╰─────────────────────────────────────────────────────────────╯\n")
-	fmt.Fprintf(os.Stderr, "\n")
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
 
-	return true
+// UpdateHint returns the one-line upgrade hint, or "" if no update is available
+func UpdateHint(result *CheckResult) string {
+	if result == nil || !result.IsOutdated {
+		return ""
+	}
+	return fmt.Sprintf("cloudrouter %s is available (you have %s). Update: npm i -g @karlorz/cloudrouter",
+		strings.TrimPrefix(result.LatestVersion, "v"), strings.TrimPrefix(result.CurrentVersion, "v"))
 }
 
-// padVersion pads a version string to a fixed width for alignment
-func padVersion(v string) string {
-	const width = 7
-	if len(v) >= width {
-		return v[:width]
+// PrintUpdateWarning prints the one-line upgrade hint to stderr if an update
+// is available. Returns true if an update is available.
+func PrintUpdateWarning(result *CheckResult) bool {
+	hint := UpdateHint(result)
+	if hint == "" {
+		return false
 	}
-	return v + strings.Repeat(" ", width-len(v))
+	fmt.Fprintf(os.Stderr, "\n%s\n", hint)
+	return true
 }
 
-// IsLongRunningCommand returns true if the command is considered long-running
-// and should trigger a version check.
+// IsLongRunningCommand returns true if the command is considered long-running.
+// The CLI waits longer for the update check to finish after these commands.
 func IsLongRunningCommand(cmdName string) bool {
 	longRunningCmds := map[string]bool{
 		"pty":   true,
@@ -1,6 +1,14 @@
 package version
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestIsNewer(t *testing.T) {
 	tests := []struct {
@@ -69,24 +77,142 @@ func TestIsNewer(t *testing.T) {
 	}
 }
 
-func TestPadVersion(t *testing.T) {
+func TestIsNewerSemver(t *testing.T) {
 	tests := []struct {
-		input    string
-		expected string
+		latest, current string
+		expected        bool
 	}{
-		{"0.7.6", "0.7.6  "},
-		{"0.7.10", "0.7.10 "},
-		{"10.10.10", "10.10.1"}, // truncated to 7 chars
+		{"1.0.0", "1.0.0-beta.2", true},
+		{"1.0.0-beta.2", "1.0.0", false},
+		{"1.0.0-beta.10", "1.0.0-beta.2", true},
+		{"1.0.0-rc.1", "1.0.0-beta.9", true},
+		{"1.0.0-beta", "1.0.0-1", true},
+		{"1.0.0+build.5", "1.0.0", false},
+		{"0.10.0", "0.9.9", true},
+		{"0.7.6.0", "0.7.6", false},
+		{"0.7.6", "0.7.6.0", false},
+		{"1.0.0", "1.0", false},
+		{"1.0.1", "1.0", true},
+		{"", "0.7.6", false},
 	}
 
 	for _, tt := range tests {
-		result := padVersion(tt.input)
-		if result != tt.expected {
-			t.Errorf("padVersion(%q) = %q, want %q", tt.input, result, tt.expected)
+		if got := isNewer(tt.latest, tt.current); got != tt.expected {
+			t.Errorf("isNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.expected)
 		}
 	}
 }
 
+func TestUpdateHint(t *testing.T) {
+	hint := UpdateHint(&CheckResult{CurrentVersion: "0.7.5", LatestVersion: "v0.8.0", IsOutdated: true})
+	if strings.Contains(hint, "\n") || !strings.Contains(hint, "0.8.0 is available (you have 0.7.5)") {
+		t.Errorf("UpdateHint() = %q", hint)
+	}
+	if hint := UpdateHint(&CheckResult{CurrentVersion: "0.8.0", LatestVersion: "0.8.0"}); hint != "" {
+		t.Errorf("UpdateHint() for current version = %q, want empty", hint)
+	}
+}
+
+func useTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(NoUpdateCheckEnv, "")
+
+	savedURL, savedVersion := npmRegistryURL, currentVersion
+	t.Cleanup(func() { npmRegistryURL, currentVersion = savedURL, savedVersion })
+	return home
+}
+
+func TestCheckForUpdatesCachesForADay(t *testing.T) {
+	useTestHome(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"dist-tags":{"latest":"0.8.0"}}`))
+	}))
+	defer server.Close()
+	npmRegistryURL = server.URL
+	SetCurrentVersion("0.7.5")
+
+	for i := 0; i < 3; i++ {
+		result := CheckForUpdates()
+		if !result.IsOutdated || result.LatestVersion != "0.8.0" {
+			t.Fatalf("CheckForUpdates() = %+v", result)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("registry queried %d times, want 1", requests)
+	}
+
+	// An expired cache is refreshed
+	if err := saveCache(&VersionCache{LatestVersion: "0.7.9", CheckedAt: time.Now().Add(-25 * time.Hour).Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	if result := CheckForUpdates(); result.LatestVersion != "0.8.0" || requests != 2 {
+		t.Errorf("after expiry: result=%+v requests=%d", result, requests)
+	}
+}
+
+func TestCheckForUpdatesCachesFailures(t *testing.T) {
+	useTestHome(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	npmRegistryURL = server.URL
+	SetCurrentVersion("0.7.5")
+
+	if err := saveCache(&VersionCache{LatestVersion: "0.7.6", CheckedAt: time.Now().Add(-48 * time.Hour).Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	result := CheckForUpdates()
+	if result.Error == nil || !result.IsOutdated {
+		t.Errorf("failed check should keep the last known release: %+v", result)
+	}
+	CheckForUpdates()
+	if requests != 1 {
+		t.Errorf("registry queried %d times after a failure, want 1", requests)
+	}
+}
+
+func TestUpdateCheckDisabled(t *testing.T) {
+	home := useTestHome(t)
+	npmRegistryURL = "http://127.0.0.1:0" // Must never be reached
+	SetCurrentVersion("0.7.5")
+
+	if UpdateCheckDisabled() {
+		t.Fatal("update check disabled by default")
+	}
+
+	t.Setenv(NoUpdateCheckEnv, "1")
+	if !UpdateCheckDisabled() {
+		t.Error("CMUX_NO_UPDATE_CHECK=1 should disable the check")
+	}
+	if result := CheckForUpdates(); result.Error != nil || result.IsOutdated {
+		t.Errorf("disabled check returned %+v", result)
+	}
+	t.Setenv(NoUpdateCheckEnv, "false")
+	if UpdateCheckDisabled() {
+		t.Error("CMUX_NO_UPDATE_CHECK=false should not disable the check")
+	}
+
+	dir := filepath.Join(home, ".config", configDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"update_check": false}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !UpdateCheckDisabled() {
+		t.Error(`"update_check": false in config.json should disable the check`)
+	}
+}
+
 func TestIsLongRunningCommand(t *testing.T) {
 	tests := []struct {
 		cmd      string
@@ -11,7 +11,13 @@ import {
   requireDevboxTeamAccessForHttp,
 } from "../_shared/devbox-http-auth";
 import { env } from "../_shared/convex-env";
-import { isValidConvexId, isConvexIdValidationError } from "./cmux_http_helpers";
+import {
+  isValidConvexId,
+  isConvexIdValidationError,
+  pageAfterCursor,
+  parseListCursor,
+  parseListParams,
+} from "./cmux_http_helpers";
 import { jsonResponse } from "../_shared/http-utils";
 import type { DevboxProvider } from "@cmux/shared/provider-types";
 import type { FunctionReference } from "convex/server";
@@ -579,6 +585,17 @@ export const listInstances = httpAction(async (ctx, req) => {
     );
   }
 
+  const listParams = parseListParams(url.searchParams);
+  if ("error" in listParams) {
+    return jsonResponse({ code: 400, message: listParams.error }, 400);
+  }
+  const cursor = listParams.cursor
+    ? parseListCursor(listParams.cursor)
+    : undefined;
+  if (listParams.cursor && !cursor) {
+    return jsonResponse({ code: 400, message: "Invalid cursor" }, 400);
+  }
+
   try {
     const teamAccess = await requireDevboxTeamAccessForHttp(
       ctx,
@@ -599,8 +616,23 @@ export const listInstances = httpAction(async (ctx, req) => {
       updatedAt: number;
     }>;
 
+    // Instances have no repository, so only status and createdAfter apply
+    const matching = rawInstances.filter(
+      (inst) =>
+        (!listParams.status ||
+          inst.status.toLowerCase() === listParams.status) &&
+        (listParams.createdAfter === undefined ||
+          inst.createdAt > listParams.createdAfter)
+    );
+    const { page, nextCursor } = pageAfterCursor(
+      matching,
+      cursor,
+      listParams.limit,
+      (inst) => ({ time: inst.createdAt, id: inst.devboxId })
+    );
+
     // Return basic instance info with id field (URLs are fetched via GET /instances/{id})
-    const instances = rawInstances.map((inst) => ({
+    const instances = page.map((inst) => ({
       id: inst.devboxId,
       status: inst.status,
       name: inst.name,
@@ -608,7 +640,7 @@ export const listInstances = httpAction(async (ctx, req) => {
       updatedAt: inst.updatedAt,
     }));
 
-    return jsonResponse({ instances });
+    return jsonResponse({ instances, nextCursor });
   } catch (error) {
     console.error("[cmux.list] Error:", error);
     return jsonResponse(
@@ -750,14 +782,66 @@ async function handleGetInstanceMorph(
 // ============================================================================
 // POST /api/v1/cmux/instances/{id}/exec - Execute command
 // ============================================================================
+const EXEC_SHELLS = new Set(["sh", "bash", "zsh"]);
+const EXEC_ENV_NAME = /^[A-Za-z_][A-Za-z0-9_]*$/;
+
+type ExecCommandOptions = {
+  env?: Record<string, string>;
+  cwd?: string;
+  shell?: string;
+};
+
+function shellQuote(value: string): string {
+  return `'${value.replace(/'/g, `'\\''`)}'`;
+}
+
+// Morph API expects command as an array and runs it directly without a shell.
+// To preserve shell operators (&&, |, >) and quoted arguments, wrap string
+// commands in a shell. Array commands are passed directly for precise control
+// unless env or cwd require a shell prelude.
+function buildExecCommand(
+  command: string | string[],
+  options: ExecCommandOptions
+): { command: string[] } | { error: string } {
+  const shell = options.shell ?? "sh";
+  if (!EXEC_SHELLS.has(shell)) {
+    return { error: `Unsupported shell: ${shell}` };
+  }
+
+  const prelude: string[] = [];
+  if (options.cwd) {
+    prelude.push(`cd ${shellQuote(options.cwd)}`);
+  }
+  for (const [name, value] of Object.entries(options.env ?? {})) {
+    if (!EXEC_ENV_NAME.test(name)) {
+      return { error: `Invalid environment variable name: ${name}` };
+    }
+    prelude.push(`export ${name}=${shellQuote(String(value))}`);
+  }
+
+  if (Array.isArray(command) && prelude.length === 0 && !options.shell) {
+    return { command };
+  }
+  const script = Array.isArray(command)
+    ? command.map(shellQuote).join(" ")
+    : command;
+  return { command: [shell, "-c", [...prelude, script].join(" && ")] };
+}
+
 async function handleExecCommand(
   ctx: ActionCtx,
   id: string,
   teamSlugOrId: string,
   userId: string,
   command: string | string[],
-  timeout?: number
+  timeout?: number,
+  options: ExecCommandOptions = {}
 ): Promise<Response> {
+  const built = buildExecCommand(command, options);
+  if ("error" in built) {
+    return jsonResponse({ code: 400, message: built.error }, 400);
+  }
+
   try {
     const instanceAccess = await requireDevboxInstanceAccessForHttp(
       ctx,
@@ -775,20 +859,13 @@ async function handleExecCommand(
       return jsonResponse({ code: 404, message: "Provider mapping not found" }, 404);
     }
 
-    // Morph API expects command as an array and runs it directly without a shell.
-    // To preserve shell operators (&&, |, >) and quoted arguments, wrap string
-    // commands in sh -c. Array commands are passed directly for precise control.
-    const commandArray = Array.isArray(command)
-      ? command
-      : ["sh", "-c", command];
-
     // Execute command via Morph API
     const morphResponse = await morphFetch(
       `/instance/${providerInstanceId}/exec`,
       {
         method: "POST",
         body: JSON.stringify({
-          command: commandArray,
+          command: built.command,
           timeout: timeout ?? 30,
         }),
       }
@@ -1697,6 +1774,9 @@ export const instanceActionRouter = httpAction(async (ctx, req) => {
     teamSlugOrId: string;
     command?: string | string[];
     timeout?: number;
+    env?: Record<string, string>;
+    cwd?: string;
+    shell?: string;
     ttlSeconds?: number;
     digest?: string;
     serviceName?: string;
@@ -1728,7 +1808,8 @@ export const instanceActionRouter = httpAction(async (ctx, req) => {
         body.teamSlugOrId,
         userId,
         body.command,
-        body.timeout
+        body.timeout,
+        { env: body.env, cwd: body.cwd, shell: body.shell }
       );
 
     case "pause":
@@ -1943,12 +2024,6 @@ export const listTasks = httpAction(async (ctx, req) => {
   const url = new URL(req.url);
   const teamSlugOrId = url.searchParams.get("teamSlugOrId");
   const archived = url.searchParams.get("archived") === "true";
-  const limitParam = url.searchParams.get("limit");
-  // Validate limit: must be positive integer, capped at 100 to prevent resource exhaustion
-  const parsedLimit = limitParam ? parseInt(limitParam, 10) : undefined;
-  const limit = parsedLimit !== undefined && !isNaN(parsedLimit) && parsedLimit > 0
-    ? Math.min(parsedLimit, 100)
-    : undefined;
 
   if (!teamSlugOrId) {
     return jsonResponse(
@@ -1957,6 +2032,13 @@ export const listTasks = httpAction(async (ctx, req) => {
     );
   }
 
+  // limit is capped at 100 to prevent resource exhaustion; callers follow
+  // nextCursor for more
+  const listParams = parseListParams(url.searchParams);
+  if ("error" in listParams) {
+    return jsonResponse({ code: 400, message: listParams.error }, 400);
+  }
+
   try {
     const userId = identity!.subject;
     const teamId = await resolveTeamIdForHttp(ctx, teamSlugOrId);
@@ -1968,69 +2050,42 @@ export const listTasks = httpAction(async (ctx, req) => {
       );
     }
 
-    // Get tasks
-    const tasks = await ctx.runQuery(internal.tasks.listInternal, {
-      teamId,
-      userId,
-      archived,
-      limit,
-    });
-
-    // For each task, get the selected run info to show status
-    const tasksWithRuns = await Promise.all(
-      tasks.map(async (task) => {
-        // eslint-disable-next-line @typescript-eslint/no-explicit-any
-        let selectedRun: any = null;
-
-        if (task.selectedTaskRunId) {
-          selectedRun = await ctx.runQuery(internal.taskRuns.getById, {
-            id: task.selectedTaskRunId as Id<"taskRuns">,
-          });
-        }
-
-        // If no selected run, get runs for this task and prefer the crowned one
-        if (!selectedRun) {
-          const runs = await ctx.runQuery(internal.taskRuns.listByTaskAndTeamInternal, {
-            taskId: task._id as Id<"tasks">,
-            teamId,
-            userId,
-          });
-          // Prefer crowned run (it has the PR), fallback to first run
-          selectedRun = runs.find((r) => r.isCrowned) ?? runs[0] ?? null;
-        }
-
-        // Extract vscode URL from vscode object or networking array
-        let vscodeUrl: string | undefined;
-        if (selectedRun?.vscode?.workspaceUrl) {
-          vscodeUrl = selectedRun.vscode.workspaceUrl;
-        } else if (selectedRun?.networking) {
-          // eslint-disable-next-line @typescript-eslint/no-explicit-any
-          const vscodeSvc = selectedRun.networking.find((n: any) => n.port === 39378);
-          vscodeUrl = vscodeSvc?.url;
-        }
-
-        return {
-          id: task._id,
-          prompt: task.text,
-          repository: task.projectFullName,
-          baseBranch: task.baseBranch,
-          status: selectedRun?.status ?? "pending",
-          agent: selectedRun?.agentName,
-          vscodeUrl,
-          isCompleted: task.isCompleted,
-          isArchived: task.isArchived,
-          createdAt: task.createdAt,
-          updatedAt: task.updatedAt,
-          taskRunId: selectedRun?._id,
-          exitCode: selectedRun?.exitCode,
-          pullRequestUrl: selectedRun?.pullRequestUrl,
-          mergeStatus: task.mergeStatus,
-          githubProjectItemId: task.githubProjectItemId,
-        };
-      })
-    );
+    // Status comes from each task's selected run, so it is filtered here
+    // rather than in the query. Pages that filter down to nothing are
+    // skipped, a bounded number of times, before returning a short page.
+    const tasksWithRuns: Awaited<ReturnType<typeof toTaskListItems>> = [];
+    let cursor: string | null = listParams.cursor ?? null;
+    let isDone = false;
+    for (
+      let reads = 0;
+      reads < MAX_TASK_LIST_READS && !isDone && tasksWithRuns.length < listParams.limit;
+      reads++
+    ) {
+      const result = await ctx.runQuery(internal.tasks.listPageInternal, {
+        teamId,
+        userId,
+        archived,
+        repository: listParams.repository,
+        createdAfter: listParams.createdAfter,
+        paginationOpts: {
+          numItems: listParams.limit - tasksWithRuns.length,
+          cursor,
+        },
+      });
+      const items = await toTaskListItems(ctx, result.page, teamId, userId);
+      tasksWithRuns.push(
+        ...items.filter(
+          (item) => !listParams.status || item.status.toLowerCase() === listParams.status
+        )
+      );
+      cursor = result.continueCursor;
+      isDone = result.isDone;
+    }
 
-    return jsonResponse({ tasks: tasksWithRuns });
+    return jsonResponse({
+      tasks: tasksWithRuns,
+      nextCursor: isDone ? undefined : cursor,
+    });
   } catch (err) {
     console.error("[cmux.tasks.list] Error:", err);
     return jsonResponse(
@@ -2040,6 +2095,71 @@ export const listTasks = httpAction(async (ctx, req) => {
   }
 });
 
+// MAX_TASK_LIST_READS bounds the page reads one list request makes while the
+// status filter drops tasks.
+const MAX_TASK_LIST_READS = 5;
+
+// toTaskListItems attaches each task's selected run (status, agent, URLs)
+async function toTaskListItems(
+  ctx: ActionCtx,
+  tasks: Doc<"tasks">[],
+  teamId: string,
+  userId: string
+) {
+  return await Promise.all(
+    tasks.map(async (task) => {
+      // eslint-disable-next-line @typescript-eslint/no-explicit-any
+      let selectedRun: any = null;
+
+      if (task.selectedTaskRunId) {
+        selectedRun = await ctx.runQuery(internal.taskRuns.getById, {
+          id: task.selectedTaskRunId as Id<"taskRuns">,
+        });
+      }
+
+      // If no selected run, get runs for this task and prefer the crowned one
+      if (!selectedRun) {
+        const runs = await ctx.runQuery(internal.taskRuns.listByTaskAndTeamInternal, {
+          taskId: task._id as Id<"tasks">,
+          teamId,
+          userId,
+        });
+        // Prefer crowned run (it has the PR), fallback to first run
+        selectedRun = runs.find((r) => r.isCrowned) ?? runs[0] ?? null;
+      }
+
+      // Extract vscode URL from vscode object or networking array
+      let vscodeUrl: string | undefined;
+      if (selectedRun?.vscode?.workspaceUrl) {
+        vscodeUrl = selectedRun.vscode.workspaceUrl;
+      } else if (selectedRun?.networking) {
+        // eslint-disable-next-line @typescript-eslint/no-explicit-any
+        const vscodeSvc = selectedRun.networking.find((n: any) => n.port === 39378);
+        vscodeUrl = vscodeSvc?.url;
+      }
+
+      return {
+        id: task._id,
+        prompt: task.text,
+        repository: task.projectFullName,
+        baseBranch: task.baseBranch,
+        status: selectedRun?.status ?? "pending",
+        agent: selectedRun?.agentName,
+        vscodeUrl,
+        isCompleted: task.isCompleted,
+        isArchived: task.isArchived,
+        createdAt: task.createdAt,
+        updatedAt: task.updatedAt,
+        taskRunId: selectedRun?._id,
+        exitCode: selectedRun?.exitCode,
+        pullRequestUrl: selectedRun?.pullRequestUrl,
+        mergeStatus: task.mergeStatus,
+        githubProjectItemId: task.githubProjectItemId,
+      };
+    })
+  );
+}
+
 // ============================================================================
 // POST /api/v1/cmux/tasks - Create task with prompt
 // ============================================================================
@@ -2880,6 +3000,107 @@ async function handleUnarchiveTask(
 // ============================================================================
 // POST /api/v1/cmux/tasks/{id}/stop - Stop/archive task
 // ============================================================================
+// Stop the sandboxes behind runs and, once every one has stopped, mark the
+// runs still pending or running as failed with errorMessage. Returns the
+// sandboxes that could not be stopped; runs are left untouched then.
+async function stopTaskRuns(
+  ctx: ActionCtx,
+  runs: Doc<"taskRuns">[],
+  teamSlugOrId: string,
+  errorMessage: string
+): Promise<string[]> {
+  const stopRuns = runs.map(toTaskRunStopState);
+  const runsById = new Map(runs.map((run) => [String(run._id), run]));
+  const stopRunsById = new Map(stopRuns.map((run) => [run._id, run]));
+  const stopTargets = collectTaskStopTargets(stopRuns);
+
+  const stopFailures: string[] = [];
+
+  for (const target of stopTargets) {
+    const stoppedAt = Date.now();
+    const run = runsById.get(target.runId);
+    const stopRun = stopRunsById.get(target.runId);
+    if (!run || !stopRun) {
+      continue;
+    }
+
+    try {
+      if (target.provider === "morph") {
+        const morphResponse = await morphFetch(`/instance/${target.instanceId}`, {
+          method: "DELETE",
+        });
+
+        if (!morphResponse.ok && morphResponse.status !== 404) {
+          const errorText = await morphResponse.text();
+          throw new Error(
+            `Failed to stop Morph instance ${target.instanceId}: HTTP ${morphResponse.status} ${errorText.slice(0, 200)}`
+          );
+        }
+
+        await recordMorphActivity(ctx, target.instanceId, "stop");
+      } else {
+        const actionsApi = getActionsApiForProvider(target.provider);
+        await ctx.runAction(actionsApi.stopInstance, {
+          instanceId: target.instanceId,
+        });
+        await recordProviderStopActivity(ctx, target.provider, target.instanceId);
+      }
+    } catch (error) {
+      if (!isIgnorableTaskStopError(error)) {
+        stopFailures.push(
+          `${target.provider}:${target.instanceId}: ${error instanceof Error ? error.message : String(error)}`
+        );
+        continue;
+      }
+    }
+
+    try {
+      await ctx.runMutation(api.devboxInstances.updateStatus, {
+        teamSlugOrId,
+        providerInstanceId: target.instanceId,
+        status: "stopped",
+      });
+    } catch (error) {
+      console.warn("[cmux.stopTaskRuns] Failed to update devbox status:", {
+        instanceId: target.instanceId,
+        error: error instanceof Error ? error.message : String(error),
+      });
+    }
+
+    const patch = buildStoppedTaskRunMetadataPatch(stopRun, stoppedAt);
+
+    if (patch) {
+      await ctx.runMutation(internal.taskRuns.updateVSCodeMetadataInternal, {
+        taskRunId: run._id,
+        ...patch,
+      });
+    }
+  }
+
+  if (stopFailures.length > 0) {
+    return stopFailures;
+  }
+
+  for (const run of stopRuns) {
+    if (!shouldMarkTaskRunStopped(run)) {
+      continue;
+    }
+
+    const taskRun = runsById.get(run._id);
+    if (!taskRun) {
+      continue;
+    }
+
+    await ctx.runMutation(api.taskRuns.failByTeamMember, {
+      teamSlugOrId,
+      id: taskRun._id,
+      errorMessage,
+      exitCode: 130,
+    });
+  }
+  return [];
+}
+
 async function handleStopTask(
   ctx: ActionCtx,
   taskId: string,
@@ -2908,98 +3129,18 @@ async function handleStopTask(
       teamId,
       userId,
     });
-    const stopRuns = runs.map(toTaskRunStopState);
-    const runsById = new Map(runs.map((run) => [String(run._id), run]));
-    const stopRunsById = new Map(stopRuns.map((run) => [run._id, run]));
-    const stopTargets = collectTaskStopTargets(stopRuns);
-
-    const stopFailures: string[] = [];
-
-    for (const target of stopTargets) {
-      const stoppedAt = Date.now();
-      const run = runsById.get(target.runId);
-      const stopRun = stopRunsById.get(target.runId);
-      if (!run || !stopRun) {
-        continue;
-      }
-
-      try {
-        if (target.provider === "morph") {
-          const morphResponse = await morphFetch(`/instance/${target.instanceId}`, {
-            method: "DELETE",
-          });
-
-          if (!morphResponse.ok && morphResponse.status !== 404) {
-            const errorText = await morphResponse.text();
-            throw new Error(
-              `Failed to stop Morph instance ${target.instanceId}: HTTP ${morphResponse.status} ${errorText.slice(0, 200)}`
-            );
-          }
-
-          await recordMorphActivity(ctx, target.instanceId, "stop");
-        } else {
-          const actionsApi = getActionsApiForProvider(target.provider);
-          await ctx.runAction(actionsApi.stopInstance, {
-            instanceId: target.instanceId,
-          });
-          await recordProviderStopActivity(ctx, target.provider, target.instanceId);
-        }
-      } catch (error) {
-        if (!isIgnorableTaskStopError(error)) {
-          stopFailures.push(
-            `${target.provider}:${target.instanceId}: ${error instanceof Error ? error.message : String(error)}`
-          );
-          continue;
-        }
-      }
-
-      try {
-        await ctx.runMutation(api.devboxInstances.updateStatus, {
-          teamSlugOrId,
-          providerInstanceId: target.instanceId,
-          status: "stopped",
-        });
-      } catch (error) {
-        console.warn("[cmux.tasks.stop] Failed to update devbox status:", {
-          instanceId: target.instanceId,
-          error: error instanceof Error ? error.message : String(error),
-        });
-      }
-
-      const patch = buildStoppedTaskRunMetadataPatch(stopRun, stoppedAt);
-
-      if (patch) {
-        await ctx.runMutation(internal.taskRuns.updateVSCodeMetadataInternal, {
-          taskRunId: run._id,
-          ...patch,
-        });
-      }
-    }
-
+    const stopFailures = await stopTaskRuns(
+      ctx,
+      runs,
+      teamSlugOrId,
+      "Task stopped by user"
+    );
     if (stopFailures.length > 0) {
       const message = `Failed to stop ${stopFailures.length} sandbox(es): ${stopFailures.join("; ")}`;
       console.error("[cmux.tasks.stop] Sandbox stop failures:", message);
       return jsonResponse({ code: 500, message }, 500);
     }
 
-    for (const run of stopRuns) {
-      if (!shouldMarkTaskRunStopped(run)) {
-        continue;
-      }
-
-      const taskRun = runsById.get(run._id);
-      if (!taskRun) {
-        continue;
-      }
-
-      await ctx.runMutation(api.taskRuns.failByTeamMember, {
-        teamSlugOrId,
-        id: taskRun._id,
-        errorMessage: "Task stopped by user",
-        exitCode: 130,
-      });
-    }
-
     await ctx.runMutation(internal.tasks.archiveInternal, {
       taskId: taskId as Id<"tasks">,
       teamId,
@@ -3122,7 +3263,8 @@ export const taskActionRouter = httpAction(async (ctx, req) => {
 
 // ============================================================================
 // GET /api/v1/cmux/task-runs/{id}/* - Unified task run GET router
-// Handles: base (task run details), /memory, /children, /parent, /children/status
+// Handles: base (task run details), /memory, /logs, /artifacts,
+// /artifacts/{id}/download, /children, /parent, /children/status
 // ============================================================================
 export const taskRunGetRouter = httpAction(async (ctx, req) => {
   const url = new URL(req.url);
@@ -3138,12 +3280,22 @@ export const taskRunGetRouter = httpAction(async (ctx, req) => {
     return handleGetTaskRun(ctx, req);
   }
 
-  const action = pathParts[5]; // memory, children, parent
+  const action = pathParts[5]; // memory, logs, artifacts, children, parent
   const subAction = pathParts[6]; // status (for children/status)
 
   // Route to appropriate handler
   if (action === "memory") {
     return handleGetTaskRunMemory(ctx, req);
+  } else if (action === "logs") {
+    return handleGetTaskRunLogs(ctx, req);
+  } else if (action === "artifacts" && pathParts.length === 6) {
+    return handleListTaskRunArtifacts(ctx, req);
+  } else if (
+    action === "artifacts" &&
+    pathParts.length === 8 &&
+    pathParts[7] === "download"
+  ) {
+    return handleDownloadTaskRunArtifact(ctx, req);
   } else if (action === "children" && subAction === "status") {
     return handleGetChildRunsStatus(ctx, req);
   } else if (action === "children") {
@@ -3350,6 +3502,368 @@ async function handleGetTaskRunMemory(ctx: ActionCtx, req: Request): Promise<Res
   }
 }
 
+// GET /api/v1/cmux/task-runs/{taskRunId}/logs - Page through agent output
+// Query: teamSlugOrId (required), cursor ("createdAt:id" of the last entry
+// seen, as returned by the previous page)
+async function handleGetTaskRunLogs(ctx: ActionCtx, req: Request): Promise<Response> {
+  const { identity, error } = await getAuthenticatedUser(ctx);
+  if (error) return error;
+
+  const url = new URL(req.url);
+  const teamSlugOrId = url.searchParams.get("teamSlugOrId");
+  const cursorParam = url.searchParams.get("cursor");
+
+  if (!teamSlugOrId) {
+    return jsonResponse(
+      { code: 400, message: "teamSlugOrId query parameter is required" },
+      400
+    );
+  }
+
+  const [cursorTime, cursorId] = (cursorParam ?? "").split(":", 2);
+  const cursor = cursorTime ? Number(cursorTime) : 0;
+  if (
+    !Number.isFinite(cursor) ||
+    cursor < 0 ||
+    (cursorId !== undefined && !isValidConvexId(cursorId))
+  ) {
+    return jsonResponse({ code: 400, message: "Invalid cursor" }, 400);
+  }
+
+  // pathParts: ["api", "v1", "cmux", "task-runs", "{taskRunId}", "logs"]
+  const pathParts = url.pathname.split("/").filter(Boolean);
+  const taskRunId = pathParts[4];
+
+  if (!taskRunId || !isValidConvexId(taskRunId)) {
+    return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+  }
+
+  try {
+    const userId = identity!.subject;
+    const teamId = await resolveTeamIdForHttp(ctx, teamSlugOrId);
+
+    if (!teamId) {
+      return jsonResponse(
+        { code: 404, message: `Team not found: ${teamSlugOrId}` },
+        404
+      );
+    }
+
+    const taskRun = await ctx.runQuery(internal.taskRuns.getById, {
+      id: taskRunId as Id<"taskRuns">,
+    });
+
+    if (!taskRun || taskRun.teamId !== teamId || taskRun.userId !== userId) {
+      return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+    }
+
+    const events = await ctx.runQuery(internal.taskRunActivity.listAfter, {
+      taskRunId: taskRunId as Id<"taskRuns">,
+      after: cursor,
+      afterId: cursorId as Id<"taskRunActivity"> | undefined,
+    });
+
+    const entries = events.map((e) => ({
+      id: e._id,
+      timestamp: e.createdAt,
+      type: e.type,
+      toolName: e.toolName,
+      summary: e.summary,
+      detail: e.detail,
+    }));
+    const last = events[events.length - 1];
+    const nextCursor = last
+      ? `${last.createdAt}:${last._id}`
+      : (cursorParam ?? "");
+    const done =
+      taskRun.status !== "pending" && taskRun.status !== "running";
+
+    return jsonResponse({
+      entries,
+      cursor: nextCursor,
+      status: taskRun.status,
+      done,
+    });
+  } catch (err) {
+    if (isConvexIdValidationError(err)) {
+      return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+    }
+    console.error("[cmux.taskRunLogs] Error:", err);
+    return jsonResponse(
+      { code: 500, message: "Failed to get task run logs" },
+      500
+    );
+  }
+}
+
+// Resolve the task run at pathParts[4] for the authenticated user, checking
+// it belongs to them and to teamSlugOrId (the query parameter unless given).
+// Returns a ready error response otherwise.
+async function getOwnedTaskRunForHttp(
+  ctx: ActionCtx,
+  req: Request,
+  teamSlugOrIdOverride?: string
+): Promise<
+  | { taskRun: Doc<"taskRuns">; teamId: string; userId: string; response?: undefined }
+  | { response: Response }
+> {
+  const { identity, error } = await getAuthenticatedUser(ctx);
+  if (error) return { response: error };
+
+  const url = new URL(req.url);
+  const teamSlugOrId =
+    teamSlugOrIdOverride ?? url.searchParams.get("teamSlugOrId");
+  if (!teamSlugOrId) {
+    return {
+      response: jsonResponse(
+        { code: 400, message: "teamSlugOrId is required" },
+        400
+      ),
+    };
+  }
+
+  const taskRunId = url.pathname.split("/").filter(Boolean)[4];
+  if (!taskRunId || !isValidConvexId(taskRunId)) {
+    return {
+      response: jsonResponse({ code: 404, message: "Task run not found" }, 404),
+    };
+  }
+
+  const userId = identity!.subject;
+  const teamId = await resolveTeamIdForHttp(ctx, teamSlugOrId);
+  if (!teamId) {
+    return {
+      response: jsonResponse(
+        { code: 404, message: `Team not found: ${teamSlugOrId}` },
+        404
+      ),
+    };
+  }
+
+  const taskRun = await ctx.runQuery(internal.taskRuns.getById, {
+    id: taskRunId as Id<"taskRuns">,
+  });
+  if (!taskRun || taskRun.teamId !== teamId || taskRun.userId !== userId) {
+    return {
+      response: jsonResponse({ code: 404, message: "Task run not found" }, 404),
+    };
+  }
+  return { taskRun, teamId, userId };
+}
+
+// GET /api/v1/cmux/task-runs/{taskRunId}/artifacts - List a run's files
+// (screenshots and videos)
+async function handleListTaskRunArtifacts(ctx: ActionCtx, req: Request): Promise<Response> {
+  try {
+    const owned = await getOwnedTaskRunForHttp(ctx, req);
+    if (owned.response) return owned.response;
+
+    const artifacts = await ctx.runQuery(internal.taskRuns.listArtifactsInternal, {
+      runId: owned.taskRun._id,
+    });
+    return jsonResponse({ artifacts });
+  } catch (err) {
+    if (isConvexIdValidationError(err)) {
+      return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+    }
+    console.error("[cmux.taskRunArtifacts] Error:", err);
+    return jsonResponse(
+      { code: 500, message: "Failed to list task run artifacts" },
+      500
+    );
+  }
+}
+
+// GET /api/v1/cmux/task-runs/{taskRunId}/artifacts/{artifactId}/download
+// Serves the file, honouring a "Range: bytes=N-" header so interrupted
+// downloads can resume.
+async function handleDownloadTaskRunArtifact(ctx: ActionCtx, req: Request): Promise<Response> {
+  try {
+    const owned = await getOwnedTaskRunForHttp(ctx, req);
+    if (owned.response) return owned.response;
+
+    // pathParts: ["api", "v1", "cmux", "task-runs", "{taskRunId}", "artifacts", "{artifactId}", "download"]
+    const artifactId = new URL(req.url).pathname.split("/").filter(Boolean)[6];
+    const artifacts = await ctx.runQuery(internal.taskRuns.listArtifactsInternal, {
+      runId: owned.taskRun._id,
+    });
+    const artifact = artifacts.find((a) => a.id === artifactId);
+    const blob = artifact ? await ctx.storage.get(artifact.id) : null;
+    if (!artifact || !blob) {
+      return jsonResponse({ code: 404, message: "Artifact not found" }, 404);
+    }
+
+    const size = blob.size;
+    const headers: Record<string, string> = {
+      "Content-Type": artifact.contentType || "application/octet-stream",
+      "Accept-Ranges": "bytes",
+    };
+    const range = /^bytes=(\d+)-$/.exec(req.headers.get("Range") ?? "");
+    if (range) {
+      const start = Number(range[1]);
+      if (start >= size) {
+        return new Response(null, {
+          status: 416,
+          headers: { ...headers, "Content-Range": `bytes */${size}` },
+        });
+      }
+      return new Response(blob.slice(start), {
+        status: 206,
+        headers: {
+          ...headers,
+          "Content-Range": `bytes ${start}-${size - 1}/${size}`,
+          "Content-Length": String(size - start),
+        },
+      });
+    }
+    return new Response(blob, {
+      status: 200,
+      headers: { ...headers, "Content-Length": String(size) },
+    });
+  } catch (err) {
+    if (isConvexIdValidationError(err)) {
+      return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+    }
+    console.error("[cmux.taskRunArtifactDownload] Error:", err);
+    return jsonResponse(
+      { code: 500, message: "Failed to download artifact" },
+      500
+    );
+  }
+}
+
+// ============================================================================
+// POST /api/v1/cmux/task-runs/{id}/{action} - Per-run control
+// Handles: /cancel, /retry
+// ============================================================================
+export const taskRunPostRouter = httpAction(async (ctx, req) => {
+  const contentTypeError = verifyContentType(req);
+  if (contentTypeError) return contentTypeError;
+
+  // pathParts: ["api", "v1", "cmux", "task-runs", "{id}", "{action}"]
+  const pathParts = new URL(req.url).pathname.split("/").filter(Boolean);
+  const action = pathParts[5];
+  if (pathParts.length !== 6 || (action !== "cancel" && action !== "retry")) {
+    return jsonResponse({ code: 404, message: "Not found" }, 404);
+  }
+
+  let body: { teamSlugOrId?: string; agentName?: string; cancelRunning?: boolean };
+  try {
+    body = await req.json();
+  } catch {
+    return jsonResponse({ code: 400, message: "Invalid JSON body" }, 400);
+  }
+  if (!body.teamSlugOrId) {
+    return jsonResponse(
+      { code: 400, message: "teamSlugOrId is required" },
+      400
+    );
+  }
+
+  try {
+    const owned = await getOwnedTaskRunForHttp(ctx, req, body.teamSlugOrId);
+    if (owned.response) return owned.response;
+
+    if (action === "cancel") {
+      return await handleCancelTaskRun(ctx, owned.taskRun, body.teamSlugOrId);
+    }
+    return await handleRetryTaskRun(ctx, owned, body.teamSlugOrId, body);
+  } catch (err) {
+    if (isConvexIdValidationError(err)) {
+      return jsonResponse({ code: 404, message: "Task run not found" }, 404);
+    }
+    console.error(`[cmux.taskRuns.${action}] Error:`, err);
+    return jsonResponse(
+      { code: 500, message: `Failed to ${action} task run` },
+      500
+    );
+  }
+});
+
+// POST /api/v1/cmux/task-runs/{id}/cancel - Stop one run's sandbox and mark
+// it failed; the task's other runs keep going. Cancelling a finished run is
+// a no-op.
+async function handleCancelTaskRun(
+  ctx: ActionCtx,
+  taskRun: Doc<"taskRuns">,
+  teamSlugOrId: string
+): Promise<Response> {
+  const failures = await stopTaskRuns(
+    ctx,
+    [taskRun],
+    teamSlugOrId,
+    "Task run cancelled by user"
+  );
+  if (failures.length > 0) {
+    return jsonResponse(
+      { code: 500, message: `Failed to stop sandbox: ${failures.join("; ")}` },
+      500
+    );
+  }
+  return jsonResponse({ cancelled: true });
+}
+
+// POST /api/v1/cmux/task-runs/{id}/retry - Create a new run of the same task
+// with the run's prompt, task class, environment and parent. An in-flight run is
+// rejected with 409 unless cancelRunning is set. The new run is pending;
+// the caller starts its agent, as with POST /api/v1/cmux/tasks.
+async function handleRetryTaskRun(
+  ctx: ActionCtx,
+  owned: { taskRun: Doc<"taskRuns">; teamId: string; userId: string },
+  teamSlugOrId: string,
+  body: { agentName?: string; cancelRunning?: boolean }
+): Promise<Response> {
+  const { taskRun, teamId, userId } = owned;
+
+  if (shouldMarkTaskRunStopped(taskRun)) {
+    if (!body.cancelRunning) {
+      return jsonResponse(
+        {
+          code: 409,
+          message: `Task run is still ${taskRun.status}; cancel it first or pass cancelRunning`,
+        },
+        409
+      );
+    }
+    const cancelled = await handleCancelTaskRun(ctx, taskRun, teamSlugOrId);
+    if (!cancelled.ok) return cancelled;
+  }
+
+  const task = await ctx.runQuery(internal.tasks.getByIdInternal, {
+    id: taskRun.taskId,
+  });
+  if (!task) {
+    return jsonResponse({ code: 404, message: "Task not found" }, 404);
+  }
+
+  const agentName = body.agentName?.trim() || taskRun.agentName;
+  const created = await ctx.runMutation(internal.taskRuns.createInternal, {
+    teamId,
+    userId,
+    taskId: taskRun.taskId,
+    prompt: taskRun.prompt,
+    agentName,
+    // The variant belongs to the original agent
+    selectedVariant:
+      agentName === taskRun.agentName ? taskRun.selectedVariant : undefined,
+    taskClass: taskRun.taskClass,
+    environmentId: taskRun.environmentId,
+    parentRunId: taskRun.parentRunId,
+  }) as { taskRunId: Id<"taskRuns">; jwt: string };
+
+  return jsonResponse({
+    taskId: taskRun.taskId,
+    taskRunId: created.taskRunId,
+    jwt: created.jwt,
+    agentName,
+    retriedRunId: taskRun._id,
+    prompt: taskRun.prompt,
+    repository: task.projectFullName,
+    baseBranch: task.baseBranch,
+    environmentId: taskRun.environmentId,
+  });
+}
+
 // ============================================================================
 // D4.2: Agent Teams - Parent-Child Task Relationship Handlers
 // ============================================================================
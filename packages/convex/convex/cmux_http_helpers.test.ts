@@ -1,5 +1,11 @@
 import { describe, expect, it } from "vitest";
-import { isValidConvexId, isConvexIdValidationError } from "./cmux_http_helpers";
+import {
+  isValidConvexId,
+  isConvexIdValidationError,
+  pageAfterCursor,
+  parseListCursor,
+  parseListParams,
+} from "./cmux_http_helpers";
 
 describe("isValidConvexId", () => {
   describe("valid IDs", () => {
@@ -86,3 +92,87 @@ describe("isConvexIdValidationError", () => {
     });
   });
 });
+
+describe("parseListParams", () => {
+  it("defaults the page size and leaves filters unset", () => {
+    expect(parseListParams(new URLSearchParams())).toEqual({
+      limit: 100,
+      cursor: undefined,
+      status: undefined,
+      repository: undefined,
+      createdAfter: undefined,
+    });
+  });
+
+  it("parses filters and caps the limit", () => {
+    const params = parseListParams(
+      new URLSearchParams(
+        "limit=500&cursor=opaque&status=Running&repository=acme/api&createdAfter=1690000000000"
+      )
+    );
+    expect(params).toEqual({
+      limit: 100,
+      cursor: "opaque",
+      status: "running",
+      repository: "acme/api",
+      createdAfter: 1690000000000,
+    });
+  });
+
+  it("rejects malformed values", () => {
+    expect(parseListParams(new URLSearchParams("limit=0"))).toEqual({ error: "Invalid limit" });
+    expect(parseListParams(new URLSearchParams("createdAfter=soon"))).toEqual({
+      error: "Invalid createdAfter",
+    });
+  });
+});
+
+describe("parseListCursor", () => {
+  it("splits time and id", () => {
+    expect(parseListCursor("1700000000000:cmux_abc")).toEqual({
+      time: 1700000000000,
+      id: "cmux_abc",
+    });
+  });
+
+  it("rejects malformed cursors", () => {
+    expect(parseListCursor("abc")).toBeUndefined();
+    expect(parseListCursor("12")).toBeUndefined();
+    expect(parseListCursor("soon:cmux_abc")).toBeUndefined();
+  });
+});
+
+describe("pageAfterCursor", () => {
+  const items = [
+    { id: "a", createdAt: 100 },
+    { id: "d", createdAt: 300 },
+    { id: "b", createdAt: 200 },
+    { id: "c", createdAt: 200 },
+  ];
+  const key = (item: { id: string; createdAt: number }) => ({
+    time: item.createdAt,
+    id: item.id,
+  });
+
+  it("walks every item newest first without skipping shared timestamps", () => {
+    const seen: string[] = [];
+    let cursor: string | undefined;
+    do {
+      const result = pageAfterCursor(
+        items,
+        cursor ? parseListCursor(cursor) : undefined,
+        2,
+        key
+      );
+      seen.push(...result.page.map((item) => item.id));
+      cursor = result.nextCursor;
+    } while (cursor);
+    expect(seen).toEqual(["d", "c", "b", "a"]);
+  });
+
+  it("returns no cursor when the last page is exactly full", () => {
+    const { page, nextCursor } = pageAfterCursor(items, undefined, 4, key);
+    expect(page).toHaveLength(4);
+    expect(nextCursor).toBeUndefined();
+  });
+});
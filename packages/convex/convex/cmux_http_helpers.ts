@@ -13,3 +13,109 @@ export function isConvexIdValidationError(error: unknown): boolean {
   const errorMessage = error instanceof Error ? error.message : String(error);
   return errorMessage.includes("Invalid ID") || errorMessage.includes("not a valid ID");
 }
+
+/** Page size for list endpoints when the request does not set `limit`. */
+export const DEFAULT_LIST_PAGE_SIZE = 100;
+const MAX_LIST_PAGE_SIZE = 100;
+
+/** Position of the last item of an in-memory page, newest first. */
+export interface ListCursor {
+  time: number;
+  id: string;
+}
+
+/** Paging and filter query parameters shared by the list endpoints. */
+export interface ListParams {
+  limit: number;
+  // Opaque; the endpoint that issued it decides the format
+  cursor?: string;
+  status?: string;
+  repository?: string;
+  createdAfter?: number;
+}
+
+/**
+ * Parse limit, cursor, status, repository and createdAfter. Returns an
+ * error message for malformed values. Status is lower-cased.
+ */
+export function parseListParams(
+  params: URLSearchParams
+): ListParams | { error: string } {
+  let limit = DEFAULT_LIST_PAGE_SIZE;
+  const limitParam = params.get("limit");
+  if (limitParam) {
+    const parsed = parseInt(limitParam, 10);
+    if (isNaN(parsed) || parsed <= 0) {
+      return { error: "Invalid limit" };
+    }
+    limit = Math.min(parsed, MAX_LIST_PAGE_SIZE);
+  }
+
+  let createdAfter: number | undefined;
+  const createdAfterParam = params.get("createdAfter");
+  if (createdAfterParam) {
+    createdAfter = Number(createdAfterParam);
+    if (!Number.isFinite(createdAfter)) {
+      return { error: "Invalid createdAfter" };
+    }
+  }
+
+  return {
+    limit,
+    cursor: params.get("cursor") || undefined,
+    status: params.get("status")?.toLowerCase() || undefined,
+    repository: params.get("repository") || undefined,
+    createdAfter,
+  };
+}
+
+export function formatListCursor(cursor: ListCursor): string {
+  return `${cursor.time}:${cursor.id}`;
+}
+
+export function parseListCursor(raw: string): ListCursor | undefined {
+  const [time, id] = raw.split(":", 2);
+  if (!time || !Number.isFinite(Number(time)) || !id) {
+    return undefined;
+  }
+  return { time: Number(time), id };
+}
+
+/**
+ * Return the page of items after cursor, newest first, and the cursor for
+ * the next page (undefined on the last page). Items are ordered by time then
+ * id, both descending, so items sharing a timestamp are not skipped between
+ * pages.
+ */
+export function pageAfterCursor<T>(
+  items: T[],
+  cursor: ListCursor | undefined,
+  limit: number,
+  key: (item: T) => ListCursor
+): { page: T[]; nextCursor?: string } {
+  const sorted = items
+    .map((item) => ({ item, key: key(item) }))
+    .sort((a, b) =>
+      a.key.time !== b.key.time
+        ? b.key.time - a.key.time
+        : a.key.id < b.key.id
+          ? 1
+          : a.key.id > b.key.id
+            ? -1
+            : 0
+    );
+  const after = cursor
+    ? sorted.filter(
+        ({ key }) =>
+          key.time < cursor.time ||
+          (key.time === cursor.time && key.id < cursor.id)
+      )
+    : sorted;
+
+  const page = after.slice(0, limit);
+  const nextCursor =
+    after.length > limit
+      ? formatListCursor(page[page.length - 1].key)
+      : undefined;
+  return { page: page.map(({ item }) => item), nextCursor };
+}
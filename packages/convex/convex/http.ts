@@ -62,6 +62,7 @@ import {
   taskGetRouter as cmuxTaskGetRouter,
   taskActionRouter as cmuxTaskActionRouter,
   taskRunGetRouter as cmuxTaskRunGetRouter,
+  taskRunPostRouter as cmuxTaskRunPostRouter,
 } from "./cmux_http";
 import {
   createInstance as devboxV2CreateInstance,
@@ -435,13 +436,20 @@ http.route({
   handler: cmuxTaskActionRouter,
 });
 
-// Task run GET endpoints (memory, children, parent, children/status)
+// Task run GET endpoints (details, memory, logs, artifacts, children, parent)
 http.route({
   pathPrefix: "/api/v1/cmux/task-runs/",
   method: "GET",
   handler: cmuxTaskRunGetRouter,
 });
 
+// Task run POST endpoints (cancel, retry)
+http.route({
+  pathPrefix: "/api/v1/cmux/task-runs/",
+  method: "POST",
+  handler: cmuxTaskRunPostRouter,
+});
+
 // =============================================================================
 // v2/devbox API - Unified devbox management with provider selection (Morph/E2B)
 // =============================================================================
@@ -1,5 +1,6 @@
 import { v } from "convex/values";
-import { internalMutation, query } from "./_generated/server";
+import type { Doc } from "./_generated/dataModel";
+import { internalMutation, internalQuery, query } from "./_generated/server";
 
 /**
  * Internal mutation called by the HTTP endpoint after JWT validation.
@@ -102,3 +103,42 @@ export const getByTaskRunAsc = query({
       .take(args.limit ?? 200);
   },
 });
+
+/**
+ * Page through activity events after a (createdAt, _id) cursor, oldest-first.
+ * Backs the CLI log stream (GET /api/v1/cmux/task-runs/{id}/logs).
+ */
+export const listAfter = internalQuery({
+  args: {
+    taskRunId: v.id("taskRuns"),
+    after: v.optional(v.number()),
+    // Last entry already seen at `after`. Entries sharing a createdAt are
+    // ordered by creation time, so paging resumes right after this one
+    // instead of skipping every entry with the same timestamp.
+    afterId: v.optional(v.id("taskRunActivity")),
+    limit: v.optional(v.number()),
+  },
+  handler: async (ctx, args) => {
+    const after = args.after ?? 0;
+    const limit = args.limit ?? 200;
+    const scan = ctx.db
+      .query("taskRunActivity")
+      .withIndex("by_task_run", (q) =>
+        q.eq("taskRunId", args.taskRunId).gte("createdAt", after)
+      )
+      .order("asc");
+
+    const events: Doc<"taskRunActivity">[] = [];
+    let pastCursor = args.afterId === undefined && after === 0;
+    for await (const event of scan) {
+      if (!pastCursor && event.createdAt === after) {
+        pastCursor = event._id === args.afterId;
+        continue;
+      }
+      pastCursor = true;
+      events.push(event);
+      if (events.length >= limit) break;
+    }
+    return events;
+  },
+});
@@ -2438,6 +2438,40 @@ export const getById = internalQuery({
   },
 });
 
+/**
+ * Files recorded for a task run: every screenshot and video from its
+ * screenshot sets, newest set first. The storage ID doubles as the artifact
+ * ID. Backs GET /api/v1/cmux/task-runs/{id}/artifacts.
+ */
+export const listArtifactsInternal = internalQuery({
+  args: { runId: v.id("taskRuns") },
+  handler: async (ctx, args) => {
+    const sets = await ctx.db
+      .query("taskRunScreenshotSets")
+      .withIndex("by_run_capturedAt", (q) => q.eq("runId", args.runId))
+      .order("desc")
+      .collect();
+
+    const files = sets.flatMap((set) => [
+      ...set.images.map((file) => ({ file, kind: "screenshot", set })),
+      ...(set.videos ?? []).map((file) => ({ file, kind: "video", set })),
+    ]);
+    return await Promise.all(
+      files.map(async ({ file, kind, set }) => {
+        const stored = await ctx.db.system.get(file.storageId);
+        return {
+          id: file.storageId,
+          name: file.fileName ?? file.storageId,
+          kind,
+          contentType: file.mimeType,
+          size: stored?.size ?? 0,
+          createdAt: set.capturedAt,
+        };
+      }),
+    );
+  },
+});
+
 export const updateStatusPublic = authMutation({
   args: {
     teamSlugOrId: v.string(),
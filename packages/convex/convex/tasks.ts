@@ -1824,6 +1824,57 @@ export const listInternal = internalQuery({
   },
 });
 
+/**
+ * One page of listInternal's tasks, for the CLI list endpoint. Repository
+ * and createdAfter are applied in the query, so pages can come back short;
+ * follow continueCursor until isDone.
+ */
+export const listPageInternal = internalQuery({
+  args: {
+    teamId: v.string(),
+    userId: v.string(),
+    archived: v.optional(v.boolean()),
+    repository: v.optional(v.string()),
+    createdAfter: v.optional(v.number()),
+    paginationOpts: paginationOptsValidator,
+  },
+  handler: async (ctx, args) => {
+    let q;
+    if (args.archived === true) {
+      q = ctx.db
+        .query("tasks")
+        .withIndex("by_team_user_archived", (idx) =>
+          idx.eq("teamId", args.teamId).eq("userId", args.userId).eq("isArchived", true),
+        )
+        .filter((qq) => qq.neq(qq.field("isPreview"), true));
+    } else {
+      q = ctx.db
+        .query("tasks")
+        .withIndex("by_team_user_active", (idx) =>
+          idx
+            .eq("teamId", args.teamId)
+            .eq("userId", args.userId)
+            .eq("isArchived", false)
+            .eq("isPreview", false),
+        );
+    }
+
+    q = q
+      .filter((qq) => qq.eq(qq.field("linkedFromCloudTaskRunId"), undefined))
+      .filter((qq) => qq.neq(qq.field("isLocalWorkspace"), true));
+    if (args.repository !== undefined) {
+      const repository = args.repository;
+      q = q.filter((qq) => qq.eq(qq.field("projectFullName"), repository));
+    }
+    if (args.createdAfter !== undefined) {
+      const createdAfter = args.createdAfter;
+      q = q.filter((qq) => qq.gt(qq.field("createdAt"), createdAfter));
+    }
+
+    return await q.order("desc").paginate(args.paginationOpts);
+  },
+});
+
 /**
  * Look up a task by its linked GitHub Project item ID.
  * Used by Phase 4 (bi-directional status sync) and head agent task dispatch.
@@ -829,6 +829,9 @@ func Logout() error {
 	if err := ClearCachedUserProfile(); err != nil {
 		return err
 	}
+	if err := ClearDefaultTeam(); err != nil {
+		return err
+	}
 	fmt.Println("✓ Logged out successfully")
 
 	// Warn if environment variable will still provide authentication
@@ -1101,15 +1104,16 @@ func GetUserProfile() (*UserProfile, error) {
 }
 
 // GetTeamSlug returns the team slug for API calls.
-// Priority: DEVSH_TEAM env var > user profile team slug > team ID
+// Priority: DEVSH_TEAM env var > saved default team > user profile team slug > team ID
 func GetTeamSlug() (string, error) {
 	// Check for team override from environment (dev mode / CI)
 	// Support both new (DEVSH_) and legacy (DEVBOX_) env var names
-	if teamOverride := os.Getenv("DEVSH_TEAM"); teamOverride != "" {
+	if teamOverride := GetTeamOverride(); teamOverride != "" {
 		return teamOverride, nil
 	}
-	if teamOverride := os.Getenv("DEVBOX_TEAM"); teamOverride != "" {
-		return teamOverride, nil
+
+	if team, err := GetDefaultTeam(); err == nil {
+		return team.SlugOrID(), nil
 	}
 
 	profile, err := GetUserProfile()
@@ -1117,12 +1121,14 @@ func GetTeamSlug() (string, error) {
 		return "", err
 	}
 
-	if profile.TeamSlug != "" {
-		return profile.TeamSlug, nil
-	}
-	if profile.TeamID != "" {
-		return profile.TeamID, nil
+	if profile.TeamSlug == "" && profile.TeamID == "" {
+		return "", fmt.Errorf("no team found for user. Please select a team at %s", GetConfig().CmuxURL)
 	}
 
-	return "", fmt.Errorf("no team found for user. Please select a team at %s", GetConfig().CmuxURL)
+	// Remember the selected team so later commands skip the profile lookup
+	team := DefaultTeam{TeamID: profile.TeamID, Slug: profile.TeamSlug, DisplayName: profile.TeamDisplayName}
+	if err := SetDefaultTeam(team); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save default team: %v\n", err)
+	}
+	return team.SlugOrID(), nil
 }
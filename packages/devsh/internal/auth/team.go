@@ -0,0 +1,108 @@
+// internal/auth/team.go
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karlorz/devsh/internal/atomicfile"
+)
+
+// DefaultTeam is the team CLI commands use when DEVSH_TEAM is not set.
+type DefaultTeam struct {
+	TeamID      string `json:"teamId,omitempty"`
+	Slug        string `json:"slug"`
+	DisplayName string `json:"displayName,omitempty"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+// SlugOrID returns the identifier to send as teamSlugOrId.
+func (t *DefaultTeam) SlugOrID() string {
+	if t.Slug != "" {
+		return t.Slug
+	}
+	return t.TeamID
+}
+
+// getDefaultTeamPath returns the path to the default team file.
+func getDefaultTeamPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	cfg := GetConfig()
+	filename := "team_prod.json"
+	if cfg.IsDev {
+		filename = "team_dev.json"
+	}
+
+	return filepath.Join(configDir, filename), nil
+}
+
+// GetTeamOverride returns the team from DEVSH_TEAM (or legacy DEVBOX_TEAM).
+func GetTeamOverride() string {
+	if team := os.Getenv("DEVSH_TEAM"); team != "" {
+		return team
+	}
+	return os.Getenv("DEVBOX_TEAM")
+}
+
+// GetDefaultTeam returns the saved default team.
+func GetDefaultTeam() (*DefaultTeam, error) {
+	path, err := getDefaultTeamPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no default team")
+	}
+
+	var team DefaultTeam
+	if err := json.Unmarshal(data, &team); err != nil {
+		return nil, fmt.Errorf("invalid default team file: %w", err)
+	}
+	if team.SlugOrID() == "" {
+		return nil, fmt.Errorf("no default team")
+	}
+	return &team, nil
+}
+
+// SetDefaultTeam saves the default team. The file is replaced atomically
+// so concurrent commands never see a partial write.
+func SetDefaultTeam(team DefaultTeam) error {
+	if team.SlugOrID() == "" {
+		return fmt.Errorf("team slug or ID is required")
+	}
+
+	path, err := getDefaultTeamPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	team.UpdatedAt = time.Now().Unix()
+	data, err := json.MarshalIndent(team, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, data, 0600)
+}
+
+// ClearDefaultTeam removes the saved default team.
+func ClearDefaultTeam() error {
+	path, err := getDefaultTeamPath()
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(path)
+	return nil
+}
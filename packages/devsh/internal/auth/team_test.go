@@ -0,0 +1,69 @@
+package auth
+
+import "testing"
+
+func TestDefaultTeamRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := GetDefaultTeam(); err == nil {
+		t.Fatal("expected no default team in a fresh config dir")
+	}
+
+	if err := SetDefaultTeam(DefaultTeam{TeamID: "team_1", Slug: "dev", DisplayName: "Dev"}); err != nil {
+		t.Fatalf("SetDefaultTeam: %v", err)
+	}
+	team, err := GetDefaultTeam()
+	if err != nil {
+		t.Fatalf("GetDefaultTeam: %v", err)
+	}
+	if team.Slug != "dev" || team.TeamID != "team_1" || team.UpdatedAt == 0 {
+		t.Errorf("unexpected team: %+v", team)
+	}
+
+	if err := ClearDefaultTeam(); err != nil {
+		t.Fatalf("ClearDefaultTeam: %v", err)
+	}
+	if _, err := GetDefaultTeam(); err == nil {
+		t.Error("expected no default team after clear")
+	}
+
+	if err := SetDefaultTeam(DefaultTeam{}); err == nil {
+		t.Error("expected error for an empty team")
+	}
+}
+
+func TestGetTeamSlugPrefersOverrideThenDefault(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEVSH_TEAM", "")
+	t.Setenv("DEVBOX_TEAM", "")
+
+	if err := SetDefaultTeam(DefaultTeam{TeamID: "team_2"}); err != nil {
+		t.Fatal(err)
+	}
+	if slug, err := GetTeamSlug(); err != nil || slug != "team_2" {
+		t.Errorf("GetTeamSlug = %q, %v; want saved default", slug, err)
+	}
+
+	t.Setenv("DEVSH_TEAM", "ci-team")
+	if slug, err := GetTeamSlug(); err != nil || slug != "ci-team" {
+		t.Errorf("GetTeamSlug = %q, %v; want env override", slug, err)
+	}
+}
+
+func TestGetTeamSlugSavesProfileTeam(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEVSH_TEAM", "")
+	t.Setenv("DEVBOX_TEAM", "")
+
+	profile := &UserProfile{UserID: "u1", TeamID: "team_3", TeamSlug: "acme"}
+	if err := CacheUserProfile(profile); err != nil {
+		t.Fatal(err)
+	}
+	if slug, err := GetTeamSlug(); err != nil || slug != "acme" {
+		t.Fatalf("GetTeamSlug = %q, %v", slug, err)
+	}
+	team, err := GetDefaultTeam()
+	if err != nil || team.Slug != "acme" {
+		t.Errorf("profile team was not saved as default: %+v, %v", team, err)
+	}
+}
@@ -12,14 +12,29 @@ import (
 
 var configCmd = &cobra.Command{
 	Use:   "config",
-	Short: "Show current configuration",
-	Long: `Show current configuration values and their sources.
+	Short: "Show and edit configuration",
+	Long: `Show current configuration values and their sources, and edit the config
+file (~/.config/cmux/config.toml).
 
 Configuration priority (highest to lowest):
-  1. CLI flags (--api-url, --convex-url, --server-url)
-  2. Environment variables (CMUX_API_URL, CONVEX_SITE_URL, CMUX_SERVER_URL, etc.)
-  3. Build-time values (compiled into binary)
-  4. Hardcoded defaults
+  1. CLI flags (--api-url, --convex-url, --provider)
+  2. Environment variables (CMUX_API_URL, CONVEX_SITE_URL, CMUX_SERVER_URL, etc.;
+     dev builds also read a .env file)
+  3. Config file profile (selected by --profile, DEVSH_PROFILE, or
+     'devsh config use <profile>')
+  4. Build-time values (compiled into binary)
+  5. Hardcoded defaults
+
+Prod builds prefer build-time auth and URL values over environment variables
+and profiles.
+
+Config file commands:
+  devsh config list                         # Effective settings and their sources
+  devsh config get server_url               # Value stored in the active profile
+  devsh config set server_url URL           # Store a value in the active profile
+  devsh config set api_url URL --profile self-hosted
+  devsh config unset server_url
+  devsh config use self-hosted              # Switch the active profile
 
 Environment variables:
   STACK_PROJECT_ID              Stack Auth project ID
@@ -32,6 +47,11 @@ Environment variables:
 }
 
 func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configUseCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
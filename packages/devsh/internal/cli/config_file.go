@@ -0,0 +1,244 @@
+// internal/cli/config_file.go
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/karlorz/devsh/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List settings with their effective values and sources",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting stored in the active profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Store a setting in the active profile",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting from the active profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUnset,
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the profile used when --profile and DEVSH_PROFILE are not set",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUse,
+}
+
+// configFlagValues returns settings overridden by CLI flags
+func configFlagValues() map[string]string {
+	return map[string]string{
+		"api_url":         flagAPIURL,
+		"convex_site_url": flagConvexSiteURL,
+		"provider":        flagProvider,
+	}
+}
+
+// applyConfigProfile exports the active config file profile into unset
+// environment variables. Problems are only warnings so a broken config file
+// never blocks `devsh config` from repairing it, and a missing profile is not
+// reported while `devsh config` is creating it.
+func applyConfigProfile(cmd *cobra.Command) {
+	f, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config file: %v\n", err)
+		return
+	}
+	if err := f.Apply(f.ActiveProfile(flagProfile)); err != nil && cmd.Parent() != configCmd {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+func lookupConfigKey(name string) (config.Key, error) {
+	if k, ok := config.LookupKey(name); ok {
+		return k, nil
+	}
+	names := make([]string, len(config.Keys))
+	for i, k := range config.Keys {
+		names[i] = k.Name
+	}
+	return config.Key{}, fmt.Errorf("unknown config key %q (valid keys: %s)", name, strings.Join(names, ", "))
+}
+
+// configSettingOutput is one row of `devsh config list`
+type configSettingOutput struct {
+	Key    string `json:"key"`
+	EnvVar string `json:"envVar"`
+	Value  string `json:"value,omitempty"`
+	Source string `json:"source"` // "flag", "env", "profile:<name>", or "unset"
+}
+
+// effectiveConfigSetting resolves a setting the way the CLI sees it after
+// the active profile has been applied.
+func effectiveConfigSetting(k config.Key, flags map[string]string) configSettingOutput {
+	out := configSettingOutput{Key: k.Name, EnvVar: k.EnvVar, Source: "unset"}
+	if v := flags[k.Name]; v != "" {
+		out.Value, out.Source = v, "flag"
+	} else if v := os.Getenv(k.EnvVar); v != "" {
+		out.Value, out.Source = v, "env"
+		if profile := config.AppliedFrom(k.EnvVar); profile != "" {
+			out.Source = "profile:" + profile
+		}
+	}
+	if k.Secret && out.Value != "" {
+		out.Value = maskMiddle(out.Value)
+	}
+	return out
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	f, err := config.Load()
+	if err != nil {
+		return err
+	}
+	path, _ := config.Path()
+	profile := f.ActiveProfile(flagProfile)
+
+	flags := configFlagValues()
+	settings := make([]configSettingOutput, 0, len(config.Keys))
+	for _, k := range config.Keys {
+		settings = append(settings, effectiveConfigSetting(k, flags))
+	}
+
+	if flagJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"path":     path,
+			"profile":  profile,
+			"profiles": f.ProfileNames(),
+			"settings": settings,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Config file: %s\n", path)
+	fmt.Printf("Profile:     %s\n", profile)
+	if names := f.ProfileNames(); len(names) > 0 {
+		fmt.Printf("Profiles:    %s\n", strings.Join(names, ", "))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV VAR")
+	fmt.Fprintln(w, "---\t-----\t------\t-------")
+	for _, s := range settings {
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, value, s.Source, s.EnvVar)
+	}
+	return w.Flush()
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	k, err := lookupConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	f, err := config.Load()
+	if err != nil {
+		return err
+	}
+	profile := f.ActiveProfile(flagProfile)
+	value, ok := f.Get(profile, k.Name)
+	if !ok {
+		return fmt.Errorf("%s is not set in profile %q", k.Name, profile)
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	k, err := lookupConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	f, err := config.Load()
+	if err != nil {
+		return err
+	}
+	profile := f.ActiveProfile(flagProfile)
+	if err := config.ValidateProfileName(profile); err != nil {
+		return err
+	}
+	f.Set(profile, k.Name, args[1])
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("Set %s in profile %q\n", k.Name, profile)
+	if os.Getenv(k.EnvVar) != "" && config.AppliedFrom(k.EnvVar) == "" {
+		fmt.Printf("Note: %s is set in the environment and takes precedence\n", k.EnvVar)
+	}
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	k, err := lookupConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	f, err := config.Load()
+	if err != nil {
+		return err
+	}
+	profile := f.ActiveProfile(flagProfile)
+	if !f.Unset(profile, k.Name) {
+		return fmt.Errorf("%s is not set in profile %q", k.Name, profile)
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Removed %s from profile %q\n", k.Name, profile)
+	return nil
+}
+
+func runConfigUse(cmd *cobra.Command, args []string) error {
+	f, err := config.Load()
+	if err != nil {
+		return err
+	}
+	profile := args[0]
+	if err := config.ValidateProfileName(profile); err != nil {
+		return err
+	}
+	f.Profile = profile
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("Active profile: %s\n", profile)
+	if _, ok := f.Profiles[profile]; !ok {
+		fmt.Printf("Note: profile %q has no settings yet; add some with 'devsh config set <key> <value>'\n", profile)
+	}
+	if env := os.Getenv(config.ProfileEnvVar); env != "" && env != profile {
+		fmt.Printf("Note: %s=%s takes precedence in this shell\n", config.ProfileEnvVar, env)
+	}
+	return nil
+}
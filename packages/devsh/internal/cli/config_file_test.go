@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/karlorz/devsh/internal/config"
+)
+
+func useTestConfigHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ProfileEnvVar, "")
+	saved := flagProfile
+	t.Cleanup(func() { flagProfile = saved })
+	flagProfile = ""
+	return home
+}
+
+func TestConfigSetGetUnset(t *testing.T) {
+	home := useTestConfigHome(t)
+	flagProfile = "self-hosted"
+
+	captureStdout(t, func() {
+		if err := runConfigSet(configSetCmd, []string{"server_url", "https://server.example.com"}); err != nil {
+			t.Fatalf("config set: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(filepath.Join(home, ".config", "cmux", config.FileName))
+	if err != nil {
+		t.Fatalf("config file not written: %v", err)
+	}
+	if !strings.Contains(string(data), "[profiles.self-hosted]\nserver_url = \"https://server.example.com\"") {
+		t.Errorf("unexpected config file:\n%s", data)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runConfigGet(configGetCmd, []string{"server_url"}); err != nil {
+			t.Fatalf("config get: %v", err)
+		}
+	})
+	if strings.TrimSpace(output) != "https://server.example.com" {
+		t.Errorf("config get = %q", output)
+	}
+
+	captureStdout(t, func() {
+		if err := runConfigUnset(configUnsetCmd, []string{"server_url"}); err != nil {
+			t.Fatalf("config unset: %v", err)
+		}
+	})
+	if err := runConfigGet(configGetCmd, []string{"server_url"}); err == nil {
+		t.Error("expected error after unset")
+	}
+}
+
+func TestConfigSetRejectsUnknownKeyAndProfile(t *testing.T) {
+	useTestConfigHome(t)
+
+	if err := runConfigSet(configSetCmd, []string{"sever_url", "x"}); err == nil || !strings.Contains(err.Error(), "valid keys") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+	flagProfile = "bad profile"
+	if err := runConfigSet(configSetCmd, []string{"server_url", "x"}); err == nil {
+		t.Error("expected invalid profile name error")
+	}
+}
+
+func TestConfigUseSelectsProfile(t *testing.T) {
+	useTestConfigHome(t)
+
+	captureStdout(t, func() {
+		flagProfile = "prod"
+		if err := runConfigSet(configSetCmd, []string{"team", "acme"}); err != nil {
+			t.Fatal(err)
+		}
+		flagProfile = ""
+		if err := runConfigUse(configUseCmd, []string{"prod"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	f, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.ActiveProfile(""); got != "prod" {
+		t.Errorf("active profile = %q, want prod", got)
+	}
+}
+
+func TestEffectiveConfigSettingSources(t *testing.T) {
+	useTestConfigHome(t)
+	t.Setenv("PVE_API_TOKEN", "root@pam!devsh=0123456789")
+	t.Setenv("PVE_NODE", "")
+
+	token, _ := config.LookupKey("pve_api_token")
+	got := effectiveConfigSetting(token, nil)
+	if got.Source != "env" || got.Value != "root...6789" {
+		t.Errorf("pve_api_token = %+v, want masked env value", got)
+	}
+
+	apiURL, _ := config.LookupKey("api_url")
+	got = effectiveConfigSetting(apiURL, map[string]string{"api_url": "https://flag.example.com"})
+	if got.Source != "flag" || got.Value != "https://flag.example.com" {
+		t.Errorf("api_url = %+v, want flag", got)
+	}
+
+	node, _ := config.LookupKey("pve_node")
+	if got := effectiveConfigSetting(node, nil); got.Source != "unset" {
+		t.Errorf("pve_node = %+v, want unset", got)
+	}
+}
@@ -0,0 +1,105 @@
+// internal/cli/console.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consoleBridge bool
+	consolePort   int
+	consolePrint  bool
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console <id>",
+	Short: "Open a PVE LXC container's console (works when the worker is down)",
+	Long: `Open the Proxmox console of a pve-lxc container. The console is PVE's
+own lxc-console, so it works when the container's worker, VS Code or VNC
+services are down.
+
+By default this opens the PVE web UI's noVNC console, which needs a browser
+logged in to the PVE web UI. With --bridge, devsh instead listens on
+localhost and bridges any VNC client to the console using PVE_API_TOKEN; no
+VNC password is needed.
+
+Requires PVE_API_URL and PVE_API_TOKEN.
+
+Examples:
+  devsh console cmux-200
+  devsh console cmux-200 --print
+  devsh console cmux-200 --bridge --port 5901   # then: vncviewer localhost:5901`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instanceID := args[0]
+		if !provider.HasPveEnv() {
+			return fmt.Errorf("console needs PVE_API_URL and PVE_API_TOKEN set")
+		}
+		client, err := pvelxc.NewClientFromEnv()
+		if err != nil {
+			return fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
+		}
+
+		if consoleBridge {
+			return runConsoleBridge(client, instanceID)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		consoleURL, err := client.ConsoleURL(ctx, instanceID)
+		if err != nil {
+			return fmt.Errorf("failed to get console URL: %w", err)
+		}
+		if consolePrint {
+			fmt.Println(consoleURL)
+			return nil
+		}
+		fmt.Println("Opening PVE console (log in to the PVE web UI if prompted)...")
+		if err := openBrowser(consoleURL); err != nil {
+			fmt.Printf("Could not open browser. Visit:\n%s\n", consoleURL)
+		}
+		return nil
+	},
+}
+
+// runConsoleBridge serves the container's console to local VNC clients
+// until interrupted.
+func runConsoleBridge(client *pvelxc.Client, instanceID string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Fail fast on a bad instance or token before printing instructions.
+	checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	_, err := client.GetConsoleTicket(checkCtx, instanceID)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to get console ticket: %w", err)
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(consolePort)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Console for %s bridged to %s (no password). Press Ctrl+C to stop.\n", instanceID, ln.Addr())
+	return client.ServeConsole(ctx, ln, instanceID, func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	})
+}
+
+func init() {
+	consoleCmd.Flags().BoolVar(&consoleBridge, "bridge", false, "Bridge the console to a local VNC port instead of opening the web UI")
+	consoleCmd.Flags().IntVar(&consolePort, "port", 5901, "Local port for --bridge (0 picks a free port)")
+	consoleCmd.Flags().BoolVar(&consolePrint, "print", false, "Print the web console URL instead of opening it")
+	rootCmd.AddCommand(consoleCmd)
+}
@@ -6,10 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/karlorz/devsh/internal/auth"
-	"github.com/karlorz/devsh/internal/e2b"
 	"github.com/karlorz/devsh/internal/provider"
-	"github.com/karlorz/devsh/internal/vm"
 	"github.com/spf13/cobra"
 )
 
@@ -27,56 +24,22 @@ Examples:
 		instanceID := args[0]
 
 		fmt.Printf("Deleting VM %s...\n", instanceID)
-		selected, err := resolveProviderForInstance(instanceID)
+		p, err := sandboxProviderForInstance(instanceID)
 		if err != nil {
 			return err
 		}
 
 		timeout := 30 * time.Second
-		if selected == provider.PveLxc {
+		if p.Name() == provider.PveLxc {
 			timeout = 5 * time.Minute
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		switch selected {
-		case provider.PveLxc:
-			if err := stopPveLxcInstance(ctx, instanceID); err != nil {
-				return err
-			}
-		case provider.Morph:
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := vm.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.StopInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to delete VM: %w", err)
-			}
-		case provider.E2B:
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := e2b.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create E2B client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.StopInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to delete sandbox: %w", err)
-			}
-		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+		if err := p.Delete(ctx, instanceID); err != nil {
+			return fmt.Errorf("failed to delete VM: %w", err)
 		}
+		recordUsageStop(instanceID)
 
 		fmt.Println("✓ VM deleted")
 		return nil
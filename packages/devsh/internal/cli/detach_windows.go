@@ -0,0 +1,14 @@
+//go:build windows
+
+package cli
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts cmd in a new process group so console Ctrl+C does
+// not reach it
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
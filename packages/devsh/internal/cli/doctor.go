@@ -0,0 +1,250 @@
+// internal/cli/doctor.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/morph"
+	"github.com/karlorz/devsh/internal/state"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// DoctorCheck is the result of one environment check
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// DoctorReport is the output of devsh doctor
+type DoctorReport struct {
+	Checks []DoctorCheck `json:"checks"`
+	Failed int           `json:"failed"`
+	Warned int           `json:"warned"`
+}
+
+func (r *DoctorReport) add(check DoctorCheck) {
+	switch check.Status {
+	case doctorFail:
+		r.Failed++
+	case doctorWarn:
+		r.Warned++
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// doctorHTTPClient is used for the Morph and CDP probes
+var doctorHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the local environment is set up correctly",
+	Long: `Validate the devsh environment end to end and report pass/warn/fail for each check.
+
+Checks:
+  - CLI configuration
+  - Login state and API access (refreshes the access token)
+  - Team selection
+  - MORPH_API_KEY validity (if set)
+  - ssh and rsync binaries (needed for sync)
+  - Chrome CDP reachability of the last used instance (if running)
+
+Examples:
+  devsh doctor
+  devsh doctor --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		report := runDoctorChecks(ctx)
+
+		if flagJSON {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			printDoctorReport(report)
+		}
+
+		if report.Failed > 0 {
+			return fmt.Errorf("%d check(s) failed", report.Failed)
+		}
+		return nil
+	},
+}
+
+func runDoctorChecks(ctx context.Context) *DoctorReport {
+	report := &DoctorReport{}
+
+	if err := auth.GetConfig().Validate(); err != nil {
+		report.add(DoctorCheck{Name: "config", Status: doctorFail, Detail: err.Error(),
+			Hint: "Reinstall devsh or pass --api-url/--convex-url"})
+	} else {
+		report.add(DoctorCheck{Name: "config", Status: doctorPass})
+	}
+
+	loggedIn := auth.IsLoggedIn()
+	if !loggedIn {
+		report.add(DoctorCheck{Name: "auth", Status: doctorFail, Detail: "not logged in",
+			Hint: "Run 'devsh login'"})
+	} else if _, err := auth.GetAccessToken(); err != nil {
+		report.add(DoctorCheck{Name: "auth", Status: doctorFail, Detail: err.Error(),
+			Hint: "Your session may have expired; run 'devsh login' again"})
+		loggedIn = false
+	} else {
+		report.add(DoctorCheck{Name: "auth", Status: doctorPass, Detail: "access token valid"})
+	}
+
+	teamSlug := ""
+	if loggedIn {
+		slug, err := auth.GetTeamSlug()
+		if err != nil || slug == "" {
+			report.add(DoctorCheck{Name: "team", Status: doctorWarn, Detail: "no team selected",
+				Hint: "Run 'devsh team switch <team>' or set DEVSH_TEAM"})
+		} else {
+			teamSlug = slug
+			report.add(DoctorCheck{Name: "team", Status: doctorPass, Detail: slug})
+		}
+	}
+
+	report.add(checkMorphAPIKey(ctx))
+
+	for _, bin := range []string{"ssh", "rsync"} {
+		if path, err := exec.LookPath(bin); err != nil {
+			report.add(DoctorCheck{Name: bin, Status: doctorWarn, Detail: "not found in PATH",
+				Hint: fmt.Sprintf("Install %s to use 'devsh sync'", bin)})
+		} else {
+			report.add(DoctorCheck{Name: bin, Status: doctorPass, Detail: path})
+		}
+	}
+
+	if teamSlug != "" {
+		if check, ok := checkLastInstanceCDP(ctx, teamSlug); ok {
+			report.add(check)
+		}
+	}
+
+	return report
+}
+
+// checkMorphAPIKey validates MORPH_API_KEY by listing instances, which is
+// the cheapest authenticated Morph call.
+func checkMorphAPIKey(ctx context.Context) DoctorCheck {
+	check := DoctorCheck{Name: "morph-api-key"}
+	key := os.Getenv("MORPH_API_KEY")
+	if key == "" {
+		check.Status = doctorPass
+		check.Detail = "not set (only needed for direct Morph access)"
+		return check
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, morph.BaseURL()+"/instance", nil)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		return check
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	resp, err := doctorHTTPClient.Do(req)
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("could not reach Morph API: %v", err)
+		check.Hint = "Check your network connection"
+		return check
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		check.Status = doctorPass
+		check.Detail = "valid"
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("rejected by Morph API (%d)", resp.StatusCode)
+		check.Hint = "Create a new key at https://cloud.morph.so and update MORPH_API_KEY"
+	default:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("unexpected Morph API response (%d)", resp.StatusCode)
+	}
+	return check
+}
+
+// checkLastInstanceCDP probes Chrome DevTools on the last used instance.
+// It reports nothing if there is no running instance to check.
+func checkLastInstanceCDP(ctx context.Context, teamSlug string) (DoctorCheck, bool) {
+	instanceID, lastTeam, err := state.GetLastInstance()
+	if err != nil || instanceID == "" || (lastTeam != "" && lastTeam != teamSlug) {
+		return DoctorCheck{}, false
+	}
+
+	client, err := vm.NewClient()
+	if err != nil {
+		return DoctorCheck{}, false
+	}
+	client.SetTeamSlug(teamSlug)
+
+	instance, err := client.GetInstance(ctx, instanceID)
+	if err != nil || instance.Status != "running" || instance.ChromeURL == "" {
+		return DoctorCheck{}, false
+	}
+
+	check := DoctorCheck{Name: "chrome-cdp"}
+	url := strings.TrimSuffix(instance.ChromeURL, "/") + "/json/version"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = doctorHTTPClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("HTTP %d", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%s: %v", instanceID, err)
+		check.Hint = "Chrome may still be starting; try again shortly"
+		return check, true
+	}
+	check.Status = doctorPass
+	check.Detail = instanceID
+	return check, true
+}
+
+func printDoctorReport(report *DoctorReport) {
+	fmt.Println("devsh doctor")
+	fmt.Println("============")
+	for _, check := range report.Checks {
+		line := fmt.Sprintf("  [%s] %-14s", strings.ToUpper(check.Status), check.Name)
+		if check.Detail != "" {
+			line += " " + check.Detail
+		}
+		fmt.Println(line)
+		if check.Hint != "" && check.Status != doctorPass {
+			fmt.Printf("         -> %s\n", check.Hint)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("%d failed, %d warning(s)\n", report.Failed, report.Warned)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckMorphAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/instance" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+	t.Setenv("MORPH_API_URL", server.URL)
+
+	t.Setenv("MORPH_API_KEY", "")
+	if check := checkMorphAPIKey(context.Background()); check.Status != doctorPass {
+		t.Errorf("unset key should pass, got %+v", check)
+	}
+
+	t.Setenv("MORPH_API_KEY", "good-key")
+	if check := checkMorphAPIKey(context.Background()); check.Status != doctorPass || check.Detail != "valid" {
+		t.Errorf("valid key: got %+v", check)
+	}
+
+	t.Setenv("MORPH_API_KEY", "bad-key")
+	check := checkMorphAPIKey(context.Background())
+	if check.Status != doctorFail || check.Hint == "" {
+		t.Errorf("invalid key: got %+v", check)
+	}
+}
+
+func TestPrintDoctorReport(t *testing.T) {
+	report := &DoctorReport{}
+	report.add(DoctorCheck{Name: "auth", Status: doctorPass, Detail: "access token valid", Hint: "unused"})
+	report.add(DoctorCheck{Name: "rsync", Status: doctorWarn, Detail: "not found in PATH", Hint: "Install rsync"})
+	report.add(DoctorCheck{Name: "config", Status: doctorFail, Detail: "missing URL"})
+
+	if report.Failed != 1 || report.Warned != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+
+	output := captureStdout(t, func() { printDoctorReport(report) })
+	for _, want := range []string{"[PASS] auth", "[WARN] rsync", "-> Install rsync", "[FAIL] config", "1 failed, 1 warning(s)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "unused") {
+		t.Errorf("hint shown for passing check:\n%s", output)
+	}
+}
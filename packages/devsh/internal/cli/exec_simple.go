@@ -4,16 +4,27 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/karlorz/devsh/internal/auth"
 	"github.com/karlorz/devsh/internal/e2b"
 	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
 	"github.com/karlorz/devsh/internal/vm"
 	"github.com/spf13/cobra"
 )
 
+var (
+	execTimeout int
+	execEnv     []string
+	execCwd     string
+	execShell   string
+	execStdin   bool
+)
+
 var execCmd = &cobra.Command{
 	Use:   "exec <id> <command>",
 	Short: "Execute a command in a VM",
@@ -22,26 +33,54 @@ var execCmd = &cobra.Command{
 Examples:
   devsh exec cmux_abc123 "ls -la"
   devsh exec cmux_abc123 "npm install"
-  devsh exec cmux_abc123 "cat /etc/os-release"`,
+  devsh exec cmux_abc123 "cat /etc/os-release"
+  devsh exec cmux_abc123 --timeout 600 --cwd /workspace "npm test"
+  devsh exec cmux_abc123 --env FOO=bar --env DEBUG=1 --shell bash 'echo $FOO'
+  devsh exec -i cmux_abc123 "psql app" < dump.sql
+
+With -i, local standard input is uploaded to the VM and fed to the command.
+It is read to the end before the command starts, so it suits files and
+pipes rather than interactive input. Morph instances only.`,
 	Args: cobra.MinimumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancel()
-
 		instanceID := args[0]
 		command := strings.Join(args[1:], " ")
 
+		env, err := parseExecEnv(execEnv)
+		if err != nil {
+			return err
+		}
+		opts := vm.ExecOptions{
+			Timeout: time.Duration(execTimeout) * time.Second,
+			Env:     env,
+			Cwd:     execCwd,
+			Shell:   execShell,
+		}
+
+		ctxTimeout := 2 * time.Minute
+		if t := opts.Timeout + time.Minute; t > ctxTimeout {
+			ctxTimeout = t
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
+		defer cancel()
+
 		selected, err := resolveProviderForInstance(instanceID)
 		if err != nil {
 			return err
 		}
+		if execStdin {
+			if selected != provider.Morph {
+				return fmt.Errorf("--stdin is only supported for Morph instances")
+			}
+			opts.Stdin = os.Stdin
+		}
 
 		var stdout, stderr string
 		var exitCode int
 
 		switch selected {
 		case provider.PveLxc:
-			stdout, stderr, exitCode, err = execPveLxcInstance(ctx, instanceID, command, 60)
+			stdout, stderr, exitCode, err = execPveLxcInstance(ctx, instanceID, wrapExecCommand(command, opts), execTimeout)
 			if err != nil {
 				return err
 			}
@@ -57,7 +96,7 @@ Examples:
 			}
 			client.SetTeamSlug(teamSlug)
 
-			stdout, stderr, exitCode, err = client.ExecCommand(ctx, instanceID, command)
+			stdout, stderr, exitCode, err = client.ExecCommandWithOptions(ctx, instanceID, command, opts)
 			if err != nil {
 				return fmt.Errorf("failed to execute command: %w", err)
 			}
@@ -73,12 +112,20 @@ Examples:
 			}
 			client.SetTeamSlug(teamSlug)
 
-			stdout, stderr, exitCode, err = client.ExecCommand(ctx, instanceID, command, 60)
+			stdout, stderr, exitCode, err = client.ExecCommand(ctx, instanceID, wrapExecCommand(command, opts), execTimeout)
 			if err != nil {
 				return fmt.Errorf("failed to execute command: %w", err)
 			}
 		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+			p, err := newSandboxProvider(selected)
+			if err != nil {
+				return err
+			}
+			result, err := p.Exec(ctx, instanceID, wrapExecCommand(command, opts))
+			if err != nil {
+				return fmt.Errorf("failed to execute command: %w", err)
+			}
+			stdout, stderr, exitCode = result.Stdout, result.Stderr, result.ExitCode
 		}
 
 		if stdout != "" {
@@ -96,6 +143,50 @@ Examples:
 	},
 }
 
+// parseExecEnv parses repeated KEY=VALUE flags.
+func parseExecEnv(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=VALUE", pair)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// wrapExecCommand applies env, cwd and shell on the client side for
+// providers whose exec API only takes a command string.
+func wrapExecCommand(command string, opts vm.ExecOptions) string {
+	var prelude []string
+	if opts.Cwd != "" {
+		prelude = append(prelude, "cd "+pvelxc.ShellSingleQuote(opts.Cwd))
+	}
+	names := make([]string, 0, len(opts.Env))
+	for name := range opts.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prelude = append(prelude, fmt.Sprintf("export %s=%s", name, pvelxc.ShellSingleQuote(opts.Env[name])))
+	}
+
+	script := strings.Join(append(prelude, command), " && ")
+	if opts.Shell != "" && opts.Shell != "sh" {
+		return opts.Shell + " -c " + pvelxc.ShellSingleQuote(script)
+	}
+	return script
+}
+
 func init() {
+	execCmd.Flags().IntVar(&execTimeout, "timeout", 60, "Command timeout in seconds")
+	execCmd.Flags().StringArrayVar(&execEnv, "env", nil, "Set an environment variable (KEY=VALUE, repeatable)")
+	execCmd.Flags().StringVar(&execCwd, "cwd", "", "Working directory for the command")
+	execCmd.Flags().StringVar(&execShell, "shell", "", "Shell to run the command with (sh, bash, zsh)")
+	execCmd.Flags().BoolVarP(&execStdin, "stdin", "i", false, "Pipe local standard input to the command")
 	rootCmd.AddCommand(execCmd)
 }
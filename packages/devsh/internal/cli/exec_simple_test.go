@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+func TestParseExecEnv(t *testing.T) {
+	env, err := parseExecEnv([]string{"FOO=bar", "EMPTY=", "URL=a=b"})
+	if err != nil {
+		t.Fatalf("parseExecEnv: %v", err)
+	}
+	if env["FOO"] != "bar" || env["EMPTY"] != "" || env["URL"] != "a=b" {
+		t.Errorf("env = %v", env)
+	}
+
+	for _, bad := range []string{"NOVALUE", "=x"} {
+		if _, err := parseExecEnv([]string{bad}); err == nil {
+			t.Errorf("parseExecEnv(%q) should fail", bad)
+		}
+	}
+}
+
+func TestWrapExecCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		opts vm.ExecOptions
+		want string
+	}{
+		{"plain", vm.ExecOptions{}, "make test"},
+		{
+			"env and cwd",
+			vm.ExecOptions{Cwd: "/work space", Env: map[string]string{"B": "it's", "A": "1"}},
+			`cd '/work space' && export A='1' && export B='it'\''s' && make test`,
+		},
+		{"bash", vm.ExecOptions{Shell: "bash"}, `bash -c 'make test'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapExecCommand("make test", tt.opts); got != tt.want {
+				t.Errorf("wrapExecCommand = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
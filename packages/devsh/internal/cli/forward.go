@@ -0,0 +1,386 @@
+// internal/cli/forward.go
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	forwardAuto     bool
+	forwardInterval time.Duration
+)
+
+// The worker's own services listen in this range; --auto skips them.
+const (
+	cmuxServicePortMin = 39375
+	cmuxServicePortMax = 39399
+)
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward <id> [[local:]remote...]",
+	Short: "Forward local ports to ports inside a VM",
+	Long: `Forward local ports to ports inside a VM over SSH.
+
+Each argument forwards a remote port to the same local port, or to a
+different one with local:remote. With --auto, every port listening in the
+VM is forwarded as it appears and dropped when it closes; ports below 1024
+and cmux's own services are skipped. An auto-forwarded port uses the same
+local port when it is free, otherwise any free one.
+
+Forwards listen on 127.0.0.1, survive SSH reconnects, and stop when the VM
+is no longer running. Morph instances only.
+
+Examples:
+  devsh forward cmux_abc123 5432                # localhost:5432 -> VM 5432
+  devsh forward cmux_abc123 15432:5432 3000
+  devsh forward cmux_abc123 --auto`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instanceID := args[0]
+		specs := make([]forwardSpec, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			spec, err := parseForwardSpec(arg)
+			if err != nil {
+				return err
+			}
+			specs = append(specs, spec)
+		}
+		if len(specs) == 0 && !forwardAuto {
+			return fmt.Errorf("give ports to forward or use --auto")
+		}
+		if forwardInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+
+		selected, err := resolveProviderForInstance(instanceID)
+		if err != nil {
+			return err
+		}
+		if selected != provider.Morph {
+			return fmt.Errorf("forward is only supported for Morph instances")
+		}
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		f := newPortForwarder(func(ctx context.Context) (forwardTunnel, error) {
+			conn, err := client.SSHDial(ctx, instanceID)
+			if err != nil {
+				return nil, err
+			}
+			return conn.Client(), nil
+		}, func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		})
+		defer f.closeAll()
+
+		if _, err := f.tunnelFor(ctx); err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			if err := f.add(ctx, spec.remote, spec.local, false); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Press Ctrl+C to stop.")
+
+		ticker := time.NewTicker(forwardInterval)
+		defer ticker.Stop()
+		for {
+			if instance, err := client.GetInstance(ctx, instanceID); err == nil && instance.Status != "running" {
+				fmt.Printf("Instance %s is %s; stopping forwards.\n", instanceID, instance.Status)
+				return nil
+			}
+			if forwardAuto {
+				ports, err := client.ListPorts(ctx, instanceID)
+				if err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to list ports: %v\n", err)
+				} else if err == nil {
+					f.sync(ctx, autoForwardPorts(ports))
+				}
+			}
+			f.keepalive(ctx)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// forwardSpec is one [local:]remote argument. A zero local means the same
+// port as remote.
+type forwardSpec struct {
+	local  int
+	remote int
+}
+
+func parseForwardSpec(arg string) (forwardSpec, error) {
+	localStr, remoteStr, hasLocal := strings.Cut(arg, ":")
+	if !hasLocal {
+		remoteStr = localStr
+	}
+	var spec forwardSpec
+	var err error
+	if spec.remote, err = parsePort(remoteStr); err != nil {
+		return forwardSpec{}, fmt.Errorf("invalid port %q: expected [local:]remote", arg)
+	}
+	if hasLocal {
+		if spec.local, err = parsePort(localStr); err != nil {
+			return forwardSpec{}, fmt.Errorf("invalid port %q: expected [local:]remote", arg)
+		}
+	}
+	return spec, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// autoForwardPorts picks the discovered ports worth forwarding: dev
+// servers and databases, not system services or cmux's own.
+func autoForwardPorts(ports []vm.ListeningPort) []int {
+	seen := make(map[int]bool)
+	var out []int
+	for _, p := range ports {
+		if p.Port < 1024 || (p.Port >= cmuxServicePortMin && p.Port <= cmuxServicePortMax) || seen[p.Port] {
+			continue
+		}
+		seen[p.Port] = true
+		out = append(out, p.Port)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// forwardTunnel is the SSH connection forwards dial through; *ssh.Client
+// implements it.
+type forwardTunnel interface {
+	Dial(network, addr string) (net.Conn, error)
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+type forwardListener struct {
+	ln    net.Listener
+	local int
+	auto  bool
+}
+
+// portForwarder keeps local listeners for remote ports and routes their
+// connections through the current tunnel, redialing it when it drops so
+// local ports stay stable across reconnects. Listeners are only changed
+// from one goroutine.
+type portForwarder struct {
+	dial func(ctx context.Context) (forwardTunnel, error)
+	logf func(format string, args ...any)
+
+	tunnelMu sync.Mutex
+	tunnel   forwardTunnel
+
+	listeners map[int]*forwardListener // By remote port
+}
+
+func newPortForwarder(dial func(ctx context.Context) (forwardTunnel, error), logf func(format string, args ...any)) *portForwarder {
+	return &portForwarder{dial: dial, logf: logf, listeners: make(map[int]*forwardListener)}
+}
+
+// tunnelFor returns the current tunnel, dialing one if there is none.
+func (f *portForwarder) tunnelFor(ctx context.Context) (forwardTunnel, error) {
+	f.tunnelMu.Lock()
+	defer f.tunnelMu.Unlock()
+	if f.tunnel == nil {
+		t, err := f.dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		f.tunnel = t
+	}
+	return f.tunnel, nil
+}
+
+// dropTunnel discards t if it is still the current tunnel.
+func (f *portForwarder) dropTunnel(t forwardTunnel) bool {
+	f.tunnelMu.Lock()
+	defer f.tunnelMu.Unlock()
+	if f.tunnel != t {
+		return false
+	}
+	t.Close()
+	f.tunnel = nil
+	return true
+}
+
+// keepalive probes the tunnel and reconnects if it is gone.
+func (f *portForwarder) keepalive(ctx context.Context) {
+	f.tunnelMu.Lock()
+	t := f.tunnel
+	f.tunnelMu.Unlock()
+	if t != nil {
+		if _, _, err := t.SendRequest("keepalive@openssh.com", true, nil); err == nil {
+			return
+		}
+		if f.dropTunnel(t) {
+			f.logf("SSH connection lost; reconnecting")
+		}
+	}
+	if _, err := f.tunnelFor(ctx); err != nil {
+		if ctx.Err() == nil {
+			f.logf("Reconnect failed: %v", err)
+		}
+		return
+	}
+	if t != nil {
+		f.logf("Reconnected")
+	}
+}
+
+// dialRemote opens a connection to port inside the instance. A refused
+// port is returned as is; any other failure means the tunnel is dead, so
+// it is redialed once.
+func (f *portForwarder) dialRemote(ctx context.Context, port int) (net.Conn, error) {
+	addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+	t, err := f.tunnelFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := t.Dial("tcp", addr)
+	var openErr *ssh.OpenChannelError
+	if err == nil || errors.As(err, &openErr) {
+		return conn, err
+	}
+	f.dropTunnel(t)
+	if t, err = f.tunnelFor(ctx); err != nil {
+		return nil, err
+	}
+	return t.Dial("tcp", addr)
+}
+
+// add forwards remote to local (or the same port when local is zero). Auto
+// forwards fall back to any free local port; explicit ones must get theirs.
+func (f *portForwarder) add(ctx context.Context, remote, local int, auto bool) error {
+	if local == 0 {
+		local = remote
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(local)))
+	if err != nil && auto {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen for port %d: %w", remote, err)
+	}
+	fl := &forwardListener{ln: ln, local: ln.Addr().(*net.TCPAddr).Port, auto: auto}
+	f.listeners[remote] = fl
+	f.logf("Forwarding localhost:%d -> %d", fl.local, remote)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(ctx, conn, remote)
+		}
+	}()
+	return nil
+}
+
+func (f *portForwarder) handle(ctx context.Context, local net.Conn, port int) {
+	defer local.Close()
+	remote, err := f.dialRemote(ctx, port)
+	if err != nil {
+		f.logf("Connection to %d failed: %v", port, err)
+		return
+	}
+	defer remote.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(remote, local)
+		remote.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(local, remote)
+		local.Close()
+	}()
+	wg.Wait()
+}
+
+// sync makes the auto forwards match ports: new ports are forwarded and
+// auto forwards for ports that stopped listening are closed. Explicit
+// forwards are left alone.
+func (f *portForwarder) sync(ctx context.Context, ports []int) {
+	want := make(map[int]bool, len(ports))
+	for _, port := range ports {
+		want[port] = true
+		if _, ok := f.listeners[port]; ok {
+			continue
+		}
+		if err := f.add(ctx, port, 0, true); err != nil {
+			f.logf("%v", err)
+		}
+	}
+	for port, fl := range f.listeners {
+		if fl.auto && !want[port] {
+			fl.ln.Close()
+			delete(f.listeners, port)
+			f.logf("Stopped forwarding %d (no longer listening)", port)
+		}
+	}
+}
+
+func (f *portForwarder) closeAll() {
+	for port, fl := range f.listeners {
+		fl.ln.Close()
+		delete(f.listeners, port)
+	}
+	f.tunnelMu.Lock()
+	defer f.tunnelMu.Unlock()
+	if f.tunnel != nil {
+		f.tunnel.Close()
+		f.tunnel = nil
+	}
+}
+
+func init() {
+	forwardCmd.Flags().BoolVar(&forwardAuto, "auto", false, "Forward every port listening in the VM, following new and closed ports")
+	forwardCmd.Flags().DurationVar(&forwardInterval, "interval", 5*time.Second, "How often to rescan ports and check the connection")
+	rootCmd.AddCommand(forwardCmd)
+}
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+func TestParseForwardSpec(t *testing.T) {
+	for arg, want := range map[string]forwardSpec{
+		"5432":       {remote: 5432},
+		"15432:5432": {local: 15432, remote: 5432},
+	} {
+		got, err := parseForwardSpec(arg)
+		if err != nil || got != want {
+			t.Errorf("parseForwardSpec(%q) = %+v, %v; want %+v", arg, got, err, want)
+		}
+	}
+	for _, arg := range []string{"", "abc", "0", "70000", "1:", ":2", "1:2:3"} {
+		if _, err := parseForwardSpec(arg); err == nil {
+			t.Errorf("parseForwardSpec(%q) should fail", arg)
+		}
+	}
+}
+
+func TestAutoForwardPortsSkipsSystemAndCmuxPorts(t *testing.T) {
+	got := autoForwardPorts([]vm.ListeningPort{
+		{Port: 22}, {Port: 5432, Address: "127.0.0.1"}, {Port: 5432, Address: "::1"},
+		{Port: 39376}, {Port: 39383}, {Port: 3000}, {Port: 53},
+	})
+	if want := []int{3000, 5432}; !reflect.DeepEqual(got, want) {
+		t.Errorf("autoForwardPorts = %v, want %v", got, want)
+	}
+}
+
+// fakeTunnel dials a local echo server for every remote address.
+type fakeTunnel struct {
+	echo string
+
+	mu     sync.Mutex
+	broken bool
+	closed bool
+}
+
+func (t *fakeTunnel) Dial(network, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.broken || t.closed {
+		return nil, io.EOF
+	}
+	return net.Dial(network, t.echo)
+}
+
+func (t *fakeTunnel) SendRequest(string, bool, []byte) (bool, []byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.broken || t.closed {
+		return false, nil, errors.New("connection lost")
+	}
+	return true, nil, nil
+}
+
+func (t *fakeTunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func assertEcho(t *testing.T, localPort int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	if err != nil {
+		t.Fatalf("dial forward: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("echo = %q, %v", buf, err)
+	}
+}
+
+func TestPortForwarderSyncAndReconnect(t *testing.T) {
+	echo := startEchoServer(t)
+	var mu sync.Mutex
+	var tunnels []*fakeTunnel
+	f := newPortForwarder(func(ctx context.Context) (forwardTunnel, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		tunnel := &fakeTunnel{echo: echo}
+		tunnels = append(tunnels, tunnel)
+		return tunnel, nil
+	}, t.Logf)
+	defer f.closeAll()
+	ctx := context.Background()
+
+	// Occupy a port so the auto forward for it falls back to a free one.
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	busyPort := taken.Addr().(*net.TCPAddr).Port
+
+	f.sync(ctx, []int{busyPort})
+	fl := f.listeners[busyPort]
+	if fl == nil || fl.local == busyPort {
+		t.Fatalf("auto forward for busy port %d = %+v, want a different local port", busyPort, fl)
+	}
+	assertEcho(t, fl.local)
+
+	// A dead tunnel is redialed on the next connection.
+	mu.Lock()
+	tunnels[0].mu.Lock()
+	tunnels[0].broken = true
+	tunnels[0].mu.Unlock()
+	mu.Unlock()
+	assertEcho(t, fl.local)
+	mu.Lock()
+	if len(tunnels) != 2 {
+		t.Errorf("tunnels dialed = %d, want 2", len(tunnels))
+	}
+	mu.Unlock()
+
+	// Explicit forwards must get their port; ports that vanish lose only
+	// their auto forward.
+	if err := f.add(ctx, 6000, busyPort, false); err == nil {
+		t.Errorf("explicit forward onto a busy local port should fail")
+	}
+	if err := f.add(ctx, 6001, 0, false); err != nil {
+		t.Logf("port 6001 unavailable locally: %v", err)
+	}
+	f.sync(ctx, nil)
+	if _, ok := f.listeners[busyPort]; ok {
+		t.Errorf("auto forward for %d should be closed", busyPort)
+	}
+	if fl6001, ok := f.listeners[6001]; ok && fl6001.auto {
+		t.Errorf("explicit forward marked auto")
+	}
+	if _, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(fl.local))); err == nil {
+		t.Errorf("closed forward still accepts connections")
+	}
+}
+
+func TestPortForwarderKeepaliveReconnects(t *testing.T) {
+	var dials int
+	var current *fakeTunnel
+	f := newPortForwarder(func(ctx context.Context) (forwardTunnel, error) {
+		dials++
+		current = &fakeTunnel{}
+		return current, nil
+	}, t.Logf)
+	defer f.closeAll()
+	ctx := context.Background()
+
+	f.keepalive(ctx)
+	f.keepalive(ctx)
+	if dials != 1 {
+		t.Fatalf("dials = %d after healthy keepalives, want 1", dials)
+	}
+	current.broken = true
+	f.keepalive(ctx)
+	if dials != 2 {
+		t.Fatalf("dials = %d after lost connection, want 2", dials)
+	}
+}
@@ -0,0 +1,181 @@
+// internal/cli/idle_watch.go
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/usage"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	idleWatchAfter    time.Duration
+	idleWatchInterval time.Duration
+	idleWatchMaxLoad  float64
+)
+
+// autoResumeSkip lists commands that must not resume an auto-paused
+// instance: they inspect or change its lifecycle themselves.
+var autoResumeSkip = map[string]bool{
+	"pause": true, "resume": true, "delete": true, "status": true, "idle-watch": true,
+}
+
+var idleWatchCmd = &cobra.Command{
+	Use:   "idle-watch <id>",
+	Short: "Pause a VM once it has been idle for a while",
+	Long: `Probe a Morph VM every --interval and pause it once it has been idle for
+--idle-after. A VM is idle when no terminals (PTYs) are open, no DevTools
+(CDP) clients are connected, and its 1-minute load average is below
+--max-load, on every probe for the whole window.
+
+The next devsh command on an auto-paused VM resumes it first and starts
+a new watcher. 'devsh start --auto-pause 30m' runs this in the background.
+
+Examples:
+  devsh idle-watch cmux_abc123
+  devsh idle-watch cmux_abc123 --idle-after 1h --max-load 0.5`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instanceID := args[0]
+		selected, err := resolveProviderForInstance(instanceID)
+		if err != nil {
+			return err
+		}
+		if selected != provider.Morph {
+			return fmt.Errorf("idle-watch supports morph instances only (got %s)", selected)
+		}
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		fmt.Printf("Watching %s: pausing after %s idle\n", instanceID, idleWatchAfter)
+		err = client.WatchIdle(ctx, instanceID, vm.IdleOptions{
+			IdleAfter: idleWatchAfter,
+			Interval:  idleWatchInterval,
+			MaxLoad:   idleWatchMaxLoad,
+			OnSample: func(s vm.IdleSample, idleFor time.Duration) {
+				if flagVerbose {
+					fmt.Printf("%s %s idle=%s\n", time.Now().Format(time.RFC3339), s, idleFor.Round(time.Second))
+				}
+			},
+		})
+		if errors.Is(err, vm.ErrNotWatching) {
+			fmt.Printf("Stopped watching %s: %v\n", instanceID, err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("idle watch failed: %w", err)
+		}
+
+		updateUsage(func(s *usage.Store, now time.Time) { s.AutoPaused(instanceID, now) })
+		fmt.Printf("✓ VM %s paused after %s idle\n", instanceID, idleWatchAfter)
+		return nil
+	},
+}
+
+// startIdleWatcher runs 'devsh idle-watch' for instanceID in the background,
+// detached from the terminal, logging next to the devsh config files.
+func startIdleWatcher(instanceID string, idleAfter time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	logDir := filepath.Join(home, ".config", "cmux", "idle-watch")
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(logDir, instanceID+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	args := []string{"idle-watch", instanceID, "--idle-after", idleAfter.String()}
+	if flagProvider != "" {
+		args = append(args, "--provider", flagProvider)
+	}
+	if flagProfile != "" {
+		args = append(args, "--profile", flagProfile)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// resumeIfAutoPaused resumes the instance named by args[0] if the idle
+// watcher paused it, and re-arms the watcher. Failures only warn: the
+// command then reports the paused instance itself.
+func resumeIfAutoPaused(cmd *cobra.Command, args []string) {
+	if len(args) == 0 || autoResumeSkip[cmd.Name()] {
+		return
+	}
+	s, err := usage.Load()
+	if err != nil {
+		return
+	}
+	rec := s.Instances[args[0]]
+	if rec == nil || rec.AutoPausedAt == nil {
+		return
+	}
+	instanceID := rec.InstanceID
+
+	fmt.Fprintf(os.Stderr, "Resuming instance %s (auto-paused %s ago)…\n", instanceID, time.Since(*rec.AutoPausedAt).Round(time.Minute))
+	p, err := sandboxProviderForInstance(instanceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not resume %s: %v\n", instanceID, err)
+		return
+	}
+	lifecycle, ok := p.(provider.Lifecycle)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if _, err := lifecycle.Resume(ctx, instanceID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not resume %s: %v\n", instanceID, err)
+		return
+	}
+	recordUsageStart(usage.Record{InstanceID: instanceID, Provider: p.Name()})
+
+	if after, err := time.ParseDuration(rec.AutoPauseAfter); err == nil && after > 0 {
+		if err := startIdleWatcher(instanceID, after); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not restart idle watcher: %v\n", err)
+		}
+	}
+}
+
+func init() {
+	idleWatchCmd.Flags().DurationVar(&idleWatchAfter, "idle-after", 30*time.Minute, "Pause after the VM has been idle this long")
+	idleWatchCmd.Flags().DurationVar(&idleWatchInterval, "interval", time.Minute, "Time between idle probes")
+	idleWatchCmd.Flags().Float64Var(&idleWatchMaxLoad, "max-load", 0.3, "1-minute load average below which the CPU counts as idle")
+	rootCmd.AddCommand(idleWatchCmd)
+}
@@ -0,0 +1,211 @@
+// internal/cli/list_all.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/e2b"
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+var listAllProviders bool
+
+// providerLister lists one provider's instances for `devsh ls --all-providers`.
+type providerLister struct {
+	provider string
+	list     func(ctx context.Context, opts vm.ListOptions) ([]vm.Instance, error)
+}
+
+// providerInstance is an instance tagged with the provider that owns it.
+type providerInstance struct {
+	Provider string
+	vm.Instance
+}
+
+// providerFailure records a provider that could not be listed.
+type providerFailure struct {
+	Provider string
+	Err      error
+}
+
+// configuredListers returns a lister for Morph, which only needs a login,
+// plus PVE LXC and E2B when their credentials are set and any other provider
+// selected with DEVSH_PROVIDER.
+func configuredListers() []providerLister {
+	listers := []providerLister{{provider: provider.Morph, list: listMorphInstances}}
+	if provider.HasPveEnv() {
+		listers = append(listers, providerLister{provider: provider.PveLxc, list: listPveLxcInstances})
+	}
+	if provider.HasE2BEnv() {
+		listers = append(listers, providerLister{provider: provider.E2B, list: listE2BInstances})
+	}
+	switch configured := provider.ConfiguredProvider(); configured {
+	case "", provider.Morph, provider.PveLxc, provider.E2B:
+	default:
+		listers = append(listers, providerLister{provider: configured, list: func(ctx context.Context, _ vm.ListOptions) ([]vm.Instance, error) {
+			return listSandboxInstances(ctx, configured)
+		}})
+	}
+	return listers
+}
+
+// listProviders runs every lister concurrently. Instances keep lister order
+// so the table groups by provider; a failing provider is reported instead of
+// failing the whole listing.
+func listProviders(ctx context.Context, listers []providerLister, opts vm.ListOptions) ([]providerInstance, []providerFailure) {
+	results := make([][]vm.Instance, len(listers))
+	errs := make([]error, len(listers))
+	var wg sync.WaitGroup
+	for i, l := range listers {
+		wg.Add(1)
+		go func(i int, l providerLister) {
+			defer wg.Done()
+			results[i], errs[i] = l.list(ctx, opts)
+		}(i, l)
+	}
+	wg.Wait()
+
+	var instances []providerInstance
+	var failures []providerFailure
+	for i, l := range listers {
+		if errs[i] != nil {
+			failures = append(failures, providerFailure{Provider: l.provider, Err: errs[i]})
+			continue
+		}
+		for _, inst := range results[i] {
+			instances = append(instances, providerInstance{Provider: l.provider, Instance: inst})
+		}
+	}
+	return instances, failures
+}
+
+// runListAllProviders prints one table covering every configured provider.
+func runListAllProviders(ctx context.Context, opts vm.ListOptions) error {
+	listers := configuredListers()
+	all, failures := listProviders(ctx, listers, opts)
+	if len(failures) == len(listers) {
+		return fmt.Errorf("failed to list instances: %w", failures[0].Err)
+	}
+
+	instances := all[:0]
+	for _, inst := range all {
+		if opts.Status == "" || strings.EqualFold(inst.Status, opts.Status) {
+			instances = append(instances, inst)
+		}
+	}
+	more := opts.Limit > 0 && len(instances) > opts.Limit
+	if more {
+		instances = instances[:opts.Limit]
+	}
+
+	if len(instances) == 0 {
+		fmt.Println("No VMs found. Run 'devsh start' to create one.")
+	} else {
+		fmt.Printf("%-10s %-20s %-10s %s\n", "PROVIDER", "ID", "STATUS", "VS CODE URL")
+		fmt.Println("---------- -------------------- ---------- " + "----------------------------------------")
+		for _, inst := range instances {
+			url := inst.VSCodeURL
+			if len(url) > 40 {
+				url = url[:40] + "..."
+			}
+			fmt.Printf("%-10s %-20s %-10s %s\n", inst.Provider, inst.ID, inst.Status, url)
+		}
+		printMoreHint(more, "instances")
+	}
+
+	if len(failures) > 0 {
+		fmt.Println()
+		for _, f := range failures {
+			fmt.Printf("Warning: could not list %s instances: %v\n", f.Provider, f.Err)
+		}
+	}
+	return nil
+}
+
+func listMorphInstances(ctx context.Context, opts vm.ListOptions) ([]vm.Instance, error) {
+	teamSlug, err := auth.GetTeamSlug()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	client, err := vm.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	client.SetTeamSlug(teamSlug)
+
+	basics, err := client.ListInstancesWithOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	// The list endpoint returns basic info only; fetch URLs per instance
+	// and fall back to the basic info if that fails.
+	instances := make([]vm.Instance, 0, len(basics))
+	for _, basic := range basics {
+		if full, err := client.GetInstance(ctx, basic.ID); err == nil {
+			instances = append(instances, *full)
+		} else {
+			instances = append(instances, basic)
+		}
+	}
+	return instances, nil
+}
+
+func listPveLxcInstances(ctx context.Context, _ vm.ListOptions) ([]vm.Instance, error) {
+	client, err := pvelxc.NewClientFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PVE LXC client: %w", err)
+	}
+	pveInstances, err := client.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]vm.Instance, 0, len(pveInstances))
+	for _, inst := range pveInstances {
+		instances = append(instances, vm.Instance{ID: inst.ID, Status: inst.Status, VSCodeURL: inst.VSCodeURL})
+	}
+	return instances, nil
+}
+
+func listE2BInstances(ctx context.Context, _ vm.ListOptions) ([]vm.Instance, error) {
+	teamSlug, err := auth.GetTeamSlug()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	client, err := e2b.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create E2B client: %w", err)
+	}
+	client.SetTeamSlug(teamSlug)
+
+	e2bInstances, err := client.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]vm.Instance, 0, len(e2bInstances))
+	for _, inst := range e2bInstances {
+		instances = append(instances, vm.Instance{ID: inst.ID, Status: inst.Status, VSCodeURL: inst.VSCodeURL})
+	}
+	return instances, nil
+}
+
+func listSandboxInstances(ctx context.Context, name string) ([]vm.Instance, error) {
+	p, err := newSandboxProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	sandboxes, err := p.List(ctx, provider.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]vm.Instance, 0, len(sandboxes))
+	for _, sb := range sandboxes {
+		instances = append(instances, vm.Instance{ID: sb.ID, Status: sb.Status, VSCodeURL: sb.VSCodeURL})
+	}
+	return instances, nil
+}
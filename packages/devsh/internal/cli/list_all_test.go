@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+func TestListProvidersMergesInListerOrder(t *testing.T) {
+	listers := []providerLister{
+		{provider: "morph", list: func(context.Context, vm.ListOptions) ([]vm.Instance, error) {
+			// Finish last so ordering can't come from completion order.
+			time.Sleep(20 * time.Millisecond)
+			return []vm.Instance{{ID: "cmux_a", Status: "running"}}, nil
+		}},
+		{provider: "pve-lxc", list: func(context.Context, vm.ListOptions) ([]vm.Instance, error) {
+			return []vm.Instance{{ID: "pvelxc-1", Status: "stopped"}, {ID: "pvelxc-2", Status: "running"}}, nil
+		}},
+	}
+
+	instances, failures := listProviders(context.Background(), listers, vm.ListOptions{})
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	want := []struct{ provider, id string }{{"morph", "cmux_a"}, {"pve-lxc", "pvelxc-1"}, {"pve-lxc", "pvelxc-2"}}
+	if len(instances) != len(want) {
+		t.Fatalf("got %d instances, want %d", len(instances), len(want))
+	}
+	for i, w := range want {
+		if instances[i].Provider != w.provider || instances[i].ID != w.id {
+			t.Errorf("instance %d = %s/%s, want %s/%s", i, instances[i].Provider, instances[i].ID, w.provider, w.id)
+		}
+	}
+}
+
+func TestListProvidersReportsPartialFailure(t *testing.T) {
+	listers := []providerLister{
+		{provider: "morph", list: func(context.Context, vm.ListOptions) ([]vm.Instance, error) {
+			return nil, errors.New("not logged in")
+		}},
+		{provider: "e2b", list: func(context.Context, vm.ListOptions) ([]vm.Instance, error) {
+			return []vm.Instance{{ID: "sbabc", Status: "running"}}, nil
+		}},
+	}
+
+	instances, failures := listProviders(context.Background(), listers, vm.ListOptions{})
+	if len(instances) != 1 || instances[0].Provider != "e2b" {
+		t.Errorf("expected the e2b instance only, got %+v", instances)
+	}
+	if len(failures) != 1 || failures[0].Provider != "morph" || failures[0].Err.Error() != "not logged in" {
+		t.Errorf("unexpected failures %+v", failures)
+	}
+}
@@ -0,0 +1,79 @@
+// internal/cli/list_flags.go
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+// defaultTaskListLimit is how many tasks `task list` shows without --limit
+// or --all. `ls` shows every instance by default, as it always has.
+const defaultTaskListLimit = 50
+
+// listFlags are the paging and filter flags shared by list commands.
+type listFlags struct {
+	limit        int
+	all          bool
+	status       string
+	repository   string
+	createdAfter string
+}
+
+// register adds the flags to cmd. A defaultLimit of 0 shows every item
+// unless --limit is given.
+func (f *listFlags) register(cmd *cobra.Command, withRepository bool, defaultLimit int) {
+	cmd.Flags().IntVar(&f.limit, "limit", defaultLimit, "Maximum number of items to show (0 for no limit)")
+	cmd.Flags().BoolVar(&f.all, "all", false, "Show every item, ignoring --limit")
+	cmd.Flags().StringVar(&f.status, "status", "", "Only show items with this status")
+	if withRepository {
+		cmd.Flags().StringVar(&f.repository, "repo", "", "Only show items for this repository (owner/name)")
+	}
+	cmd.Flags().StringVar(&f.createdAfter, "created-after", "", "Only show items created after a time (RFC 3339, YYYY-MM-DD, or an age like 24h or 7d)")
+}
+
+func (f *listFlags) options() (vm.ListOptions, error) {
+	opts := vm.ListOptions{
+		Status:     f.status,
+		Repository: f.repository,
+	}
+	if !f.all {
+		if f.limit < 0 {
+			return opts, fmt.Errorf("--limit must not be negative (use --all for everything)")
+		}
+		opts.Limit = f.limit
+	}
+	if f.createdAfter != "" {
+		t, err := parseCreatedAfter(f.createdAfter, time.Now())
+		if err != nil {
+			return opts, err
+		}
+		opts.CreatedAfter = t
+	}
+	return opts, nil
+}
+
+// parseCreatedAfter accepts an RFC 3339 time, a date, or an age relative to
+// now.
+func parseCreatedAfter(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	age, err := parseDays(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --created-after %q (use RFC 3339, YYYY-MM-DD, or an age like 24h or 7d)", value)
+	}
+	return now.Add(-age), nil
+}
+
+// printMoreHint tells the user the list was cut off by --limit.
+func printMoreHint(more bool, noun string) {
+	if more {
+		fmt.Printf("\nMore %s available; use --limit N or --all to see them.\n", noun)
+	}
+}
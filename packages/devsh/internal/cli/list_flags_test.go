@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+func TestParseCreatedAfter(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2026-05-01T08:00:00Z": time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+		"24h":                  now.Add(-24 * time.Hour),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"2026-05-01":           time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local),
+	}
+	for in, want := range cases {
+		got, err := parseCreatedAfter(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseCreatedAfter(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseCreatedAfter("last week", now); err == nil {
+		t.Error("expected error for unparseable value")
+	}
+}
+
+func TestListFlagsOptions(t *testing.T) {
+	f := listFlags{limit: 20, status: "running"}
+	opts, err := f.options()
+	if err != nil || opts.Limit != 20 || opts.Status != "running" {
+		t.Fatalf("options() = %+v, %v", opts, err)
+	}
+
+	f.all = true
+	if opts, _ := f.options(); opts.Limit != 0 {
+		t.Fatalf("--all limit = %d, want 0", opts.Limit)
+	}
+
+	if opts, err := (&listFlags{limit: 0}).options(); err != nil || opts.Limit != 0 {
+		t.Fatalf("--limit 0 = %+v, %v; want no limit", opts, err)
+	}
+	if _, err := (&listFlags{limit: -1}).options(); err == nil {
+		t.Fatal("expected error for --limit -1")
+	}
+}
+
+func TestFilterInstances(t *testing.T) {
+	instances := []vm.Instance{{ID: "a", Status: "running"}, {ID: "b", Status: "paused"}, {ID: "c", Status: "Running"}, {ID: "d", Status: "running"}}
+
+	got, more := filterInstances(append([]vm.Instance(nil), instances...), vm.ListOptions{Status: "running", Limit: 2})
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" || !more {
+		t.Fatalf("filterInstances() = %v, more = %v", got, more)
+	}
+	if got, more := filterInstances(instances, vm.ListOptions{}); len(got) != 4 || more {
+		t.Fatalf("unfiltered = %v, more = %v", got, more)
+	}
+}
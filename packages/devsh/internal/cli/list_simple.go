@@ -4,6 +4,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/karlorz/devsh/internal/auth"
@@ -22,16 +23,43 @@ var listCmd = &cobra.Command{
 
 Examples:
   devsh ls
-  devsh list`,
+  devsh list
+  devsh ls --detailed   # Uptime, CPU, memory and IP (pve-lxc)
+  devsh ls --all        # Every instance, not just the first 50
+  devsh ls --status running --created-after 24h
+  devsh ls --all-providers   # Morph, PVE LXC and E2B in one table`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if listAllProviders {
+			if listDetailed {
+				return fmt.Errorf("--detailed cannot be combined with --all-providers")
+			}
+			opts, err := listPaging.options()
+			if err != nil {
+				return err
+			}
+			return runListAllProviders(ctx, opts)
+		}
+
 		selected, err := resolveProviderForCommand()
 		if err != nil {
 			return err
 		}
 
+		if listDetailed {
+			if selected != provider.PveLxc || !provider.HasPveEnv() {
+				return fmt.Errorf("--detailed is only supported for provider pve-lxc with PVE_API_URL and PVE_API_TOKEN set")
+			}
+			return runListDetailedPveLxc(ctx)
+		}
+
+		opts, err := listPaging.options()
+		if err != nil {
+			return err
+		}
+
 		var instances []vm.Instance
 
 		switch selected {
@@ -83,22 +111,7 @@ Examples:
 			}
 			client.SetTeamSlug(teamSlug)
 
-			basicInstances, err := client.ListInstances(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to list instances: %w", err)
-			}
-
-			// Fetch full details for each instance to get URLs
-			// (list endpoint returns basic info only)
-			for _, basic := range basicInstances {
-				full, err := client.GetInstance(ctx, basic.ID)
-				if err != nil {
-					// Fall back to basic info if fetch fails
-					instances = append(instances, basic)
-				} else {
-					instances = append(instances, *full)
-				}
-			}
+			return runListMorph(ctx, client, opts)
 		case provider.E2B:
 			teamSlug, err := auth.GetTeamSlug()
 			if err != nil {
@@ -123,29 +136,145 @@ Examples:
 				})
 			}
 		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+			p, err := newSandboxProvider(selected)
+			if err != nil {
+				return err
+			}
+			sandboxes, err := p.List(ctx, provider.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list instances: %w", err)
+			}
+			for _, sb := range sandboxes {
+				instances = append(instances, vm.Instance{
+					ID:        sb.ID,
+					Status:    sb.Status,
+					VSCodeURL: sb.VSCodeURL,
+				})
+			}
 		}
 
+		// Only Morph filters server-side; apply --status and --limit here
+		// for the other providers.
+		instances, more := filterInstances(instances, opts)
 		if len(instances) == 0 {
 			fmt.Println("No VMs found. Run 'devsh start' to create one.")
 			return nil
 		}
 
-		fmt.Printf("%-20s %-10s %s\n", "ID", "STATUS", "VS CODE URL")
-		fmt.Println("-------------------- ---------- " + "----------------------------------------")
+		printInstanceColumns()
+		printInstanceRows(instances)
+		printMoreHint(more, "instances")
+		return nil
+	},
+}
+
+// runListMorph prints Morph instances page by page as they arrive.
+func runListMorph(ctx context.Context, client *vm.Client, opts vm.ListOptions) error {
+	count := 0
+	more, err := client.EachInstancePage(ctx, opts, func(page []vm.Instance) error {
+		if count == 0 {
+			printInstanceColumns()
+		}
+		// Fetch full details for each instance to get URLs
+		// (list endpoint returns basic info only)
+		rows := make([]vm.Instance, 0, len(page))
+		for _, basic := range page {
+			full, err := client.GetInstance(ctx, basic.ID)
+			if err != nil {
+				// Fall back to basic info if fetch fails
+				rows = append(rows, basic)
+			} else {
+				rows = append(rows, *full)
+			}
+		}
+		printInstanceRows(rows)
+		count += len(page)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+	if count == 0 {
+		fmt.Println("No VMs found. Run 'devsh start' to create one.")
+		return nil
+	}
+	printMoreHint(more, "instances")
+	return nil
+}
 
+// filterInstances applies opts' status filter and limit client-side.
+func filterInstances(instances []vm.Instance, opts vm.ListOptions) ([]vm.Instance, bool) {
+	if opts.Status != "" {
+		filtered := instances[:0]
 		for _, inst := range instances {
-			url := inst.VSCodeURL
-			if len(url) > 40 {
-				url = url[:40] + "..."
+			if strings.EqualFold(inst.Status, opts.Status) {
+				filtered = append(filtered, inst)
 			}
-			fmt.Printf("%-20s %-10s %s\n", inst.ID, inst.Status, url)
 		}
+		instances = filtered
+	}
+	if opts.Limit > 0 && len(instances) > opts.Limit {
+		return instances[:opts.Limit], true
+	}
+	return instances, false
+}
+
+func printInstanceColumns() {
+	fmt.Printf("%-20s %-10s %s\n", "ID", "STATUS", "VS CODE URL")
+	fmt.Println("-------------------- ---------- " + "----------------------------------------")
+}
+
+func printInstanceRows(instances []vm.Instance) {
+	for _, inst := range instances {
+		url := inst.VSCodeURL
+		if len(url) > 40 {
+			url = url[:40] + "..."
+		}
+		fmt.Printf("%-20s %-10s %s\n", inst.ID, inst.Status, url)
+	}
+}
 
+var (
+	listDetailed bool
+	listPaging   listFlags
+)
+
+// runListDetailedPveLxc prints cmux containers with resource usage, using one
+// node list call plus concurrent config fetches.
+func runListDetailedPveLxc(ctx context.Context) error {
+	client, err := pvelxc.NewClientFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
+	}
+	details, err := client.ListInstancesDetailed(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+	if len(details) == 0 {
+		fmt.Println("No VMs found. Run 'devsh start' to create one.")
 		return nil
-	},
+	}
+
+	fmt.Printf("%-20s %-10s %-10s %-6s %-16s %-15s %s\n", "ID", "STATUS", "UPTIME", "CPU", "MEM (MiB)", "IP", "VS CODE URL")
+	for _, d := range details {
+		uptime, cpu, mem := "-", "-", "-"
+		if d.Status == "running" {
+			uptime = formatDuration(d.Uptime.Milliseconds())
+			cpu = fmt.Sprintf("%.0f%%", d.CPU*100)
+			mem = fmt.Sprintf("%d/%d", d.Mem>>20, d.MaxMem>>20)
+		}
+		ip := d.IP
+		if ip == "" {
+			ip = "-"
+		}
+		fmt.Printf("%-20s %-10s %-10s %-6s %-16s %-15s %s\n", d.ID, d.Status, uptime, cpu, mem, ip, d.VSCodeURL)
+	}
+	return nil
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listDetailed, "detailed", false, "Show uptime, CPU, memory and IP for each instance (pve-lxc)")
+	listCmd.Flags().BoolVar(&listAllProviders, "all-providers", false, "List instances from every configured provider in one table")
+	listPaging.register(listCmd, false, 0)
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,87 @@
+// internal/cli/logs.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
+	"github.com/spf13/cobra"
+)
+
+var logsLines int
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <id>",
+	Short: "Show container system logs",
+	Long: `Show the last lines of a container's system log.
+
+Useful when a container starts but its services never come up. With
+PVE_API_URL/PVE_API_TOKEN set, the node's journal for the container is
+read directly, so this works even when nothing inside the container
+responds. Otherwise the log is read through the in-container exec daemon.
+
+Currently supported for pve-lxc instances only.
+
+Examples:
+  devsh logs pvelxc-abc123
+  devsh logs pvelxc-abc123 -n 300`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		instanceID := args[0]
+
+		selected, err := resolveProviderForInstance(instanceID)
+		if err != nil {
+			return err
+		}
+		if selected != provider.PveLxc {
+			return fmt.Errorf("logs is not supported for provider %s", selected)
+		}
+
+		output, err := fetchPveLxcLogs(ctx, instanceID, logsLines)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(output) == "" {
+			fmt.Println("(no log output)")
+			return nil
+		}
+		fmt.Println(output)
+		return nil
+	},
+}
+
+func fetchPveLxcLogs(ctx context.Context, instanceID string, lines int) (string, error) {
+	if provider.HasPveEnv() {
+		client, err := pvelxc.NewClientFromEnv()
+		if err != nil {
+			return "", fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
+		}
+
+		output, err := client.ConsoleLogs(ctx, instanceID, lines)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch logs: %w", err)
+		}
+		return output, nil
+	}
+
+	stdout, stderr, exitCode, err := execPveLxcInstance(ctx, instanceID, pvelxc.ConsoleLogCommand(lines), 60)
+	if err != nil {
+		return "", err
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("failed to fetch logs: exit %d: %s", exitCode, strings.TrimSpace(stderr))
+	}
+	return strings.TrimRight(stdout, "\n"), nil
+}
+
+func init() {
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", pvelxc.DefaultConsoleLogLines, "Number of log lines to show")
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,197 @@
+// internal/cli/models_capabilities.go
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ModelPricing is the list price in USD per million tokens
+type ModelPricing struct {
+	InputPerMillion  float64 `json:"inputPerMillion"`
+	OutputPerMillion float64 `json:"outputPerMillion"`
+}
+
+// modelCapabilityFilter narrows models by capability metadata. A model
+// without the metadata a filter needs never matches that filter; when no
+// model has it at all, the filter is dropped instead (see applicable).
+type modelCapabilityFilter struct {
+	minContext  int
+	maxPriceIn  *float64
+	maxPriceOut *float64
+	modalities  []string
+}
+
+func addModelCapabilityFlags(cmd *cobra.Command) {
+	cmd.Flags().String("min-context", "", "Minimum context window (e.g. 128k, 1m, 200000)")
+	cmd.Flags().Float64("max-price-in", 0, "Maximum input price in USD per 1M tokens")
+	cmd.Flags().Float64("max-price-out", 0, "Maximum output price in USD per 1M tokens")
+	cmd.Flags().StringSlice("modality", nil, "Required modality (e.g. vision); repeatable")
+	cmd.Flags().String("sort", "", "Sort by price (cheapest first) or context (largest first)")
+}
+
+func capabilityFilterFromFlags(cmd *cobra.Command) (modelCapabilityFilter, error) {
+	var f modelCapabilityFilter
+
+	if raw, _ := cmd.Flags().GetString("min-context"); raw != "" {
+		n, err := parseTokenCount(raw)
+		if err != nil {
+			return f, fmt.Errorf("invalid --min-context: %w", err)
+		}
+		f.minContext = n
+	}
+	if cmd.Flags().Changed("max-price-in") {
+		v, _ := cmd.Flags().GetFloat64("max-price-in")
+		f.maxPriceIn = &v
+	}
+	if cmd.Flags().Changed("max-price-out") {
+		v, _ := cmd.Flags().GetFloat64("max-price-out")
+		f.maxPriceOut = &v
+	}
+	modalities, _ := cmd.Flags().GetStringSlice("modality")
+	for _, m := range modalities {
+		if m = strings.ToLower(strings.TrimSpace(m)); m != "" {
+			f.modalities = append(f.modalities, m)
+		}
+	}
+	return f, nil
+}
+
+// applicable returns f without the filters that no model in models carries
+// metadata for, along with the flags that were dropped. The control plane
+// does not publish context windows, pricing or modalities for every
+// provider, and filtering on absent metadata would hide every model.
+func (f modelCapabilityFilter) applicable(models []ModelInfo) (modelCapabilityFilter, []string) {
+	var hasContext, hasPricing, hasModalities bool
+	for _, m := range models {
+		hasContext = hasContext || m.ContextWindow > 0
+		hasPricing = hasPricing || m.Pricing != nil
+		hasModalities = hasModalities || len(m.Modalities) > 0
+	}
+
+	var dropped []string
+	if f.minContext > 0 && !hasContext {
+		f.minContext = 0
+		dropped = append(dropped, "--min-context")
+	}
+	if f.maxPriceIn != nil && !hasPricing {
+		f.maxPriceIn = nil
+		dropped = append(dropped, "--max-price-in")
+	}
+	if f.maxPriceOut != nil && !hasPricing {
+		f.maxPriceOut = nil
+		dropped = append(dropped, "--max-price-out")
+	}
+	if len(f.modalities) > 0 && !hasModalities {
+		f.modalities = nil
+		dropped = append(dropped, "--modality")
+	}
+	return f, dropped
+}
+
+func (f modelCapabilityFilter) matches(m ModelInfo) bool {
+	if f.minContext > 0 && m.ContextWindow < f.minContext {
+		return false
+	}
+	if f.maxPriceIn != nil && (m.Pricing == nil || m.Pricing.InputPerMillion > *f.maxPriceIn) {
+		return false
+	}
+	if f.maxPriceOut != nil && (m.Pricing == nil || m.Pricing.OutputPerMillion > *f.maxPriceOut) {
+		return false
+	}
+	for _, want := range f.modalities {
+		if !hasModality(m, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasModality(m ModelInfo, modality string) bool {
+	for _, have := range m.Modalities {
+		if strings.EqualFold(have, modality) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterModelsByCapability keeps models matching every set capability filter
+func filterModelsByCapability(models []ModelInfo, f modelCapabilityFilter) []ModelInfo {
+	var result []ModelInfo
+	for _, m := range models {
+		if f.matches(m) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// parseTokenCount parses a token count with an optional k or m suffix
+// (decimal: 128k = 128000).
+func parseTokenCount(raw string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		multiplier, s = 1e6, strings.TrimSuffix(s, "m")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("expected a token count like 128k, got %q", raw)
+	}
+	return int(v * multiplier), nil
+}
+
+// sortModelsBy sorts by "price" (input then output price, cheapest first) or
+// "context" (largest first). Models missing the metadata sort last.
+func sortModelsBy(models []ModelInfo, key string) error {
+	switch strings.ToLower(key) {
+	case "price":
+		sort.SliceStable(models, func(i, j int) bool {
+			a, b := models[i].Pricing, models[j].Pricing
+			if a == nil || b == nil {
+				return a != nil
+			}
+			if a.InputPerMillion != b.InputPerMillion {
+				return a.InputPerMillion < b.InputPerMillion
+			}
+			return a.OutputPerMillion < b.OutputPerMillion
+		})
+	case "context":
+		sort.SliceStable(models, func(i, j int) bool {
+			return models[i].ContextWindow > models[j].ContextWindow
+		})
+	default:
+		return fmt.Errorf("invalid --sort %q (expected price or context)", key)
+	}
+	return nil
+}
+
+// formatContextWindow renders a token count compactly, e.g. 128K or 1M.
+func formatContextWindow(n int) string {
+	switch {
+	case n <= 0:
+		return "-"
+	case n >= 1e6 && n%1e5 == 0:
+		return strconv.FormatFloat(float64(n)/1e6, 'f', -1, 64) + "M"
+	case n >= 1e3:
+		return strconv.Itoa(n/1e3) + "K"
+	}
+	return strconv.Itoa(n)
+}
+
+// formatPricing renders input/output prices per 1M tokens, e.g. $3/$15.
+func formatPricing(p *ModelPricing) string {
+	if p == nil {
+		return "-"
+	}
+	return "$" + strconv.FormatFloat(p.InputPerMillion, 'f', -1, 64) +
+		"/$" + strconv.FormatFloat(p.OutputPerMillion, 'f', -1, 64)
+}
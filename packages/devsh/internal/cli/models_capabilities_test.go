@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func capabilityTestModels() []ModelInfo {
+	return []ModelInfo{
+		{Name: "claude/opus", ContextWindow: 1_000_000, Pricing: &ModelPricing{InputPerMillion: 15, OutputPerMillion: 75}, Modalities: []string{"text", "vision"}},
+		{Name: "claude/sonnet", ContextWindow: 200_000, Pricing: &ModelPricing{InputPerMillion: 3, OutputPerMillion: 15}, Modalities: []string{"text", "Vision"}},
+		{Name: "qwen/coder", ContextWindow: 131072, Pricing: &ModelPricing{InputPerMillion: 0, OutputPerMillion: 0}, Modalities: []string{"text"}},
+		{Name: "unknown/model"},
+	}
+}
+
+func modelNames(models []ModelInfo) string {
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFilterModelsByCapability(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter modelCapabilityFilter
+		want   string
+	}{
+		{"no filter", modelCapabilityFilter{}, "claude/opus,claude/sonnet,qwen/coder,unknown/model"},
+		{"min context 128k", modelCapabilityFilter{minContext: 128_000}, "claude/opus,claude/sonnet,qwen/coder"},
+		{"min context 1m", modelCapabilityFilter{minContext: 1_000_000}, "claude/opus"},
+		{"max price in 5", modelCapabilityFilter{maxPriceIn: float64Ptr(5)}, "claude/sonnet,qwen/coder"},
+		{"free only", modelCapabilityFilter{maxPriceIn: float64Ptr(0), maxPriceOut: float64Ptr(0)}, "qwen/coder"},
+		{"vision case-insensitive", modelCapabilityFilter{modalities: []string{"vision"}}, "claude/opus,claude/sonnet"},
+		{"combined", modelCapabilityFilter{minContext: 150_000, maxPriceIn: float64Ptr(5), modalities: []string{"vision"}}, "claude/sonnet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := modelNames(filterModelsByCapability(capabilityTestModels(), tt.filter))
+			if got != tt.want {
+				t.Errorf("filterModelsByCapability() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapabilityFilterApplicable(t *testing.T) {
+	f := modelCapabilityFilter{minContext: 128_000, maxPriceIn: float64Ptr(5), modalities: []string{"vision"}}
+
+	got, dropped := f.applicable(capabilityTestModels())
+	if len(dropped) != 0 || got.minContext != 128_000 || got.maxPriceIn == nil || len(got.modalities) != 1 {
+		t.Errorf("with metadata: filter = %+v, dropped = %v", got, dropped)
+	}
+
+	bare := []ModelInfo{{Name: "a/one"}, {Name: "b/two", ContextWindow: 200_000}}
+	got, dropped = f.applicable(bare)
+	if strings.Join(dropped, ",") != "--max-price-in,--modality" {
+		t.Errorf("dropped = %v, want --max-price-in,--modality", dropped)
+	}
+	if names := modelNames(filterModelsByCapability(bare, got)); names != "b/two" {
+		t.Errorf("filtered = %s, want b/two", names)
+	}
+}
+
+func TestParseTokenCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"128k", 128_000},
+		{"128K", 128_000},
+		{"1m", 1_000_000},
+		{"1.5M", 1_500_000},
+		{"200000", 200_000},
+	}
+	for _, tt := range tests {
+		got, err := parseTokenCount(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTokenCount(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "k", "lots", "-5k"} {
+		if _, err := parseTokenCount(bad); err == nil {
+			t.Errorf("parseTokenCount(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSortModelsBy(t *testing.T) {
+	models := capabilityTestModels()
+	if err := sortModelsBy(models, "price"); err != nil {
+		t.Fatal(err)
+	}
+	if got := modelNames(models); got != "qwen/coder,claude/sonnet,claude/opus,unknown/model" {
+		t.Errorf("sort by price = %s", got)
+	}
+
+	if err := sortModelsBy(models, "context"); err != nil {
+		t.Fatal(err)
+	}
+	if got := modelNames(models); got != "claude/opus,claude/sonnet,qwen/coder,unknown/model" {
+		t.Errorf("sort by context = %s", got)
+	}
+
+	if err := sortModelsBy(models, "name"); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+}
+
+func TestFormatCapabilities(t *testing.T) {
+	contexts := map[int]string{0: "-", 131072: "131K", 200_000: "200K", 1_000_000: "1M", 2_000_000: "2M", 1_500_000: "1.5M", 512: "512"}
+	for n, want := range contexts {
+		if got := formatContextWindow(n); got != want {
+			t.Errorf("formatContextWindow(%d) = %q, want %q", n, got, want)
+		}
+	}
+
+	if got := formatPricing(&ModelPricing{InputPerMillion: 0.25, OutputPerMillion: 1.25}); got != "$0.25/$1.25" {
+		t.Errorf("formatPricing() = %q", got)
+	}
+	if got := formatPricing(nil); got != "-" {
+		t.Errorf("formatPricing(nil) = %q", got)
+	}
+}
+
+func TestPrintModelsTableVerboseIncludesCapabilities(t *testing.T) {
+	output := captureStdout(t, func() {
+		if err := printModelsTable(capabilityTestModels()[:1], true); err != nil {
+			t.Fatalf("printModelsTable failed: %v", err)
+		}
+	})
+
+	for _, want := range []string{"CONTEXT", "PRICE (IN/OUT)", "1M", "$15/$75", "text,vision"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got %q", want, output)
+		}
+	}
+}
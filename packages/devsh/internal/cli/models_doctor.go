@@ -0,0 +1,330 @@
+// internal/cli/models_doctor.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karlorz/devsh/internal/credentials"
+	"github.com/spf13/cobra"
+)
+
+var modelsDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose provider credentials and show which models are usable",
+	Long: `Check each provider's credentials locally and on the server, validate local
+API keys with one cheap live request per provider, and report which models are
+actually usable and why the others are not (missing key, invalid key, quota
+exhausted).
+
+A model is usable when its provider is connected on the server or has working
+local credentials. OAuth tokens and CLI config files are reported as present
+but are not validated live.
+
+Examples:
+  devsh models doctor                 # Check everything
+  devsh models doctor --timeout 2s    # Shorter live checks
+  devsh models doctor --no-live       # Skip live validation requests
+  devsh models doctor --json          # JSON output`,
+	RunE: runModelsDoctor,
+}
+
+func init() {
+	modelsDoctorCmd.Flags().Duration("timeout", 5*time.Second, "Timeout for each live credential check")
+	modelsDoctorCmd.Flags().Bool("no-live", false, "Skip live validation requests")
+	modelsCmd.AddCommand(modelsDoctorCmd)
+}
+
+// DoctorProvider is the credential diagnosis for one provider
+type DoctorProvider struct {
+	ID              string               `json:"id"`
+	LocalSource     string               `json:"localSource,omitempty"`
+	Live            credentials.KeyCheck `json:"live"`
+	LatencyMs       int64                `json:"latencyMs,omitempty"`
+	ServerConnected bool                 `json:"serverConnected"`
+	ServerSource    string               `json:"serverSource,omitempty"`
+	Usable          bool                 `json:"usable"`
+	Via             string               `json:"via,omitempty"` // "server" or "local"
+	Reason          string               `json:"reason,omitempty"`
+}
+
+// DoctorModel is the usability diagnosis for one model
+type DoctorModel struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Usable   bool   `json:"usable"`
+	Via      string `json:"via,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func runModelsDoctor(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	noLive, _ := cmd.Flags().GetBool("no-live")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	local := credentials.CheckAllProviders()
+
+	var server map[string]ControlPlaneProvider
+	if resp, err := fetchControlPlaneProviders(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not fetch server provider status (%v), checking local credentials only\n", err)
+	} else {
+		server = make(map[string]ControlPlaneProvider, len(resp.Providers))
+		for _, p := range resp.Providers {
+			server[p.ID] = p
+		}
+	}
+
+	ids := doctorProviderIDs(server)
+	live := checkProvidersLive(ctx, ids, local, timeout, noLive)
+
+	providers := make([]DoctorProvider, 0, len(ids))
+	byID := make(map[string]DoctorProvider, len(ids))
+	for _, id := range ids {
+		var sp *ControlPlaneProvider
+		if p, ok := server[id]; ok {
+			sp = &p
+		}
+		d := diagnoseProvider(id, local.Providers[id], live[id], sp)
+		providers = append(providers, d)
+		byID[id] = d
+	}
+
+	models, err := FetchModelsFromControlPlane(ctx, "all", "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not fetch models (%v)\n", err)
+	}
+	sortModelsByVendor(models)
+	diagnosed := diagnoseModels(models, byID)
+
+	if flagJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"providers":     providers,
+			"models":        diagnosed,
+			"serverChecked": server != nil,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printDoctorProviders(providers, server != nil)
+	printDoctorModels(diagnosed)
+	return nil
+}
+
+// doctorProviderIDs lists the known providers in display order, followed by
+// any extra providers the server reports.
+func doctorProviderIDs(server map[string]ControlPlaneProvider) []string {
+	ids := append([]string(nil), credentials.ProviderOrder...)
+	var extra []string
+	for id := range server {
+		if !slices.Contains(ids, id) {
+			extra = append(extra, id)
+		}
+	}
+	sort.Strings(extra)
+	return append(ids, extra...)
+}
+
+// checkProvidersLive validates local credentials for every provider
+// concurrently, each bounded by timeout.
+func checkProvidersLive(ctx context.Context, ids []string, local credentials.AllProviderStatus, timeout time.Duration, skip bool) map[string]credentials.KeyCheck {
+	results := make(map[string]credentials.KeyCheck, len(ids))
+	client := &http.Client{Timeout: timeout}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		status := local.Providers[id]
+		if skip || !status.Available {
+			check := credentials.KeyCheck{Result: credentials.KeyMissing}
+			if status.Available {
+				check = credentials.KeyCheck{Result: credentials.KeyNotValidated, Detail: "live check skipped"}
+			}
+			results[id] = check
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string, status credentials.ProviderStatus) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			check := credentials.ValidateProvider(checkCtx, client, id, status)
+			mu.Lock()
+			results[id] = check
+			mu.Unlock()
+		}(id, status)
+	}
+	wg.Wait()
+	return results
+}
+
+// diagnoseProvider combines local, live and server state. Server-side
+// credentials win because sandboxes use them; otherwise local credentials
+// count unless the live check rejected them.
+func diagnoseProvider(id string, local credentials.ProviderStatus, live credentials.KeyCheck, server *ControlPlaneProvider) DoctorProvider {
+	d := DoctorProvider{ID: id, Live: live, LatencyMs: live.Latency.Milliseconds()}
+	if local.Available {
+		d.LocalSource = local.Source
+	}
+	if server != nil && server.ConnectionState.IsConnected {
+		d.ServerConnected = true
+		if server.ConnectionState.Source != nil {
+			d.ServerSource = *server.ConnectionState.Source
+		}
+	}
+
+	switch {
+	case d.ServerConnected:
+		d.Usable, d.Via = true, "server"
+	case live.Result == credentials.KeyValid || live.Result == credentials.KeyNotValidated || live.Result == credentials.KeyRateLimited:
+		d.Usable, d.Via = true, "local"
+	default:
+		d.Reason = keyCheckReason(live)
+	}
+	return d
+}
+
+func keyCheckReason(check credentials.KeyCheck) string {
+	withDetail := func(reason string) string {
+		if check.Detail != "" {
+			return reason + " (" + check.Detail + ")"
+		}
+		return reason
+	}
+
+	switch check.Result {
+	case credentials.KeyMissing:
+		return "missing key"
+	case credentials.KeyInvalid:
+		return withDetail("invalid key")
+	case credentials.KeyQuotaExhausted:
+		return withDetail("quota exhausted")
+	default:
+		return withDetail("validation failed")
+	}
+}
+
+// diagnoseModels marks each model usable when its provider is usable.
+func diagnoseModels(models []ModelInfo, providers map[string]DoctorProvider) []DoctorModel {
+	result := make([]DoctorModel, 0, len(models))
+	for _, m := range models {
+		provider := credentials.GetProviderForVendor(strings.ToLower(m.Vendor))
+		d := DoctorModel{Name: m.Name, Provider: provider}
+
+		p, known := providers[provider]
+		switch {
+		case m.Disabled:
+			d.Reason = "disabled"
+			if m.DisabledReason != nil && *m.DisabledReason != "" {
+				d.Reason = "disabled: " + *m.DisabledReason
+			}
+		case !known:
+			d.Reason = "no credentials for " + provider
+		case p.Usable:
+			d.Usable, d.Via = true, p.Via
+		default:
+			d.Reason = p.Reason
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+func printDoctorProviders(providers []DoctorProvider, serverChecked bool) {
+	fmt.Println("Provider Credentials")
+	fmt.Println("====================")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tLOCAL\tLIVE CHECK\tSERVER\tSTATUS")
+	fmt.Fprintln(w, "--------\t-----\t----------\t------\t------")
+	for _, p := range providers {
+		localSource := "-"
+		if p.LocalSource != "" {
+			localSource = p.LocalSource
+		}
+
+		serverStatus := "Not configured"
+		switch {
+		case !serverChecked:
+			serverStatus = "unknown"
+		case p.ServerConnected:
+			serverStatus = "Connected"
+			if p.ServerSource != "" {
+				serverStatus += " (" + formatConnectionSource(p.ServerSource) + ")"
+			}
+		}
+
+		status := "usable via " + p.Via
+		if !p.Usable {
+			status = p.Reason
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.ID, localSource, formatKeyCheck(p.Live), serverStatus, status)
+	}
+	w.Flush()
+}
+
+func formatKeyCheck(check credentials.KeyCheck) string {
+	switch check.Result {
+	case credentials.KeyValid:
+		return fmt.Sprintf("valid (%dms)", check.Latency.Milliseconds())
+	case credentials.KeyMissing:
+		return "-"
+	case credentials.KeyNotValidated:
+		return "not checked"
+	case credentials.KeyInvalid:
+		return "invalid key"
+	case credentials.KeyQuotaExhausted:
+		return "quota exhausted"
+	case credentials.KeyRateLimited:
+		return "rate limited"
+	default:
+		return "error"
+	}
+}
+
+func printDoctorModels(models []DoctorModel) {
+	var usable, unusable []DoctorModel
+	for _, m := range models {
+		if m.Usable {
+			usable = append(usable, m)
+		} else {
+			unusable = append(unusable, m)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Usable Models (%d)\n", len(usable))
+	fmt.Println("=================")
+	for _, m := range usable {
+		fmt.Printf("  %s (via %s)\n", m.Name, m.Via)
+	}
+
+	if len(unusable) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Unusable Models (%d)\n", len(unusable))
+	fmt.Println("===================")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, m := range unusable {
+		fmt.Fprintf(w, "  %s\t%s\n", m.Name, m.Reason)
+	}
+	w.Flush()
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/karlorz/devsh/internal/credentials"
+)
+
+func connectedProvider(id, source string) *ControlPlaneProvider {
+	return &ControlPlaneProvider{ID: id, ConnectionState: ControlPlaneConnectionState{IsConnected: true, Source: &source}}
+}
+
+func TestDiagnoseProvider(t *testing.T) {
+	envKey := credentials.ProviderStatus{Available: true, Source: "env:OPENAI_API_KEY"}
+
+	tests := []struct {
+		name       string
+		local      credentials.ProviderStatus
+		live       credentials.KeyCheck
+		server     *ControlPlaneProvider
+		wantUsable bool
+		wantVia    string
+		wantReason string
+	}{
+		{"server connected beats invalid local key", envKey, credentials.KeyCheck{Result: credentials.KeyInvalid}, connectedProvider("openai", "stored_api_key"), true, "server", ""},
+		{"valid local key", envKey, credentials.KeyCheck{Result: credentials.KeyValid}, &ControlPlaneProvider{ID: "openai"}, true, "local", ""},
+		{"oauth not validated", credentials.ProviderStatus{Available: true, Source: "~/.codex/auth.json"}, credentials.KeyCheck{Result: credentials.KeyNotValidated}, nil, true, "local", ""},
+		{"missing", credentials.ProviderStatus{}, credentials.KeyCheck{Result: credentials.KeyMissing}, nil, false, "", "missing key"},
+		{"invalid", envKey, credentials.KeyCheck{Result: credentials.KeyInvalid, Detail: "HTTP 401"}, nil, false, "", "invalid key (HTTP 401)"},
+		{"quota", envKey, credentials.KeyCheck{Result: credentials.KeyQuotaExhausted, Detail: "HTTP 429"}, nil, false, "", "quota exhausted (HTTP 429)"},
+		{"rate limited local key", envKey, credentials.KeyCheck{Result: credentials.KeyRateLimited, Detail: "HTTP 429"}, nil, true, "local", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diagnoseProvider("openai", tt.local, tt.live, tt.server)
+			if got.Usable != tt.wantUsable || got.Via != tt.wantVia || got.Reason != tt.wantReason {
+				t.Errorf("diagnoseProvider() = usable=%v via=%q reason=%q, want usable=%v via=%q reason=%q",
+					got.Usable, got.Via, got.Reason, tt.wantUsable, tt.wantVia, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDiagnoseModels(t *testing.T) {
+	retired := "retired upstream"
+	models := []ModelInfo{
+		{Name: "claude/opus", Vendor: "claude"},
+		{Name: "codex/gpt-5", Vendor: "openai"},
+		{Name: "claude/old", Vendor: "anthropic", Disabled: true, DisabledReason: &retired},
+		{Name: "mystery/model", Vendor: "mystery"},
+	}
+	providers := map[string]DoctorProvider{
+		"anthropic": {ID: "anthropic", Usable: true, Via: "server"},
+		"openai":    {ID: "openai", Reason: "quota exhausted (HTTP 429)"},
+	}
+
+	got := diagnoseModels(models, providers)
+	want := []DoctorModel{
+		{Name: "claude/opus", Provider: "anthropic", Usable: true, Via: "server"},
+		{Name: "codex/gpt-5", Provider: "openai", Reason: "quota exhausted (HTTP 429)"},
+		{Name: "claude/old", Provider: "anthropic", Reason: "disabled: retired upstream"},
+		{Name: "mystery/model", Provider: "mystery", Reason: "no credentials for mystery"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diagnoseModels() returned %d models, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("model %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDoctorProviderIDsAppendsServerOnlyProviders(t *testing.T) {
+	ids := doctorProviderIDs(map[string]ControlPlaneProvider{"anthropic": {}, "zai": {}, "moonshot": {}})
+	tail := strings.Join(ids[len(ids)-2:], ",")
+	if tail != "moonshot,zai" {
+		t.Errorf("server-only providers = %s, want moonshot,zai", tail)
+	}
+	if ids[0] != "anthropic" || len(ids) != len(credentials.ProviderOrder)+2 {
+		t.Errorf("unexpected provider order: %v", ids)
+	}
+}
+
+func TestPrintDoctorOutput(t *testing.T) {
+	output := captureStdout(t, func() {
+		printDoctorProviders([]DoctorProvider{
+			{ID: "anthropic", LocalSource: "env:ANTHROPIC_API_KEY", Live: credentials.KeyCheck{Result: credentials.KeyValid}, Usable: true, Via: "local"},
+			{ID: "openai", Live: credentials.KeyCheck{Result: credentials.KeyMissing}, Reason: "missing key"},
+		}, false)
+		printDoctorModels([]DoctorModel{
+			{Name: "claude/opus", Usable: true, Via: "local"},
+			{Name: "codex/gpt-5", Reason: "missing key"},
+		})
+	})
+
+	for _, want := range []string{"LIVE CHECK", "valid (0ms)", "usable via local", "unknown", "Usable Models (1)", "claude/opus (via local)", "Unusable Models (1)", "missing key"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
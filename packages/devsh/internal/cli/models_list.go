@@ -43,6 +43,10 @@ type ModelInfo struct {
 	Tags            []string       `json:"tags"`
 	Variants        []ModelVariant `json:"variants"`
 	DefaultVariant  string         `json:"defaultVariant"`
+	ContextWindow   int            `json:"contextWindow,omitempty"`   // Tokens; 0 if unknown
+	MaxOutputTokens int            `json:"maxOutputTokens,omitempty"` // Tokens; 0 if unknown
+	Pricing         *ModelPricing  `json:"pricing,omitempty"`
+	Modalities      []string       `json:"modalities,omitempty"` // e.g. "text", "vision"
 }
 
 // ModelsListResponse from /api/models (legacy)
@@ -75,6 +79,10 @@ type ControlPlaneModel struct {
 	DefaultVariant  string         `json:"defaultVariant"`
 	Disabled        bool           `json:"disabled,omitempty"`
 	DisabledReason  string         `json:"disabledReason,omitempty"`
+	ContextWindow   int            `json:"contextWindow,omitempty"`
+	MaxOutputTokens int            `json:"maxOutputTokens,omitempty"`
+	Pricing         *ModelPricing  `json:"pricing,omitempty"`
+	Modalities      []string       `json:"modalities,omitempty"`
 }
 
 // ControlPlaneDefault represents a default model for a provider
@@ -129,7 +137,9 @@ Examples:
   devsh models --verbose               # Show table with details
   devsh models --json                  # JSON output
   devsh models claude                  # Filter by name
-  devsh models --provider openai       # Filter by vendor`,
+  devsh models --provider openai       # Filter by vendor
+  devsh models --min-context 128k --sort price   # Large context, cheapest first
+  devsh models --modality vision --max-price-in 5 --verbose`,
 	RunE: runModelsList,
 }
 
@@ -148,7 +158,8 @@ Examples:
   devsh models list --verbose          # Show table with details
   devsh models list --json             # JSON output
   devsh models list claude             # Filter by name
-  devsh models list --provider openai  # Filter by vendor`,
+  devsh models list --provider openai  # Filter by vendor
+  devsh models list --min-context 1m --sort context`,
 	RunE: runModelsList,
 }
 
@@ -167,6 +178,10 @@ func init() {
 	modelsListCmd.Flags().Bool("all", false, "Show all models (including unavailable)")
 	modelsListCmd.Flags().Bool("local", false, "Use local credentials for filtering (default: server-side)")
 
+	// Capability filters use metadata the server provides; models without it are excluded
+	addModelCapabilityFlags(modelsCmd)
+	addModelCapabilityFlags(modelsListCmd)
+
 	modelsCmd.AddCommand(modelsListCmd)
 	rootCmd.AddCommand(modelsCmd)
 }
@@ -187,6 +202,12 @@ func runModelsList(cmd *cobra.Command, args []string) error {
 	showAll, _ := cmd.Flags().GetBool("all")
 	useLocal, _ := cmd.Flags().GetBool("local")
 
+	capFilter, err := capabilityFilterFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	sortKey, _ := cmd.Flags().GetString("sort")
+
 	// Filter text from args
 	filter := ""
 	if len(args) > 0 {
@@ -194,7 +215,6 @@ func runModelsList(cmd *cobra.Command, args []string) error {
 	}
 
 	var models []ModelInfo
-	var err error
 
 	// Decide filtering approach
 	if useLocal {
@@ -231,9 +251,19 @@ func runModelsList(cmd *cobra.Command, args []string) error {
 
 	// Apply additional client-side filters (enabled-only, text filter)
 	filtered := filterModels(models, "", enabledOnly, filter) // provider already applied server-side
+	capFilter, dropped := capFilter.applicable(filtered)
+	if len(dropped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: the control plane returned no capability metadata for %s; ignoring\n", strings.Join(dropped, ", "))
+	}
+	filtered = filterModelsByCapability(filtered, capFilter)
 
-	// Sort by vendor to group models together
+	// Sort by vendor to group models together, unless another order was requested
 	sortModelsByVendor(filtered)
+	if sortKey != "" {
+		if err := sortModelsBy(filtered, sortKey); err != nil {
+			return err
+		}
+	}
 
 	// JSON output
 	if flagJSON {
@@ -358,6 +388,10 @@ func FetchModelsFromControlPlane(ctx context.Context, view string, vendorFilter
 			Tags:            m.Tags,
 			Variants:        m.Variants,
 			DefaultVariant:  m.DefaultVariant,
+			ContextWindow:   m.ContextWindow,
+			MaxOutputTokens: m.MaxOutputTokens,
+			Pricing:         m.Pricing,
+			Modalities:      m.Modalities,
 		})
 	}
 
@@ -482,8 +516,8 @@ func printModelsTable(models []ModelInfo, verbose bool) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	if verbose {
-		fmt.Fprintf(w, "NAME\tDISPLAY\tVENDOR\tTIER\tEFFORT\tTAGS\n")
-		fmt.Fprintf(w, "----\t-------\t------\t----\t------\t----\n")
+		fmt.Fprintf(w, "NAME\tDISPLAY\tVENDOR\tTIER\tCONTEXT\tPRICE (IN/OUT)\tMODALITIES\tEFFORT\tTAGS\n")
+		fmt.Fprintf(w, "----\t-------\t------\t----\t-------\t--------------\t----------\t------\t----\n")
 		for _, m := range models {
 			tags := ""
 			if len(m.Tags) > 0 {
@@ -501,12 +535,17 @@ func printModelsTable(models []ModelInfo, verbose bool) error {
 				}
 				effort = strings.Join(labels, ",")
 			}
+			modalities := "-"
+			if len(m.Modalities) > 0 {
+				modalities = strings.Join(m.Modalities, ",")
+			}
 			disabled := ""
 			if m.Disabled {
 				disabled = " (disabled)"
 			}
-			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\n",
-				m.Name, disabled, m.DisplayName, m.Vendor, m.Tier, effort, tags)
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				m.Name, disabled, m.DisplayName, m.Vendor, m.Tier,
+				formatContextWindow(m.ContextWindow), formatPricing(m.Pricing), modalities, effort, tags)
 		}
 	} else {
 		// Simple list (one per line)
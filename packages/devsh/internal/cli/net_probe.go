@@ -0,0 +1,87 @@
+// internal/cli/net_probe.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
+	"github.com/spf13/cobra"
+)
+
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: "Network diagnostics for sandboxes",
+}
+
+var netProbeCmd = &cobra.Command{
+	Use:   "probe <id>",
+	Short: "Check which exec endpoints of a PVE LXC sandbox are reachable",
+	Long: `Probe every way devsh can reach a PVE LXC sandbox's exec daemon (public
+proxy, direct IP, tailnet hostname) in parallel and rank them by latency.
+
+The fastest reachable endpoint is cached for 10 minutes, so later exec and
+file-push commands try it first instead of waiting on dead ones.
+
+Requires PVE_API_URL and PVE_API_TOKEN.
+
+Examples:
+  devsh net probe pvelxc-1234
+  devsh net probe 1234 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !provider.HasPveEnv() {
+			return fmt.Errorf("net probe requires PVE_API_URL and PVE_API_TOKEN")
+		}
+		client, err := pvelxc.NewClientFromEnv()
+		if err != nil {
+			return fmt.Errorf("failed to create PVE LXC client: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		report, err := client.ProbeEndpoints(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("probe failed: %w", err)
+		}
+
+		if flagJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tHOST\tRESULT")
+			for _, ep := range report.Endpoints {
+				result := fmt.Sprintf("ok (%s)", ep.Latency.Round(time.Millisecond))
+				if !ep.Reachable {
+					result = "unreachable: " + ep.Error
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", ep.Kind, ep.Host, result)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if report.Best == "" {
+			return fmt.Errorf("no exec endpoint of container %d is reachable", report.VMID)
+		}
+		if !flagJSON {
+			fmt.Printf("\nUsing %s for exec (cached 10m)\n", report.Best)
+		}
+		return nil
+	},
+}
+
+func init() {
+	netCmd.AddCommand(netProbeCmd)
+	rootCmd.AddCommand(netCmd)
+}
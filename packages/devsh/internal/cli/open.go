@@ -4,7 +4,6 @@ package cli
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
 	"runtime"
@@ -18,16 +17,7 @@ import (
 )
 
 func buildAuthURL(workerURL, targetPath, token string) (string, error) {
-	parsed, err := url.Parse(workerURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid URL: %w", err)
-	}
-	parsed.Path = "/_cmux/auth"
-	query := parsed.Query()
-	query.Set("token", token)
-	query.Set("return", targetPath)
-	parsed.RawQuery = query.Encode()
-	return parsed.String(), nil
+	return vm.BuildAuthURL(workerURL, targetPath, token)
 }
 
 // getAuthToken calls the worker to generate a one-time auth token
@@ -105,7 +95,7 @@ Examples:
 			fmt.Printf("Opening VS Code...\n")
 			return openBrowser(authURL)
 		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+			return openSandboxURL(ctx, selected, instanceID, "VS Code", func(sb *provider.Sandbox) string { return sb.VSCodeURL })
 		}
 	},
 }
@@ -176,7 +166,7 @@ Examples:
 			fmt.Printf("Opening VNC...\n")
 			return openBrowser(authURL)
 		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+			return openSandboxURL(ctx, selected, instanceID, "VNC", func(sb *provider.Sandbox) string { return sb.VNCURL })
 		}
 	},
 }
@@ -329,11 +319,49 @@ Examples:
 			}
 			return nil
 		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+			p, err := newSandboxProvider(selected)
+			if err != nil {
+				return err
+			}
+			sb, err := p.Get(ctx, instanceID)
+			if err != nil {
+				return fmt.Errorf("failed to get instance: %w", err)
+			}
+
+			fmt.Printf("ID:       %s\n", sb.ID)
+			fmt.Printf("Status:   %s\n", sb.Status)
+			if sb.VSCodeURL != "" {
+				fmt.Printf("VS Code:  %s\n", sb.VSCodeURL)
+			}
+			if sb.VNCURL != "" {
+				fmt.Printf("VNC:      %s\n", sb.VNCURL)
+			}
+			if sb.XTermURL != "" {
+				fmt.Printf("XTerm:    %s\n", sb.XTermURL)
+			}
+			return nil
 		}
 	},
 }
 
+// openSandboxURL opens a service URL for providers without a dedicated case.
+func openSandboxURL(ctx context.Context, selected, instanceID, service string, pick func(*provider.Sandbox) string) error {
+	p, err := newSandboxProvider(selected)
+	if err != nil {
+		return err
+	}
+	sb, err := provider.URLs(ctx, p, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get instance: %w", err)
+	}
+	url := pick(sb)
+	if url == "" {
+		return fmt.Errorf("%s URL not available", service)
+	}
+	fmt.Printf("Opening %s...\n", service)
+	return openBrowser(url)
+}
+
 func openBrowser(url string) error {
 	var cmd *exec.Cmd
 
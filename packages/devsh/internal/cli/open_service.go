@@ -0,0 +1,138 @@
+// internal/cli/open_service.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var openPrint bool
+
+// openServices maps the services `devsh open` knows to their sandbox URL.
+// Morph URLs come back from provider.URLs already carrying a one-time auth
+// token; the Chrome DevTools proxy is not behind worker auth.
+var openServices = []struct {
+	Name    string
+	Aliases []string
+	Label   string
+	URL     func(*provider.Sandbox) string
+}{
+	{"vscode", []string{"code"}, "VS Code", func(sb *provider.Sandbox) string { return sb.VSCodeURL }},
+	{"vnc", []string{"desktop"}, "VNC", func(sb *provider.Sandbox) string { return sb.VNCURL }},
+	{"chrome", []string{"cdp", "devtools"}, "Chrome DevTools", func(sb *provider.Sandbox) string { return sb.ChromeURL }},
+	{"xterm", []string{"terminal"}, "XTerm", func(sb *provider.Sandbox) string { return sb.XTermURL }},
+}
+
+// resolveOpenService returns the display label and URL for service.
+func resolveOpenService(sb *provider.Sandbox, service string) (string, string, error) {
+	service = strings.ToLower(strings.TrimSpace(service))
+	names := make([]string, 0, len(openServices))
+	for _, s := range openServices {
+		names = append(names, s.Name)
+		if service != s.Name && !slices.Contains(s.Aliases, service) {
+			continue
+		}
+		url := s.URL(sb)
+		if url == "" {
+			return "", "", fmt.Errorf("%s URL not available", s.Label)
+		}
+		return s.Label, url, nil
+	}
+	return "", "", fmt.Errorf("unknown service %q (expected one of: %s)", service, strings.Join(names, ", "))
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open <id> [vscode|vnc|chrome|xterm]",
+	Short: "Open a sandbox service in the browser",
+	Long: `Open a sandbox service in your browser, logged in.
+
+For Morph instances a one-time auth token is generated and attached, so
+the URL signs the browser in to the worker before redirecting to the
+service. The service defaults to vscode.
+
+Use --print to write the URL to stdout instead, e.g. on a headless
+machine or to open it elsewhere. Auth tokens are single use, so run the
+command again for each browser.
+
+Examples:
+  devsh open cmux_abc123
+  devsh open cmux_abc123 vnc
+  devsh open cmux_abc123 chrome --print`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		instanceID := args[0]
+		service := "vscode"
+		if len(args) == 2 {
+			service = args[1]
+		}
+
+		sb, err := sandboxServiceURLs(ctx, instanceID)
+		if err != nil {
+			return err
+		}
+		label, url, err := resolveOpenService(sb, service)
+		if err != nil {
+			return err
+		}
+
+		if openPrint {
+			fmt.Println(url)
+			return nil
+		}
+		fmt.Printf("Opening %s...\n", label)
+		if err := openBrowser(url); err != nil {
+			return fmt.Errorf("failed to open browser (use --print to get the URL): %w", err)
+		}
+		return nil
+	},
+}
+
+// sandboxServiceURLs returns an instance's service URLs, authenticated where
+// the provider supports it.
+func sandboxServiceURLs(ctx context.Context, instanceID string) (*provider.Sandbox, error) {
+	selected, err := resolveProviderForInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if selected == provider.PveLxc {
+		instance, err := getPveLxcInstance(ctx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+		return &provider.Sandbox{
+			ID:        instance.ID,
+			Status:    instance.Status,
+			VSCodeURL: instance.VSCodeURL,
+			VNCURL:    instance.VNCURL,
+			WorkerURL: instance.WorkerURL,
+			ChromeURL: instance.ChromeURL,
+			XTermURL:  instance.XTermURL,
+			Provider:  provider.PveLxc,
+		}, nil
+	}
+
+	p, err := newSandboxProvider(selected)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := provider.URLs(ctx, p, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %w", err)
+	}
+	return sb, nil
+}
+
+func init() {
+	openCmd.Flags().BoolVar(&openPrint, "print", false, "Print the URL instead of opening a browser")
+	rootCmd.AddCommand(openCmd)
+}
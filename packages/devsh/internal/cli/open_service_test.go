@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/karlorz/devsh/internal/provider"
+)
+
+func TestResolveOpenService(t *testing.T) {
+	sb := &provider.Sandbox{
+		VSCodeURL: "https://worker/_cmux/auth?token=t&return=%2Fcode%2F",
+		VNCURL:    "https://worker/_cmux/auth?token=t&return=%2Fvnc%2F",
+		ChromeURL: "https://cdp",
+	}
+
+	cases := map[string]string{
+		"vscode":   sb.VSCodeURL,
+		"code":     sb.VSCodeURL,
+		"VNC":      sb.VNCURL,
+		"devtools": sb.ChromeURL,
+	}
+	for service, want := range cases {
+		_, got, err := resolveOpenService(sb, service)
+		if err != nil || got != want {
+			t.Errorf("resolveOpenService(%q) = %q, %v; want %q", service, got, err, want)
+		}
+	}
+
+	if _, _, err := resolveOpenService(sb, "xterm"); err == nil || !strings.Contains(err.Error(), "XTerm URL not available") {
+		t.Errorf("missing URL error = %v", err)
+	}
+	if _, _, err := resolveOpenService(sb, "jupyter"); err == nil || !strings.Contains(err.Error(), "vscode, vnc, chrome, xterm") {
+		t.Errorf("unknown service error = %v", err)
+	}
+}
@@ -6,10 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/karlorz/devsh/internal/auth"
-	"github.com/karlorz/devsh/internal/e2b"
 	"github.com/karlorz/devsh/internal/provider"
-	"github.com/karlorz/devsh/internal/vm"
 	"github.com/spf13/cobra"
 )
 
@@ -24,13 +21,17 @@ Examples:
 	RunE: func(cmd *cobra.Command, args []string) error {
 		instanceID := args[0]
 
-		selected, err := resolveProviderForInstance(instanceID)
+		p, err := sandboxProviderForInstance(instanceID)
 		if err != nil {
 			return err
 		}
+		lifecycle, ok := p.(provider.Lifecycle)
+		if !ok {
+			return fmt.Errorf("provider %s does not support pause", p.Name())
+		}
 
 		timeout := 30 * time.Second
-		if selected == provider.PveLxc {
+		if p.Name() == provider.PveLxc {
 			timeout = 2 * time.Minute
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -38,44 +39,10 @@ Examples:
 
 		fmt.Printf("Pausing VM %s...\n", instanceID)
 
-		switch selected {
-		case provider.PveLxc:
-			if err := pausePveLxcInstance(ctx, instanceID); err != nil {
-				return err
-			}
-		case provider.Morph:
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := vm.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.PauseInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to pause VM: %w", err)
-			}
-		case provider.E2B:
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := e2b.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create E2B client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.PauseInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to pause sandbox: %w", err)
-			}
-		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+		if err := lifecycle.Pause(ctx, instanceID); err != nil {
+			return fmt.Errorf("failed to pause VM: %w", err)
 		}
+		recordUsageStop(instanceID)
 
 		fmt.Println("✓ VM paused")
 		fmt.Printf("  Resume with: devsh resume %s\n", instanceID)
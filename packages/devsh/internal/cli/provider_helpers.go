@@ -10,6 +10,7 @@ import (
 
 	"github.com/karlorz/devsh/internal/auth"
 	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/sandbox"
 )
 
 var sandboxConfigHTTPClient = &http.Client{Timeout: 5 * time.Second}
@@ -51,6 +52,32 @@ func resolveProviderForInstance(instanceID string) (string, error) {
 	return provider.ProviderForInstanceID(instanceID), nil
 }
 
+// sandboxProviderForInstance returns the backend that owns instanceID.
+func sandboxProviderForInstance(instanceID string) (provider.SandboxProvider, error) {
+	selected, err := resolveProviderForInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return newSandboxProvider(selected)
+}
+
+// newSandboxProvider constructs a registered backend, scoped to the current
+// team when the backend goes through the cmux API.
+func newSandboxProvider(name string) (provider.SandboxProvider, error) {
+	p, err := sandbox.NewProvider(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", name, err)
+	}
+	if scoped, ok := p.(provider.TeamScoped); ok {
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get team: %w", err)
+		}
+		scoped.SetTeamSlug(teamSlug)
+	}
+	return p, nil
+}
+
 func fetchServerSandboxProvider(ctx context.Context, cmuxURL string) (string, error) {
 	baseURL := strings.TrimRight(strings.TrimSpace(cmuxURL), "/")
 	if baseURL == "" {
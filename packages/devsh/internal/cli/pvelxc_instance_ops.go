@@ -3,7 +3,6 @@ package cli
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/karlorz/devsh/internal/auth"
 	"github.com/karlorz/devsh/internal/provider"
@@ -96,89 +95,3 @@ func execPveLxcInstance(
 
 	return stdout, stderr, exitCode, nil
 }
-
-func pausePveLxcInstance(ctx context.Context, instanceID string) error {
-	if provider.HasPveEnv() {
-		client, err := pvelxc.NewClientFromEnv()
-		if err != nil {
-			return fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
-		}
-
-		if err := client.PauseInstance(ctx, instanceID); err != nil {
-			return fmt.Errorf("failed to pause VM: %w", err)
-		}
-		return nil
-	}
-
-	client, err := newTeamVMClient()
-	if err != nil {
-		return err
-	}
-
-	if err := client.PausePveLxcInstance(ctx, instanceID); err != nil {
-		return fmt.Errorf("failed to pause VM: %w", err)
-	}
-
-	return nil
-}
-
-func resumePveLxcInstance(ctx context.Context, instanceID string) (*vm.Instance, error) {
-	if provider.HasPveEnv() {
-		client, err := pvelxc.NewClientFromEnv()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
-		}
-
-		if err := client.ResumeInstance(ctx, instanceID); err != nil {
-			return nil, fmt.Errorf("failed to resume VM: %w", err)
-		}
-
-		if err := client.WaitForExecReady(ctx, instanceID, 2*time.Minute); err != nil {
-			return nil, fmt.Errorf("VM failed to resume: %w", err)
-		}
-
-		instance, err := client.GetInstance(ctx, instanceID)
-		if err != nil {
-			return nil, fmt.Errorf("VM failed to resume: %w", err)
-		}
-
-		return vmInstanceFromPveInstance(instance), nil
-	}
-
-	client, err := newTeamVMClient()
-	if err != nil {
-		return nil, err
-	}
-
-	instance, err := client.ResumePveLxcInstance(ctx, instanceID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resume VM: %w", err)
-	}
-
-	return instance, nil
-}
-
-func stopPveLxcInstance(ctx context.Context, instanceID string) error {
-	if provider.HasPveEnv() {
-		client, err := pvelxc.NewClientFromEnv()
-		if err != nil {
-			return fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
-		}
-
-		if err := client.StopInstance(ctx, instanceID); err != nil {
-			return fmt.Errorf("failed to delete VM: %w", err)
-		}
-		return nil
-	}
-
-	client, err := newTeamVMClient()
-	if err != nil {
-		return err
-	}
-
-	if err := client.StopPveLxcInstance(ctx, instanceID); err != nil {
-		return fmt.Errorf("failed to delete VM: %w", err)
-	}
-
-	return nil
-}
@@ -7,10 +7,9 @@ import (
 	"time"
 
 	"github.com/karlorz/devsh/internal/auth"
-	"github.com/karlorz/devsh/internal/e2b"
 	"github.com/karlorz/devsh/internal/provider"
 	"github.com/karlorz/devsh/internal/state"
-	"github.com/karlorz/devsh/internal/vm"
+	"github.com/karlorz/devsh/internal/usage"
 	"github.com/spf13/cobra"
 )
 
@@ -26,134 +25,54 @@ Examples:
 		instanceID := args[0]
 
 		fmt.Printf("Resuming VM %s...\n", instanceID)
-		selected, err := resolveProviderForInstance(instanceID)
+		p, err := sandboxProviderForInstance(instanceID)
 		if err != nil {
 			return err
 		}
+		lifecycle, ok := p.(provider.Lifecycle)
+		if !ok {
+			return fmt.Errorf("provider %s does not support resume", p.Name())
+		}
 
 		timeout := 2 * time.Minute
-		if selected == provider.PveLxc {
+		if p.Name() == provider.PveLxc {
 			timeout = 5 * time.Minute
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		switch selected {
-		case provider.PveLxc:
-			instance, err := resumePveLxcInstance(ctx, instanceID)
-			if err != nil {
-				return err
-			}
-
-			_ = state.SetLastInstance(instance.ID, "")
-
-			fmt.Println("\n✓ VM resumed!")
-			fmt.Printf("  ID:       %s\n", instance.ID)
-			if instance.VSCodeURL != "" {
-				fmt.Printf("  VS Code:  %s\n", instance.VSCodeURL)
-			}
-			if instance.VNCURL != "" {
-				fmt.Printf("  VNC:      %s\n", instance.VNCURL)
-			}
-			if instance.XTermURL != "" {
-				fmt.Printf("  XTerm:    %s\n", instance.XTermURL)
-			}
-			return nil
-		case provider.Morph:
-			// Get team slug
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := vm.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.ResumeInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to resume VM: %w", err)
-			}
-
-			// Wait for ready
-			fmt.Println("Waiting for VM to be ready...")
-			instance, err := client.WaitForReady(ctx, instanceID, 2*time.Minute)
-			if err != nil {
-				return fmt.Errorf("VM failed to resume: %w", err)
-			}
-
-			// Save as last used
-			state.SetLastInstance(instanceID, teamSlug)
-
-			// Generate auth token for authenticated URLs
-			token, err := getAuthToken(ctx, client, instance.ID)
-			if err != nil {
-				// Fall back to raw URLs if token generation fails
-				fmt.Printf("Warning: could not generate auth token: %v\n", err)
-				fmt.Println("\n✓ VM resumed!")
-				fmt.Printf("  ID:       %s\n", instance.ID)
-				fmt.Printf("  VS Code:  %s\n", instance.VSCodeURL)
-				fmt.Printf("  VNC:      %s\n", instance.VNCURL)
-				return nil
-			}
-
-			// Build authenticated URLs
-			codeAuthURL, err := buildAuthURL(instance.WorkerURL, "/code/?folder=/home/cmux/workspace", token)
-			if err != nil {
-				return fmt.Errorf("failed to build VS Code URL: %w", err)
-			}
-			vncAuthURL, err := buildAuthURL(instance.WorkerURL, "/vnc/vnc.html?path=vnc/websockify&resize=scale&quality=9&compression=0", token)
-			if err != nil {
-				return fmt.Errorf("failed to build VNC URL: %w", err)
-			}
-
-			fmt.Println("\n✓ VM resumed!")
-			fmt.Printf("  ID:       %s\n", instance.ID)
-			fmt.Printf("  VS Code:  %s\n", codeAuthURL)
-			fmt.Printf("  VNC:      %s\n", vncAuthURL)
-			return nil
-		case provider.E2B:
-			teamSlug, err := auth.GetTeamSlug()
-			if err != nil {
-				return fmt.Errorf("failed to get team: %w", err)
-			}
-
-			client, err := e2b.NewClient()
-			if err != nil {
-				return fmt.Errorf("failed to create E2B client: %w", err)
-			}
-			client.SetTeamSlug(teamSlug)
-
-			if err := client.ResumeInstance(ctx, instanceID); err != nil {
-				return fmt.Errorf("failed to resume sandbox: %w", err)
-			}
+		instance, err := lifecycle.Resume(ctx, instanceID)
+		if err != nil {
+			return fmt.Errorf("failed to resume VM: %w", err)
+		}
 
-			// Wait for ready
-			fmt.Println("Waiting for sandbox to be ready...")
-			instance, err := client.WaitForReady(ctx, instanceID, 2*time.Minute)
-			if err != nil {
-				return fmt.Errorf("sandbox failed to resume: %w", err)
-			}
+		// Save as last used
+		teamSlug := ""
+		if _, ok := p.(provider.TeamScoped); ok {
+			teamSlug, _ = auth.GetTeamSlug()
+		}
+		_ = state.SetLastInstance(instance.ID, teamSlug)
+		recordUsageStart(usage.Record{InstanceID: instance.ID, Team: teamSlug, Provider: p.Name()})
 
-			// Save as last used
-			state.SetLastInstance(instanceID, teamSlug)
+		// Prefer authenticated URLs; fall back to the raw ones.
+		urls, err := provider.URLs(ctx, p, instance.ID)
+		if err != nil {
+			fmt.Printf("Warning: could not get authenticated URLs: %v\n", err)
+			urls = instance
+		}
 
-			fmt.Println("\n✓ Sandbox resumed!")
-			fmt.Printf("  ID:       %s\n", instance.ID)
-			if instance.VSCodeURL != "" {
-				fmt.Printf("  VS Code:  %s\n", instance.VSCodeURL)
-			}
-			if instance.VNCURL != "" {
-				fmt.Printf("  VNC:      %s\n", instance.VNCURL)
-			}
-			if instance.XTermURL != "" {
-				fmt.Printf("  XTerm:    %s\n", instance.XTermURL)
-			}
-			return nil
-		default:
-			return fmt.Errorf("unsupported provider: %s", selected)
+		fmt.Println("\n✓ VM resumed!")
+		fmt.Printf("  ID:       %s\n", instance.ID)
+		if urls.VSCodeURL != "" {
+			fmt.Printf("  VS Code:  %s\n", urls.VSCodeURL)
+		}
+		if urls.VNCURL != "" {
+			fmt.Printf("  VNC:      %s\n", urls.VNCURL)
+		}
+		if urls.XTermURL != "" {
+			fmt.Printf("  XTerm:    %s\n", urls.XTermURL)
 		}
+		return nil
 	},
 }
 
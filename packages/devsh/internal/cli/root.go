@@ -19,6 +19,9 @@ var (
 	// Config override flags
 	flagAPIURL        string
 	flagConvexSiteURL string
+
+	// Config file profile (overrides $DEVSH_PROFILE and the file's profile)
+	flagProfile string
 )
 
 var rootCmd = &cobra.Command{
@@ -42,8 +45,12 @@ Quick start:
 	SilenceErrors: true,
 	// Apply config overrides before any command runs
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Fill unset env vars from the active config file profile
+		applyConfigProfile(cmd)
 		// Set config overrides from CLI flags (empty strings are ignored)
 		auth.SetConfigOverrides("", "", flagAPIURL, flagConvexSiteURL)
+		// Wake an instance the idle watcher paused before using it
+		resumeIfAutoPaused(cmd, args)
 	},
 }
 
@@ -51,11 +58,12 @@ func init() {
 	// Global flags available to all commands
 	rootCmd.PersistentFlags().BoolVar(&flagJSON, "json", false, "Output as JSON")
 	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "Verbose output")
-	rootCmd.PersistentFlags().StringVarP(&flagProvider, "provider", "p", "", "Sandbox provider: morph, pve-lxc (auto-detected from local provider env or server config)")
+	rootCmd.PersistentFlags().StringVarP(&flagProvider, "provider", "p", "", "Sandbox provider: morph, pve-lxc, e2b, docker (default: $DEVSH_PROVIDER, else auto-detected from local provider env or server config)")
 
 	// Config override flags (override env vars and build-time values)
 	rootCmd.PersistentFlags().StringVar(&flagAPIURL, "api-url", "", "Override API URL (default: https://cmux-www.karldigi.dev)")
 	rootCmd.PersistentFlags().StringVar(&flagConvexSiteURL, "convex-url", "", "Override Convex site URL")
+	rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "Config file profile to use (default: $DEVSH_PROFILE, else the profile set in ~/.config/cmux/config.toml)")
 
 	// Version command
 	rootCmd.AddCommand(versionCmd)
@@ -0,0 +1,213 @@
+// internal/cli/run.go
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/usage"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+// runDeleteTimeout bounds deleting the VM once the run is over, including
+// after Ctrl+C when the run's own context is already cancelled.
+const runDeleteTimeout = time.Minute
+
+var (
+	runPath      string
+	runNoSync    bool
+	runSnapshot  string
+	runEnv       []string
+	runOutputs   []string
+	runOutputDir string
+	runTimeout   time.Duration
+	runKeep      bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <command>",
+	Short: "Run a command in a throwaway VM",
+	Long: `Create a VM, sync a directory into it, run a command, download its
+outputs and delete the VM, all in one step.
+
+The command runs in the synced directory with its output streamed as it
+arrives. Paths given with --output are downloaded into --output-dir even if
+the command fails, keeping their path relative to the synced directory.
+The VM is deleted when the run ends, including on failure and Ctrl+C,
+unless --keep is set. Morph instances only.
+
+Examples:
+  devsh run -- make test
+  devsh run --path ./api -- "npm ci && npm test"
+  devsh run -o coverage -o junit.xml --output-dir ./artifacts -- ./ci.sh
+  devsh run --env CI=1 --timeout 30m -- go test ./...
+  devsh run --keep -- ./reproduce-bug.sh   # Keep the VM for debugging`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		command := strings.Join(args, " ")
+		env, err := parseExecEnv(runEnv)
+		if err != nil {
+			return err
+		}
+		outputs, err := parseRunOutputs(runOutputs)
+		if err != nil {
+			return err
+		}
+		if runTimeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+
+		mode, err := resolveStartMode(flagProvider)
+		if err != nil {
+			return err
+		}
+		if !mode.serverManaged && mode.provider != provider.Morph {
+			return fmt.Errorf("run is only supported for provider morph (got %s)", mode.provider)
+		}
+
+		var name, syncPath string
+		if !runNoSync {
+			name, syncPath, err = resolveOptionalStartPath([]string{runPath})
+			if err != nil {
+				return err
+			}
+		}
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w\nRun 'devsh auth login' to authenticate", err)
+		}
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+
+		fmt.Println("Creating VM...")
+		instance, err := client.CreateInstance(ctx, vm.CreateOptions{SnapshotID: runSnapshot, Name: name})
+		if err != nil {
+			return runError(ctx, fmt.Errorf("failed to create VM: %w", err))
+		}
+		fmt.Printf("VM created: %s\n", instance.ID)
+		recordUsageStart(usage.Record{InstanceID: instance.ID, Workspace: name, Team: teamSlug, Provider: provider.Morph})
+		defer finishRunInstance(client, instance.ID)
+
+		fmt.Println("Waiting for VM to be ready...")
+		if _, err := client.WaitForReadyWithOptions(ctx, instance.ID, vm.WaitOptions{
+			Timeout:    2 * time.Minute,
+			OnProgress: printWaitProgress(),
+		}); err != nil {
+			return runError(ctx, fmt.Errorf("VM failed to start: %w", err))
+		}
+
+		if syncPath != "" {
+			fmt.Printf("Syncing %s to VM...\n", syncPath)
+			if err := client.SyncToVM(ctx, instance.ID, syncPath); err != nil {
+				return runError(ctx, fmt.Errorf("failed to sync files: %w", err))
+			}
+		}
+
+		conn, err := client.SSHDial(ctx, instance.ID)
+		if err != nil {
+			return runError(ctx, err)
+		}
+		defer conn.Close()
+
+		fmt.Printf("Running: %s\n", command)
+		exitCode, err := conn.Run(ctx, vm.InSyncDir(wrapExecCommand(command, vm.ExecOptions{Env: env})), os.Stdout, os.Stderr)
+		if err != nil {
+			return runError(ctx, fmt.Errorf("failed to run command: %w", err))
+		}
+
+		if len(outputs) > 0 {
+			fmt.Printf("Downloading %s to %s...\n", strings.Join(outputs, ", "), runOutputDir)
+			if err := client.DownloadFromVM(ctx, instance.ID, outputs, runOutputDir); err != nil {
+				err = runError(ctx, fmt.Errorf("failed to download outputs: %w", err))
+				if exitCode != 0 {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				} else {
+					return err
+				}
+			}
+		}
+
+		if exitCode != 0 {
+			return fmt.Errorf("command exited with code %d", exitCode)
+		}
+		return nil
+	},
+}
+
+// parseRunOutputs validates --output paths. Relative paths must stay
+// inside the synced directory so they have a place under --output-dir.
+func parseRunOutputs(outputs []string) ([]string, error) {
+	parsed := make([]string, 0, len(outputs))
+	for _, p := range outputs {
+		if strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("--output must not be empty")
+		}
+		cleaned := path.Clean(p)
+		if !path.IsAbs(cleaned) && (cleaned == ".." || strings.HasPrefix(cleaned, "../")) {
+			return nil, fmt.Errorf("--output %q is outside the synced directory; use an absolute path", p)
+		}
+		parsed = append(parsed, cleaned)
+	}
+	return parsed, nil
+}
+
+// runError explains errors caused by Ctrl+C or --timeout rather than
+// reporting whichever step they happened to interrupt.
+func runError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("run timed out after %s: %w", runTimeout, err)
+	case ctx.Err() != nil:
+		return fmt.Errorf("interrupted")
+	}
+	return err
+}
+
+// finishRunInstance deletes the run's VM, or with --keep says how to reach
+// it. It uses its own context so it still runs after Ctrl+C.
+func finishRunInstance(client *vm.Client, instanceID string) {
+	if runKeep {
+		fmt.Printf("Keeping VM %s (devsh ssh %s, devsh delete %s)\n", instanceID, instanceID, instanceID)
+		return
+	}
+	fmt.Printf("Deleting VM %s...\n", instanceID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), runDeleteTimeout)
+	defer cancel()
+	if err := client.StopInstance(ctx, instanceID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete VM %s: %v\nDelete it with: devsh delete %s\n", instanceID, err, instanceID)
+		return
+	}
+	recordUsageStop(instanceID)
+}
+
+func init() {
+	runCmd.Flags().StringVar(&runPath, "path", ".", "Directory to sync into the VM")
+	runCmd.Flags().BoolVar(&runNoSync, "no-sync", false, "Don't sync a directory into the VM")
+	runCmd.Flags().StringVar(&runSnapshot, "snapshot", "", "Snapshot ID to create the VM from")
+	runCmd.Flags().StringArrayVar(&runEnv, "env", nil, "Set an environment variable (KEY=VALUE, repeatable)")
+	runCmd.Flags().StringArrayVarP(&runOutputs, "output", "o", nil, "Path to download after the command (repeatable)")
+	runCmd.Flags().StringVar(&runOutputDir, "output-dir", ".", "Local directory to download outputs into")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", time.Hour, "Time limit for the whole run")
+	runCmd.Flags().BoolVar(&runKeep, "keep", false, "Keep the VM instead of deleting it")
+	rootCmd.AddCommand(runCmd)
+}
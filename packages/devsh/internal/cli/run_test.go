@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRunOutputs(t *testing.T) {
+	got, err := parseRunOutputs([]string{"coverage/", "./junit.xml", "/tmp/report.html", "dist/../build"})
+	if err != nil {
+		t.Fatalf("parseRunOutputs: %v", err)
+	}
+	if want := []string{"coverage", "junit.xml", "/tmp/report.html", "build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRunOutputs = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"", " ", "..", "../secrets", "dist/../../x"} {
+		if _, err := parseRunOutputs([]string{bad}); err == nil {
+			t.Errorf("parseRunOutputs(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// internal/cli/snapshots.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karlorz/devsh/internal/morph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotsSelector  []string
+	snapshotsKeepLast  int
+	snapshotsOlderThan string
+	snapshotsDigest    string
+	snapshotsDryRun    bool
+	snapshotsYes       bool
+)
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "Manage Morph snapshots",
+	Long: `List, tag, delete and prune Morph snapshots.
+
+Requires MORPH_API_KEY.
+
+Examples:
+  devsh snapshots list --selector template=base
+  devsh snapshots tag snapshot_abc123 channel=stable
+  devsh snapshots delete snapshot_abc123
+  devsh snapshots prune --keep-last 5 --older-than 30d --selector template=base`,
+}
+
+var snapshotsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, selector, err := snapshotsClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		snaps, err := client.ListSnapshots(ctx, selector)
+		if err != nil {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].Created > snaps[j].Created })
+		return printSnapshots(snaps)
+	},
+}
+
+var snapshotsTagCmd = &cobra.Command{
+	Use:   "tag <snapshot-id> <key=value>...",
+	Short: "Set metadata on a snapshot",
+	Long: `Set metadata keys on a snapshot. An empty value (key=) clears the key.
+
+Examples:
+  devsh snapshots tag snapshot_abc123 channel=stable owner=infra
+  devsh snapshots tag snapshot_abc123 channel=`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		metadata := make(map[string]string, len(args)-1)
+		for _, pair := range args[1:] {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid metadata %q: expected key=value", pair)
+			}
+			metadata[key] = value
+		}
+		client, err := morph.NewAPIClientFromEnv()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		snap, err := client.SetSnapshotMetadata(ctx, args[0], metadata)
+		if err != nil {
+			return fmt.Errorf("failed to tag snapshot: %w", err)
+		}
+		if flagJSON {
+			return printSnapshotsJSON(snap)
+		}
+		fmt.Printf("Tagged %s: %s\n", snap.ID, formatSnapshotMetadata(snap.Metadata))
+		return nil
+	},
+}
+
+var snapshotsDeleteCmd = &cobra.Command{
+	Use:   "delete <snapshot-id>...",
+	Short: "Delete snapshots",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := morph.NewAPIClientFromEnv()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var failed int
+		for _, id := range args {
+			if err := client.DeleteSnapshot(ctx, id); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to delete %s: %v\n", id, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Deleted %s\n", id)
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to delete %d of %d snapshots", failed, len(args))
+		}
+		return nil
+	},
+}
+
+var snapshotsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old snapshots",
+	Long: `Delete old snapshots, keeping the newest ones.
+
+The --keep-last newest snapshots are always kept. Of the rest, only those
+older than --older-than are deleted. At least one of the two is required.
+Snapshots listed in packages/shared/src/morph-snapshots.json back the
+published presets and are never deleted; run from the repo or set
+CMUX_MORPH_SNAPSHOT_MANIFEST so devsh can read it.
+
+Without --yes, prune only prints the plan. Use --selector or --digest to
+prune one family of snapshots at a time.
+
+Examples:
+  devsh snapshots prune --keep-last 5 --older-than 30d --selector template=base
+  devsh snapshots prune --keep-last 3 --selector template=base --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := morph.PruneOptions{KeepLast: snapshotsKeepLast, Digest: snapshotsDigest}
+		if snapshotsOlderThan != "" {
+			d, err := parseDays(snapshotsOlderThan)
+			if err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+			opts.OlderThan = d
+		}
+		manifest, err := morph.FindSnapshotManifest()
+		if err != nil {
+			return fmt.Errorf("refusing to prune without the list of published snapshots: %w", err)
+		}
+		if opts.Protected, err = morph.PublishedSnapshotIDs(manifest); err != nil {
+			return err
+		}
+		client, selector, err := snapshotsClient()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		snaps, err := client.ListSnapshots(ctx, selector)
+		if err != nil {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		prune, err := morph.PlanSnapshotPrune(snaps, opts, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if snapshotsDryRun || !snapshotsYes {
+			if flagJSON {
+				return printSnapshotsJSON(prune)
+			}
+			fmt.Printf("Would delete %d of %d snapshots:\n", len(prune), len(snaps))
+			if err := printSnapshots(prune); err != nil {
+				return err
+			}
+			if !snapshotsDryRun && len(prune) > 0 {
+				fmt.Println("Re-run with --yes to delete them.")
+			}
+			return nil
+		}
+		if len(prune) == 0 {
+			if !flagJSON {
+				fmt.Printf("No snapshots to prune (keeping %d)\n", len(snaps))
+			}
+			return nil
+		}
+
+		var deleted []string
+		var failed int
+		for _, snap := range prune {
+			if err := client.DeleteSnapshot(ctx, snap.ID); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Fprintf(os.Stderr, "Failed to delete %s: %v\n", snap.ID, err)
+				failed++
+				continue
+			}
+			deleted = append(deleted, snap.ID)
+			if !flagJSON {
+				fmt.Printf("Deleted %s (created %s)\n", snap.ID, snap.CreatedAt().Format(time.RFC3339))
+			}
+		}
+		if flagJSON {
+			if err := printSnapshotsJSON(map[string]interface{}{"deleted": deleted, "failed": failed}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Pruned %d snapshots, kept %d\n", len(deleted), len(snaps)-len(prune))
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to delete %d of %d snapshots", failed, len(prune))
+		}
+		return nil
+	},
+}
+
+// snapshotsClient creates the Morph client and parses --selector.
+func snapshotsClient() (*morph.APIClient, map[string]string, error) {
+	selector := make(map[string]string, len(snapshotsSelector))
+	for _, pair := range snapshotsSelector {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("invalid --selector %q: expected key=value", pair)
+		}
+		selector[key] = value
+	}
+	client, err := morph.NewAPIClientFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, selector, nil
+}
+
+func printSnapshotsJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func printSnapshots(snaps []morph.APISnapshot) error {
+	if flagJSON {
+		return printSnapshotsJSON(snaps)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tMETADATA")
+	for _, snap := range snaps {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", snap.ID, snap.CreatedAt().Format("2006-01-02 15:04"), formatSnapshotMetadata(snap.Metadata))
+	}
+	return w.Flush()
+}
+
+func formatSnapshotMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func init() {
+	snapshotsCmd.PersistentFlags().StringArrayVar(&snapshotsSelector, "selector", nil, "Only snapshots with this metadata (key=value, repeatable)")
+	snapshotsPruneCmd.Flags().IntVar(&snapshotsKeepLast, "keep-last", 0, "Always keep this many of the newest snapshots")
+	snapshotsPruneCmd.Flags().StringVar(&snapshotsOlderThan, "older-than", "", "Only delete snapshots older than this (e.g. 30d, 72h)")
+	snapshotsPruneCmd.Flags().StringVar(&snapshotsDigest, "digest", "", "Only prune snapshots with this digest")
+	snapshotsPruneCmd.Flags().BoolVar(&snapshotsDryRun, "dry-run", false, "Show what would be deleted without deleting, even with --yes")
+	snapshotsPruneCmd.Flags().BoolVar(&snapshotsYes, "yes", false, "Delete the planned snapshots (default: only show the plan)")
+	snapshotsCmd.AddCommand(snapshotsListCmd)
+	snapshotsCmd.AddCommand(snapshotsTagCmd)
+	snapshotsCmd.AddCommand(snapshotsDeleteCmd)
+	snapshotsCmd.AddCommand(snapshotsPruneCmd)
+	rootCmd.AddCommand(snapshotsCmd)
+}
@@ -0,0 +1,108 @@
+// internal/cli/ssh_config.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var sshConfigCmd = &cobra.Command{
+	Use:   "ssh-config",
+	Short: "Manage ~/.ssh/config entries for running VMs",
+	Long: `Manage Host aliases in ~/.ssh/config so running VMs can be reached
+with plain ssh, scp, rsync, or editor remote extensions.
+
+Entries live in a managed block at the top of ~/.ssh/config; the rest of
+the file is never touched. Keys issued for each VM are stored in ~/.ssh/cmux.`,
+}
+
+var sshConfigSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Write Host aliases for running VMs and remove stopped ones",
+	Long: `Write a "Host cmux-<id>" entry to ~/.ssh/config for every running VM
+and remove entries for VMs that are no longer running. Re-run after
+starting, pausing, or deleting VMs.
+
+Examples:
+  devsh ssh-config sync
+  ssh cmux-abc123                     # VM cmux_abc123
+  rsync -a ./src cmux-abc123:workspace/`,
+	Args: cobra.NoArgs,
+	RunE: runSSHConfigSync,
+}
+
+func runSSHConfigSync(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	teamSlug, err := auth.GetTeamSlug()
+	if err != nil {
+		return fmt.Errorf("failed to get team: %w", err)
+	}
+
+	client, err := vm.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	client.SetTeamSlug(teamSlug)
+
+	instances, err := client.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	hosts := make(map[string]*vm.SSHInfo)
+	for _, inst := range instances {
+		if inst.Status != "running" {
+			continue
+		}
+		info, err := client.GetSSHInfo(ctx, inst.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", inst.ID, err)
+			continue
+		}
+		hosts[inst.ID] = info
+	}
+
+	writer, err := vm.DefaultSSHConfigWriter()
+	if err != nil {
+		return err
+	}
+	result, err := writer.Sync(hosts)
+	if err != nil {
+		return err
+	}
+
+	if flagJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, alias := range result.Hosts {
+		fmt.Printf("  + %s\n", alias)
+	}
+	for _, alias := range result.Removed {
+		fmt.Printf("  - %s\n", alias)
+	}
+	fmt.Printf("Updated %s (%d hosts, %d removed)\n", writer.ConfigPath, len(result.Hosts), len(result.Removed))
+	if len(result.Hosts) > 0 {
+		fmt.Printf("Connect with: ssh %s\n", result.Hosts[0])
+	}
+	return nil
+}
+
+func init() {
+	sshConfigCmd.AddCommand(sshConfigSyncCmd)
+	rootCmd.AddCommand(sshConfigCmd)
+}
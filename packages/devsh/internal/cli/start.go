@@ -15,6 +15,7 @@ import (
 	"github.com/karlorz/devsh/internal/provider"
 	"github.com/karlorz/devsh/internal/pvelxc"
 	"github.com/karlorz/devsh/internal/state"
+	"github.com/karlorz/devsh/internal/usage"
 	"github.com/karlorz/devsh/internal/vm"
 	"github.com/spf13/cobra"
 )
@@ -37,6 +38,8 @@ Examples:
   devsh start --no-auth          # Skip ownership recording and provider auth
   devsh start --clean            # Record ownership; skip provider auth injection
   devsh start --mirror-local     # Pack/redact local agent config into the box (pve-lxc)
+  devsh start --locked-down      # Firewall to cmux ports + tailnet SSH (pve-lxc)
+  devsh start --full-clone --storage nvme-zfs  # Independent copy on another storage (pve-lxc)
   devsh start --template name    # Expand ~/.cmux/templates/<name>.yaml into flags`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -57,6 +60,26 @@ Examples:
 		if mode.serverManaged && (clean || mirrorLocal) {
 			return fmt.Errorf("--clean and --mirror-local require an explicit pve-lxc provider (server-managed start is unsupported for these flags)")
 		}
+		lockedDown, _ := cmd.Flags().GetBool("locked-down")
+		if lockedDown && (mode.serverManaged || mode.provider != provider.PveLxc) {
+			return fmt.Errorf("--locked-down requires provider pve-lxc with PVE_API_URL and PVE_API_TOKEN set")
+		}
+		fullClone, _ := cmd.Flags().GetBool("full-clone")
+		storage, _ := cmd.Flags().GetString("storage")
+		if storage != "" && !fullClone {
+			return fmt.Errorf("--storage requires --full-clone (linked clones stay on the template's storage)")
+		}
+		if fullClone && (mode.serverManaged || mode.provider != provider.PveLxc) {
+			return fmt.Errorf("--full-clone requires provider pve-lxc with PVE_API_URL and PVE_API_TOKEN set")
+		}
+		keepFailed, _ := cmd.Flags().GetBool("keep-failed")
+		if keepFailed && (mode.serverManaged || mode.provider != provider.PveLxc) {
+			return fmt.Errorf("--keep-failed requires provider pve-lxc with PVE_API_URL and PVE_API_TOKEN set")
+		}
+
+		if autoPause, _ := cmd.Flags().GetDuration("auto-pause"); autoPause > 0 && !mode.serverManaged && mode.provider != provider.Morph {
+			return fmt.Errorf("--auto-pause is only supported for provider morph (got %s)", mode.provider)
+		}
 
 		if mode.serverManaged {
 			return runStartServerManaged(cmd, args)
@@ -70,7 +93,7 @@ Examples:
 		case provider.E2B:
 			return runStartE2B(cmd, args)
 		default:
-			return fmt.Errorf("unsupported provider: %s", mode.provider)
+			return runStartWithProvider(cmd, args, mode.provider)
 		}
 	},
 }
@@ -173,7 +196,7 @@ func runStartServerManaged(cmd *cobra.Command, args []string) error {
 	client.SetTeamSlug(teamSlug)
 
 	snapshotID, _ := cmd.Flags().GetString("snapshot")
-	_, syncPath, err := resolveOptionalStartPath(args)
+	workspace, syncPath, err := resolveOptionalStartPath(args)
 	if err != nil {
 		return err
 	}
@@ -203,6 +226,12 @@ func runStartServerManaged(cmd *cobra.Command, args []string) error {
 	}
 
 	state.SetLastInstance(result.InstanceID, teamSlug)
+	recordUsageStart(usage.Record{InstanceID: result.InstanceID, Workspace: workspace, Team: teamSlug, Provider: result.Provider})
+	if result.Provider == provider.Morph {
+		armAutoPause(cmd, result.InstanceID)
+	} else if autoPause, _ := cmd.Flags().GetDuration("auto-pause"); autoPause > 0 {
+		fmt.Printf("Warning: --auto-pause is not supported for %s sandboxes\n", result.Provider)
+	}
 
 	fmt.Println("\nSandbox is ready!")
 	fmt.Printf("  ID:       %s\n", result.InstanceID)
@@ -266,11 +295,25 @@ func runStartMorph(cmd *cobra.Command, args []string) error {
 
 	// Wait for VM to be ready
 	fmt.Println("Waiting for VM to be ready...")
-	instance, err = client.WaitForReady(ctx, instance.ID, 2*time.Minute)
+	instance, err = client.WaitForReadyWithOptions(ctx, instance.ID, vm.WaitOptions{
+		Timeout:    2 * time.Minute,
+		OnProgress: printWaitProgress(),
+	})
 	if err != nil {
 		return fmt.Errorf("VM failed to start: %w", err)
 	}
 
+	// Verify services came up
+	health, err := client.CheckServices(ctx, instance.ID, vm.HealthCheckOptions{})
+	if err != nil {
+		fmt.Printf("Warning: could not verify services: %v\n", err)
+	} else {
+		instance.Health = health
+		if health.Degraded {
+			fmt.Printf("Warning: VM is degraded, services not healthy: %s\n", strings.Join(health.Unhealthy(), ", "))
+		}
+	}
+
 	// Sync directory if specified
 	if syncPath != "" {
 		fmt.Printf("Syncing %s to VM...\n", syncPath)
@@ -286,6 +329,8 @@ func runStartMorph(cmd *cobra.Command, args []string) error {
 
 	// Save as last used instance
 	state.SetLastInstance(instance.ID, teamSlug)
+	recordUsageStart(usage.Record{InstanceID: instance.ID, Workspace: name, Team: teamSlug, Provider: provider.Morph})
+	armAutoPause(cmd, instance.ID)
 
 	// Generate auth token for authenticated URLs
 	token, err := getAuthToken(ctx, client, instance.ID)
@@ -328,14 +373,20 @@ func runStartMorph(cmd *cobra.Command, args []string) error {
 }
 
 func runStartPveLxc(cmd *cobra.Command, args []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	// A full clone copies the template's whole rootfs before it can boot.
+	fullClone, _ := cmd.Flags().GetBool("full-clone")
+	timeout := 10 * time.Minute
+	if fullClone {
+		timeout = 40 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Get snapshot ID (canonical snapshot_*)
 	snapshotID, _ := cmd.Flags().GetString("snapshot")
 
 	// Optional: accept a path argument for consistency, but sync is not yet implemented for PVE LXC.
-	_, syncPath, err := resolveOptionalStartPath(args)
+	workspace, syncPath, err := resolveOptionalStartPath(args)
 	if err != nil {
 		return err
 	}
@@ -345,16 +396,28 @@ func runStartPveLxc(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
 	}
 
-	fmt.Println("Creating container...")
-	instance, err := client.StartInstance(ctx, pvelxc.StartOptions{
+	startOpts := pvelxc.StartOptions{
 		SnapshotID: snapshotID,
-	})
+	}
+	if lockedDown, _ := cmd.Flags().GetBool("locked-down"); lockedDown {
+		startOpts.Firewall = &pvelxc.FirewallOptions{}
+	}
+	startOpts.KeepFailed, _ = cmd.Flags().GetBool("keep-failed")
+	startOpts.FullClone = fullClone
+	startOpts.Storage, _ = cmd.Flags().GetString("storage")
+
+	fmt.Println("Creating container...")
+	instance, err := client.StartInstance(ctx, startOpts)
 	if err != nil {
 		return fmt.Errorf("failed to create container: %w", err)
 	}
 
 	fmt.Printf("Container created: %s\n", instance.ID)
 
+	if err := client.WaitForExecReady(ctx, instance.ID, 3*time.Minute); err != nil {
+		return fmt.Errorf("container %s failed to start: %w\nFetch more logs with: devsh logs %s", instance.ID, client.WithConsoleLogs(ctx, instance.ID, err), instance.ID)
+	}
+
 	timezone := resolveSandboxTimezone()
 	result, err := client.ApplyTimezone(ctx, instance.ID, timezone)
 	if err != nil {
@@ -419,6 +482,7 @@ func runStartPveLxc(cmd *cobra.Command, args []string) error {
 
 	// Save as last used instance (team slug not applicable for PVE LXC)
 	_ = state.SetLastInstance(instance.ID, "")
+	recordUsageStart(usage.Record{InstanceID: instance.ID, Workspace: workspace, Provider: provider.PveLxc})
 
 	fmt.Println("\nVM is ready!")
 	fmt.Printf("  ID:       %s\n", instance.ID)
@@ -505,6 +569,7 @@ func runStartE2B(cmd *cobra.Command, args []string) error {
 
 	// Save as last used instance
 	state.SetLastInstance(instance.ID, teamSlug)
+	recordUsageStart(usage.Record{InstanceID: instance.ID, Workspace: name, Team: teamSlug, Provider: provider.E2B})
 
 	fmt.Println("\nSandbox is ready!")
 	fmt.Printf("  ID:       %s\n", instance.ID)
@@ -529,6 +594,66 @@ func runStartE2B(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runStartWithProvider starts a sandbox on a registered provider without a
+// dedicated start path, such as the local docker provider.
+func runStartWithProvider(cmd *cobra.Command, args []string, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	p, err := newSandboxProvider(name)
+	if err != nil {
+		return err
+	}
+
+	workspace, syncPath, err := resolveOptionalStartPath(args)
+	if err != nil {
+		return err
+	}
+	template, _ := cmd.Flags().GetString("snapshot")
+
+	fmt.Printf("Creating %s sandbox...\n", p.Name())
+	sb, err := p.Create(ctx, provider.CreateOptions{Template: template})
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox: %w", err)
+	}
+	fmt.Printf("Sandbox created: %s\n", sb.ID)
+
+	fmt.Println("Waiting for sandbox to be ready...")
+	sb, err = p.WaitReady(ctx, sb.ID, 3*time.Minute)
+	if err != nil {
+		return fmt.Errorf("sandbox failed to start: %w", err)
+	}
+
+	if syncPath != "" {
+		fmt.Printf("Warning: sync is not yet supported for %s (skipping sync of %s)\n", p.Name(), syncPath)
+	}
+
+	_ = state.SetLastInstance(sb.ID, "")
+	recordUsageStart(usage.Record{InstanceID: sb.ID, Workspace: workspace, Provider: p.Name()})
+
+	fmt.Println("\nSandbox is ready!")
+	fmt.Printf("  ID:       %s\n", sb.ID)
+	if sb.VSCodeURL != "" {
+		fmt.Printf("  VS Code:  %s\n", sb.VSCodeURL)
+	}
+	if sb.VNCURL != "" {
+		fmt.Printf("  VNC:      %s\n", sb.VNCURL)
+	}
+	if sb.XTermURL != "" {
+		fmt.Printf("  XTerm:    %s\n", sb.XTermURL)
+	}
+
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	if interactive && sb.VSCodeURL != "" {
+		fmt.Println("\nOpening VS Code in browser...")
+		if err := openBrowser(sb.VSCodeURL); err != nil {
+			fmt.Printf("Warning: could not open browser: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
 // mirrorLocalAgentConfig packs a redacted ~/.claude + ~/.codex subset and dual-path
 // pushes it into the container, then extracts under /root. Soft-fail at caller.
 func mirrorLocalAgentConfig(ctx context.Context, client *pvelxc.Client, instance *pvelxc.Instance) error {
@@ -582,6 +707,49 @@ func init() {
 	startCmd.Flags().Bool("no-auth", false, "Skip ownership recording and automatic provider auth setup")
 	startCmd.Flags().Bool("clean", false, "Skip provider auth setup but still record sandbox ownership (pve-lxc)")
 	startCmd.Flags().Bool("mirror-local", false, "Pack/redact local ~/.claude and ~/.codex into the box (pve-lxc; soft-fail)")
+	startCmd.Flags().Bool("locked-down", false, "Allow only cmux service ports and SSH from the tailnet via the PVE firewall (pve-lxc)")
+	startCmd.Flags().Bool("keep-failed", false, "Keep a container that fails to come up, stopped and renamed cmux-failed-<name>, instead of deleting it (pve-lxc)")
+	startCmd.Flags().Bool("full-clone", false, "Copy the template's rootfs instead of linking to it, so the container outlives the template (pve-lxc)")
+	startCmd.Flags().String("storage", "", "PVE storage for a full clone's rootfs, e.g. nvme-zfs; its type decides the rootfs format (pve-lxc, needs --full-clone)")
+	startCmd.Flags().Duration("auto-pause", 0, "Pause the VM after it has been idle this long, e.g. 30m (morph)")
 	startCmd.Flags().String("template", "", "Load ~/.cmux/templates/<name>.yaml (or path) and expand to start flags")
 	rootCmd.AddCommand(startCmd)
 }
+
+// armAutoPause starts the idle watcher when --auto-pause is set and
+// remembers the window so an auto-resume can start it again.
+func armAutoPause(cmd *cobra.Command, instanceID string) {
+	autoPause, _ := cmd.Flags().GetDuration("auto-pause")
+	if autoPause <= 0 {
+		return
+	}
+	updateUsage(func(s *usage.Store, now time.Time) {
+		s.Started(usage.Record{InstanceID: instanceID, AutoPauseAfter: autoPause.String()}, now)
+	})
+	if err := startIdleWatcher(instanceID, autoPause); err != nil {
+		fmt.Printf("Warning: could not start idle watcher: %v\n", err)
+		return
+	}
+	fmt.Printf("Auto-pause: VM pauses after %s idle\n", autoPause)
+}
+
+// printWaitProgress returns a WaitForReady progress callback that prints
+// status changes and transient errors, without repeating a line per poll.
+func printWaitProgress() func(vm.WaitProgress) {
+	var lastStatus, lastErr string
+	return func(p vm.WaitProgress) {
+		elapsed := p.Elapsed.Round(time.Second)
+		if p.Status != "" && p.Status != lastStatus {
+			lastStatus = p.Status
+			fmt.Printf("  status: %s (%s)\n", p.Status, elapsed)
+		}
+		errText := ""
+		if p.LastErr != nil {
+			errText = p.LastErr.Error()
+		}
+		if errText != "" && errText != lastErr {
+			fmt.Printf("  retrying after error: %s (%s)\n", errText, elapsed)
+		}
+		lastErr = errText
+	}
+}
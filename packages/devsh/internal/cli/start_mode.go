@@ -21,6 +21,9 @@ func resolveStartMode(flagValue string) (startMode, error) {
 	if normalized != "" {
 		return startMode{provider: normalized}, nil
 	}
+	if configured := provider.ConfiguredProvider(); configured != "" {
+		return startMode{provider: configured}, nil
+	}
 	if provider.HasPveEnv() {
 		return startMode{provider: provider.PveLxc}, nil
 	}
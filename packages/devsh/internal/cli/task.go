@@ -20,6 +20,8 @@ Examples:
   devsh task status <task-id>          # Get task details
   devsh task show <task-id>            # Show task details (enhanced)
   devsh task runs <task-id>            # List runs with exit codes
+  devsh task logs <task-id> -f         # Stream agent output
+  devsh task watch <task-id> --notify  # Watch status changes
   devsh task pin <task-id>             # Pin/unpin a task
   devsh task archive <task-id>         # Archive a task
   devsh task stop <task-id>            # Stop/archive a task`,
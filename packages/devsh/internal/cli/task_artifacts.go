@@ -0,0 +1,170 @@
+// internal/cli/task_artifacts.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	taskArtifactsOut  string
+	taskArtifactsList bool
+)
+
+var taskArtifactsCmd = &cobra.Command{
+	Use:   "artifacts <task-id-or-run-id> [name...]",
+	Short: "Download the artifacts of a task run",
+	Long: `Download the files a task run produced (diffs, patches, screenshots).
+
+You can provide either:
+  - A task ID - uses the latest task run
+  - A task run ID - uses that specific run
+
+Name arguments limit the download to those artifacts. Interrupted downloads
+leave a <name>.part file and resume from where they stopped on the next
+run; files already downloaded in full are skipped.
+
+Examples:
+  devsh task artifacts ns7xyz123abc... --list
+  devsh task artifacts ns7xyz123abc... --out ./artifacts
+  devsh task artifacts <id> changes.diff`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		taskRunID, err := resolveTaskRunID(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+
+		artifacts, err := client.ListTaskRunArtifacts(ctx, taskRunID)
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		artifacts, err = selectArtifacts(artifacts, args[1:])
+		if err != nil {
+			return err
+		}
+
+		if taskArtifactsList {
+			return printArtifacts(artifacts)
+		}
+		if len(artifacts) == 0 {
+			fmt.Println("No artifacts for this run.")
+			return nil
+		}
+
+		var downloads []*vm.ArtifactDownload
+		for _, a := range artifacts {
+			dest := filepath.Join(taskArtifactsOut, a.FileName())
+			dl, err := client.DownloadArtifact(ctx, taskRunID, a, dest)
+			if err != nil {
+				return fmt.Errorf("failed to download %s: %w", a.Name, err)
+			}
+			downloads = append(downloads, dl)
+			if flagJSON {
+				continue
+			}
+			switch {
+			case dl.Skipped:
+				fmt.Printf("= %s (already downloaded)\n", dl.Path)
+			case dl.ResumedFrom > 0:
+				fmt.Printf("✓ %s (%s, resumed at %s)\n", dl.Path, formatBytes(dl.Size), formatBytes(dl.ResumedFrom))
+			default:
+				fmt.Printf("✓ %s (%s)\n", dl.Path, formatBytes(dl.Size))
+			}
+		}
+
+		if flagJSON {
+			data, _ := json.MarshalIndent(downloads, "", "  ")
+			fmt.Println(string(data))
+		}
+		return nil
+	},
+}
+
+// selectArtifacts keeps the artifacts named in names (by name or ID), or all
+// of them if names is empty.
+func selectArtifacts(artifacts []vm.TaskRunArtifact, names []string) ([]vm.TaskRunArtifact, error) {
+	if len(names) == 0 {
+		return artifacts, nil
+	}
+	var selected []vm.TaskRunArtifact
+	for _, name := range names {
+		found := false
+		for _, a := range artifacts {
+			if a.Name == name || a.ID == name || a.FileName() == name {
+				selected = append(selected, a)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no artifact named %q in this run", name)
+		}
+	}
+	return selected, nil
+}
+
+func printArtifacts(artifacts []vm.TaskRunArtifact) error {
+	if flagJSON {
+		data, _ := json.MarshalIndent(artifacts, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(artifacts) == 0 {
+		fmt.Println("No artifacts for this run.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tSIZE\tCREATED")
+	for _, a := range artifacts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name, a.Kind, formatBytes(a.Size), a.Time().Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+// formatBytes renders n as a human-readable size, "-" when unknown.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n <= 0 {
+		return "-"
+	}
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	taskArtifactsCmd.Flags().StringVarP(&taskArtifactsOut, "out", "o", ".", "Directory to save artifacts in")
+	taskArtifactsCmd.Flags().BoolVar(&taskArtifactsList, "list", false, "List artifacts without downloading them")
+	taskCmd.AddCommand(taskArtifactsCmd)
+}
@@ -0,0 +1,53 @@
+// internal/cli/task_cancel_run.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var taskCancelRunCmd = &cobra.Command{
+	Use:   "cancel-run <run-id>",
+	Short: "Cancel a single task run",
+	Long: `Stop one task run's agent and mark the run cancelled. The task and its
+other runs are left alone; use 'devsh task stop' to archive the whole task.
+
+Find run IDs with 'devsh task runs <task-id>'.
+
+Examples:
+  devsh task cancel-run ns7xyz123abc...`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runID := args[0]
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		if err := client.CancelTaskRun(ctx, runID); err != nil {
+			return fmt.Errorf("failed to cancel task run: %w", err)
+		}
+
+		fmt.Printf("Task run %s cancelled\n", runID)
+		return nil
+	},
+}
+
+func init() {
+	taskCmd.AddCommand(taskCancelRunCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -51,6 +52,10 @@ var (
 	taskCreateRalphMode          bool
 	taskCreateRalphCompletionTag string
 	taskCreateRalphMaxIterations int
+	// Local diff as task context
+	taskCreateFromDiff  bool
+	taskCreateDiffRange string
+	taskCreatePushBase  bool
 )
 
 var taskCreateCmd = &cobra.Command{
@@ -68,6 +73,8 @@ Use --cloud-workspace to create a cloud workspace (prompt is optional, defaults
 Use --from-project-item to create a task from a GitHub Project item (auto-fetches title+body as prompt).
 Use --parent-task-run to create a child task linked to a parent task run (for agent teams).
 Use --autopilot to run the agent in long-running autopilot mode with heartbeat-based timeout.
+Use --from-diff to send your uncommitted changes (or --diff-range) so the agent starts from your local state.
+The base commit must already be on an origin branch; --push-base pushes it if not.
 
 Examples:
   devsh task create "Add unit tests for auth module"
@@ -81,6 +88,8 @@ Examples:
   devsh task create --repo owner/repo --env env_abc123 --agent claude-code "With custom environment"
   devsh task create --repo owner/repo --cloud-workspace --agent claude-code "Create as cloud workspace"
   devsh task create --repo owner/repo --cloud-workspace  # No prompt (interactive TUI session)
+  devsh task create --from-diff --agent claude-code "Finish this refactor"
+  devsh task create --from-diff --diff-range origin/main..HEAD --agent claude-code "Review my branch"
   devsh task create --repo owner/repo --agent claude-code --gh-project-id PVT_xxx --gh-project-item-id PVTI_xxx --gh-project-installation-id 12345 --gh-project-owner my-org --gh-project-owner-type organization "From project item"
   devsh task create --from-project-item PVTI_xxx --gh-project-id PVT_xxx --gh-project-installation-id 12345 --gh-project-owner my-org --gh-project-owner-type organization --repo owner/repo --agent claude-code`,
 	Args: cobra.MaximumNArgs(1),
@@ -98,7 +107,7 @@ Examples:
 		if taskCreateCloudWorkspace && !taskCreateNoSandbox {
 			timeout = 5 * time.Minute // Cloud workspace creation includes sandbox provisioning
 		}
-		if len(taskCreateImages) > 0 && timeout < 2*time.Minute {
+		if (len(taskCreateImages) > 0 || taskCreateFromDiff || taskCreateDiffRange != "") && timeout < 2*time.Minute {
 			timeout = 2 * time.Minute // Uploading images can take a bit
 		}
 
@@ -225,6 +234,47 @@ Examples:
 			}
 		}
 
+		// --from-diff: attach the local patch and record its base commit
+		if taskCreateFromDiff || taskCreateDiffRange != "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			diff, err := captureLocalDiff(cwd, taskCreateDiffRange)
+			if err != nil {
+				return fmt.Errorf("failed to capture local diff: %w", err)
+			}
+			if taskCreateRepo == "" {
+				taskCreateRepo = diff.Repository
+			}
+			if err := diff.ensureBaseOnRemote(taskCreatePushBase); err != nil {
+				return err
+			}
+			if diff.PushedBranch != "" && !flagJSON {
+				fmt.Printf("Pushed base commit to origin/%s\n", diff.PushedBranch)
+			}
+
+			patchPath, cleanup, err := diff.writeTemp()
+			if err != nil {
+				return fmt.Errorf("failed to write diff: %w", err)
+			}
+			storageID, err := client.UploadFileToStorage(ctx, patchPath)
+			cleanup()
+			if err != nil {
+				return fmt.Errorf("failed to upload diff: %w", err)
+			}
+			uploadedImages = append(uploadedImages, vm.TaskImage{
+				StorageID: storageID,
+				FileName:  localDiffFileName,
+				AltText:   "local changes",
+			})
+			prompt = strings.TrimSpace(prompt + diff.promptSection())
+
+			if !flagJSON {
+				fmt.Printf("Attached local diff: %d files on top of %s\n", diff.Files, diff.BaseCommit)
+			}
+		}
+
 		// Resolve environment ID
 		environmentID := taskCreateEnv
 		if environmentID == "" && taskCreateRepo != "" {
@@ -632,5 +682,8 @@ func init() {
 	taskCreateCmd.Flags().BoolVar(&taskCreateRalphMode, "ralph-mode", false, "Run agent in Ralph Loop mode (iterate until <promise>DONE</promise> completion signal)")
 	taskCreateCmd.Flags().StringVar(&taskCreateRalphCompletionTag, "ralph-completion-tag", "DONE", "Completion tag for Ralph mode (default: DONE)")
 	taskCreateCmd.Flags().IntVar(&taskCreateRalphMaxIterations, "ralph-max-iterations", 50, "Max iterations for Ralph mode (default: 50)")
+	taskCreateCmd.Flags().BoolVar(&taskCreateFromDiff, "from-diff", false, "Attach uncommitted local changes as a patch and start from its base commit")
+	taskCreateCmd.Flags().StringVar(&taskCreateDiffRange, "diff-range", "", "Send a commit range instead of uncommitted changes (e.g. origin/main..HEAD); implies --from-diff")
+	taskCreateCmd.Flags().BoolVar(&taskCreatePushBase, "push-base", false, "With --from-diff, push the base commit to a devsh/base-<sha> branch on origin if no origin branch contains it")
 	taskCmd.AddCommand(taskCreateCmd)
 }
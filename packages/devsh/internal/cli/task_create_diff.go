@@ -0,0 +1,196 @@
+// internal/cli/task_create_diff.go
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxLocalDiffBytes caps the patch uploaded with --from-diff.
+const maxLocalDiffBytes = 5 << 20
+
+// localDiffFileName is the attachment name; the server writes attachments
+// to /root/prompt/<name> in the sandbox.
+const localDiffFileName = "local-changes.diff"
+
+// pushedBaseBranchPrefix names the branches --push-base creates on origin.
+const pushedBaseBranchPrefix = "devsh/base-"
+
+// localDiff is the local repository state captured by --from-diff.
+type localDiff struct {
+	Root         string // Repository top level
+	BaseCommit   string // Commit the patch applies to
+	Branch       string
+	Repository   string // owner/repo from origin, if it is a GitHub remote
+	PushedBranch string // Branch --push-base created for BaseCommit, if any
+	Patch        []byte
+	Files        int
+}
+
+var reGitHubRemote = regexp.MustCompile(`github\.com[:/]([^/]+/[^/]+?)(?:\.git)?/?$`)
+
+// repoFromRemote returns owner/repo for a GitHub remote URL.
+func repoFromRemote(remote string) string {
+	m := reGitHubRemote.FindStringSubmatch(strings.TrimSpace(remote))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// captureLocalDiff captures the diff of the repository containing dir.
+// With an empty rangeSpec it takes every uncommitted change (staged,
+// unstaged and untracked) against HEAD. Otherwise rangeSpec is passed to
+// git diff: "A..B" diffs two commits, a single revision diffs it against
+// the working tree. The base commit is the commit the patch applies to.
+func captureLocalDiff(dir, rangeSpec string) (*localDiff, error) {
+	top, err := runGitCommand(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", dir)
+	}
+	root := strings.TrimSpace(top)
+
+	d := &localDiff{Root: root}
+	if out, err := runGitCommand(root, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		d.Branch = strings.TrimSpace(out)
+	}
+	if out, err := runGitCommand(root, "remote", "get-url", "origin"); err == nil {
+		d.Repository = repoFromRemote(out)
+	}
+
+	base := "HEAD"
+	diffArgs := []string{"diff", "--binary"}
+	switch {
+	case rangeSpec == "":
+		diffArgs = append(diffArgs, "HEAD")
+	case strings.Contains(rangeSpec, "..."):
+		// Symmetric range: the patch applies to the merge base.
+		parts := strings.SplitN(rangeSpec, "...", 2)
+		out, err := runGitCommand(root, "merge-base", defaultRev(parts[0]), defaultRev(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find merge base for %s: %w", rangeSpec, err)
+		}
+		base = strings.TrimSpace(out)
+		diffArgs = append(diffArgs, rangeSpec)
+	case strings.Contains(rangeSpec, ".."):
+		base = defaultRev(strings.SplitN(rangeSpec, "..", 2)[0])
+		diffArgs = append(diffArgs, rangeSpec)
+	default:
+		base = rangeSpec
+		diffArgs = append(diffArgs, rangeSpec)
+	}
+
+	out, err := runGitCommand(root, "rev-parse", "--verify", base+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision: %s", base)
+	}
+	d.BaseCommit = strings.TrimSpace(out)
+
+	patch, err := runGitCommand(root, diffArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	var b strings.Builder
+	b.WriteString(patch)
+
+	// Untracked files are part of the local state but not of git diff.
+	if rangeSpec == "" {
+		untracked, err := runGitCommand(root, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list untracked files: %w", err)
+		}
+		for _, file := range strings.Split(untracked, "\x00") {
+			if file == "" {
+				continue
+			}
+			filePatch, err := diffUntrackedFile(root, file)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(filePatch)
+		}
+	}
+
+	d.Patch = []byte(b.String())
+	if len(d.Patch) == 0 {
+		return nil, fmt.Errorf("no local changes to send")
+	}
+	if len(d.Patch) > maxLocalDiffBytes {
+		return nil, fmt.Errorf("diff is too large (%d bytes, limit %d)", len(d.Patch), maxLocalDiffBytes)
+	}
+	d.Files = strings.Count("\n"+b.String(), "\ndiff --git ")
+	return d, nil
+}
+
+// ensureBaseOnRemote checks that the sandbox, which clones origin, can check
+// out the base commit. The check uses the remote-tracking branches, so run
+// git fetch first if the commit was pushed from elsewhere. With push, a
+// missing commit is pushed to a devsh/base-<sha> branch instead.
+func (d *localDiff) ensureBaseOnRemote(push bool) error {
+	out, err := runGitCommand(d.Root, "branch", "-r", "--list", "origin/*", "--contains", d.BaseCommit)
+	if err == nil && strings.TrimSpace(out) != "" {
+		return nil
+	}
+	short := d.BaseCommit[:min(12, len(d.BaseCommit))]
+	if !push {
+		return fmt.Errorf("base commit %s is not on any origin branch, so the sandbox cannot check it out; push it first or pass --push-base", short)
+	}
+	branch := pushedBaseBranchPrefix + short
+	if _, err := runGitCommand(d.Root, "push", "-q", "origin", d.BaseCommit+":refs/heads/"+branch); err != nil {
+		return fmt.Errorf("failed to push base commit %s to origin: %w", short, err)
+	}
+	d.PushedBranch = branch
+	return nil
+}
+
+// diffUntrackedFile renders an untracked file as a new-file patch.
+func diffUntrackedFile(root, file string) (string, error) {
+	out, err := runGitCommand(root, "diff", "--binary", "--no-index", "--", os.DevNull, file)
+	// --no-index exits 1 when the files differ, which is always the case here.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", fmt.Errorf("failed to diff untracked file %s: %w", file, err)
+	}
+	return out, nil
+}
+
+func defaultRev(rev string) string {
+	if rev == "" {
+		return "HEAD"
+	}
+	return rev
+}
+
+// writeTemp writes the patch to a temporary file for upload.
+func (d *localDiff) writeTemp() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "devsh-diff-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	path := filepath.Join(dir, localDiffFileName)
+	if err := os.WriteFile(path, d.Patch, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// promptSection tells the agent how to reproduce the local state.
+func (d *localDiff) promptSection() string {
+	var b strings.Builder
+	b.WriteString("\n\nLocal changes:\n")
+	fmt.Fprintf(&b, "The user's working tree is commit %s", d.BaseCommit)
+	if d.Branch != "" && d.Branch != "HEAD" {
+		fmt.Fprintf(&b, " (branch %s)", d.Branch)
+	}
+	fmt.Fprintf(&b, " plus the patch in %s (%d files). ", localDiffFileName, d.Files)
+	fmt.Fprintf(&b, "Before starting, run `git checkout %s` and `git apply --index %s`, ", d.BaseCommit, localDiffFileName)
+	b.WriteString("then work from that state.")
+	return b.String()
+}
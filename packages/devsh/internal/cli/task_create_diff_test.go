@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initDiffTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("remote", "add", "origin", "git@github.com:acme/widgets.git")
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "one\n")
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "init")
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCaptureLocalDiffIncludesUntracked(t *testing.T) {
+	dir := initDiffTestRepo(t)
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "one\ntwo\n")
+	writeTestFile(t, filepath.Join(dir, "new.txt"), "fresh\n")
+
+	d, err := captureLocalDiff(dir, "")
+	if err != nil {
+		t.Fatalf("captureLocalDiff: %v", err)
+	}
+	patch := string(d.Patch)
+	if !strings.Contains(patch, "+two") || !strings.Contains(patch, "+fresh") {
+		t.Errorf("patch missing changes:\n%s", patch)
+	}
+	if d.Files != 2 {
+		t.Errorf("Files = %d, want 2", d.Files)
+	}
+	if d.Repository != "acme/widgets" || d.Branch != "main" || len(d.BaseCommit) != 40 {
+		t.Errorf("unexpected metadata: %+v", d)
+	}
+	if !strings.Contains(d.promptSection(), "git checkout "+d.BaseCommit) {
+		t.Errorf("prompt section missing base commit: %s", d.promptSection())
+	}
+}
+
+func TestCaptureLocalDiffClean(t *testing.T) {
+	dir := initDiffTestRepo(t)
+	if _, err := captureLocalDiff(dir, ""); err == nil {
+		t.Error("expected error for a clean tree")
+	}
+}
+
+func TestRepoFromRemote(t *testing.T) {
+	for remote, want := range map[string]string{
+		"git@github.com:acme/widgets.git":       "acme/widgets",
+		"https://github.com/acme/widgets":       "acme/widgets",
+		"https://github.com/acme/widgets.git\n": "acme/widgets",
+		"https://gitlab.com/acme/widgets.git":   "",
+	} {
+		if got := repoFromRemote(remote); got != want {
+			t.Errorf("repoFromRemote(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestEnsureBaseOnRemote(t *testing.T) {
+	dir := initDiffTestRepo(t)
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "--bare", remote},
+		{"-C", dir, "remote", "set-url", "origin", remote},
+		{"-C", dir, "push", "-q", "origin", "main"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	// HEAD is on origin/main.
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "one\ntwo\n")
+	d, err := captureLocalDiff(dir, "")
+	if err != nil {
+		t.Fatalf("captureLocalDiff: %v", err)
+	}
+	if err := d.ensureBaseOnRemote(false); err != nil {
+		t.Fatalf("pushed base rejected: %v", err)
+	}
+
+	// A local-only commit must be pushed first.
+	cmd := exec.Command("git", "-C", dir, "commit", "-q", "-am", "local")
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "one\ntwo\nthree\n")
+	d, err = captureLocalDiff(dir, "")
+	if err != nil {
+		t.Fatalf("captureLocalDiff: %v", err)
+	}
+	if err := d.ensureBaseOnRemote(false); err == nil || !strings.Contains(err.Error(), "--push-base") {
+		t.Fatalf("expected unpushed base error, got %v", err)
+	}
+	if err := d.ensureBaseOnRemote(true); err != nil {
+		t.Fatalf("push base: %v", err)
+	}
+	if d.PushedBranch != pushedBaseBranchPrefix+d.BaseCommit[:12] {
+		t.Errorf("PushedBranch = %q", d.PushedBranch)
+	}
+	out, err := exec.Command("git", "-C", remote, "rev-parse", "refs/heads/"+d.PushedBranch).Output()
+	if err != nil || strings.TrimSpace(string(out)) != d.BaseCommit {
+		t.Errorf("origin branch = %q (%v), want %s", out, err, d.BaseCommit)
+	}
+}
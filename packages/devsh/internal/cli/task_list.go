@@ -20,6 +20,7 @@ var (
 	taskListArchived bool
 	taskListWatch    bool
 	taskListInterval int
+	taskListPaging   listFlags
 )
 
 var taskListCmd = &cobra.Command{
@@ -30,6 +31,8 @@ var taskListCmd = &cobra.Command{
 Examples:
   devsh task list                    # List active tasks
   devsh task list --archived         # List archived tasks
+  devsh task list --all              # List every task, not just the first 50
+  devsh task list --status running --repo owner/repo --created-after 7d
   devsh task list --json             # Output as JSON
   devsh task list --watch            # Watch mode with live updates
   devsh task list --watch --interval 5  # Watch with 5-second interval`,
@@ -57,18 +60,39 @@ func runTaskListOnce() error {
 	}
 	client.SetTeamSlug(teamSlug)
 
-	result, err := client.ListTasks(ctx, taskListArchived)
+	opts, err := taskListPaging.options()
 	if err != nil {
-		return fmt.Errorf("failed to list tasks: %w", err)
+		return err
 	}
 
 	if flagJSON {
+		result, err := client.ListTasksWithOptions(ctx, taskListArchived, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
 		data, _ := json.MarshalIndent(result, "", "  ")
 		fmt.Println(string(data))
 		return nil
 	}
 
-	printTaskList(result)
+	// Print rows page by page so large teams see output immediately.
+	printTaskListServer()
+	count := 0
+	more, err := client.EachTaskPage(ctx, taskListArchived, opts, func(page []vm.Task) error {
+		if count == 0 {
+			printTaskListColumns()
+		}
+		printTaskRows(page)
+		count += len(page)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if count == 0 {
+		printNoTasks()
+	}
+	printMoreHint(more, "tasks")
 	return nil
 }
 
@@ -88,6 +112,11 @@ func runTaskListWatch() error {
 	}
 	client.SetTeamSlug(teamSlug)
 
+	opts, err := taskListPaging.options()
+	if err != nil {
+		return err
+	}
+
 	interval := time.Duration(taskListInterval) * time.Second
 	serverIndicator := getServerIndicator()
 	header := fmt.Sprintf("Task List [%s] (watching, interval: %ds, Ctrl+C to stop)", serverIndicator, taskListInterval)
@@ -111,7 +140,7 @@ func runTaskListWatch() error {
 		func(ctx context.Context) (interface{}, error) {
 			fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 			defer cancel()
-			return client.ListTasks(fetchCtx, taskListArchived)
+			return client.ListTasksWithOptions(fetchCtx, taskListArchived, opts)
 		},
 		// shouldStop - never stop unless cancelled
 		func(result interface{}, lastValue string) (bool, string, error) {
@@ -157,7 +186,17 @@ func getServerIndicator() string {
 }
 
 func printTaskList(result *vm.ListTasksResult) {
-	// Print server indicator header
+	printTaskListServer()
+	if len(result.Tasks) == 0 {
+		printNoTasks()
+		return
+	}
+	printTaskListColumns()
+	printTaskRows(result.Tasks)
+}
+
+// printTaskListServer prints which server the list came from
+func printTaskListServer() {
 	serverIndicator := getServerIndicator()
 	cfg := auth.GetConfig()
 	fmt.Printf("Server: %s", serverIndicator)
@@ -171,20 +210,23 @@ func printTaskList(result *vm.ListTasksResult) {
 	}
 	fmt.Println()
 	fmt.Println()
+}
 
-	if len(result.Tasks) == 0 {
-		if taskListArchived {
-			fmt.Println("No archived tasks found.")
-		} else {
-			fmt.Println("No active tasks found. Create one with 'devsh task create'")
-		}
-		return
+func printNoTasks() {
+	if taskListArchived {
+		fmt.Println("No archived tasks found.")
+	} else {
+		fmt.Println("No active tasks found. Create one with 'devsh task create'")
 	}
+}
 
+func printTaskListColumns() {
 	fmt.Printf("%-30s %-12s %-15s %-8s %-50s %s\n", "TASK ID", "STATUS", "AGENT", "PR", "PR URL", "PROMPT")
 	fmt.Println("------------------------------", "------------", "---------------", "--------", "--------------------------------------------------", "--------------------")
+}
 
-	for _, task := range result.Tasks {
+func printTaskRows(tasks []vm.Task) {
+	for _, task := range tasks {
 		prompt := task.Prompt
 		if len(prompt) > 30 {
 			prompt = prompt[:27] + "..."
@@ -245,5 +287,6 @@ func init() {
 	taskListCmd.Flags().BoolVar(&taskListArchived, "archived", false, "List archived tasks instead of active tasks")
 	taskListCmd.Flags().BoolVarP(&taskListWatch, "watch", "w", false, "Watch mode with live updates")
 	taskListCmd.Flags().IntVar(&taskListInterval, "interval", 10, "Refresh interval in seconds (for --watch mode)")
+	taskListPaging.register(taskListCmd, true, defaultTaskListLimit)
 	taskCmd.AddCommand(taskListCmd)
 }
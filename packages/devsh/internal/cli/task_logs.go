@@ -0,0 +1,128 @@
+// internal/cli/task_logs.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var taskLogsFollow bool
+
+var taskLogsCmd = &cobra.Command{
+	Use:   "logs <task-id-or-run-id>",
+	Short: "Show agent output for a task run",
+	Long: `Show agent output (tool calls, commands, lifecycle events) for a task run.
+
+You can provide either:
+  - A task ID - shows output from the latest task run
+  - A task run ID - shows output from that specific run
+
+With -f, keeps streaming new output until the run completes.
+
+Examples:
+  devsh task logs p17xyz123abc...          # Output so far from the latest run
+  devsh task logs ns7xyz123abc... -f       # Follow a specific run
+  devsh task logs <id> -f --json           # One JSON object per line`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+		go func() {
+			select {
+			case <-sigChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		taskRunID, err := resolveTaskRunID(ctx, client, id)
+		if err != nil {
+			return err
+		}
+
+		entries, errc := client.GetTaskRunLogs(ctx, taskRunID, taskLogsFollow)
+		for entry := range entries {
+			printTaskRunLogEntry(os.Stdout, entry)
+		}
+		if err := <-errc; err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get logs: %w", err)
+		}
+		return nil
+	},
+}
+
+// resolveTaskRunID accepts a task ID (using its latest run) or a task run ID.
+func resolveTaskRunID(ctx context.Context, client *vm.Client, id string) (string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	task, err := client.GetTask(lookupCtx, id)
+	if err == nil {
+		if len(task.TaskRuns) == 0 {
+			return "", fmt.Errorf("task has no runs yet")
+		}
+		if !flagJSON {
+			fmt.Fprintf(os.Stderr, "Using latest run: %s (%s)\n", task.TaskRuns[0].ID, task.TaskRuns[0].Agent)
+		}
+		return task.TaskRuns[0].ID, nil
+	}
+	if isFatalAPIError(err) {
+		return "", fmt.Errorf("failed to resolve ID: %w", err)
+	}
+	return id, nil
+}
+
+func printTaskRunLogEntry(w io.Writer, entry vm.TaskRunLogEntry) {
+	if flagJSON {
+		data, _ := json.Marshal(entry)
+		fmt.Fprintln(w, string(data))
+		return
+	}
+
+	label := entry.Type
+	if entry.ToolName != "" {
+		label += "/" + entry.ToolName
+	}
+	fmt.Fprintf(w, "%s  %-24s %s\n", entry.Time().Format("15:04:05"), label, entry.Summary)
+	if entry.Detail != "" {
+		for _, line := range strings.Split(strings.TrimRight(entry.Detail, "\n"), "\n") {
+			fmt.Fprintf(w, "          %s\n", line)
+		}
+	}
+}
+
+func init() {
+	taskLogsCmd.Flags().BoolVarP(&taskLogsFollow, "follow", "f", false, "Stream new output until the run completes")
+	taskCmd.AddCommand(taskLogsCmd)
+}
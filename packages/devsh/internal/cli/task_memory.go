@@ -4,6 +4,7 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -161,8 +162,12 @@ func isFatalAPIError(err error) bool {
 	if err == nil {
 		return false
 	}
-	errStr := err.Error()
 	// Auth errors - user needs to re-authenticate
+	if errors.Is(err, vm.ErrUnauthorized) || errors.Is(err, vm.ErrForbidden) {
+		return true
+	}
+	// Untyped errors from other clients still carry the status in the text
+	errStr := err.Error()
 	if strings.Contains(errStr, "(401)") || strings.Contains(errStr, "(403)") {
 		return true
 	}
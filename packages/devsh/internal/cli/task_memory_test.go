@@ -223,6 +223,9 @@ func TestFatalAPIError(t *testing.T) {
 		{fmt.Errorf("network error: connection refused"), true, "connection refused - fatal network error"},
 		{fmt.Errorf("dial tcp: no such host"), true, "no such host - fatal network error"},
 		{fmt.Errorf("context deadline exceeded"), true, "timeout - fatal"},
+		{&vm.APIError{StatusCode: 401, Op: "get task"}, true, "typed 401 - fatal auth error"},
+		{fmt.Errorf("wrapped: %w", &vm.APIError{StatusCode: 403}), true, "wrapped typed 403 - fatal auth error"},
+		{&vm.APIError{StatusCode: 404, Message: "Task not found"}, false, "typed 404 - not fatal, fall back"},
 	}
 
 	for _, tt := range tests {
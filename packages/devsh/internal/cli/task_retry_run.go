@@ -0,0 +1,83 @@
+// internal/cli/task_retry_run.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	taskRetryRunAgent string
+	taskRetryRunForce bool
+)
+
+var taskRetryRunCmd = &cobra.Command{
+	Use:   "retry-run <run-id>",
+	Short: "Start a fresh run in place of a single task run",
+	Long: `Start a new run of the task with the same prompt and branch settings as
+the given run, without touching the task's other runs.
+
+A run that is still pending or running is rejected unless --force is set,
+which cancels it first. Use this to restart one stuck agent.
+
+Unlike 'devsh task retry', this does not look at PR checks.
+
+Examples:
+  devsh task retry-run ns7xyz123abc...
+  devsh task retry-run ns7xyz123abc... --force
+  devsh task retry-run ns7xyz123abc... --agent claude/haiku-4.5`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runID := args[0]
+
+		ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+		defer cancel()
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		result, err := client.RetryTaskRun(ctx, runID, vm.RetryTaskRunOptions{
+			Agent:         strings.TrimSpace(taskRetryRunAgent),
+			CancelRunning: taskRetryRunForce,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to retry task run: %w", err)
+		}
+
+		if flagJSON {
+			data, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(data))
+			return nil
+		}
+
+		fmt.Println("Retry started")
+		fmt.Printf("  Task ID: %s\n", result.TaskID)
+		fmt.Printf("  Agent:   %s\n", result.AgentName)
+		fmt.Printf("  TaskRun: %s (replaces %s)\n", result.TaskRunID, runID)
+		if result.VSCodeURL != "" {
+			fmt.Printf("  VSCode:  %s\n", result.VSCodeURL)
+		}
+		return nil
+	},
+}
+
+func init() {
+	taskRetryRunCmd.Flags().StringVar(&taskRetryRunAgent, "agent", "", "Agent to run (defaults to the original run's agent)")
+	taskRetryRunCmd.Flags().BoolVar(&taskRetryRunForce, "force", false, "Cancel the run first if it is still pending or running")
+	taskCmd.AddCommand(taskRetryRunCmd)
+}
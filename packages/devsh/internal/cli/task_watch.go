@@ -0,0 +1,366 @@
+// internal/cli/task_watch.go
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	taskWatchInterval int
+	taskWatchNotify   string
+)
+
+var taskWatchCmd = &cobra.Command{
+	Use:   "watch [task-id]",
+	Short: "Watch task status and print changes as they happen",
+	Long: `Poll task status and print each transition (pending -> running -> completed,
+PR opened) as it happens.
+
+With a task ID, watches that task's runs and exits once every run has
+finished. Without one, watches all active tasks until interrupted.
+
+--notify sends a notification when a task finishes:
+  --notify              Desktop notification (notify-send / osascript)
+  --notify=<url>        POST a JSON payload to a webhook
+
+Examples:
+  devsh task watch                          # Watch all active tasks
+  devsh task watch p17xyz123abc...          # Watch one task until it finishes
+  devsh task watch <task-id> --notify       # Desktop notification on completion
+  devsh task watch --notify=https://hooks.example.com/cmux`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+		go func() {
+			select {
+			case <-sigChan:
+				fmt.Println("\nStopped watching.")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		teamSlug, err := auth.GetTeamSlug()
+		if err != nil {
+			return fmt.Errorf("failed to get team: %w", err)
+		}
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		client.SetTeamSlug(teamSlug)
+
+		w := &taskWatcher{
+			notifier: newTaskNotifier(taskWatchNotify),
+			seen:     map[string]taskWatchState{},
+			perItem:  len(args) == 0,
+		}
+		interval := time.Duration(taskWatchInterval) * time.Second
+		if interval < time.Second {
+			interval = 5 * time.Second
+		}
+
+		var fetch func(ctx context.Context) ([]taskWatchState, bool, error)
+		if len(args) == 1 {
+			taskID := args[0]
+			fetch = func(ctx context.Context) ([]taskWatchState, bool, error) {
+				task, err := client.GetTask(ctx, taskID)
+				if err != nil {
+					return nil, false, err
+				}
+				states, done := taskRunWatchStates(task)
+				return states, done, nil
+			}
+			fmt.Printf("Watching task %s (interval: %s, Ctrl+C to stop)\n", taskID, interval)
+		} else {
+			fetch = func(ctx context.Context) ([]taskWatchState, bool, error) {
+				result, err := client.ListTasks(ctx, false)
+				if err != nil {
+					return nil, false, err
+				}
+				return taskListWatchStates(result.Tasks), false, nil
+			}
+			fmt.Printf("Watching active tasks (interval: %s, Ctrl+C to stop)\n", interval)
+		}
+
+		return w.run(ctx, interval, fetch)
+	},
+}
+
+// taskWatchState is the watched state of one task run (or, when watching
+// the task list, one task).
+type taskWatchState struct {
+	Key            string `json:"id"`
+	TaskID         string `json:"taskId"`
+	Label          string `json:"label"`
+	Status         string `json:"status"`
+	PullRequestURL string `json:"pullRequestUrl,omitempty"`
+}
+
+func (s taskWatchState) finished() bool {
+	switch s.Status {
+	case "completed", "failed", "skipped", "merged", "closed":
+		return true
+	}
+	return false
+}
+
+// taskTransition is a change observed between two polls.
+type taskTransition struct {
+	State  taskWatchState
+	From   string // Previous status; empty when first seen
+	PRNew  bool   // A pull request URL appeared
+	Change bool   // Status changed
+}
+
+func (t taskTransition) String() string {
+	switch {
+	case t.Change && t.From == "":
+		return fmt.Sprintf("%s: %s", t.State.Label, t.State.Status)
+	case t.Change:
+		return fmt.Sprintf("%s: %s -> %s", t.State.Label, t.From, t.State.Status)
+	default:
+		return fmt.Sprintf("%s: PR opened %s", t.State.Label, t.State.PullRequestURL)
+	}
+}
+
+func taskRunWatchStates(task *vm.TaskDetail) ([]taskWatchState, bool) {
+	states := make([]taskWatchState, 0, len(task.TaskRuns))
+	done := len(task.TaskRuns) > 0
+	for _, run := range task.TaskRuns {
+		agent := run.Agent
+		if agent == "" {
+			agent = run.AgentName
+		}
+		state := taskWatchState{
+			Key:            run.ID,
+			TaskID:         task.ID,
+			Label:          fmt.Sprintf("run %s (%s)", run.ID, agent),
+			Status:         run.Status,
+			PullRequestURL: run.PullRequestURL,
+		}
+		if !state.finished() {
+			done = false
+		}
+		states = append(states, state)
+	}
+	return states, done || task.IsCompleted
+}
+
+func taskListWatchStates(tasks []vm.Task) []taskWatchState {
+	states := make([]taskWatchState, 0, len(tasks))
+	for _, task := range tasks {
+		status := task.Status
+		if status == "" && task.IsCompleted {
+			status = "completed"
+		}
+		states = append(states, taskWatchState{
+			Key:            task.ID,
+			TaskID:         task.ID,
+			Label:          fmt.Sprintf("task %s %q", task.ID, truncateString(task.Prompt, 40)),
+			Status:         status,
+			PullRequestURL: task.PullRequestURL,
+		})
+	}
+	return states
+}
+
+// taskWatcher prints transitions between successive polls.
+type taskWatcher struct {
+	notifier taskNotifier
+	seen     map[string]taskWatchState
+	perItem  bool // Notify as each item finishes rather than once at the end
+}
+
+// diff records states and returns what changed since the previous call.
+func (w *taskWatcher) diff(states []taskWatchState) []taskTransition {
+	var transitions []taskTransition
+	for _, s := range states {
+		prev, ok := w.seen[s.Key]
+		w.seen[s.Key] = s
+		t := taskTransition{State: s, From: prev.Status}
+		t.Change = !ok || prev.Status != s.Status
+		t.PRNew = s.PullRequestURL != "" && prev.PullRequestURL != s.PullRequestURL
+		if t.Change || t.PRNew {
+			transitions = append(transitions, t)
+		}
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].State.Key < transitions[j].State.Key
+	})
+	return transitions
+}
+
+func (w *taskWatcher) run(ctx context.Context, interval time.Duration, fetch func(ctx context.Context) ([]taskWatchState, bool, error)) error {
+	initial := true
+	for {
+		states, done, err := fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if initial {
+				return fmt.Errorf("failed to get task status: %w", err)
+			}
+			fmt.Printf("[%s] Error: %v\n", time.Now().Format("15:04:05"), err)
+		} else {
+			for _, t := range w.diff(states) {
+				fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), t)
+				if t.PRNew && t.Change {
+					fmt.Printf("[%s] %s: PR opened %s\n", time.Now().Format("15:04:05"), t.State.Label, t.State.PullRequestURL)
+				}
+				// The initial snapshot is not news.
+				if w.perItem && !initial && t.Change && t.State.finished() && w.notifier != nil {
+					w.notify(ctx, t.State)
+				}
+			}
+			if done {
+				fmt.Println("All runs finished.")
+				if w.notifier != nil && len(states) > 0 {
+					w.notify(ctx, taskSummaryState(states))
+				}
+				return nil
+			}
+			initial = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *taskWatcher) notify(ctx context.Context, s taskWatchState) {
+	if err := w.notifier.Notify(ctx, s); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notification failed: %v\n", err)
+	}
+}
+
+// taskSummaryState folds a finished task's runs into one notification.
+func taskSummaryState(states []taskWatchState) taskWatchState {
+	summary := taskWatchState{
+		TaskID: states[0].TaskID,
+		Label:  "task " + states[0].TaskID,
+		Status: summarizeStatuses(states),
+	}
+	for _, s := range states {
+		if s.PullRequestURL != "" {
+			summary.PullRequestURL = s.PullRequestURL
+			break
+		}
+	}
+	return summary
+}
+
+func summarizeStatuses(states []taskWatchState) string {
+	counts := map[string]int{}
+	for _, s := range states {
+		counts[s.Status]++
+	}
+	parts := make([]string, 0, len(counts))
+	for status, n := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", n, status))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// taskNotifier delivers a completion notification.
+type taskNotifier interface {
+	Notify(ctx context.Context, s taskWatchState) error
+}
+
+// newTaskNotifier returns nil when notifications are off, a webhook
+// notifier for URLs, and a desktop notifier otherwise.
+func newTaskNotifier(target string) taskNotifier {
+	switch {
+	case target == "":
+		return nil
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		return webhookNotifier{url: target, client: &http.Client{Timeout: 10 * time.Second}}
+	default:
+		return desktopNotifier{}
+	}
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n webhookNotifier) Notify(ctx context.Context, s taskWatchState) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type desktopNotifier struct{}
+
+func (desktopNotifier) Notify(ctx context.Context, s taskWatchState) error {
+	title := "cmux: " + s.Status
+	message := s.Label
+	if s.PullRequestURL != "" {
+		message += "\n" + s.PullRequestURL
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return fmt.Errorf("notify-send not found")
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", title, message)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	return cmd.Run()
+}
+
+func init() {
+	taskWatchCmd.Flags().IntVar(&taskWatchInterval, "interval", 5, "Polling interval in seconds")
+	taskWatchCmd.Flags().StringVar(&taskWatchNotify, "notify", "", "Notify on completion: desktop (default) or a webhook URL")
+	taskWatchCmd.Flags().Lookup("notify").NoOptDefVal = "desktop"
+	taskCmd.AddCommand(taskWatchCmd)
+}
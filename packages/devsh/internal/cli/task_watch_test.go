@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+type recordingNotifier struct {
+	got []taskWatchState
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, s taskWatchState) error {
+	n.got = append(n.got, s)
+	return nil
+}
+
+func TestTaskWatcherDiff(t *testing.T) {
+	w := &taskWatcher{seen: map[string]taskWatchState{}}
+
+	first := w.diff([]taskWatchState{{Key: "r1", Label: "run r1", Status: "pending"}})
+	if len(first) != 1 || first[0].String() != "run r1: pending" {
+		t.Fatalf("first diff = %+v", first)
+	}
+
+	if got := w.diff([]taskWatchState{{Key: "r1", Label: "run r1", Status: "pending"}}); len(got) != 0 {
+		t.Fatalf("unchanged poll produced transitions: %+v", got)
+	}
+
+	got := w.diff([]taskWatchState{{Key: "r1", Label: "run r1", Status: "running"}})
+	if len(got) != 1 || got[0].String() != "run r1: pending -> running" {
+		t.Fatalf("status diff = %+v", got)
+	}
+
+	got = w.diff([]taskWatchState{{Key: "r1", Label: "run r1", Status: "running", PullRequestURL: "https://github.com/o/r/pull/1"}})
+	if len(got) != 1 || got[0].Change || !got[0].PRNew {
+		t.Fatalf("PR diff = %+v", got)
+	}
+	if !strings.Contains(got[0].String(), "PR opened https://github.com/o/r/pull/1") {
+		t.Errorf("PR transition = %q", got[0].String())
+	}
+}
+
+func TestTaskRunWatchStatesDone(t *testing.T) {
+	task := &vm.TaskDetail{ID: "t1", TaskRuns: []vm.TaskRun{
+		{ID: "r1", Agent: "claude/opus", Status: "completed"},
+		{ID: "r2", AgentName: "codex", Status: "running"},
+	}}
+	states, done := taskRunWatchStates(task)
+	if done {
+		t.Error("task with a running run should not be done")
+	}
+	if states[1].Label != "run r2 (codex)" {
+		t.Errorf("label = %q", states[1].Label)
+	}
+
+	task.TaskRuns[1].Status = "failed"
+	if _, done := taskRunWatchStates(task); !done {
+		t.Error("task with all runs finished should be done")
+	}
+
+	if _, done := taskRunWatchStates(&vm.TaskDetail{ID: "t2"}); done {
+		t.Error("task without runs should not be done")
+	}
+}
+
+func TestTaskWatcherRunNotifiesOnceWhenTaskFinishes(t *testing.T) {
+	statuses := []string{"pending", "running", "completed"}
+	polls := 0
+	fetch := func(ctx context.Context) ([]taskWatchState, bool, error) {
+		status := statuses[polls]
+		polls++
+		return []taskWatchState{{Key: "r1", TaskID: "t1", Label: "run r1", Status: status}}, status == "completed", nil
+	}
+
+	notifier := &recordingNotifier{}
+	w := &taskWatcher{notifier: notifier, seen: map[string]taskWatchState{}}
+	output := captureStdout(t, func() {
+		if err := w.run(context.Background(), time.Millisecond, fetch); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	})
+
+	for _, want := range []string{"run r1: pending", "pending -> running", "running -> completed", "All runs finished."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if len(notifier.got) != 1 || notifier.got[0].Status != "1 completed" {
+		t.Errorf("notifications = %+v", notifier.got)
+	}
+}
+
+func TestNewTaskNotifier(t *testing.T) {
+	if newTaskNotifier("") != nil {
+		t.Error("empty target should disable notifications")
+	}
+	if _, ok := newTaskNotifier("desktop").(desktopNotifier); !ok {
+		t.Error("desktop target should use desktop notifier")
+	}
+	if _, ok := newTaskNotifier("https://hooks.example.com/x").(webhookNotifier); !ok {
+		t.Error("URL target should use webhook notifier")
+	}
+}
+
+func TestWebhookNotifierPostsState(t *testing.T) {
+	var got taskWatchState
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	n := newTaskNotifier(server.URL)
+	state := taskWatchState{Key: "r1", TaskID: "t1", Status: "completed", PullRequestURL: "https://github.com/o/r/pull/2"}
+	if err := n.Notify(context.Background(), state); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got != state {
+		t.Errorf("webhook payload = %+v, want %+v", got, state)
+	}
+}
@@ -16,7 +16,12 @@ import (
 var teamCmd = &cobra.Command{
 	Use:   "team",
 	Short: "Manage teams",
-	Long:  `List teams and switch between them.`,
+	Long: `List teams and switch between them.
+
+CLI commands use the default team, resolved in this order:
+  1. DEVSH_TEAM environment variable
+  2. The team saved by 'devsh team use' or 'devsh team switch'
+  3. The team selected in the web app (saved as the default on first use)`,
 }
 
 var teamSwitchCmd = &cobra.Command{
@@ -82,6 +87,58 @@ Examples:
 	},
 }
 
+var teamUseCmd = &cobra.Command{
+	Use:   "use <team>",
+	Short: "Set the default team for CLI commands",
+	Long: `Set the default team used by CLI commands, without changing the team
+selected in the web app. Use 'devsh team switch' to change both.
+
+The team can be specified by slug or ID.
+
+Examples:
+  devsh team use dev
+  devsh team use e2afe2c9-bcb9-4c2e-82d9-f8d789d3f3c5`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		client, err := vm.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		team, err := client.FindTeam(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to set default team: %w", err)
+		}
+
+		if err := auth.SetDefaultTeam(auth.DefaultTeam{
+			TeamID:      team.TeamID,
+			Slug:        team.Slug,
+			DisplayName: team.DisplayName,
+		}); err != nil {
+			return fmt.Errorf("failed to save default team: %w", err)
+		}
+
+		if flagJSON {
+			data, _ := json.MarshalIndent(team, "", "  ")
+			fmt.Println(string(data))
+			return nil
+		}
+
+		name := team.DisplayName
+		if name == "" {
+			name = team.Slug
+		}
+		fmt.Printf("✓ Default team set to %s\n", name)
+		if envTeam := auth.GetTeamOverride(); envTeam != "" {
+			fmt.Printf("Note: DEVSH_TEAM=%s is set and overrides the default.\n", envTeam)
+		}
+		return nil
+	},
+}
+
 var teamListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List your teams",
@@ -109,6 +166,7 @@ var teamListCmd = &cobra.Command{
 				return nil
 			}
 
+			defaultTeam, _ := auth.GetDefaultTeam()
 			fmt.Println("Teams:")
 			for _, team := range result.Teams {
 				teamName := team.DisplayName
@@ -125,11 +183,14 @@ var teamListCmd = &cobra.Command{
 					marker = "* "
 					suffix = " (selected)"
 				}
+				if defaultTeam != nil && (defaultTeam.SlugOrID() == team.Slug || defaultTeam.SlugOrID() == team.TeamID) {
+					suffix += " (default)"
+				}
 
 				fmt.Printf("  %s%s%s\n", marker, teamName, suffix)
 			}
 			fmt.Println()
-			fmt.Println("Use 'devsh team switch <team>' to switch teams.")
+			fmt.Println("Use 'devsh team switch <team>' to switch teams, or 'devsh team use <team>' to change only the CLI default.")
 		}
 
 		return nil
@@ -138,5 +199,6 @@ var teamListCmd = &cobra.Command{
 
 func init() {
 	teamCmd.AddCommand(teamSwitchCmd)
+	teamCmd.AddCommand(teamUseCmd)
 	teamCmd.AddCommand(teamListCmd)
 }
@@ -0,0 +1,106 @@
+// internal/cli/template.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/pvelxc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templatePublishPreset        string
+	templatePublishFrom          int
+	templatePublishScript        string
+	templatePublishManifest      string
+	templatePublishTimeout       time.Duration
+	templatePublishKeepOnFailure bool
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage PVE LXC templates",
+}
+
+var templatePublishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Build and publish a new template version",
+	Long: `Build a new version of a PVE LXC template preset.
+
+Clones the preset's latest template (or --from), runs the provisioning
+script inside it as root, shuts it down, converts it to a template, and
+appends the new version to packages/shared/src/pve-lxc-snapshots.json.
+On failure the build container is deleted unless --keep-on-failure is set.
+
+Requires PVE_API_URL and PVE_API_TOKEN. Run from the repo, or pass
+--manifest.
+
+Examples:
+  devsh template publish --preset 4vcpu_8gb_32gb --script ./provision.sh
+  devsh template publish --from 9128 --script ./upgrade-node.sh
+  devsh template publish --preset 6vcpu_8gb_40gb --script ./provision.sh --keep-on-failure`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if templatePublishPreset == "" && templatePublishFrom <= 0 {
+			return fmt.Errorf("--preset or --from is required")
+		}
+		if !provider.HasPveEnv() {
+			return fmt.Errorf("template publish requires PVE_API_URL and PVE_API_TOKEN")
+		}
+		client, err := pvelxc.NewClientFromEnv()
+		if err != nil {
+			return fmt.Errorf("failed to create PVE LXC client: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		progress := func(msg string) {
+			if !flagJSON {
+				fmt.Println(msg)
+			}
+		}
+		result, err := client.PublishTemplate(ctx, pvelxc.PublishOptions{
+			PresetID:           templatePublishPreset,
+			SourceTemplateVMID: templatePublishFrom,
+			ScriptPath:         templatePublishScript,
+			ScriptTimeout:      templatePublishTimeout,
+			ManifestPath:       templatePublishManifest,
+			KeepOnFailure:      templatePublishKeepOnFailure,
+			Progress:           progress,
+		})
+		if err != nil {
+			return fmt.Errorf("template publish failed: %w", err)
+		}
+
+		if flagJSON {
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Printf("Published %s version %d: %s (template %d)\n", result.PresetID, result.Version, result.SnapshotID, result.TemplateVMID)
+		fmt.Printf("Updated %s; commit it to roll out the new template.\n", result.ManifestPath)
+		return nil
+	},
+}
+
+func init() {
+	templatePublishCmd.Flags().StringVar(&templatePublishPreset, "preset", "", "Preset to publish a new version of")
+	templatePublishCmd.Flags().IntVar(&templatePublishFrom, "from", 0, "Source template VMID (default: the preset's latest version)")
+	templatePublishCmd.Flags().StringVar(&templatePublishScript, "script", "", "Provisioning script to run in the build container")
+	templatePublishCmd.Flags().StringVar(&templatePublishManifest, "manifest", "", "Path to pve-lxc-snapshots.json (default: found from the working directory)")
+	templatePublishCmd.Flags().DurationVar(&templatePublishTimeout, "timeout", 30*time.Minute, "Timeout for the provisioning script")
+	templatePublishCmd.Flags().BoolVar(&templatePublishKeepOnFailure, "keep-on-failure", false, "Keep the build container if publishing fails")
+	templatePublishCmd.MarkFlagRequired("script")
+	templateCmd.AddCommand(templatePublishCmd)
+	rootCmd.AddCommand(templateCmd)
+}
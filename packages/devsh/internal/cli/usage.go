@@ -0,0 +1,206 @@
+// internal/cli/usage.go
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/cost"
+	"github.com/karlorz/devsh/internal/provider"
+	"github.com/karlorz/devsh/internal/usage"
+	"github.com/karlorz/devsh/internal/vm"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Summarize sandbox runtime and estimated spend per workspace",
+	Long: `Summarize sandbox runtime and estimated spend per workspace.
+
+devsh records when instances are started, resumed, paused, and deleted
+through the CLI. Before summarizing, running Morph instances are
+reconciled with the API: instances started elsewhere are picked up, and
+instances stopped elsewhere are closed at the last time devsh saw them.
+
+Costs are estimates from per-vCPU and per-GB hourly rates. Instances of
+unknown size are assumed to be 2 vCPU / 4 GB.
+
+Examples:
+  devsh usage                      # Last 7 days
+  devsh usage --since 30d
+  devsh usage --warn-after 4h      # Flag instances running over 4 hours
+  devsh usage --vcpu-hour 0.05 --gb-hour 0.01`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+var (
+	usageSince     string
+	usageWarnAfter string
+	usageVCPUHour  float64
+	usageGBHour    float64
+)
+
+// recordUsageStart opens a usage session. Failures never block the command.
+func recordUsageStart(r usage.Record) {
+	updateUsage(func(s *usage.Store, now time.Time) { s.Started(r, now) })
+}
+
+// recordUsageStop closes an instance's usage session
+func recordUsageStop(instanceID string) {
+	updateUsage(func(s *usage.Store, now time.Time) { s.Stopped(instanceID, now) })
+}
+
+func updateUsage(fn func(s *usage.Store, now time.Time)) {
+	_, _ = usage.Update(fn)
+}
+
+// parseDays parses a duration that may use a "d" (day) suffix, e.g. 7d
+func parseDays(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 12h or 7d)", value)
+	}
+	return d, nil
+}
+
+// runningMorphInstances returns the IDs of the team's running Morph
+// instances, for reconciling the usage store.
+func runningMorphInstances() (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	teamSlug, err := auth.GetTeamSlug()
+	if err != nil {
+		return nil, err
+	}
+	client, err := vm.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	client.SetTeamSlug(teamSlug)
+
+	instances, err := client.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[string]bool)
+	for _, inst := range instances {
+		if inst.Status == "running" {
+			running[inst.ID] = true
+		}
+	}
+	return running, nil
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	since, err := parseDays(usageSince)
+	if err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	warnAfter, err := parseDays(usageWarnAfter)
+	if err != nil {
+		return fmt.Errorf("--warn-after: %w", err)
+	}
+	rates := cost.InstanceRates{VCPUHour: usageVCPUHour, GBHour: usageGBHour}
+
+	// List instances before taking the store lock, so a slow API call
+	// doesn't hold up other commands recording usage.
+	running, err := runningMorphInstances()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check running instances, showing recorded usage only: %v\n", err)
+	}
+	var now time.Time
+	s, err := usage.Update(func(s *usage.Store, at time.Time) {
+		now = at
+		if running != nil {
+			s.Reconcile(provider.Morph, running, at)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update usage: %w", err)
+	}
+
+	summaries := s.Summarize(now.Add(-since), now, rates)
+	longRunning := s.LongRunning(warnAfter, now)
+
+	if flagJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"since":       now.Add(-since),
+			"workspaces":  summaries,
+			"longRunning": longRunning,
+			"rates":       map[string]float64{"vcpuHour": rates.VCPUHour, "gbHour": rates.GBHour},
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(summaries) == 0 {
+		fmt.Printf("No recorded usage in the last %s\n", usageSince)
+		return nil
+	}
+
+	var total float64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tINSTANCES\tRUNNING\tRUNTIME\tVCPU-H\tGB-H\tEST. COST")
+	fmt.Fprintln(w, "---------\t---------\t-------\t-------\t------\t----\t---------")
+	for _, ws := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%.1f\t%.1f\t$%.2f\n",
+			ws.Workspace, len(ws.Instances), ws.Running, formatRuntime(ws.Runtime), ws.VCPUHours, ws.GBHours, ws.EstCostUSD)
+		total += ws.EstCostUSD
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nTotal (last %s): $%.2f at $%.3f/vCPU-hour + $%.3f/GB-hour\n", usageSince, total, rates.VCPUHour, rates.GBHour)
+
+	if len(longRunning) > 0 {
+		fmt.Printf("\nRunning longer than %s:\n", usageWarnAfter)
+		for _, lr := range longRunning {
+			fmt.Printf("  ⚠ %s (%s) running for %s — pause with: devsh pause %s\n",
+				lr.InstanceID, lr.Workspace, formatRuntime(lr.Running), lr.InstanceID)
+		}
+	}
+	return nil
+}
+
+// formatRuntime renders a duration as e.g. 3d4h, 5h12m, or 42m
+func formatRuntime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "7d", "Period to summarize (e.g. 24h, 7d, 30d)")
+	usageCmd.Flags().StringVar(&usageWarnAfter, "warn-after", "8h", "Flag instances running longer than this")
+	usageCmd.Flags().Float64Var(&usageVCPUHour, "vcpu-hour", cost.DefaultInstanceRates.VCPUHour, "Estimated USD per vCPU-hour")
+	usageCmd.Flags().Float64Var(&usageGBHour, "gb-hour", cost.DefaultInstanceRates.GBHour, "Estimated USD per GB-hour of memory")
+	rootCmd.AddCommand(usageCmd)
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDays(t *testing.T) {
+	cases := map[string]time.Duration{
+		"7d":   7 * 24 * time.Hour,
+		"1.5d": 36 * time.Hour,
+		"12h":  12 * time.Hour,
+		"90m":  90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := parseDays(in)
+		if err != nil || got != want {
+			t.Errorf("parseDays(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "week"} {
+		if _, err := parseDays(in); err == nil {
+			t.Errorf("parseDays(%q) should fail", in)
+		}
+	}
+}
+
+func TestFormatRuntime(t *testing.T) {
+	cases := map[time.Duration]string{
+		42 * time.Minute:              "42m",
+		5*time.Hour + 12*time.Minute:  "5h12m",
+		76*time.Hour + 20*time.Minute: "3d4h",
+		0:                             "0m",
+	}
+	for in, want := range cases {
+		if got := formatRuntime(in); got != want {
+			t.Errorf("formatRuntime(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package config reads and writes the devsh config file
+// (~/.config/cmux/config.toml). The file holds named profiles (for example
+// dev, prod, self-hosted) whose settings map onto the environment variables
+// the CLI already understands. Profile values only fill in variables that are
+// not already set, so the precedence is: flags > environment > profile >
+// built-in defaults.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// FileName is the config file name inside the shared cmux config dir
+	FileName = "config.toml"
+
+	// ProfileEnvVar selects the active profile when --profile is not given
+	ProfileEnvVar = "DEVSH_PROFILE"
+
+	// DefaultProfile is used when nothing selects a profile
+	DefaultProfile = "default"
+)
+
+// Key is a supported config setting and the environment variable it feeds
+type Key struct {
+	Name        string
+	EnvVar      string
+	Description string
+	Secret      bool // Masked in listings
+}
+
+// Keys lists the supported settings in display order
+var Keys = []Key{
+	{Name: "api_url", EnvVar: "CMUX_API_URL", Description: "cmux web app URL"},
+	{Name: "convex_site_url", EnvVar: "CONVEX_SITE_URL", Description: "Convex HTTP site URL"},
+	{Name: "server_url", EnvVar: "CMUX_SERVER_URL", Description: "apps/server HTTP API URL"},
+	{Name: "auth_api_url", EnvVar: "AUTH_API_URL", Description: "Stack Auth API URL"},
+	{Name: "stack_project_id", EnvVar: "STACK_PROJECT_ID", Description: "Stack Auth project ID"},
+	{Name: "stack_publishable_client_key", EnvVar: "STACK_PUBLISHABLE_CLIENT_KEY", Description: "Stack Auth publishable client key", Secret: true},
+	{Name: "team", EnvVar: "DEVSH_TEAM", Description: "Default team slug"},
+	{Name: "provider", EnvVar: "DEVSH_PROVIDER", Description: "Sandbox provider (morph, pve-lxc, e2b, docker)"},
+	{Name: "docker_image", EnvVar: "DEVSH_DOCKER_IMAGE", Description: "Docker provider image"},
+	{Name: "morph_api_key", EnvVar: "MORPH_API_KEY", Description: "Morph API key", Secret: true},
+	{Name: "pve_api_url", EnvVar: "PVE_API_URL", Description: "Proxmox VE API URL"},
+	{Name: "pve_api_token", EnvVar: "PVE_API_TOKEN", Description: "Proxmox VE API token", Secret: true},
+	{Name: "pve_node", EnvVar: "PVE_NODE", Description: "Proxmox VE node name"},
+	{Name: "pve_public_domain", EnvVar: "PVE_PUBLIC_DOMAIN", Description: "Public domain for PVE sandboxes"},
+	{Name: "pve_verify_tls", EnvVar: "PVE_VERIFY_TLS", Description: "Verify the PVE API TLS certificate (true/false)"},
+}
+
+// LookupKey returns the setting with the given name
+func LookupKey(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// ValidateProfileName checks that name can be written as a TOML table key
+func ValidateProfileName(name string) error {
+	if !bareKeyPattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, '-' and '_')", name)
+	}
+	return nil
+}
+
+// File is the parsed config file
+type File struct {
+	// Profile is the active profile recorded in the file
+	Profile string
+	// Profiles maps profile name to setting name to value
+	Profiles map[string]map[string]string
+}
+
+// Path returns the config file path (~/.config/cmux/config.toml)
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "cmux", FileName), nil
+}
+
+// Load reads the config file. A missing file yields an empty config.
+func Load() (*File, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the config file at path. A missing file yields an empty config.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &File{Profiles: map[string]map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Save writes the config file, creating the config dir if needed.
+// Comments in the existing file are not preserved.
+func (f *File) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	return f.SaveFile(path)
+}
+
+// SaveFile writes the config to path. The file may hold secrets, so it is
+// only readable by the owner.
+func (f *File) SaveFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return os.WriteFile(path, f.Marshal(), 0600)
+}
+
+// Get returns a setting from profile
+func (f *File) Get(profile, key string) (string, bool) {
+	v, ok := f.Profiles[profile][key]
+	return v, ok
+}
+
+// Set stores a setting in profile, creating the profile if needed
+func (f *File) Set(profile, key, value string) {
+	if f.Profiles == nil {
+		f.Profiles = map[string]map[string]string{}
+	}
+	if f.Profiles[profile] == nil {
+		f.Profiles[profile] = map[string]string{}
+	}
+	f.Profiles[profile][key] = value
+}
+
+// Unset removes a setting from profile and reports whether it was present
+func (f *File) Unset(profile, key string) bool {
+	if _, ok := f.Profiles[profile][key]; !ok {
+		return false
+	}
+	delete(f.Profiles[profile], key)
+	return true
+}
+
+// ProfileNames returns the defined profiles, sorted
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ActiveProfile resolves which profile applies: explicit (the --profile
+// flag), then DEVSH_PROFILE, then the file's profile, then DefaultProfile.
+func (f *File) ActiveProfile(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if env := os.Getenv(ProfileEnvVar); env != "" {
+		return env
+	}
+	if f.Profile != "" {
+		return f.Profile
+	}
+	return DefaultProfile
+}
+
+// appliedEnv records the environment variables Apply set, so callers can
+// tell profile values apart from values the user exported.
+var appliedEnv = map[string]string{}
+
+// Apply exports the settings of profile as environment variables, skipping
+// variables that are already set. It returns an error if profile is not
+// DefaultProfile and is not defined in the file.
+func (f *File) Apply(profile string) error {
+	settings, ok := f.Profiles[profile]
+	if !ok {
+		if profile == DefaultProfile {
+			return nil
+		}
+		return fmt.Errorf("profile %q not found in %s", profile, FileName)
+	}
+
+	for _, k := range Keys {
+		value, ok := settings[k.Name]
+		if !ok || os.Getenv(k.EnvVar) != "" {
+			continue
+		}
+		os.Setenv(k.EnvVar, value)
+		appliedEnv[k.EnvVar] = profile
+	}
+	return nil
+}
+
+// AppliedFrom returns the profile that set envVar, or "" if the variable
+// was not set by Apply.
+func AppliedFrom(envVar string) string {
+	return appliedEnv[envVar]
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `# my profiles
+profile = "dev"
+
+[profiles.dev]
+server_url = "http://localhost:9776"   # local apps/server
+pve_verify_tls = false
+
+[profiles."self-hosted"]
+api_url = 'https://cmux.example.com'
+pve_api_token = "root@pam!devsh=a\"b"
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(sampleConfig))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Profile != "dev" {
+		t.Errorf("Profile = %q, want dev", f.Profile)
+	}
+
+	tests := []struct {
+		profile, key, want string
+	}{
+		{"dev", "server_url", "http://localhost:9776"},
+		{"dev", "pve_verify_tls", "false"},
+		{"self-hosted", "api_url", "https://cmux.example.com"},
+		{"self-hosted", "pve_api_token", `root@pam!devsh=a"b`},
+	}
+	for _, tt := range tests {
+		if got, ok := f.Get(tt.profile, tt.key); !ok || got != tt.want {
+			t.Errorf("Get(%s, %s) = %q, %v; want %q", tt.profile, tt.key, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown table":     "[servers]\n",
+		"top-level setting": "server_url = \"http://x\"\n",
+		"unterminated":      "[profiles.dev]\nserver_url = \"http://x\n",
+		"bare string":       "[profiles.dev]\nserver_url = http://x\n",
+		"trailing garbage":  "[profiles.dev]\nserver_url = \"a\" b\n",
+		"array of tables":   "[[profiles.dev]]\n",
+		"invalid profile":   "[profiles.a b]\n",
+		"missing equals":    "[profiles.dev]\nserver_url\n",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(input)); err == nil {
+				t.Errorf("Parse(%q) expected error", input)
+			}
+		})
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	f := &File{Profile: "prod"}
+	f.Set("prod", "server_url", "https://server.example.com")
+	f.Set("prod", "api_url", "https://cmux.example.com")
+	f.Set("self-hosted", "pve_api_token", "tok\"en\\with\nescapes")
+
+	out := f.Marshal()
+	if !strings.Contains(string(out), "[profiles.prod]\napi_url = ") {
+		t.Errorf("keys not in Keys order:\n%s", out)
+	}
+
+	parsed, err := Parse(out)
+	if err != nil {
+		t.Fatalf("Parse(Marshal()): %v\n%s", err, out)
+	}
+	if parsed.Profile != "prod" {
+		t.Errorf("Profile = %q", parsed.Profile)
+	}
+	for _, name := range f.ProfileNames() {
+		for k, v := range f.Profiles[name] {
+			if got, _ := parsed.Get(name, k); got != v {
+				t.Errorf("%s.%s = %q, want %q", name, k, got, v)
+			}
+		}
+	}
+}
+
+func TestLoadAndSaveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmux", FileName)
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile(missing): %v", err)
+	}
+	f.Set("dev", "team", "acme")
+	if err := f.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("config file mode = %o, want 600", perm)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if got, _ := loaded.Get("dev", "team"); got != "acme" {
+		t.Errorf("team = %q, want acme", got)
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	f := &File{Profile: "prod"}
+
+	t.Setenv(ProfileEnvVar, "")
+	if got := f.ActiveProfile(""); got != "prod" {
+		t.Errorf("file profile: got %q", got)
+	}
+	t.Setenv(ProfileEnvVar, "self-hosted")
+	if got := f.ActiveProfile(""); got != "self-hosted" {
+		t.Errorf("env profile: got %q", got)
+	}
+	if got := f.ActiveProfile("dev"); got != "dev" {
+		t.Errorf("flag profile: got %q", got)
+	}
+	t.Setenv(ProfileEnvVar, "")
+	if got := (&File{}).ActiveProfile(""); got != DefaultProfile {
+		t.Errorf("no profile: got %q", got)
+	}
+}
+
+func TestApplyDoesNotOverrideEnvironment(t *testing.T) {
+	t.Setenv("CMUX_SERVER_URL", "http://from-env")
+	t.Setenv("DEVSH_TEAM", "")
+	os.Unsetenv("DEVSH_TEAM")
+
+	f := &File{}
+	f.Set("dev", "server_url", "http://from-profile")
+	f.Set("dev", "team", "acme")
+	if err := f.Apply("dev"); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if got := os.Getenv("CMUX_SERVER_URL"); got != "http://from-env" {
+		t.Errorf("CMUX_SERVER_URL = %q, env should win", got)
+	}
+	if got := os.Getenv("DEVSH_TEAM"); got != "acme" {
+		t.Errorf("DEVSH_TEAM = %q, want profile value", got)
+	}
+	if AppliedFrom("DEVSH_TEAM") != "dev" || AppliedFrom("CMUX_SERVER_URL") != "" {
+		t.Errorf("AppliedFrom mismatch: team=%q server=%q", AppliedFrom("DEVSH_TEAM"), AppliedFrom("CMUX_SERVER_URL"))
+	}
+
+	if err := f.Apply("missing"); err == nil {
+		t.Error("expected error for undefined profile")
+	}
+	if err := f.Apply(DefaultProfile); err != nil {
+		t.Errorf("undefined default profile should be a no-op: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The config file uses a small subset of TOML: comments, a top-level
+// `profile = "..."` key, and `[profiles.<name>]` tables of string settings.
+// Booleans and numbers are accepted and kept as their literal text, since
+// every setting ends up in an environment variable.
+
+var (
+	bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	literalPattern = regexp.MustCompile(`^(true|false|[+-]?[0-9][0-9_.eE+-]*)$`)
+)
+
+// Parse parses config file contents
+func Parse(data []byte) (*File, error) {
+	f := &File{Profiles: map[string]map[string]string{}}
+	var profile string // Current [profiles.<name>] table, "" at top level
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, err := parseTableHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			profile = name
+			if f.Profiles[profile] == nil {
+				f.Profiles[profile] = map[string]string{}
+			}
+			continue
+		}
+
+		key, value, err := parseKeyValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if profile == "" {
+			if key != "profile" {
+				return nil, fmt.Errorf("line %d: unknown top-level key %q (settings belong in a [profiles.<name>] table)", lineNo, key)
+			}
+			f.Profile = value
+			continue
+		}
+		f.Profiles[profile][key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parseTableHeader(line string) (string, error) {
+	if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+		return "", fmt.Errorf("invalid table header %q", line)
+	}
+	inner := strings.TrimSpace(line[1 : len(line)-1])
+	name, ok := strings.CutPrefix(inner, "profiles.")
+	if !ok {
+		return "", fmt.Errorf("unsupported table [%s] (expected [profiles.<name>])", inner)
+	}
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, `"`) {
+		unquoted, err := strconv.Unquote(name)
+		if err != nil {
+			return "", fmt.Errorf("invalid profile name %s", name)
+		}
+		name = unquoted
+	}
+	if !bareKeyPattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	return name, nil
+}
+
+func parseKeyValue(line string) (string, string, error) {
+	key, rest, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", fmt.Errorf("expected key = value, got %q", line)
+	}
+	key = strings.TrimSpace(key)
+	if !bareKeyPattern.MatchString(key) {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+
+	value, err := parseValue(strings.TrimSpace(rest))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", key, err)
+	}
+	return key, value, nil
+}
+
+// parseValue parses a basic string, literal string, boolean or number,
+// followed by an optional comment.
+func parseValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := closingQuote(s)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", s[:end+1])
+		}
+		return value, checkTrailing(s[end+1:])
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], checkTrailing(s[end+2:])
+	}
+
+	literal, _, _ := strings.Cut(s, "#")
+	literal = strings.TrimSpace(literal)
+	if !literalPattern.MatchString(literal) {
+		return "", fmt.Errorf("unsupported value %q (quote strings)", literal)
+	}
+	return literal, nil
+}
+
+// closingQuote returns the index of the quote ending the basic string at the
+// start of s, skipping escaped quotes.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected text after value: %q", rest)
+	}
+	return nil
+}
+
+// Marshal renders the config file with profiles and keys sorted
+func (f *File) Marshal() []byte {
+	var b bytes.Buffer
+	b.WriteString("# devsh configuration. Precedence: flags > environment > profile.\n")
+	b.WriteString("# Edit with `devsh config set <key> <value> [--profile <name>]`.\n")
+	if f.Profile != "" {
+		fmt.Fprintf(&b, "profile = %s\n", quote(f.Profile))
+	}
+
+	for _, name := range f.ProfileNames() {
+		fmt.Fprintf(&b, "\n[profiles.%s]\n", name)
+		settings := f.Profiles[name]
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sortKeys(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s = %s\n", k, quote(settings[k]))
+		}
+	}
+	return b.Bytes()
+}
+
+// sortKeys orders known settings as in Keys, then unknown ones by name
+func sortKeys(keys []string) {
+	rank := func(name string) int {
+		for i, k := range Keys {
+			if k.Name == name {
+				return i
+			}
+		}
+		return len(Keys)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ri, rj := rank(keys[i]), rank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// quote renders s as a TOML basic string
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
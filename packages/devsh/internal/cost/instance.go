@@ -0,0 +1,16 @@
+package cost
+
+// InstanceRates prices sandbox runtime in USD per hour
+type InstanceRates struct {
+	VCPUHour float64 // Per vCPU
+	GBHour   float64 // Per GB of memory
+}
+
+// DefaultInstanceRates are approximate sandbox prices for planning; pass
+// your provider's actual rates for real accounting.
+var DefaultInstanceRates = InstanceRates{VCPUHour: 0.02, GBHour: 0.005}
+
+// Hourly returns the hourly cost of a sandbox with the given resources
+func (r InstanceRates) Hourly(vcpus, memoryMB int) float64 {
+	return float64(vcpus)*r.VCPUHour + float64(memoryMB)/1024*r.GBHour
+}
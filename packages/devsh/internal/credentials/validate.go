@@ -0,0 +1,165 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Key check results reported by ValidateProvider
+const (
+	KeyValid          = "valid"
+	KeyMissing        = "missing"
+	KeyInvalid        = "invalid"
+	KeyQuotaExhausted = "quota_exhausted"
+	KeyRateLimited    = "rate_limited"
+	KeyNotValidated   = "not_validated"
+	KeyError          = "error"
+)
+
+// KeyCheck is the outcome of a live credential validation call
+type KeyCheck struct {
+	Result  string        `json:"result"`
+	Detail  string        `json:"detail,omitempty"`
+	Latency time.Duration `json:"-"`
+}
+
+// validationEndpoint is the cheapest authenticated call a provider offers
+// (usually listing models), used to confirm an API key actually works.
+type validationEndpoint struct {
+	envVars []string // Sources that hold a plain API key
+	url     string
+	auth    func(req *http.Request, key string)
+}
+
+func bearerAuth(req *http.Request, key string) {
+	req.Header.Set("Authorization", "Bearer "+key)
+}
+
+// validationEndpoints maps providers to their validation call. Providers not
+// listed here (OAuth-only CLIs, opencode) are reported as not validated.
+var validationEndpoints = map[string]validationEndpoint{
+	"anthropic": {
+		envVars: []string{"ANTHROPIC_API_KEY"},
+		url:     "https://api.anthropic.com/v1/models?limit=1",
+		auth: func(req *http.Request, key string) {
+			req.Header.Set("x-api-key", key)
+			req.Header.Set("anthropic-version", "2023-06-01")
+		},
+	},
+	"openai": {
+		envVars: []string{"OPENAI_API_KEY"},
+		url:     "https://api.openai.com/v1/models",
+		auth:    bearerAuth,
+	},
+	"google": {
+		envVars: []string{"GEMINI_API_KEY", "GOOGLE_API_KEY"},
+		url:     "https://generativelanguage.googleapis.com/v1beta/models?pageSize=1",
+		auth: func(req *http.Request, key string) {
+			req.Header.Set("x-goog-api-key", key)
+		},
+	},
+	"qwen": {
+		envVars: []string{"MODEL_STUDIO_API_KEY", "DASHSCOPE_API_KEY"},
+		url:     "https://dashscope-intl.aliyuncs.com/compatible-mode/v1/models",
+		auth:    bearerAuth,
+	},
+	"xai": {
+		envVars: []string{"XAI_API_KEY"},
+		url:     "https://api.x.ai/v1/models",
+		auth:    bearerAuth,
+	},
+	"deepseek": {
+		envVars: []string{"DEEPSEEK_API_KEY"},
+		url:     "https://api.deepseek.com/user/balance",
+		auth:    bearerAuth,
+	},
+	"groq": {
+		envVars: []string{"GROQ_API_KEY"},
+		url:     "https://api.groq.com/openai/v1/models",
+		auth:    bearerAuth,
+	},
+	"openrouter": {
+		envVars: []string{"OPENROUTER_API_KEY"},
+		url:     "https://openrouter.ai/api/v1/key",
+		auth:    bearerAuth,
+	},
+}
+
+// ValidateProvider makes one authenticated request with the local credential
+// behind status. Only env var API keys are validated; OAuth tokens and CLI
+// config files are reported as not validated.
+func ValidateProvider(ctx context.Context, client *http.Client, provider string, status ProviderStatus) KeyCheck {
+	if !status.Available {
+		return KeyCheck{Result: KeyMissing, Detail: "no local credentials found"}
+	}
+
+	endpoint, ok := validationEndpoints[provider]
+	envVar, isEnv := strings.CutPrefix(status.Source, "env:")
+	if !ok || !isEnv || !slices.Contains(endpoint.envVars, envVar) {
+		return KeyCheck{Result: KeyNotValidated, Detail: "no live check for " + status.Source}
+	}
+	key := os.Getenv(envVar)
+	if key == "" {
+		return KeyCheck{Result: KeyMissing, Detail: envVar + " is empty"}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.url, nil)
+	if err != nil {
+		return KeyCheck{Result: KeyError, Detail: err.Error()}
+	}
+	endpoint.auth(req, key)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return KeyCheck{Result: KeyError, Detail: err.Error(), Latency: time.Since(start)}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	check := classifyValidationResponse(resp.StatusCode, body)
+	check.Latency = time.Since(start)
+	return check
+}
+
+// classifyValidationResponse maps a provider's HTTP response to a key check
+// result. Providers disagree on status codes for exhausted credit (402, 403,
+// 429), so the body is checked for quota wording before falling back to the
+// status code. A bare 429 is a rate limit: the key worked, it was just
+// throttled.
+func classifyValidationResponse(statusCode int, body []byte) KeyCheck {
+	if statusCode >= 200 && statusCode < 300 {
+		return KeyCheck{Result: KeyValid}
+	}
+
+	detail := fmt.Sprintf("HTTP %d", statusCode)
+	lower := strings.ToLower(string(body))
+	if statusCode >= 400 && statusCode < 500 {
+		for _, marker := range []string{"quota", "credit", "billing", "balance"} {
+			if strings.Contains(lower, marker) {
+				return KeyCheck{Result: KeyQuotaExhausted, Detail: detail}
+			}
+		}
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return KeyCheck{Result: KeyInvalid, Detail: detail}
+	case http.StatusBadRequest:
+		// Google reports malformed keys as 400 API_KEY_INVALID
+		if strings.Contains(lower, "api_key_invalid") || strings.Contains(lower, "api key not valid") {
+			return KeyCheck{Result: KeyInvalid, Detail: detail}
+		}
+	case http.StatusPaymentRequired:
+		return KeyCheck{Result: KeyQuotaExhausted, Detail: detail}
+	case http.StatusTooManyRequests:
+		return KeyCheck{Result: KeyRateLimited, Detail: detail}
+	}
+	return KeyCheck{Result: KeyError, Detail: detail}
+}
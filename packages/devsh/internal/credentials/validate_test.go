@@ -0,0 +1,80 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyValidationResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"ok", 200, `{"data":[]}`, KeyValid},
+		{"unauthorized", 401, `{"error":{"message":"Incorrect API key provided"}}`, KeyInvalid},
+		{"forbidden", 403, `{"error":"forbidden"}`, KeyInvalid},
+		{"openai insufficient quota", 429, `{"error":{"code":"insufficient_quota"}}`, KeyQuotaExhausted},
+		{"rate limited", 429, `{"error":{"code":"rate_limit_exceeded"}}`, KeyRateLimited},
+		{"payment required", 402, `{"error":"Insufficient Balance"}`, KeyQuotaExhausted},
+		{"credit via 400", 400, `{"error":{"message":"Your credit balance is too low"}}`, KeyQuotaExhausted},
+		{"google invalid key", 400, `{"error":{"status":"INVALID_ARGUMENT","details":[{"reason":"API_KEY_INVALID"}]}}`, KeyInvalid},
+		{"server error", 500, `{"error":"quota service down"}`, KeyError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyValidationResponse(tt.status, []byte(tt.body))
+			if got.Result != tt.want {
+				t.Errorf("classifyValidationResponse(%d) = %q, want %q", tt.status, got.Result, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if gotAuth != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	saved := validationEndpoints["openai"]
+	t.Cleanup(func() { validationEndpoints["openai"] = saved })
+	endpoint := saved
+	endpoint.url = server.URL
+	validationEndpoints["openai"] = endpoint
+
+	ctx := context.Background()
+	status := ProviderStatus{Available: true, Source: "env:OPENAI_API_KEY"}
+
+	t.Setenv("OPENAI_API_KEY", "good-key")
+	if got := ValidateProvider(ctx, server.Client(), "openai", status); got.Result != KeyValid {
+		t.Errorf("good key: got %+v", got)
+	}
+	if gotAuth != "Bearer good-key" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "bad-key")
+	if got := ValidateProvider(ctx, server.Client(), "openai", status); got.Result != KeyInvalid {
+		t.Errorf("bad key: got %+v", got)
+	}
+
+	if got := ValidateProvider(ctx, server.Client(), "openai", ProviderStatus{}); got.Result != KeyMissing {
+		t.Errorf("missing key: got %+v", got)
+	}
+
+	codex := ProviderStatus{Available: true, Source: "~/.codex/auth.json"}
+	if got := ValidateProvider(ctx, server.Client(), "openai", codex); got.Result != KeyNotValidated {
+		t.Errorf("codex auth file: got %+v", got)
+	}
+}
@@ -0,0 +1,347 @@
+// Package docker runs the devbox image as a local Docker container, so the CLI
+// can be developed and tested end to end without Morph or PVE credentials.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultImage is the devbox image used when DEVSH_DOCKER_IMAGE and
+// WORKER_IMAGE_NAME are unset.
+const DefaultImage = "docker.io/karl8080/cmux:latest"
+
+// Service ports inside the devbox image.
+const (
+	PortWorker = 39376
+	PortVSCode = 39378
+	PortVNC    = 39380
+	PortXTerm  = 39383
+)
+
+// ServicePorts are published to ports Docker allocates on the host.
+var ServicePorts = []int{PortWorker, PortVSCode, PortVNC, PortXTerm}
+
+// managedLabel marks containers created by devsh so List ignores the rest.
+const managedLabel = "dev.cmux.devsh"
+
+// Instance is a devbox container.
+type Instance struct {
+	ID        string
+	Status    string // running, paused, stopped, created, ...
+	Image     string
+	VSCodeURL string
+	WorkerURL string
+	VNCURL    string
+	XTermURL  string
+	Ports     map[int]int // Container port -> host port
+}
+
+// StartOptions configures a new container.
+type StartOptions struct {
+	Image string // Defaults to the client image
+	Env   map[string]string
+}
+
+// runFunc runs the docker CLI and returns its output. A non-zero exit is
+// reported as an error with an ExitCode method.
+type runFunc func(ctx context.Context, args ...string) (stdout, stderr string, err error)
+
+// Client drives the local docker CLI.
+type Client struct {
+	image string
+	host  string // Host address published ports bind to
+	run   runFunc
+}
+
+// NewClientFromEnv creates a client for the docker CLI on PATH. The image
+// comes from DEVSH_DOCKER_IMAGE, then WORKER_IMAGE_NAME, then DefaultImage.
+func NewClientFromEnv() (*Client, error) {
+	bin, err := exec.LookPath("docker")
+	if err != nil {
+		return nil, fmt.Errorf("docker not found in PATH: install Docker to use the docker provider")
+	}
+
+	image := os.Getenv("DEVSH_DOCKER_IMAGE")
+	if image == "" {
+		image = os.Getenv("WORKER_IMAGE_NAME")
+	}
+	if image == "" {
+		image = DefaultImage
+	}
+
+	return &Client{image: image, host: "127.0.0.1", run: commandRunner(bin)}, nil
+}
+
+func commandRunner(bin string) runFunc {
+	return func(ctx context.Context, args ...string) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, bin, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+}
+
+// docker runs a docker subcommand, folding stderr into the error.
+func (c *Client) docker(ctx context.Context, args ...string) (string, error) {
+	stdout, stderr, err := c.run(ctx, args...)
+	if err != nil {
+		msg := strings.TrimSpace(stderr)
+		if msg == "" {
+			msg = err.Error()
+		}
+		if isNoSuchContainer(msg) {
+			return "", fmt.Errorf("instance not found: %s", msg)
+		}
+		return "", fmt.Errorf("docker %s failed: %s", args[0], msg)
+	}
+	return stdout, nil
+}
+
+func isDaemonError(stderr string) bool {
+	msg := strings.TrimSpace(stderr)
+	return strings.HasPrefix(msg, "Error response from daemon:") || isNoSuchContainer(msg)
+}
+
+func isNoSuchContainer(msg string) bool {
+	return strings.Contains(msg, "No such container") || strings.Contains(msg, "No such object")
+}
+
+// newInstanceID returns a container name that identifies the docker provider.
+func newInstanceID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "docker-" + hex.EncodeToString(b), nil
+}
+
+// StartInstance runs a new devbox container. The image runs systemd, so the
+// container needs the same privileges as scripts/docker-shell.sh.
+func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instance, error) {
+	id, err := newInstanceID()
+	if err != nil {
+		return nil, err
+	}
+	image := opts.Image
+	if image == "" {
+		image = c.image
+	}
+
+	args := []string{
+		"run", "-d",
+		"--name", id,
+		"--hostname", id,
+		"--label", managedLabel + "=1",
+		"--privileged",
+		"--cgroupns=host",
+		"--tmpfs", "/run",
+		"--tmpfs", "/run/lock",
+		"-v", "/sys/fs/cgroup:/sys/fs/cgroup:rw",
+	}
+	for _, port := range ServicePorts {
+		// An empty host port lets Docker allocate a free one.
+		args = append(args, "-p", fmt.Sprintf("%s::%d", c.host, port))
+	}
+	keys := make([]string, 0, len(opts.Env))
+	for k := range opts.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+opts.Env[k])
+	}
+	args = append(args, image)
+
+	if _, err := c.docker(ctx, args...); err != nil {
+		return nil, err
+	}
+	return c.GetInstance(ctx, id)
+}
+
+// inspectResult is the subset of docker inspect output we use.
+type inspectResult struct {
+	Name  string `json:"Name"`
+	State struct {
+		Status string `json:"Status"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+	} `json:"NetworkSettings"`
+}
+
+func (c *Client) inspect(ctx context.Context, ids ...string) ([]Instance, error) {
+	args := append([]string{"inspect", "--type", "container"}, ids...)
+	out, err := c.docker(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []inspectResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %w", err)
+	}
+
+	instances := make([]Instance, 0, len(results))
+	for _, r := range results {
+		if r.Config.Labels[managedLabel] == "" {
+			return nil, fmt.Errorf("container %s was not created by devsh", strings.TrimPrefix(r.Name, "/"))
+		}
+		instances = append(instances, c.toInstance(r))
+	}
+	return instances, nil
+}
+
+func (c *Client) toInstance(r inspectResult) Instance {
+	inst := Instance{
+		ID:     strings.TrimPrefix(r.Name, "/"),
+		Status: r.State.Status,
+		Image:  r.Config.Image,
+		Ports:  map[int]int{},
+	}
+	if inst.Status == "exited" || inst.Status == "dead" {
+		inst.Status = "stopped"
+	}
+
+	for spec, bindings := range r.NetworkSettings.Ports {
+		port, err := strconv.Atoi(strings.TrimSuffix(spec, "/tcp"))
+		if err != nil || len(bindings) == 0 {
+			continue
+		}
+		hostPort, err := strconv.Atoi(bindings[0].HostPort)
+		if err != nil {
+			continue
+		}
+		inst.Ports[port] = hostPort
+	}
+
+	inst.WorkerURL = c.serviceURL(inst.Ports, PortWorker)
+	inst.VSCodeURL = c.serviceURL(inst.Ports, PortVSCode)
+	inst.VNCURL = c.serviceURL(inst.Ports, PortVNC)
+	inst.XTermURL = c.serviceURL(inst.Ports, PortXTerm)
+	return inst
+}
+
+func (c *Client) serviceURL(ports map[int]int, port int) string {
+	hostPort, ok := ports[port]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(c.host, strconv.Itoa(hostPort)))
+}
+
+// GetInstance returns a container by ID.
+func (c *Client) GetInstance(ctx context.Context, instanceID string) (*Instance, error) {
+	instances, err := c.inspect(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("instance not found: %s", instanceID)
+	}
+	return &instances[0], nil
+}
+
+// ListInstances returns every container created by devsh.
+func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
+	out, err := c.docker(ctx, "ps", "-a", "--filter", "label="+managedLabel, "--format", "{{.Names}}")
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(out)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return c.inspect(ctx, ids...)
+}
+
+// StopInstance removes a container.
+func (c *Client) StopInstance(ctx context.Context, instanceID string) error {
+	_, err := c.docker(ctx, "rm", "-f", instanceID)
+	return err
+}
+
+// PauseInstance freezes a container's processes, keeping its memory.
+func (c *Client) PauseInstance(ctx context.Context, instanceID string) error {
+	_, err := c.docker(ctx, "pause", instanceID)
+	return err
+}
+
+// ResumeInstance unfreezes a paused container.
+func (c *Client) ResumeInstance(ctx context.Context, instanceID string) error {
+	_, err := c.docker(ctx, "unpause", instanceID)
+	return err
+}
+
+// ExecCommand runs a shell command in a container.
+func (c *Client) ExecCommand(ctx context.Context, instanceID string, command string) (string, string, int, error) {
+	stdout, stderr, err := c.run(ctx, "exec", instanceID, "sh", "-c", command)
+	if err == nil {
+		return stdout, stderr, 0, nil
+	}
+
+	// docker exec reports its own failures as daemon errors; any other
+	// non-zero exit is the command's exit status.
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && !isDaemonError(stderr) {
+		return stdout, stderr, exitErr.ExitCode(), nil
+	}
+	msg := strings.TrimSpace(stderr)
+	if msg == "" {
+		msg = err.Error()
+	}
+	if isNoSuchContainer(msg) {
+		return "", "", -1, fmt.Errorf("instance not found: %s", instanceID)
+	}
+	return "", "", -1, fmt.Errorf("docker exec failed: %s", msg)
+}
+
+// WaitForReady waits until the container is running and its worker port
+// accepts connections.
+func (c *Client) WaitForReady(ctx context.Context, instanceID string, timeout time.Duration) (*Instance, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		instance, err := c.GetInstance(ctx, instanceID)
+		if err != nil {
+			return nil, err
+		}
+		if instance.Status == "running" {
+			if hostPort, ok := instance.Ports[PortWorker]; ok {
+				addr := net.JoinHostPort(c.host, strconv.Itoa(hostPort))
+				if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+					conn.Close()
+					return instance, nil
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for instance to be ready")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+type fakeCall struct {
+	stdout string
+	stderr string
+	err    error
+}
+
+// fakeDocker records docker invocations and answers by subcommand.
+type fakeDocker struct {
+	calls   [][]string
+	answers map[string]fakeCall
+}
+
+func (f *fakeDocker) run(ctx context.Context, args ...string) (string, string, error) {
+	f.calls = append(f.calls, args)
+	a := f.answers[args[0]]
+	return a.stdout, a.stderr, a.err
+}
+
+func newFakeClient(answers map[string]fakeCall) (*Client, *fakeDocker) {
+	f := &fakeDocker{answers: answers}
+	return &Client{image: DefaultImage, host: "127.0.0.1", run: f.run}, f
+}
+
+const inspectJSON = `[{
+	"Name": "/docker-abc123",
+	"State": {"Status": "running"},
+	"Config": {"Image": "cmux:dev", "Labels": {"dev.cmux.devsh": "1"}},
+	"NetworkSettings": {"Ports": {
+		"39376/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49001"}],
+		"39378/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49002"}],
+		"39380/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49003"}],
+		"39383/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49004"}],
+		"22/tcp": null
+	}}
+}]`
+
+func TestStartInstancePublishesServicePorts(t *testing.T) {
+	client, f := newFakeClient(map[string]fakeCall{
+		"run":     {stdout: "0123456789ab\n"},
+		"inspect": {stdout: inspectJSON},
+	})
+
+	inst, err := client.StartInstance(context.Background(), StartOptions{
+		Image: "cmux:dev",
+		Env:   map[string]string{"B": "2", "A": "1"},
+	})
+	if err != nil {
+		t.Fatalf("StartInstance: %v", err)
+	}
+
+	run := strings.Join(f.calls[0], " ")
+	for _, want := range []string{
+		"-p 127.0.0.1::39376", "-p 127.0.0.1::39378", "-p 127.0.0.1::39380", "-p 127.0.0.1::39383",
+		"--label dev.cmux.devsh=1", "-e A=1 -e B=2 cmux:dev",
+	} {
+		if !strings.Contains(run, want) {
+			t.Errorf("docker run args %q missing %q", run, want)
+		}
+	}
+	if !strings.HasPrefix(f.calls[0][3], "docker-") {
+		t.Errorf("expected docker- container name, got %q", f.calls[0][3])
+	}
+
+	if inst.VSCodeURL != "http://127.0.0.1:49002" {
+		t.Errorf("VSCodeURL = %q", inst.VSCodeURL)
+	}
+	if inst.WorkerURL != "http://127.0.0.1:49001" || inst.VNCURL != "http://127.0.0.1:49003" || inst.XTermURL != "http://127.0.0.1:49004" {
+		t.Errorf("unexpected service URLs: %+v", inst)
+	}
+}
+
+func TestGetInstanceMapsStatus(t *testing.T) {
+	client, _ := newFakeClient(map[string]fakeCall{
+		"inspect": {stdout: strings.Replace(inspectJSON, `"running"`, `"exited"`, 1)},
+	})
+
+	inst, err := client.GetInstance(context.Background(), "docker-abc123")
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	if inst.ID != "docker-abc123" || inst.Status != "stopped" {
+		t.Errorf("got ID %q status %q", inst.ID, inst.Status)
+	}
+}
+
+func TestGetInstanceRejectsUnmanagedContainer(t *testing.T) {
+	client, _ := newFakeClient(map[string]fakeCall{
+		"inspect": {stdout: strings.Replace(inspectJSON, `"dev.cmux.devsh": "1"`, `"other": "1"`, 1)},
+	})
+
+	if _, err := client.GetInstance(context.Background(), "docker-abc123"); err == nil {
+		t.Fatal("expected error for a container devsh did not create")
+	}
+}
+
+func TestGetInstanceNotFound(t *testing.T) {
+	client, _ := newFakeClient(map[string]fakeCall{
+		"inspect": {stderr: "Error: No such container: docker-gone\n", err: exitError(1)},
+	})
+
+	_, err := client.GetInstance(context.Background(), "docker-gone")
+	if err == nil || !strings.Contains(err.Error(), "instance not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestListInstancesEmpty(t *testing.T) {
+	client, f := newFakeClient(map[string]fakeCall{"ps": {stdout: "\n"}})
+
+	instances, err := client.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	if len(instances) != 0 {
+		t.Errorf("expected no instances, got %d", len(instances))
+	}
+	if len(f.calls) != 1 {
+		t.Errorf("expected no inspect call, got %v", f.calls)
+	}
+}
+
+func TestExecCommandExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     fakeCall
+		wantCode int
+		wantErr  bool
+	}{
+		{"success", fakeCall{stdout: "ok\n"}, 0, false},
+		{"command fails", fakeCall{stderr: "Error: tests failed\n", err: exitError(2)}, 2, false},
+		{"daemon error", fakeCall{stderr: "Error response from daemon: container is paused\n", err: exitError(1)}, -1, true},
+		{"docker missing", fakeCall{err: errors.New("exec: not started")}, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, f := newFakeClient(map[string]fakeCall{"exec": tt.call})
+			_, _, code, err := client.ExecCommand(context.Background(), "docker-abc123", "make test")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if got := strings.Join(f.calls[0], " "); got != "exec docker-abc123 sh -c make test" {
+				t.Errorf("unexpected args %q", got)
+			}
+		})
+	}
+}
+
+func TestPauseResumeStop(t *testing.T) {
+	client, f := newFakeClient(map[string]fakeCall{})
+	ctx := context.Background()
+
+	if err := client.PauseInstance(ctx, "docker-abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ResumeInstance(ctx, "docker-abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StopInstance(ctx, "docker-abc123"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"pause docker-abc123", "unpause docker-abc123", "rm -f docker-abc123"}
+	for i, w := range want {
+		if got := strings.Join(f.calls[i], " "); got != w {
+			t.Errorf("call %d = %q, want %q", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// sandbox_provider.go implements the provider.SandboxProvider interface for local Docker.
+package docker
+
+import (
+	"context"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+)
+
+// Ensure DockerProvider implements provider.SandboxProvider at compile time.
+var (
+	_ provider.SandboxProvider = (*DockerProvider)(nil)
+	_ provider.Lifecycle       = (*DockerProvider)(nil)
+)
+
+// DockerProvider wraps docker.Client to implement provider.SandboxProvider.
+type DockerProvider struct {
+	client *Client
+}
+
+// NewProvider creates a new Docker sandbox provider.
+func NewProvider() (*DockerProvider, error) {
+	client, err := NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &DockerProvider{client: client}, nil
+}
+
+// NewProviderWithClient creates a new Docker sandbox provider with an existing client.
+func NewProviderWithClient(client *Client) *DockerProvider {
+	return &DockerProvider{client: client}
+}
+
+// Name returns the provider identifier.
+func (p *DockerProvider) Name() string {
+	return provider.Docker
+}
+
+// Create starts a new devbox container. Template overrides the image.
+func (p *DockerProvider) Create(ctx context.Context, opts provider.CreateOptions) (*provider.Sandbox, error) {
+	instance, err := p.client.StartInstance(ctx, StartOptions{
+		Image: opts.Template,
+		Env:   opts.Environment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceToSandbox(instance), nil
+}
+
+// Get retrieves a sandbox by ID.
+func (p *DockerProvider) Get(ctx context.Context, id string) (*provider.Sandbox, error) {
+	instance, err := p.client.GetInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return instanceToSandbox(instance), nil
+}
+
+// Delete removes the container.
+func (p *DockerProvider) Delete(ctx context.Context, id string) error {
+	return p.client.StopInstance(ctx, id)
+}
+
+// Exec executes a command in a sandbox.
+func (p *DockerProvider) Exec(ctx context.Context, id string, command string) (*provider.ExecResult, error) {
+	stdout, stderr, exitCode, err := p.client.ExecCommand(ctx, id, command)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.ExecResult{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: exitCode,
+	}, nil
+}
+
+// List returns sandboxes matching the given options.
+func (p *DockerProvider) List(ctx context.Context, opts provider.ListOptions) ([]provider.Sandbox, error) {
+	instances, err := p.client.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxes := make([]provider.Sandbox, 0, len(instances))
+	for _, inst := range instances {
+		// Apply status filter if specified
+		if opts.Status != "" && inst.Status != opts.Status {
+			continue
+		}
+		sandboxes = append(sandboxes, *instanceToSandbox(&inst))
+
+		// Apply limit if specified
+		if opts.Limit > 0 && len(sandboxes) >= opts.Limit {
+			break
+		}
+	}
+
+	return sandboxes, nil
+}
+
+// WaitReady blocks until the sandbox is ready or timeout.
+func (p *DockerProvider) WaitReady(ctx context.Context, id string, timeout time.Duration) (*provider.Sandbox, error) {
+	instance, err := p.client.WaitForReady(ctx, id, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return instanceToSandbox(instance), nil
+}
+
+// Pause freezes the container, preserving its state.
+func (p *DockerProvider) Pause(ctx context.Context, id string) error {
+	return p.client.PauseInstance(ctx, id)
+}
+
+// Resume unfreezes the container and waits for it to be ready.
+func (p *DockerProvider) Resume(ctx context.Context, id string) (*provider.Sandbox, error) {
+	if err := p.client.ResumeInstance(ctx, id); err != nil {
+		return nil, err
+	}
+	return p.WaitReady(ctx, id, 2*time.Minute)
+}
+
+// instanceToSandbox converts a docker.Instance to provider.Sandbox.
+func instanceToSandbox(inst *Instance) *provider.Sandbox {
+	return &provider.Sandbox{
+		ID:        inst.ID,
+		Status:    inst.Status,
+		VSCodeURL: inst.VSCodeURL,
+		VNCURL:    inst.VNCURL,
+		WorkerURL: inst.WorkerURL,
+		XTermURL:  inst.XTermURL,
+		Provider:  provider.Docker,
+	}
+}
@@ -9,7 +9,10 @@ import (
 )
 
 // Ensure E2BProvider implements provider.SandboxProvider at compile time.
-var _ provider.SandboxProvider = (*E2BProvider)(nil)
+var (
+	_ provider.SandboxProvider = (*E2BProvider)(nil)
+	_ provider.Lifecycle       = (*E2BProvider)(nil)
+)
 
 // E2BProvider wraps e2b.Client to implement provider.SandboxProvider.
 type E2BProvider struct {
@@ -116,6 +119,19 @@ func (p *E2BProvider) WaitReady(ctx context.Context, id string, timeout time.Dur
 	return instanceToSandbox(instance), nil
 }
 
+// Pause suspends a sandbox, preserving its state.
+func (p *E2BProvider) Pause(ctx context.Context, id string) error {
+	return p.client.PauseInstance(ctx, id)
+}
+
+// Resume restarts a paused sandbox and waits for it to be ready.
+func (p *E2BProvider) Resume(ctx context.Context, id string) (*provider.Sandbox, error) {
+	if err := p.client.ResumeInstance(ctx, id); err != nil {
+		return nil, err
+	}
+	return p.WaitReady(ctx, id, 2*time.Minute)
+}
+
 // instanceToSandbox converts an e2b.Instance to provider.Sandbox.
 func instanceToSandbox(inst *Instance) *provider.Sandbox {
 	return &provider.Sandbox{
@@ -124,6 +140,7 @@ func instanceToSandbox(inst *Instance) *provider.Sandbox {
 		VSCodeURL: inst.VSCodeURL,
 		VNCURL:    inst.VNCURL,
 		WorkerURL: inst.WorkerURL,
+		XTermURL:  inst.XTermURL,
 		Provider:  provider.E2B,
 	}
 }
@@ -0,0 +1,38 @@
+//go:build !unix
+
+// Package filelock serializes work across devsh processes with a lock file.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// staleLockAge is when a leftover lock file from a crashed process is
+// ignored.
+const staleLockAge = 2 * time.Minute
+
+// Lock creates path exclusively, waiting up to timeout for another
+// holder to remove it.
+func Lock(path string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
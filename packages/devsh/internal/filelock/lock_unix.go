@@ -0,0 +1,36 @@
+//go:build unix
+
+// Package filelock serializes work across devsh processes with a lock file.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Lock takes an exclusive flock on path, waiting up to timeout. The
+// lock is released by the returned func or when the process exits.
+func Lock(path string, timeout time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return func() {
+				_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				f.Close()
+			}, nil
+		}
+		if err != syscall.EWOULDBLOCK || time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
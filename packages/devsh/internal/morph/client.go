@@ -0,0 +1,180 @@
+// Package morph is a minimal client for the Morph Cloud API. Most of devsh
+// reaches Morph through the cmux API (internal/vm); this package is for
+// tooling that holds a MORPH_API_KEY and needs Morph-level features such as
+// instance metadata, for example to tag instances with the workspace or team
+// they belong to and find them again during reconcile or GC.
+//
+// The Morph API takes a different key and base URL from the cmux API, so
+// this is a separate client rather than more methods on vm.Client, but it
+// shares vm's retry policy (vm.DoWithRetry) and error model (vm.APIError).
+package morph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+// DefaultBaseURL is the Morph API used when MORPH_API_URL is unset
+const DefaultBaseURL = "https://cloud.morph.so/api"
+
+// BaseURL returns the Morph API base, overridable with MORPH_API_URL
+func BaseURL() string {
+	if v := os.Getenv("MORPH_API_URL"); v != "" {
+		return strings.TrimSuffix(v, "/")
+	}
+	return DefaultBaseURL
+}
+
+// APIInstance is the subset of Morph's instance model devsh uses
+type APIInstance struct {
+	ID       string            `json:"id"`
+	Status   string            `json:"status"` // pending, ready, paused, saving, error
+	Created  int64             `json:"created"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// APIError is a non-2xx response from the Morph API. Use errors.Is with the
+// vm.Err* sentinels to branch on the kind of failure.
+type APIError = vm.APIError
+
+// APIClient calls the Morph API with an API key
+type APIClient struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+// NewAPIClient creates a client for apiKey against BaseURL()
+func NewAPIClient(apiKey string) *APIClient {
+	return &APIClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    BaseURL(),
+		apiKey:     apiKey,
+	}
+}
+
+// NewAPIClientFromEnv creates a client using MORPH_API_KEY
+func NewAPIClientFromEnv() (*APIClient, error) {
+	key := os.Getenv("MORPH_API_KEY")
+	if key == "" {
+		return nil, errors.New("MORPH_API_KEY is not set")
+	}
+	return NewAPIClient(key), nil
+}
+
+func (c *APIClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+
+	resp, err := vm.DoWithRetry(ctx, method, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return c.httpClient.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		op, _, _ := strings.Cut(path, "?")
+		return vm.NewAPIError(resp, "morph API "+method+" "+op)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode morph API response: %w", err)
+	}
+	return nil
+}
+
+// GetInstance returns one instance
+func (c *APIClient) GetInstance(ctx context.Context, id string) (*APIInstance, error) {
+	var inst APIInstance
+	if err := c.do(ctx, http.MethodGet, "/instance/"+url.PathEscape(id), nil, &inst); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+// SetInstanceMetadata writes metadata keys on an instance and returns the
+// instance as Morph stores it afterwards.
+func (c *APIClient) SetInstanceMetadata(ctx context.Context, id string, metadata map[string]string) (*APIInstance, error) {
+	if len(metadata) == 0 {
+		return nil, errors.New("metadata must not be empty")
+	}
+	var inst APIInstance
+	if err := c.do(ctx, http.MethodPost, "/instance/"+url.PathEscape(id)+"/metadata", metadata, &inst); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+// GetInstancesByMetadata returns the instances whose metadata contains every
+// key/value in selector. Matches are re-checked locally so GC callers never
+// act on an instance the server returned for a looser query. An empty
+// selector is rejected rather than treated as "all instances".
+func (c *APIClient) GetInstancesByMetadata(ctx context.Context, selector map[string]string) ([]APIInstance, error) {
+	if len(selector) == 0 {
+		return nil, errors.New("metadata selector must not be empty")
+	}
+
+	keys := make([]string, 0, len(selector))
+	for k := range selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	query := url.Values{}
+	for _, k := range keys {
+		query.Set("metadata["+k+"]", selector[k])
+	}
+
+	var list struct {
+		Data []APIInstance `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/instance?"+query.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+
+	matched := make([]APIInstance, 0, len(list.Data))
+	for _, inst := range list.Data {
+		if MatchesMetadata(inst.Metadata, selector) {
+			matched = append(matched, inst)
+		}
+	}
+	return matched, nil
+}
+
+// MatchesMetadata reports whether metadata contains every key/value in selector
+func MatchesMetadata(metadata, selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,267 @@
+package morph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/vm"
+)
+
+func newTestAPIClient(t *testing.T, handler http.HandlerFunc) *APIClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Setenv("MORPH_API_URL", srv.URL+"/")
+	return NewAPIClient("test-key")
+}
+
+func TestSetInstanceMetadata(t *testing.T) {
+	client := newTestAPIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/instance/morphvm_1/metadata" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(APIInstance{ID: "morphvm_1", Status: "ready", Metadata: body})
+	})
+
+	inst, err := client.SetInstanceMetadata(context.Background(), "morphvm_1", map[string]string{"workspace": "ws_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Metadata["workspace"] != "ws_1" {
+		t.Errorf("metadata = %v", inst.Metadata)
+	}
+
+	if _, err := client.SetInstanceMetadata(context.Background(), "morphvm_1", nil); err == nil {
+		t.Error("expected error for empty metadata")
+	}
+}
+
+func TestGetInstancesByMetadata(t *testing.T) {
+	client := newTestAPIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/instance" || q.Get("metadata[team]") != "acme" || q.Get("metadata[workspace]") != "ws_1" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []APIInstance{
+				{ID: "a", Metadata: map[string]string{"team": "acme", "workspace": "ws_1", "app": "cmux"}},
+				{ID: "b", Metadata: map[string]string{"team": "acme"}},
+			},
+		})
+	})
+
+	insts, err := client.GetInstancesByMetadata(context.Background(), map[string]string{"team": "acme", "workspace": "ws_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insts) != 1 || insts[0].ID != "a" {
+		t.Errorf("instances = %+v, want only a", insts)
+	}
+
+	if _, err := client.GetInstancesByMetadata(context.Background(), nil); err == nil {
+		t.Error("expected error for empty selector")
+	}
+}
+
+func TestAPIErrorStatus(t *testing.T) {
+	client := newTestAPIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"detail":"not found"}`, http.StatusNotFound)
+	})
+
+	_, err := client.GetInstance(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want 404 APIError", err)
+	}
+	if !errors.Is(err, vm.ErrNotFound) {
+		t.Errorf("errors.Is(%v, vm.ErrNotFound) = false", err)
+	}
+}
+
+func TestNewAPIClientFromEnvRequiresKey(t *testing.T) {
+	t.Setenv("MORPH_API_KEY", "")
+	if _, err := NewAPIClientFromEnv(); err == nil {
+		t.Error("expected error without MORPH_API_KEY")
+	}
+}
+
+func TestDeleteAndTagSnapshot(t *testing.T) {
+	var deleted string
+	client := newTestAPIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/snapshot/snapshot_1":
+			deleted = "snapshot_1"
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/snapshot/snapshot_1/metadata":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			json.NewEncoder(w).Encode(APISnapshot{ID: "snapshot_1", Metadata: body})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	snap, err := client.SetSnapshotMetadata(context.Background(), "snapshot_1", map[string]string{"channel": "stable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Metadata["channel"] != "stable" {
+		t.Errorf("metadata = %v", snap.Metadata)
+	}
+	if err := client.DeleteSnapshot(context.Background(), "snapshot_1"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted != "snapshot_1" {
+		t.Error("expected DELETE /snapshot/snapshot_1")
+	}
+}
+
+func TestListSnapshotsFiltersBySelector(t *testing.T) {
+	client := newTestAPIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/snapshot" || r.URL.Query().Get("metadata[template]") != "base" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []APISnapshot{
+				{ID: "a", Metadata: map[string]string{"template": "base"}},
+				{ID: "b", Metadata: map[string]string{"template": "other"}},
+			},
+		})
+	})
+
+	snaps, err := client.ListSnapshots(context.Background(), map[string]string{"template": "base"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 || snaps[0].ID != "a" {
+		t.Errorf("snapshots = %+v, want only a", snaps)
+	}
+}
+
+func TestPlanSnapshotPrune(t *testing.T) {
+	now := time.Unix(100*86400, 0)
+	day := int64(86400)
+	snaps := []APISnapshot{
+		{ID: "d1", Created: now.Unix() - 1*day},
+		{ID: "d60", Created: now.Unix() - 60*day},
+		{ID: "d10", Created: now.Unix() - 10*day},
+		{ID: "d40", Created: now.Unix() - 40*day},
+		{ID: "d90", Created: now.Unix() - 90*day},
+	}
+
+	tests := []struct {
+		name string
+		opts PruneOptions
+		want []string
+	}{
+		{name: "keep last", opts: PruneOptions{KeepLast: 3}, want: []string{"d90", "d60"}},
+		{name: "older than", opts: PruneOptions{OlderThan: 30 * 24 * time.Hour}, want: []string{"d90", "d60", "d40"}},
+		{name: "both", opts: PruneOptions{KeepLast: 4, OlderThan: 30 * 24 * time.Hour}, want: []string{"d90"}},
+		{name: "keep more than exist", opts: PruneOptions{KeepLast: 10}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prune, err := PlanSnapshotPrune(snaps, tt.opts, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range prune {
+				got = append(got, s.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("prune = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := PlanSnapshotPrune(snaps, PruneOptions{}, now); err == nil {
+		t.Error("expected error without keep-last or older-than")
+	}
+}
+
+func TestPlanSnapshotPruneSkipsProtectedAndOtherDigests(t *testing.T) {
+	now := time.Unix(100*86400, 0)
+	day := int64(86400)
+	snaps := []APISnapshot{
+		{ID: "published", Created: now.Unix() - 90*day, Digest: "base"},
+		{ID: "old", Created: now.Unix() - 60*day, Digest: "base"},
+		{ID: "other", Created: now.Unix() - 50*day, Digest: "gpu"},
+		{ID: "new", Created: now.Unix() - 1*day, Digest: "base"},
+	}
+	prune, err := PlanSnapshotPrune(snaps, PruneOptions{
+		KeepLast:  1,
+		Digest:    "base",
+		Protected: map[string]bool{"published": true},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prune) != 1 || prune[0].ID != "old" {
+		t.Errorf("prune = %+v, want only old", prune)
+	}
+}
+
+func TestPublishedSnapshotIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "morph-snapshots.json")
+	manifest := `{"schemaVersion":1,"presets":[{"presetId":"a","versions":[{"version":1,"snapshotId":"snapshot_a1"},{"version":2,"snapshotId":"snapshot_a2"}]},{"presetId":"b","versions":[{"version":1,"snapshotId":"snapshot_b1"}]}]}`
+	if err := os.WriteFile(path, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := PublishedSnapshotIDs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || !ids["snapshot_a1"] || !ids["snapshot_a2"] || !ids["snapshot_b1"] {
+		t.Errorf("ids = %v", ids)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"presets":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PublishedSnapshotIDs(path); err == nil {
+		t.Error("expected error for a manifest without snapshot IDs")
+	}
+}
+
+func TestFindSnapshotManifestWalksUp(t *testing.T) {
+	root := t.TempDir()
+	want := filepath.Join(root, SnapshotManifestRelPath)
+	if err := os.MkdirAll(filepath.Dir(want), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(want, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "packages", "devsh")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	t.Setenv(SnapshotManifestEnvVar, "")
+	if got, err := FindSnapshotManifest(); err != nil || got != want {
+		t.Errorf("FindSnapshotManifest() = %q, %v; want %q", got, err, want)
+	}
+	t.Setenv(SnapshotManifestEnvVar, "/etc/morph-snapshots.json")
+	if got, _ := FindSnapshotManifest(); got != "/etc/morph-snapshots.json" {
+		t.Errorf("FindSnapshotManifest() = %q, want the %s path", got, SnapshotManifestEnvVar)
+	}
+}
@@ -0,0 +1,117 @@
+package morph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// APISnapshot is the subset of Morph's snapshot model devsh uses
+type APISnapshot struct {
+	ID       string            `json:"id"`
+	Created  int64             `json:"created"` // Unix seconds
+	Digest   string            `json:"digest,omitempty"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// CreatedAt returns the snapshot's creation time
+func (s APISnapshot) CreatedAt() time.Time {
+	return time.Unix(s.Created, 0)
+}
+
+// ListSnapshots returns the snapshots whose metadata contains every
+// key/value in selector, or every snapshot if selector is empty.
+func (c *APIClient) ListSnapshots(ctx context.Context, selector map[string]string) ([]APISnapshot, error) {
+	path := "/snapshot"
+	if len(selector) > 0 {
+		query := url.Values{}
+		for k, v := range selector {
+			query.Set("metadata["+k+"]", v)
+		}
+		path += "?" + query.Encode()
+	}
+
+	var list struct {
+		Data []APISnapshot `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+
+	matched := make([]APISnapshot, 0, len(list.Data))
+	for _, snap := range list.Data {
+		if MatchesMetadata(snap.Metadata, selector) {
+			matched = append(matched, snap)
+		}
+	}
+	return matched, nil
+}
+
+// DeleteSnapshot deletes a snapshot. Morph refuses to delete a snapshot
+// that running instances were started from.
+func (c *APIClient) DeleteSnapshot(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/snapshot/"+url.PathEscape(id), nil, nil)
+}
+
+// SetSnapshotMetadata writes metadata keys on a snapshot and returns the
+// snapshot as Morph stores it afterwards. An empty value clears a key.
+func (c *APIClient) SetSnapshotMetadata(ctx context.Context, id string, metadata map[string]string) (*APISnapshot, error) {
+	if len(metadata) == 0 {
+		return nil, errors.New("metadata must not be empty")
+	}
+	var snap APISnapshot
+	if err := c.do(ctx, http.MethodPost, "/snapshot/"+url.PathEscape(id)+"/metadata", metadata, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// PruneOptions selects snapshots for PlanSnapshotPrune. At least one of
+// KeepLast or OlderThan must be set.
+type PruneOptions struct {
+	KeepLast  int             // Always keep this many of the newest snapshots
+	OlderThan time.Duration   // Only prune snapshots older than this
+	Digest    string          // Only prune snapshots with this digest
+	Protected map[string]bool // Never prune these IDs (see PublishedSnapshotIDs)
+}
+
+// PlanSnapshotPrune returns the snapshots to delete, oldest first.
+// Protected snapshots and those not matching Digest are set aside first.
+// Of the rest, the KeepLast newest are always kept, and those younger than
+// OlderThan are kept too.
+func PlanSnapshotPrune(snapshots []APISnapshot, opts PruneOptions, now time.Time) ([]APISnapshot, error) {
+	if opts.KeepLast < 0 || opts.OlderThan < 0 {
+		return nil, errors.New("keep-last and older-than must not be negative")
+	}
+	if opts.KeepLast == 0 && opts.OlderThan == 0 {
+		return nil, errors.New("refusing to prune every snapshot: set keep-last or older-than")
+	}
+
+	var sorted []APISnapshot
+	for _, snap := range snapshots {
+		if opts.Protected[snap.ID] || (opts.Digest != "" && snap.Digest != opts.Digest) {
+			continue
+		}
+		sorted = append(sorted, snap)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Created > sorted[j].Created })
+
+	var prune []APISnapshot
+	for i, snap := range sorted {
+		if i < opts.KeepLast {
+			continue
+		}
+		if opts.OlderThan > 0 && now.Sub(snap.CreatedAt()) < opts.OlderThan {
+			continue
+		}
+		prune = append(prune, snap)
+	}
+	// Oldest first, so an interrupted prune leaves the newest behind
+	for i, j := 0, len(prune)-1; i < j; i, j = i+1, j-1 {
+		prune[i], prune[j] = prune[j], prune[i]
+	}
+	return prune, nil
+}
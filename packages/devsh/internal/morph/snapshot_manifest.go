@@ -0,0 +1,76 @@
+package morph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SnapshotManifestEnvVar points at morph-snapshots.json, for binaries that
+// run outside a repo checkout.
+const SnapshotManifestEnvVar = "CMUX_MORPH_SNAPSHOT_MANIFEST"
+
+// SnapshotManifestRelPath is where the manifest of published snapshots
+// lives relative to the repo root.
+var SnapshotManifestRelPath = filepath.Join("packages", "shared", "src", "morph-snapshots.json")
+
+// snapshotManifest is the part of morph-snapshots.json devsh reads
+type snapshotManifest struct {
+	Presets []struct {
+		Versions []struct {
+			SnapshotID string `json:"snapshotId"`
+		} `json:"versions"`
+	} `json:"presets"`
+}
+
+// FindSnapshotManifest returns the manifest named by
+// CMUX_MORPH_SNAPSHOT_MANIFEST, or else the one found by walking up from the
+// working directory.
+func FindSnapshotManifest() (string, error) {
+	if path := strings.TrimSpace(os.Getenv(SnapshotManifestEnvVar)); path != "" {
+		return path, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(wd, SnapshotManifestRelPath)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		next := filepath.Dir(wd)
+		if next == wd {
+			return "", fmt.Errorf("%s not found (run from the repo or set %s)", filepath.Base(SnapshotManifestRelPath), SnapshotManifestEnvVar)
+		}
+		wd = next
+	}
+}
+
+// PublishedSnapshotIDs returns every snapshot ID listed in the manifest at
+// path. These back the presets users boot from and must never be pruned.
+func PublishedSnapshotIDs(path string) (map[string]bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest snapshotManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest %s: %w", path, err)
+	}
+	ids := make(map[string]bool)
+	for _, preset := range manifest.Presets {
+		for _, version := range preset.Versions {
+			if version.SnapshotID != "" {
+				ids[version.SnapshotID] = true
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("invalid snapshot manifest " + path + ": no snapshot IDs")
+	}
+	return ids, nil
+}
@@ -15,6 +15,10 @@ const (
 	Morph  = "morph"
 	PveLxc = "pve-lxc"
 	E2B    = "e2b"
+
+	// Docker runs the devbox image locally. It is registered by the sandbox
+	// package rather than built into NormalizeProvider.
+	Docker = "docker"
 )
 
 var (
@@ -22,6 +26,7 @@ var (
 	reCmuxVmid = regexp.MustCompile(`^cmux-\d+$`)
 	reMorphID  = regexp.MustCompile(`^(cmux|manaflow)_[a-z0-9]+$`)
 	reE2BID    = regexp.MustCompile(`^sb[a-z0-9]+$`) // E2B sandbox IDs start with "sb"
+	reDockerID = regexp.MustCompile(`^docker-[a-f0-9]+$`)
 )
 
 func HasPveEnv() bool {
@@ -33,9 +38,22 @@ func HasE2BEnv() bool {
 	return os.Getenv("E2B_API_KEY") != ""
 }
 
+// ConfiguredProvider returns the provider set with DEVSH_PROVIDER, or "" if
+// it is unset or not a known provider.
+func ConfiguredProvider() string {
+	configured, err := NormalizeProvider(os.Getenv("DEVSH_PROVIDER"))
+	if err != nil {
+		return ""
+	}
+	return configured
+}
+
 // DetectFromEnv selects a provider based on environment variables.
-// Priority: PVE-LXC (cheapest) > E2B > Morph (fallback).
+// Priority: DEVSH_PROVIDER > PVE-LXC (cheapest) > E2B > Morph (fallback).
 func DetectFromEnv() string {
+	if configured := ConfiguredProvider(); configured != "" {
+		return configured
+	}
 	if HasPveEnv() {
 		return PveLxc
 	}
@@ -45,7 +63,8 @@ func DetectFromEnv() string {
 	return Morph
 }
 
-// NormalizeProvider normalizes and validates a provider string.
+// NormalizeProvider normalizes and validates a provider string. Besides the
+// built-in providers, any name passed to Register is accepted.
 // Returns "" for empty input (caller may use auto-detection).
 func NormalizeProvider(value string) (string, error) {
 	v := strings.TrimSpace(strings.ToLower(value))
@@ -60,9 +79,22 @@ func NormalizeProvider(value string) (string, error) {
 		return PveLxc, nil
 	case E2B:
 		return E2B, nil
-	default:
-		return "", fmt.Errorf("unknown provider %q (expected %q, %q, or %q)", value, Morph, PveLxc, E2B)
 	}
+	if IsRegistered(v) {
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown provider %q (expected one of: %s)", value, strings.Join(knownProviders(), ", "))
+}
+
+// knownProviders returns the built-in and registered provider names.
+func knownProviders() []string {
+	names := []string{Morph, PveLxc, E2B}
+	for _, name := range Registered() {
+		if name != Morph && name != PveLxc && name != E2B {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
 // IsPveLxcInstanceID returns true if the instance ID looks like a PVE LXC instance.
@@ -98,6 +130,13 @@ func IsE2BInstanceID(id string) bool {
 	return reE2BID.MatchString(normalized)
 }
 
+// IsDockerInstanceID returns true if the instance ID looks like a local Docker devbox.
+// Expected format: "docker-<hex>".
+func IsDockerInstanceID(id string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(id))
+	return reDockerID.MatchString(normalized)
+}
+
 // ProviderForInstanceID infers provider from an instance ID, falling back to env detection.
 func ProviderForInstanceID(instanceID string) string {
 	if IsPveLxcInstanceID(instanceID) {
@@ -109,6 +148,9 @@ func ProviderForInstanceID(instanceID string) string {
 	if IsE2BInstanceID(instanceID) {
 		return E2B
 	}
+	if IsDockerInstanceID(instanceID) {
+		return Docker
+	}
 	return DetectFromEnv()
 }
 
@@ -124,6 +166,7 @@ type Sandbox struct {
 	VNCURL    string `json:"vncUrl"`    // VNC access URL
 	WorkerURL string `json:"workerUrl"` // Worker/API URL
 	ChromeURL string `json:"chromeUrl"` // Chrome DevTools URL
+	XTermURL  string `json:"xtermUrl"`  // Web terminal URL
 	Provider  string `json:"provider"`  // Provider name (morph, pve-lxc, e2b)
 }
 
@@ -198,3 +241,34 @@ type StreamExecer interface {
 	// ExecStream executes a command and streams output to the provided writers.
 	ExecStream(ctx context.Context, id string, command string, stdout, stderr func(line string)) error
 }
+
+// Lifecycle is an optional interface for providers that can pause a sandbox
+// and later resume it with its state intact.
+type Lifecycle interface {
+	// Pause suspends a running sandbox.
+	Pause(ctx context.Context, id string) error
+
+	// Resume restarts a paused sandbox and returns it once it is ready.
+	Resume(ctx context.Context, id string) (*Sandbox, error)
+}
+
+// URLResolver is an optional interface for providers whose service URLs need
+// more than Get to be usable, e.g. a one-time auth token.
+type URLResolver interface {
+	// URLs returns the sandbox with browser-ready service URLs.
+	URLs(ctx context.Context, id string) (*Sandbox, error)
+}
+
+// TeamScoped is implemented by providers that act on behalf of a cmux team.
+type TeamScoped interface {
+	SetTeamSlug(teamSlug string)
+}
+
+// URLs returns browser-ready service URLs for a sandbox, falling back to Get
+// for providers that don't implement URLResolver.
+func URLs(ctx context.Context, p SandboxProvider, id string) (*Sandbox, error) {
+	if r, ok := p.(URLResolver); ok {
+		return r.URLs(ctx, id)
+	}
+	return p.Get(ctx, id)
+}
@@ -217,6 +217,8 @@ func TestProviderForInstanceID(t *testing.T) {
 		{"123", PveLxc},
 		{"cmux_abc123", Morph},
 		{"manaflow_xyz", Morph},
+		{"docker-0a1b2c3d4e5f", Docker},
+		{"docker-notHex", Morph},
 		{"unknown-format", Morph}, // Fallback to env detection (morph without PVE env)
 	}
 
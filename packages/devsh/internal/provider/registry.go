@@ -0,0 +1,65 @@
+// registry.go lets sandbox backends register themselves by name so commands
+// can look them up without knowing about each implementation.
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory constructs a provider backend.
+type Factory func() (SandboxProvider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a backend available under name. It panics if name is empty
+// or already registered, since that is a programming error.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("provider: Register requires a name and a factory")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("provider: Register called twice for %q", name))
+	}
+	registry[name] = factory
+}
+
+// IsRegistered reports whether a backend is registered under name.
+func IsRegistered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// Registered returns the registered backend names in sorted order.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New constructs the backend registered under name.
+func New(name string) (SandboxProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return factory()
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct{ name string }
+
+func (f *fakeProvider) Name() string { return f.name }
+func (f *fakeProvider) Create(ctx context.Context, opts CreateOptions) (*Sandbox, error) {
+	return &Sandbox{ID: "created", Provider: f.name}, nil
+}
+func (f *fakeProvider) Get(ctx context.Context, id string) (*Sandbox, error) {
+	return &Sandbox{ID: id, VSCodeURL: "https://raw.example.com", Provider: f.name}, nil
+}
+func (f *fakeProvider) Delete(ctx context.Context, id string) error { return nil }
+func (f *fakeProvider) Exec(ctx context.Context, id string, command string) (*ExecResult, error) {
+	return &ExecResult{}, nil
+}
+func (f *fakeProvider) List(ctx context.Context, opts ListOptions) ([]Sandbox, error) {
+	return nil, nil
+}
+func (f *fakeProvider) WaitReady(ctx context.Context, id string, timeout time.Duration) (*Sandbox, error) {
+	return f.Get(ctx, id)
+}
+
+type fakeURLProvider struct{ fakeProvider }
+
+func (f *fakeURLProvider) URLs(ctx context.Context, id string) (*Sandbox, error) {
+	return &Sandbox{ID: id, VSCodeURL: "https://auth.example.com", Provider: f.name}, nil
+}
+
+// registerForTest registers a backend and removes it when the test ends.
+func registerForTest(t *testing.T, name string, factory Factory) {
+	t.Helper()
+	Register(name, factory)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	})
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	registerForTest(t, "fake", func() (SandboxProvider, error) {
+		return &fakeProvider{name: "fake"}, nil
+	})
+
+	if !IsRegistered("fake") {
+		t.Fatal("expected fake to be registered")
+	}
+	p, err := New("fake")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if p.Name() != "fake" {
+		t.Errorf("expected fake provider, got %q", p.Name())
+	}
+
+	found := false
+	for _, name := range Registered() {
+		if name == "fake" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Registered() = %v, want it to include fake", Registered())
+	}
+}
+
+func TestNewPropagatesFactoryError(t *testing.T) {
+	want := errors.New("not configured")
+	registerForTest(t, "broken", func() (SandboxProvider, error) {
+		return nil, want
+	})
+
+	if _, err := New("broken"); !errors.Is(err, want) {
+		t.Errorf("expected factory error, got %v", err)
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New("nope"); err == nil {
+		t.Error("expected error for unregistered provider")
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	factory := func() (SandboxProvider, error) { return &fakeProvider{name: "dup"}, nil }
+	registerForTest(t, "dup", factory)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	Register("dup", factory)
+}
+
+func TestNormalizeProviderAcceptsRegistered(t *testing.T) {
+	if _, err := NormalizeProvider("local-test"); err == nil {
+		t.Fatal("expected error before registration")
+	}
+
+	registerForTest(t, "local-test", func() (SandboxProvider, error) {
+		return &fakeProvider{name: "local-test"}, nil
+	})
+
+	got, err := NormalizeProvider("LOCAL_TEST")
+	if err != nil {
+		t.Fatalf("NormalizeProvider: %v", err)
+	}
+	if got != "local-test" {
+		t.Errorf("expected local-test, got %q", got)
+	}
+}
+
+func TestDetectFromEnvPrefersConfiguredProvider(t *testing.T) {
+	t.Setenv("PVE_API_URL", "http://test")
+	t.Setenv("PVE_API_TOKEN", "test-token")
+
+	t.Setenv("DEVSH_PROVIDER", "e2b")
+	if p := DetectFromEnv(); p != E2B {
+		t.Errorf("expected e2b from DEVSH_PROVIDER, got %q", p)
+	}
+
+	// An unknown configured provider is ignored rather than fatal.
+	t.Setenv("DEVSH_PROVIDER", "bogus")
+	if p := DetectFromEnv(); p != PveLxc {
+		t.Errorf("expected pve-lxc fallback, got %q", p)
+	}
+}
+
+func TestURLsFallsBackToGet(t *testing.T) {
+	ctx := context.Background()
+
+	sb, err := URLs(ctx, &fakeProvider{name: "plain"}, "sb1")
+	if err != nil {
+		t.Fatalf("URLs: %v", err)
+	}
+	if sb.VSCodeURL != "https://raw.example.com" {
+		t.Errorf("expected raw URL from Get, got %q", sb.VSCodeURL)
+	}
+
+	sb, err = URLs(ctx, &fakeURLProvider{fakeProvider{name: "auth"}}, "sb1")
+	if err != nil {
+		t.Fatalf("URLs: %v", err)
+	}
+	if sb.VSCodeURL != "https://auth.example.com" {
+		t.Errorf("expected authenticated URL, got %q", sb.VSCodeURL)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -18,6 +17,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	pve "github.com/karlorz/pve-go"
 )
 
 const (
@@ -34,16 +35,16 @@ type Config struct {
 	PublicDomain     string
 	VerifyTLS        bool
 	SnapshotResolver SnapshotResolver
+	IPPool           *IPPool // When set, new containers get a static IP from the pool
 }
 
 type Client struct {
-	apiURL   string
-	apiToken string
+	api      *pve.Client
+	apiToken string // For the console websocket, which pve.Client doesn't dial
 
 	publicDomain string
 	verifyTLS    bool
 
-	apiHTTP  *http.Client
 	execHTTP *http.Client
 
 	snapshotResolver SnapshotResolver
@@ -54,6 +55,8 @@ type Client struct {
 	dnsMu         sync.Mutex
 	domainSuffix  string
 	domainFetched bool
+
+	ipPool *IPPool
 }
 
 type Instance struct {
@@ -62,6 +65,7 @@ type Instance struct {
 	Status    string
 	Hostname  string
 	FQDN      string
+	IP        string // Static IPv4 address from net0, empty for DHCP
 	VSCodeURL string
 	WorkerURL string
 	VNCURL    string
@@ -72,35 +76,15 @@ type StartOptions struct {
 	SnapshotID   string
 	TemplateVMID int
 	InstanceID   string
-}
-
-type pveEnvelope struct {
-	Data json.RawMessage `json:"data"`
-}
-
-type pveNodeInfo struct {
-	Node string `json:"node"`
-}
-
-type pveDNSConfig struct {
-	Search string `json:"search,omitempty"`
-}
-
-type pveTaskStatus struct {
-	Status     string `json:"status"`
-	ExitStatus string `json:"exitstatus,omitempty"`
-}
-
-type pveContainerStatus struct {
-	Status   string `json:"status"`
-	VMID     int    `json:"vmid"`
-	Name     string `json:"name,omitempty"`
-	Template int    `json:"template,omitempty"`
-}
-
-type pveContainerConfig struct {
-	Net0     string `json:"net0,omitempty"`
-	Hostname string `json:"hostname,omitempty"`
+	Firewall     *FirewallOptions // nil keeps the template's firewall settings
+	// KeepFailed parks a clone that fails to come up as a stopped
+	// cmux-failed-<hostname> container for inspection instead of deleting it.
+	KeepFailed bool
+	// FullClone copies the template's rootfs instead of linking to it, so
+	// the instance outlives the template. Storage picks where the copy
+	// lands (e.g. "nvme-zfs"); its type decides the rootfs format.
+	FullClone bool
+	Storage   string
 }
 
 var (
@@ -121,15 +105,24 @@ func NewClient(cfg Config) (*Client, error) {
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: !cfg.VerifyTLS}
 
+	api, err := pve.NewClient(pve.Config{
+		BaseURL:    apiURL,
+		Auth:       pve.APIToken(cfg.APIToken),
+		HTTPClient: &http.Client{Transport: transport, Timeout: 180 * time.Second},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
-		apiURL:           apiURL,
+		api:              api,
 		apiToken:         cfg.APIToken,
 		publicDomain:     strings.TrimSpace(cfg.PublicDomain),
 		verifyTLS:        cfg.VerifyTLS,
-		apiHTTP:          &http.Client{Transport: transport, Timeout: 180 * time.Second},
 		execHTTP:         &http.Client{Timeout: 0},
 		snapshotResolver: cfg.SnapshotResolver,
 		node:             strings.TrimSpace(cfg.Node),
+		ipPool:           cfg.IPPool,
 	}, nil
 }
 
@@ -142,6 +135,15 @@ func NewClientFromEnv() (*Client, error) {
 		verifyTLS = true
 	}
 
+	var ipPool *IPPool
+	if spec := strings.TrimSpace(os.Getenv("PVE_IP_POOL")); spec != "" {
+		pool, err := ParseIPPool(spec, os.Getenv("PVE_IP_GATEWAY"))
+		if err != nil {
+			return nil, err
+		}
+		ipPool = pool
+	}
+
 	return NewClient(Config{
 		APIURL:           apiURL,
 		APIToken:         apiToken,
@@ -149,6 +151,7 @@ func NewClientFromEnv() (*Client, error) {
 		PublicDomain:     os.Getenv("PVE_PUBLIC_DOMAIN"),
 		VerifyTLS:        verifyTLS,
 		SnapshotResolver: resolveSnapshotFromManifestOrDefault,
+		IPPool:           ipPool,
 	})
 }
 
@@ -177,97 +180,6 @@ func ParseVMID(instanceID string) (int, bool) {
 	return 0, false
 }
 
-func (c *Client) apiRequestData(ctx context.Context, method, path string, params url.Values) (json.RawMessage, error) {
-	reqURL := c.apiURL + path
-	headers := http.Header{}
-	headers.Set("Authorization", "PVEAPIToken="+c.apiToken)
-
-	var body io.Reader
-	if params != nil && len(params) > 0 {
-		encoded := params.Encode()
-		if method == http.MethodGet || method == http.MethodDelete {
-			if strings.Contains(reqURL, "?") {
-				reqURL += "&" + encoded
-			} else {
-				reqURL += "?" + encoded
-			}
-		} else {
-			headers.Set("Content-Type", "application/x-www-form-urlencoded")
-			body = strings.NewReader(encoded)
-		}
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
-	if err != nil {
-		return nil, err
-	}
-	req.Header = headers
-
-	resp, err := c.apiHTTP.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	raw, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(raw))
-		if msg == "" {
-			msg = "(empty response)"
-		}
-		return nil, fmt.Errorf("PVE API error %d: %s", resp.StatusCode, msg)
-	}
-
-	var env pveEnvelope
-	if err := json.Unmarshal(raw, &env); err != nil {
-		return nil, fmt.Errorf("failed to decode PVE response: %w", err)
-	}
-	return env.Data, nil
-}
-
-func apiRequest[T any](ctx context.Context, c *Client, method, path string, params url.Values) (T, error) {
-	var zero T
-	data, err := c.apiRequestData(ctx, method, path, params)
-	if err != nil {
-		return zero, err
-	}
-	if err := json.Unmarshal(data, &zero); err != nil {
-		return zero, fmt.Errorf("failed to decode PVE data: %w", err)
-	}
-	return zero, nil
-}
-
-func normalizeUpid(value string) string {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return ""
-	}
-	if strings.Contains(trimmed, "%3A") {
-		if decoded, err := url.QueryUnescape(trimmed); err == nil {
-			return decoded
-		}
-	}
-	return trimmed
-}
-
-func extractUpid(data json.RawMessage) string {
-	var s string
-	if err := json.Unmarshal(data, &s); err == nil {
-		return normalizeUpid(s)
-	}
-	var obj struct {
-		UPID string `json:"upid"`
-	}
-	if err := json.Unmarshal(data, &obj); err == nil {
-		return normalizeUpid(obj.UPID)
-	}
-	return ""
-}
-
 func (c *Client) getNode(ctx context.Context) (string, error) {
 	c.nodeMu.Lock()
 	defer c.nodeMu.Unlock()
@@ -275,7 +187,7 @@ func (c *Client) getNode(ctx context.Context) (string, error) {
 		return c.node, nil
 	}
 
-	nodes, err := apiRequest[[]pveNodeInfo](ctx, c, http.MethodGet, "/api2/json/nodes", nil)
+	nodes, err := c.api.Nodes(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -301,7 +213,7 @@ func (c *Client) getDomainSuffix(ctx context.Context) (string, error) {
 		return "", err
 	}
 
-	dns, err := apiRequest[pveDNSConfig](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/dns", node), nil)
+	dns, err := c.api.DNS(ctx, node)
 	if err != nil {
 		c.domainFetched = true
 		c.domainSuffix = ""
@@ -320,12 +232,12 @@ func (c *Client) getDomainSuffix(ctx context.Context) (string, error) {
 	return c.domainSuffix, nil
 }
 
-func (c *Client) getContainerConfig(ctx context.Context, vmid int) (pveContainerConfig, error) {
+func (c *Client) getContainerConfig(ctx context.Context, vmid int) (pve.ContainerConfig, error) {
 	node, err := c.getNode(ctx)
 	if err != nil {
-		return pveContainerConfig{}, err
+		return pve.ContainerConfig{}, err
 	}
-	return apiRequest[pveContainerConfig](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d/config", node, vmid), nil)
+	return c.api.LXCConfig(ctx, node, vmid)
 }
 
 func (c *Client) getContainerIP(ctx context.Context, vmid int) (string, error) {
@@ -358,7 +270,7 @@ func (c *Client) getContainerStatus(ctx context.Context, vmid int) (string, erro
 		return "", err
 	}
 
-	status, err := apiRequest[pveContainerStatus](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d/status/current", node, vmid), nil)
+	status, err := c.api.LXCStatus(ctx, node, vmid)
 	if err != nil {
 		return "unknown", nil
 	}
@@ -371,8 +283,7 @@ func (c *Client) getContainerStatus(ctx context.Context, vmid int) (string, erro
 }
 
 func (c *Client) waitForTask(ctx context.Context, upid string, timeout time.Duration) error {
-	upid = normalizeUpid(upid)
-	if upid == "" {
+	if pve.NormalizeUPID(upid) == "" {
 		return nil
 	}
 
@@ -381,56 +292,34 @@ func (c *Client) waitForTask(ctx context.Context, upid string, timeout time.Dura
 		return err
 	}
 
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		status, err := apiRequest[pveTaskStatus](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/tasks/%s/status", node, url.PathEscape(upid)), nil)
-		if err != nil {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(2 * time.Second):
-			}
-			continue
-		}
-		if status.Status == "stopped" {
-			if status.ExitStatus != "" && status.ExitStatus != "OK" {
-				return fmt.Errorf("task failed: %s", status.ExitStatus)
-			}
-			return nil
-		}
-
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(2 * time.Second):
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := c.api.WaitForTask(waitCtx, node, upid, pve.WaitOptions{}); err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return errors.New("task timeout")
 		}
+		return err
 	}
-
-	return errors.New("task timeout")
+	return nil
 }
 
-func (c *Client) linkedCloneFromTemplate(ctx context.Context, templateVMID, newVMID int, hostname string) error {
+// cloneFromTemplate clones a template and waits for the task. Full clones
+// copy the whole rootfs, so they get longer to finish than linked ones.
+func (c *Client) cloneFromTemplate(ctx context.Context, templateVMID int, opts pve.CloneOptions) error {
 	node, err := c.getNode(ctx)
 	if err != nil {
 		return err
 	}
 
-	data, err := c.apiRequestData(ctx, http.MethodPost, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d/clone", node, templateVMID), url.Values{
-		"newid":    []string{strconv.Itoa(newVMID)},
-		"hostname": []string{hostname},
-		"full":     []string{"0"},
-	})
+	upid, err := c.api.CloneLXC(ctx, node, templateVMID, opts)
 	if err != nil {
 		return err
 	}
-	upid := extractUpid(data)
-	return c.waitForTask(ctx, upid, 5*time.Minute)
+	timeout := 5 * time.Minute
+	if opts.Full {
+		timeout = 30 * time.Minute
+	}
+	return c.waitForTask(ctx, upid, timeout)
 }
 
 func (c *Client) startContainer(ctx context.Context, vmid int) error {
@@ -438,11 +327,11 @@ func (c *Client) startContainer(ctx context.Context, vmid int) error {
 	if err != nil {
 		return err
 	}
-	data, err := c.apiRequestData(ctx, http.MethodPost, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d/status/start", node, vmid), nil)
+	upid, err := c.api.StartLXC(ctx, node, vmid)
 	if err != nil {
 		return err
 	}
-	return c.waitForTask(ctx, extractUpid(data), 5*time.Minute)
+	return c.waitForTask(ctx, upid, 5*time.Minute)
 }
 
 func (c *Client) stopContainer(ctx context.Context, vmid int) error {
@@ -455,11 +344,11 @@ func (c *Client) stopContainer(ctx context.Context, vmid int) error {
 	if err != nil {
 		return err
 	}
-	data, err := c.apiRequestData(ctx, http.MethodPost, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d/status/stop", node, vmid), nil)
+	upid, err := c.api.StopLXC(ctx, node, vmid)
 	if err != nil {
 		return err
 	}
-	return c.waitForTask(ctx, extractUpid(data), 5*time.Minute)
+	return c.waitForTask(ctx, upid, 5*time.Minute)
 }
 
 func (c *Client) deleteContainer(ctx context.Context, vmid int) error {
@@ -469,18 +358,32 @@ func (c *Client) deleteContainer(ctx context.Context, vmid int) error {
 	if err != nil {
 		return err
 	}
-	data, err := c.apiRequestData(ctx, http.MethodDelete, fmt.Sprintf("/api2/json/nodes/%s/lxc/%d", node, vmid), url.Values{
-		"force": []string{"1"},
-		"purge": []string{"1"},
-	})
+	upid, err := c.api.DeleteLXC(ctx, node, vmid, pve.DeleteOptions{Force: true, Purge: true})
 	if err != nil {
-		msg := err.Error()
-		if strings.Contains(msg, "does not exist") || strings.Contains(msg, "not found") {
+		if errors.Is(err, ErrNotFound) {
 			return nil
 		}
 		return err
 	}
-	return c.waitForTask(ctx, extractUpid(data), 5*time.Minute)
+	return c.waitForTask(ctx, upid, 5*time.Minute)
+}
+
+// failedContainerPrefix marks clones parked by StartOptions.KeepFailed.
+const failedContainerPrefix = "cmux-failed-"
+
+// parkFailedContainer stops a clone that failed to come up and renames it
+// so it is neither reused nor mistaken for a live sandbox.
+func (c *Client) parkFailedContainer(ctx context.Context, vmid int, hostname string) error {
+	if err := c.stopContainer(ctx, vmid); err != nil {
+		return err
+	}
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return err
+	}
+	return c.api.UpdateLXCConfig(ctx, node, vmid, url.Values{
+		"hostname": []string{hostname},
+	})
 }
 
 func (c *Client) findNextVMID(ctx context.Context) (int, error) {
@@ -489,13 +392,13 @@ func (c *Client) findNextVMID(ctx context.Context) (int, error) {
 		return 0, err
 	}
 
-	containers, err := apiRequest[[]pveContainerStatus](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/lxc", node), nil)
+	containers, err := c.api.LXCList(ctx, node)
 	if err != nil {
 		return 0, err
 	}
-	vms, _ := apiRequest[[]struct {
+	vms, _ := pve.Request[[]struct {
 		VMID int `json:"vmid"`
-	}](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/qemu", node), nil)
+	}](ctx, c.api, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/qemu", node), nil)
 
 	used := map[int]struct{}{}
 	for _, c := range containers {
@@ -519,7 +422,7 @@ func (c *Client) findVMIDByHostname(ctx context.Context, hostname string) (int,
 	if err != nil {
 		return 0, err
 	}
-	containers, err := apiRequest[[]pveContainerStatus](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/lxc", node), nil)
+	containers, err := c.api.LXCList(ctx, node)
 	if err != nil {
 		return 0, err
 	}
@@ -529,7 +432,7 @@ func (c *Client) findVMIDByHostname(ctx context.Context, hostname string) (int,
 			return ctr.VMID, nil
 		}
 	}
-	return 0, fmt.Errorf("unable to resolve VMID for instance %s", hostname)
+	return 0, fmt.Errorf("unable to resolve VMID for instance %s: %w", hostname, ErrNotFound)
 }
 
 func readPveSnapshotManifest() (struct {
@@ -551,13 +454,7 @@ func readPveSnapshotManifest() (struct {
 		} `json:"presets"`
 	}
 
-	root, ok := findRepoRootForPveManifest()
-	if !ok {
-		return manifest, fmt.Errorf("snapshot manifest not found")
-	}
-
-	path := filepath.Join(root, "packages", "shared", "src", "pve-lxc-snapshots.json")
-	raw, err := os.ReadFile(path)
+	raw, err := loadPveSnapshotManifestBytes()
 	if err != nil {
 		return manifest, err
 	}
@@ -622,11 +519,8 @@ func (c *Client) buildPublicServiceURL(port int, hostID string) (string, bool) {
 }
 
 func (c *Client) buildServiceURL(ctx context.Context, port int, vmid int, hostname string, domainSuffix string, publicHostID string) (string, error) {
-	if publicURL, ok := c.buildPublicServiceURL(port, publicHostID); ok {
-		return publicURL, nil
-	}
-	if domainSuffix != "" {
-		return fmt.Sprintf("http://%s%s:%d", hostname, domainSuffix, port), nil
+	if serviceURL, ok := c.buildNamedServiceURL(port, hostname, domainSuffix, publicHostID); ok {
+		return serviceURL, nil
 	}
 	ip, err := c.getContainerIP(ctx, vmid)
 	if err != nil {
@@ -638,7 +532,19 @@ func (c *Client) buildServiceURL(ctx context.Context, port int, vmid int, hostna
 	return "", fmt.Errorf("cannot build service URL for container %d: no public domain, DNS search domain, or container IP available", vmid)
 }
 
-func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instance, error) {
+// buildNamedServiceURL builds a service URL from the public domain or DNS
+// search domain, without an API call.
+func (c *Client) buildNamedServiceURL(port int, hostname string, domainSuffix string, publicHostID string) (string, bool) {
+	if publicURL, ok := c.buildPublicServiceURL(port, publicHostID); ok {
+		return publicURL, true
+	}
+	if domainSuffix != "" {
+		return fmt.Sprintf("http://%s%s:%d", hostname, domainSuffix, port), true
+	}
+	return "", false
+}
+
+func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (_ *Instance, err error) {
 	_, templateVMID, err := c.resolveSnapshot(opts.SnapshotID)
 	if err != nil {
 		return nil, err
@@ -646,6 +552,9 @@ func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instanc
 	if opts.TemplateVMID > 0 {
 		templateVMID = opts.TemplateVMID
 	}
+	if opts.Storage != "" && !opts.FullClone {
+		return nil, errors.New("a target storage requires a full clone")
+	}
 
 	instanceID := normalizeHostID(opts.InstanceID)
 	if instanceID == "" {
@@ -663,6 +572,27 @@ func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instanc
 		fqdn = hostname + domainSuffix
 	}
 
+	// Once a clone exists, every error return must remove (or park) it;
+	// the cleanup gets its own context since ctx may be what failed.
+	created := 0
+	defer func() {
+		if err == nil || created == 0 {
+			return
+		}
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if !opts.KeepFailed {
+			_ = c.deleteContainer(cleanupCtx, created)
+			return
+		}
+		parked := failedContainerPrefix + hostname
+		if parkErr := c.parkFailedContainer(cleanupCtx, created, parked); parkErr != nil {
+			err = fmt.Errorf("%w (parking container %d failed: %v)", err, created, parkErr)
+			return
+		}
+		err = fmt.Errorf("%w (container %d kept as %s)", err, created, parked)
+	}()
+
 	var vmid int
 	var lastErr error
 	for attempt := 1; attempt <= 5; attempt++ {
@@ -671,18 +601,40 @@ func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instanc
 			return nil, err
 		}
 
-		if err := c.linkedCloneFromTemplate(ctx, templateVMID, vmid, hostname); err != nil {
-			msg := err.Error()
-			if strings.Contains(msg, "already exists") {
+		if err := c.cloneFromTemplate(ctx, templateVMID, pve.CloneOptions{
+			NewID:    vmid,
+			Hostname: hostname,
+			Full:     opts.FullClone,
+			Storage:  opts.Storage,
+		}); err != nil {
+			// Another clone may have grabbed the same VMID or be holding
+			// the template config lock; both clear up on retry.
+			if errors.Is(err, ErrVMIDConflict) || errors.Is(err, ErrLocked) {
 				lastErr = err
 				time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
 				continue
 			}
 			return nil, err
 		}
+		created = vmid
+
+		ip := ""
+		if c.ipPool != nil {
+			ip, err = c.assignStaticIP(ctx, vmid)
+			if err != nil {
+				return nil, fmt.Errorf("failed to assign static IP: %w", err)
+			}
+		}
+
+		// Lock down before the first start so the container is never
+		// reachable with the template's permissive settings.
+		if opts.Firewall != nil {
+			if err := c.applyFirewall(ctx, vmid, *opts.Firewall); err != nil {
+				return nil, fmt.Errorf("failed to configure container firewall: %w", err)
+			}
+		}
 
 		if err := c.startContainer(ctx, vmid); err != nil {
-			_ = c.deleteContainer(ctx, vmid)
 			return nil, err
 		}
 
@@ -711,6 +663,7 @@ func (c *Client) StartInstance(ctx context.Context, opts StartOptions) (*Instanc
 			Status:    "running",
 			Hostname:  hostname,
 			FQDN:      fqdn,
+			IP:        ip,
 			VSCodeURL: vscodeURL,
 			WorkerURL: workerURL,
 			VNCURL:    vncURL,
@@ -741,6 +694,7 @@ func (c *Client) GetInstance(ctx context.Context, instanceID string) (*Instance,
 	}
 
 	status, _ := c.getContainerStatus(ctx, vmid)
+	ip, _ := c.getContainerIP(ctx, vmid)
 	domainSuffix, _ := c.getDomainSuffix(ctx)
 	fqdn := ""
 	if domainSuffix != "" {
@@ -758,6 +712,7 @@ func (c *Client) GetInstance(ctx context.Context, instanceID string) (*Instance,
 		Status:    status,
 		Hostname:  hostname,
 		FQDN:      fqdn,
+		IP:        ip,
 		VSCodeURL: vscodeURL,
 		WorkerURL: workerURL,
 		VNCURL:    vncURL,
@@ -770,7 +725,7 @@ func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
 	if err != nil {
 		return nil, err
 	}
-	containers, err := apiRequest[[]pveContainerStatus](ctx, c, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/lxc", node), nil)
+	containers, err := c.api.LXCList(ctx, node)
 	if err != nil {
 		return nil, err
 	}
@@ -779,10 +734,7 @@ func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
 	instances := make([]Instance, 0, len(containers))
 	for _, ctr := range containers {
 		hostname := strings.TrimSpace(ctr.Name)
-		if hostname == "" {
-			continue
-		}
-		if !strings.HasPrefix(hostname, "cmux-") && !strings.HasPrefix(hostname, "pvelxc-") {
+		if !isCmuxHostname(hostname) {
 			continue
 		}
 
@@ -870,7 +822,10 @@ func resolveSnapshotFromManifestOrDefault(snapshotID string) (int, error) {
 		if id == defaultSnapshotID {
 			return defaultTemplateVMID, nil
 		}
-		return 0, fmt.Errorf("snapshot manifest not found (run from repo root to resolve %s)", id)
+		if errors.Is(err, errManifestNotFound) {
+			return 0, fmt.Errorf("snapshot manifest not found (run from repo root or set %s to resolve %s)", ManifestEnvVar, id)
+		}
+		return 0, fmt.Errorf("failed to load snapshot manifest to resolve %s: %w", id, err)
 	}
 
 	for _, preset := range manifest.Presets {
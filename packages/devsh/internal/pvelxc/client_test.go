@@ -1,14 +1,29 @@
 package pvelxc
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/karlorz/devsh/internal/provider"
+	pve "github.com/karlorz/pve-go"
 )
 
+// newTestAPI returns a PVE API client that talks to server.
+func newTestAPI(t *testing.T, server *httptest.Server) *pve.Client {
+	t.Helper()
+	api, err := pve.NewClient(pve.Config{BaseURL: server.URL, Auth: pve.APIToken("token"), HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("pve.NewClient: %v", err)
+	}
+	return api
+}
+
 func TestIsPveLxcInstanceID(t *testing.T) {
 	tests := []struct {
 		id   string
@@ -268,8 +283,8 @@ func TestNewClientTrimsAPIURL(t *testing.T) {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 	// URL should be trimmed of whitespace and trailing slash
-	if client.apiURL != "https://pve.example.com:8006" {
-		t.Errorf("expected trimmed URL, got '%s'", client.apiURL)
+	if client.api.BaseURL() != "https://pve.example.com:8006" {
+		t.Errorf("expected trimmed URL, got '%s'", client.api.BaseURL())
 	}
 }
 
@@ -382,3 +397,116 @@ func TestResolveSnapshotFromManifestOrDefaultUsesUpdatedFallback(t *testing.T) {
 		t.Fatalf("resolveSnapshotFromManifestOrDefault(\"\") unexpectedly returned stale template VMID 9045")
 	}
 }
+
+// newFailingStartClient returns a client whose PVE API clones container 200
+// and then fails to start it, recording every mutating request.
+func newFailingStartClient(t *testing.T) (*Client, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/api2/json/nodes/test-node")
+		if r.Method != http.MethodGet {
+			_ = r.ParseForm()
+			mu.Lock()
+			calls = append(calls, fmt.Sprintf("%s %s %s", r.Method, path, r.Form.Encode()))
+			mu.Unlock()
+		}
+		switch {
+		case r.Method == http.MethodGet && (path == "/lxc" || path == "/qemu"):
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		case r.Method == http.MethodGet && path == "/dns":
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case r.Method == http.MethodGet && path == "/lxc/200/status/current":
+			_, _ = w.Write([]byte(`{"data":{"status":"stopped"}}`))
+		case path == "/lxc/200/status/start":
+			http.Error(w, `{"errors":{"start":"boot failed"}}`, http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"data":null}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &Client{
+		api:              newTestAPI(t, server),
+		node:             "test-node",
+		snapshotResolver: func(string) (int, error) { return 9000, nil },
+	}
+	return client, &calls
+}
+
+func TestStartInstanceDeletesFailedClone(t *testing.T) {
+	client, calls := newFailingStartClient(t)
+
+	_, err := client.StartInstance(context.Background(), StartOptions{SnapshotID: "snapshot_test", InstanceID: "pvelxc-test"})
+	if err == nil {
+		t.Fatal("expected start failure")
+	}
+	last := (*calls)[len(*calls)-1]
+	if !strings.HasPrefix(last, "DELETE /lxc/200 ") {
+		t.Errorf("expected clone to be deleted, calls: %v", *calls)
+	}
+}
+
+func TestStartInstanceKeepFailedParksClone(t *testing.T) {
+	client, calls := newFailingStartClient(t)
+
+	_, err := client.StartInstance(context.Background(), StartOptions{SnapshotID: "snapshot_test", InstanceID: "pvelxc-test", KeepFailed: true})
+	if err == nil || !strings.Contains(err.Error(), "kept as cmux-failed-pvelxc-test") {
+		t.Fatalf("expected parked error, got %v", err)
+	}
+	for _, call := range *calls {
+		if strings.HasPrefix(call, "DELETE ") {
+			t.Errorf("parked clone must not be deleted, calls: %v", *calls)
+		}
+	}
+	last := (*calls)[len(*calls)-1]
+	if last != "PUT /lxc/200/config hostname=cmux-failed-pvelxc-test" {
+		t.Errorf("expected hostname rename, got %q", last)
+	}
+}
+
+func TestDeleteContainerOnlyIgnoresMissingContainer(t *testing.T) {
+	tests := []struct {
+		message string
+		wantErr bool
+	}{
+		{"Configuration file 'nodes/test-node/lxc/201.conf' does not exist", false},
+		{"CT 201 does not exist", false},
+		{"storage 'local-zfs' does not exist", true},
+		{"unable to remove '/var/lib/vz/images/201' - No such file or directory", true},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprintf(w, `{"data":null,"message":%q}`, tt.message+"\n")
+		}))
+		client := &Client{api: newTestAPI(t, server), node: "test-node"}
+
+		err := client.deleteContainer(context.Background(), 201)
+		server.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("deleteContainer with %q: err = %v, wantErr %v", tt.message, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStartInstanceFullCloneToStorage(t *testing.T) {
+	client, calls := newFailingStartClient(t)
+
+	_, _ = client.StartInstance(context.Background(), StartOptions{SnapshotID: "snapshot_test", InstanceID: "pvelxc-test", FullClone: true, Storage: "nvme-zfs"})
+	if len(*calls) == 0 || (*calls)[0] != "POST /lxc/9000/clone full=1&hostname=pvelxc-test&newid=200&storage=nvme-zfs" {
+		t.Errorf("expected full clone to nvme-zfs, calls: %v", *calls)
+	}
+}
+
+func TestStartInstanceStorageRequiresFullClone(t *testing.T) {
+	client, calls := newFailingStartClient(t)
+
+	_, err := client.StartInstance(context.Background(), StartOptions{SnapshotID: "snapshot_test", Storage: "nvme-zfs"})
+	if err == nil || len(*calls) != 0 {
+		t.Fatalf("expected error before any API call, got %v, calls: %v", err, *calls)
+	}
+}
@@ -0,0 +1,350 @@
+package pvelxc
+
+import (
+	"bytes"
+	"context"
+	"crypto/des"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	pve "github.com/karlorz/pve-go"
+)
+
+// ConsoleTicket is a one-shot vncproxy ticket for a container's console.
+// PVE starts a VNC server for it that accepts a single websocket connection
+// within about 10 seconds, so mint a new ticket per connection.
+type ConsoleTicket struct {
+	VMID   int
+	Node   string
+	Port   int    // vncproxy port on the node, passed to vncwebsocket
+	Ticket string // Also the VNC password
+	User   string
+	UPID   string
+}
+
+// resolveVMID accepts a VMID, cmux-<vmid> or a container hostname.
+func (c *Client) resolveVMID(ctx context.Context, instanceID string) (int, error) {
+	if vmid, ok := ParseVMID(instanceID); ok {
+		return vmid, nil
+	}
+	return c.findVMIDByHostname(ctx, instanceID)
+}
+
+// GetConsoleTicket asks PVE for a websocket console ticket via
+// /lxc/{vmid}/vncproxy. The console is PVE's own lxc-console, so it works
+// even when the container's worker is down.
+func (c *Client) GetConsoleTicket(ctx context.Context, instanceID string) (*ConsoleTicket, error) {
+	vmid, err := c.resolveVMID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.api.LXCVNCProxy(ctx, node, vmid)
+	if err != nil {
+		return nil, fmt.Errorf("vncproxy for CT %d: %w", vmid, err)
+	}
+	return &ConsoleTicket{VMID: vmid, Node: node, Port: resp.Port, Ticket: resp.Ticket, User: resp.User, UPID: resp.UPID}, nil
+}
+
+// ConsoleURL returns the PVE web UI's noVNC console for the container. It
+// only works in a browser that is logged in to the PVE web UI.
+func (c *Client) ConsoleURL(ctx context.Context, instanceID string) (string, error) {
+	vmid, err := c.resolveVMID(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(c.api.BaseURL())
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/"}
+	u.RawQuery = url.Values{
+		"console": []string{"lxc"},
+		"novnc":   []string{"1"},
+		"vmid":    []string{strconv.Itoa(vmid)},
+		"node":    []string{node},
+		"resize":  []string{"scale"},
+	}.Encode()
+	return u.String(), nil
+}
+
+// DialConsole opens the vncwebsocket for ticket. The stream carries raw
+// RFB; the VNC password is the ticket.
+func (c *Client) DialConsole(ctx context.Context, ticket *ConsoleTicket) (io.ReadWriteCloser, error) {
+	wsURL, err := c.api.LXCVNCWebsocketURL(ticket.Node, ticket.VMID, pve.VNCTicket{Port: ticket.Port, Ticket: ticket.Ticket})
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := websocket.Dialer{
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: !c.verifyTLS},
+		HandshakeTimeout: 15 * time.Second,
+		Subprotocols:     []string{"binary"},
+	}
+	header := http.Header{}
+	if c.apiToken != "" {
+		header.Set("Authorization", "PVEAPIToken="+c.apiToken)
+	}
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("vncwebsocket for CT %d: %s", ticket.VMID, resp.Status)
+		}
+		return nil, fmt.Errorf("vncwebsocket for CT %d: %w", ticket.VMID, err)
+	}
+	return &wsStream{conn: conn}, nil
+}
+
+// ServeConsole bridges every connection accepted on ln to the container's
+// console until ctx is done. It authenticates to PVE with a fresh ticket
+// per connection and offers local VNC clients no-password access, so ln
+// should only listen on loopback.
+func (c *Client) ServeConsole(ctx context.Context, ln net.Listener, instanceID string, logf func(format string, args ...any)) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		local, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer local.Close()
+			if err := c.bridgeConsole(ctx, local, instanceID); err != nil {
+				logf("console connection from %s: %v", local.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (c *Client) bridgeConsole(ctx context.Context, local net.Conn, instanceID string) error {
+	ticket, err := c.GetConsoleTicket(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	remote, err := c.DialConsole(ctx, ticket)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	if err := vncClientHandshake(remote, ticket.Ticket); err != nil {
+		return fmt.Errorf("PVE console handshake: %w", err)
+	}
+	if err := vncServerHandshake(local); err != nil {
+		return fmt.Errorf("local VNC handshake: %w", err)
+	}
+
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(remote, local); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(local, remote); done <- struct{}{} }()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// RFB security types and version used by the console bridge.
+const (
+	rfbVersion38    = "RFB 003.008\n"
+	rfbSecurityNone = 1
+	rfbSecurityVNC  = 2
+)
+
+// vncClientHandshake negotiates RFB 3.8 with PVE's VNC server, answering
+// VNC authentication with password.
+func vncClientHandshake(conn io.ReadWriter, password string) error {
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(conn, version); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(version, []byte("RFB ")) {
+		return fmt.Errorf("not a VNC server: %q", version)
+	}
+	if _, err := io.WriteString(conn, rfbVersion38); err != nil {
+		return err
+	}
+
+	var count [1]byte
+	if _, err := io.ReadFull(conn, count[:]); err != nil {
+		return err
+	}
+	if count[0] == 0 {
+		return readRFBReason(conn)
+	}
+	types := make([]byte, count[0])
+	if _, err := io.ReadFull(conn, types); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.IndexByte(types, rfbSecurityVNC) >= 0:
+		if _, err := conn.Write([]byte{rfbSecurityVNC}); err != nil {
+			return err
+		}
+		challenge := make([]byte, 16)
+		if _, err := io.ReadFull(conn, challenge); err != nil {
+			return err
+		}
+		response, err := vncAuthResponse(challenge, password)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(response); err != nil {
+			return err
+		}
+	case bytes.IndexByte(types, rfbSecurityNone) >= 0:
+		if _, err := conn.Write([]byte{rfbSecurityNone}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("no supported security type in %v", types)
+	}
+
+	var result [4]byte
+	if _, err := io.ReadFull(conn, result[:]); err != nil {
+		return err
+	}
+	if binary.BigEndian.Uint32(result[:]) != 0 {
+		if err := readRFBReason(conn); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+		return errors.New("authentication failed")
+	}
+	return nil
+}
+
+// vncServerHandshake offers a local VNC client RFB without authentication,
+// supporting the 3.3, 3.7 and 3.8 security handshakes.
+func vncServerHandshake(conn io.ReadWriter) error {
+	if _, err := io.WriteString(conn, rfbVersion38); err != nil {
+		return err
+	}
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(conn, version); err != nil {
+		return err
+	}
+	if string(version) == "RFB 003.003\n" {
+		// 3.3: the server picks the security type.
+		return binary.Write(conn, binary.BigEndian, uint32(rfbSecurityNone))
+	}
+	if _, err := conn.Write([]byte{1, rfbSecurityNone}); err != nil {
+		return err
+	}
+	var choice [1]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil {
+		return err
+	}
+	if choice[0] != rfbSecurityNone {
+		return fmt.Errorf("client chose unsupported security type %d", choice[0])
+	}
+	if string(version) == "RFB 003.007\n" {
+		return nil
+	}
+	return binary.Write(conn, binary.BigEndian, uint32(0))
+}
+
+// vncAuthResponse encrypts the VNC challenge with the password as a DES key,
+// bits mirrored per byte as the RFB spec requires.
+func vncAuthResponse(challenge []byte, password string) ([]byte, error) {
+	key := make([]byte, 8)
+	copy(key, password)
+	for i, b := range key {
+		var mirrored byte
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				mirrored |= 0x80 >> bit
+			}
+		}
+		key[i] = mirrored
+	}
+	block, err := des.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]byte, len(challenge))
+	for i := 0; i+8 <= len(challenge); i += 8 {
+		block.Encrypt(response[i:i+8], challenge[i:i+8])
+	}
+	return response, nil
+}
+
+func readRFBReason(r io.Reader) error {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > 4096 {
+		n = 4096
+	}
+	reason := make([]byte, n)
+	if _, err := io.ReadFull(r, reason); err != nil {
+		return err
+	}
+	return errors.New(string(reason))
+}
+
+// wsStream reads and writes a websocket's binary messages as a byte stream.
+type wsStream struct {
+	conn    *websocket.Conn
+	reader  io.Reader
+	writeMu sync.Mutex
+}
+
+func (s *wsStream) Read(p []byte) (int, error) {
+	for {
+		if s.reader == nil {
+			_, r, err := s.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			s.reader = r
+		}
+		n, err := s.reader.Read(p)
+		if err == io.EOF {
+			s.reader = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (s *wsStream) Write(p []byte) (int, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *wsStream) Close() error {
+	return s.conn.Close()
+}
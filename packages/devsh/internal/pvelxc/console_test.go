@@ -0,0 +1,189 @@
+package pvelxc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeVNCServer plays PVE's side of an RFB 3.8 handshake with VNC
+// authentication, then echoes.
+func fakeVNCServer(t *testing.T, conn io.ReadWriter, password string) {
+	t.Helper()
+	if _, err := io.WriteString(conn, rfbVersion38); err != nil {
+		t.Errorf("write version: %v", err)
+		return
+	}
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(conn, version); err != nil {
+		t.Errorf("read version: %v", err)
+		return
+	}
+	conn.Write([]byte{1, rfbSecurityVNC})
+	choice := make([]byte, 1)
+	io.ReadFull(conn, choice)
+	challenge := []byte("0123456789abcdef")
+	conn.Write(challenge)
+	response := make([]byte, 16)
+	io.ReadFull(conn, response)
+	want, _ := vncAuthResponse(challenge, password)
+	if !bytes.Equal(response, want) {
+		binary.Write(conn, binary.BigEndian, uint32(1))
+		binary.Write(conn, binary.BigEndian, uint32(len("bad password")))
+		io.WriteString(conn, "bad password")
+		return
+	}
+	binary.Write(conn, binary.BigEndian, uint32(0))
+	io.Copy(conn, conn)
+}
+
+func TestVNCClientHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		fakeVNCServer(t, server, "PVEVNC:ticket")
+	}()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := vncClientHandshake(client, "PVEVNC:ticket"); err != nil {
+		t.Fatalf("vncClientHandshake: %v", err)
+	}
+}
+
+func TestVNCClientHandshakeWrongPassword(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		fakeVNCServer(t, server, "right")
+	}()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	err := vncClientHandshake(client, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "bad password") {
+		t.Fatalf("expected authentication failure, got %v", err)
+	}
+}
+
+func TestVNCServerHandshakeVersions(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		reply   []byte // What the client reads after sending its version
+	}{
+		{"RFB 003.008\n", []byte{1, rfbSecurityNone, 0, 0, 0, 0}},
+		{"RFB 003.007\n", []byte{1, rfbSecurityNone}},
+		{"RFB 003.003\n", []byte{0, 0, 0, rfbSecurityNone}},
+	} {
+		client, server := net.Pipe()
+		errc := make(chan error, 1)
+		go func() { errc <- vncServerHandshake(server) }()
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+
+		io.ReadFull(client, make([]byte, 12))
+		io.WriteString(client, tt.version)
+		got := make([]byte, len(tt.reply))
+		if tt.version == "RFB 003.003\n" {
+			io.ReadFull(client, got)
+		} else {
+			io.ReadFull(client, got[:2])
+			client.Write([]byte{rfbSecurityNone})
+			io.ReadFull(client, got[2:])
+		}
+		if err := <-errc; err != nil {
+			t.Errorf("%q: vncServerHandshake: %v", tt.version, err)
+		}
+		if !bytes.Equal(got, tt.reply) {
+			t.Errorf("%q: client read %v, want %v", tt.version, got, tt.reply)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestConsoleURL(t *testing.T) {
+	client := &Client{node: "pve1"}
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	client.api = newTestAPI(t, server)
+
+	got, err := client.ConsoleURL(context.Background(), "cmux-200")
+	if err != nil {
+		t.Fatalf("ConsoleURL: %v", err)
+	}
+	want := server.URL + "/?console=lxc&node=pve1&novnc=1&resize=scale&vmid=200"
+	if got != want {
+		t.Errorf("ConsoleURL = %s, want %s", got, want)
+	}
+}
+
+func TestServeConsoleBridgesToPVE(t *testing.T) {
+	const ticket = "PVEVNC:abc123"
+	upgrader := websocket.Upgrader{Subprotocols: []string{"binary"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/nodes/pve/lxc/200/vncproxy":
+			if r.Method != http.MethodPost {
+				t.Errorf("vncproxy method = %s", r.Method)
+			}
+			r.ParseForm()
+			if r.PostForm.Get("websocket") != "1" {
+				t.Errorf("vncproxy without websocket=1: %v", r.PostForm)
+			}
+			io.WriteString(w, `{"data":{"port":"5900","ticket":"`+ticket+`","user":"root@pam!cmux","upid":"UPID:pve:1"}}`)
+		case "/api2/json/nodes/pve/lxc/200/vncwebsocket":
+			if r.URL.Query().Get("port") != "5900" || r.URL.Query().Get("vncticket") != ticket {
+				t.Errorf("vncwebsocket query = %s", r.URL.RawQuery)
+			}
+			if got := r.Header.Get("Authorization"); got != "PVEAPIToken=root@pam!cmux=secret" {
+				t.Errorf("vncwebsocket Authorization = %q", got)
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("upgrade: %v", err)
+				return
+			}
+			stream := &wsStream{conn: conn}
+			defer stream.Close()
+			fakeVNCServer(t, stream, ticket)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{api: newTestAPI(t, server), apiToken: "root@pam!cmux=secret", node: "pve"}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.ServeConsole(ctx, ln, "cmux-200", t.Logf)
+
+	local, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	local.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// A local VNC client needs no password.
+	if err := vncClientHandshake(local, ""); err != nil {
+		t.Fatalf("local handshake: %v", err)
+	}
+	io.WriteString(local, "ping")
+	echo := make([]byte, 4)
+	if _, err := io.ReadFull(local, echo); err != nil || string(echo) != "ping" {
+		t.Fatalf("echo = %q, %v", echo, err)
+	}
+}
@@ -0,0 +1,20 @@
+package pvelxc
+
+import (
+	pve "github.com/karlorz/pve-go"
+)
+
+// Sentinel errors for PVE failure classes. Use errors.Is against errors
+// returned by the client instead of matching on message text.
+var (
+	ErrVMIDConflict = pve.ErrVMIDConflict
+	ErrNotFound     = pve.ErrNotFound
+	ErrLocked       = pve.ErrLocked
+)
+
+// APIError is a non-2xx response from the PVE API.
+type APIError = pve.APIError
+
+// ErrTaskFailed is returned when a PVE task finishes with a non-OK exit
+// status; see pve.ErrTaskFailed.
+type ErrTaskFailed = pve.ErrTaskFailed
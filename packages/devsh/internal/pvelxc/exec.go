@@ -96,7 +96,22 @@ func (c *Client) VerifyTimezone(ctx context.Context, instanceID string, timezone
 	return &ExecResult{ExitCode: exitCode, Stdout: stdout, Stderr: stderr}, nil
 }
 
+// errExecUnavailable marks a host that could not be reached or refused the
+// request, as opposed to a command that ran and failed.
+var errExecUnavailable = errors.New("exec endpoint unavailable")
+
+// tryHTTPExec runs command via host. An unreachable host yields a nil
+// result and nil error so callers move on to the next candidate.
 func (c *Client) tryHTTPExec(ctx context.Context, host string, command string, timeout time.Duration) (*ExecResult, error) {
+	result, err := c.doHTTPExec(ctx, host, command, timeout)
+	if errors.Is(err, errExecUnavailable) {
+		return nil, nil
+	}
+	return result, err
+}
+
+// doHTTPExec is tryHTTPExec, but reports why a host was unavailable.
+func (c *Client) doHTTPExec(ctx context.Context, host string, command string, timeout time.Duration) (*ExecResult, error) {
 	execURL, err := buildExecURL(host)
 	if err != nil {
 		return nil, err
@@ -133,12 +148,12 @@ func (c *Client) tryHTTPExec(ctx context.Context, host string, command string, t
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
-		return nil, nil
+		return nil, fmt.Errorf("%w: %v", errExecUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil
+		return nil, fmt.Errorf("%w: HTTP %s", errExecUnavailable, resp.Status)
 	}
 
 	var stdout strings.Builder
@@ -187,7 +202,30 @@ func (c *Client) tryHTTPExec(ctx context.Context, host string, command string, t
 	}, nil
 }
 
+// execEndpoint is one way to reach a container's exec daemon.
+type execEndpoint struct {
+	Host string
+	Kind string // EndpointPublic, EndpointDirectIP or EndpointTailnet
+}
+
+// resolveExecCandidates returns the exec hosts to try in order. A winner
+// cached by ProbeEndpoints goes first.
 func (c *Client) resolveExecCandidates(ctx context.Context, instanceID string) (int, []string, error) {
+	vmid, endpoints, err := c.resolveExecEndpoints(ctx, instanceID)
+	if err != nil {
+		return 0, nil, err
+	}
+	candidates := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		candidates = append(candidates, ep.Host)
+	}
+	if best, ok := loadCachedExecEndpoint(instanceID); ok {
+		candidates = preferHost(candidates, best)
+	}
+	return vmid, candidates, nil
+}
+
+func (c *Client) resolveExecEndpoints(ctx context.Context, instanceID string) (int, []execEndpoint, error) {
 	vmid, ok := ParseVMID(instanceID)
 	hostname := normalizeHostID(instanceID)
 	if ok {
@@ -207,22 +245,24 @@ func (c *Client) resolveExecCandidates(ctx context.Context, instanceID string) (
 
 	domainSuffix, _ := c.getDomainSuffix(ctx)
 
-	candidates := make([]string, 0, 3)
+	endpoints := make([]execEndpoint, 0, 3)
 	if publicURL, ok := c.buildPublicServiceURL(39375, hostname); ok {
-		candidates = append(candidates, publicURL)
-	}
-	if domainSuffix != "" {
-		candidates = append(candidates, fmt.Sprintf("http://%s%s:%d", hostname, domainSuffix, 39375))
+		endpoints = append(endpoints, execEndpoint{Host: publicURL, Kind: EndpointPublic})
 	}
+	// A static IP is known before boot, so try it ahead of DNS, which may
+	// not have picked up a new container yet.
 	if ip, _ := c.getContainerIP(ctx, vmid); ip != "" {
-		candidates = append(candidates, fmt.Sprintf("http://%s:%d", ip, 39375))
+		endpoints = append(endpoints, execEndpoint{Host: fmt.Sprintf("http://%s:%d", ip, 39375), Kind: EndpointDirectIP})
+	}
+	if domainSuffix != "" {
+		endpoints = append(endpoints, execEndpoint{Host: fmt.Sprintf("http://%s%s:%d", hostname, domainSuffix, 39375), Kind: EndpointTailnet})
 	}
 
-	if len(candidates) == 0 {
+	if len(endpoints) == 0 {
 		return 0, nil, fmt.Errorf("cannot execute command in container %d: no reachable exec host candidates", vmid)
 	}
 
-	return vmid, candidates, nil
+	return vmid, endpoints, nil
 }
 
 func execReadyProbeError(instanceID string, waitErr error, lastErr error) error {
@@ -347,6 +387,8 @@ func (c *Client) ExecCommand(ctx context.Context, instanceID string, command str
 		}
 	}
 
+	// The cached winner, if any, was tried first and failed too.
+	forgetCachedExecEndpoint(instanceID)
 	return "", "", -1, fmt.Errorf("HTTP exec failed for container %d via candidates: %s", vmid, strings.Join(candidates, ", "))
 }
 
@@ -50,10 +50,8 @@ func newExecReadyTestClient(t *testing.T, execHandler http.HandlerFunc) *Client
 	}
 
 	return &Client{
-		apiURL:       apiServer.URL,
-		apiToken:     "token",
+		api:          newTestAPI(t, apiServer),
 		publicDomain: "example.com",
-		apiHTTP:      apiServer.Client(),
 		execHTTP: &http.Client{
 			Transport: &rewriteExecTransport{target: targetURL},
 		},
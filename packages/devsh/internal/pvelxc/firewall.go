@@ -0,0 +1,124 @@
+package pvelxc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	pve "github.com/karlorz/pve-go"
+)
+
+// DefaultTailnetCIDR is the Tailscale address range SSH is allowed from when
+// FirewallOptions.SSHSourceCIDR is empty.
+const DefaultTailnetCIDR = "100.64.0.0/10"
+
+// LockedDownPorts are the cmux services reachable from anywhere on a
+// locked-down container: exec, worker, VS Code, VNC and xterm.
+var LockedDownPorts = []int{39375, 39376, 39378, 39380, 39383}
+
+const firewallRuleComment = "devsh locked-down"
+
+// FirewallOptions replaces the template's firewall with a default-deny
+// inbound policy that only admits the cmux service ports and SSH.
+type FirewallOptions struct {
+	SSHSourceCIDR string // Defaults to DefaultTailnetCIDR
+}
+
+// applyFirewall locks down a stopped container. Rules inherited from the
+// template are removed first so only the devsh rules remain.
+func (c *Client) applyFirewall(ctx context.Context, vmid int, opts FirewallOptions) error {
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return err
+	}
+
+	sshCIDR := strings.TrimSpace(opts.SSHSourceCIDR)
+	if sshCIDR == "" {
+		sshCIDR = DefaultTailnetCIDR
+	}
+
+	existing, err := c.api.LXCFirewallRules(ctx, node, vmid)
+	if err != nil {
+		return fmt.Errorf("list firewall rules: %w", err)
+	}
+	// Delete from the bottom so earlier positions stay valid.
+	sort.Slice(existing, func(i, j int) bool { return existing[i].Pos > existing[j].Pos })
+	for _, rule := range existing {
+		if err := c.api.DeleteLXCFirewallRule(ctx, node, vmid, rule.Pos); err != nil {
+			return fmt.Errorf("delete firewall rule %d: %w", rule.Pos, err)
+		}
+	}
+
+	rules := []pve.FirewallRule{{DPort: "22", Source: sshCIDR}}
+	for _, port := range LockedDownPorts {
+		rules = append(rules, pve.FirewallRule{DPort: strconv.Itoa(port)})
+	}
+	for _, rule := range rules {
+		rule.Type, rule.Action, rule.Proto = "in", "ACCEPT", "tcp"
+		rule.Enable = 1
+		rule.Comment = firewallRuleComment
+		if err := c.api.AddLXCFirewallRule(ctx, node, vmid, rule); err != nil {
+			return fmt.Errorf("add firewall rule for port %s: %w", rule.DPort, err)
+		}
+	}
+
+	config, err := c.getContainerConfig(ctx, vmid)
+	if err != nil {
+		return err
+	}
+	if config.Net0 == "" {
+		return fmt.Errorf("container %d has no net0 interface to firewall", vmid)
+	}
+
+	// A DROP policy also drops DHCP offers and IPv6 neighbor discovery
+	// unless they are allowed explicitly, and the container would come up
+	// without an address. ipfilter only admits the addresses in net0, which
+	// a DHCP lease is not, so it is turned off for DHCP interfaces.
+	options := url.Values{
+		"enable":     []string{"1"},
+		"policy_in":  []string{"DROP"},
+		"policy_out": []string{"ACCEPT"},
+		"dhcp":       []string{"1"},
+		"ndp":        []string{"1"},
+	}
+	if netOption(config.Net0, "ip") == "dhcp" {
+		options.Set("ipfilter", "0")
+	}
+	if err := c.api.UpdateLXCFirewallOptions(ctx, node, vmid, options); err != nil {
+		return fmt.Errorf("enable firewall: %w", err)
+	}
+
+	// PVE only filters traffic on interfaces with firewall=1.
+	if net0 := setNetOption(config.Net0, "firewall", "1"); net0 != config.Net0 {
+		if err := c.api.UpdateLXCConfig(ctx, node, vmid, url.Values{"net0": []string{net0}}); err != nil {
+			return fmt.Errorf("enable firewall on net0: %w", err)
+		}
+	}
+	return nil
+}
+
+// netOption returns the value of key in a PVE netN property string.
+func netOption(net, key string) string {
+	for _, part := range strings.Split(net, ",") {
+		if value, ok := strings.CutPrefix(part, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// setNetOption sets key=value in a PVE netN property string, replacing any
+// existing value for key.
+func setNetOption(net, key, value string) string {
+	parts := strings.Split(net, ",")
+	for i, part := range parts {
+		if strings.HasPrefix(part, key+"=") {
+			parts[i] = key + "=" + value
+			return strings.Join(parts, ",")
+		}
+	}
+	return net + "," + key + "=" + value
+}
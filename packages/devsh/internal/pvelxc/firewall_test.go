@@ -0,0 +1,121 @@
+package pvelxc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetNetOption(t *testing.T) {
+	tests := []struct {
+		net   string
+		key   string
+		value string
+		want  string
+	}{
+		{"name=eth0,bridge=vmbr0,ip=dhcp", "firewall", "1", "name=eth0,bridge=vmbr0,ip=dhcp,firewall=1"},
+		{"name=eth0,bridge=vmbr0,firewall=0,ip=dhcp", "firewall", "1", "name=eth0,bridge=vmbr0,firewall=1,ip=dhcp"},
+		{"name=eth0,firewall=1", "firewall", "1", "name=eth0,firewall=1"},
+		{"name=eth0,ip=dhcp,ip6=auto", "ip", "10.0.0.5/24", "name=eth0,ip=10.0.0.5/24,ip6=auto"},
+	}
+
+	for _, tt := range tests {
+		if got := setNetOption(tt.net, tt.key, tt.value); got != tt.want {
+			t.Errorf("setNetOption(%q, %q, %q) = %q, want %q", tt.net, tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestApplyFirewallReplacesTemplateRules(t *testing.T) {
+	var calls []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm: %v", err)
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/api2/json/nodes/test-node/lxc/200")
+		call := r.Method + " " + path
+		switch {
+		case r.Method == http.MethodPost && path == "/firewall/rules":
+			call += " dport=" + r.PostForm.Get("dport") + " source=" + r.PostForm.Get("source") + " action=" + r.PostForm.Get("action")
+		case r.Method == http.MethodPut:
+			call += " " + r.PostForm.Encode()
+		}
+		calls = append(calls, call)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && path == "/firewall/rules":
+			_, _ = w.Write([]byte(`{"data":[{"pos":0},{"pos":1}]}`))
+		case r.Method == http.MethodGet && path == "/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=dhcp"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":null}`))
+		}
+	}))
+	defer apiServer.Close()
+
+	client := &Client{
+		api:  newTestAPI(t, apiServer),
+		node: "test-node",
+	}
+
+	if err := client.applyFirewall(context.Background(), 200, FirewallOptions{}); err != nil {
+		t.Fatalf("applyFirewall() error = %v", err)
+	}
+
+	want := []string{
+		"GET /firewall/rules",
+		"DELETE /firewall/rules/1",
+		"DELETE /firewall/rules/0",
+		"POST /firewall/rules dport=22 source=100.64.0.0/10 action=ACCEPT",
+		"POST /firewall/rules dport=39375 source= action=ACCEPT",
+		"POST /firewall/rules dport=39376 source= action=ACCEPT",
+		"POST /firewall/rules dport=39378 source= action=ACCEPT",
+		"POST /firewall/rules dport=39380 source= action=ACCEPT",
+		"POST /firewall/rules dport=39383 source= action=ACCEPT",
+		"GET /config",
+		"PUT /firewall/options dhcp=1&enable=1&ipfilter=0&ndp=1&policy_in=DROP&policy_out=ACCEPT",
+		"PUT /config net0=name%3Deth0%2Cbridge%3Dvmbr0%2Cip%3Ddhcp%2Cfirewall%3D1",
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %q, want %q", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestApplyFirewallLeavesIPFilterForStaticIP(t *testing.T) {
+	var options string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm: %v", err)
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/api2/json/nodes/test-node/lxc/200")
+		if r.Method == http.MethodPut && path == "/firewall/options" {
+			options = r.PostForm.Encode()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && path == "/firewall/rules":
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		case r.Method == http.MethodGet && path == "/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=10.0.0.5/24,gw=10.0.0.1,firewall=1"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":null}`))
+		}
+	}))
+	defer apiServer.Close()
+
+	client := &Client{api: newTestAPI(t, apiServer), node: "test-node"}
+	if err := client.applyFirewall(context.Background(), 200, FirewallOptions{}); err != nil {
+		t.Fatalf("applyFirewall() error = %v", err)
+	}
+	if want := "dhcp=1&enable=1&ndp=1&policy_in=DROP&policy_out=ACCEPT"; options != want {
+		t.Errorf("firewall options = %q, want %q", options, want)
+	}
+}
@@ -0,0 +1,213 @@
+package pvelxc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/filelock"
+)
+
+// IPPool is a range of IPv4 addresses handed out as static container IPs, so
+// exec and service URLs work as soon as the container boots instead of
+// waiting for DHCP and DNS.
+type IPPool struct {
+	First   netip.Addr
+	Last    netip.Addr
+	Bits    int        // Prefix length written into net0
+	Gateway netip.Addr // Optional gw= for net0
+}
+
+// ParseIPPool parses "10.100.0.100-10.100.0.199/24" (a range within a /24)
+// or "10.100.0.128/25" (every host address in the prefix). gateway may be
+// empty and is never handed out.
+func ParseIPPool(spec, gateway string) (*IPPool, error) {
+	spec = strings.TrimSpace(spec)
+	rangePart, bitsPart, ok := strings.Cut(spec, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid IP pool %q: missing /prefix", spec)
+	}
+
+	pool := &IPPool{}
+	if first, last, isRange := strings.Cut(rangePart, "-"); isRange {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(first) + "/" + bitsPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP pool %q: %w", spec, err)
+		}
+		if !prefix.Addr().Is4() {
+			return nil, fmt.Errorf("invalid IP pool %q: only IPv4 is supported", spec)
+		}
+		lastAddr, err := netip.ParseAddr(strings.TrimSpace(last))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP pool %q: %w", spec, err)
+		}
+		if !prefix.Contains(lastAddr) || lastAddr.Less(prefix.Addr()) {
+			return nil, fmt.Errorf("invalid IP pool %q: %s is not after %s in the same /%s", spec, lastAddr, prefix.Addr(), bitsPart)
+		}
+		pool.First, pool.Last, pool.Bits = prefix.Addr(), lastAddr, prefix.Bits()
+	} else {
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP pool %q: %w", spec, err)
+		}
+		if !prefix.Addr().Is4() {
+			return nil, fmt.Errorf("invalid IP pool %q: only IPv4 is supported", spec)
+		}
+		prefix = prefix.Masked()
+		pool.First, pool.Last, pool.Bits = prefix.Addr(), lastAddrInPrefix(prefix), prefix.Bits()
+		// Skip the network and broadcast addresses.
+		if pool.Bits < 31 {
+			pool.First, pool.Last = pool.First.Next(), pool.Last.Prev()
+		}
+	}
+
+	if gw := strings.TrimSpace(gateway); gw != "" {
+		addr, err := netip.ParseAddr(gw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP gateway %q: %w", gateway, err)
+		}
+		pool.Gateway = addr
+	}
+	return pool, nil
+}
+
+func lastAddrInPrefix(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().As4()
+	hostBits := 32 - prefix.Bits()
+	for i := 3; i >= 0 && hostBits > 0; i-- {
+		n := min(hostBits, 8)
+		b[i] |= byte(1<<n - 1)
+		hostBits -= n
+	}
+	return netip.AddrFrom4(b)
+}
+
+// next returns the first address in the pool not in used.
+func (p *IPPool) next(used map[netip.Addr]bool) (netip.Addr, bool) {
+	for addr := p.First; addr.IsValid() && !p.Last.Less(addr); addr = addr.Next() {
+		if addr == p.Gateway || used[addr] {
+			continue
+		}
+		return addr, true
+	}
+	return netip.Addr{}, false
+}
+
+// ErrIPPoolExhausted is returned when every pool address is already assigned.
+var ErrIPPoolExhausted = errors.New("pve: IP pool exhausted")
+
+const (
+	// ipPoolLockTimeout bounds how long a start waits for another devsh
+	// process to finish assigning an address.
+	ipPoolLockTimeout = time.Minute
+	// ipReadAttempts is how often a container's net0 is read before the
+	// assignment fails.
+	ipReadAttempts = 3
+	// ipReadBackoff is the wait after the first failed read; it grows
+	// linearly with each attempt.
+	ipReadBackoff = 100 * time.Millisecond
+)
+
+// ipPoolLockPath returns the lock file serializing pool assignment on node
+// across devsh processes. Overridden in tests.
+var ipPoolLockPath = func(node string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "cmux", "pve-ip-pool-"+node+".lock"), nil
+}
+
+// assignStaticIP picks a free pool address and writes it into the
+// container's net0. Addresses in use are read from the other containers'
+// net0 on this node, so the pool should not overlap DHCP or manual ranges.
+// A lock file held until net0 is written keeps concurrent devsh starts on
+// this machine from picking the same address.
+func (c *Client) assignStaticIP(ctx context.Context, vmid int) (string, error) {
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return "", err
+	}
+	lockPath, err := ipPoolLockPath(node)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		return "", err
+	}
+	unlock, err := filelock.Lock(lockPath, ipPoolLockTimeout)
+	if err != nil {
+		return "", fmt.Errorf("lock IP pool: %w", err)
+	}
+	defer unlock()
+
+	containers, err := c.api.LXCList(ctx, node)
+	if err != nil {
+		return "", err
+	}
+	used := make(map[netip.Addr]bool, len(containers))
+	for _, ctr := range containers {
+		if ctr.VMID == vmid {
+			continue
+		}
+		addr, err := c.readContainerAddr(ctx, ctr.VMID)
+		if err != nil {
+			return "", fmt.Errorf("read address of container %d: %w", ctr.VMID, err)
+		}
+		if addr.IsValid() {
+			used[addr] = true
+		}
+	}
+
+	addr, ok := c.ipPool.next(used)
+	if !ok {
+		return "", ErrIPPoolExhausted
+	}
+
+	config, err := c.getContainerConfig(ctx, vmid)
+	if err != nil {
+		return "", err
+	}
+	if config.Net0 == "" {
+		return "", fmt.Errorf("container %d has no net0 interface to assign an IP to", vmid)
+	}
+	net0 := setNetOption(config.Net0, "ip", fmt.Sprintf("%s/%d", addr, c.ipPool.Bits))
+	if c.ipPool.Gateway.IsValid() {
+		net0 = setNetOption(net0, "gw", c.ipPool.Gateway.String())
+	}
+	if err := c.api.UpdateLXCConfig(ctx, node, vmid, url.Values{"net0": []string{net0}}); err != nil {
+		return "", fmt.Errorf("set net0: %w", err)
+	}
+	return addr.String(), nil
+}
+
+// readContainerAddr returns the static IP in a container's net0, or the zero
+// Addr if it has none or was deleted meanwhile. Other failures are retried;
+// a container that still cannot be read is an error, since its address
+// could otherwise be handed out twice.
+func (c *Client) readContainerAddr(ctx context.Context, vmid int) (netip.Addr, error) {
+	for attempt := 1; ; attempt++ {
+		ip, err := c.getContainerIP(ctx, vmid)
+		if err == nil {
+			addr, _ := netip.ParseAddr(ip)
+			return addr, nil
+		}
+		if errors.Is(err, ErrNotFound) {
+			return netip.Addr{}, nil
+		}
+		if attempt == ipReadAttempts {
+			return netip.Addr{}, err
+		}
+		select {
+		case <-ctx.Done():
+			return netip.Addr{}, ctx.Err()
+		case <-time.After(time.Duration(attempt) * ipReadBackoff):
+		}
+	}
+}
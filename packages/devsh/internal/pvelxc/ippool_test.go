@@ -0,0 +1,285 @@
+package pvelxc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useTestIPPoolLock puts the IP pool lock file in a temp dir.
+func useTestIPPoolLock(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	orig := ipPoolLockPath
+	ipPoolLockPath = func(node string) (string, error) {
+		return filepath.Join(dir, "pve-ip-pool-"+node+".lock"), nil
+	}
+	t.Cleanup(func() { ipPoolLockPath = orig })
+}
+
+func TestParseIPPool(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantFirst string
+		wantLast  string
+		wantBits  int
+		wantErr   bool
+	}{
+		{spec: "10.100.0.100-10.100.0.199/24", wantFirst: "10.100.0.100", wantLast: "10.100.0.199", wantBits: 24},
+		{spec: "10.100.0.128/25", wantFirst: "10.100.0.129", wantLast: "10.100.0.254", wantBits: 25},
+		{spec: "10.100.0.7/31", wantFirst: "10.100.0.6", wantLast: "10.100.0.7", wantBits: 31},
+		{spec: "10.100.0.100-10.100.1.5/24", wantErr: true},
+		{spec: "10.100.0.199-10.100.0.100/24", wantErr: true},
+		{spec: "10.100.0.100", wantErr: true},
+		{spec: "fd00::/64", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		pool, err := ParseIPPool(tt.spec, "")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseIPPool(%q) expected error, got %+v", tt.spec, pool)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseIPPool(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if pool.First.String() != tt.wantFirst || pool.Last.String() != tt.wantLast || pool.Bits != tt.wantBits {
+			t.Errorf("ParseIPPool(%q) = %s-%s/%d, want %s-%s/%d", tt.spec, pool.First, pool.Last, pool.Bits, tt.wantFirst, tt.wantLast, tt.wantBits)
+		}
+	}
+}
+
+func TestIPPoolNextSkipsGatewayAndUsed(t *testing.T) {
+	pool, err := ParseIPPool("10.100.0.1-10.100.0.3/24", "10.100.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	used := map[netip.Addr]bool{netip.MustParseAddr("10.100.0.2"): true}
+	if got, ok := pool.next(used); !ok || got.String() != "10.100.0.3" {
+		t.Fatalf("next() = %s, %v; want 10.100.0.3", got, ok)
+	}
+
+	used[netip.MustParseAddr("10.100.0.3")] = true
+	if _, ok := pool.next(used); ok {
+		t.Fatal("expected exhausted pool")
+	}
+}
+
+func TestAssignStaticIP(t *testing.T) {
+	useTestIPPoolLock(t)
+	var putNet0 string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/nodes/test-node/lxc":
+			_, _ = w.Write([]byte(`{"data":[{"vmid":100},{"vmid":101},{"vmid":200}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/nodes/test-node/lxc/100/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=10.100.0.10/24"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/nodes/test-node/lxc/101/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=dhcp"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api2/json/nodes/test-node/lxc/200/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=dhcp"}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/api2/json/nodes/test-node/lxc/200/config":
+			if err := r.ParseForm(); err != nil {
+				t.Fatalf("ParseForm: %v", err)
+			}
+			putNet0 = r.PostForm.Get("net0")
+			_, _ = w.Write([]byte(`{"data":null}`))
+		default:
+			t.Fatalf("unexpected PVE API call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer apiServer.Close()
+
+	pool, err := ParseIPPool("10.100.0.10-10.100.0.20/24", "10.100.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{
+		api:    newTestAPI(t, apiServer),
+		node:   "test-node",
+		ipPool: pool,
+	}
+
+	ip, err := client.assignStaticIP(context.Background(), 200)
+	if err != nil {
+		t.Fatalf("assignStaticIP() error = %v", err)
+	}
+	if ip != "10.100.0.11" {
+		t.Fatalf("assignStaticIP() = %q, want 10.100.0.11", ip)
+	}
+	if want := "name=eth0,bridge=vmbr0,ip=10.100.0.11/24,gw=10.100.0.1"; putNet0 != want {
+		t.Fatalf("net0 = %q, want %q", putNet0, want)
+	}
+}
+
+func TestAssignStaticIPExhausted(t *testing.T) {
+	useTestIPPoolLock(t)
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api2/json/nodes/test-node/lxc":
+			_, _ = w.Write([]byte(`{"data":[{"vmid":100},{"vmid":200}]}`))
+		case "/api2/json/nodes/test-node/lxc/100/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,ip=10.100.0.10/24"}}`))
+		default:
+			t.Fatalf("unexpected PVE API call: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer apiServer.Close()
+
+	pool, err := ParseIPPool("10.100.0.10-10.100.0.10/24", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{api: newTestAPI(t, apiServer), node: "test-node", ipPool: pool}
+
+	if _, err := client.assignStaticIP(context.Background(), 200); !errors.Is(err, ErrIPPoolExhausted) {
+		t.Fatalf("assignStaticIP() error = %v, want ErrIPPoolExhausted", err)
+	}
+}
+
+// newUnreadableContainerAPI serves a node where container 100 was deleted
+// after the list and, if failing is set, container 101's config read always
+// fails with a 502. It returns how often 101's config was read.
+func newUnreadableContainerAPI(t *testing.T, failing bool) (*httptest.Server, *int) {
+	t.Helper()
+	reads := 0
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc":
+			_, _ = w.Write([]byte(`{"data":[{"vmid":100},{"vmid":101},{"vmid":200}]}`))
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc/100/config":
+			// Deleted between the list and the config read
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":{"vmid":"Configuration file 'nodes/test-node/lxc/100.conf' does not exist"}}`))
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc/101/config":
+			reads++
+			if failing {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=10.100.0.10/24"}}`))
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc/200/config":
+			_, _ = w.Write([]byte(`{"data":{"net0":"name=eth0,bridge=vmbr0,ip=dhcp"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":null}`))
+		}
+	}))
+	t.Cleanup(apiServer.Close)
+	return apiServer, &reads
+}
+
+func TestAssignStaticIPSkipsDeletedContainers(t *testing.T) {
+	useTestIPPoolLock(t)
+	apiServer, _ := newUnreadableContainerAPI(t, false)
+
+	pool, err := ParseIPPool("10.100.0.10-10.100.0.20/24", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{api: newTestAPI(t, apiServer), node: "test-node", ipPool: pool}
+
+	ip, err := client.assignStaticIP(context.Background(), 200)
+	if err != nil {
+		t.Fatalf("assignStaticIP() error = %v", err)
+	}
+	if ip != "10.100.0.11" {
+		t.Errorf("assignStaticIP() = %q, want 10.100.0.11", ip)
+	}
+}
+
+func TestAssignStaticIPFailsOnUnreadableContainer(t *testing.T) {
+	useTestIPPoolLock(t)
+	apiServer, reads := newUnreadableContainerAPI(t, true)
+
+	pool, err := ParseIPPool("10.100.0.10-10.100.0.20/24", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{api: newTestAPI(t, apiServer), node: "test-node", ipPool: pool}
+
+	// Container 101 may hold any pool address, so none is safe to hand out.
+	if ip, err := client.assignStaticIP(context.Background(), 200); err == nil {
+		t.Fatalf("assignStaticIP() = %q, want error", ip)
+	}
+	if *reads != ipReadAttempts {
+		t.Errorf("container 101 read %d times, want %d attempts", *reads, ipReadAttempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.assignStaticIP(ctx, 200); !errors.Is(err, context.Canceled) {
+		t.Errorf("assignStaticIP() with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestAssignStaticIPConcurrentClients(t *testing.T) {
+	useTestIPPoolLock(t)
+	var mu sync.Mutex
+	net0 := map[int]string{
+		200: "name=eth0,bridge=vmbr0,ip=dhcp",
+		201: "name=eth0,bridge=vmbr0,ip=dhcp",
+	}
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var vmid int
+		if _, err := fmt.Sscanf(r.URL.Path, "/api2/json/nodes/test-node/lxc/%d/config", &vmid); err == nil {
+			mu.Lock()
+			defer mu.Unlock()
+			if r.Method == http.MethodPut {
+				_ = r.ParseForm()
+				net0[vmid] = r.PostForm.Get("net0")
+				_, _ = w.Write([]byte(`{"data":null}`))
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"net0":%q}}`, net0[vmid])
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/lxc") {
+			// Widen the window between reading used addresses and writing one.
+			time.Sleep(20 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"data":[{"vmid":200},{"vmid":201}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":null}`))
+	}))
+	defer apiServer.Close()
+
+	pool, err := ParseIPPool("10.100.0.10-10.100.0.20/24", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Separate clients share nothing in memory, like two devsh processes.
+	ips := make([]string, 2)
+	var wg sync.WaitGroup
+	for i, vmid := range []int{200, 201} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := &Client{api: newTestAPI(t, apiServer), node: "test-node", ipPool: pool}
+			ip, err := client.assignStaticIP(context.Background(), vmid)
+			if err != nil {
+				t.Error(err)
+			}
+			ips[i] = ip
+		}()
+	}
+	wg.Wait()
+	if ips[0] == ips[1] {
+		t.Errorf("both containers got %s", ips[0])
+	}
+}
@@ -0,0 +1,109 @@
+package pvelxc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// detailedConfigConcurrency bounds parallel config fetches in
+// ListInstancesDetailed so large nodes don't flood the PVE API.
+const detailedConfigConcurrency = 8
+
+// InstanceDetail is an Instance with the resource usage PVE reports in its
+// container list.
+type InstanceDetail struct {
+	Instance
+	Uptime time.Duration
+	CPU    float64 // Fraction of the allotted CPUs in use (0.5 = half)
+	CPUs   float64
+	Mem    uint64 // Bytes
+	MaxMem uint64 // Bytes
+}
+
+// isCmuxHostname reports whether a container is a cmux sandbox. Clones
+// parked by StartOptions.KeepFailed keep the cmux- prefix but are not
+// sandboxes, so they are left out of listings.
+func isCmuxHostname(hostname string) bool {
+	if strings.HasPrefix(hostname, failedContainerPrefix) {
+		return false
+	}
+	return strings.HasPrefix(hostname, "cmux-") || strings.HasPrefix(hostname, "pvelxc-")
+}
+
+// ListInstancesDetailed lists cmux containers with status and resource usage
+// from a single node list call. Configs, needed for the container IP, are
+// fetched concurrently; a failed config fetch leaves that IP empty.
+func (c *Client) ListInstancesDetailed(ctx context.Context) ([]InstanceDetail, error) {
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := c.api.LXCList(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	domainSuffix, _ := c.getDomainSuffix(ctx)
+
+	details := make([]InstanceDetail, 0, len(containers))
+	for _, ctr := range containers {
+		hostname := strings.TrimSpace(ctr.Name)
+		if !isCmuxHostname(hostname) {
+			continue
+		}
+		fqdn := ""
+		if domainSuffix != "" {
+			fqdn = hostname + domainSuffix
+		}
+		details = append(details, InstanceDetail{
+			Instance: Instance{
+				ID:       hostname,
+				VMID:     ctr.VMID,
+				Status:   ctr.Status,
+				Hostname: hostname,
+				FQDN:     fqdn,
+			},
+			Uptime: time.Duration(ctr.Uptime) * time.Second,
+			CPU:    ctr.CPU,
+			CPUs:   ctr.CPUs,
+			Mem:    ctr.Mem,
+			MaxMem: ctr.MaxMem,
+		})
+	}
+
+	sem := make(chan struct{}, detailedConfigConcurrency)
+	var wg sync.WaitGroup
+	for i := range details {
+		wg.Add(1)
+		go func(d *InstanceDetail) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			d.IP, _ = c.getContainerIP(ctx, d.VMID)
+		}(&details[i])
+	}
+	wg.Wait()
+
+	for i := range details {
+		d := &details[i]
+		d.VSCodeURL = c.serviceURLWithIP(39378, d.Hostname, domainSuffix, d.IP)
+		d.WorkerURL = c.serviceURLWithIP(39376, d.Hostname, domainSuffix, d.IP)
+		d.VNCURL = c.serviceURLWithIP(39380, d.Hostname, domainSuffix, d.IP)
+		d.XTermURL = c.serviceURLWithIP(39383, d.Hostname, domainSuffix, d.IP)
+	}
+	return details, nil
+}
+
+// serviceURLWithIP is buildServiceURL with the container IP already known.
+func (c *Client) serviceURLWithIP(port int, hostname, domainSuffix, ip string) string {
+	if serviceURL, ok := c.buildNamedServiceURL(port, hostname, domainSuffix, hostname); ok {
+		return serviceURL
+	}
+	if ip == "" {
+		return ""
+	}
+	return fmt.Sprintf("http://%s:%d", ip, port)
+}
@@ -0,0 +1,106 @@
+package pvelxc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListInstancesDetailed(t *testing.T) {
+	var listCalls, inFlight, maxInFlight atomic.Int32
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api2/json/nodes/test-node/dns":
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc":
+			listCalls.Add(1)
+			var entries []string
+			entries = append(entries, `{"vmid":100,"name":"template","status":"stopped","template":1}`)
+			entries = append(entries, `{"vmid":150,"name":"cmux-failed-cmux-150","status":"stopped"}`)
+			for vmid := 200; vmid < 220; vmid++ {
+				entries = append(entries, fmt.Sprintf(`{"vmid":%d,"name":"cmux-%d","status":"running","uptime":3700,"cpu":0.25,"cpus":4,"mem":536870912,"maxmem":2147483648}`, vmid, vmid))
+			}
+			_, _ = w.Write([]byte(`{"data":[` + strings.Join(entries, ",") + `]}`))
+		case strings.HasSuffix(r.URL.Path, "/config"):
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				prev := maxInFlight.Load()
+				if n <= prev || maxInFlight.CompareAndSwap(prev, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			var vmid int
+			fmt.Sscanf(r.URL.Path, "/api2/json/nodes/test-node/lxc/%d/config", &vmid)
+			_, _ = fmt.Fprintf(w, `{"data":{"net0":"name=eth0,bridge=vmbr0,ip=10.100.0.%d/24"}}`, vmid-200+10)
+		default:
+			t.Errorf("unexpected PVE API call: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	client := &Client{
+		api:  newTestAPI(t, apiServer),
+		node: "test-node",
+	}
+
+	details, err := client.ListInstancesDetailed(context.Background())
+	if err != nil {
+		t.Fatalf("ListInstancesDetailed() error = %v", err)
+	}
+	if len(details) != 20 {
+		t.Fatalf("got %d instances, want 20", len(details))
+	}
+	if listCalls.Load() != 1 {
+		t.Errorf("node list fetched %d times, want 1", listCalls.Load())
+	}
+	if got := maxInFlight.Load(); got > detailedConfigConcurrency {
+		t.Errorf("max concurrent config fetches = %d, want <= %d", got, detailedConfigConcurrency)
+	}
+
+	d := details[0]
+	if d.ID != "cmux-200" || d.Status != "running" || d.IP != "10.100.0.10" {
+		t.Errorf("unexpected instance: %+v", d.Instance)
+	}
+	if d.Uptime != 3700*time.Second || d.CPU != 0.25 || d.CPUs != 4 || d.Mem != 512<<20 || d.MaxMem != 2<<30 {
+		t.Errorf("unexpected usage: uptime=%s cpu=%v cpus=%v mem=%d maxmem=%d", d.Uptime, d.CPU, d.CPUs, d.Mem, d.MaxMem)
+	}
+	if d.VSCodeURL != "http://10.100.0.10:39378" {
+		t.Errorf("VSCodeURL = %q", d.VSCodeURL)
+	}
+}
+
+func TestListInstancesSkipsParkedClones(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api2/json/nodes/test-node/dns":
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case r.URL.Path == "/api2/json/nodes/test-node/lxc":
+			_, _ = w.Write([]byte(`{"data":[{"vmid":150,"name":"cmux-failed-cmux-150","status":"stopped"},{"vmid":200,"name":"cmux-200","status":"running"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/config"):
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		default:
+			t.Errorf("unexpected PVE API call: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	client := &Client{api: newTestAPI(t, apiServer), node: "test-node"}
+	instances, err := client.ListInstances(context.Background())
+	if err != nil {
+		t.Fatalf("ListInstances() error = %v", err)
+	}
+	if len(instances) != 1 || instances[0].ID != "cmux-200" {
+		t.Errorf("ListInstances() = %+v, want only cmux-200", instances)
+	}
+}
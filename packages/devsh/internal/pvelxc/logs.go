@@ -0,0 +1,149 @@
+package pvelxc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pve "github.com/karlorz/pve-go"
+)
+
+// DefaultConsoleLogLines is how much container log is attached to startup
+// failures and printed by `devsh logs` when no count is given.
+const DefaultConsoleLogLines = 100
+
+const consoleLogExecTimeout = 15 * time.Second
+
+type pveSyslogLine struct {
+	N int    `json:"n"`
+	T string `json:"t"`
+}
+
+// StartupError is returned when a container started but never became
+// usable. ConsoleLog holds the tail of the container log, if it could be
+// fetched, so failures are diagnosable without shelling into the node.
+type StartupError struct {
+	InstanceID string
+	ConsoleLog string
+	Err        error
+}
+
+func (e *StartupError) Error() string {
+	if strings.TrimSpace(e.ConsoleLog) == "" {
+		return fmt.Sprintf("%s did not become ready: %v", e.InstanceID, e.Err)
+	}
+	return fmt.Sprintf("%s did not become ready: %v\n--- container log (last lines) ---\n%s", e.InstanceID, e.Err, e.ConsoleLog)
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// ConsoleLogCommand returns a shell command that prints the last lines of
+// the container's system log from inside the container.
+func ConsoleLogCommand(lines int) string {
+	if lines <= 0 {
+		lines = DefaultConsoleLogLines
+	}
+	n := strconv.Itoa(lines)
+	return "journalctl --no-pager -o short -n " + n + " 2>/dev/null" +
+		" || tail -n " + n + " /var/log/syslog 2>/dev/null" +
+		" || dmesg 2>/dev/null | tail -n " + n
+}
+
+// ConsoleLogs returns the last lines of the container's log. The node's
+// journal for the pve-container@<vmid> unit is tried first because it is
+// available even when nothing inside the container is serving; cmux-execd
+// is used as a fallback.
+func (c *Client) ConsoleLogs(ctx context.Context, instanceID string, lines int) (string, error) {
+	if lines <= 0 {
+		lines = DefaultConsoleLogLines
+	}
+
+	vmid, ok := ParseVMID(instanceID)
+	if !ok {
+		resolved, err := c.findVMIDByHostname(ctx, instanceID)
+		if err != nil {
+			return "", err
+		}
+		vmid = resolved
+	}
+
+	hostLog, hostErr := c.hostContainerLog(ctx, vmid, lines)
+	if hostErr == nil && strings.TrimSpace(hostLog) != "" {
+		return hostLog, nil
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, consoleLogExecTimeout)
+	defer cancel()
+	stdout, stderr, exitCode, execErr := c.ExecCommand(execCtx, instanceID, ConsoleLogCommand(lines))
+	if execErr == nil && exitCode == 0 && strings.TrimSpace(stdout) != "" {
+		return strings.TrimRight(stdout, "\n"), nil
+	}
+
+	if hostErr == nil && execErr == nil {
+		if exitCode != 0 {
+			return "", fmt.Errorf("log command exited with code %d: %s", exitCode, strings.TrimSpace(stderr))
+		}
+		return "", nil
+	}
+	return "", errors.Join(hostErr, execErr)
+}
+
+func (c *Client) hostContainerLog(ctx context.Context, vmid int, lines int) (string, error) {
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := pve.Request[[]pveSyslogLine](ctx, c.api, http.MethodGet, fmt.Sprintf("/api2/json/nodes/%s/syslog", node), url.Values{
+		"service": []string{fmt.Sprintf("pve-container@%d", vmid)},
+		"limit":   []string{strconv.Itoa(lines)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return tailSyslogLines(entries, lines), nil
+}
+
+// tailSyslogLines keeps the last n entries, dropping journald's
+// "-- No entries --" placeholder.
+func tailSyslogLines(entries []pveSyslogLine, n int) string {
+	out := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(strings.TrimSpace(entry.T), "-- No entries --") {
+			continue
+		}
+		out = append(out, entry.T)
+	}
+	if n > 0 && len(out) > n {
+		out = out[len(out)-n:]
+	}
+	return strings.Join(out, "\n")
+}
+
+// WithConsoleLogs wraps a readiness failure in a StartupError carrying the
+// tail of the container log. Log collection is best-effort; err is always
+// preserved. Cancellation is passed through untouched.
+func (c *Client) WithConsoleLogs(ctx context.Context, instanceID string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// The caller's context has often expired by now; give log collection
+	// its own budget.
+	logCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	consoleLog, _ := c.ConsoleLogs(logCtx, instanceID, DefaultConsoleLogLines)
+	return &StartupError{
+		InstanceID: instanceID,
+		ConsoleLog: consoleLog,
+		Err:        err,
+	}
+}
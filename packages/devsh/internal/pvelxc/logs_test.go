@@ -0,0 +1,81 @@
+package pvelxc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTailSyslogLines(t *testing.T) {
+	entries := []pveSyslogLine{
+		{N: 1, T: "one"},
+		{N: 2, T: "two"},
+		{N: 3, T: "three"},
+	}
+	if got := tailSyslogLines(entries, 2); got != "two\nthree" {
+		t.Fatalf("tailSyslogLines() = %q", got)
+	}
+	if got := tailSyslogLines([]pveSyslogLine{{N: 1, T: "-- No entries --"}}, 10); got != "" {
+		t.Fatalf("tailSyslogLines() with placeholder = %q, want empty", got)
+	}
+}
+
+func TestConsoleLogCommandDefaultsLines(t *testing.T) {
+	cmd := ConsoleLogCommand(0)
+	if !strings.Contains(cmd, "journalctl --no-pager -o short -n 100") {
+		t.Fatalf("ConsoleLogCommand(0) = %q", cmd)
+	}
+}
+
+func TestConsoleLogsReadsNodeJournal(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/test-node/syslog" {
+			t.Fatalf("unexpected PVE API path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("service"); got != "pve-container@200" {
+			t.Fatalf("service = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[{"n":1,"t":"Starting CT 200"},{"n":2,"t":"startup for container '200' failed"}]}`))
+	}))
+	defer apiServer.Close()
+
+	client := &Client{
+		api:  newTestAPI(t, apiServer),
+		node: "test-node",
+	}
+
+	got, err := client.ConsoleLogs(context.Background(), "cmux-200", 50)
+	if err != nil {
+		t.Fatalf("ConsoleLogs() error = %v", err)
+	}
+	if got != "Starting CT 200\nstartup for container '200' failed" {
+		t.Fatalf("ConsoleLogs() = %q", got)
+	}
+}
+
+func TestStartupErrorIncludesLogAndUnwraps(t *testing.T) {
+	base := errors.New("exec endpoint did not become ready")
+	err := &StartupError{InstanceID: "cmux-200", ConsoleLog: "kernel panic", Err: base}
+
+	if !errors.Is(err, base) {
+		t.Fatal("expected StartupError to unwrap to the readiness error")
+	}
+	if !strings.Contains(err.Error(), "kernel panic") {
+		t.Fatalf("Error() = %q, want console log included", err.Error())
+	}
+}
+
+func TestWithConsoleLogsPassesThroughCancellation(t *testing.T) {
+	client := &Client{}
+	if err := client.WithConsoleLogs(context.Background(), "cmux-200", context.Canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithConsoleLogs() = %v, want context.Canceled", err)
+	}
+	var startupErr *StartupError
+	if errors.As(client.WithConsoleLogs(context.Background(), "cmux-200", context.Canceled), &startupErr) {
+		t.Fatal("cancellation should not be wrapped in StartupError")
+	}
+}
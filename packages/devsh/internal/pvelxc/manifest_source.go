@@ -0,0 +1,162 @@
+package pvelxc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/atomicfile"
+)
+
+const (
+	// ManifestEnvVar points at the snapshot manifest as a local path or an
+	// https:// URL, for binaries that run outside a repo checkout.
+	ManifestEnvVar = "CMUX_PVE_SNAPSHOT_MANIFEST"
+	// ManifestSHA256EnvVar optionally pins the manifest's SHA-256 (hex).
+	ManifestSHA256EnvVar = "CMUX_PVE_SNAPSHOT_MANIFEST_SHA256"
+
+	// manifestCacheTTL is how long a downloaded manifest is used before it
+	// is fetched again. A stale copy is still used if the fetch fails.
+	manifestCacheTTL = 10 * time.Minute
+	// maxManifestBytes caps downloads; the real file is a few KB.
+	maxManifestBytes = 4 << 20
+)
+
+var errManifestNotFound = errors.New("snapshot manifest not found")
+
+// manifestHTTPClient fetches remote manifests; tests swap it for a TLS test
+// server's client.
+var manifestHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// manifestCachePath returns where downloaded manifests are cached, keyed by
+// URL so switching sources never serves the wrong file. Overridden in tests.
+var manifestCachePath = func(rawURL string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	name := "pve-lxc-snapshots-" + hex.EncodeToString(sum[:6]) + ".json"
+	return filepath.Join(home, ".config", "cmux", "cache", name), nil
+}
+
+// loadPveSnapshotManifestBytes returns the raw snapshot manifest from
+// CMUX_PVE_SNAPSHOT_MANIFEST when set, otherwise from the enclosing repo
+// checkout. It returns errManifestNotFound when neither is available.
+func loadPveSnapshotManifestBytes() ([]byte, error) {
+	source := strings.TrimSpace(os.Getenv(ManifestEnvVar))
+	if source == "" {
+		root, ok := findRepoRootForPveManifest()
+		if !ok {
+			return nil, errManifestNotFound
+		}
+		return os.ReadFile(filepath.Join(root, TemplateManifestRelPath))
+	}
+
+	var raw []byte
+	var err error
+	if isManifestURL(source) {
+		raw, err = fetchManifestCached(source)
+	} else if raw, err = os.ReadFile(source); err == nil {
+		err = verifyManifest(raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s=%s: %w", ManifestEnvVar, source, err)
+	}
+	return raw, nil
+}
+
+func isManifestURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// verifyManifest checks that raw parses as a manifest with at least one
+// preset and, when a checksum is pinned, that it matches.
+func verifyManifest(raw []byte) error {
+	if want := strings.ToLower(strings.TrimSpace(os.Getenv(ManifestSHA256EnvVar))); want != "" {
+		sum := sha256.Sum256(raw)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return fmt.Errorf("manifest checksum mismatch: got sha256 %s, want %s", got, want)
+		}
+	}
+	var manifest TemplateManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	if len(manifest.Presets) == 0 {
+		return errors.New("invalid manifest: no presets")
+	}
+	return nil
+}
+
+// fetchManifestCached returns the manifest at rawURL, using the on-disk copy
+// while it is fresh and falling back to a stale copy if the download fails.
+// Only https URLs are fetched.
+func fetchManifestCached(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, errors.New("manifest URL must use https")
+	}
+
+	cachePath, cacheErr := manifestCachePath(rawURL)
+	var cached []byte
+	if cacheErr == nil {
+		if info, err := os.Stat(cachePath); err == nil {
+			if raw, err := os.ReadFile(cachePath); err == nil && verifyManifest(raw) == nil {
+				if time.Since(info.ModTime()) < manifestCacheTTL {
+					return raw, nil
+				}
+				cached = raw
+			}
+		}
+	}
+
+	raw, err := fetchManifest(rawURL)
+	if err == nil {
+		err = verifyManifest(raw)
+	}
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if cacheErr == nil {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err == nil {
+			_ = atomicfile.WriteFile(cachePath, raw, 0o644)
+		}
+	}
+	return raw, nil
+}
+
+func fetchManifest(rawURL string) ([]byte, error) {
+	resp, err := manifestHTTPClient.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch manifest: HTTP %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxManifestBytes {
+		return nil, fmt.Errorf("fetch manifest: larger than %d bytes", maxManifestBytes)
+	}
+	return raw, nil
+}
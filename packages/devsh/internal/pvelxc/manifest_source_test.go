@@ -0,0 +1,122 @@
+package pvelxc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testManifestJSON = `{
+  "schemaVersion": 2,
+  "updatedAt": "2026-07-23T22:55:42Z",
+  "presets": [
+    {
+      "presetId": "4vcpu_8gb_32gb",
+      "label": "Standard workspace",
+      "cpu": "4 vCPU",
+      "memory": "8 GB RAM",
+      "disk": "32 GB SSD",
+      "versions": [
+        {"version": 1, "snapshotId": "snapshot_abc12345", "templateVmid": 9300, "capturedAt": "2026-07-23T22:55:42Z"}
+      ]
+    }
+  ]
+}
+`
+
+func TestManifestFromEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	os.WriteFile(path, []byte(testManifestJSON), 0o644)
+	t.Setenv(ManifestEnvVar, path)
+	t.Setenv(ManifestSHA256EnvVar, "")
+
+	vmid, err := resolveSnapshotFromManifestOrDefault("snapshot_abc12345")
+	if err != nil || vmid != 9300 {
+		t.Fatalf("resolveSnapshotFromManifestOrDefault() = %d, %v", vmid, err)
+	}
+	if got := resolveDefaultSnapshotIDFromManifestOrDefault(); got != "snapshot_abc12345" {
+		t.Fatalf("default snapshot = %q", got)
+	}
+	if got, _ := FindTemplateManifest(); got != path {
+		t.Fatalf("FindTemplateManifest() = %q, want %q", got, path)
+	}
+}
+
+func TestManifestChecksumMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	os.WriteFile(path, []byte(testManifestJSON), 0o644)
+	t.Setenv(ManifestEnvVar, path)
+
+	sum := sha256.Sum256([]byte(testManifestJSON))
+	t.Setenv(ManifestSHA256EnvVar, hex.EncodeToString(sum[:]))
+	if _, err := resolveSnapshotFromManifestOrDefault("snapshot_abc12345"); err != nil {
+		t.Fatalf("matching checksum: %v", err)
+	}
+
+	t.Setenv(ManifestSHA256EnvVar, strings.Repeat("0", 64))
+	_, err := resolveSnapshotFromManifestOrDefault("snapshot_abc12345")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("mismatched checksum error = %v", err)
+	}
+	// The compiled-in default still resolves without a usable manifest.
+	if vmid, err := resolveSnapshotFromManifestOrDefault(defaultSnapshotID); err != nil || vmid != defaultTemplateVMID {
+		t.Fatalf("default fallback = %d, %v", vmid, err)
+	}
+}
+
+func TestManifestFromURLIsCached(t *testing.T) {
+	var hits atomic.Int32
+	var failing atomic.Bool
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(testManifestJSON))
+	}))
+	defer srv.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	origClient, origCache := manifestHTTPClient, manifestCachePath
+	manifestHTTPClient = srv.Client()
+	manifestCachePath = func(string) (string, error) { return cachePath, nil }
+	defer func() { manifestHTTPClient, manifestCachePath = origClient, origCache }()
+
+	t.Setenv(ManifestEnvVar, srv.URL+"/pve-lxc-snapshots.json")
+	t.Setenv(ManifestSHA256EnvVar, "")
+
+	for i := 0; i < 2; i++ {
+		if vmid, err := resolveSnapshotFromManifestOrDefault("snapshot_abc12345"); err != nil || vmid != 9300 {
+			t.Fatalf("resolve #%d = %d, %v", i, vmid, err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("server hits = %d, want 1 (second load should use the cache)", hits.Load())
+	}
+
+	// An expired cache is refreshed, but still used if the refresh fails.
+	old := time.Now().Add(-2 * manifestCacheTTL)
+	os.Chtimes(cachePath, old, old)
+	failing.Store(true)
+	if vmid, err := resolveSnapshotFromManifestOrDefault("snapshot_abc12345"); err != nil || vmid != 9300 {
+		t.Fatalf("stale fallback = %d, %v", vmid, err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("server hits = %d, want 2", hits.Load())
+	}
+}
+
+func TestManifestURLRequiresHTTPS(t *testing.T) {
+	t.Setenv(ManifestEnvVar, "http://example.com/manifest.json")
+	if _, err := loadPveSnapshotManifestBytes(); err == nil || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("loadPveSnapshotManifestBytes() error = %v", err)
+	}
+}
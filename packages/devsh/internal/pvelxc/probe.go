@@ -0,0 +1,209 @@
+package pvelxc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exec endpoint kinds, in the order resolveExecEndpoints tries them.
+const (
+	EndpointPublic   = "public"    // Public proxy (port-39375-<host>.<domain>)
+	EndpointDirectIP = "direct-ip" // Container's static IP
+	EndpointTailnet  = "tailnet"   // Hostname under the node's DNS search domain
+)
+
+const (
+	// endpointProbeTimeout bounds each candidate's probe; they run in
+	// parallel, so it is also roughly how long ProbeEndpoints takes.
+	endpointProbeTimeout = 3 * time.Second
+	// execEndpointCacheTTL is how long a probe winner is tried first.
+	execEndpointCacheTTL = 10 * time.Minute
+)
+
+// EndpointResult is one candidate's outcome in a ProbeReport.
+type EndpointResult struct {
+	Kind      string        `json:"kind"`
+	Host      string        `json:"host"`
+	Reachable bool          `json:"reachable"`
+	Latency   time.Duration `json:"latencyNs"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ProbeReport ranks an instance's exec endpoints: reachable ones first,
+// fastest first, then unreachable ones in candidate order.
+type ProbeReport struct {
+	InstanceID string           `json:"instanceId"`
+	VMID       int              `json:"vmid"`
+	Endpoints  []EndpointResult `json:"endpoints"`
+	// Best is the fastest reachable host, empty if none answered.
+	Best string `json:"best,omitempty"`
+}
+
+// ProbeEndpoints runs a trivial command through every exec candidate in
+// parallel and ranks them. The winner is cached for execEndpointCacheTTL,
+// so later exec calls for the instance try it first.
+func (c *Client) ProbeEndpoints(ctx context.Context, instanceID string) (*ProbeReport, error) {
+	vmid, endpoints, err := c.resolveExecEndpoints(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]EndpointResult, len(endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep execEndpoint) {
+			defer wg.Done()
+			results[i] = c.probeEndpoint(ctx, ep)
+		}(i, ep)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &ProbeReport{InstanceID: instanceID, VMID: vmid, Endpoints: rankEndpoints(results)}
+	if best := report.Endpoints[0]; best.Reachable {
+		report.Best = best.Host
+		saveCachedExecEndpoint(instanceID, best.Host)
+	} else {
+		forgetCachedExecEndpoint(instanceID)
+	}
+	return report, nil
+}
+
+func (c *Client) probeEndpoint(ctx context.Context, ep execEndpoint) EndpointResult {
+	probeCtx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+
+	res := EndpointResult{Kind: ep.Kind, Host: ep.Host}
+	start := time.Now()
+	result, err := c.doHTTPExec(probeCtx, ep.Host, execReadyProbeCommand, endpointProbeTimeout)
+	res.Latency = time.Since(start)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		res.Error = fmt.Sprintf("timed out after %s", endpointProbeTimeout)
+	case err != nil:
+		res.Error = err.Error()
+	case result.ExitCode != 0 || !strings.Contains(result.Stdout, "ready"):
+		res.Error = fmt.Sprintf("probe returned exit=%d stdout=%q", result.ExitCode, result.Stdout)
+	default:
+		res.Reachable = true
+	}
+	return res
+}
+
+// rankEndpoints orders reachable endpoints by latency ahead of unreachable
+// ones, keeping candidate order among equals.
+func rankEndpoints(results []EndpointResult) []EndpointResult {
+	ranked := append([]EndpointResult(nil), results...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Reachable != ranked[j].Reachable {
+			return ranked[i].Reachable
+		}
+		return ranked[i].Reachable && ranked[i].Latency < ranked[j].Latency
+	})
+	return ranked
+}
+
+// preferHost moves best to the front of hosts if present.
+func preferHost(hosts []string, best string) []string {
+	for i, h := range hosts {
+		if h == best {
+			out := make([]string, 0, len(hosts))
+			out = append(out, best)
+			out = append(out, hosts[:i]...)
+			return append(out, hosts[i+1:]...)
+		}
+	}
+	return hosts
+}
+
+type cachedExecEndpoint struct {
+	Host      string    `json:"host"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// execEndpointCachePath returns where probe winners are cached, shared by
+// every devsh invocation. Overridden in tests.
+var execEndpointCachePath = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "cmux", "cache", "pve-exec-endpoints.json"), nil
+}
+
+var execEndpointCacheMu sync.Mutex
+
+func readExecEndpointCache() (string, map[string]cachedExecEndpoint) {
+	path, err := execEndpointCachePath()
+	if err != nil {
+		return "", nil
+	}
+	cache := map[string]cachedExecEndpoint{}
+	if raw, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(raw, &cache)
+	}
+	return path, cache
+}
+
+func writeExecEndpointCache(path string, cache map[string]cachedExecEndpoint) {
+	now := time.Now()
+	for id, entry := range cache {
+		if now.Sub(entry.CheckedAt) > execEndpointCacheTTL {
+			delete(cache, id)
+		}
+	}
+	raw, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, raw, 0o600)
+}
+
+// loadCachedExecEndpoint returns the instance's probe winner while fresh.
+func loadCachedExecEndpoint(instanceID string) (string, bool) {
+	execEndpointCacheMu.Lock()
+	defer execEndpointCacheMu.Unlock()
+	_, cache := readExecEndpointCache()
+	entry, ok := cache[instanceID]
+	if !ok || time.Since(entry.CheckedAt) > execEndpointCacheTTL {
+		return "", false
+	}
+	return entry.Host, true
+}
+
+func saveCachedExecEndpoint(instanceID, host string) {
+	execEndpointCacheMu.Lock()
+	defer execEndpointCacheMu.Unlock()
+	path, cache := readExecEndpointCache()
+	if path == "" {
+		return
+	}
+	cache[instanceID] = cachedExecEndpoint{Host: host, CheckedAt: time.Now()}
+	writeExecEndpointCache(path, cache)
+}
+
+func forgetCachedExecEndpoint(instanceID string) {
+	execEndpointCacheMu.Lock()
+	defer execEndpointCacheMu.Unlock()
+	path, cache := readExecEndpointCache()
+	if _, ok := cache[instanceID]; !ok {
+		return
+	}
+	delete(cache, instanceID)
+	writeExecEndpointCache(path, cache)
+}
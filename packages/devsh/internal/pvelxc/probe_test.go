@@ -0,0 +1,128 @@
+package pvelxc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostRoutingTransport answers exec requests per candidate host: hosts in
+// ok succeed after their delay, every other host refuses the connection.
+type hostRoutingTransport struct {
+	ok map[string]time.Duration
+
+	mu   sync.Mutex
+	seen []string
+}
+
+func (t *hostRoutingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.seen = append(t.seen, req.URL.Host)
+	t.mu.Unlock()
+
+	delay, ok := t.ok[req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	time.Sleep(delay)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("{\"type\":\"stdout\",\"data\":\"ready\"}\n{\"type\":\"exit\",\"code\":0}\n")),
+		Request:    req,
+	}, nil
+}
+
+func newProbeTestClient(t *testing.T, transport http.RoundTripper) *Client {
+	t.Helper()
+	cachePath := filepath.Join(t.TempDir(), "endpoints.json")
+	saved := execEndpointCachePath
+	execEndpointCachePath = func() (string, error) { return cachePath, nil }
+	t.Cleanup(func() { execEndpointCachePath = saved })
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dns"):
+			_, _ = w.Write([]byte(`{"data":{"search":"tail.ts.net"}}`))
+		case strings.HasSuffix(r.URL.Path, "/config"):
+			_, _ = w.Write([]byte(`{"data":{"hostname":"cmux-200","net0":"name=eth0,bridge=vmbr0,ip=10.100.0.20/24"}}`))
+		default:
+			t.Fatalf("unexpected PVE API path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(apiServer.Close)
+
+	return &Client{
+		api:          newTestAPI(t, apiServer),
+		publicDomain: "example.com",
+		execHTTP:     &http.Client{Transport: transport},
+		node:         "test-node",
+	}
+}
+
+func TestProbeEndpointsRanksAndCaches(t *testing.T) {
+	transport := &hostRoutingTransport{ok: map[string]time.Duration{
+		"10.100.0.20:39375":          20 * time.Millisecond,
+		"cmux-200.tail.ts.net:39375": 0,
+	}}
+	client := newProbeTestClient(t, transport)
+
+	report, err := client.ProbeEndpoints(context.Background(), "200")
+	if err != nil {
+		t.Fatalf("ProbeEndpoints: %v", err)
+	}
+	var kinds []string
+	for _, ep := range report.Endpoints {
+		kinds = append(kinds, ep.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "tailnet,direct-ip,public" {
+		t.Fatalf("ranking = %s", got)
+	}
+	if report.Best != "http://cmux-200.tail.ts.net:39375" || report.Endpoints[2].Reachable || report.Endpoints[2].Error == "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	if best, ok := loadCachedExecEndpoint("200"); !ok || best != report.Best {
+		t.Fatalf("cached winner = %q, %v", best, ok)
+	}
+	_, candidates, err := client.resolveExecCandidates(context.Background(), "200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candidates[0] != report.Best {
+		t.Errorf("expected cached winner first, got %v", candidates)
+	}
+}
+
+func TestProbeEndpointsNoneReachable(t *testing.T) {
+	client := newProbeTestClient(t, &hostRoutingTransport{})
+	saveCachedExecEndpoint("200", "http://10.100.0.20:39375")
+
+	report, err := client.ProbeEndpoints(context.Background(), "200")
+	if err != nil {
+		t.Fatalf("ProbeEndpoints: %v", err)
+	}
+	if report.Best != "" {
+		t.Errorf("Best = %q, want none", report.Best)
+	}
+	if _, ok := loadCachedExecEndpoint("200"); ok {
+		t.Error("expected stale winner to be forgotten")
+	}
+}
+
+func TestPreferHost(t *testing.T) {
+	got := preferHost([]string{"a", "b", "c"}, "c")
+	if strings.Join(got, ",") != "c,a,b" {
+		t.Errorf("preferHost = %v", got)
+	}
+	if got := preferHost([]string{"a", "b"}, "z"); strings.Join(got, ",") != "a,b" {
+		t.Errorf("unknown host should keep order, got %v", got)
+	}
+}
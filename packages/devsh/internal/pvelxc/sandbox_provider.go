@@ -9,7 +9,10 @@ import (
 )
 
 // Ensure PVELXCProvider implements provider.SandboxProvider at compile time.
-var _ provider.SandboxProvider = (*PVELXCProvider)(nil)
+var (
+	_ provider.SandboxProvider = (*PVELXCProvider)(nil)
+	_ provider.Lifecycle       = (*PVELXCProvider)(nil)
+)
 
 // PVELXCProvider wraps pvelxc.Client to implement provider.SandboxProvider.
 type PVELXCProvider struct {
@@ -130,6 +133,22 @@ func (p *PVELXCProvider) WaitReady(ctx context.Context, id string, timeout time.
 	}
 }
 
+// Pause suspends a sandbox, preserving its state.
+func (p *PVELXCProvider) Pause(ctx context.Context, id string) error {
+	return p.client.PauseInstance(ctx, id)
+}
+
+// Resume restarts a paused sandbox and waits until it accepts commands.
+func (p *PVELXCProvider) Resume(ctx context.Context, id string) (*provider.Sandbox, error) {
+	if err := p.client.ResumeInstance(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := p.client.WaitForExecReady(ctx, id, 2*time.Minute); err != nil {
+		return nil, p.client.WithConsoleLogs(ctx, id, err)
+	}
+	return p.Get(ctx, id)
+}
+
 // instanceToSandbox converts a pvelxc.Instance to provider.Sandbox.
 func instanceToSandbox(inst *Instance) *provider.Sandbox {
 	return &provider.Sandbox{
@@ -138,6 +157,7 @@ func instanceToSandbox(inst *Instance) *provider.Sandbox {
 		VSCodeURL: inst.VSCodeURL,
 		VNCURL:    inst.VNCURL,
 		WorkerURL: inst.WorkerURL,
+		XTermURL:  inst.XTermURL,
 		Provider:  provider.PveLxc,
 	}
 }
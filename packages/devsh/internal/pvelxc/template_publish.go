@@ -0,0 +1,455 @@
+package pvelxc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/atomicfile"
+	pve "github.com/karlorz/pve-go"
+)
+
+// TemplateManifestRelPath is where the template manifest lives relative to
+// the repo root.
+var TemplateManifestRelPath = filepath.Join("packages", "shared", "src", "pve-lxc-snapshots.json")
+
+const defaultPublishScriptTimeout = 30 * time.Minute
+
+// TemplateManifest mirrors packages/shared/src/pve-lxc-snapshots.json. Field
+// order matches the file so a load/save round-trip only changes what was
+// edited.
+type TemplateManifest struct {
+	SchemaVersion    int              `json:"schemaVersion"`
+	UpdatedAt        string           `json:"updatedAt"`
+	Presets          []TemplatePreset `json:"presets"`
+	BaseTemplateVMID int              `json:"baseTemplateVmid,omitempty"`
+	Node             string           `json:"node,omitempty"`
+}
+
+type TemplatePreset struct {
+	PresetID    string            `json:"presetId"`
+	Label       string            `json:"label"`
+	CPU         string            `json:"cpu"`
+	Memory      string            `json:"memory"`
+	Disk        string            `json:"disk"`
+	Description string            `json:"description,omitempty"`
+	Versions    []TemplateVersion `json:"versions"`
+}
+
+type TemplateVersion struct {
+	Version           int    `json:"version"`
+	SnapshotID        string `json:"snapshotId"`
+	TemplateVMID      int    `json:"templateVmid"`
+	CapturedAt        string `json:"capturedAt"`
+	NovncVersion      string `json:"novncVersion,omitempty"`
+	NovncSource       string `json:"novncSource,omitempty"`
+	NovncPackageState string `json:"novncPackageState,omitempty"`
+}
+
+// LoadTemplateManifest reads the manifest at path.
+func LoadTemplateManifest(path string) (*TemplateManifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest TemplateManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest in the same layout as scripts/snapshot-pvelxc.py
+// (two-space indent, trailing newline), replacing the file atomically.
+func (m *TemplateManifest) Save(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Preset returns the preset with the given ID.
+func (m *TemplateManifest) Preset(presetID string) (*TemplatePreset, bool) {
+	for i := range m.Presets {
+		if m.Presets[i].PresetID == presetID {
+			return &m.Presets[i], true
+		}
+	}
+	return nil, false
+}
+
+// PresetForTemplate returns the preset that has a version backed by vmid.
+func (m *TemplateManifest) PresetForTemplate(vmid int) (*TemplatePreset, *TemplateVersion, bool) {
+	for i := range m.Presets {
+		for j := range m.Presets[i].Versions {
+			if m.Presets[i].Versions[j].TemplateVMID == vmid {
+				return &m.Presets[i], &m.Presets[i].Versions[j], true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// Latest returns the preset's highest version.
+func (p *TemplatePreset) Latest() (*TemplateVersion, bool) {
+	if len(p.Versions) == 0 {
+		return nil, false
+	}
+	latest := &p.Versions[0]
+	for i := range p.Versions[1:] {
+		if v := &p.Versions[i+1]; v.Version > latest.Version {
+			latest = v
+		}
+	}
+	return latest, true
+}
+
+// AppendVersion adds the next version of the preset for templateVMID,
+// carrying over the noVNC metadata of base (which may be nil), and bumps the
+// manifest's updatedAt.
+func (m *TemplateManifest) AppendVersion(presetID string, templateVMID int, snapshotID string, base *TemplateVersion, capturedAt time.Time) (TemplateVersion, error) {
+	preset, ok := m.Preset(presetID)
+	if !ok {
+		return TemplateVersion{}, fmt.Errorf("preset %s not found in manifest", presetID)
+	}
+	next := 1
+	if latest, ok := preset.Latest(); ok {
+		next = latest.Version + 1
+	}
+
+	stamp := capturedAt.UTC().Format("2006-01-02T15:04:05Z")
+	version := TemplateVersion{
+		Version:      next,
+		SnapshotID:   snapshotID,
+		TemplateVMID: templateVMID,
+		CapturedAt:   stamp,
+	}
+	if base != nil {
+		version.NovncVersion = base.NovncVersion
+		version.NovncSource = base.NovncSource
+		version.NovncPackageState = base.NovncPackageState
+	}
+	preset.Versions = append(preset.Versions, version)
+	m.UpdatedAt = stamp
+	return version, nil
+}
+
+func generateSnapshotID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "snapshot_" + hex.EncodeToString(b), nil
+}
+
+// FindTemplateManifest locates a writable manifest: the local file named by
+// CMUX_PVE_SNAPSHOT_MANIFEST, or else the one found by walking up from the
+// working directory.
+func FindTemplateManifest() (string, error) {
+	if source := strings.TrimSpace(os.Getenv(ManifestEnvVar)); source != "" && !isManifestURL(source) {
+		return source, nil
+	}
+	root, ok := findRepoRootForPveManifest()
+	if !ok {
+		return "", fmt.Errorf("snapshot manifest not found (run from the repo or pass a manifest path)")
+	}
+	return filepath.Join(root, TemplateManifestRelPath), nil
+}
+
+// PublishOptions configures PublishTemplate.
+type PublishOptions struct {
+	// PresetID selects the preset to publish a new version of. When empty it
+	// is inferred from SourceTemplateVMID.
+	PresetID string
+	// SourceTemplateVMID is the template to build from. Defaults to the
+	// preset's latest version.
+	SourceTemplateVMID int
+	// ScriptPath is a local provisioning script, run with bash as root
+	// inside the build container.
+	ScriptPath string
+	// ScriptTimeout bounds the provisioning script (default 30m).
+	ScriptTimeout time.Duration
+	// ManifestPath defaults to the manifest found by FindTemplateManifest.
+	ManifestPath string
+	// KeepOnFailure leaves the build container in place for debugging
+	// instead of deleting it.
+	KeepOnFailure bool
+	// Progress, when set, receives a line per step.
+	Progress func(string)
+}
+
+// PublishResult describes a published template version.
+type PublishResult struct {
+	PresetID           string `json:"presetId"`
+	Version            int    `json:"version"`
+	SnapshotID         string `json:"snapshotId"`
+	TemplateVMID       int    `json:"templateVmid"`
+	SourceTemplateVMID int    `json:"sourceTemplateVmid"`
+	CapturedAt         string `json:"capturedAt"`
+	ManifestPath       string `json:"manifestPath"`
+	ScriptOutput       string `json:"scriptOutput,omitempty"`
+}
+
+// PublishTemplate builds a new template version: it clones the source
+// template, runs the provisioning script inside it, shuts it down, converts
+// it to a template, and appends the version to the manifest. The manifest is
+// only written once the template exists; on failure the build container is
+// deleted unless KeepOnFailure is set.
+func (c *Client) PublishTemplate(ctx context.Context, opts PublishOptions) (*PublishResult, error) {
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+	if strings.TrimSpace(opts.ScriptPath) == "" {
+		return nil, errors.New("provisioning script is required")
+	}
+	if _, err := os.Stat(opts.ScriptPath); err != nil {
+		return nil, fmt.Errorf("provisioning script: %w", err)
+	}
+	scriptTimeout := opts.ScriptTimeout
+	if scriptTimeout <= 0 {
+		scriptTimeout = defaultPublishScriptTimeout
+	}
+
+	manifestPath := opts.ManifestPath
+	if manifestPath == "" {
+		found, err := FindTemplateManifest()
+		if err != nil {
+			return nil, err
+		}
+		manifestPath = found
+	}
+	manifest, err := LoadTemplateManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	presetID, sourceVMID, base, err := resolvePublishSource(manifest, opts.PresetID, opts.SourceTemplateVMID)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshotID, err := generateSnapshotID()
+	if err != nil {
+		return nil, err
+	}
+	hostname := "cmux-template-" + strings.TrimPrefix(snapshotID, "snapshot_")
+
+	var vmid int
+	for attempt := 1; ; attempt++ {
+		vmid, err = c.findNextVMID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		progress(fmt.Sprintf("Cloning template %d to %d", sourceVMID, vmid))
+		err = c.cloneFromTemplate(ctx, sourceVMID, pve.CloneOptions{NewID: vmid, Hostname: hostname})
+		if err == nil {
+			break
+		}
+		if attempt == 5 || !(errors.Is(err, ErrVMIDConflict) || errors.Is(err, ErrLocked)) {
+			return nil, fmt.Errorf("clone template %d: %w", sourceVMID, err)
+		}
+		time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+	}
+
+	converted := false
+	defer func() {
+		if converted || opts.KeepOnFailure {
+			return
+		}
+		progress(fmt.Sprintf("Deleting build container %d", vmid))
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		_ = c.deleteContainer(cleanupCtx, vmid)
+	}()
+
+	instanceID := fmt.Sprintf("%d", vmid)
+	progress(fmt.Sprintf("Starting container %d", vmid))
+	if err := c.startContainer(ctx, vmid); err != nil {
+		return nil, fmt.Errorf("start container %d: %w", vmid, err)
+	}
+	if err := c.WaitForExecReady(ctx, instanceID, 3*time.Minute); err != nil {
+		return nil, c.WithConsoleLogs(ctx, instanceID, err)
+	}
+
+	progress(fmt.Sprintf("Running %s", filepath.Base(opts.ScriptPath)))
+	remoteScript := "/tmp/" + snapshotID + "-provision.sh"
+	if _, err := c.PushFileFromEnv(ctx, instanceID, vmid, opts.ScriptPath, remoteScript); err != nil {
+		return nil, fmt.Errorf("upload provisioning script: %w", err)
+	}
+	output, err := c.runProvisioningScript(ctx, instanceID, remoteScript, scriptTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	progress(fmt.Sprintf("Shutting down container %d", vmid))
+	if err := c.shutdownContainer(ctx, vmid); err != nil {
+		return nil, fmt.Errorf("shut down container %d: %w", vmid, err)
+	}
+
+	capturedAt := time.Now()
+	stamp := capturedAt.UTC().Format("2006-01-02T15:04:05Z")
+	if err := c.api.UpdateLXCConfig(ctx, node, vmid, url.Values{
+		"description": []string{templateDescription(snapshotID, presetID, stamp, sourceVMID, base, hostname)},
+		"tags":        []string{templateTags(presetID)},
+	}); err != nil {
+		return nil, fmt.Errorf("set template metadata: %w", err)
+	}
+
+	progress(fmt.Sprintf("Converting container %d to a template", vmid))
+	if err := c.api.ConvertLXCToTemplate(ctx, node, vmid); err != nil {
+		return nil, fmt.Errorf("convert container %d to template: %w", vmid, err)
+	}
+	converted = true
+
+	// Re-read so concurrent edits made while the script ran are kept.
+	manifest, err = LoadTemplateManifest(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("template %d created but manifest reload failed: %w", vmid, err)
+	}
+	version, err := manifest.AppendVersion(presetID, vmid, snapshotID, base, capturedAt)
+	if err != nil {
+		return nil, fmt.Errorf("template %d created but not recorded: %w", vmid, err)
+	}
+	manifest.Node = node
+	if err := manifest.Save(manifestPath); err != nil {
+		return nil, fmt.Errorf("template %d created but manifest write failed: %w", vmid, err)
+	}
+	progress(fmt.Sprintf("Recorded %s as %s version %d", snapshotID, presetID, version.Version))
+
+	return &PublishResult{
+		PresetID:           presetID,
+		Version:            version.Version,
+		SnapshotID:         snapshotID,
+		TemplateVMID:       vmid,
+		SourceTemplateVMID: sourceVMID,
+		CapturedAt:         version.CapturedAt,
+		ManifestPath:       manifestPath,
+		ScriptOutput:       output,
+	}, nil
+}
+
+// resolvePublishSource picks the preset and source template for a publish.
+func resolvePublishSource(manifest *TemplateManifest, presetID string, sourceVMID int) (string, int, *TemplateVersion, error) {
+	if presetID == "" {
+		if sourceVMID <= 0 {
+			return "", 0, nil, errors.New("preset or source template VMID is required")
+		}
+		preset, version, ok := manifest.PresetForTemplate(sourceVMID)
+		if !ok {
+			return "", 0, nil, fmt.Errorf("template %d is not in the manifest; pass a preset", sourceVMID)
+		}
+		return preset.PresetID, sourceVMID, version, nil
+	}
+
+	preset, ok := manifest.Preset(presetID)
+	if !ok {
+		return "", 0, nil, fmt.Errorf("preset %s not found in manifest", presetID)
+	}
+	latest, _ := preset.Latest()
+	if sourceVMID > 0 {
+		return presetID, sourceVMID, latest, nil
+	}
+	if latest == nil || latest.TemplateVMID <= 0 {
+		return "", 0, nil, fmt.Errorf("preset %s has no template to build from", presetID)
+	}
+	return presetID, latest.TemplateVMID, latest, nil
+}
+
+// runProvisioningScript runs the script once on the first reachable exec
+// host. Unlike ExecCommand it never retries a host after the request was
+// sent, since provisioning scripts are rarely idempotent.
+func (c *Client) runProvisioningScript(ctx context.Context, instanceID, remoteScript string, timeout time.Duration) (string, error) {
+	_, candidates, err := c.resolveExecCandidates(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	command := fmt.Sprintf("bash %s 2>&1", ShellSingleQuote(remoteScript))
+
+	var lastErr error
+	for _, host := range candidates {
+		result, err := c.tryHTTPExec(ctx, host, command, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		if result.ExitCode != 0 {
+			return result.Stdout, fmt.Errorf("provisioning script exited with code %d:\n%s", result.ExitCode, tailLines(result.Stdout, 40))
+		}
+		return result.Stdout, nil
+	}
+	return "", fmt.Errorf("run provisioning script: %w", lastErr)
+}
+
+// shutdownContainer stops a container cleanly so its filesystem is
+// consistent, falling back to a hard stop if the shutdown times out.
+func (c *Client) shutdownContainer(ctx context.Context, vmid int) error {
+	node, err := c.getNode(ctx)
+	if err != nil {
+		return err
+	}
+	upid, err := c.api.ShutdownLXC(ctx, node, vmid, time.Minute)
+	if err == nil {
+		err = c.waitForTask(ctx, upid, 2*time.Minute)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return c.stopContainer(ctx, vmid)
+	}
+	return nil
+}
+
+// templateTags and templateDescription match scripts/snapshot-pvelxc.py so
+// templates look the same whichever tool built them.
+func templateTags(presetID string) string {
+	return "cmux;preset-" + strings.ReplaceAll(presetID, "_", "-")
+}
+
+func templateDescription(snapshotID, presetID, capturedAt string, sourceVMID int, base *TemplateVersion, hostname string) string {
+	lines := []string{
+		"cmux template snapshot",
+		"snapshotId: " + snapshotID,
+		"presetId: " + presetID,
+		"capturedAt: " + capturedAt,
+		fmt.Sprintf("sourceVmid: %d", sourceVMID),
+	}
+	if base != nil && base.NovncVersion != "" {
+		lines = append(lines,
+			"novncVersion: "+base.NovncVersion,
+			"novncSource: "+base.NovncSource,
+			"novncPackageState: "+base.NovncPackageState,
+		)
+	}
+	lines = append(lines, "hostname: "+hostname)
+	return strings.Join(lines, "\n")
+}
+
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+package pvelxc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateManifestRoundTripIsByteIdentical(t *testing.T) {
+	src := filepath.Join("..", "..", "..", "shared", "src", "pve-lxc-snapshots.json")
+	want, err := os.ReadFile(src)
+	if err != nil {
+		t.Skipf("manifest not available: %v", err)
+	}
+	manifest, err := LoadTemplateManifest(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "manifest.json")
+	if err := manifest.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(out)
+	if string(got) != string(want) {
+		t.Fatal("Save() changed an unmodified manifest; field order or formatting drifted")
+	}
+}
+
+func testManifest() *TemplateManifest {
+	return &TemplateManifest{
+		SchemaVersion: 2,
+		UpdatedAt:     "2026-01-01T00:00:00Z",
+		Presets: []TemplatePreset{
+			{
+				PresetID: "4vcpu_8gb_32gb",
+				Versions: []TemplateVersion{
+					{Version: 103, SnapshotID: "snapshot_cccc", TemplateVMID: 9128, NovncVersion: "v1.7.0-beta", NovncSource: "github:noVNC/v1.7.0-beta", NovncPackageState: "purged"},
+					{Version: 101, SnapshotID: "snapshot_aaaa", TemplateVMID: 9091},
+				},
+			},
+			{PresetID: "6vcpu_8gb_40gb"},
+		},
+	}
+}
+
+func TestAppendVersionBumpsLatest(t *testing.T) {
+	m := testManifest()
+	base, _ := m.Presets[0].Latest()
+	captured := time.Date(2026, 10, 16, 12, 30, 5, 123, time.FixedZone("PDT", -7*3600))
+
+	v, err := m.AppendVersion("4vcpu_8gb_32gb", 9200, "snapshot_dddd", base, captured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != 104 || v.TemplateVMID != 9200 || v.CapturedAt != "2026-10-16T19:30:05Z" {
+		t.Fatalf("AppendVersion() = %+v", v)
+	}
+	if v.NovncVersion != "v1.7.0-beta" || v.NovncPackageState != "purged" {
+		t.Fatalf("noVNC metadata not carried over: %+v", v)
+	}
+	if m.UpdatedAt != v.CapturedAt {
+		t.Fatalf("UpdatedAt = %q, want %q", m.UpdatedAt, v.CapturedAt)
+	}
+	if latest, _ := m.Presets[0].Latest(); latest.SnapshotID != "snapshot_dddd" {
+		t.Fatalf("Latest() = %+v", latest)
+	}
+
+	if v, _ := m.AppendVersion("6vcpu_8gb_40gb", 9201, "snapshot_eeee", nil, captured); v.Version != 1 {
+		t.Fatalf("first version of empty preset = %d, want 1", v.Version)
+	}
+	if _, err := m.AppendVersion("missing", 9202, "snapshot_ffff", nil, captured); err == nil {
+		t.Fatal("expected error for unknown preset")
+	}
+}
+
+func TestResolvePublishSource(t *testing.T) {
+	m := testManifest()
+
+	preset, vmid, base, err := resolvePublishSource(m, "4vcpu_8gb_32gb", 0)
+	if err != nil || preset != "4vcpu_8gb_32gb" || vmid != 9128 || base.Version != 103 {
+		t.Fatalf("by preset = %q %d %+v %v", preset, vmid, base, err)
+	}
+
+	preset, vmid, base, err = resolvePublishSource(m, "", 9091)
+	if err != nil || preset != "4vcpu_8gb_32gb" || vmid != 9091 || base.Version != 101 {
+		t.Fatalf("by VMID = %q %d %+v %v", preset, vmid, base, err)
+	}
+
+	if _, _, _, err := resolvePublishSource(m, "", 9999); err == nil {
+		t.Fatal("expected error for template not in manifest")
+	}
+	if _, _, _, err := resolvePublishSource(m, "6vcpu_8gb_40gb", 0); err == nil {
+		t.Fatal("expected error for preset without versions")
+	}
+	if _, _, _, err := resolvePublishSource(m, "", 0); err == nil {
+		t.Fatal("expected error without preset or VMID")
+	}
+}
+
+func TestTemplateMetadataMatchesSnapshotScript(t *testing.T) {
+	if got := templateTags("4vcpu_8gb_32gb"); got != "cmux;preset-4vcpu-8gb-32gb" {
+		t.Fatalf("templateTags() = %q", got)
+	}
+	desc := templateDescription("snapshot_dddd", "4vcpu_8gb_32gb", "2026-10-16T19:30:05Z", 9128, &testManifest().Presets[0].Versions[0], "cmux-template-dddd")
+	want := strings.Join([]string{
+		"cmux template snapshot",
+		"snapshotId: snapshot_dddd",
+		"presetId: 4vcpu_8gb_32gb",
+		"capturedAt: 2026-10-16T19:30:05Z",
+		"sourceVmid: 9128",
+		"novncVersion: v1.7.0-beta",
+		"novncSource: github:noVNC/v1.7.0-beta",
+		"novncPackageState: purged",
+		"hostname: cmux-template-dddd",
+	}, "\n")
+	if desc != want {
+		t.Fatalf("templateDescription() =\n%s\nwant\n%s", desc, want)
+	}
+}
@@ -1,15 +1,62 @@
 // Package sandbox provides a factory for creating sandbox providers.
+//
+// Backends are looked up in the provider registry. Importing this package
+// registers the built-in backends; a new backend only needs to call
+// provider.Register from an init function in a package imported here.
 package sandbox
 
 import (
 	"fmt"
 
+	"github.com/karlorz/devsh/internal/docker"
 	"github.com/karlorz/devsh/internal/e2b"
 	"github.com/karlorz/devsh/internal/provider"
 	"github.com/karlorz/devsh/internal/pvelxc"
 	"github.com/karlorz/devsh/internal/vm"
 )
 
+func init() {
+	provider.Register(provider.Morph, func() (provider.SandboxProvider, error) {
+		p, err := vm.NewProvider()
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	provider.Register(provider.PveLxc, newPveLxcProvider)
+	provider.Register(provider.E2B, func() (provider.SandboxProvider, error) {
+		p, err := e2b.NewProvider()
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	provider.Register(provider.Docker, func() (provider.SandboxProvider, error) {
+		p, err := docker.NewProvider()
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+}
+
+// newPveLxcProvider talks to PVE directly when PVE_API_URL and PVE_API_TOKEN
+// are set, and goes through the www API otherwise.
+func newPveLxcProvider() (provider.SandboxProvider, error) {
+	if provider.HasPveEnv() {
+		p, err := pvelxc.NewProvider()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PVE LXC client: %w\nSet PVE_API_URL and PVE_API_TOKEN", err)
+		}
+		return p, nil
+	}
+	p, err := vm.NewPveLxcProvider()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
 // NewProvider creates a SandboxProvider for the given provider name.
 // If name is empty, auto-detects from environment variables.
 // Returns an error if the provider is unknown or cannot be initialized.
@@ -25,16 +72,7 @@ func NewProvider(name string) (provider.SandboxProvider, error) {
 		normalized = provider.DetectFromEnv()
 	}
 
-	switch normalized {
-	case provider.PveLxc:
-		return pvelxc.NewProvider()
-	case provider.Morph:
-		return vm.NewProvider()
-	case provider.E2B:
-		return e2b.NewProvider()
-	default:
-		return nil, fmt.Errorf("unknown provider: %s", normalized)
-	}
+	return provider.New(normalized)
 }
 
 // NewProviderForInstance creates a SandboxProvider based on an instance ID.
@@ -0,0 +1,51 @@
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/karlorz/devsh/internal/provider"
+)
+
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	for _, name := range []string{provider.Morph, provider.PveLxc, provider.E2B, provider.Docker} {
+		if !provider.IsRegistered(name) {
+			t.Errorf("expected %s to be registered", name)
+		}
+	}
+}
+
+func TestNewProviderPveLxcWithoutEnvUsesAPI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PVE_API_URL", "")
+	t.Setenv("PVE_API_TOKEN", "")
+
+	p, err := NewProvider(provider.PveLxc)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if p.Name() != provider.PveLxc {
+		t.Errorf("expected pve-lxc, got %q", p.Name())
+	}
+	if _, ok := p.(provider.TeamScoped); !ok {
+		t.Error("expected API-backed PVE LXC provider to be team scoped")
+	}
+	if _, ok := p.(provider.Lifecycle); !ok {
+		t.Error("expected PVE LXC provider to support pause/resume")
+	}
+}
+
+func TestNewProviderUnknown(t *testing.T) {
+	if _, err := NewProvider("nope"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestNormalizeProviderAcceptsDocker(t *testing.T) {
+	got, err := provider.NormalizeProvider("Docker")
+	if err != nil {
+		t.Fatalf("NormalizeProvider: %v", err)
+	}
+	if got != provider.Docker {
+		t.Errorf("expected docker, got %q", got)
+	}
+}
@@ -0,0 +1,371 @@
+// Package usage records sandbox runtime locally so devsh can estimate spend
+// per workspace. Sessions are opened and closed by the lifecycle commands
+// (start, resume, pause, delete) and reconciled against the provider's
+// instance list when a summary is requested.
+package usage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/karlorz/devsh/internal/atomicfile"
+	"github.com/karlorz/devsh/internal/auth"
+	"github.com/karlorz/devsh/internal/cost"
+	"github.com/karlorz/devsh/internal/filelock"
+)
+
+// Resources assumed when the sandbox size is unknown. They match the cmux
+// API's defaults for a Morph boot.
+const (
+	DefaultVCPUs    = 2
+	DefaultMemoryMB = 4096
+)
+
+// retention is how long finished instances are kept in the store
+const retention = 90 * 24 * time.Hour
+
+// lockTimeout is how long Update waits for another devsh process to finish
+// its own update.
+const lockTimeout = 5 * time.Second
+
+// Session is one stretch of runtime. End is nil while the instance runs.
+type Session struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Record is the usage history of one instance
+type Record struct {
+	InstanceID string    `json:"instanceId"`
+	Workspace  string    `json:"workspace,omitempty"`
+	Team       string    `json:"team,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	VCPUs      int       `json:"vcpus,omitempty"`
+	MemoryMB   int       `json:"memoryMb,omitempty"`
+	Sessions   []Session `json:"sessions"`
+	LastSeen   time.Time `json:"lastSeen"`
+	// AutoPauseAfter is the idle watcher's window (a Go duration), if one
+	// was started for the instance. AutoPausedAt is set while the watcher
+	// has it paused, so the next command on it can resume it.
+	AutoPauseAfter string     `json:"autoPauseAfter,omitempty"`
+	AutoPausedAt   *time.Time `json:"autoPausedAt,omitempty"`
+}
+
+// Open returns the running session, if any
+func (r *Record) Open() *Session {
+	if n := len(r.Sessions); n > 0 && r.Sessions[n-1].End == nil {
+		return &r.Sessions[n-1]
+	}
+	return nil
+}
+
+// WorkspaceName returns the workspace, falling back to the instance ID
+func (r *Record) WorkspaceName() string {
+	if r.Workspace != "" {
+		return r.Workspace
+	}
+	return r.InstanceID
+}
+
+func (r *Record) resources() (int, int) {
+	vcpus, mem := r.VCPUs, r.MemoryMB
+	if vcpus <= 0 {
+		vcpus = DefaultVCPUs
+	}
+	if mem <= 0 {
+		mem = DefaultMemoryMB
+	}
+	return vcpus, mem
+}
+
+// Runtime returns how long the instance ran inside [since, now]
+func (r *Record) Runtime(since, now time.Time) time.Duration {
+	var total time.Duration
+	for _, s := range r.Sessions {
+		start, end := s.Start, now
+		if s.End != nil {
+			end = *s.End
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// Store is the usage file
+type Store struct {
+	Instances map[string]*Record `json:"instances"`
+	path      string
+}
+
+// storePath returns the usage file next to the devsh state file
+func storePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	filename := "cmux_devbox_usage_prod.json"
+	if auth.GetConfig().IsDev {
+		filename = "cmux_devbox_usage_dev.json"
+	}
+	return filepath.Join(home, ".config", "cmux", filename), nil
+}
+
+// Load reads the usage file. A missing file yields an empty store.
+func Load() (*Store, error) {
+	path, err := storePath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the usage file at path
+func LoadFile(path string) (*Store, error) {
+	s := &Store{Instances: map[string]*Record{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Instances == nil {
+		s.Instances = map[string]*Record{}
+	}
+	return s, nil
+}
+
+// Update loads the usage file, applies fn and saves the result, holding a
+// lock so concurrent devsh commands don't lose each other's sessions.
+func Update(fn func(s *Store, now time.Time)) (*Store, error) {
+	path, err := storePath()
+	if err != nil {
+		return nil, err
+	}
+	return UpdateFile(path, fn)
+}
+
+// UpdateFile is Update for the usage file at path
+func UpdateFile(path string, fn func(s *Store, now time.Time)) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	unlock, err := filelock.Lock(path+".lock", lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	s, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	fn(s, now)
+	if err := s.Save(now); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the usage file, dropping instances that finished more than
+// 90 days ago. The file is replaced by a rename, so readers never see a
+// partial write; use Update to keep concurrent writers from racing.
+func (s *Store) Save(now time.Time) error {
+	for id, r := range s.Instances {
+		if r.Open() == nil && now.Sub(r.LastSeen) > retention {
+			delete(s.Instances, id)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(s.path, data, 0600)
+}
+
+// Started opens a session for r.InstanceID at now. Fields set on r fill
+// in the stored record; an already-open session is kept.
+func (s *Store) Started(r Record, now time.Time) {
+	rec := s.Instances[r.InstanceID]
+	if rec == nil {
+		rec = &Record{InstanceID: r.InstanceID}
+		s.Instances[r.InstanceID] = rec
+	}
+	if r.Workspace != "" {
+		rec.Workspace = r.Workspace
+	}
+	if r.Team != "" {
+		rec.Team = r.Team
+	}
+	if r.Provider != "" {
+		rec.Provider = r.Provider
+	}
+	if r.VCPUs > 0 {
+		rec.VCPUs = r.VCPUs
+	}
+	if r.MemoryMB > 0 {
+		rec.MemoryMB = r.MemoryMB
+	}
+	if r.AutoPauseAfter != "" {
+		rec.AutoPauseAfter = r.AutoPauseAfter
+	}
+	if rec.Open() == nil {
+		rec.Sessions = append(rec.Sessions, Session{Start: now})
+	}
+	rec.AutoPausedAt = nil
+	rec.LastSeen = now
+}
+
+// Stopped closes the open session of instanceID at now
+func (s *Store) Stopped(instanceID string, now time.Time) {
+	rec := s.Instances[instanceID]
+	if rec == nil {
+		return
+	}
+	if open := rec.Open(); open != nil {
+		end := now
+		open.End = &end
+	}
+	rec.LastSeen = now
+}
+
+// AutoPaused closes the open session of instanceID at now and marks the
+// instance as paused by the idle watcher.
+func (s *Store) AutoPaused(instanceID string, now time.Time) {
+	rec := s.Instances[instanceID]
+	if rec == nil {
+		rec = &Record{InstanceID: instanceID}
+		s.Instances[instanceID] = rec
+	}
+	s.Stopped(instanceID, now)
+	at := now
+	rec.AutoPausedAt = &at
+}
+
+// Reconcile brings the store in line with the instances a provider reports
+// as running. Running instances without an open session (started outside
+// devsh) get one from now. Instances of provider that are no longer running
+// are closed at the last time devsh saw them, since the real stop time is
+// unknown.
+func (s *Store) Reconcile(provider string, running map[string]bool, now time.Time) {
+	for id := range running {
+		if rec := s.Instances[id]; rec != nil && rec.Open() != nil {
+			rec.LastSeen = now
+			continue
+		}
+		s.Started(Record{InstanceID: id, Provider: provider}, now)
+	}
+	for id, rec := range s.Instances {
+		if running[id] || rec.Provider != provider {
+			continue
+		}
+		if open := rec.Open(); open != nil {
+			end := rec.LastSeen
+			if end.Before(open.Start) {
+				end = open.Start
+			}
+			open.End = &end
+		}
+	}
+}
+
+// WorkspaceSummary is the usage of one workspace over a period
+type WorkspaceSummary struct {
+	Workspace  string        `json:"workspace"`
+	Instances  []string      `json:"instances"`
+	Running    int           `json:"running"`
+	Runtime    time.Duration `json:"-"`
+	Hours      float64       `json:"hours"`
+	VCPUHours  float64       `json:"vcpuHours"`
+	GBHours    float64       `json:"gbHours"`
+	EstCostUSD float64       `json:"estCostUsd"`
+}
+
+// LongRunner is an instance that has been running longer than a threshold
+type LongRunner struct {
+	InstanceID string        `json:"instanceId"`
+	Workspace  string        `json:"workspace"`
+	Since      time.Time     `json:"since"`
+	Running    time.Duration `json:"-"`
+	Hours      float64       `json:"hours"`
+}
+
+// Summarize totals runtime and estimated cost per workspace since since,
+// most expensive first.
+func (s *Store) Summarize(since, now time.Time, rates cost.InstanceRates) []WorkspaceSummary {
+	byWorkspace := map[string]*WorkspaceSummary{}
+	for _, rec := range s.Instances {
+		runtime := rec.Runtime(since, now)
+		open := rec.Open() != nil
+		if runtime == 0 && !open {
+			continue
+		}
+		name := rec.WorkspaceName()
+		ws := byWorkspace[name]
+		if ws == nil {
+			ws = &WorkspaceSummary{Workspace: name}
+			byWorkspace[name] = ws
+		}
+		vcpus, mem := rec.resources()
+		hours := runtime.Hours()
+		ws.Instances = append(ws.Instances, rec.InstanceID)
+		if open {
+			ws.Running++
+		}
+		ws.Runtime += runtime
+		ws.Hours += hours
+		ws.VCPUHours += hours * float64(vcpus)
+		ws.GBHours += hours * float64(mem) / 1024
+		ws.EstCostUSD += hours * rates.Hourly(vcpus, mem)
+	}
+
+	out := make([]WorkspaceSummary, 0, len(byWorkspace))
+	for _, ws := range byWorkspace {
+		sort.Strings(ws.Instances)
+		out = append(out, *ws)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].EstCostUSD != out[j].EstCostUSD {
+			return out[i].EstCostUSD > out[j].EstCostUSD
+		}
+		return out[i].Workspace < out[j].Workspace
+	})
+	return out
+}
+
+// LongRunning returns instances whose current session started more than
+// threshold ago, longest first.
+func (s *Store) LongRunning(threshold time.Duration, now time.Time) []LongRunner {
+	var out []LongRunner
+	for _, rec := range s.Instances {
+		open := rec.Open()
+		if open == nil || now.Sub(open.Start) < threshold {
+			continue
+		}
+		out = append(out, LongRunner{
+			InstanceID: rec.InstanceID,
+			Workspace:  rec.WorkspaceName(),
+			Since:      open.Start,
+			Running:    now.Sub(open.Start),
+			Hours:      now.Sub(open.Start).Hours(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Running > out[j].Running })
+	return out
+}
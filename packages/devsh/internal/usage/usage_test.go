@@ -0,0 +1,161 @@
+package usage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/cost"
+)
+
+var t0 = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := LoadFile(filepath.Join(t.TempDir(), "usage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStartStopRuntime(t *testing.T) {
+	s := newTestStore(t)
+	s.Started(Record{InstanceID: "cmux_a", Workspace: "api", VCPUs: 4, MemoryMB: 8192}, t0)
+	s.Started(Record{InstanceID: "cmux_a"}, t0.Add(time.Hour)) // already running: no new session
+	s.Stopped("cmux_a", t0.Add(2*time.Hour))
+	s.Started(Record{InstanceID: "cmux_a"}, t0.Add(5*time.Hour))
+
+	rec := s.Instances["cmux_a"]
+	if len(rec.Sessions) != 2 || rec.Workspace != "api" || rec.VCPUs != 4 {
+		t.Fatalf("record = %+v", rec)
+	}
+	now := t0.Add(6 * time.Hour)
+	if got := rec.Runtime(t0, now); got != 3*time.Hour {
+		t.Errorf("runtime = %v, want 3h", got)
+	}
+	if got := rec.Runtime(t0.Add(90*time.Minute), now); got != 90*time.Minute {
+		t.Errorf("clipped runtime = %v, want 1h30m", got)
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	s, _ := LoadFile(path)
+	s.Started(Record{InstanceID: "cmux_a", Workspace: "api"}, t0)
+	s.Started(Record{InstanceID: "cmux_old"}, t0.Add(-200*24*time.Hour))
+	s.Stopped("cmux_old", t0.Add(-199*24*time.Hour))
+	if err := s.Save(t0); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Instances["cmux_a"].Open() == nil {
+		t.Error("open session not preserved")
+	}
+	if _, ok := loaded.Instances["cmux_old"]; ok {
+		t.Error("instance finished beyond retention should be pruned")
+	}
+}
+
+func TestUpdateFileConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "usage.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := UpdateFile(path, func(s *Store, now time.Time) {
+				s.Started(Record{InstanceID: id}, now)
+			}); err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprintf("cmux_%02d", i))
+	}
+	wg.Wait()
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Instances) != 20 {
+		t.Errorf("stored %d instances, want 20: concurrent updates were lost", len(loaded.Instances))
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if name := e.Name(); name != "usage.json" && name != "usage.json.lock" {
+			t.Errorf("leftover file %s", name)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	s := newTestStore(t)
+	s.Started(Record{InstanceID: "cmux_gone", Provider: "morph"}, t0)
+	s.Instances["cmux_gone"].LastSeen = t0.Add(30 * time.Minute)
+	s.Started(Record{InstanceID: "pvelxc_1", Provider: "pve-lxc"}, t0)
+
+	now := t0.Add(3 * time.Hour)
+	s.Reconcile("morph", map[string]bool{"cmux_new": true}, now)
+
+	gone := s.Instances["cmux_gone"]
+	if gone.Open() != nil || gone.Runtime(t0, now) != 30*time.Minute {
+		t.Errorf("stopped instance should close at last seen: %+v", gone.Sessions)
+	}
+	if s.Instances["cmux_new"].Open() == nil {
+		t.Error("instance started elsewhere should get an open session")
+	}
+	if s.Instances["pvelxc_1"].Open() == nil {
+		t.Error("other providers must not be closed")
+	}
+}
+
+func TestSummarizeAndLongRunning(t *testing.T) {
+	s := newTestStore(t)
+	s.Started(Record{InstanceID: "cmux_a", Workspace: "api", VCPUs: 4, MemoryMB: 8192}, t0)
+	s.Started(Record{InstanceID: "cmux_b", Workspace: "api"}, t0)
+	s.Stopped("cmux_b", t0.Add(time.Hour))
+	s.Started(Record{InstanceID: "cmux_c"}, t0.Add(9*time.Hour))
+
+	now := t0.Add(10 * time.Hour)
+	rates := cost.InstanceRates{VCPUHour: 0.1, GBHour: 0.01}
+	got := s.Summarize(t0, now, rates)
+	if len(got) != 2 || got[0].Workspace != "api" || got[1].Workspace != "cmux_c" {
+		t.Fatalf("summaries = %+v", got)
+	}
+	api := got[0]
+	// cmux_a: 10h * (4*0.1 + 8*0.01); cmux_b: 1h * (2*0.1 + 4*0.01) with default size
+	wantCost := 10*(0.4+0.08) + (0.2 + 0.04)
+	if math.Abs(api.EstCostUSD-wantCost) > 1e-9 || api.Running != 1 || api.VCPUHours != 42 {
+		t.Errorf("api = %+v, want cost %.2f", api, wantCost)
+	}
+
+	long := s.LongRunning(8*time.Hour, now)
+	if len(long) != 1 || long[0].InstanceID != "cmux_a" || long[0].Running != 10*time.Hour {
+		t.Errorf("long running = %+v", long)
+	}
+}
+
+func TestAutoPaused(t *testing.T) {
+	s := newTestStore(t)
+	s.Started(Record{InstanceID: "cmux_a", AutoPauseAfter: "30m0s"}, t0)
+	s.AutoPaused("cmux_a", t0.Add(time.Hour))
+
+	rec := s.Instances["cmux_a"]
+	if rec.Open() != nil || rec.AutoPausedAt == nil || !rec.AutoPausedAt.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("record = %+v", rec)
+	}
+	s.Started(Record{InstanceID: "cmux_a"}, t0.Add(2*time.Hour))
+	if rec.AutoPausedAt != nil || rec.AutoPauseAfter != "30m0s" {
+		t.Errorf("resume should clear AutoPausedAt and keep the window: %+v", rec)
+	}
+}
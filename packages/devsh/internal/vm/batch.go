@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultStartConcurrency bounds parallel instance creation when
+// StartInstancesOptions.Concurrency is not set.
+const defaultStartConcurrency = 4
+
+// StartSpec describes one instance to start in a batch
+type StartSpec = CreateOptions
+
+// StartInstancesOptions configures StartInstances
+type StartInstancesOptions struct {
+	// Concurrency is the maximum number of instances created at once
+	Concurrency int
+	// ReadyTimeout, if set, waits for each instance to be running
+	ReadyTimeout time.Duration
+	// AllOrNothing stops every started instance if any start fails
+	AllOrNothing bool
+}
+
+// StartResult is the outcome of starting one spec
+type StartResult struct {
+	Spec     StartSpec
+	Instance *Instance
+	Err      error
+	// RolledBack is set when the instance was stopped by AllOrNothing
+	RolledBack bool
+}
+
+// StartInstancesError reports the specs that failed in a batch
+type StartInstancesError struct {
+	Failed []StartResult
+	Total  int
+}
+
+func (e *StartInstancesError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, r := range e.Failed {
+		name := r.Spec.Name
+		if name == "" {
+			name = r.Spec.SnapshotID
+		}
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, r.Err))
+	}
+	return fmt.Sprintf("%d of %d instances failed to start: %s", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
+
+// StartInstances starts several instances in parallel. Results are returned
+// in spec order. If any start fails, the error is a *StartInstancesError;
+// with AllOrNothing, instances that did start are stopped before returning.
+func (c *Client) StartInstances(ctx context.Context, specs []StartSpec, opts StartInstancesOptions) ([]StartResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultStartConcurrency
+	}
+
+	results := make([]StartResult, len(specs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		results[i].Spec = spec
+		wg.Add(1)
+		go func(r *StartResult) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			r.Instance, r.Err = c.startOne(ctx, r.Spec, opts.ReadyTimeout)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var failed []StartResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return results, nil
+	}
+
+	if opts.AllOrNothing {
+		c.rollbackStarted(results)
+	}
+	return results, &StartInstancesError{Failed: failed, Total: len(specs)}
+}
+
+func (c *Client) startOne(ctx context.Context, spec StartSpec, readyTimeout time.Duration) (*Instance, error) {
+	instance, err := c.CreateInstance(ctx, spec)
+	if err != nil || readyTimeout <= 0 {
+		return instance, err
+	}
+	ready, err := c.WaitForReady(ctx, instance.ID, readyTimeout)
+	if err != nil {
+		// Keep the created instance so it can be rolled back
+		return instance, err
+	}
+	return ready, nil
+}
+
+// rollbackStarted stops every instance that was created. It uses its own
+// context so a cancelled batch still cleans up.
+func (c *Client) rollbackStarted(results []StartResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range results {
+		r := &results[i]
+		if r.Instance == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.StopInstance(ctx, r.Instance.ID); err != nil {
+				if r.Err == nil {
+					r.Err = fmt.Errorf("rollback failed: %w", err)
+				}
+				return
+			}
+			r.RolledBack = true
+		}()
+	}
+	wg.Wait()
+}
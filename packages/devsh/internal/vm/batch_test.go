@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+// batchTestServer fakes instance create/stop. Creating a spec named
+// "bad" fails.
+type batchTestServer struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (s *batchTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/stop") {
+		s.mu.Lock()
+		s.stopped = append(s.stopped, strings.Split(r.URL.Path, "/")[5])
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		max := s.maxInFlight.Load()
+		if n <= max || s.maxInFlight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	name, _ := body["name"].(string)
+	if name == "bad" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid snapshot"))
+		return
+	}
+	fmt.Fprintf(w, `{"id":"inst-%s","status":"running"}`, name)
+}
+
+func newBatchTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.CacheAccessToken("test-token", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("CacheAccessToken failed: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &Client{httpClient: server.Client(), baseURL: server.URL, teamSlug: "example-team"}
+}
+
+func TestStartInstancesBoundsConcurrency(t *testing.T) {
+	fake := &batchTestServer{}
+	client := newBatchTestClient(t, fake)
+
+	specs := []StartSpec{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	results, err := client.StartInstances(context.Background(), specs, StartInstancesOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("StartInstances failed: %v", err)
+	}
+	for i, r := range results {
+		if r.Instance == nil || r.Instance.ID != "inst-"+specs[i].Name {
+			t.Errorf("result %d out of order: %+v", i, r)
+		}
+	}
+	if max := fake.maxInFlight.Load(); max > 2 {
+		t.Errorf("expected at most 2 concurrent creates, saw %d", max)
+	}
+}
+
+func TestStartInstancesAggregatesErrors(t *testing.T) {
+	fake := &batchTestServer{}
+	client := newBatchTestClient(t, fake)
+
+	specs := []StartSpec{{Name: "a"}, {Name: "bad"}, {Name: "c"}}
+	results, err := client.StartInstances(context.Background(), specs, StartInstancesOptions{})
+
+	var batchErr *StartInstancesError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected StartInstancesError, got %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0].Spec.Name != "bad" {
+		t.Errorf("unexpected failures: %+v", batchErr.Failed)
+	}
+	if !strings.Contains(err.Error(), "1 of 3") {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if results[0].Instance == nil || results[2].Instance == nil {
+		t.Errorf("successful starts missing from results: %+v", results)
+	}
+	if len(fake.stopped) != 0 {
+		t.Errorf("instances stopped without AllOrNothing: %v", fake.stopped)
+	}
+}
+
+func TestStartInstancesAllOrNothingRollsBack(t *testing.T) {
+	fake := &batchTestServer{}
+	client := newBatchTestClient(t, fake)
+
+	specs := []StartSpec{{Name: "a"}, {Name: "bad"}, {Name: "c"}}
+	results, err := client.StartInstances(context.Background(), specs, StartInstancesOptions{AllOrNothing: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	fake.mu.Lock()
+	stopped := strings.Join(fake.stopped, ",")
+	fake.mu.Unlock()
+	if !strings.Contains(stopped, "inst-a") || !strings.Contains(stopped, "inst-c") || len(fake.stopped) != 2 {
+		t.Errorf("unexpected rollback: %s", stopped)
+	}
+	if !results[0].RolledBack || !results[2].RolledBack || results[1].RolledBack {
+		t.Errorf("RolledBack flags wrong: %+v", results)
+	}
+}
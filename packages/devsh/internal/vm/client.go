@@ -12,8 +12,10 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/karlorz/devsh/internal/auth"
@@ -34,28 +36,9 @@ func readErrorBody(body io.Reader) string {
 // formatAPIError creates a user-friendly error message based on HTTP status code
 // This provides actionable guidance for common authentication and authorization errors
 func formatAPIError(statusCode int, body string, endpoint string) error {
-	switch statusCode {
-	case http.StatusUnauthorized: // 401
-		return fmt.Errorf("authentication failed (401): %s\n\nPossible causes:\n"+
-			"  - Session expired: run 'devsh login' to re-authenticate\n"+
-			"  - Invalid or missing API token\n"+
-			"  - CMUX_TASK_RUN_JWT is invalid or expired (for sub-agent operations)", body)
-	case http.StatusForbidden: // 403
-		return fmt.Errorf("access denied (403): %s\n\nPossible causes:\n"+
-			"  - You don't have permission to access this resource\n"+
-			"  - The resource belongs to a different team\n"+
-			"  - Your team subscription may have expired", body)
-	case http.StatusNotFound: // 404
-		return fmt.Errorf("not found (404): %s\n\nThe requested resource does not exist or you don't have access to it", body)
-	case http.StatusTooManyRequests: // 429
-		return fmt.Errorf("rate limited (429): %s\n\nToo many requests. Please wait a moment and try again", body)
-	case http.StatusInternalServerError: // 500
-		return fmt.Errorf("server error (500): %s\n\nThe server encountered an error. If this persists, please report it", body)
-	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout: // 502, 503, 504
-		return fmt.Errorf("service unavailable (%d): %s\n\nThe service is temporarily unavailable. Please try again later", statusCode, body)
-	default:
-		return fmt.Errorf("API error (%d): %s", statusCode, body)
-	}
+	e := parseAPIError(statusCode, body, endpoint)
+	e.guided = true
+	return e
 }
 
 // Instance represents a VM instance
@@ -68,6 +51,9 @@ type Instance struct {
 	XTermURL        string `json:"xtermUrl"`
 	WorkerURL       string `json:"workerUrl"`
 	ChromeURL       string `json:"chromeUrl"` // Chrome DevTools proxy URL
+
+	// Health is set by CheckServices callers after the instance starts
+	Health *HealthMatrix `json:"health,omitempty"`
 }
 
 // Client is a simple VM management client
@@ -75,6 +61,11 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	teamSlug   string
+	hooks      lifecycleHooks
+
+	// autoTeam resolves the team on first use when SetTeamSlug wasn't called
+	autoTeam bool
+	teamMu   sync.Mutex
 }
 
 // NewClient creates a new VM client
@@ -83,126 +74,29 @@ func NewClient() (*Client, error) {
 	return &Client{
 		httpClient: &http.Client{Timeout: 180 * time.Second}, // 3 minutes for slow Morph operations
 		baseURL:    cfg.ConvexSiteURL,
+		autoTeam:   true,
 	}, nil
 }
 
 // SetTeamSlug sets the team slug for API calls
 func (c *Client) SetTeamSlug(teamSlug string) {
+	c.teamMu.Lock()
+	defer c.teamMu.Unlock()
 	c.teamSlug = teamSlug
 }
 
-// isRetryableStatusCode returns true if the HTTP status code indicates a transient error
-func isRetryableStatusCode(statusCode int) bool {
-	return statusCode == http.StatusBadGateway || // 502
-		statusCode == http.StatusServiceUnavailable || // 503
-		statusCode == http.StatusGatewayTimeout // 504
-}
-
-// isRetryableError returns true if the error indicates a transient network issue
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "connection reset") ||
-		strings.Contains(errStr, "connection refused") ||
-		strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "eof") ||
-		strings.Contains(errStr, "broken pipe")
-}
-
-// retryConfig holds retry parameters
-type retryConfig struct {
-	maxRetries int
-	baseDelay  time.Duration
-}
-
-// defaultRetryConfig returns the default retry configuration
-func defaultRetryConfig() retryConfig {
-	return retryConfig{
-		maxRetries: 2, // 3 total attempts
-		baseDelay:  time.Second,
-	}
-}
-
 // doRequestWithRetry makes an authenticated request with retry for transient errors
 func (c *Client) doRequestWithRetry(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
-	cfg := defaultRetryConfig()
-	var lastErr error
-	var lastResp *http.Response
-
-	for attempt := 0; attempt <= cfg.maxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff with jitter
-			delay := cfg.baseDelay * time.Duration(1<<(attempt-1))
-			jitter := time.Duration(float64(delay) * 0.25 * (0.5 - float64(time.Now().UnixNano()%1000)/1000))
-			time.Sleep(delay + jitter)
-		}
-
-		resp, err := c.doRequest(ctx, method, path, body)
-		if err != nil {
-			if isRetryableError(err) && attempt < cfg.maxRetries {
-				lastErr = err
-				continue
-			}
-			return nil, err
-		}
-
-		// Check for retryable status codes
-		if isRetryableStatusCode(resp.StatusCode) && attempt < cfg.maxRetries {
-			lastResp = resp
-			lastErr = fmt.Errorf("server returned %d", resp.StatusCode)
-			resp.Body.Close()
-			continue
-		}
-
-		return resp, nil
-	}
-
-	if lastResp != nil {
-		return lastResp, nil
-	}
-	return nil, lastErr
+	return withRetry(ctx, defaultRetryConfig(), method, func() (*http.Response, error) {
+		return c.doRequest(ctx, method, path, body)
+	})
 }
 
 // doServerRequestWithRetry makes an authenticated request to apps/server with retry
 func (c *Client) doServerRequestWithRetry(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
-	cfg := defaultRetryConfig()
-	var lastErr error
-	var lastResp *http.Response
-
-	for attempt := 0; attempt <= cfg.maxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff with jitter
-			delay := cfg.baseDelay * time.Duration(1<<(attempt-1))
-			jitter := time.Duration(float64(delay) * 0.25 * (0.5 - float64(time.Now().UnixNano()%1000)/1000))
-			time.Sleep(delay + jitter)
-		}
-
-		resp, err := c.doServerRequest(ctx, method, path, body)
-		if err != nil {
-			if isRetryableError(err) && attempt < cfg.maxRetries {
-				lastErr = err
-				continue
-			}
-			return nil, err
-		}
-
-		// Check for retryable status codes
-		if isRetryableStatusCode(resp.StatusCode) && attempt < cfg.maxRetries {
-			lastResp = resp
-			lastErr = fmt.Errorf("server returned %d", resp.StatusCode)
-			resp.Body.Close()
-			continue
-		}
-
-		return resp, nil
-	}
-
-	if lastResp != nil {
-		return lastResp, nil
-	}
-	return nil, lastErr
+	return withRetry(ctx, defaultRetryConfig(), method, func() (*http.Response, error) {
+		return c.doServerRequest(ctx, method, path, body)
+	})
 }
 
 // doRequest makes an authenticated request to the Convex HTTP API
@@ -273,9 +167,14 @@ type CreateOptions struct {
 }
 
 // CreateInstance creates a new VM instance
-func (c *Client) CreateInstance(ctx context.Context, opts CreateOptions) (*Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+func (c *Client) CreateInstance(ctx context.Context, opts CreateOptions) (instance *Instance, err error) {
+	defer func() {
+		c.observeError("", "create", err)
+		c.observeStatus(instance)
+	}()
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -310,20 +209,22 @@ func (c *Client) CreateInstance(ctx context.Context, opts CreateOptions) (*Insta
 }
 
 // GetInstance gets the status of an instance
-func (c *Client) GetInstance(ctx context.Context, instanceID string) (*Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+func (c *Client) GetInstance(ctx context.Context, instanceID string) (instance *Instance, err error) {
+	defer func() { c.observeStatus(instance) }()
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/v1/cmux/instances/%s?teamSlugOrId=%s", instanceID, c.teamSlug)
-	resp, err := c.doRequest(ctx, "GET", path, nil)
+	resp, err := c.doRequestWithRetry(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result Instance
@@ -335,9 +236,16 @@ func (c *Client) GetInstance(ctx context.Context, instanceID string) (*Instance,
 }
 
 // StopInstance stops (deletes) an instance
-func (c *Client) StopInstance(ctx context.Context, instanceID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+func (c *Client) StopInstance(ctx context.Context, instanceID string) (err error) {
+	defer func() {
+		c.observeError(instanceID, "stop", err)
+		if err == nil {
+			c.observeStatus(&Instance{ID: instanceID, Status: "stopped"})
+		}
+	}()
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -351,16 +259,23 @@ func (c *Client) StopInstance(ctx context.Context, instanceID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
 }
 
 // PauseInstance pauses an instance
-func (c *Client) PauseInstance(ctx context.Context, instanceID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+func (c *Client) PauseInstance(ctx context.Context, instanceID string) (err error) {
+	defer func() {
+		c.observeError(instanceID, "pause", err)
+		if err == nil {
+			c.observeStatus(&Instance{ID: instanceID, Status: "paused"})
+		}
+	}()
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -374,16 +289,18 @@ func (c *Client) PauseInstance(ctx context.Context, instanceID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
 }
 
 // ResumeInstance resumes a paused instance
-func (c *Client) ResumeInstance(ctx context.Context, instanceID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+func (c *Client) ResumeInstance(ctx context.Context, instanceID string) (err error) {
+	defer func() { c.observeError(instanceID, "resume", err) }()
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -397,43 +314,34 @@ func (c *Client) ResumeInstance(ctx context.Context, instanceID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
 }
 
-// ListInstances lists all instances for the team
+// ListInstances lists all instances for the team, following every page
 func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
-	}
+	return c.ListInstancesWithOptions(ctx, ListOptions{})
+}
 
-	path := fmt.Sprintf("/api/v1/cmux/instances?teamSlugOrId=%s", c.teamSlug)
-	resp, err := c.doRequest(ctx, "GET", path, nil)
+// ListInstancesWithOptions lists the team's instances matching opts
+func (c *Client) ListInstancesWithOptions(ctx context.Context, opts ListOptions) ([]Instance, error) {
+	instances := []Instance{}
+	_, err := c.EachInstancePage(ctx, opts, func(page []Instance) error {
+		instances = append(instances, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
-	}
-
-	var result struct {
-		Instances []Instance `json:"instances"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return result.Instances, nil
+	return instances, nil
 }
 
 // ListPveLxcInstances lists PVE LXC instances via the www API.
 func (c *Client) ListPveLxcInstances(ctx context.Context) ([]Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/pve-lxc/instances?teamSlugOrId=%s", url.QueryEscape(c.teamSlug))
@@ -444,7 +352,7 @@ func (c *Client) ListPveLxcInstances(ctx context.Context) ([]Instance, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result struct {
@@ -459,8 +367,8 @@ func (c *Client) ListPveLxcInstances(ctx context.Context) ([]Instance, error) {
 
 // GetPveLxcInstance gets a PVE LXC instance via the www API.
 func (c *Client) GetPveLxcInstance(ctx context.Context, instanceID string) (*Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf(
@@ -475,7 +383,7 @@ func (c *Client) GetPveLxcInstance(ctx context.Context, instanceID string) (*Ins
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result Instance
@@ -493,8 +401,8 @@ func (c *Client) ExecPveLxcInstance(
 	command string,
 	timeoutSeconds int,
 ) (string, string, int, error) {
-	if c.teamSlug == "" {
-		return "", "", -1, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return "", "", -1, err
 	}
 
 	body := map[string]interface{}{
@@ -517,7 +425,7 @@ func (c *Client) ExecPveLxcInstance(
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", -1, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return "", "", -1, newAPIError(resp, "")
 	}
 
 	var result struct {
@@ -534,8 +442,8 @@ func (c *Client) ExecPveLxcInstance(
 
 // PausePveLxcInstance pauses a PVE LXC instance via the www API.
 func (c *Client) PausePveLxcInstance(ctx context.Context, instanceID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -553,7 +461,7 @@ func (c *Client) PausePveLxcInstance(ctx context.Context, instanceID string) err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
@@ -561,8 +469,8 @@ func (c *Client) PausePveLxcInstance(ctx context.Context, instanceID string) err
 
 // ResumePveLxcInstance resumes a PVE LXC instance via the www API.
 func (c *Client) ResumePveLxcInstance(ctx context.Context, instanceID string) (*Instance, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -580,7 +488,7 @@ func (c *Client) ResumePveLxcInstance(ctx context.Context, instanceID string) (*
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result Instance
@@ -593,8 +501,8 @@ func (c *Client) ResumePveLxcInstance(ctx context.Context, instanceID string) (*
 
 // StopPveLxcInstance deletes a PVE LXC instance via the www API.
 func (c *Client) StopPveLxcInstance(ctx context.Context, instanceID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -612,76 +520,36 @@ func (c *Client) StopPveLxcInstance(ctx context.Context, instanceID string) erro
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
 }
 
-// WaitForReady waits for an instance to be ready
-func (c *Client) WaitForReady(ctx context.Context, instanceID string, timeout time.Duration) (*Instance, error) {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		instance, err := c.GetInstance(ctx, instanceID)
-		if err != nil {
-			// Keep trying on transient errors
-			time.Sleep(2 * time.Second)
-			continue
-		}
-
-		if instance.Status == "running" {
-			return instance, nil
-		}
-
-		if instance.Status == "stopped" || instance.Status == "error" {
-			return nil, fmt.Errorf("instance failed with status: %s", instance.Status)
-		}
-
-		time.Sleep(2 * time.Second)
-	}
-
-	return nil, fmt.Errorf("timeout waiting for instance to be ready")
-}
-
 // ExecCommand executes a command in the VM
 func (c *Client) ExecCommand(ctx context.Context, instanceID string, command string) (string, string, int, error) {
-	if c.teamSlug == "" {
-		return "", "", -1, fmt.Errorf("team slug not set")
-	}
-
-	body := map[string]interface{}{
-		"teamSlugOrId": c.teamSlug,
-		"command":      command,
-		"timeout":      60,
-	}
+	return c.ExecCommandWithOptions(ctx, instanceID, command, ExecOptions{})
+}
 
-	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/cmux/instances/%s/exec", instanceID), body)
+// BuildAuthURL returns a worker URL that exchanges token for a session and
+// then redirects to targetPath.
+func BuildAuthURL(workerURL, targetPath, token string) (string, error) {
+	parsed, err := url.Parse(workerURL)
 	if err != nil {
-		return "", "", -1, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", "", -1, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
-	}
-
-	var result struct {
-		Stdout   string `json:"stdout"`
-		Stderr   string `json:"stderr"`
-		ExitCode int    `json:"exit_code"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", "", -1, fmt.Errorf("failed to decode response: %w", err)
+		return "", fmt.Errorf("invalid URL: %w", err)
 	}
-
-	return result.Stdout, result.Stderr, result.ExitCode, nil
+	parsed.Path = "/_cmux/auth"
+	query := parsed.Query()
+	query.Set("token", token)
+	query.Set("return", targetPath)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
 }
 
 // GenerateAuthToken generates a one-time auth token for browser access
 func (c *Client) GenerateAuthToken(ctx context.Context, instanceID string) (string, error) {
-	if c.teamSlug == "" {
-		return "", fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return "", err
 	}
 
 	// First, get the instance to get the worker URL
@@ -717,7 +585,7 @@ func (c *Client) GenerateAuthToken(ctx context.Context, instanceID string) (stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("worker error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return "", newAPIError(resp, "worker request")
 	}
 
 	var result struct {
@@ -732,28 +600,10 @@ func (c *Client) GenerateAuthToken(ctx context.Context, instanceID string) (stri
 
 // GetSSHCredentials gets SSH credentials for an instance
 func (c *Client) GetSSHCredentials(ctx context.Context, instanceID string) (string, error) {
-	if c.teamSlug == "" {
-		return "", fmt.Errorf("team slug not set")
-	}
-
-	path := fmt.Sprintf("/api/v1/cmux/instances/%s/ssh?teamSlugOrId=%s", instanceID, c.teamSlug)
-	resp, err := c.doRequest(ctx, "GET", path, nil)
+	result, err := c.getSSHDetails(ctx, instanceID)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
-	}
-
-	var result struct {
-		SSHCommand string `json:"sshCommand"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
 	return result.SSHCommand, nil
 }
 
@@ -773,10 +623,18 @@ func sshOptions() []string {
 	}
 }
 
+// remoteSyncPathScript prints the directory SyncToVM syncs into. It is a
+// single line so it works reliably over SSH.
+const remoteSyncPathScript = `for p in /home/cmux/workspace /root/workspace /workspace /home/user/project; do [ -d "$p" ] && echo "$p" && exit 0; done; echo "$HOME"`
+
+// InSyncDir wraps a shell command to run in the directory SyncToVM syncs
+// into.
+func InSyncDir(command string) string {
+	return `cd "$(` + remoteSyncPathScript + `)" && ` + command
+}
+
 func resolveRemoteSyncPath(ctx context.Context, sshTarget string) (string, error) {
-	// Use a single-line command that works reliably over SSH
-	script := `for p in /home/cmux/workspace /root/workspace /workspace /home/user/project; do [ -d "$p" ] && echo "$p" && exit 0; done; echo "$HOME"`
-	cmdArgs := append(sshOptions(), sshTarget, script)
+	cmdArgs := append(sshOptions(), sshTarget, remoteSyncPathScript)
 	cmd := exec.CommandContext(ctx, "ssh", cmdArgs...)
 	// Use Output() not CombinedOutput() to avoid stderr (SSH warnings) in the path
 	output, err := cmd.Output()
@@ -931,6 +789,56 @@ func (c *Client) SyncFromVM(ctx context.Context, instanceID string, localPath st
 	return nil
 }
 
+// DownloadFromVM copies the given VM paths into localDir using rsync over
+// SSH. Relative paths are resolved against the sync directory and keep
+// that relative path under localDir, so "dist/report.xml" lands in
+// localDir/dist/report.xml.
+func (c *Client) DownloadFromVM(ctx context.Context, instanceID string, remotePaths []string, localDir string) error {
+	sshCmd, err := c.GetSSHCredentials(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get SSH credentials: %w", err)
+	}
+
+	parts := strings.Fields(sshCmd)
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid SSH command format")
+	}
+	sshTarget := parts[1]
+
+	remotePath, err := resolveRemoteSyncPath(ctx, sshTarget)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return fmt.Errorf("failed to create local directory: %w", err)
+	}
+
+	// The "/./" marker tells --relative which part of the path to keep.
+	rsyncArgs := []string{
+		"-az",
+		"--relative",
+		"-e", "ssh " + strings.Join(sshOptions(), " "),
+	}
+	for _, p := range remotePaths {
+		if !path.IsAbs(p) {
+			p = formatRemotePath(remotePath) + "./" + path.Clean(p)
+		}
+		rsyncArgs = append(rsyncArgs, fmt.Sprintf("%s:%s", sshTarget, p))
+	}
+	rsyncArgs = append(rsyncArgs, filepath.Clean(localDir)+"/")
+
+	cmd := exec.CommandContext(ctx, "rsync", rsyncArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("rsync failed: %w", err)
+	}
+
+	return nil
+}
+
 // PtySession represents a PTY session
 type PtySession struct {
 	ID          string `json:"id"`
@@ -940,8 +848,8 @@ type PtySession struct {
 
 // ListPtySessions lists all PTY sessions in a VM
 func (c *Client) ListPtySessions(ctx context.Context, instanceID string) ([]PtySession, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	// Get instance to get worker URL
@@ -977,7 +885,7 @@ func (c *Client) ListPtySessions(ctx context.Context, instanceID string) ([]PtyS
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("worker error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "worker request")
 	}
 
 	var result struct {
@@ -1022,7 +930,7 @@ func (c *Client) ListTeams(ctx context.Context) (*ListTeamsResult, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result ListTeamsResult
@@ -1046,7 +954,7 @@ func (c *Client) SwitchTeam(ctx context.Context, teamSlugOrId string) (*SwitchTe
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result SwitchTeamResult
@@ -1054,6 +962,13 @@ func (c *Client) SwitchTeam(ctx context.Context, teamSlugOrId string) (*SwitchTe
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	// Keep the local default in step with the server selection
+	team := auth.DefaultTeam{TeamID: result.TeamID, Slug: result.TeamSlug, DisplayName: result.TeamDisplayName}
+	if err := auth.SetDefaultTeam(team); err != nil {
+		return &result, fmt.Errorf("switched team but failed to save default: %w", err)
+	}
+	c.SetTeamSlug(team.SlugOrID())
+
 	return &result, nil
 }
 
@@ -1136,7 +1051,8 @@ type TaskDetail struct {
 
 // ListTasksResult represents the result of listing tasks
 type ListTasksResult struct {
-	Tasks []Task `json:"tasks"`
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // Environment represents a sandbox environment configuration
@@ -1231,36 +1147,29 @@ type StartSandboxResult struct {
 // StartWorkspaceResult represents the result of starting a personal workspace.
 type StartWorkspaceResult = StartSandboxResult
 
-// ListTasks lists all tasks for the team
+// ListTasks lists all tasks for the team, following every page
 func (c *Client) ListTasks(ctx context.Context, archived bool) (*ListTasksResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
-	}
+	return c.ListTasksWithOptions(ctx, archived, ListOptions{})
+}
 
-	path := fmt.Sprintf("/api/v1/cmux/tasks?teamSlugOrId=%s&archived=%t", c.teamSlug, archived)
-	resp, err := c.doRequest(ctx, "GET", path, nil)
+// ListTasksWithOptions lists the team's tasks matching opts
+func (c *Client) ListTasksWithOptions(ctx context.Context, archived bool, opts ListOptions) (*ListTasksResult, error) {
+	result := &ListTasksResult{Tasks: []Task{}}
+	_, err := c.EachTaskPage(ctx, archived, opts, func(page []Task) error {
+		result.Tasks = append(result.Tasks, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, formatAPIError(resp.StatusCode, readErrorBody(resp.Body), "list tasks")
-	}
-
-	var result ListTasksResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return &result, nil
+	return result, nil
 }
 
 // ListEnvironments lists all environments for the team.
 // /api/environments is a Hono route on the www app, not a Convex site route.
 func (c *Client) ListEnvironments(ctx context.Context) ([]Environment, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/environments?teamSlugOrId=%s", c.teamSlug)
@@ -1271,7 +1180,7 @@ func (c *Client) ListEnvironments(ctx context.Context) ([]Environment, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result []Environment
@@ -1305,8 +1214,8 @@ func (c *Client) FindEnvironmentForRepo(ctx context.Context, repository string)
 
 // CreateTask creates a new task with optional task runs
 func (c *Client) CreateTask(ctx context.Context, opts CreateTaskOptions) (*CreateTaskResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -1383,7 +1292,7 @@ func (c *Client) CreateTask(ctx context.Context, opts CreateTaskOptions) (*Creat
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result CreateTaskResult
@@ -1396,8 +1305,8 @@ func (c *Client) CreateTask(ctx context.Context, opts CreateTaskOptions) (*Creat
 
 // CreateStorageUploadURL returns a one-time Convex upload URL for storing a file.
 func (c *Client) CreateStorageUploadURL(ctx context.Context) (string, error) {
-	if c.teamSlug == "" {
-		return "", fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return "", err
 	}
 
 	body := map[string]interface{}{
@@ -1411,7 +1320,7 @@ func (c *Client) CreateStorageUploadURL(ctx context.Context) (string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return "", newAPIError(resp, "")
 	}
 
 	var result struct {
@@ -1454,7 +1363,7 @@ func (c *Client) UploadFileToStorage(ctx context.Context, filePath string) (stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("upload failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return "", newAPIError(resp, "upload")
 	}
 
 	var result struct {
@@ -1471,8 +1380,8 @@ func (c *Client) UploadFileToStorage(ctx context.Context, filePath string) (stri
 }
 
 func (c *Client) postSandboxStart(ctx context.Context, body map[string]interface{}) (*StartSandboxResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	resp, err := c.doWwwRequest(ctx, "POST", "/api/sandboxes/start", body)
@@ -1482,7 +1391,7 @@ func (c *Client) postSandboxStart(ctx context.Context, body map[string]interface
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("sandbox start failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "sandbox start")
 	}
 
 	var result StartSandboxResult
@@ -1599,8 +1508,8 @@ type GetProjectFieldsResult struct {
 
 // ListProjects calls GET /api/integrations/github/projects.
 func (c *Client) ListProjects(ctx context.Context, opts ListProjectsOptions) (*ListProjectsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if opts.InstallationID <= 0 {
 		return nil, fmt.Errorf("installation ID is required")
@@ -1624,7 +1533,7 @@ func (c *Client) ListProjects(ctx context.Context, opts ListProjectsOptions) (*L
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("project list failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "project list")
 	}
 
 	var result ListProjectsResult
@@ -1637,8 +1546,8 @@ func (c *Client) ListProjects(ctx context.Context, opts ListProjectsOptions) (*L
 
 // GetProjectFields calls GET /api/integrations/github/projects/fields.
 func (c *Client) GetProjectFields(ctx context.Context, opts GetProjectFieldsOptions) (*GetProjectFieldsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if opts.ProjectID == "" {
 		return nil, fmt.Errorf("project ID is required")
@@ -1660,7 +1569,7 @@ func (c *Client) GetProjectFields(ctx context.Context, opts GetProjectFieldsOpti
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("project fields fetch failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "project fields fetch")
 	}
 
 	var result GetProjectFieldsResult
@@ -1713,8 +1622,8 @@ type GetProjectItemsResult struct {
 
 // GetProjectItems calls GET /api/integrations/github/projects/items.
 func (c *Client) GetProjectItems(ctx context.Context, opts GetProjectItemsOptions) (*GetProjectItemsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if opts.ProjectID == "" {
 		return nil, fmt.Errorf("project ID is required")
@@ -1751,7 +1660,7 @@ func (c *Client) GetProjectItems(ctx context.Context, opts GetProjectItemsOption
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("project items fetch failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "project items fetch")
 	}
 
 	var result GetProjectItemsResult
@@ -1789,8 +1698,8 @@ type BatchCreateDraftsResult struct {
 
 // BatchCreateDrafts calls POST /api/integrations/github/projects/drafts/batch.
 func (c *Client) BatchCreateDrafts(ctx context.Context, opts BatchCreateDraftsOptions) (*BatchCreateDraftsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if opts.ProjectID == "" {
 		return nil, fmt.Errorf("project ID is required")
@@ -1819,7 +1728,7 @@ func (c *Client) BatchCreateDrafts(ctx context.Context, opts BatchCreateDraftsOp
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("batch draft creation failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "batch draft creation")
 	}
 
 	var result BatchCreateDraftsResult
@@ -1850,8 +1759,8 @@ type RecordSandboxCreateRequest struct {
 
 // RecordSandboxCreate registers a sandbox instance ownership/activity record on the www API.
 func (c *Client) RecordSandboxCreate(ctx context.Context, req RecordSandboxCreateRequest) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 	if strings.TrimSpace(req.InstanceID) == "" {
 		return fmt.Errorf("instance ID is required")
@@ -1890,7 +1799,7 @@ func (c *Client) RecordSandboxCreate(ctx context.Context, req RecordSandboxCreat
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("record-create failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "record-create")
 	}
 
 	return nil
@@ -1899,8 +1808,8 @@ func (c *Client) RecordSandboxCreate(ctx context.Context, req RecordSandboxCreat
 // SetupProviders configures Claude + Codex provider auth on an existing sandbox.
 // Calls POST /api/sandboxes/{id}/setup-providers on the www API.
 func (c *Client) SetupProviders(ctx context.Context, instanceID string) (*SetupProvidersResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -1915,7 +1824,7 @@ func (c *Client) SetupProviders(ctx context.Context, instanceID string) (*SetupP
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("setup-providers failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "setup-providers")
 	}
 
 	var result SetupProvidersResult
@@ -1928,8 +1837,8 @@ func (c *Client) SetupProviders(ctx context.Context, instanceID string) (*SetupP
 
 // GetTask gets the details of a specific task
 func (c *Client) GetTask(ctx context.Context, taskID string) (*TaskDetail, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/v1/cmux/tasks/%s?teamSlugOrId=%s", taskID, c.teamSlug)
@@ -1953,8 +1862,8 @@ func (c *Client) GetTask(ctx context.Context, taskID string) (*TaskDetail, error
 
 // StopTask stops/archives a task
 func (c *Client) StopTask(ctx context.Context, taskID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -1976,8 +1885,8 @@ func (c *Client) StopTask(ctx context.Context, taskID string) error {
 
 // ToggleTaskPin toggles a task's pinned state and returns the new state.
 func (c *Client) ToggleTaskPin(ctx context.Context, taskID string) (bool, error) {
-	if c.teamSlug == "" {
-		return false, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return false, err
 	}
 
 	body := map[string]interface{}{
@@ -1991,7 +1900,7 @@ func (c *Client) ToggleTaskPin(ctx context.Context, taskID string) (bool, error)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return false, newAPIError(resp, "")
 	}
 
 	var result struct {
@@ -2006,8 +1915,8 @@ func (c *Client) ToggleTaskPin(ctx context.Context, taskID string) (bool, error)
 
 // ArchiveTask archives a task and all of its runs.
 func (c *Client) ArchiveTask(ctx context.Context, taskID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -2021,7 +1930,7 @@ func (c *Client) ArchiveTask(ctx context.Context, taskID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
@@ -2029,8 +1938,8 @@ func (c *Client) ArchiveTask(ctx context.Context, taskID string) error {
 
 // UnarchiveTask unarchives a task and all of its runs.
 func (c *Client) UnarchiveTask(ctx context.Context, taskID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -2044,7 +1953,7 @@ func (c *Client) UnarchiveTask(ctx context.Context, taskID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return newAPIError(resp, "")
 	}
 
 	return nil
@@ -2157,8 +2066,8 @@ func (c *Client) doServerRequestWithJwt(ctx context.Context, method, path string
 // StartTaskAgents starts agents for a task using the same flow as web app
 // This calls apps/server HTTP API which uses the same agentSpawner as socket.io
 func (c *Client) StartTaskAgents(ctx context.Context, opts StartTaskAgentsOptions) (*StartTaskAgentsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -2220,7 +2129,7 @@ func (c *Client) StartTaskAgents(ctx context.Context, opts StartTaskAgentsOption
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, fmt.Errorf("start-task failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "start-task")
 	}
 
 	var result StartTaskAgentsResult
@@ -2287,8 +2196,8 @@ type TaskQualityGateResponse struct {
 
 // GetTaskQualityGate fetches quality gate status and retry context for a task.
 func (c *Client) GetTaskQualityGate(ctx context.Context, taskID string, maxRetries int, limit int) (*TaskQualityGateResponse, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if maxRetries < 0 {
 		maxRetries = 0
@@ -2311,7 +2220,7 @@ func (c *Client) GetTaskQualityGate(ctx context.Context, taskID string, maxRetri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result TaskQualityGateResponse
@@ -2343,8 +2252,8 @@ type CreateCloudWorkspaceResult struct {
 // CreateCloudWorkspace creates a cloud workspace without running an agent
 // This spawns a sandbox with VSCode access, matching the web UI flow
 func (c *Client) CreateCloudWorkspace(ctx context.Context, opts CreateCloudWorkspaceOptions) (*CreateCloudWorkspaceResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -2401,8 +2310,8 @@ type GetTaskRunMemoryResult struct {
 
 // GetTaskRunWithPty gets a task run with PTY session info for terminal attachment
 func (c *Client) GetTaskRunWithPty(ctx context.Context, taskRunID string) (*TaskRun, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/v1/cmux/task-runs/%s?teamSlugOrId=%s", taskRunID, c.teamSlug)
@@ -2413,7 +2322,7 @@ func (c *Client) GetTaskRunWithPty(ctx context.Context, taskRunID string) (*Task
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result TaskRun
@@ -2426,8 +2335,8 @@ func (c *Client) GetTaskRunWithPty(ctx context.Context, taskRunID string) (*Task
 
 // GetTaskRunMemory gets memory snapshots for a specific task run
 func (c *Client) GetTaskRunMemory(ctx context.Context, taskRunID string, memoryType string) (*GetTaskRunMemoryResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/v1/cmux/task-runs/%s/memory?teamSlugOrId=%s", taskRunID, c.teamSlug)
@@ -2442,7 +2351,7 @@ func (c *Client) GetTaskRunMemory(ctx context.Context, taskRunID string, memoryT
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result GetTaskRunMemoryResult
@@ -2529,8 +2438,8 @@ type OrchestrationStatusResult struct {
 
 // OrchestrationSpawn spawns an agent with orchestration tracking
 func (c *Client) OrchestrationSpawn(ctx context.Context, opts OrchestrationSpawnOptions) (*OrchestrationSpawnResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	body := map[string]interface{}{
@@ -2617,8 +2526,8 @@ func (c *Client) OrchestrationSpawn(ctx context.Context, opts OrchestrationSpawn
 
 // OrchestrationList lists orchestration tasks for the team
 func (c *Client) OrchestrationList(ctx context.Context, status string) (*OrchestrationListResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/orchestrate/list?teamSlugOrId=%s", c.teamSlug)
@@ -2646,8 +2555,8 @@ func (c *Client) OrchestrationList(ctx context.Context, status string) (*Orchest
 
 // OrchestrationStatus gets the status of an orchestration task
 func (c *Client) OrchestrationStatus(ctx context.Context, orchTaskID string) (*OrchestrationStatusResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/orchestrate/status/%s?teamSlugOrId=%s", orchTaskID, c.teamSlug)
@@ -2672,8 +2581,8 @@ func (c *Client) OrchestrationStatus(ctx context.Context, orchTaskID string) (*O
 
 // OrchestrationCancel cancels an orchestration task
 func (c *Client) OrchestrationCancel(ctx context.Context, orchTaskID string) error {
-	if c.teamSlug == "" {
-		return fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
 	}
 
 	body := map[string]interface{}{
@@ -2738,8 +2647,8 @@ type OrchestrationMigrateResult struct {
 
 // OrchestrationMigrate migrates local orchestration state to a sandbox
 func (c *Client) OrchestrationMigrate(ctx context.Context, opts OrchestrationMigrateOptions) (*OrchestrationMigrateResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	if opts.PlanJson == "" {
@@ -2773,7 +2682,7 @@ func (c *Client) OrchestrationMigrate(ctx context.Context, opts OrchestrationMig
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("orchestration migrate failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "orchestration migrate")
 	}
 
 	var result OrchestrationMigrateResult
@@ -2815,8 +2724,8 @@ func (c *Client) OrchestrationResults(ctx context.Context, orchestrationID strin
 	if taskRunJwt != "" {
 		resp, err = c.doServerRequestWithJwt(ctx, "GET", path, nil, taskRunJwt)
 	} else {
-		if c.teamSlug == "" {
-			return nil, fmt.Errorf("team slug not set")
+		if err := c.ensureTeam(ctx); err != nil {
+			return nil, err
 		}
 		path = fmt.Sprintf("/api/orchestrate/results/%s?teamSlugOrId=%s", orchestrationID, c.teamSlug)
 		resp, err = c.doServerRequest(ctx, "GET", path, nil)
@@ -2827,7 +2736,7 @@ func (c *Client) OrchestrationResults(ctx context.Context, orchestrationID strin
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("orchestration results failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "orchestration results")
 	}
 
 	var result OrchestrationResultsResult
@@ -2997,8 +2906,8 @@ func (c *Client) SubscribeOrchestrationEvents(ctx context.Context, orchestration
 
 // OrchestrationMetrics gets orchestration metrics including provider health
 func (c *Client) OrchestrationMetrics(ctx context.Context) (*OrchestrationMetricsResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/orchestrate/metrics?teamSlugOrId=%s", c.teamSlug)
@@ -3010,7 +2919,7 @@ func (c *Client) OrchestrationMetrics(ctx context.Context) (*OrchestrationMetric
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("orchestration metrics failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "orchestration metrics")
 	}
 
 	var result OrchestrationMetricsResult
@@ -3100,8 +3009,8 @@ type RunControlSummary struct {
 
 // GetProviderSession retrieves the provider session binding for a task
 func (c *Client) GetProviderSession(ctx context.Context, taskID string) (*ProviderSession, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf("/api/v1/cmux/orchestration/sessions/%s", taskID)
@@ -3116,7 +3025,7 @@ func (c *Client) GetProviderSession(ctx context.Context, taskID string) (*Provid
 		return nil, fmt.Errorf("no session found for task %s", taskID)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("get provider session failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "get provider session")
 	}
 
 	var result ProviderSession
@@ -3128,8 +3037,8 @@ func (c *Client) GetProviderSession(ctx context.Context, taskID string) (*Provid
 }
 
 func (c *Client) GetRunControlSummary(ctx context.Context, taskRunID string) (*RunControlSummary, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	path := fmt.Sprintf(
@@ -3148,11 +3057,7 @@ func (c *Client) GetRunControlSummary(ctx context.Context, taskRunID string) (*R
 		return nil, fmt.Errorf("no run-control summary found for task run %s", taskRunID)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"get run-control summary failed (%d): %s",
-			resp.StatusCode,
-			readErrorBody(resp.Body),
-		)
+		return nil, newAPIError(resp, "get run-control summary")
 	}
 
 	var result RunControlSummary
@@ -3194,8 +3099,8 @@ type DispatchProjectResult struct {
 // DispatchProject calls POST /api/projects/:projectId/dispatch.
 // This dispatches a project plan, creating orchestration tasks for each plan task.
 func (c *Client) DispatchProject(ctx context.Context, opts DispatchProjectOptions) (*DispatchProjectResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 	if opts.ProjectID == "" {
 		return nil, fmt.Errorf("project ID is required")
@@ -3215,7 +3120,7 @@ func (c *Client) DispatchProject(ctx context.Context, opts DispatchProjectOption
 		return nil, fmt.Errorf("no plan tasks to dispatch")
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("dispatch failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "dispatch")
 	}
 
 	var result DispatchProjectResult
@@ -3247,7 +3152,7 @@ func (c *Client) GetAutopilotInfo(ctx context.Context, jwt string) (*AutopilotIn
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "")
 	}
 
 	var result AutopilotInfo
@@ -3296,7 +3201,7 @@ func (c *Client) UploadBundle(ctx context.Context, bundleJSON []byte, taskRunJwt
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upload bundle failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "upload bundle")
 	}
 
 	var result UploadBundleResult
@@ -3324,8 +3229,8 @@ type CreateCheckpointResult struct {
 
 // CreateCheckpoint creates a checkpoint of the current task state
 func (c *Client) CreateCheckpoint(ctx context.Context, opts CreateCheckpointOptions) (*CreateCheckpointResult, error) {
-	if c.teamSlug == "" {
-		return nil, fmt.Errorf("team slug not set")
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
 	}
 
 	if opts.TaskID == "" {
@@ -3347,7 +3252,7 @@ func (c *Client) CreateCheckpoint(ctx context.Context, opts CreateCheckpointOpti
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("checkpoint creation failed (%d): %s", resp.StatusCode, readErrorBody(resp.Body))
+		return nil, newAPIError(resp, "checkpoint creation")
 	}
 
 	var checkpointResult CreateCheckpointResult
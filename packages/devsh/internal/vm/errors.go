@@ -0,0 +1,185 @@
+// internal/vm/errors.go
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by *APIError through errors.Is.
+var (
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrNotFound      = errors.New("not found")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrServer        = errors.New("server error")
+	ErrUnavailable   = errors.New("service unavailable")
+)
+
+// APIError is a non-2xx response from the cmux API. Use errors.Is with the
+// Err* sentinels to branch on the kind of failure, or errors.As to read the
+// details.
+type APIError struct {
+	StatusCode int
+	Code       string // Server error code, e.g. "QUOTA_EXCEEDED" or "404"
+	Message    string // Server message, or the raw body if it wasn't JSON
+	RequestID  string
+	Op         string // Operation that failed, e.g. "get task"
+
+	// guided errors carry the troubleshooting text formatAPIError has
+	// always printed.
+	guided bool
+}
+
+// newAPIError builds an APIError from a response, consuming its body.
+func newAPIError(resp *http.Response, op string) *APIError {
+	e := parseAPIError(resp.StatusCode, readErrorBody(resp.Body), op)
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-Id")
+	}
+	return e
+}
+
+// NewAPIError builds an APIError from a non-2xx response of another API
+// devsh calls directly, such as Morph's, consuming its body. op names the
+// failed operation.
+func NewAPIError(resp *http.Response, op string) *APIError {
+	return newAPIError(resp, op)
+}
+
+// parseAPIError extracts code, message and requestId from a structured
+// error body such as {"code": 404, "message": "..."} or
+// {"error": {"code": "QUOTA_EXCEEDED", "message": "..."}}.
+func parseAPIError(statusCode int, body, op string) *APIError {
+	e := &APIError{StatusCode: statusCode, Message: body, Op: op}
+
+	var payload struct {
+		Code      json.RawMessage `json:"code"`
+		Message   string          `json:"message"`
+		Error     json.RawMessage `json:"error"`
+		RequestID string          `json:"requestId"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		return e
+	}
+
+	var nested struct {
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	var errString string
+	if len(payload.Error) > 0 {
+		if json.Unmarshal(payload.Error, &nested) != nil {
+			_ = json.Unmarshal(payload.Error, &errString)
+		}
+	}
+
+	switch {
+	case payload.Message != "":
+		e.Message = payload.Message
+	case nested.Message != "":
+		e.Message = nested.Message
+	case errString != "":
+		e.Message = errString
+	}
+	e.Code = rawCode(payload.Code)
+	if e.Code == "" {
+		e.Code = rawCode(nested.Code)
+	}
+	e.RequestID = payload.RequestID
+	return e
+}
+
+// rawCode renders a string or numeric JSON code as a string.
+func rawCode(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+func (e *APIError) isQuota() bool {
+	if e.StatusCode == http.StatusPaymentRequired {
+		return true
+	}
+	code := strings.ToLower(e.Code)
+	return strings.Contains(code, "quota") || strings.Contains(code, "limit_exceeded")
+}
+
+// Is reports whether target is the sentinel for this error's kind.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests && !e.isQuota()
+	case ErrQuotaExceeded:
+		return e.isQuota()
+	case ErrServer:
+		return e.StatusCode >= 500
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	if e.guided {
+		return guidedErrorText(e.StatusCode, msg)
+	}
+	if e.Op != "" {
+		return fmt.Sprintf("%s failed (%d): %s", e.Op, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("API error (%d): %s", e.StatusCode, msg)
+}
+
+// guidedErrorText adds actionable guidance for common authentication and
+// authorization errors.
+func guidedErrorText(statusCode int, body string) string {
+	switch statusCode {
+	case http.StatusUnauthorized: // 401
+		return fmt.Sprintf("authentication failed (401): %s\n\nPossible causes:\n"+
+			"  - Session expired: run 'devsh login' to re-authenticate\n"+
+			"  - Invalid or missing API token\n"+
+			"  - CMUX_TASK_RUN_JWT is invalid or expired (for sub-agent operations)", body)
+	case http.StatusForbidden: // 403
+		return fmt.Sprintf("access denied (403): %s\n\nPossible causes:\n"+
+			"  - You don't have permission to access this resource\n"+
+			"  - The resource belongs to a different team\n"+
+			"  - Your team subscription may have expired", body)
+	case http.StatusPaymentRequired: // 402
+		return fmt.Sprintf("quota exceeded (402): %s\n\nYour team has reached its usage limit", body)
+	case http.StatusNotFound: // 404
+		return fmt.Sprintf("not found (404): %s\n\nThe requested resource does not exist or you don't have access to it", body)
+	case http.StatusTooManyRequests: // 429
+		return fmt.Sprintf("rate limited (429): %s\n\nToo many requests. Please wait a moment and try again", body)
+	case http.StatusInternalServerError: // 500
+		return fmt.Sprintf("server error (500): %s\n\nThe server encountered an error. If this persists, please report it", body)
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout: // 502, 503, 504
+		return fmt.Sprintf("service unavailable (%d): %s\n\nThe service is temporarily unavailable. Please try again later", statusCode, body)
+	default:
+		return fmt.Sprintf("API error (%d): %s", statusCode, body)
+	}
+}
@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIErrorStructuredBodies(t *testing.T) {
+	tests := []struct {
+		body        string
+		wantCode    string
+		wantMessage string
+		wantRequest string
+	}{
+		{`{"code": 404, "message": "Task not found"}`, "404", "Task not found", ""},
+		{`{"error": {"code": "QUOTA_EXCEEDED", "message": "limit hit"}, "requestId": "req_1"}`, "QUOTA_EXCEEDED", "limit hit", "req_1"},
+		{`{"error": "bad input"}`, "", "bad input", ""},
+		{`plain text`, "", "plain text", ""},
+	}
+	for _, tt := range tests {
+		e := parseAPIError(400, tt.body, "")
+		if e.Code != tt.wantCode || e.Message != tt.wantMessage || e.RequestID != tt.wantRequest {
+			t.Errorf("parseAPIError(%q) = %+v", tt.body, e)
+		}
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		err  *APIError
+		want error
+	}{
+		{&APIError{StatusCode: 401}, ErrUnauthorized},
+		{&APIError{StatusCode: 403}, ErrForbidden},
+		{&APIError{StatusCode: 404}, ErrNotFound},
+		{&APIError{StatusCode: 429}, ErrRateLimited},
+		{&APIError{StatusCode: 402}, ErrQuotaExceeded},
+		{&APIError{StatusCode: 429, Code: "QUOTA_EXCEEDED"}, ErrQuotaExceeded},
+		{&APIError{StatusCode: 503}, ErrUnavailable},
+		{&APIError{StatusCode: 500}, ErrServer},
+	}
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("outer: %w", tt.err)
+		if !errors.Is(wrapped, tt.want) {
+			t.Errorf("errors.Is(%d/%s, %v) = false", tt.err.StatusCode, tt.err.Code, tt.want)
+		}
+	}
+
+	quota := &APIError{StatusCode: 429, Code: "QUOTA_EXCEEDED"}
+	if errors.Is(quota, ErrRateLimited) {
+		t.Error("quota errors should not also match ErrRateLimited")
+	}
+	if errors.Is(&APIError{StatusCode: 404}, ErrUnauthorized) {
+		t.Error("404 should not match ErrUnauthorized")
+	}
+}
+
+func TestClientMethodsReturnAPIError(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req_42")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code": 404, "message": "Task not found"}`))
+	}))
+
+	_, err := client.GetTask(context.Background(), "task_1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetTask error %T is not an *APIError", err)
+	}
+	if !errors.Is(err, ErrNotFound) || apiErr.Message != "Task not found" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	// formatAPIError keeps its guidance text.
+	if !strings.Contains(err.Error(), "not found (404): Task not found") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	_, _, _, err = client.ExecCommand(context.Background(), "cmux_1", "true")
+	if !errors.As(err, &apiErr) || apiErr.RequestID != "req_42" {
+		t.Fatalf("ExecCommand error = %v", err)
+	}
+	if err.Error() != "API error (404): Task not found (request req_42)" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// lifecycleHooks holds lifecycle callbacks and the last status seen for
+// each instance. The zero value is ready to use.
+type lifecycleHooks struct {
+	mu       sync.Mutex
+	statuses map[string]string
+
+	onStart        []func(instance *Instance)
+	onStop         []func(instanceID string)
+	onStatusChange []func(instanceID, from, to string)
+	onError        []func(instanceID, op string, err error)
+}
+
+// OnStart registers fn to run when an instance is seen running
+func (c *Client) OnStart(fn func(instance *Instance)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.onStart = append(c.hooks.onStart, fn)
+}
+
+// OnStop registers fn to run when an instance is stopped
+func (c *Client) OnStop(fn func(instanceID string)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.onStop = append(c.hooks.onStop, fn)
+}
+
+// OnStatusChange registers fn to run when an instance's status changes.
+// from is empty the first time an instance is seen.
+func (c *Client) OnStatusChange(fn func(instanceID, from, to string)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.onStatusChange = append(c.hooks.onStatusChange, fn)
+}
+
+// OnError registers fn to run when a lifecycle operation (create, stop,
+// pause, resume, wait) fails
+func (c *Client) OnError(fn func(instanceID, op string, err error)) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+	c.hooks.onError = append(c.hooks.onError, fn)
+}
+
+// observeStatus records an instance's status and fires hooks if it
+// changed. Hooks run synchronously on the calling goroutine.
+func (c *Client) observeStatus(instance *Instance) {
+	if instance == nil || instance.ID == "" || instance.Status == "" {
+		return
+	}
+
+	c.hooks.mu.Lock()
+	if c.hooks.statuses == nil {
+		c.hooks.statuses = make(map[string]string)
+	}
+	from := c.hooks.statuses[instance.ID]
+	if from == instance.Status {
+		c.hooks.mu.Unlock()
+		return
+	}
+	c.hooks.statuses[instance.ID] = instance.Status
+	onStatusChange := c.hooks.onStatusChange
+	onStart := c.hooks.onStart
+	onStop := c.hooks.onStop
+	c.hooks.mu.Unlock()
+
+	for _, fn := range onStatusChange {
+		fn(instance.ID, from, instance.Status)
+	}
+	switch instance.Status {
+	case "running":
+		for _, fn := range onStart {
+			fn(instance)
+		}
+	case "stopped":
+		for _, fn := range onStop {
+			fn(instance.ID)
+		}
+	}
+}
+
+// observeError fires error hooks for a failed lifecycle operation
+func (c *Client) observeError(instanceID, op string, err error) {
+	if err == nil {
+		return
+	}
+	c.hooks.mu.Lock()
+	onError := c.hooks.onError
+	c.hooks.mu.Unlock()
+
+	for _, fn := range onError {
+		fn(instanceID, op, err)
+	}
+}
+
+// WatchInstance polls an instance until ctx is done, firing lifecycle
+// hooks as its status changes. Polling errors are reported to OnError.
+func (c *Client) WatchInstance(ctx context.Context, instanceID string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		if _, err := c.GetInstance(ctx, instanceID); err != nil && ctx.Err() == nil {
+			c.observeError(instanceID, "watch", err)
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
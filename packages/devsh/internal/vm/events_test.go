@@ -0,0 +1,79 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	status := "pending"
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/stop"):
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, "/pause"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("cannot pause"))
+		default:
+			fmt.Fprintf(w, `{"id":"inst-1","status":%q}`, status)
+		}
+	}))
+
+	var events []string
+	client.OnStatusChange(func(id, from, to string) {
+		events = append(events, fmt.Sprintf("status %s %s->%s", id, from, to))
+	})
+	client.OnStart(func(instance *Instance) {
+		events = append(events, "start "+instance.ID)
+	})
+	client.OnStop(func(id string) {
+		events = append(events, "stop "+id)
+	})
+	client.OnError(func(id, op string, err error) {
+		events = append(events, fmt.Sprintf("error %s %s", id, op))
+	})
+
+	ctx := context.Background()
+	if _, err := client.CreateInstance(ctx, CreateOptions{}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	client.GetInstance(ctx, "inst-1") // unchanged, no event
+	status = "running"
+	client.GetInstance(ctx, "inst-1")
+	client.PauseInstance(ctx, "inst-1")
+	client.StopInstance(ctx, "inst-1")
+
+	want := []string{
+		"status inst-1 ->pending",
+		"status inst-1 pending->running",
+		"start inst-1",
+		"error inst-1 pause",
+		"status inst-1 running->stopped",
+		"stop inst-1",
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected events:\n%s\nwant:\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWatchInstanceStopsOnCancel(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"inst-1","status":"running"}`)
+	}))
+
+	started := make(chan struct{}, 1)
+	client.OnStart(func(*Instance) { started <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.WatchInstance(ctx, "inst-1", 0) }()
+
+	<-started
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+// internal/vm/exec.go
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// defaultExecTimeout is the command timeout when ExecOptions.Timeout is unset.
+const defaultExecTimeout = 60 * time.Second
+
+// execHTTPMargin is added to the command timeout for the HTTP round trip.
+const execHTTPMargin = 30 * time.Second
+
+var reEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExecOptions controls how ExecCommandWithOptions runs a command.
+type ExecOptions struct {
+	Timeout time.Duration     // Command timeout (default 60s)
+	Env     map[string]string // Exported before the command runs
+	Cwd     string            // Working directory
+	Shell   string            // sh (default), bash or zsh
+	Stdin   io.Reader         // Fed to the command's standard input when set
+}
+
+func (o ExecOptions) validate() error {
+	for name := range o.Env {
+		if !reEnvName.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name: %q", name)
+		}
+	}
+	switch o.Shell {
+	case "", "sh", "bash", "zsh":
+	default:
+		return fmt.Errorf("unsupported shell: %q (use sh, bash or zsh)", o.Shell)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// ExecCommandWithOptions executes a command in the VM with a per-call
+// timeout, environment, working directory, shell and standard input.
+func (c *Client) ExecCommandWithOptions(ctx context.Context, instanceID string, command string, opts ExecOptions) (string, string, int, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return "", "", -1, err
+	}
+	if err := opts.validate(); err != nil {
+		return "", "", -1, err
+	}
+	if opts.Stdin != nil {
+		return c.execWithStdin(ctx, instanceID, command, opts)
+	}
+	return c.exec(ctx, instanceID, command, opts)
+}
+
+// exec makes a single exec call; opts.Stdin is ignored.
+func (c *Client) exec(ctx context.Context, instanceID string, command string, opts ExecOptions) (string, string, int, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultExecTimeout
+	}
+	// Round up so sub-second timeouts don't become zero.
+	timeoutSeconds := int((timeout + time.Second - 1) / time.Second)
+
+	body := map[string]interface{}{
+		"teamSlugOrId": c.teamSlug,
+		"command":      command,
+		"timeout":      timeoutSeconds,
+	}
+	if len(opts.Env) > 0 {
+		body["env"] = opts.Env
+	}
+	if opts.Cwd != "" {
+		body["cwd"] = opts.Cwd
+	}
+	if opts.Shell != "" {
+		body["shell"] = opts.Shell
+	}
+
+	// Long commands outlive the client's default HTTP timeout.
+	client := c
+	if need := timeout + execHTTPMargin; c.httpClient.Timeout != 0 && need > c.httpClient.Timeout {
+		client = &Client{
+			httpClient: &http.Client{Transport: c.httpClient.Transport, Timeout: need},
+			baseURL:    c.baseURL,
+			teamSlug:   c.teamSlug,
+		}
+	}
+
+	resp, err := client.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/cmux/instances/%s/exec", instanceID), body)
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", -1, newAPIError(resp, "")
+	}
+
+	var result struct {
+		Stdout   string `json:"stdout"`
+		Stderr   string `json:"stderr"`
+		ExitCode int    `json:"exit_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", -1, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Stdout, result.Stderr, result.ExitCode, nil
+}
@@ -0,0 +1,82 @@
+// internal/vm/exec_stdin.go
+package vm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// execStdinChunk is how much standard input one upload call carries. Its
+// base64 form stays well under Linux's 128 KiB limit on a single argument,
+// since the exec API runs each command as "sh -c <script>".
+const execStdinChunk = 48 * 1024
+
+// execWithStdin runs command with opts.Stdin as its standard input. The
+// exec API only takes a command string, so the input is first uploaded to
+// a private temp file in the VM in base64 chunks, one exec call each. The
+// file is removed once the command finishes.
+func (c *Client) execWithStdin(ctx context.Context, instanceID string, command string, opts ExecOptions) (string, string, int, error) {
+	path, err := c.uploadExecStdin(ctx, instanceID, opts.Stdin)
+	if err != nil {
+		return "", "", -1, fmt.Errorf("failed to upload stdin: %w", err)
+	}
+	// The newline keeps a trailing comment in command from swallowing the
+	// redirect.
+	script := fmt.Sprintf("(%s\n) < %s; status=$?; rm -f %s; exit $status", command, path, path)
+	stdout, stderr, exitCode, err := c.exec(ctx, instanceID, script, opts)
+	if err != nil {
+		c.removeExecStdin(instanceID, path)
+	}
+	return stdout, stderr, exitCode, err
+}
+
+// uploadExecStdin copies r into a new temp file in the VM and returns its
+// path.
+func (c *Client) uploadExecStdin(ctx context.Context, instanceID string, r io.Reader) (string, error) {
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	path := "/tmp/devsh-stdin-" + hex.EncodeToString(suffix[:])
+
+	buf := make([]byte, execStdinChunk)
+	redirect := ">"
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			c.removeExecStdin(instanceID, path)
+			return "", readErr
+		}
+		// Always make one call so empty input still creates the file.
+		if n > 0 || redirect == ">" {
+			chunk := base64.StdEncoding.EncodeToString(buf[:n])
+			command := fmt.Sprintf("umask 077 && printf %%s '%s' | base64 -d %s %s", chunk, redirect, path)
+			_, stderr, exitCode, err := c.exec(ctx, instanceID, command, ExecOptions{})
+			if err == nil && exitCode != 0 {
+				err = fmt.Errorf("exit code %d: %s", exitCode, strings.TrimSpace(stderr))
+			}
+			if err != nil {
+				c.removeExecStdin(instanceID, path)
+				return "", err
+			}
+			redirect = ">>"
+		}
+		if readErr != nil {
+			return path, nil
+		}
+	}
+}
+
+// removeExecStdin deletes an uploaded stdin file, best effort. It uses its
+// own context because the caller's may already be done.
+func (c *Client) removeExecStdin(instanceID, path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	_, _, _, _ = c.exec(ctx, instanceID, "rm -f "+path, ExecOptions{Timeout: 10 * time.Second})
+}
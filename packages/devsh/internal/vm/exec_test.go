@@ -0,0 +1,135 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCommandWithOptionsPayload(t *testing.T) {
+	var body map[string]interface{}
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/cmux/instances/cmux_1/exec" {
+			http.NotFound(w, r)
+			return
+		}
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"stdout":"bar\n","stderr":"","exit_code":0}`))
+	}))
+
+	stdout, _, code, err := client.ExecCommandWithOptions(context.Background(), "cmux_1", "echo $FOO", ExecOptions{
+		Timeout: 600 * time.Second,
+		Env:     map[string]string{"FOO": "bar"},
+		Cwd:     "/workspace",
+		Shell:   "bash",
+	})
+	if err != nil || stdout != "bar\n" || code != 0 {
+		t.Fatalf("ExecCommandWithOptions = %q, %d, %v", stdout, code, err)
+	}
+	if body["timeout"] != float64(600) || body["cwd"] != "/workspace" || body["shell"] != "bash" {
+		t.Errorf("unexpected payload: %v", body)
+	}
+	if env, _ := body["env"].(map[string]interface{}); env["FOO"] != "bar" {
+		t.Errorf("env = %v", body["env"])
+	}
+
+	if _, _, _, err := client.ExecCommand(context.Background(), "cmux_1", "true"); err != nil {
+		t.Fatalf("ExecCommand: %v", err)
+	}
+	if body["timeout"] != float64(60) {
+		t.Errorf("default timeout = %v, want 60", body["timeout"])
+	}
+	if _, ok := body["env"]; ok {
+		t.Errorf("env should be omitted by default: %v", body)
+	}
+}
+
+func TestExecOptionsValidate(t *testing.T) {
+	bad := []ExecOptions{
+		{Env: map[string]string{"1BAD": "x"}},
+		{Env: map[string]string{"A;rm": "x"}},
+		{Shell: "fish"},
+		{Timeout: -time.Second},
+	}
+	for _, opts := range bad {
+		if err := opts.validate(); err == nil {
+			t.Errorf("validate(%+v) should fail", opts)
+		}
+	}
+	if err := (ExecOptions{Env: map[string]string{"_OK1": ""}, Shell: "zsh"}).validate(); err != nil {
+		t.Errorf("validate: %v", err)
+	}
+}
+
+func TestExecCommandWithOptionsStdin(t *testing.T) {
+	upload := regexp.MustCompile(`^umask 077 && printf %s '([A-Za-z0-9+/=]*)' \| base64 -d (>>?) (/tmp/devsh-stdin-[0-9a-f]+)$`)
+	files := map[string]*bytes.Buffer{}
+	var commands []string
+	var ran string
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		command, _ := body["command"].(string)
+		commands = append(commands, command)
+		if m := upload.FindStringSubmatch(command); m != nil {
+			data, err := base64.StdEncoding.DecodeString(m[1])
+			if err != nil {
+				t.Errorf("bad chunk: %v", err)
+			}
+			if m[2] == ">" {
+				files[m[3]] = &bytes.Buffer{}
+			}
+			files[m[3]].Write(data)
+			_, _ = w.Write([]byte(`{"stdout":"","stderr":"","exit_code":0}`))
+			return
+		}
+		ran = command
+		if body["cwd"] != "/workspace" {
+			t.Errorf("command options not passed through: %v", body)
+		}
+		_, _ = w.Write([]byte(`{"stdout":"ok\n","stderr":"","exit_code":3}`))
+	}))
+
+	input := bytes.Repeat([]byte("insert into t values (1);\n"), 5000) // Three chunks
+	stdout, _, code, err := client.ExecCommandWithOptions(context.Background(), "cmux_1", "psql app # restore", ExecOptions{
+		Cwd:   "/workspace",
+		Stdin: bytes.NewReader(input),
+	})
+	if err != nil || stdout != "ok\n" || code != 3 {
+		t.Fatalf("ExecCommandWithOptions = %q, %d, %v", stdout, code, err)
+	}
+	if len(commands) != 4 {
+		t.Fatalf("exec calls = %d, want 3 uploads and the command: %q", len(commands), commands)
+	}
+	if len(files) != 1 {
+		t.Fatalf("uploaded files = %d, want 1", len(files))
+	}
+	for path, data := range files {
+		if !bytes.Equal(data.Bytes(), input) {
+			t.Errorf("uploaded %d bytes, want %d", data.Len(), len(input))
+		}
+		want := "(psql app # restore\n) < " + path + "; status=$?; rm -f " + path + "; exit $status"
+		if ran != want {
+			t.Errorf("command = %q, want %q", ran, want)
+		}
+	}
+
+	// Empty input still redirects from an (empty) file.
+	commands = nil
+	if _, _, _, err := client.ExecCommandWithOptions(context.Background(), "cmux_1", "cat", ExecOptions{Cwd: "/workspace", Stdin: strings.NewReader("")}); err != nil {
+		t.Fatalf("empty stdin: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("empty stdin calls = %q", commands)
+	}
+	if m := upload.FindStringSubmatch(commands[0]); m == nil || m[1] != "" || m[2] != ">" {
+		t.Errorf("empty stdin upload = %q", commands[0])
+	}
+}
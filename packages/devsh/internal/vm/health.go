@@ -0,0 +1,188 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServiceCheck describes a service expected to run in a Morph VM. The
+// service is considered active if any of its units is active.
+type ServiceCheck struct {
+	Name  string
+	Units []string
+	// UnitScript, if set, is a shell command run in the VM that prints the
+	// unit instead, for services whose unit depends on the snapshot.
+	UnitScript string
+	Port       int
+}
+
+// ideUnitScript prints the unit serving the IDE. Only that unit may be
+// started: the IDE units all listen on 39378.
+var ideUnitScript = ideUnitCommand("/etc/cmux/ide.env")
+
+// ideUnitCommand returns a script printing the IDE unit. Snapshots install
+// the configured IDE's unit as cmux-ide; without it, the unit is named
+// after IDE_PROVIDER in envFile (cmux-code when unset).
+func ideUnitCommand(envFile string) string {
+	return `if systemctl cat cmux-ide.service >/dev/null 2>&1; then echo cmux-ide; ` +
+		`else IDE_PROVIDER=; . ` + envFile + ` 2>/dev/null; echo "cmux-${IDE_PROVIDER:-cmux-code}"; fi`
+}
+
+// DefaultServiceChecks are the services started by cmux.target
+var DefaultServiceChecks = []ServiceCheck{
+	{Name: "vnc", Units: []string{"cmux-vnc-proxy"}, Port: 39380},
+	{Name: "code-server", UnitScript: ideUnitScript, Port: 39378},
+	{Name: "proxy", Units: []string{"cmux-proxy"}, Port: 39379},
+	{Name: "chrome-cdp", Units: []string{"cmux-cdp-proxy"}, Port: 39381},
+}
+
+// ServiceHealth is the health of one service
+type ServiceHealth struct {
+	Name     string `json:"name"`
+	Unit     string `json:"unit,omitempty"` // the unit found active
+	Active   bool   `json:"active"`
+	PortOpen bool   `json:"portOpen"`
+	Port     int    `json:"port"`
+	Attempts int    `json:"attempts"`
+}
+
+// Healthy reports whether the service is active and accepting connections
+func (s ServiceHealth) Healthy() bool {
+	return s.Active && s.PortOpen
+}
+
+// HealthMatrix is the result of verifying an instance's services
+type HealthMatrix struct {
+	Services []ServiceHealth `json:"services"`
+	Degraded bool            `json:"degraded"`
+}
+
+// Unhealthy returns the names of services that failed verification
+func (m *HealthMatrix) Unhealthy() []string {
+	var names []string
+	for _, s := range m.Services {
+		if !s.Healthy() {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// HealthCheckOptions configures CheckServices
+type HealthCheckOptions struct {
+	Services []ServiceCheck // defaults to DefaultServiceChecks
+	Attempts int            // per service, defaults to 5
+	Interval time.Duration  // between attempts, defaults to 3s
+}
+
+// CheckServices verifies that each service is active and its port accepts
+// connections. Failing services are started again and rechecked until they
+// pass or run out of attempts; the instance is degraded if any never pass.
+func (c *Client) CheckServices(ctx context.Context, instanceID string, opts HealthCheckOptions) (*HealthMatrix, error) {
+	checks := opts.Services
+	if len(checks) == 0 {
+		checks = DefaultServiceChecks
+	}
+	attempts := opts.Attempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+
+	matrix := &HealthMatrix{Services: make([]ServiceHealth, len(checks))}
+	for i, check := range checks {
+		matrix.Services[i] = ServiceHealth{Name: check.Name, Port: check.Port}
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var pending []int
+		for i, s := range matrix.Services {
+			if !s.Healthy() {
+				pending = append(pending, i)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		if attempt > 1 {
+			if err := sleepContext(ctx, interval); err != nil {
+				return nil, err
+			}
+		}
+
+		var script strings.Builder
+		for _, i := range pending {
+			script.WriteString(serviceCheckScript(checks[i]))
+		}
+		stdout, _, _, err := c.ExecCommand(ctx, instanceID, script.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check services: %w", err)
+		}
+		results := parseServiceCheckOutput(stdout)
+
+		var restart []string
+		for _, i := range pending {
+			s := &matrix.Services[i]
+			s.Attempts = attempt
+			if r, ok := results[s.Name]; ok {
+				s.Unit, s.Active, s.PortOpen = r.Unit, r.Active, r.PortOpen
+			}
+			if !s.Active {
+				restart = append(restart, checks[i].unitList())
+			}
+		}
+
+		// Re-issue the start for inactive units; ports that are merely slow
+		// to open are just rechecked.
+		if len(restart) > 0 && attempt < attempts {
+			cmd := "systemctl start " + strings.Join(restart, " ") + " 2>/dev/null || true"
+			if _, _, _, err := c.ExecCommand(ctx, instanceID, cmd); err != nil {
+				return nil, fmt.Errorf("failed to restart services: %w", err)
+			}
+		}
+	}
+
+	matrix.Degraded = len(matrix.Unhealthy()) > 0
+	return matrix, nil
+}
+
+// unitList is the check's units as shell words, expanded in the VM
+func (check ServiceCheck) unitList() string {
+	if check.UnitScript != "" {
+		return "$(" + check.UnitScript + ")"
+	}
+	return strings.Join(check.Units, " ")
+}
+
+// serviceCheckScript prints "name unit port_ok" for one service, with "-"
+// as the unit when none is active.
+func serviceCheckScript(check ServiceCheck) string {
+	return fmt.Sprintf(
+		"unit=-; for u in %s; do systemctl is-active --quiet \"$u\" 2>/dev/null && unit=\"$u\" && break; done; "+
+			"port=0; timeout 2 bash -c 'exec 3<>/dev/tcp/127.0.0.1/%d' 2>/dev/null && port=1; "+
+			"echo \"%s $unit $port\"\n",
+		check.unitList(), check.Port, check.Name)
+}
+
+func parseServiceCheckOutput(stdout string) map[string]ServiceHealth {
+	results := make(map[string]ServiceHealth)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		s := ServiceHealth{Name: fields[0], PortOpen: fields[2] == "1"}
+		if fields[1] != "-" {
+			s.Unit = fields[1]
+			s.Active = true
+		}
+		results[s.Name] = s
+	}
+	return results
+}
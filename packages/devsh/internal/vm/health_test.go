@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseServiceCheckOutput(t *testing.T) {
+	results := parseServiceCheckOutput("vnc cmux-vnc-proxy 1\ncode-server - 0\ngarbage\n")
+	if r := results["vnc"]; !r.Active || !r.PortOpen || r.Unit != "cmux-vnc-proxy" {
+		t.Errorf("unexpected vnc result: %+v", r)
+	}
+	if r := results["code-server"]; r.Active || r.PortOpen {
+		t.Errorf("unexpected code-server result: %+v", r)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 results, got %d", len(results))
+	}
+}
+
+func TestCheckServicesRestartsFailingService(t *testing.T) {
+	var commands []string
+	checks := 0
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		command, _ := body["command"].(string)
+		commands = append(commands, command)
+
+		stdout := ""
+		if strings.Contains(command, "is-active") {
+			checks++
+			if strings.Contains(command, "echo \"vnc ") {
+				stdout += "vnc cmux-vnc-proxy 1\n"
+			}
+			if checks == 1 {
+				stdout += "code-server - 0\n"
+			} else {
+				stdout += "code-server cmux-ide 1\n"
+			}
+		}
+		fmt.Fprintf(w, `{"stdout":%q,"stderr":"","exit_code":0}`, stdout)
+	}))
+
+	matrix, err := client.CheckServices(context.Background(), "inst-1", HealthCheckOptions{
+		Services: DefaultServiceChecks[:2],
+		Interval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("CheckServices failed: %v", err)
+	}
+	if matrix.Degraded {
+		t.Errorf("expected healthy matrix, got %+v", matrix)
+	}
+	// Only the configured IDE's unit is started: the others would fight it
+	// for port 39378.
+	if len(commands) != 3 || commands[1] != "systemctl start $("+ideUnitScript+") 2>/dev/null || true" {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+	// The second check only covers the service that failed
+	if strings.Contains(commands[2], "cmux-vnc-proxy") {
+		t.Errorf("healthy service was rechecked: %q", commands[2])
+	}
+	if cs := matrix.Services[1]; cs.Unit != "cmux-ide" || cs.Attempts != 2 {
+		t.Errorf("unexpected code-server health: %+v", cs)
+	}
+}
+
+func TestIDEUnitCommand(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "ide.env")
+	systemctl := filepath.Join(dir, "systemctl")
+	run := func(hasIDEUnit bool) string {
+		t.Helper()
+		exit := "1"
+		if hasIDEUnit {
+			exit = "0"
+		}
+		if err := os.WriteFile(systemctl, []byte("#!/bin/sh\nexit "+exit+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("bash", "-c", ideUnitCommand(envFile))
+		cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "IDE_PROVIDER=openvscode")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	if got := run(false); got != "cmux-cmux-code" {
+		t.Errorf("without ide.env: unit = %q, want cmux-cmux-code", got)
+	}
+	if err := os.WriteFile(envFile, []byte("IDE_PROVIDER=coder\nIDE_HTTP_PORT=39378\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(false); got != "cmux-coder" {
+		t.Errorf("coder ide.env: unit = %q, want cmux-coder", got)
+	}
+	if got := run(true); got != "cmux-ide" {
+		t.Errorf("with cmux-ide installed: unit = %q, want cmux-ide", got)
+	}
+}
+
+func TestCheckServicesReportsDegraded(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"stdout":"chrome-cdp cmux-cdp-proxy 0\n","stderr":"","exit_code":0}`)
+	}))
+
+	matrix, err := client.CheckServices(context.Background(), "inst-1", HealthCheckOptions{
+		Services: DefaultServiceChecks[3:],
+		Attempts: 2,
+		Interval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("CheckServices failed: %v", err)
+	}
+	if !matrix.Degraded || strings.Join(matrix.Unhealthy(), ",") != "chrome-cdp" {
+		t.Errorf("expected chrome-cdp degraded, got %+v", matrix)
+	}
+	if s := matrix.Services[0]; !s.Active || s.PortOpen || s.Attempts != 2 {
+		t.Errorf("unexpected service health: %+v", s)
+	}
+}
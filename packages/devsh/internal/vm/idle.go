@@ -0,0 +1,190 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cdpProxyPort is where cmux-cdp-proxy serves DevTools clients (see the
+// chrome-cdp entry in DefaultServiceChecks); cdpChromePort is the Chrome
+// debugging port behind it. vscodePort and vncPort serve VS Code in the
+// browser and noVNC (the code-server and vnc entries).
+const (
+	cdpProxyPort  = 39381
+	cdpChromePort = 39382
+	vscodePort    = 39378
+	vncPort       = 39380
+)
+
+// idleProbeScript prints "ptys=N cdp=N web=N load=F". PTYs are numbered
+// entries in /dev/pts (terminals, SSH, VS Code shells); CDP and web
+// connections are established TCP sessions on the CDP proxy and Chrome
+// ports, and on the VS Code and noVNC ports. A browser tab on VS Code
+// keeps a websocket open even when no terminal is. They are read from
+// /proc so the probe needs nothing beyond awk.
+var idleProbeScript = `ptys=$(ls /dev/pts 2>/dev/null | grep -c '^[0-9]'); ` +
+	`cdp=$(` + establishedOn(cdpProxyPort, cdpChromePort) + `); ` +
+	`web=$(` + establishedOn(vscodePort, vncPort) + `); ` +
+	`load=$(cut -d' ' -f1 /proc/loadavg); ` +
+	`echo "ptys=$ptys cdp=$cdp web=$web load=$load"`
+
+// establishedOn is a shell pipeline counting established TCP sessions
+// whose local end is one of ports.
+func establishedOn(ports ...int) string {
+	hex := make([]string, len(ports))
+	for i, port := range ports {
+		hex[i] = procNetPort(port)
+	}
+	return `cat /proc/net/tcp /proc/net/tcp6 2>/dev/null | awk '$4 == "01" && $2 ~ /:(` + strings.Join(hex, "|") + `)$/' | wc -l`
+}
+
+// procNetPort formats a port the way /proc/net/tcp prints it.
+func procNetPort(port int) string {
+	return fmt.Sprintf("%04X", port)
+}
+
+// IdleSample is one observation of an instance's activity.
+type IdleSample struct {
+	PTYs           int
+	CDPConnections int
+	WebConnections int     // VS Code web and noVNC clients
+	Load1          float64 // 1-minute load average
+}
+
+// Idle reports whether nothing is attached and the CPU is below maxLoad.
+func (s IdleSample) Idle(maxLoad float64) bool {
+	return s.PTYs == 0 && s.CDPConnections == 0 && s.WebConnections == 0 && s.Load1 < maxLoad
+}
+
+func (s IdleSample) String() string {
+	return fmt.Sprintf("ptys=%d cdp=%d web=%d load=%.2f", s.PTYs, s.CDPConnections, s.WebConnections, s.Load1)
+}
+
+// ProbeIdle runs the idle probe on an instance.
+func (c *Client) ProbeIdle(ctx context.Context, instanceID string) (*IdleSample, error) {
+	stdout, stderr, exitCode, err := c.ExecCommandWithOptions(ctx, instanceID, idleProbeScript, ExecOptions{Timeout: 15 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("idle probe exited with code %d: %s", exitCode, strings.TrimSpace(stderr))
+	}
+	return parseIdleProbe(stdout)
+}
+
+func parseIdleProbe(stdout string) (*IdleSample, error) {
+	var s IdleSample
+	seen := 0
+	for _, field := range strings.Fields(stdout) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch key {
+		case "ptys":
+			s.PTYs, err = strconv.Atoi(value)
+		case "cdp":
+			s.CDPConnections, err = strconv.Atoi(value)
+		case "web":
+			s.WebConnections, err = strconv.Atoi(value)
+		case "load":
+			s.Load1, err = strconv.ParseFloat(value, 64)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid idle probe output %q: %w", strings.TrimSpace(stdout), err)
+		}
+		seen++
+	}
+	if seen != 4 {
+		return nil, fmt.Errorf("invalid idle probe output %q", strings.TrimSpace(stdout))
+	}
+	return &s, nil
+}
+
+// ErrNotWatching is returned by WatchIdle when the instance stopped running
+// for another reason before it went idle.
+var ErrNotWatching = errors.New("instance no longer running")
+
+// IdleOptions configures WatchIdle.
+type IdleOptions struct {
+	IdleAfter time.Duration // Pause after this long idle (default 30m)
+	Interval  time.Duration // Between probes (default 1m)
+	MaxLoad   float64       // Load average below which the CPU counts as idle (default 0.3)
+	// OnSample, if set, is called after each successful probe with how
+	// long the instance has been idle so far.
+	OnSample func(sample IdleSample, idleFor time.Duration)
+}
+
+func (o IdleOptions) withDefaults() IdleOptions {
+	if o.IdleAfter <= 0 {
+		o.IdleAfter = 30 * time.Minute
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	if o.MaxLoad <= 0 {
+		o.MaxLoad = 0.3
+	}
+	return o
+}
+
+// idleTracker turns samples into an idle duration. Any busy sample resets
+// it, so the instance must look idle on every probe for the whole window.
+type idleTracker struct {
+	since time.Time
+}
+
+func (t *idleTracker) observe(s IdleSample, maxLoad float64, now time.Time) time.Duration {
+	if !s.Idle(maxLoad) {
+		t.since = time.Time{}
+		return 0
+	}
+	if t.since.IsZero() {
+		t.since = now
+	}
+	return now.Sub(t.since)
+}
+
+// WatchIdle probes an instance every Interval and pauses it once it has
+// been idle for IdleAfter. It returns nil after pausing, and an error if
+// the instance cannot be probed for a reason retrying will not fix: it was
+// deleted or paused elsewhere, or access was revoked. Transient probe
+// failures are skipped without resetting the idle window.
+func (c *Client) WatchIdle(ctx context.Context, instanceID string, opts IdleOptions) error {
+	opts = opts.withDefaults()
+	var tracker idleTracker
+
+	for {
+		sample, err := c.ProbeIdle(ctx, instanceID)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && isFatalWaitError(err):
+			return err
+		case err != nil:
+			// Paused or stopped by someone else: nothing left to watch
+			if inst, getErr := c.GetInstance(ctx, instanceID); getErr == nil && inst.Status != "running" {
+				return fmt.Errorf("%w: instance is %s", ErrNotWatching, inst.Status)
+			}
+		default:
+			idleFor := tracker.observe(*sample, opts.MaxLoad, time.Now())
+			if opts.OnSample != nil {
+				opts.OnSample(*sample, idleFor)
+			}
+			if idleFor >= opts.IdleAfter {
+				return c.PauseInstance(ctx, instanceID)
+			}
+		}
+
+		if err := sleepContext(ctx, opts.Interval); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseIdleProbe(t *testing.T) {
+	s, err := parseIdleProbe("ptys=2 cdp=1 web=3 load=0.45\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PTYs != 2 || s.CDPConnections != 1 || s.WebConnections != 3 || s.Load1 != 0.45 {
+		t.Errorf("sample = %+v", s)
+	}
+	if s.Idle(0.3) {
+		t.Error("sample with open PTYs should not be idle")
+	}
+	if !(IdleSample{Load1: 0.1}).Idle(0.3) {
+		t.Error("quiet sample should be idle")
+	}
+	if (IdleSample{Load1: 0.1, WebConnections: 1}).Idle(0.3) {
+		t.Error("sample with a VS Code or noVNC client should not be idle")
+	}
+
+	for _, bad := range []string{"", "ptys=1 cdp=0 load=0.1", "ptys=x cdp=0 web=0 load=0.1"} {
+		if _, err := parseIdleProbe(bad); err == nil {
+			t.Errorf("parseIdleProbe(%q) should fail", bad)
+		}
+	}
+}
+
+func TestIdleProbeCountsCDPProxySessions(t *testing.T) {
+	var cdpPort int
+	for _, check := range DefaultServiceChecks {
+		if check.Name == "chrome-cdp" {
+			cdpPort = check.Port
+		}
+	}
+	if cdpPort != cdpProxyPort {
+		t.Fatalf("chrome-cdp service port = %d, idle probe counts %d", cdpPort, cdpProxyPort)
+	}
+	for _, port := range []string{"99D5", "99D6"} {
+		if !strings.Contains(idleProbeScript, port) {
+			t.Errorf("idle probe does not count sessions on %s: %s", port, idleProbeScript)
+		}
+	}
+	if strings.Contains(idleProbeScript, "2406") {
+		t.Errorf("idle probe still counts port 9222: %s", idleProbeScript)
+	}
+}
+
+func TestIdleProbeCountsWebClients(t *testing.T) {
+	ports := map[string]int{}
+	for _, check := range DefaultServiceChecks {
+		ports[check.Name] = check.Port
+	}
+	if ports["code-server"] != vscodePort || ports["vnc"] != vncPort {
+		t.Fatalf("service ports = %v, idle probe counts %d and %d", ports, vscodePort, vncPort)
+	}
+	for _, port := range []string{"99D2", "99D4"} {
+		if !strings.Contains(idleProbeScript, port) {
+			t.Errorf("idle probe does not count sessions on %s: %s", port, idleProbeScript)
+		}
+	}
+}
+
+func TestIdleTrackerResetsOnActivity(t *testing.T) {
+	var tr idleTracker
+	now := time.Unix(1000, 0)
+	quiet := IdleSample{Load1: 0.05}
+	busy := IdleSample{Load1: 0.05, CDPConnections: 1}
+
+	tr.observe(quiet, 0.3, now)
+	if got := tr.observe(quiet, 0.3, now.Add(10*time.Minute)); got != 10*time.Minute {
+		t.Errorf("idle = %v, want 10m", got)
+	}
+	if got := tr.observe(busy, 0.3, now.Add(11*time.Minute)); got != 0 {
+		t.Errorf("idle after activity = %v, want 0", got)
+	}
+	tr.observe(quiet, 0.3, now.Add(12*time.Minute))
+	if got := tr.observe(quiet, 0.3, now.Add(15*time.Minute)); got != 3*time.Minute {
+		t.Errorf("idle = %v, want 3m", got)
+	}
+}
+
+func TestWatchIdlePausesIdleInstance(t *testing.T) {
+	var probes atomic.Int32
+	var paused atomic.Bool
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/exec"):
+			// Busy on the first probe, then quiet
+			ptys := 0
+			if probes.Add(1) == 1 {
+				ptys = 1
+			}
+			fmt.Fprintf(w, `{"stdout":"ptys=%d cdp=0 web=0 load=0.01\n","stderr":"","exit_code":0}`, ptys)
+		case strings.HasSuffix(r.URL.Path, "/pause"):
+			paused.Store(true)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	err := client.WatchIdle(context.Background(), "cmux_1", IdleOptions{IdleAfter: 20 * time.Millisecond, Interval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("WatchIdle: %v", err)
+	}
+	if !paused.Load() {
+		t.Error("expected the instance to be paused")
+	}
+	if n := probes.Load(); n < 3 {
+		t.Errorf("expected the busy probe to delay the pause, got %d probes", n)
+	}
+}
+
+func TestWatchIdleStopsWhenInstanceNotRunning(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/exec") {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		fmt.Fprint(w, `{"id":"cmux_1","status":"paused"}`)
+	}))
+
+	err := client.WatchIdle(context.Background(), "cmux_1", IdleOptions{Interval: time.Millisecond})
+	if !errors.Is(err, ErrNotWatching) {
+		t.Fatalf("expected ErrNotWatching, got %v", err)
+	}
+}
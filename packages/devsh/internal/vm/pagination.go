@@ -0,0 +1,146 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListOptions filters and bounds ListInstances/ListTasks style calls. Filters
+// are sent as query parameters and applied by the server; zero values are
+// omitted.
+type ListOptions struct {
+	Status       string
+	Repository   string
+	CreatedAfter time.Time
+	// Limit caps the total number of items returned across pages; 0 returns
+	// everything.
+	Limit int
+	// PageSize is how many items each request asks for; 0 leaves it to the
+	// server.
+	PageSize int
+}
+
+// query returns the list query parameters for one page. A pageSize of 0
+// sends no limit, so servers without pagination return everything.
+func (o ListOptions) query(teamSlug, cursor string, pageSize int) url.Values {
+	q := url.Values{}
+	q.Set("teamSlugOrId", teamSlug)
+	if pageSize > 0 {
+		q.Set("limit", strconv.Itoa(pageSize))
+	}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	if o.Repository != "" {
+		q.Set("repository", o.Repository)
+	}
+	if !o.CreatedAfter.IsZero() {
+		q.Set("createdAfter", strconv.FormatInt(o.CreatedAfter.UnixMilli(), 10))
+	}
+	return q
+}
+
+// paginate drives a cursor-paginated list: fetch returns one page and the
+// cursor for the next (empty when done). Each page is passed to emit as it
+// arrives, trimmed so no more than opts.Limit items are emitted in total.
+// It reports whether more items were left on the server.
+func paginate[T any](opts ListOptions, fetch func(cursor string, pageSize int) ([]T, string, error), emit func([]T) error) (bool, error) {
+	cursor := ""
+	emitted := 0
+	for {
+		pageSize := opts.PageSize
+		if remaining := opts.Limit - emitted; opts.Limit > 0 && (pageSize <= 0 || remaining < pageSize) {
+			pageSize = remaining
+		}
+		items, next, err := fetch(cursor, pageSize)
+		if err != nil {
+			return false, err
+		}
+
+		// Servers without pagination ignore limit and return everything.
+		more := next != ""
+		if opts.Limit > 0 && emitted+len(items) > opts.Limit {
+			items = items[:opts.Limit-emitted]
+			more = true
+		}
+		if len(items) > 0 {
+			if err := emit(items); err != nil {
+				return false, err
+			}
+		}
+		emitted += len(items)
+
+		if next == "" || (opts.Limit > 0 && emitted >= opts.Limit) {
+			return more, nil
+		}
+		if next == cursor {
+			return false, fmt.Errorf("server returned the same cursor twice")
+		}
+		cursor = next
+	}
+}
+
+// EachInstancePage lists instances page by page, calling fn with each page
+// as it arrives. It reports whether more instances matched than opts.Limit
+// allowed.
+func (c *Client) EachInstancePage(ctx context.Context, opts ListOptions, fn func([]Instance) error) (bool, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return false, err
+	}
+	return paginate(opts, func(cursor string, pageSize int) ([]Instance, string, error) {
+		path := "/api/v1/cmux/instances?" + opts.query(c.teamSlug, cursor, pageSize).Encode()
+		resp, err := c.doRequestWithRetry(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", newAPIError(resp, "")
+		}
+
+		var result struct {
+			Instances  []Instance `json:"instances"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, "", fmt.Errorf("failed to decode response: %w", err)
+		}
+		return result.Instances, result.NextCursor, nil
+	}, fn)
+}
+
+// EachTaskPage lists tasks page by page, calling fn with each page as it
+// arrives. It reports whether more tasks matched than opts.Limit allowed.
+func (c *Client) EachTaskPage(ctx context.Context, archived bool, opts ListOptions, fn func([]Task) error) (bool, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return false, err
+	}
+	return paginate(opts, func(cursor string, pageSize int) ([]Task, string, error) {
+		q := opts.query(c.teamSlug, cursor, pageSize)
+		q.Set("archived", strconv.FormatBool(archived))
+		resp, err := c.doRequest(ctx, http.MethodGet, "/api/v1/cmux/tasks?"+q.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", formatAPIError(resp.StatusCode, readErrorBody(resp.Body), "list tasks")
+		}
+
+		var result ListTasksResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, "", fmt.Errorf("failed to decode response: %w", err)
+		}
+		return result.Tasks, result.NextCursor, nil
+	}, fn)
+}
@@ -0,0 +1,118 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// pagedTaskServer serves 5 tasks two per page, recording each query.
+func pagedTaskServer(t *testing.T, queries *[]map[string]string, paginated bool) *Client {
+	t.Helper()
+	return newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/cmux/tasks" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		*queries = append(*queries, q)
+
+		all := []Task{{ID: "t1"}, {ID: "t2"}, {ID: "t3"}, {ID: "t4"}, {ID: "t5"}}
+		if !paginated {
+			json.NewEncoder(w).Encode(ListTasksResult{Tasks: all})
+			return
+		}
+		start := 0
+		fmt.Sscanf(q["cursor"], "c%d", &start)
+		end := start + 2
+		if end > len(all) {
+			end = len(all)
+		}
+		next := ""
+		if end < len(all) {
+			next = fmt.Sprintf("c%d", end)
+		}
+		json.NewEncoder(w).Encode(ListTasksResult{Tasks: all[start:end], NextCursor: next})
+	}))
+}
+
+func collectTaskPages(t *testing.T, client *Client, opts ListOptions) ([][]string, bool) {
+	t.Helper()
+	var pages [][]string
+	more, err := client.EachTaskPage(context.Background(), false, opts, func(page []Task) error {
+		var ids []string
+		for _, task := range page {
+			ids = append(ids, task.ID)
+		}
+		pages = append(pages, ids)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachTaskPage() error = %v", err)
+	}
+	return pages, more
+}
+
+func TestEachTaskPageFollowsCursors(t *testing.T) {
+	var queries []map[string]string
+	client := pagedTaskServer(t, &queries, true)
+
+	pages, more := collectTaskPages(t, client, ListOptions{PageSize: 2})
+	if fmt.Sprint(pages) != "[[t1 t2] [t3 t4] [t5]]" || more {
+		t.Fatalf("pages = %v, more = %v", pages, more)
+	}
+	if len(queries) != 3 || queries[0]["cursor"] != "" || queries[1]["cursor"] != "c2" || queries[2]["cursor"] != "c4" {
+		t.Fatalf("queries = %v", queries)
+	}
+	if queries[0]["archived"] != "false" || queries[0]["teamSlugOrId"] != "example-team" {
+		t.Fatalf("first query = %v", queries[0])
+	}
+}
+
+func TestEachTaskPageStopsAtLimit(t *testing.T) {
+	var queries []map[string]string
+	client := pagedTaskServer(t, &queries, true)
+
+	pages, more := collectTaskPages(t, client, ListOptions{Limit: 3, PageSize: 2})
+	if fmt.Sprint(pages) != "[[t1 t2] [t3]]" || !more {
+		t.Fatalf("pages = %v, more = %v", pages, more)
+	}
+	if queries[1]["limit"] != "1" {
+		t.Fatalf("second page limit = %q, want 1", queries[1]["limit"])
+	}
+}
+
+func TestEachTaskPageSendsFilters(t *testing.T) {
+	var queries []map[string]string
+	client := pagedTaskServer(t, &queries, true)
+
+	after := time.UnixMilli(1700000000000)
+	collectTaskPages(t, client, ListOptions{Status: "running", Repository: "owner/repo", CreatedAfter: after, Limit: 1})
+	q := queries[0]
+	if q["status"] != "running" || q["repository"] != "owner/repo" || q["createdAfter"] != "1700000000000" || q["limit"] != "1" {
+		t.Fatalf("query = %v", q)
+	}
+}
+
+func TestEachTaskPageTrimsUnpaginatedServer(t *testing.T) {
+	var queries []map[string]string
+	client := pagedTaskServer(t, &queries, false)
+
+	pages, more := collectTaskPages(t, client, ListOptions{Limit: 2})
+	if fmt.Sprint(pages) != "[[t1 t2]]" || !more || len(queries) != 1 {
+		t.Fatalf("pages = %v, more = %v, requests = %d", pages, more, len(queries))
+	}
+
+	result, err := client.ListTasks(context.Background(), false)
+	if err != nil || len(result.Tasks) != 5 {
+		t.Fatalf("ListTasks() = %d tasks, %v", len(result.Tasks), err)
+	}
+	if _, ok := queries[1]["limit"]; ok {
+		t.Fatalf("ListTasks() sent limit=%s; an uncapped list must leave the page size to the server", queries[1]["limit"])
+	}
+}
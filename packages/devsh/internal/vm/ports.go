@@ -0,0 +1,67 @@
+// internal/vm/ports.go
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+// ListeningPort is a TCP listener inside an instance, as reported by the
+// worker's /_cmux/ports endpoint.
+type ListeningPort struct {
+	Port    int    `json:"port"`
+	Address string `json:"address"`
+	PID     int    `json:"pid,omitempty"`
+	Process string `json:"process,omitempty"`
+}
+
+// ListPorts returns the TCP ports listening inside an instance, one entry
+// per port and address.
+func (c *Client) ListPorts(ctx context.Context, instanceID string) ([]ListeningPort, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
+	}
+
+	instance, err := c.GetInstance(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %w", err)
+	}
+	if instance.WorkerURL == "" {
+		return nil, fmt.Errorf("worker URL not available")
+	}
+
+	accessToken, err := auth.GetAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("not authenticated: %w", err)
+	}
+
+	workerURL := strings.TrimRight(instance.WorkerURL, "/") + "/_cmux/ports"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, workerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call worker: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, "worker request")
+	}
+
+	var result struct {
+		Ports []ListeningPort `json:"ports"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Ports, nil
+}
@@ -0,0 +1,35 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestListPortsQueriesWorker(t *testing.T) {
+	var workerURL string
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/cmux/instances/cmux_1":
+			_ = json.NewEncoder(w).Encode(Instance{ID: "cmux_1", Status: "running", WorkerURL: workerURL + "/"})
+		case "/_cmux/ports":
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"ports":[{"port":5432,"address":"127.0.0.1","pid":42,"process":"postgres"},{"port":3000,"address":"::"}],"docker":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	workerURL = client.baseURL
+
+	ports, err := client.ListPorts(context.Background(), "cmux_1")
+	if err != nil {
+		t.Fatalf("ListPorts: %v", err)
+	}
+	if len(ports) != 2 || ports[0] != (ListeningPort{Port: 5432, Address: "127.0.0.1", PID: 42, Process: "postgres"}) || ports[1].Port != 3000 {
+		t.Errorf("ports = %+v", ports)
+	}
+}
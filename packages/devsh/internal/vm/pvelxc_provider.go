@@ -0,0 +1,141 @@
+// pvelxc_provider.go implements provider.SandboxProvider for PVE LXC through
+// the www API, for users without direct PVE credentials.
+package vm
+
+import (
+	"context"
+	"time"
+
+	"github.com/karlorz/devsh/internal/provider"
+)
+
+var (
+	_ provider.SandboxProvider = (*PveLxcProvider)(nil)
+	_ provider.Lifecycle       = (*PveLxcProvider)(nil)
+)
+
+// PveLxcProvider manages PVE LXC sandboxes via the www API.
+type PveLxcProvider struct {
+	client *Client
+}
+
+// NewPveLxcProvider creates a PVE LXC provider backed by the www API.
+func NewPveLxcProvider() (*PveLxcProvider, error) {
+	client, err := NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return &PveLxcProvider{client: client}, nil
+}
+
+// SetTeamSlug sets the team slug for API calls.
+func (p *PveLxcProvider) SetTeamSlug(teamSlug string) {
+	p.client.SetTeamSlug(teamSlug)
+}
+
+// Name returns the provider identifier.
+func (p *PveLxcProvider) Name() string {
+	return provider.PveLxc
+}
+
+// Create creates a new sandbox. The server picks the backend, so this only
+// yields a PVE LXC sandbox when the server is configured for PVE LXC.
+func (p *PveLxcProvider) Create(ctx context.Context, opts provider.CreateOptions) (*provider.Sandbox, error) {
+	createOpts := CreateOptions{SnapshotID: opts.Template}
+	if opts.Timeout > 0 {
+		createOpts.TTLSeconds = int(opts.Timeout.Seconds())
+	}
+
+	instance, err := p.client.CreateInstance(ctx, createOpts)
+	if err != nil {
+		return nil, err
+	}
+	return pveInstanceToSandbox(instance), nil
+}
+
+// Get retrieves a sandbox by ID.
+func (p *PveLxcProvider) Get(ctx context.Context, id string) (*provider.Sandbox, error) {
+	instance, err := p.client.GetPveLxcInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return pveInstanceToSandbox(instance), nil
+}
+
+// Delete terminates and removes a sandbox.
+func (p *PveLxcProvider) Delete(ctx context.Context, id string) error {
+	return p.client.StopPveLxcInstance(ctx, id)
+}
+
+// Exec executes a command in a sandbox.
+func (p *PveLxcProvider) Exec(ctx context.Context, id string, command string) (*provider.ExecResult, error) {
+	stdout, stderr, exitCode, err := p.client.ExecPveLxcInstance(ctx, id, command, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.ExecResult{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: exitCode,
+	}, nil
+}
+
+// List returns sandboxes matching the given options.
+func (p *PveLxcProvider) List(ctx context.Context, opts provider.ListOptions) ([]provider.Sandbox, error) {
+	instances, err := p.client.ListPveLxcInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxes := make([]provider.Sandbox, 0, len(instances))
+	for _, inst := range instances {
+		if opts.Status != "" && inst.Status != opts.Status {
+			continue
+		}
+		sandboxes = append(sandboxes, *pveInstanceToSandbox(&inst))
+		if opts.Limit > 0 && len(sandboxes) >= opts.Limit {
+			break
+		}
+	}
+	return sandboxes, nil
+}
+
+// WaitReady polls until the sandbox is running or timeout.
+func (p *PveLxcProvider) WaitReady(ctx context.Context, id string, timeout time.Duration) (*provider.Sandbox, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		instance, err := p.client.GetPveLxcInstance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if instance.Status == "running" {
+			return pveInstanceToSandbox(instance), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, context.DeadlineExceeded
+		}
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Pause suspends a sandbox, preserving its state.
+func (p *PveLxcProvider) Pause(ctx context.Context, id string) error {
+	return p.client.PausePveLxcInstance(ctx, id)
+}
+
+// Resume restarts a paused sandbox. The server waits for it to be ready.
+func (p *PveLxcProvider) Resume(ctx context.Context, id string) (*provider.Sandbox, error) {
+	instance, err := p.client.ResumePveLxcInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return pveInstanceToSandbox(instance), nil
+}
+
+func pveInstanceToSandbox(inst *Instance) *provider.Sandbox {
+	sandbox := instanceToSandbox(inst)
+	sandbox.Provider = provider.PveLxc
+	return sandbox
+}
@@ -0,0 +1,174 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// retryConfig holds retry parameters
+type retryConfig struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	// maxElapsed bounds the total time spent retrying, including waits
+	// requested by Retry-After.
+	maxElapsed time.Duration
+}
+
+// defaultRetryConfig returns the default retry configuration
+func defaultRetryConfig() retryConfig {
+	return retryConfig{
+		maxRetries: 3, // 4 total attempts
+		baseDelay:  time.Second,
+		maxDelay:   10 * time.Second,
+		maxElapsed: 45 * time.Second,
+	}
+}
+
+// isRetryableStatusCode returns true if the HTTP status code indicates a transient error
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || // 429
+		statusCode == http.StatusBadGateway || // 502
+		statusCode == http.StatusServiceUnavailable || // 503
+		statusCode == http.StatusGatewayTimeout // 504
+}
+
+// isRetryableError returns true if the error indicates a transient network issue
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := strings.ToLower(err.Error())
+	return strings.Contains(errStr, "connection reset") ||
+		strings.Contains(errStr, "connection refused") ||
+		strings.Contains(errStr, "timeout") ||
+		strings.Contains(errStr, "eof") ||
+		strings.Contains(errStr, "broken pipe")
+}
+
+// isIdempotentMethod reports whether repeating the request is harmless.
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isSafeToRetry decides whether a failed attempt may be repeated. Idempotent
+// requests retry on any transient failure. Other requests (e.g. instance
+// creation) only retry when the server cannot have acted on them: the
+// connection was refused, or it answered 429/503 to reject the request.
+func isSafeToRetry(method string, statusCode int, err error) bool {
+	if err != nil {
+		if !isRetryableError(err) {
+			return false
+		}
+		return isIdempotentMethod(method) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			strings.Contains(strings.ToLower(err.Error()), "connection refused")
+	}
+	if !isRetryableStatusCode(statusCode) {
+		return false
+	}
+	return isIdempotentMethod(method) ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 if the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// backoff returns the delay before retry number attempt (1-based):
+// exponential from baseDelay, capped at maxDelay, with ±25% jitter.
+func (cfg retryConfig) backoff(attempt int) time.Duration {
+	delay := cfg.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > cfg.maxDelay {
+		delay = cfg.maxDelay
+	}
+	jitter := time.Duration(float64(delay) * 0.25 * (2*rand.Float64() - 1))
+	return delay + jitter
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DoWithRetry runs do with the default retry policy, for packages that
+// call other APIs directly, such as internal/morph.
+func DoWithRetry(ctx context.Context, method string, do func() (*http.Response, error)) (*http.Response, error) {
+	return withRetry(ctx, defaultRetryConfig(), method, do)
+}
+
+// withRetry runs do until it succeeds, fails permanently, or the retry
+// budget is spent. On giving up after a retryable status, the last
+// response is returned unread so callers can report the server's error.
+func withRetry(ctx context.Context, cfg retryConfig, method string, do func() (*http.Response, error)) (*http.Response, error) {
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := do()
+
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		if (err == nil && !isRetryableStatusCode(statusCode)) || attempt >= cfg.maxRetries || !isSafeToRetry(method, statusCode, err) {
+			return resp, err
+		}
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		delay := cfg.backoff(attempt + 1)
+		if resp != nil {
+			if ra := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ra > 0 {
+				delay = ra
+			}
+		}
+		if time.Since(start)+delay > cfg.maxElapsed {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
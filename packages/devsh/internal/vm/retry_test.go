@@ -0,0 +1,182 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func fastRetryConfig() retryConfig {
+	return retryConfig{
+		maxRetries: 3,
+		baseDelay:  time.Millisecond,
+		maxDelay:   5 * time.Millisecond,
+		maxElapsed: time.Second,
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := parseRetryAfter("3", now); got != 3*time.Second {
+		t.Errorf("seconds: got %v", got)
+	}
+	date := now.Add(10 * time.Second).Format(http.TimeFormat)
+	if got := parseRetryAfter(date, now); got != 10*time.Second {
+		t.Errorf("http date: got %v", got)
+	}
+	for _, v := range []string{"", "-1", "soon", now.Add(-time.Minute).Format(http.TimeFormat)} {
+		if got := parseRetryAfter(v, now); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", v, got)
+		}
+	}
+}
+
+func TestIsSafeToRetry(t *testing.T) {
+	cases := []struct {
+		method string
+		status int
+		err    error
+		want   bool
+	}{
+		{"GET", 502, nil, true},
+		{"GET", 429, nil, true},
+		{"GET", 500, nil, false},
+		{"GET", 0, errors.New("read: connection reset by peer"), true},
+		{"POST", 502, nil, false},
+		{"POST", 503, nil, true},
+		{"POST", 429, nil, true},
+		{"POST", 0, errors.New("read: connection reset by peer"), false},
+		{"POST", 0, errors.New("dial tcp: connection refused"), true},
+	}
+	for _, tc := range cases {
+		if got := isSafeToRetry(tc.method, tc.status, tc.err); got != tc.want {
+			t.Errorf("isSafeToRetry(%s, %d, %v) = %v, want %v", tc.method, tc.status, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWithRetryHonorsRetryAfter(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := fastRetryConfig()
+	cfg.maxElapsed = 5 * time.Second
+	start := time.Now()
+	resp, err := withRetry(context.Background(), cfg, "POST", func() (*http.Response, error) {
+		return http.Post(server.URL, "application/json", nil)
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Errorf("status=%d attempts=%d", resp.StatusCode, attempts)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, expected to wait for Retry-After", elapsed)
+	}
+}
+
+func TestWithRetryReturnsLastResponse(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream down"))
+	}))
+	defer server.Close()
+
+	resp, err := withRetry(context.Background(), fastRetryConfig(), "GET", func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if attempts != 4 {
+		t.Errorf("expected 4 attempts, got %d", attempts)
+	}
+	// The final response must still be readable for error reporting
+	if body := readErrorBody(resp.Body); body != "upstream down" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestWithRetryDoesNotRetryPostOnBadGateway(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	resp, err := withRetry(context.Background(), fastRetryConfig(), "POST", func() (*http.Response, error) {
+		return http.Post(server.URL, "application/json", nil)
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	resp.Body.Close()
+	if attempts != 1 {
+		t.Errorf("POST was retried: %d attempts", attempts)
+	}
+}
+
+func TestWithRetryStopsOnContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := fastRetryConfig()
+	cfg.maxElapsed = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := withRetry(ctx, cfg, "GET", func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("withRetry ignored cancellation, took %v", elapsed)
+	}
+}
+
+func TestWithRetryRespectsElapsedBudget(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := withRetry(context.Background(), fastRetryConfig(), "GET", func() (*http.Response, error) {
+		return http.Get(server.URL)
+	})
+	if err != nil {
+		t.Fatalf("withRetry: %v", err)
+	}
+	resp.Body.Close()
+	if attempts != 1 || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected to give up immediately, attempts=%d status=%d", attempts, resp.StatusCode)
+	}
+}
@@ -3,13 +3,24 @@ package vm
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/karlorz/devsh/internal/provider"
 )
 
 // Ensure MorphProvider implements provider.SandboxProvider at compile time.
-var _ provider.SandboxProvider = (*MorphProvider)(nil)
+var (
+	_ provider.SandboxProvider = (*MorphProvider)(nil)
+	_ provider.Lifecycle       = (*MorphProvider)(nil)
+	_ provider.URLResolver     = (*MorphProvider)(nil)
+)
+
+// Worker paths the authenticated service URLs redirect to.
+const (
+	vscodeAuthPath = "/code/?folder=/home/cmux/workspace"
+	vncAuthPath    = "/vnc/vnc.html?path=vnc/websockify&resize=scale&quality=9&compression=0"
+)
 
 // MorphProvider wraps vm.Client to implement provider.SandboxProvider.
 type MorphProvider struct {
@@ -119,6 +130,41 @@ func (p *MorphProvider) WaitReady(ctx context.Context, id string, timeout time.D
 	return instanceToSandbox(instance), nil
 }
 
+// Pause suspends a sandbox, preserving its state.
+func (p *MorphProvider) Pause(ctx context.Context, id string) error {
+	return p.client.PauseInstance(ctx, id)
+}
+
+// Resume restarts a paused sandbox and waits for it to be ready.
+func (p *MorphProvider) Resume(ctx context.Context, id string) (*provider.Sandbox, error) {
+	if err := p.client.ResumeInstance(ctx, id); err != nil {
+		return nil, err
+	}
+	return p.WaitReady(ctx, id, 2*time.Minute)
+}
+
+// URLs returns the sandbox with VS Code and VNC URLs that log the browser in
+// with a one-time token.
+func (p *MorphProvider) URLs(ctx context.Context, id string) (*provider.Sandbox, error) {
+	instance, err := p.client.GetInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sandbox := instanceToSandbox(instance)
+
+	token, err := p.client.GenerateAuthToken(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate auth token: %w", err)
+	}
+	if sandbox.VSCodeURL, err = BuildAuthURL(instance.WorkerURL, vscodeAuthPath, token); err != nil {
+		return nil, err
+	}
+	if sandbox.VNCURL, err = BuildAuthURL(instance.WorkerURL, vncAuthPath, token); err != nil {
+		return nil, err
+	}
+	return sandbox, nil
+}
+
 // instanceToSandbox converts a vm.Instance to provider.Sandbox.
 func instanceToSandbox(inst *Instance) *provider.Sandbox {
 	return &provider.Sandbox{
@@ -128,6 +174,7 @@ func instanceToSandbox(inst *Instance) *provider.Sandbox {
 		VNCURL:    inst.VNCURL,
 		WorkerURL: inst.WorkerURL,
 		ChromeURL: inst.ChromeURL,
+		XTermURL:  inst.XTermURL,
 		Provider:  provider.Morph,
 	}
 }
@@ -0,0 +1,282 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHInfo describes how to reach an instance through the Morph SSH proxy
+type SSHInfo struct {
+	Command    string // e.g. "ssh token@ssh.cloud.morph.so"
+	User       string
+	Host       string
+	Port       int
+	PrivateKey string // PEM private key, if the API issued one
+	HostKey    string // proxy host key in authorized_keys format, if known
+}
+
+// Addr returns the host:port of the SSH proxy
+func (i *SSHInfo) Addr() string {
+	return net.JoinHostPort(i.Host, strconv.Itoa(i.Port))
+}
+
+type sshDetails struct {
+	SSHCommand string `json:"sshCommand"`
+	PrivateKey string `json:"privateKey"`
+	HostKey    string `json:"hostKey"`
+}
+
+func (c *Client) getSSHDetails(ctx context.Context, instanceID string) (*sshDetails, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/api/v1/cmux/instances/%s/ssh?teamSlugOrId=%s", instanceID, c.teamSlug)
+	resp, err := c.doRequestWithRetry(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, "")
+	}
+
+	var result sshDetails
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// GetSSHInfo gets SSH connection details for an instance
+func (c *Client) GetSSHInfo(ctx context.Context, instanceID string) (*SSHInfo, error) {
+	result, err := c.getSSHDetails(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseSSHCommand(result.SSHCommand)
+	if err != nil {
+		return nil, err
+	}
+	info.PrivateKey = result.PrivateKey
+	info.HostKey = result.HostKey
+	return info, nil
+}
+
+// parseSSHCommand parses commands like "ssh token@ssh.cloud.morph.so" or
+// "ssh -p 2222 token@host" into their connection details.
+func parseSSHCommand(command string) (*SSHInfo, error) {
+	info := &SSHInfo{Command: command, Port: 22}
+	parts := strings.Fields(command)
+	if len(parts) < 2 || parts[0] != "ssh" {
+		return nil, fmt.Errorf("invalid SSH command format")
+	}
+	for i := 1; i < len(parts); i++ {
+		switch {
+		case parts[i] == "-p" && i+1 < len(parts):
+			port, err := strconv.Atoi(parts[i+1])
+			if err != nil || port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid SSH port %q", parts[i+1])
+			}
+			info.Port = port
+			i++
+		case strings.Contains(parts[i], "@"):
+			user, host, _ := strings.Cut(parts[i], "@")
+			info.User, info.Host = user, host
+		}
+	}
+	if info.User == "" || info.Host == "" {
+		return nil, fmt.Errorf("invalid SSH command format")
+	}
+	return info, nil
+}
+
+// SSHConn is an SSH connection to an instance
+type SSHConn struct {
+	client *ssh.Client
+}
+
+// SSHDial connects to an instance through the Morph SSH proxy. If the API
+// returns a proxy host key it is pinned; otherwise host key checking is
+// skipped for the same reasons as sshOptions.
+func (c *Client) SSHDial(ctx context.Context, instanceID string) (*SSHConn, error) {
+	info, err := c.GetSSHInfo(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH credentials: %w", err)
+	}
+	config, err := sshClientConfig(info)
+	if err != nil {
+		return nil, err
+	}
+	return dialSSH(ctx, info.Addr(), config)
+}
+
+func sshClientConfig(info *SSHInfo) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User:            info.User,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	}
+	if info.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(info.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH private key: %w", err)
+		}
+		config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
+	}
+	if info.HostKey != "" {
+		hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(info.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH host key: %w", err)
+		}
+		config.HostKeyCallback = ssh.FixedHostKey(hostKey)
+		config.HostKeyAlgorithms = []string{hostKey.Type()}
+	}
+	return config, nil
+}
+
+// dialSSH dials addr and performs the SSH handshake, aborting if ctx is
+// cancelled before the handshake completes.
+func dialSSH(ctx context.Context, addr string, config *ssh.ClientConfig) (*SSHConn, error) {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH proxy: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if !stop() {
+		if err == nil {
+			sshConn.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("SSH handshake failed: %w", err)
+	}
+
+	return &SSHConn{client: ssh.NewClient(sshConn, chans, reqs)}, nil
+}
+
+// Client returns the underlying SSH client
+func (s *SSHConn) Client() *ssh.Client {
+	return s.client
+}
+
+// Close closes the connection and any forwards using it
+func (s *SSHConn) Close() error {
+	return s.client.Close()
+}
+
+// SSHExecResult is the result of running a command over SSH
+type SSHExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Exec runs a command on the instance. A non-zero exit status is reported
+// in the result, not as an error. Cancelling ctx closes the session.
+func (s *SSHConn) Exec(ctx context.Context, command string) (*SSHExecResult, error) {
+	var stdout, stderr bytes.Buffer
+	exitCode, err := s.Run(ctx, command, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHExecResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: exitCode}, nil
+}
+
+// Run runs a command on the instance, streaming its output to stdout and
+// stderr as it arrives, and returns its exit status. Cancelling ctx closes
+// the session.
+func (s *SSHConn) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	err = session.Run(command)
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus(), nil
+	case err != nil:
+		return 0, fmt.Errorf("SSH command failed: %w", err)
+	}
+	return 0, nil
+}
+
+// Forward listens on localAddr and forwards each connection to remoteAddr
+// as seen from the instance (e.g. "localhost:5432"). Forwarding stops when
+// ctx is cancelled or the returned listener is closed.
+func (s *SSHConn) Forward(ctx context.Context, localAddr, remoteAddr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "tcp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", localAddr, err)
+	}
+
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	go func() {
+		defer stop()
+		for {
+			local, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.forwardConn(local, remoteAddr)
+		}
+	}()
+
+	return listener, nil
+}
+
+func (s *SSHConn) forwardConn(local net.Conn, remoteAddr string) {
+	defer local.Close()
+	remote, err := s.client.Dial("tcp", remoteAddr)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(remote, local)
+		remote.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(local, remote)
+		local.Close()
+	}()
+	wg.Wait()
+}
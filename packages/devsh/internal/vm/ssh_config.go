@@ -0,0 +1,211 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/karlorz/devsh/internal/atomicfile"
+)
+
+// SSHHostPrefix prefixes the ssh config Host alias written for each instance
+const SSHHostPrefix = "cmux-"
+
+const (
+	sshConfigBegin = "# BEGIN devsh managed hosts (generated by `devsh ssh-config sync`; edits are overwritten)"
+	sshConfigEnd   = "# END devsh managed hosts"
+)
+
+// SSHHostAlias returns the ssh config alias for an instance, e.g.
+// cmux-abc123 for cmux_abc123.
+func SSHHostAlias(instanceID string) string {
+	return SSHHostPrefix + strings.TrimPrefix(instanceID, "cmux_")
+}
+
+// SSHConfigWriter maintains a managed block of Host entries in an ssh config
+// file. Private keys and pinned proxy host keys issued by the API are stored
+// as per-alias files in KeyDir.
+type SSHConfigWriter struct {
+	ConfigPath string // e.g. ~/.ssh/config
+	KeyDir     string // e.g. ~/.ssh/cmux
+}
+
+// SSHConfigSyncResult lists the aliases written and removed by Sync
+type SSHConfigSyncResult struct {
+	Hosts   []string `json:"hosts"`
+	Removed []string `json:"removed"`
+}
+
+// DefaultSSHConfigWriter writes to ~/.ssh/config with keys in ~/.ssh/cmux
+func DefaultSSHConfigWriter() (*SSHConfigWriter, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sshDir := filepath.Join(home, ".ssh")
+	return &SSHConfigWriter{
+		ConfigPath: filepath.Join(sshDir, "config"),
+		KeyDir:     filepath.Join(sshDir, "cmux"),
+	}, nil
+}
+
+// Sync rewrites the managed block so it holds exactly the given instances
+// (keyed by instance ID), and deletes key files of aliases no longer listed.
+// Content outside the managed block is left untouched.
+func (w *SSHConfigWriter) Sync(instances map[string]*SSHInfo) (*SSHConfigSyncResult, error) {
+	existing, err := os.ReadFile(w.ConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", w.ConfigPath, err)
+	}
+
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := &SSHConfigSyncResult{}
+	current := make(map[string]bool, len(ids))
+	var block strings.Builder
+	for _, id := range ids {
+		alias := SSHHostAlias(id)
+		entry, err := w.hostEntry(alias, instances[id])
+		if err != nil {
+			return nil, err
+		}
+		block.WriteString(entry)
+		current[alias] = true
+		result.Hosts = append(result.Hosts, alias)
+	}
+
+	for _, alias := range managedSSHAliases(string(existing)) {
+		if !current[alias] {
+			result.Removed = append(result.Removed, alias)
+			os.Remove(filepath.Join(w.KeyDir, alias))
+			os.Remove(filepath.Join(w.KeyDir, alias+".known_hosts"))
+		}
+	}
+
+	updated := replaceManagedSSHBlock(string(existing), block.String())
+	if err := os.MkdirAll(filepath.Dir(w.ConfigPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(w.ConfigPath), err)
+	}
+	if err := atomicfile.WriteFile(w.ConfigPath, []byte(updated), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", w.ConfigPath, err)
+	}
+	return result, nil
+}
+
+// hostEntry renders the Host entry for alias, writing its key files. Host
+// keys are pinned when the API provides one; otherwise checking is disabled
+// for the same reasons as sshOptions.
+func (w *SSHConfigWriter) hostEntry(alias string, info *SSHInfo) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host %s\n", alias)
+	fmt.Fprintf(&b, "  HostName %s\n", info.Host)
+	fmt.Fprintf(&b, "  Port %d\n", info.Port)
+	fmt.Fprintf(&b, "  User %s\n", info.User)
+
+	if info.PrivateKey != "" {
+		path := filepath.Join(w.KeyDir, alias)
+		if err := w.writeKeyFile(path, info.PrivateKey); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "  IdentityFile %s\n", sshConfigQuote(path))
+		b.WriteString("  IdentitiesOnly yes\n")
+	}
+
+	if info.HostKey != "" {
+		path := filepath.Join(w.KeyDir, alias+".known_hosts")
+		if err := w.writeKeyFile(path, alias+" "+strings.TrimSpace(info.HostKey)); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "  HostKeyAlias %s\n", alias)
+		fmt.Fprintf(&b, "  UserKnownHostsFile %s\n", sshConfigQuote(path))
+		b.WriteString("  StrictHostKeyChecking yes\n")
+	} else {
+		b.WriteString("  StrictHostKeyChecking no\n")
+		b.WriteString("  UserKnownHostsFile /dev/null\n")
+		b.WriteString("  LogLevel ERROR\n")
+	}
+	b.WriteString("  ServerAliveInterval 30\n")
+	return b.String(), nil
+}
+
+func (w *SSHConfigWriter) writeKeyFile(path, content string) error {
+	if err := os.MkdirAll(w.KeyDir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", w.KeyDir, err)
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if err := atomicfile.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// managedSSHAliases returns the Host aliases inside the managed block
+func managedSSHAliases(config string) []string {
+	var aliases []string
+	inBlock := false
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == sshConfigBegin:
+			inBlock = true
+		case trimmed == sshConfigEnd:
+			inBlock = false
+		case inBlock && strings.HasPrefix(trimmed, "Host "):
+			if alias := strings.TrimSpace(strings.TrimPrefix(trimmed, "Host ")); alias != "*" {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases
+}
+
+// replaceManagedSSHBlock swaps the managed block in config for entries. A new
+// block goes at the top of the file: ssh uses the first value it finds for
+// each option, so a later `Host *` section cannot override these entries.
+// The block ends with `Host *` so options the user wrote at the top level,
+// after it, still apply to every host instead of only the last managed
+// one. Empty entries remove the block.
+func replaceManagedSSHBlock(config, entries string) string {
+	block := ""
+	if entries != "" {
+		block = sshConfigBegin + "\n" + entries + "Host *\n" + sshConfigEnd + "\n"
+	}
+
+	start := strings.Index(config, sshConfigBegin)
+	if start >= 0 {
+		end := strings.Index(config[start:], sshConfigEnd)
+		if end >= 0 {
+			end += start + len(sshConfigEnd)
+			rest := strings.TrimPrefix(config[end:], "\n")
+			if block == "" {
+				rest = strings.TrimPrefix(rest, "\n")
+			}
+			return config[:start] + block + rest
+		}
+	}
+
+	if block == "" {
+		return config
+	}
+	if config == "" {
+		return block
+	}
+	return block + "\n" + config
+}
+
+// sshConfigQuote quotes values containing spaces for ssh_config
+func sshConfigQuote(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,291 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseSSHCommand(t *testing.T) {
+	info, err := parseSSHCommand("ssh token@ssh.cloud.morph.so")
+	if err != nil {
+		t.Fatalf("parseSSHCommand: %v", err)
+	}
+	if info.User != "token" || info.Host != "ssh.cloud.morph.so" || info.Addr() != "ssh.cloud.morph.so:22" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	info, err = parseSSHCommand("ssh -p 2222 token@127.0.0.1")
+	if err != nil {
+		t.Fatalf("parseSSHCommand with port: %v", err)
+	}
+	if info.Addr() != "127.0.0.1:2222" {
+		t.Errorf("unexpected addr: %s", info.Addr())
+	}
+
+	for _, bad := range []string{"", "ssh", "ssh host", "scp token@host", "ssh -p x token@host"} {
+		if _, err := parseSSHCommand(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// startTestSSHServer runs a minimal SSH server that answers exec requests
+// with "ran: <command>" and exit status 7 for "fail", and supports
+// direct-tcpip forwarding.
+func startTestSSHServer(t *testing.T) (addr string, hostKey ssh.PublicKey) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("host signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSHConn(conn, config)
+		}
+	}()
+
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func serveTestSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				defer channel.Close()
+				for req := range requests {
+					if req.Type != "exec" {
+						req.Reply(false, nil)
+						continue
+					}
+					command := string(req.Payload[4:])
+					req.Reply(true, nil)
+					status := uint32(0)
+					if command == "fail" {
+						status = 7
+						fmt.Fprint(channel.Stderr(), "failed")
+					} else {
+						fmt.Fprintf(channel, "ran: %s", command)
+					}
+					payload := make([]byte, 4)
+					binary.BigEndian.PutUint32(payload, status)
+					channel.SendRequest("exit-status", false, payload)
+					return
+				}
+			}()
+		case "direct-tcpip":
+			var target struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+				newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+			if err != nil {
+				newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				remote.Close()
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			go func() {
+				defer channel.Close()
+				defer remote.Close()
+				go io.Copy(remote, channel)
+				io.Copy(channel, remote)
+			}()
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+func newSSHTestClient(t *testing.T, sshAddr, hostKey string) *Client {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.CacheAccessToken("test-token", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("CacheAccessToken failed: %v", err)
+	}
+
+	host, port, _ := net.SplitHostPort(sshAddr)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/cmux/instances/inst-1/ssh" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"sshCommand":"ssh -p %s token@%s","hostKey":%q}`, port, host, hostKey)
+	}))
+	t.Cleanup(server.Close)
+
+	return &Client{httpClient: server.Client(), baseURL: server.URL, teamSlug: "example-team"}
+}
+
+func TestSSHDialExecWithPinnedHostKey(t *testing.T) {
+	addr, hostKey := startTestSSHServer(t)
+	client := newSSHTestClient(t, addr, string(ssh.MarshalAuthorizedKey(hostKey)))
+
+	conn, err := client.SSHDial(context.Background(), "inst-1")
+	if err != nil {
+		t.Fatalf("SSHDial failed: %v", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Exec(context.Background(), "uname")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if result.Stdout != "ran: uname" || result.ExitCode != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	result, err = conn.Exec(context.Background(), "fail")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if result.ExitCode != 7 || result.Stderr != "failed" {
+		t.Errorf("unexpected failing result: %+v", result)
+	}
+}
+
+func TestSSHConnRunStreamsOutput(t *testing.T) {
+	addr, hostKey := startTestSSHServer(t)
+	client := newSSHTestClient(t, addr, string(ssh.MarshalAuthorizedKey(hostKey)))
+
+	conn, err := client.SSHDial(context.Background(), "inst-1")
+	if err != nil {
+		t.Fatalf("SSHDial failed: %v", err)
+	}
+	defer conn.Close()
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := conn.Run(context.Background(), InSyncDir("make test"), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if exitCode != 0 || !strings.HasPrefix(stdout.String(), `ran: cd "$(for p in /home/cmux/workspace`) || !strings.HasSuffix(stdout.String(), `)" && make test`) {
+		t.Errorf("Run = %d, stdout %q", exitCode, stdout.String())
+	}
+
+	stdout.Reset()
+	exitCode, err = conn.Run(context.Background(), "fail", &stdout, &stderr)
+	if err != nil || exitCode != 7 || stderr.String() != "failed" {
+		t.Errorf("failing Run = %d, %v, stderr %q", exitCode, err, stderr.String())
+	}
+}
+
+func TestSSHDialRejectsWrongHostKey(t *testing.T) {
+	addr, _ := startTestSSHServer(t)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	otherKey, err := ssh.NewPublicKey(otherPub)
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	client := newSSHTestClient(t, addr, string(ssh.MarshalAuthorizedKey(otherKey)))
+
+	conn, err := client.SSHDial(context.Background(), "inst-1")
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected host key mismatch to fail")
+	}
+	if !strings.Contains(err.Error(), "handshake failed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSSHConnForward(t *testing.T) {
+	addr, _ := startTestSSHServer(t)
+	client := newSSHTestClient(t, addr, "")
+
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer target.Close()
+	go func() {
+		conn, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "hello from instance")
+	}()
+
+	conn, err := client.SSHDial(context.Background(), "inst-1")
+	if err != nil {
+		t.Fatalf("SSHDial failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener, err := conn.Forward(ctx, "127.0.0.1:0", target.Addr().String())
+	if err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+
+	local, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial forward: %v", err)
+	}
+	defer local.Close()
+	local.SetDeadline(time.Now().Add(5 * time.Second))
+	data, err := io.ReadAll(local)
+	if err != nil {
+		t.Fatalf("read forward: %v", err)
+	}
+	if string(data) != "hello from instance" {
+		t.Errorf("unexpected forwarded data %q", data)
+	}
+}
@@ -0,0 +1,183 @@
+// internal/vm/task_artifacts.go
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+// TaskRunArtifact is a file produced by a task run (diff, patch,
+// screenshot, ...).
+type TaskRunArtifact struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind,omitempty"` // e.g. "diff", "patch", "screenshot"
+	ContentType string `json:"contentType,omitempty"`
+	Size        int64  `json:"size"`      // Bytes, 0 if unknown
+	CreatedAt   int64  `json:"createdAt"` // Unix milliseconds
+}
+
+// Time returns when the artifact was created.
+func (a TaskRunArtifact) Time() time.Time {
+	return time.UnixMilli(a.CreatedAt)
+}
+
+// FileName returns a local file name for the artifact: the base of its
+// name, falling back to its ID, so a server-supplied name can never
+// escape the download directory.
+func (a TaskRunArtifact) FileName() string {
+	name := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(a.Name, "\\", "/")))
+	if name == "/" || name == "." || name == "" {
+		return a.ID
+	}
+	return name
+}
+
+// ArtifactDownload describes a finished DownloadArtifact call.
+type ArtifactDownload struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	ResumedFrom int64  `json:"resumedFrom,omitempty"` // Bytes already on disk from an earlier attempt
+	Skipped     bool   `json:"skipped,omitempty"`     // Path already held the complete file
+}
+
+// ListTaskRunArtifacts lists the artifacts recorded for a task run.
+func (c *Client) ListTaskRunArtifacts(ctx context.Context, runID string) ([]TaskRunArtifact, error) {
+	path := fmt.Sprintf("/api/v1/cmux/task-runs/%s/artifacts?teamSlugOrId=%s",
+		url.PathEscape(runID), url.QueryEscape(c.teamSlug))
+	resp, err := c.doRequestWithRetry(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, formatAPIError(resp.StatusCode, readErrorBody(resp.Body), "list task run artifacts")
+	}
+
+	var result struct {
+		Artifacts []TaskRunArtifact `json:"artifacts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Artifacts, nil
+}
+
+// DownloadArtifact saves an artifact to dest. Data is written to
+// dest+".part" and renamed once complete; if an earlier attempt left a
+// partial file, the download resumes from its end with a Range request
+// (starting over if the server ignores the range).
+func (c *Client) DownloadArtifact(ctx context.Context, runID string, artifact TaskRunArtifact, dest string) (*ArtifactDownload, error) {
+	if fi, err := os.Stat(dest); err == nil && artifact.Size > 0 && fi.Size() == artifact.Size {
+		return &ArtifactDownload{Path: dest, Size: artifact.Size, Skipped: true}, nil
+	}
+
+	partPath := dest + ".part"
+	var offset int64
+	if fi, err := os.Stat(partPath); err == nil {
+		offset = fi.Size()
+		if artifact.Size > 0 && offset > artifact.Size {
+			offset = 0
+		}
+	}
+
+	resp, err := c.getArtifact(ctx, runID, artifact.ID, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return nil, fmt.Errorf("server resumed artifact %s at an unexpected offset (%q)", artifact.ID, resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && offset == artifact.Size:
+		// The partial file already holds every byte.
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+		flags |= os.O_TRUNC
+	default:
+		return nil, formatAPIError(resp.StatusCode, readErrorBody(resp.Body), "download artifact")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	var written int64
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		written, err = io.Copy(f, resp.Body)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Keep the .part file so the next attempt resumes from here.
+		return nil, fmt.Errorf("download artifact %s: %w", artifact.ID, err)
+	}
+
+	size := offset + written
+	if artifact.Size > 0 && size != artifact.Size {
+		return nil, fmt.Errorf("download artifact %s: got %d bytes, expected %d", artifact.ID, size, artifact.Size)
+	}
+	if err := os.Rename(partPath, dest); err != nil {
+		return nil, err
+	}
+	return &ArtifactDownload{Path: dest, Size: size, ResumedFrom: offset}, nil
+}
+
+// getArtifact requests an artifact's content from offset. It cannot use
+// doRequest, which does not take extra headers.
+func (c *Client) getArtifact(ctx context.Context, runID, artifactID string, offset int64) (*http.Response, error) {
+	accessToken, err := auth.GetAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("not authenticated: %w", err)
+	}
+
+	path := fmt.Sprintf("/api/v1/cmux/task-runs/%s/artifacts/%s/download?teamSlugOrId=%s",
+		url.PathEscape(runID), url.PathEscape(artifactID), url.QueryEscape(c.teamSlug))
+	return withRetry(ctx, defaultRetryConfig(), "GET", func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		return c.httpClient.Do(req)
+	})
+}
+
+// contentRangeStart parses the first byte position from a Content-Range
+// header such as "bytes 100-199/200".
+func contentRangeStart(header string) (int64, bool) {
+	rest, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,120 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const artifactBody = "diff --git a/x b/x\n+hello\n"
+
+// artifactServer serves one artifact and honours open-ended Range requests
+// unless ignoreRange is set.
+type artifactServer struct {
+	ignoreRange bool
+	ranges      []string
+}
+
+func (s *artifactServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/cmux/task-runs/run-1/artifacts":
+		_ = json.NewEncoder(w).Encode(map[string]any{"artifacts": []TaskRunArtifact{
+			{ID: "art-1", Name: "changes.diff", Kind: "diff", Size: int64(len(artifactBody))},
+		}})
+	case "/api/v1/cmux/task-runs/run-1/artifacts/art-1/download":
+		rng := r.Header.Get("Range")
+		s.ranges = append(s.ranges, rng)
+		var start int
+		if rng != "" && !s.ignoreRange {
+			fmt.Sscanf(rng, "bytes=%d-", &start)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(artifactBody)-1, len(artifactBody)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		_, _ = w.Write([]byte(artifactBody[start:]))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestListTaskRunArtifacts(t *testing.T) {
+	client := newBatchTestClient(t, &artifactServer{})
+	artifacts, err := client.ListTaskRunArtifacts(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("ListTaskRunArtifacts failed: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "changes.diff" {
+		t.Fatalf("unexpected artifacts: %+v", artifacts)
+	}
+}
+
+func TestDownloadArtifactResumesPartialFile(t *testing.T) {
+	server := &artifactServer{}
+	client := newBatchTestClient(t, server)
+	artifact := TaskRunArtifact{ID: "art-1", Name: "changes.diff", Size: int64(len(artifactBody))}
+	dest := filepath.Join(t.TempDir(), "changes.diff")
+	if err := os.WriteFile(dest+".part", []byte(artifactBody[:10]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := client.DownloadArtifact(context.Background(), "run-1", artifact, dest)
+	if err != nil {
+		t.Fatalf("DownloadArtifact failed: %v", err)
+	}
+	if dl.ResumedFrom != 10 || dl.Size != artifact.Size {
+		t.Errorf("unexpected result: %+v", dl)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != artifactBody {
+		t.Errorf("file content = %q", got)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("expected .part file to be renamed")
+	}
+	if server.ranges[0] != "bytes=10-" {
+		t.Errorf("Range = %q", server.ranges[0])
+	}
+
+	again, err := client.DownloadArtifact(context.Background(), "run-1", artifact, dest)
+	if err != nil || !again.Skipped || len(server.ranges) != 1 {
+		t.Errorf("expected complete file to be skipped, got %+v, %v", again, err)
+	}
+}
+
+func TestDownloadArtifactRestartsWhenRangeIgnored(t *testing.T) {
+	client := newBatchTestClient(t, &artifactServer{ignoreRange: true})
+	artifact := TaskRunArtifact{ID: "art-1", Name: "changes.diff", Size: int64(len(artifactBody))}
+	dest := filepath.Join(t.TempDir(), "changes.diff")
+	if err := os.WriteFile(dest+".part", []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dl, err := client.DownloadArtifact(context.Background(), "run-1", artifact, dest)
+	if err != nil {
+		t.Fatalf("DownloadArtifact failed: %v", err)
+	}
+	if dl.ResumedFrom != 0 {
+		t.Errorf("ResumedFrom = %d, want 0", dl.ResumedFrom)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != artifactBody {
+		t.Errorf("file content = %q", got)
+	}
+}
+
+func TestArtifactFileName(t *testing.T) {
+	tests := map[string]string{
+		"changes.diff":           "changes.diff",
+		"screens/after.png":      "after.png",
+		"../../etc/passwd":       "passwd",
+		`..\..\windows\evil.bat`: "evil.bat",
+		"":                       "art-1",
+		"..":                     "art-1",
+	}
+	for name, want := range tests {
+		if got := (TaskRunArtifact{ID: "art-1", Name: name}).FileName(); got != want {
+			t.Errorf("FileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// internal/vm/task_logs.go
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// taskRunLogPollInterval is how often a followed log stream asks for new
+// entries.
+var taskRunLogPollInterval = 2 * time.Second
+
+// TaskRunLogEntry is one line of agent output from a task run.
+type TaskRunLogEntry struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	Type      string `json:"type"`
+	ToolName  string `json:"toolName,omitempty"`
+	Summary   string `json:"summary"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Time returns the entry timestamp.
+func (e TaskRunLogEntry) Time() time.Time {
+	return time.UnixMilli(e.Timestamp)
+}
+
+// TaskRunLogPage is a page of log entries after a cursor.
+type TaskRunLogPage struct {
+	Entries []TaskRunLogEntry `json:"entries"`
+	Cursor  string            `json:"cursor"` // Opaque; pass back to resume after this page
+	Status  string            `json:"status"`
+	Done    bool              `json:"done"` // Run has finished; no more entries will be written
+}
+
+// getTaskRunLogPage fetches log entries written after cursor.
+func (c *Client) getTaskRunLogPage(ctx context.Context, runID, cursor string) (*TaskRunLogPage, error) {
+	path := fmt.Sprintf("/api/v1/cmux/task-runs/%s/logs?teamSlugOrId=%s&cursor=%s",
+		url.PathEscape(runID), url.QueryEscape(c.teamSlug), url.QueryEscape(cursor))
+	resp, err := c.doRequestWithRetry(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, formatAPIError(resp.StatusCode, readErrorBody(resp.Body), "get task run logs")
+	}
+
+	var page TaskRunLogPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
+
+// GetTaskRunLogs streams a task run's log entries in order. Without follow
+// it sends what has been written so far; with follow it keeps polling until
+// the run completes or ctx is done. The error channel receives a single
+// value (nil on success) after the entry channel is closed.
+func (c *Client) GetTaskRunLogs(ctx context.Context, runID string, follow bool) (<-chan TaskRunLogEntry, <-chan error) {
+	entries := make(chan TaskRunLogEntry)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		errc <- c.streamTaskRunLogs(ctx, runID, follow, entries)
+	}()
+
+	return entries, errc
+}
+
+func (c *Client) streamTaskRunLogs(ctx context.Context, runID string, follow bool, out chan<- TaskRunLogEntry) error {
+	defer close(out)
+
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
+	}
+
+	var cursor string
+	for {
+		page, err := c.getTaskRunLogPage(ctx, runID, cursor)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range page.Entries {
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if page.Cursor != "" {
+			cursor = page.Cursor
+		}
+
+		// Keep draining while full pages come back, even for a finished run.
+		if len(page.Entries) > 0 {
+			continue
+		}
+		if !follow || page.Done {
+			return nil
+		}
+		if err := sleepContext(ctx, taskRunLogPollInterval); err != nil {
+			return err
+		}
+	}
+}
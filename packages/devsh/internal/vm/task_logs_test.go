@@ -0,0 +1,102 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// taskLogServer serves log entries one per page using the server's
+// "createdAt:id" cursor. The first two share a timestamp; the last appears
+// after a few polls, then the run reports done.
+type taskLogServer struct {
+	polls atomic.Int32
+}
+
+var taskLogEntries = []TaskRunLogEntry{
+	{ID: "a", Timestamp: 100, Type: "tool_call", Summary: "read file"},
+	{ID: "a2", Timestamp: 100, Type: "tool_call", Summary: "edit file"},
+	{ID: "b", Timestamp: 200, Type: "session_finished", Summary: "done"},
+}
+
+func (s *taskLogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/cmux/task-runs/run-1/logs" {
+		http.NotFound(w, r)
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	n := s.polls.Add(1)
+
+	next := 0
+	for i, e := range taskLogEntries {
+		if cursor == fmt.Sprintf("%d:%s", e.Timestamp, e.ID) {
+			next = i + 1
+		}
+	}
+	page := TaskRunLogPage{Cursor: cursor, Status: "running"}
+	switch {
+	case next < 2 || (next == 2 && n >= 4):
+		e := taskLogEntries[next]
+		page.Entries = []TaskRunLogEntry{e}
+		page.Cursor = fmt.Sprintf("%d:%s", e.Timestamp, e.ID)
+	case next == 3:
+		page.Status = "completed"
+		page.Done = true
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func collectTaskRunLogs(t *testing.T, client *Client, follow bool) []TaskRunLogEntry {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entries, errc := client.GetTaskRunLogs(ctx, "run-1", follow)
+	var got []TaskRunLogEntry
+	for entry := range entries {
+		got = append(got, entry)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("GetTaskRunLogs failed: %v", err)
+	}
+	return got
+}
+
+func TestGetTaskRunLogsSnapshot(t *testing.T) {
+	client := newBatchTestClient(t, &taskLogServer{})
+
+	got := collectTaskRunLogs(t, client, false)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "a2" {
+		t.Fatalf("entries = %+v, want the two entries written so far", got)
+	}
+}
+
+func TestGetTaskRunLogsFollowUntilDone(t *testing.T) {
+	orig := taskRunLogPollInterval
+	taskRunLogPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { taskRunLogPollInterval = orig })
+
+	client := newBatchTestClient(t, &taskLogServer{})
+
+	got := collectTaskRunLogs(t, client, true)
+	if len(got) != 3 || got[0].ID != "a" || got[1].ID != "a2" || got[2].ID != "b" {
+		t.Fatalf("entries = %+v, want a, a2 then b", got)
+	}
+	if got[2].Time() != time.UnixMilli(200) {
+		t.Errorf("Time() = %v", got[2].Time())
+	}
+}
+
+func TestGetTaskRunLogsRequiresTeam(t *testing.T) {
+	client := &Client{}
+	entries, errc := client.GetTaskRunLogs(context.Background(), "run-1", false)
+	for range entries {
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("expected error without team slug")
+	}
+}
@@ -0,0 +1,118 @@
+// internal/vm/task_runs.go
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RetryTaskRunOptions controls RetryTaskRun.
+type RetryTaskRunOptions struct {
+	// Agent runs the retry; empty reuses the original run's agent.
+	Agent string
+	// CancelRunning cancels the run first if it is still pending or
+	// running. Without it, retrying an in-flight run is rejected.
+	CancelRunning bool
+}
+
+// RetryTaskRunResult is the run started by RetryTaskRun.
+type RetryTaskRunResult struct {
+	TaskID     string `json:"taskId"`
+	TaskRunID  string `json:"taskRunId"`
+	AgentName  string `json:"agentName"`
+	RetriedRun string `json:"retriedRunId"`
+	VSCodeURL  string `json:"vscodeUrl,omitempty"`
+}
+
+// CancelTaskRun stops a single task run's agent and marks the run
+// cancelled. Other runs of the task keep going.
+func (c *Client) CancelTaskRun(ctx context.Context, runID string) error {
+	if err := c.ensureTeam(ctx); err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"teamSlugOrId": c.teamSlug,
+	}
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/cmux/task-runs/%s/cancel", url.PathEscape(runID)), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, "cancel task run")
+	}
+	return nil
+}
+
+// RetryTaskRun starts a new run of the same task with the original run's
+// prompt and branch settings. The server creates the run; its agent is then
+// started through apps/server, as task create does.
+func (c *Client) RetryTaskRun(ctx context.Context, runID string, opts RetryTaskRunOptions) (*RetryTaskRunResult, error) {
+	if err := c.ensureTeam(ctx); err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"teamSlugOrId": c.teamSlug,
+	}
+	if opts.Agent != "" {
+		body["agentName"] = opts.Agent
+	}
+	if opts.CancelRunning {
+		body["cancelRunning"] = true
+	}
+	resp, err := c.doRequest(ctx, "POST", fmt.Sprintf("/api/v1/cmux/task-runs/%s/retry", url.PathEscape(runID)), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, "retry task run")
+	}
+
+	var created struct {
+		RetryTaskRunResult
+		Prompt        string `json:"prompt"`
+		Repository    string `json:"repository"`
+		BaseBranch    string `json:"baseBranch"`
+		EnvironmentID string `json:"environmentId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	result := created.RetryTaskRunResult
+
+	startOpts := StartTaskAgentsOptions{
+		TaskID:          result.TaskID,
+		TaskDescription: created.Prompt,
+		ProjectFullName: created.Repository,
+		Branch:          created.BaseBranch,
+		TaskRunIDs:      []string{result.TaskRunID},
+		SelectedAgents:  []string{result.AgentName},
+		EnvironmentID:   created.EnvironmentID,
+		IsCloudMode:     true,
+	}
+	if created.Repository != "" {
+		startOpts.RepoURL = "https://github.com/" + created.Repository
+	}
+	started, err := c.StartTaskAgents(ctx, startOpts)
+	if err != nil {
+		return nil, fmt.Errorf("created run %s but could not start its agent: %w", result.TaskRunID, err)
+	}
+	for _, r := range started.Results {
+		if r.TaskRunID != result.TaskRunID {
+			continue
+		}
+		if !r.Success {
+			return nil, fmt.Errorf("created run %s but its agent failed to start: %s", result.TaskRunID, r.Error)
+		}
+		result.VSCodeURL = r.VSCodeURL
+	}
+	return &result, nil
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+func TestCancelTaskRun(t *testing.T) {
+	var body map[string]any
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/cmux/task-runs/run-1/cancel" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"cancelled":true}`))
+	}))
+
+	if err := client.CancelTaskRun(context.Background(), "run-1"); err != nil {
+		t.Fatalf("CancelTaskRun failed: %v", err)
+	}
+	if body["teamSlugOrId"] != "example-team" {
+		t.Errorf("unexpected body: %v", body)
+	}
+
+	if err := client.CancelTaskRun(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRetryTaskRun(t *testing.T) {
+	var body, startBody map[string]any
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/cmux/task-runs/run-1/retry":
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_, _ = w.Write([]byte(`{"taskId":"task-1","taskRunId":"run-2","jwt":"jwt-2","agentName":"claude/opus","retriedRunId":"run-1",` +
+				`"prompt":"fix the flaky test","repository":"acme/api","baseBranch":"develop"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/start-task":
+			_ = json.NewDecoder(r.Body).Decode(&startBody)
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"taskId":"task-1","results":[{"agentName":"claude/opus","taskRunId":"run-2","success":true,"vscodeUrl":"https://vscode.example"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	auth.SetServerURLOverride(client.baseURL)
+	t.Cleanup(func() { auth.SetServerURLOverride("") })
+
+	result, err := client.RetryTaskRun(context.Background(), "run-1", RetryTaskRunOptions{CancelRunning: true})
+	if err != nil {
+		t.Fatalf("RetryTaskRun failed: %v", err)
+	}
+	if result.TaskRunID != "run-2" || result.RetriedRun != "run-1" || result.VSCodeURL != "https://vscode.example" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if body["cancelRunning"] != true {
+		t.Errorf("expected cancelRunning in body, got %v", body)
+	}
+	if _, ok := body["agentName"]; ok {
+		t.Errorf("empty agent should be omitted, got %v", body)
+	}
+
+	// The agent for the run the server created is started, not a new one.
+	if ids, _ := startBody["taskRunIds"].([]any); len(ids) != 1 || ids[0] != "run-2" {
+		t.Errorf("start-task taskRunIds = %v, want [run-2]", startBody["taskRunIds"])
+	}
+	if startBody["taskDescription"] != "fix the flaky test" || startBody["repoUrl"] != "https://github.com/acme/api" || startBody["branch"] != "develop" {
+		t.Errorf("unexpected start-task body: %v", startBody)
+	}
+}
+
+func TestRetryTaskRunAgentStartFails(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/cmux/task-runs/run-1/retry":
+			_, _ = w.Write([]byte(`{"taskId":"task-1","taskRunId":"run-2","agentName":"claude/opus","retriedRunId":"run-1","prompt":"p"}`))
+		case "/api/start-task":
+			_, _ = w.Write([]byte(`{"taskId":"task-1","results":[{"agentName":"claude/opus","taskRunId":"run-2","success":false,"error":"no credentials"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	auth.SetServerURLOverride(client.baseURL)
+	t.Cleanup(func() { auth.SetServerURLOverride("") })
+
+	_, err := client.RetryTaskRun(context.Background(), "run-1", RetryTaskRunOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Fatalf("expected agent start error, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// internal/vm/team.go
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+// ensureTeam makes sure a team is set before a team-scoped request. Clients
+// from NewClient resolve it on first use: DEVSH_TEAM, then the saved default
+// team, then the selected team from /me/teams, which is saved as the default.
+func (c *Client) ensureTeam(ctx context.Context) error {
+	c.teamMu.Lock()
+	defer c.teamMu.Unlock()
+
+	if c.teamSlug != "" {
+		return nil
+	}
+	if !c.autoTeam {
+		return fmt.Errorf("team slug not set")
+	}
+
+	slug, err := c.resolveTeam(ctx)
+	if err != nil {
+		return fmt.Errorf("team slug not set: %w\nRun 'devsh team use <team>' to choose a default team", err)
+	}
+	c.teamSlug = slug
+	return nil
+}
+
+func (c *Client) resolveTeam(ctx context.Context) (string, error) {
+	if slug := auth.GetTeamOverride(); slug != "" {
+		return slug, nil
+	}
+	if team, err := auth.GetDefaultTeam(); err == nil {
+		return team.SlugOrID(), nil
+	}
+
+	result, err := c.ListTeams(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list teams: %w", err)
+	}
+	selected := selectedTeam(result)
+	if selected == nil {
+		return "", fmt.Errorf("no team selected")
+	}
+
+	team := auth.DefaultTeam{TeamID: selected.TeamID, Slug: selected.Slug, DisplayName: selected.DisplayName}
+	if err := auth.SetDefaultTeam(team); err != nil {
+		return "", fmt.Errorf("failed to save default team: %w", err)
+	}
+	return team.SlugOrID(), nil
+}
+
+// selectedTeam returns the server-selected team, or the only team when the
+// user belongs to exactly one.
+func selectedTeam(result *ListTeamsResult) *Team {
+	for i := range result.Teams {
+		t := &result.Teams[i]
+		if t.Selected || (result.SelectedTeamID != "" && t.TeamID == result.SelectedTeamID) {
+			return t
+		}
+	}
+	if len(result.Teams) == 1 {
+		return &result.Teams[0]
+	}
+	return nil
+}
+
+// FindTeam returns the team matching a slug or ID from the user's teams.
+func (c *Client) FindTeam(ctx context.Context, slugOrID string) (*Team, error) {
+	result, err := c.ListTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Teams {
+		t := &result.Teams[i]
+		if t.Slug == slugOrID || t.TeamID == slugOrID {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("you are not a member of team %q", slugOrID)
+}
@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/karlorz/devsh/internal/auth"
+)
+
+func TestEnsureTeamResolvesAndSavesSelectedTeam(t *testing.T) {
+	t.Setenv("DEVSH_TEAM", "")
+	t.Setenv("DEVBOX_TEAM", "")
+
+	var teamCalls atomic.Int32
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/cmux/me/teams":
+			teamCalls.Add(1)
+			_ = json.NewEncoder(w).Encode(ListTeamsResult{Teams: []Team{
+				{TeamID: "t1", Slug: "other"},
+				{TeamID: "t2", Slug: "picked", Selected: true},
+			}})
+		case "/api/v1/cmux/tasks":
+			if got := r.URL.Query().Get("teamSlugOrId"); got != "picked" {
+				t.Errorf("teamSlugOrId = %q, want picked", got)
+			}
+			_, _ = w.Write([]byte(`{"tasks":[]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	client.teamSlug = ""
+	client.autoTeam = true
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListTasks(context.Background(), false); err != nil {
+			t.Fatalf("ListTasks: %v", err)
+		}
+	}
+	if n := teamCalls.Load(); n != 1 {
+		t.Errorf("expected one team lookup, got %d", n)
+	}
+	if team, err := auth.GetDefaultTeam(); err != nil || team.Slug != "picked" {
+		t.Errorf("default team = %+v, %v", team, err)
+	}
+}
+
+func TestEnsureTeamUsesSavedDefault(t *testing.T) {
+	t.Setenv("DEVSH_TEAM", "")
+	t.Setenv("DEVBOX_TEAM", "")
+	client := newBatchTestClient(t, http.NotFoundHandler())
+	client.teamSlug = ""
+	client.autoTeam = true
+
+	if err := auth.SetDefaultTeam(auth.DefaultTeam{Slug: "saved"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ensureTeam(context.Background()); err != nil {
+		t.Fatalf("ensureTeam: %v", err)
+	}
+	if client.teamSlug != "saved" {
+		t.Errorf("teamSlug = %q", client.teamSlug)
+	}
+}
+
+func TestEnsureTeamWithoutAutoResolution(t *testing.T) {
+	client := &Client{}
+	err := client.ensureTeam(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "team slug not set") {
+		t.Fatalf("ensureTeam = %v", err)
+	}
+}
+
+func TestSwitchTeamUpdatesDefault(t *testing.T) {
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(SwitchTeamResult{TeamID: "t9", TeamSlug: "new-team"})
+	}))
+
+	if _, err := client.SwitchTeam(context.Background(), "new-team"); err != nil {
+		t.Fatalf("SwitchTeam: %v", err)
+	}
+	if client.teamSlug != "new-team" {
+		t.Errorf("client teamSlug = %q", client.teamSlug)
+	}
+	if team, err := auth.GetDefaultTeam(); err != nil || team.Slug != "new-team" || team.TeamID != "t9" {
+		t.Errorf("default team = %+v, %v", team, err)
+	}
+}
@@ -0,0 +1,133 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// readyPollConfig paces WaitForReady's polls: quick at first while the
+// instance boots, backing off to maxDelay for slow starts.
+var readyPollConfig = retryConfig{
+	baseDelay: time.Second,
+	maxDelay:  5 * time.Second,
+}
+
+// Phases reported to WaitOptions.OnProgress.
+const (
+	WaitPhasePolling  = "polling"  // Instance seen, not running yet
+	WaitPhaseRetrying = "retrying" // Last poll failed with a transient error
+)
+
+// WaitProgress is passed to WaitOptions.OnProgress after each poll.
+type WaitProgress struct {
+	Phase   string
+	Elapsed time.Duration
+	Status  string // Last instance status seen, "" before the first
+	LastErr error  // Error from this poll, if it failed
+}
+
+// WaitOptions configures WaitForReadyWithOptions.
+type WaitOptions struct {
+	Timeout    time.Duration
+	OnProgress func(WaitProgress)
+}
+
+// WaitTimeoutError is returned when an instance is not running before the
+// timeout. Instance is the last state seen (nil if every poll failed) and
+// LastErr the most recent poll error.
+type WaitTimeoutError struct {
+	InstanceID string
+	Timeout    time.Duration
+	Instance   *Instance
+	LastErr    error
+}
+
+func (e *WaitTimeoutError) Error() string {
+	msg := fmt.Sprintf("instance %s not ready after %s", e.InstanceID, e.Timeout)
+	var details []string
+	if e.Instance != nil {
+		details = append(details, "last status: "+e.Instance.Status)
+	}
+	if e.LastErr != nil {
+		details = append(details, "last error: "+e.LastErr.Error())
+	}
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+	return msg
+}
+
+func (e *WaitTimeoutError) Unwrap() error { return e.LastErr }
+
+// isFatalWaitError reports whether a poll error means the instance will
+// never become ready, so waiting longer is pointless.
+func isFatalWaitError(err error) bool {
+	return errors.Is(err, ErrUnauthorized) ||
+		errors.Is(err, ErrForbidden) ||
+		errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrQuotaExceeded)
+}
+
+// WaitForReady waits for an instance to be ready
+func (c *Client) WaitForReady(ctx context.Context, instanceID string, timeout time.Duration) (*Instance, error) {
+	return c.WaitForReadyWithOptions(ctx, instanceID, WaitOptions{Timeout: timeout})
+}
+
+// WaitForReadyWithOptions polls an instance with jittered backoff until it
+// is running. Network errors, 429s and 5xxs are retried; auth, not-found
+// and quota errors, or a stopped/errored instance, end the wait at once.
+// On timeout it returns the last instance seen with a *WaitTimeoutError.
+func (c *Client) WaitForReadyWithOptions(ctx context.Context, instanceID string, opts WaitOptions) (last *Instance, err error) {
+	defer func() { c.observeError(instanceID, "wait", err) }()
+
+	start := time.Now()
+	deadline := start.Add(opts.Timeout)
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		instance, err := c.GetInstance(ctx, instanceID)
+		phase := WaitPhasePolling
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			if isFatalWaitError(err) {
+				return last, err
+			}
+			lastErr = err
+			phase = WaitPhaseRetrying
+		case instance.Status == "running":
+			return instance, nil
+		case instance.Status == "stopped" || instance.Status == "error":
+			return instance, fmt.Errorf("instance failed with status: %s", instance.Status)
+		default:
+			last = instance
+			lastErr = nil
+		}
+
+		if opts.OnProgress != nil {
+			status := ""
+			if last != nil {
+				status = last.Status
+			}
+			opts.OnProgress(WaitProgress{Phase: phase, Elapsed: time.Since(start), Status: status, LastErr: err})
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return last, &WaitTimeoutError{InstanceID: instanceID, Timeout: opts.Timeout, Instance: last, LastErr: lastErr}
+		}
+		// Land the final poll on the deadline rather than overshooting it.
+		delay := readyPollConfig.backoff(attempt)
+		if delay > remaining {
+			delay = remaining
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return last, err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastReadyPolls(t *testing.T) {
+	t.Helper()
+	saved := readyPollConfig
+	readyPollConfig = retryConfig{baseDelay: time.Millisecond, maxDelay: 5 * time.Millisecond}
+	t.Cleanup(func() { readyPollConfig = saved })
+}
+
+func TestWaitForReadyRetriesTransientErrors(t *testing.T) {
+	fastReadyPolls(t)
+	var polls atomic.Int32
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch polls.Add(1) {
+		case 1:
+			fmt.Fprint(w, `{"id":"inst-1","status":"pending"}`)
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `{"id":"inst-1","status":"running"}`)
+		}
+	}))
+
+	var progress []WaitProgress
+	instance, err := client.WaitForReadyWithOptions(context.Background(), "inst-1", WaitOptions{
+		Timeout:    5 * time.Second,
+		OnProgress: func(p WaitProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("WaitForReady failed: %v", err)
+	}
+	if instance.Status != "running" {
+		t.Errorf("unexpected instance: %+v", instance)
+	}
+	if len(progress) != 2 || progress[0].Phase != WaitPhasePolling || progress[0].Status != "pending" {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+	if progress[1].Phase != WaitPhaseRetrying || !errors.Is(progress[1].LastErr, ErrServer) || progress[1].Status != "pending" {
+		t.Errorf("unexpected retry progress: %+v", progress[1])
+	}
+}
+
+func TestWaitForReadyStopsOnFatalError(t *testing.T) {
+	fastReadyPolls(t)
+	var polls atomic.Int32
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	_, err := client.WaitForReady(context.Background(), "inst-1", 5*time.Second)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if n := polls.Load(); n != 1 {
+		t.Errorf("expected a single poll, got %d", n)
+	}
+}
+
+func TestWaitForReadyTimeoutReportsLastState(t *testing.T) {
+	fastReadyPolls(t)
+	var polls atomic.Int32
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) == 1 {
+			fmt.Fprint(w, `{"id":"inst-1","status":"booting"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("bad poll"))
+	}))
+
+	instance, err := client.WaitForReady(context.Background(), "inst-1", 50*time.Millisecond)
+	var timeoutErr *WaitTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected WaitTimeoutError, got %v", err)
+	}
+	if instance == nil || instance.Status != "booting" || timeoutErr.Instance != instance {
+		t.Errorf("expected last instance state, got %+v", instance)
+	}
+	if timeoutErr.LastErr == nil || !strings.Contains(err.Error(), "last status: booting") || !strings.Contains(err.Error(), "bad poll") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestWaitForReadyFailedStatus(t *testing.T) {
+	fastReadyPolls(t)
+	client := newBatchTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"inst-1","status":"error"}`)
+	}))
+
+	instance, err := client.WaitForReady(context.Background(), "inst-1", 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "status: error") {
+		t.Fatalf("expected failed status error, got %v", err)
+	}
+	if instance == nil || instance.Status != "error" {
+		t.Errorf("expected final instance state, got %+v", instance)
+	}
+}
@@ -0,0 +1,162 @@
+// Package pve is a small Proxmox VE API client shared by devsh and the PVE
+// helper proxies: envelope decoding, error classification, UPID handling,
+// task polling and typed endpoints for the LXC calls cmux relies on.
+package pve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Auth adds credentials to an outgoing API request.
+type Auth interface {
+	Apply(h http.Header)
+}
+
+// AuthFunc adapts a function to Auth.
+type AuthFunc func(h http.Header)
+
+// Apply calls f(h).
+func (f AuthFunc) Apply(h http.Header) { f(h) }
+
+// APIToken authenticates with a PVE API token ("user@realm!id=secret").
+func APIToken(token string) Auth {
+	return AuthFunc(func(h http.Header) {
+		h.Set("Authorization", "PVEAPIToken="+token)
+	})
+}
+
+// forwardedAuthHeaders are the request headers PVE accepts credentials in.
+var forwardedAuthHeaders = []string{"Authorization", "Cookie", "CSRFPreventionToken", "Ticket"}
+
+// ForwardedAuth reuses a caller's credentials, for proxies that make
+// follow-up calls on the caller's behalf.
+func ForwardedAuth(src http.Header) Auth {
+	saved := http.Header{}
+	for _, key := range forwardedAuthHeaders {
+		for _, v := range src.Values(key) {
+			saved.Add(key, v)
+		}
+	}
+	return AuthFunc(func(h http.Header) {
+		for key, values := range saved {
+			h[key] = append([]string(nil), values...)
+		}
+	})
+}
+
+// Config configures a Client.
+type Config struct {
+	BaseURL    string       // e.g. https://pve.example.com:8006; may include a path prefix
+	Auth       Auth         // Optional
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+}
+
+// Client calls the PVE API.
+type Client struct {
+	baseURL string
+	auth    Auth
+	http    *http.Client
+}
+
+// NewClient creates a client for cfg.BaseURL.
+func NewClient(cfg Config) (*Client, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		return nil, errors.New("PVE apiUrl is required")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: baseURL, auth: cfg.Auth, http: httpClient}, nil
+}
+
+// BaseURL returns the API base URL without a trailing slash.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// WithAuth returns a copy of c that authenticates with auth.
+func (c *Client) WithAuth(auth Auth) *Client {
+	clone := *c
+	clone.auth = auth
+	return &clone
+}
+
+// Do calls path (starting with /api2/json) and returns the envelope's data.
+// params are sent as the query string for GET and DELETE and as a form body
+// otherwise. Non-2xx responses are returned as *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, params url.Values) (json.RawMessage, error) {
+	reqURL := c.baseURL + path
+
+	var body io.Reader
+	headers := http.Header{}
+	if len(params) > 0 {
+		encoded := params.Encode()
+		if method == http.MethodGet || method == http.MethodDelete {
+			if strings.Contains(reqURL, "?") {
+				reqURL += "&" + encoded
+			} else {
+				reqURL += "?" + encoded
+			}
+		} else {
+			headers.Set("Content-Type", "application/x-www-form-urlencoded")
+			body = strings.NewReader(encoded)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	if c.auth != nil {
+		c.auth.Apply(req.Header)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, ParseAPIError(resp.StatusCode, raw)
+	}
+	return DecodeEnvelope(raw)
+}
+
+// Request calls Do and decodes the data into T.
+func Request[T any](ctx context.Context, c *Client, method, path string, params url.Values) (T, error) {
+	var out T
+	data, err := c.Do(ctx, method, path, params)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("failed to decode PVE data: %w", err)
+	}
+	return out, nil
+}
+
+// DecodeEnvelope returns the data field of a PVE {"data": ...} response.
+func DecodeEnvelope(raw []byte) (json.RawMessage, error) {
+	var env struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode PVE response: %w", err)
+	}
+	return env.Data, nil
+}
@@ -0,0 +1,119 @@
+package pve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(Config{BaseURL: server.URL + "/", Auth: APIToken("root@pam!t=secret"), HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestDoSendsParamsAndAuth(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "PVEAPIToken=root@pam!t=secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.RawQuery != "service=pve-container%40200" {
+				t.Errorf("query = %q", r.URL.RawQuery)
+			}
+		case http.MethodPost:
+			if r.PostForm.Get("newid") != "201" || r.URL.RawQuery != "" {
+				t.Errorf("form = %v, query = %q", r.PostForm, r.URL.RawQuery)
+			}
+		}
+		_, _ = w.Write([]byte(`{"data":{"ok":true}}`))
+	})
+
+	for _, tc := range []struct {
+		method string
+		params url.Values
+	}{
+		{http.MethodGet, url.Values{"service": []string{"pve-container@200"}}},
+		{http.MethodPost, url.Values{"newid": []string{"201"}}},
+	} {
+		data, err := client.Do(context.Background(), tc.method, "/api2/json/nodes/pve/syslog", tc.params)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.method, err)
+		}
+		if string(data) != `{"ok":true}` {
+			t.Errorf("%s data = %s", tc.method, data)
+		}
+	}
+}
+
+func TestDoReturnsClassifiedAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"data":null,"message":"CT 200 already exists on node 'pve'\n"}`))
+	})
+
+	_, err := client.Do(context.Background(), http.MethodPost, "/api2/json/nodes/pve/lxc/9000/clone", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+		t.Fatalf("expected *APIError with status 500, got %v", err)
+	}
+	if !errors.Is(err, ErrVMIDConflict) {
+		t.Errorf("expected ErrVMIDConflict, got %v", err)
+	}
+}
+
+func TestForwardedAuthCopiesCredentialHeaders(t *testing.T) {
+	src := http.Header{}
+	src.Set("Cookie", "PVEAuthCookie=abc")
+	src.Set("CSRFPreventionToken", "tok")
+	src.Set("X-Other", "ignored")
+
+	dst := http.Header{}
+	ForwardedAuth(src).Apply(dst)
+	src.Set("Cookie", "changed")
+
+	if dst.Get("Cookie") != "PVEAuthCookie=abc" || dst.Get("CSRFPreventionToken") != "tok" {
+		t.Errorf("unexpected forwarded headers: %v", dst)
+	}
+	if dst.Get("X-Other") != "" {
+		t.Errorf("non-credential header forwarded: %v", dst)
+	}
+}
+
+func TestWithAuthDoesNotModifyOriginal(t *testing.T) {
+	var seen []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"data":null}`))
+	})
+
+	other := client.WithAuth(APIToken("other"))
+	ctx := context.Background()
+	if _, err := other.Do(ctx, http.MethodGet, "/api2/json/version", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(ctx, http.MethodGet, "/api2/json/version", nil); err != nil {
+		t.Fatal(err)
+	}
+	if seen[0] != "PVEAPIToken=other" || seen[1] != "PVEAPIToken=root@pam!t=secret" {
+		t.Errorf("Authorization headers = %q", seen)
+	}
+}
+
+func TestNewClientRequiresBaseURL(t *testing.T) {
+	if _, err := NewClient(Config{BaseURL: "  "}); err == nil {
+		t.Fatal("expected error for empty base URL")
+	}
+}
@@ -0,0 +1,111 @@
+package pve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// guestMissingPattern matches PVE's messages for a container or VM that does
+// not exist, e.g. "Configuration file 'nodes/pve/lxc/201.conf' does not
+// exist" or "CT 201 does not exist". Other "does not exist" / "not found"
+// messages (a storage, bridge, user or file) do not mean the guest is gone,
+// and callers such as container deletion treat ErrNotFound as success.
+var guestMissingPattern = regexp.MustCompile(`(?i)configuration file '[^']*/(?:lxc|qemu-server)/\d+\.conf' does not exist|\b(?:ct|vm|vmid)\s+'?\d+'?\s+(?:does not exist|not found)|unable to find configuration file for (?:ct|vm) \d+`)
+
+// Sentinel errors for PVE failure classes. Use errors.Is against errors
+// returned by the client instead of matching on message text.
+var (
+	ErrVMIDConflict = errors.New("pve: vmid already exists")
+	ErrNotFound     = errors.New("pve: resource does not exist")
+	ErrLocked       = errors.New("pve: resource is locked")
+)
+
+// APIError is a non-2xx response from the PVE API.
+type APIError struct {
+	StatusCode int
+	Message    string
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("PVE API error %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// ErrTaskFailed is returned when a PVE task finishes with a non-OK exit
+// status. The exit status is classified the same way as API errors, so
+// errors.Is(err, ErrLocked) works for tasks that failed on a config lock.
+type ErrTaskFailed struct {
+	UPID       string
+	ExitStatus string
+	kind       error
+}
+
+func (e *ErrTaskFailed) Error() string {
+	return fmt.Sprintf("task failed: %s", e.ExitStatus)
+}
+
+func (e *ErrTaskFailed) Unwrap() error {
+	return e.kind
+}
+
+// NewTaskFailedError classifies a task's non-OK exit status.
+func NewTaskFailedError(upid, exitStatus string) *ErrTaskFailed {
+	return &ErrTaskFailed{
+		UPID:       upid,
+		ExitStatus: exitStatus,
+		kind:       ClassifyMessage(exitStatus),
+	}
+}
+
+// ParseAPIError builds an APIError from a failed PVE response. PVE reports
+// most failures as HTTP 500 with the reason in the body's "message" field (or
+// only in the raw body), so classification is done on the text.
+func ParseAPIError(statusCode int, raw []byte) *APIError {
+	msg := strings.TrimSpace(string(raw))
+	if msg == "" {
+		msg = "(empty response)"
+	}
+
+	text := msg
+	var body struct {
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(raw, &body); err == nil {
+		parts := []string{body.Message}
+		for _, v := range body.Errors {
+			parts = append(parts, v)
+		}
+		text = strings.Join(parts, " ")
+	}
+
+	// The status code alone is not classified: PVE also answers 404 for an
+	// unknown node or API path, which must not read as a deleted guest.
+	return &APIError{StatusCode: statusCode, Message: msg, kind: ClassifyMessage(text)}
+}
+
+// ClassifyMessage maps PVE error text to one of the sentinel errors, or nil
+// when the failure does not belong to a known class. ErrNotFound is only
+// reported for a missing container or VM.
+func ClassifyMessage(text string) error {
+	lower := strings.ToLower(text)
+	switch {
+	case strings.Contains(lower, "already exists"):
+		return ErrVMIDConflict
+	case strings.Contains(lower, "can't lock file"),
+		strings.Contains(lower, "is locked"),
+		strings.Contains(lower, "lock timeout"):
+		return ErrLocked
+	case guestMissingPattern.MatchString(text):
+		return ErrNotFound
+	default:
+		return nil
+	}
+}
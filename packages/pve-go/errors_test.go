@@ -0,0 +1,128 @@
+package pve
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{
+			name:   "vmid conflict",
+			status: 500,
+			body:   `{"data":null,"message":"CT 200 already exists on node 'pve'\n"}`,
+			want:   ErrVMIDConflict,
+		},
+		{
+			name:   "missing config",
+			status: 500,
+			body:   `{"data":null,"message":"Configuration file 'nodes/pve/lxc/201.conf' does not exist\n"}`,
+			want:   ErrNotFound,
+		},
+		{
+			name:   "missing ct",
+			status: 500,
+			body:   `{"data":null,"message":"CT 201 does not exist\n"}`,
+			want:   ErrNotFound,
+		},
+		{
+			name:   "missing storage",
+			status: 500,
+			body:   `{"data":null,"message":"storage 'local-zfs' does not exist\n"}`,
+			want:   nil,
+		},
+		{
+			name:   "missing bridge",
+			status: 500,
+			body:   `{"data":null,"message":"bridge 'vmbr1' not found\n"}`,
+			want:   nil,
+		},
+		{
+			name:   "missing file",
+			status: 500,
+			body:   `{"data":null,"message":"unable to open file '/var/lib/vz/template/cache/x.tar.zst' - No such file or directory\n"}`,
+			want:   nil,
+		},
+		{
+			name:   "template lock",
+			status: 500,
+			body:   `{"data":null,"message":"can't lock file '/run/lock/lxc/pve-config-9027.conf' - got timeout\n"}`,
+			want:   ErrLocked,
+		},
+		{
+			name:   "field errors",
+			status: 400,
+			body:   `{"data":null,"errors":{"newid":"vmid 202 already exists"}}`,
+			want:   ErrVMIDConflict,
+		},
+		{
+			name:   "unknown path 404",
+			status: 404,
+			body:   "Method 'GET /nodes/pve/lxc/9' not implemented",
+			want:   nil,
+		},
+		{
+			name:   "unknown node 404",
+			status: 404,
+			body:   `{"data":null,"message":"no such node 'pve2'\n"}`,
+			want:   nil,
+		},
+		{
+			name:   "missing guest 404",
+			status: 404,
+			body:   `{"data":null,"message":"CT 201 does not exist\n"}`,
+			want:   ErrNotFound,
+		},
+		{
+			name:   "unclassified",
+			status: 500,
+			body:   `{"data":null,"message":"permission denied"}`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseAPIError(tt.status, []byte(tt.body))
+			if err.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", err.StatusCode, tt.status)
+			}
+			for _, sentinel := range []error{ErrVMIDConflict, ErrNotFound, ErrLocked} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAPIErrorEmptyBody(t *testing.T) {
+	err := ParseAPIError(502, nil)
+	if got, want := err.Error(), "PVE API error 502: (empty response)"; got != want {
+		t.Fatalf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestTaskFailedErrorUnwraps(t *testing.T) {
+	var err error = fmt.Errorf("clone: %w", NewTaskFailedError("UPID:pve:1", "can't lock file '/run/lock/lxc/pve-config-9027.conf' - got timeout"))
+
+	var taskErr *ErrTaskFailed
+	if !errors.As(err, &taskErr) {
+		t.Fatalf("errors.As(%v, *ErrTaskFailed) = false", err)
+	}
+	if taskErr.UPID != "UPID:pve:1" {
+		t.Errorf("UPID = %q", taskErr.UPID)
+	}
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("expected task failure to classify as ErrLocked")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("did not expect task failure to classify as ErrNotFound")
+	}
+}
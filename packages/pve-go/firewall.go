@@ -0,0 +1,61 @@
+package pve
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// FirewallRule is a rule in a guest's firewall. Pos is assigned by PVE and
+// ignored when adding a rule.
+type FirewallRule struct {
+	Pos     int    `json:"pos"`
+	Type    string `json:"type,omitempty"`   // "in", "out" or "group"
+	Action  string `json:"action,omitempty"` // "ACCEPT", "DROP", "REJECT" or a group name
+	Proto   string `json:"proto,omitempty"`
+	DPort   string `json:"dport,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Enable  int    `json:"enable,omitempty"`
+}
+
+// LXCFirewallRules lists a container's firewall rules.
+func (c *Client) LXCFirewallRules(ctx context.Context, node string, vmid int) ([]FirewallRule, error) {
+	return Request[[]FirewallRule](ctx, c, http.MethodGet, lxcPath(node, vmid, "/firewall/rules"), nil)
+}
+
+// AddLXCFirewallRule appends a rule to a container's firewall.
+func (c *Client) AddLXCFirewallRule(ctx context.Context, node string, vmid int, rule FirewallRule) error {
+	params := url.Values{
+		"type":   []string{rule.Type},
+		"action": []string{rule.Action},
+		"enable": []string{strconv.Itoa(rule.Enable)},
+	}
+	for key, value := range map[string]string{
+		"proto":   rule.Proto,
+		"dport":   rule.DPort,
+		"source":  rule.Source,
+		"comment": rule.Comment,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	_, err := c.Do(ctx, http.MethodPost, lxcPath(node, vmid, "/firewall/rules"), params)
+	return err
+}
+
+// DeleteLXCFirewallRule removes the rule at pos. Later rules move up one
+// position.
+func (c *Client) DeleteLXCFirewallRule(ctx context.Context, node string, vmid int, pos int) error {
+	_, err := c.Do(ctx, http.MethodDelete, lxcPath(node, vmid, "/firewall/rules/"+strconv.Itoa(pos)), nil)
+	return err
+}
+
+// UpdateLXCFirewallOptions sets a container's firewall options, such as
+// enable and policy_in.
+func (c *Client) UpdateLXCFirewallOptions(ctx context.Context, node string, vmid int, values url.Values) error {
+	_, err := c.Do(ctx, http.MethodPut, lxcPath(node, vmid, "/firewall/options"), values)
+	return err
+}
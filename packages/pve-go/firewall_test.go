@@ -0,0 +1,53 @@
+package pve
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestLXCFirewall(t *testing.T) {
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+r.PostForm.Encode())
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"data":[{"pos":0,"type":"in","action":"ACCEPT","proto":"tcp","dport":"22","enable":1}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":null}`))
+	})
+
+	ctx := context.Background()
+	rules, err := client.LXCFirewallRules(ctx, "pve", 204)
+	if err != nil {
+		t.Fatalf("LXCFirewallRules: %v", err)
+	}
+	if want := (FirewallRule{Type: "in", Action: "ACCEPT", Proto: "tcp", DPort: "22", Enable: 1}); len(rules) != 1 || rules[0] != want {
+		t.Errorf("rules = %+v", rules)
+	}
+	if err := client.DeleteLXCFirewallRule(ctx, "pve", 204, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddLXCFirewallRule(ctx, "pve", 204, FirewallRule{Type: "in", Action: "ACCEPT", Proto: "tcp", DPort: "39375", Enable: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateLXCFirewallOptions(ctx, "pve", 204, url.Values{"enable": []string{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /api2/json/nodes/pve/lxc/204/firewall/rules ",
+		"DELETE /api2/json/nodes/pve/lxc/204/firewall/rules/3 ",
+		"POST /api2/json/nodes/pve/lxc/204/firewall/rules action=ACCEPT&dport=39375&enable=1&proto=tcp&type=in",
+		"PUT /api2/json/nodes/pve/lxc/204/firewall/options enable=1",
+	}
+	for i := range want {
+		if i >= len(calls) || calls[i] != want[i] {
+			t.Fatalf("calls = %q, want %q", calls, want)
+		}
+	}
+}
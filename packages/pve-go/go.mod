@@ -0,0 +1,3 @@
+module github.com/karlorz/pve-go
+
+go 1.22
@@ -0,0 +1,235 @@
+package pve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node is an entry in the cluster node list.
+type Node struct {
+	Node   string `json:"node"`
+	Status string `json:"status,omitempty"`
+}
+
+// Nodes lists the cluster's nodes.
+func (c *Client) Nodes(ctx context.Context) ([]Node, error) {
+	return Request[[]Node](ctx, c, http.MethodGet, "/api2/json/nodes", nil)
+}
+
+// DNSConfig is a node's DNS settings.
+type DNSConfig struct {
+	Search string `json:"search,omitempty"`
+}
+
+// DNS returns a node's DNS settings.
+func (c *Client) DNS(ctx context.Context, node string) (DNSConfig, error) {
+	return Request[DNSConfig](ctx, c, http.MethodGet, nodePath(node, "/dns"), nil)
+}
+
+// NextID asks the cluster for a free VMID.
+func (c *Client) NextID(ctx context.Context) (int, error) {
+	data, err := c.Do(ctx, http.MethodGet, "/api2/json/cluster/nextid", nil)
+	if err != nil {
+		return 0, err
+	}
+	// PVE returns the id as a JSON string.
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil {
+			return 0, fmt.Errorf("failed to decode next VMID: %w", err)
+		}
+		return n, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Container is an LXC container as reported by the node list and status
+// endpoints.
+type Container struct {
+	VMID     int     `json:"vmid"`
+	Name     string  `json:"name,omitempty"`
+	Status   string  `json:"status"`
+	Template int     `json:"template,omitempty"`
+	Uptime   int64   `json:"uptime,omitempty"` // Seconds
+	CPU      float64 `json:"cpu,omitempty"`    // Fraction of the allotted CPUs in use
+	CPUs     float64 `json:"cpus,omitempty"`
+	Mem      uint64  `json:"mem,omitempty"`
+	MaxMem   uint64  `json:"maxmem,omitempty"`
+}
+
+// LXCList lists a node's containers, including their status and usage.
+func (c *Client) LXCList(ctx context.Context, node string) ([]Container, error) {
+	return Request[[]Container](ctx, c, http.MethodGet, nodePath(node, "/lxc"), nil)
+}
+
+// LXCStatus returns a container's current status.
+func (c *Client) LXCStatus(ctx context.Context, node string, vmid int) (Container, error) {
+	return Request[Container](ctx, c, http.MethodGet, lxcPath(node, vmid, "/status/current"), nil)
+}
+
+// ContainerConfig is the subset of an LXC config cmux reads.
+type ContainerConfig struct {
+	Hostname string `json:"hostname,omitempty"`
+	Net0     string `json:"net0,omitempty"`
+}
+
+// LXCConfig returns a container's config.
+func (c *Client) LXCConfig(ctx context.Context, node string, vmid int) (ContainerConfig, error) {
+	return Request[ContainerConfig](ctx, c, http.MethodGet, lxcPath(node, vmid, "/config"), nil)
+}
+
+// UpdateLXCConfig sets container config options.
+func (c *Client) UpdateLXCConfig(ctx context.Context, node string, vmid int, values url.Values) error {
+	_, err := c.Do(ctx, http.MethodPut, lxcPath(node, vmid, "/config"), values)
+	return err
+}
+
+// CloneOptions configures CloneLXC.
+type CloneOptions struct {
+	NewID    int
+	Hostname string
+	Full     bool   // Full copy instead of a linked clone
+	Storage  string // Target storage for a full clone; empty keeps the source's
+}
+
+// CloneLXC starts a clone of a container or template and returns the task
+// UPID.
+func (c *Client) CloneLXC(ctx context.Context, node string, vmid int, opts CloneOptions) (string, error) {
+	if opts.NewID <= 0 {
+		return "", errors.New("clone requires a new VMID")
+	}
+	if opts.Storage != "" && !opts.Full {
+		return "", errors.New("clone target storage requires a full clone")
+	}
+	params := url.Values{"newid": []string{strconv.Itoa(opts.NewID)}}
+	if opts.Hostname != "" {
+		params.Set("hostname", opts.Hostname)
+	}
+	if opts.Full {
+		params.Set("full", "1")
+	} else {
+		params.Set("full", "0")
+	}
+	if opts.Storage != "" {
+		params.Set("storage", opts.Storage)
+	}
+	return c.lxcTask(ctx, http.MethodPost, lxcPath(node, vmid, "/clone"), params)
+}
+
+// StartLXC starts a container and returns the task UPID.
+func (c *Client) StartLXC(ctx context.Context, node string, vmid int) (string, error) {
+	return c.lxcTask(ctx, http.MethodPost, lxcPath(node, vmid, "/status/start"), nil)
+}
+
+// StopLXC stops a container immediately, like pulling the plug, and returns
+// the task UPID.
+func (c *Client) StopLXC(ctx context.Context, node string, vmid int) (string, error) {
+	return c.lxcTask(ctx, http.MethodPost, lxcPath(node, vmid, "/status/stop"), nil)
+}
+
+// ShutdownLXC asks a container to shut down cleanly, giving it timeout
+// before PVE fails the task, and returns the task UPID.
+func (c *Client) ShutdownLXC(ctx context.Context, node string, vmid int, timeout time.Duration) (string, error) {
+	params := url.Values{"timeout": []string{strconv.Itoa(int(timeout.Seconds()))}}
+	return c.lxcTask(ctx, http.MethodPost, lxcPath(node, vmid, "/status/shutdown"), params)
+}
+
+// DeleteOptions configures DeleteLXC.
+type DeleteOptions struct {
+	Force bool // Stop the container first if it is running
+	Purge bool // Also remove it from backup jobs, replication and ACLs
+}
+
+// DeleteLXC destroys a container and returns the task UPID.
+func (c *Client) DeleteLXC(ctx context.Context, node string, vmid int, opts DeleteOptions) (string, error) {
+	params := url.Values{}
+	if opts.Force {
+		params.Set("force", "1")
+	}
+	if opts.Purge {
+		params.Set("purge", "1")
+	}
+	return c.lxcTask(ctx, http.MethodDelete, lxcPath(node, vmid, ""), params)
+}
+
+// ConvertLXCToTemplate turns a stopped container into a template.
+func (c *Client) ConvertLXCToTemplate(ctx context.Context, node string, vmid int) error {
+	_, err := c.Do(ctx, http.MethodPost, lxcPath(node, vmid, "/template"), nil)
+	return err
+}
+
+// VNCTicket is a one-shot vncproxy ticket for a container's console.
+type VNCTicket struct {
+	Port   int    // Port on the node, passed to vncwebsocket
+	Ticket string // Also the VNC password
+	User   string
+	UPID   string
+}
+
+// LXCVNCProxy starts a websocket-capable VNC server for a container's
+// console. It accepts a single vncwebsocket connection within about 10
+// seconds, so request a new ticket per connection.
+func (c *Client) LXCVNCProxy(ctx context.Context, node string, vmid int) (VNCTicket, error) {
+	data, err := c.Do(ctx, http.MethodPost, lxcPath(node, vmid, "/vncproxy"), url.Values{"websocket": []string{"1"}})
+	if err != nil {
+		return VNCTicket{}, err
+	}
+	var resp struct {
+		Port   json.RawMessage `json:"port"` // A string on some PVE versions
+		Ticket string          `json:"ticket"`
+		User   string          `json:"user"`
+		UPID   string          `json:"upid"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return VNCTicket{}, fmt.Errorf("failed to decode PVE data: %w", err)
+	}
+	port, err := strconv.Atoi(strings.Trim(string(resp.Port), `"`))
+	if err != nil || resp.Ticket == "" {
+		return VNCTicket{}, fmt.Errorf("unexpected vncproxy response: %s", data)
+	}
+	return VNCTicket{Port: port, Ticket: resp.Ticket, User: resp.User, UPID: resp.UPID}, nil
+}
+
+// LXCVNCWebsocketURL returns the ws:// or wss:// vncwebsocket URL for a
+// ticket from LXCVNCProxy. The stream carries raw RFB.
+func (c *Client) LXCVNCWebsocketURL(node string, vmid int, ticket VNCTicket) (string, error) {
+	u, err := url.Parse(c.baseURL + lxcPath(node, vmid, "/vncwebsocket"))
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	u.RawQuery = url.Values{
+		"port":      []string{strconv.Itoa(ticket.Port)},
+		"vncticket": []string{ticket.Ticket},
+	}.Encode()
+	return u.String(), nil
+}
+
+func (c *Client) lxcTask(ctx context.Context, method, path string, params url.Values) (string, error) {
+	data, err := c.Do(ctx, method, path, params)
+	if err != nil {
+		return "", err
+	}
+	return ExtractUPID(data), nil
+}
+
+func nodePath(node, suffix string) string {
+	return "/api2/json/nodes/" + url.PathEscape(strings.TrimSpace(node)) + suffix
+}
+
+func lxcPath(node string, vmid int, suffix string) string {
+	return nodePath(node, fmt.Sprintf("/lxc/%d%s", vmid, suffix))
+}
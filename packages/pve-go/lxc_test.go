@@ -0,0 +1,186 @@
+package pve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextID(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/cluster/nextid" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"data":"204"}`))
+	})
+
+	id, err := client.NextID(context.Background())
+	if err != nil || id != 204 {
+		t.Fatalf("NextID() = %d, %v; want 204", id, err)
+	}
+}
+
+func TestCloneLXC(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api2/json/nodes/pve/lxc/9027/clone" {
+			t.Errorf("unexpected call %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.PostForm.Encode(); got != "full=0&hostname=cmux-204&newid=204" {
+			t.Errorf("form = %q", got)
+		}
+		_, _ = w.Write([]byte(`{"data":"UPID:pve:0001:vzclone:9027:root@pam:"}`))
+	})
+
+	upid, err := client.CloneLXC(context.Background(), "pve", 9027, CloneOptions{NewID: 204, Hostname: "cmux-204"})
+	if err != nil {
+		t.Fatalf("CloneLXC: %v", err)
+	}
+	if upid != "UPID:pve:0001:vzclone:9027:root@pam:" {
+		t.Errorf("upid = %q", upid)
+	}
+}
+
+func TestCloneLXCFullToStorage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.PostForm.Encode(); got != "full=1&newid=204&storage=nvme-zfs" {
+			t.Errorf("form = %q", got)
+		}
+		_, _ = w.Write([]byte(`{"data":"UPID:pve:0001:vzclone:9027:root@pam:"}`))
+	})
+
+	if _, err := client.CloneLXC(context.Background(), "pve", 9027, CloneOptions{NewID: 204, Full: true, Storage: "nvme-zfs"}); err != nil {
+		t.Fatalf("CloneLXC: %v", err)
+	}
+}
+
+func TestCloneLXCValidatesOptions(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected API call")
+	})
+	if _, err := client.CloneLXC(context.Background(), "pve", 9027, CloneOptions{}); err == nil {
+		t.Fatal("expected error without NewID")
+	}
+	if _, err := client.CloneLXC(context.Background(), "pve", 9027, CloneOptions{NewID: 204, Storage: "nvme-zfs"}); err == nil {
+		t.Fatal("expected error for target storage on a linked clone")
+	}
+}
+
+func TestLXCListDecodesUsage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[{"vmid":200,"name":"cmux-200","status":"running","uptime":60,"cpu":0.5,"cpus":2,"mem":1024,"maxmem":4096}]}`))
+	})
+
+	containers, err := client.LXCList(context.Background(), "pve")
+	if err != nil {
+		t.Fatalf("LXCList: %v", err)
+	}
+	want := Container{VMID: 200, Name: "cmux-200", Status: "running", Uptime: 60, CPU: 0.5, CPUs: 2, Mem: 1024, MaxMem: 4096}
+	if len(containers) != 1 || containers[0] != want {
+		t.Errorf("LXCList() = %+v", containers)
+	}
+}
+
+func TestLXCPowerTasks(t *testing.T) {
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			calls[len(calls)-1] += " " + r.PostForm.Encode()
+		}
+		_, _ = w.Write([]byte(`{"data":"UPID:pve:0001:vzstart:204:root@pam:"}`))
+	})
+
+	ctx := context.Background()
+	for name, call := range map[string]func() (string, error){
+		"start":    func() (string, error) { return client.StartLXC(ctx, "pve", 204) },
+		"stop":     func() (string, error) { return client.StopLXC(ctx, "pve", 204) },
+		"shutdown": func() (string, error) { return client.ShutdownLXC(ctx, "pve", 204, time.Minute) },
+		"delete": func() (string, error) {
+			return client.DeleteLXC(ctx, "pve", 204, DeleteOptions{Force: true, Purge: true})
+		},
+	} {
+		if upid, err := call(); err != nil || upid != "UPID:pve:0001:vzstart:204:root@pam:" {
+			t.Errorf("%s = %q, %v", name, upid, err)
+		}
+	}
+
+	sort.Strings(calls)
+	want := []string{
+		"DELETE /api2/json/nodes/pve/lxc/204?force=1&purge=1",
+		"POST /api2/json/nodes/pve/lxc/204/status/shutdown timeout=60",
+		"POST /api2/json/nodes/pve/lxc/204/status/start ",
+		"POST /api2/json/nodes/pve/lxc/204/status/stop ",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestLXCVNCProxy(t *testing.T) {
+	for _, port := range []string{`5900`, `"5900"`} {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.URL.EscapedPath() != "/api2/json/nodes/pve%2F1/lxc/204/vncproxy" {
+				t.Errorf("unexpected call %s %s", r.Method, r.URL.EscapedPath())
+			}
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			if r.PostForm.Get("websocket") != "1" {
+				t.Errorf("form = %v", r.PostForm)
+			}
+			_, _ = w.Write([]byte(`{"data":{"port":` + port + `,"ticket":"PVEVNC:abc","user":"root@pam","upid":"UPID:pve:0001:vncproxy:204:root@pam:"}}`))
+		})
+
+		ticket, err := client.LXCVNCProxy(context.Background(), "pve/1", 204)
+		want := VNCTicket{Port: 5900, Ticket: "PVEVNC:abc", User: "root@pam", UPID: "UPID:pve:0001:vncproxy:204:root@pam:"}
+		if err != nil || ticket != want {
+			t.Errorf("port %s: LXCVNCProxy() = %+v, %v; want %+v", port, ticket, err, want)
+		}
+	}
+}
+
+func TestLXCVNCProxyRejectsMissingTicket(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"port":5900}}`))
+	})
+
+	if _, err := client.LXCVNCProxy(context.Background(), "pve", 204); err == nil {
+		t.Fatal("LXCVNCProxy() succeeded without a ticket")
+	}
+}
+
+func TestLXCVNCWebsocketURL(t *testing.T) {
+	client, err := NewClient(Config{BaseURL: "https://pve.example.com:8006/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.LXCVNCWebsocketURL("pve/1", 204, VNCTicket{Port: 5900, Ticket: "PVEVNC:a+b"})
+	want := "wss://pve.example.com:8006/api2/json/nodes/pve%2F1/lxc/204/vncwebsocket?port=5900&vncticket=PVEVNC%3Aa%2Bb"
+	if err != nil || got != want {
+		t.Errorf("LXCVNCWebsocketURL() = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestDeleteLXCReturnsClassifiedError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"message":"Configuration file 'nodes/pve/lxc/204.conf' does not exist\n"}`))
+	})
+	if _, err := client.DeleteLXC(context.Background(), "pve", 204, DeleteOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteLXC error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPollInterval is used by WaitForTask when no interval is given.
+const DefaultPollInterval = 2 * time.Second
+
+// Task is the status of a PVE task.
+type Task struct {
+	Status     string `json:"status"` // running or stopped
+	ExitStatus string `json:"exitstatus,omitempty"`
+}
+
+// Done reports whether the task has finished.
+func (t Task) Done() bool {
+	return t.Status == "stopped"
+}
+
+// NormalizeUPID trims a UPID and decodes it if it arrived URL-encoded.
+func NormalizeUPID(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return ""
+	}
+	if strings.Contains(trimmed, "%3A") {
+		if decoded, err := url.QueryUnescape(trimmed); err == nil {
+			return decoded
+		}
+	}
+	return trimmed
+}
+
+// ExtractUPID returns the task UPID from an envelope's data, which PVE sends
+// either as a bare string or as an object with a upid field.
+func ExtractUPID(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return NormalizeUPID(s)
+	}
+	var obj struct {
+		UPID string `json:"upid"`
+	}
+	if err := json.Unmarshal(data, &obj); err == nil {
+		return NormalizeUPID(obj.UPID)
+	}
+	return ""
+}
+
+// TaskStatus returns the current status of a task.
+func (c *Client) TaskStatus(ctx context.Context, node, upid string) (Task, error) {
+	path := fmt.Sprintf("/api2/json/nodes/%s/tasks/%s/status", url.PathEscape(node), url.PathEscape(NormalizeUPID(upid)))
+	return Request[Task](ctx, c, http.MethodGet, path, nil)
+}
+
+// WaitOptions tunes WaitForTask.
+type WaitOptions struct {
+	Interval    time.Duration   // Defaults to DefaultPollInterval
+	OnPollError func(err error) // Called for status polls that fail; polling continues
+}
+
+// WaitForTask polls a task until it stops or ctx is done. A task that stops
+// with a non-OK exit status returns its final status and *ErrTaskFailed.
+// Failed polls are retried, so bound the wait with ctx.
+func (c *Client) WaitForTask(ctx context.Context, node, upid string, opts WaitOptions) (Task, error) {
+	upid = NormalizeUPID(upid)
+	if upid == "" {
+		return Task{}, nil
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		task, err := c.TaskStatus(ctx, node, upid)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return Task{}, ctx.Err()
+			}
+			if opts.OnPollError != nil {
+				opts.OnPollError(err)
+			}
+		case task.Done():
+			if task.ExitStatus != "" && task.ExitStatus != "OK" {
+				return task, NewTaskFailedError(upid, task.ExitStatus)
+			}
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Task{}, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtractUPID(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`"UPID:pve:0001:clone:200:root@pam:"`, "UPID:pve:0001:clone:200:root@pam:"},
+		{`{"upid":"UPID%3Apve%3A0001"}`, "UPID:pve:0001"},
+		{`"  "`, ""},
+		{`null`, ""},
+		{`42`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := ExtractUPID([]byte(tt.data)); got != tt.want {
+			t.Errorf("ExtractUPID(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestWaitForTaskPollsUntilStopped(t *testing.T) {
+	var polls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api2/json/nodes/pve/tasks/UPID:pve:0001/status" {
+			t.Errorf("path = %q", r.URL.EscapedPath())
+		}
+		switch polls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			_, _ = w.Write([]byte(`{"data":{"status":"running"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"status":"stopped","exitstatus":"OK"}}`))
+		}
+	})
+
+	var pollErrors int
+	task, err := client.WaitForTask(context.Background(), "pve", "UPID%3Apve%3A0001", WaitOptions{
+		Interval:    time.Millisecond,
+		OnPollError: func(error) { pollErrors++ },
+	})
+	if err != nil {
+		t.Fatalf("WaitForTask: %v", err)
+	}
+	if task.ExitStatus != "OK" || polls.Load() != 3 || pollErrors != 1 {
+		t.Errorf("task=%+v polls=%d pollErrors=%d", task, polls.Load(), pollErrors)
+	}
+}
+
+func TestWaitForTaskFailedExitStatus(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"status":"stopped","exitstatus":"can't lock file '/run/lock/lxc/pve-config-9027.conf' - got timeout"}}`))
+	})
+
+	task, err := client.WaitForTask(context.Background(), "pve", "UPID:pve:1", WaitOptions{})
+	var taskErr *ErrTaskFailed
+	if !errors.As(err, &taskErr) || taskErr.UPID != "UPID:pve:1" {
+		t.Fatalf("expected *ErrTaskFailed, got %v", err)
+	}
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if !task.Done() {
+		t.Errorf("expected final task status, got %+v", task)
+	}
+}
+
+func TestWaitForTaskHonorsContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"status":"running"}}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitForTask(ctx, "pve", "UPID:pve:1", WaitOptions{Interval: time.Millisecond}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
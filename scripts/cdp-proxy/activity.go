@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// activityTracker counts proxied DevTools websocket sessions, so a drain
+// can wait for them to end. Plain HTTP requests (/json/version and friends)
+// are health probes and are not counted.
+type activityTracker struct {
+	sessions atomic.Int64
+}
+
+func (t *activityTracker) begin() func() {
+	t.sessions.Add(1)
+	return func() { t.sessions.Add(-1) }
+}
+
+// active returns the number of open websocket sessions.
+func (t *activityTracker) active() int {
+	return int(t.sessions.Load())
+}
+
+// waitIdle blocks until no websocket sessions are open or ctx is done, and
+// returns the number of sessions still open.
+func (t *activityTracker) waitIdle(ctx context.Context) int {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if t.active() == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return t.active()
+		case <-ticker.C:
+		}
+	}
+}
+
+// wrap returns a handler that records websocket sessions before handing
+// off to next.
+func (t *activityTracker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebSocketUpgrade(r) {
+			// ReverseProxy blocks in ServeHTTP until the upgraded
+			// connection is closed, so end() marks the disconnect.
+			end := t.begin()
+			defer end()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestActivityTracker_CountsWebSocketSessions(t *testing.T) {
+	tracker := &activityTracker{}
+
+	var during int
+	handler := tracker.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = tracker.active()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/devtools/page/1", nil)
+	req.Header.Set("Upgrade", "websocket")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if during != 1 {
+		t.Errorf("expected 1 active session during upgrade, got %d", during)
+	}
+	if after := tracker.active(); after != 0 {
+		t.Errorf("expected 0 active sessions after close, got %d", after)
+	}
+}
+
+func TestActivityTracker_IgnoresPlainHTTP(t *testing.T) {
+	tracker := &activityTracker{}
+
+	var during int
+	handler := tracker.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = tracker.active()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/json/version", nil))
+
+	if during != 0 {
+		t.Errorf("plain HTTP should not count as a session, got %d", during)
+	}
+}
+
+func TestActivityTracker_WaitIdle(t *testing.T) {
+	tracker := &activityTracker{}
+	end := tracker.begin()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		end()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if remaining := tracker.waitIdle(ctx); remaining != 0 {
+		t.Errorf("expected idle, %d sessions remaining", remaining)
+	}
+
+	tracker.begin()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if remaining := tracker.waitIdle(ctx); remaining != 1 {
+		t.Errorf("expected 1 session remaining at timeout, got %d", remaining)
+	}
+}
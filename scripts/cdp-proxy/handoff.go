@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// readyFDEnv names the inherited pipe a handoff child writes to once all its
+// listeners are bound. The parent only starts draining after that.
+const readyFDEnv = "CMUX_CDP_READY_FD"
+
+// handoffReadyTimeout bounds how long the parent waits for the new process.
+const handoffReadyTimeout = 30 * time.Second
+
+// listen binds addr with SO_REUSEPORT so a replacement process can bind the
+// same port while this one drains.
+func listen(ctx context.Context, addr string) (net.Listener, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	return lc.Listen(ctx, "tcp", addr)
+}
+
+// startHandoff launches the current executable (which may have been replaced
+// on disk since this process started) with the same arguments and waits for
+// it to report that its listeners are bound. It returns the child's pid.
+func startHandoff() (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("resolve executable: %w", err)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{readyW} // fd 3 in the child
+	cmd.Env = append(os.Environ(), readyFDEnv+"=3")
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return 0, fmt.Errorf("start %s: %w", exe, err)
+	}
+	readyW.Close()
+
+	// Reap the child if it exits while we are still around.
+	go func() { _ = cmd.Wait() }()
+
+	_ = readyR.SetReadDeadline(time.Now().Add(handoffReadyTimeout))
+	buf := make([]byte, 1)
+	if _, err := readyR.Read(buf); err != nil {
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("new process did not become ready: %w", err)
+	}
+	return cmd.Process.Pid, nil
+}
+
+// signalReady tells a handoff parent, if any, that this process is serving.
+func signalReady() {
+	raw := os.Getenv(readyFDEnv)
+	if raw == "" {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("warning: invalid %s=%q", readyFDEnv, raw)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "handoff-ready")
+	if f == nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		log.Printf("warning: failed to signal handoff parent: %v", err)
+	}
+}
+
+// sdNotify sends state to systemd when running under Type=notify. It is a
+// no-op outside systemd.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenSharesPort(t *testing.T) {
+	if !reusePortSupported {
+		t.Skip("SO_REUSEPORT not supported")
+	}
+	first, err := listen(context.Background(), "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer first.Close()
+
+	second, err := listen(context.Background(), first.Addr().String())
+	if err != nil {
+		t.Fatalf("second listen on %s: %v", first.Addr(), err)
+	}
+	second.Close()
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatalf("expected no-op without NOTIFY_SOCKET, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen unixgram: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	if err := sdNotify("MAINPID=42"); err != nil {
+		t.Fatalf("sdNotify: %v", err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "MAINPID=42" {
+		t.Errorf("read %q, %v", buf[:n], err)
+	}
+}
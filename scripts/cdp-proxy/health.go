@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// healthPath is served on every listener. Unlike a plain port check it
+// confirms Chrome itself answers, without opening a DevTools websocket.
+const healthPath = "/healthz"
+
+// healthChecker probes the upstream Chrome's /json/version.
+type healthChecker struct {
+	target     *url.URL
+	hostHeader string
+	timeout    time.Duration
+	client     *http.Client
+	// browserPID finds the process listening on the target port; nil or a
+	// zero result leaves the PID out of the report.
+	browserPID func(port int) int
+}
+
+type healthReport struct {
+	OK              bool   `json:"ok"`
+	Browser         string `json:"browser,omitempty"`
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+	BrowserPID      int    `json:"browserPid,omitempty"`
+	LatencyMs       int64  `json:"latencyMs"`
+	Error           string `json:"error,omitempty"`
+}
+
+func newHealthChecker(target *url.URL, hostHeader string, timeout time.Duration) *healthChecker {
+	return &healthChecker{
+		target:     target,
+		hostHeader: hostHeader,
+		timeout:    timeout,
+		client:     &http.Client{},
+		browserPID: listenerPID,
+	}
+}
+
+// wrap returns a handler that serves healthPath and passes everything else
+// to next.
+func (h *healthChecker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		report := h.probe(r.Context())
+		code := http.StatusOK
+		if !report.OK {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (h *healthChecker) probe(ctx context.Context) healthReport {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+
+	report := healthReport{}
+	version, err := h.fetchVersion(ctx)
+	report.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	report.OK = true
+	report.Browser = version.Browser
+	report.ProtocolVersion = version.ProtocolVersion
+	// The PID is only meaningful when Chrome runs on this host.
+	if ip := net.ParseIP(h.target.Hostname()); h.browserPID != nil && (h.target.Hostname() == "localhost" || ip != nil && ip.IsLoopback()) {
+		if port, err := strconv.Atoi(h.target.Port()); err == nil {
+			report.BrowserPID = h.browserPID(port)
+		}
+	}
+	return report
+}
+
+type chromeVersion struct {
+	Browser              string `json:"Browser"`
+	ProtocolVersion      string `json:"Protocol-Version"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+func (h *healthChecker) fetchVersion(ctx context.Context) (*chromeVersion, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.target.JoinPath("/json/version").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Chrome rejects /json requests whose Host is not localhost or an IP.
+	req.Host = h.hostHeader
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Chrome CDP not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Chrome CDP returned %s", resp.Status)
+	}
+
+	var version chromeVersion
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, fmt.Errorf("failed to decode CDP version: %w", err)
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("Chrome CDP reported no debugger URL")
+	}
+	return &version, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker_ReportsChrome(t *testing.T) {
+	var gotHost string
+	chrome := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		if r.URL.Path != "/json/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Browser":"Chrome/126.0.6478.126","Protocol-Version":"1.3","webSocketDebuggerUrl":"ws://localhost/devtools/browser/abc"}`))
+	}))
+	defer chrome.Close()
+
+	target, _ := url.Parse(chrome.URL)
+	h := newHealthChecker(target, "localhost:39382", time.Second)
+	h.browserPID = func(port int) int { return 4242 }
+
+	rec := httptest.NewRecorder()
+	h.wrap(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthPath, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var report healthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK || report.Browser != "Chrome/126.0.6478.126" || report.ProtocolVersion != "1.3" || report.BrowserPID != 4242 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if gotHost != "localhost:39382" {
+		t.Errorf("Host = %q, want the configured host header", gotHost)
+	}
+}
+
+func TestHealthChecker_UnreachableChrome(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h := newHealthChecker(&url.URL{Scheme: "http", Host: addr}, "localhost", time.Second)
+	rec := httptest.NewRecorder()
+	h.wrap(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthPath, nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var report healthReport
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	if report.OK || report.Error == "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestHealthChecker_PassesThroughOtherPaths(t *testing.T) {
+	h := newHealthChecker(&url.URL{Scheme: "http", Host: "127.0.0.1:1"}, "localhost", time.Second)
+	rec := httptest.NewRecorder()
+	h.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/json/version", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want request passed through", rec.Code)
+	}
+}
+
+func TestListenerPID_FindsSelf(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("listenerPID is Linux only")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if pid := listenerPID(ln.Addr().(*net.TCPAddr).Port); pid != os.Getpid() {
+		t.Errorf("listenerPID = %d, want %d", pid, os.Getpid())
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limitsConfig bounds what one client can make the proxy hold. Zero
+// disables a limit.
+type limitsConfig struct {
+	maxRequestBytes  int64         // Non-websocket request bodies
+	maxResponseBytes int64         // Non-websocket response bodies from Chrome
+	writeTimeout     time.Duration // Per write to a client, websockets included
+	idleTimeout      time.Duration // Keep-alive connections between requests
+}
+
+var errResponseTooLarge = errors.New("upstream response exceeds size limit")
+
+func parseByteSize(raw string, fallback int64) int64 {
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		log.Fatalf("invalid byte size %q", raw)
+	}
+	return value
+}
+
+// limitRequestBody rejects oversized request bodies with 413 before they
+// are forwarded, and caps bodies of unknown length as they are read.
+// Websocket upgrades carry no body and pass through.
+func limitRequestBody(limit int64, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitResponseBody is a ReverseProxy.ModifyResponse hook that fails
+// responses declaring more than limit bytes and cuts off bodies that turn
+// out larger while streaming. Switching-protocols responses are left alone.
+func limitResponseBody(limit int64) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if limit <= 0 || resp.StatusCode == http.StatusSwitchingProtocols {
+			return nil
+		}
+		if resp.ContentLength > limit {
+			return fmt.Errorf("%w (%d > %d bytes)", errResponseTooLarge, resp.ContentLength, limit)
+		}
+		resp.Body = &maxBytesReadCloser{rc: resp.Body, remaining: limit}
+		return nil
+	}
+}
+
+type maxBytesReadCloser struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+func (m *maxBytesReadCloser) Read(p []byte) (int, error) {
+	if m.remaining <= 0 {
+		// Probe for one more byte so a body of exactly limit bytes still
+		// ends cleanly.
+		var one [1]byte
+		if n, err := m.rc.Read(one[:]); n == 0 {
+			return 0, err
+		}
+		return 0, errResponseTooLarge
+	}
+	if int64(len(p)) > m.remaining {
+		p = p[:m.remaining]
+	}
+	n, err := m.rc.Read(p)
+	m.remaining -= int64(n)
+	return n, err
+}
+
+func (m *maxBytesReadCloser) Close() error {
+	return m.rc.Close()
+}
+
+// writeDeadlineListener gives every accepted connection a fresh write
+// deadline before each write, so a client that stops reading is dropped
+// after timeout instead of pinning a goroutine, buffers and a descriptor.
+// It sits below TLS and survives websocket hijacking, which
+// http.Server.WriteTimeout does not.
+type writeDeadlineListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+func (l *writeDeadlineListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &writeDeadlineConn{Conn: conn, timeout: l.timeout}, nil
+}
+
+type writeDeadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *writeDeadlineConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+// withWriteDeadline wraps ln when timeout is set.
+func withWriteDeadline(ln net.Listener, timeout time.Duration) net.Listener {
+	if timeout <= 0 {
+		return ln
+	}
+	return &writeDeadlineListener{Listener: ln, timeout: timeout}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	var got string
+	handler := limitRequestBody(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		got = string(body)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/json/new", strings.NewReader("short")))
+	if rec.Code != http.StatusOK || got != "short" {
+		t.Fatalf("small body: status %d, got %q", rec.Code, got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/json/new", strings.NewReader("far too long")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("declared oversize body: status %d", rec.Code)
+	}
+
+	// Unknown length is capped while reading.
+	req := httptest.NewRequest(http.MethodPut, "/json/new", io.NopCloser(strings.NewReader("far too long")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("streamed oversize body: status %d", rec.Code)
+	}
+}
+
+func TestLimitResponseBody(t *testing.T) {
+	limit := limitResponseBody(4)
+
+	resp := &http.Response{StatusCode: http.StatusOK, ContentLength: 10, Body: io.NopCloser(strings.NewReader("0123456789"))}
+	if err := limit(resp); !errors.Is(err, errResponseTooLarge) {
+		t.Fatalf("declared oversize response: err = %v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusOK, ContentLength: -1, Body: io.NopCloser(strings.NewReader("0123456789"))}
+	if err := limit(resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, errResponseTooLarge) {
+		t.Fatalf("streamed oversize response: err = %v", err)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusOK, ContentLength: -1, Body: io.NopCloser(strings.NewReader("0123"))}
+	if err := limit(resp); err != nil {
+		t.Fatal(err)
+	}
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "0123" {
+		t.Fatalf("body at limit: %q, %v", body, err)
+	}
+
+	upgrade := &http.Response{StatusCode: http.StatusSwitchingProtocols, Body: io.NopCloser(strings.NewReader(""))}
+	if err := limit(upgrade); err != nil {
+		t.Fatalf("websocket upgrade: %v", err)
+	}
+}
+
+func TestWriteDeadlineConnDropsStalledReader(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &writeDeadlineConn{Conn: server, timeout: 50 * time.Millisecond}
+	defer conn.Close()
+
+	// Nobody reads from client, so the write can never complete.
+	start := time.Now()
+	_, err := conn.Write([]byte("event"))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("write blocked for %s", elapsed)
+	}
+
+	// A reader that keeps up is unaffected.
+	go func() { _, _ = io.Copy(io.Discard, client) }()
+	if _, err := conn.Write([]byte("event")); err != nil {
+		t.Fatalf("write with reader: %v", err)
+	}
+}
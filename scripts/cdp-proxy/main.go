@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
@@ -10,9 +11,11 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -22,6 +25,10 @@ type proxyConfig struct {
 	targetPort    int
 	targetHost    string
 	hostHeader    string
+	drainTimeout  time.Duration
+	healthTimeout time.Duration
+	tls           tlsConfig // External listener only
+	limits        limitsConfig
 }
 
 type intSliceFlag struct {
@@ -77,6 +84,17 @@ func parsePort(raw string, fallback int) int {
 	return value
 }
 
+func parseDuration(raw string, fallback time.Duration) time.Duration {
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		log.Fatalf("invalid duration value %q", raw)
+	}
+	return value
+}
+
 func parseInternalPorts(raw string) []int {
 	if raw == "" {
 		return nil
@@ -115,6 +133,11 @@ func dedupePorts(ports []int) []int {
 func loadConfig(flagPorts []int) proxyConfig {
 	targetPort := parsePort(getenv("CMUX_CDP_TARGET_PORT", "39382"), 39382)
 
+	tlsCfg, err := loadTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	envInternalPorts := parseInternalPorts(getenv("CMUX_CDP_INTERNAL_PORTS", ""))
 	var internalPorts []int
 	switch {
@@ -132,6 +155,15 @@ func loadConfig(flagPorts []int) proxyConfig {
 		targetPort:    targetPort,
 		targetHost:    getenv("CMUX_CDP_TARGET_HOST", "127.0.0.1"),
 		hostHeader:    getenv("CMUX_CDP_TARGET_HOST_HEADER", fmt.Sprintf("localhost:%d", targetPort)),
+		drainTimeout:  parseDuration(getenv("CMUX_CDP_DRAIN_TIMEOUT", "5m"), 5*time.Minute),
+		healthTimeout: parseDuration(getenv("CMUX_CDP_HEALTH_TIMEOUT", "2s"), 2*time.Second),
+		tls:           tlsCfg,
+		limits: limitsConfig{
+			maxRequestBytes:  parseByteSize(getenv("CMUX_CDP_MAX_REQUEST_BYTES", ""), 1<<20),
+			maxResponseBytes: parseByteSize(getenv("CMUX_CDP_MAX_RESPONSE_BYTES", ""), 16<<20),
+			writeTimeout:     parseDuration(getenv("CMUX_CDP_WRITE_TIMEOUT", "30s"), 30*time.Second),
+			idleTimeout:      parseDuration(getenv("CMUX_CDP_IDLE_TIMEOUT", "2m"), 2*time.Minute),
+		},
 	}
 }
 
@@ -182,6 +214,7 @@ func main() {
 		req.Header.Del("Proxy-Connection")
 	}
 
+	proxy.ModifyResponse = limitResponseBody(cfg.limits.maxResponseBytes)
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
 		log.Printf("proxy error: %v", err)
 		rw.Header().Set("Content-Type", "text/plain")
@@ -191,65 +224,142 @@ func main() {
 
 	proxy.FlushInterval = 100 * time.Millisecond
 
+	activity := &activityTracker{}
+	health := newHealthChecker(targetURL, cfg.hostHeader, cfg.healthTimeout)
+	handler := health.wrap(activity.wrap(limitRequestBody(cfg.limits.maxRequestBytes, proxy)))
+
 	log.Print("TCP_NODELAY enabled for low-latency proxying")
+	log.Printf(
+		"limits: request body %d bytes, response body %d bytes, write timeout %s, idle timeout %s (0 = unlimited)",
+		cfg.limits.maxRequestBytes,
+		cfg.limits.maxResponseBytes,
+		cfg.limits.writeTimeout,
+		cfg.limits.idleTimeout,
+	)
+
+	// Internal listeners stay plain HTTP; only clients across the network
+	// need TLS.
+	var externalTLS *tls.Config
+	if cfg.tls.enabled() {
+		var err error
+		externalTLS, err = serverTLSConfig(cfg.tls, time.Now())
+		if err != nil {
+			log.Fatalf("external listener TLS: %v", err)
+		}
+	}
 
 	type listenerConfig struct {
 		host  string
 		port  int
 		label string
+		tls   *tls.Config
 	}
 
 	listeners := []listenerConfig{
-		{host: "0.0.0.0", port: cfg.externalPort, label: "external"},
+		{host: "0.0.0.0", port: cfg.externalPort, label: "external", tls: externalTLS},
 	}
 	for _, port := range cfg.internalPorts {
 		listeners = append(listeners, listenerConfig{host: "127.0.0.1", port: port, label: "internal"})
 	}
 
+	servers := make([]*http.Server, 0, len(listeners))
 	errCh := make(chan error, len(listeners))
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	var wg sync.WaitGroup
-	for _, listener := range listeners {
-		listener := listener
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			addr := net.JoinHostPort(listener.host, strconv.Itoa(listener.port))
-			server := &http.Server{
-				Addr:              addr,
-				Handler:           proxy,
-				ReadHeaderTimeout: 5 * time.Second,
+	for _, lc := range listeners {
+		addr := net.JoinHostPort(lc.host, strconv.Itoa(lc.port))
+		ln, err := listen(context.Background(), addr)
+		if err != nil {
+			log.Fatalf("%s listener on %s: %v", lc.label, addr, err)
+		}
+		ln = withWriteDeadline(ln, cfg.limits.writeTimeout)
+		scheme := "http"
+		if lc.tls != nil {
+			ln = tls.NewListener(ln, lc.tls)
+			scheme = "https"
+		}
+		server := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       cfg.limits.idleTimeout,
+			MaxHeaderBytes:    64 << 10,
+		}
+		servers = append(servers, server)
+
+		log.Printf(
+			"cmux CDP proxy listening on %s (%s, %s), forwarding to %s (Host header: %s)",
+			addr,
+			lc.label,
+			scheme,
+			targetURL.Host,
+			cfg.hostHeader,
+		)
+
+		go func(label, addr string) {
+			if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("%s listener on %s exited: %w", label, addr, err)
 			}
+		}(lc.label, addr)
+	}
+
+	signalReady()
+	if err := sdNotify("READY=1"); err != nil {
+		log.Printf("warning: sd_notify READY failed: %v", err)
+	}
 
-			go func() {
-				<-ctx.Done()
-				shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancelShutdown()
-				_ = server.Shutdown(shutdownCtx)
-			}()
-
-			log.Printf(
-				"cmux CDP proxy listening on %s (%s), forwarding to %s (Host header: %s)",
-				addr,
-				listener.label,
-				targetURL.Host,
-				cfg.hostHeader,
-			)
-
-			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				select {
-				case errCh <- fmt.Errorf("%s listener on %s exited: %w", listener.label, addr, err):
-				default:
+	// SIGHUP hands the ports to a freshly started binary and then drains;
+	// SIGTERM/SIGINT just drain.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	for {
+		select {
+		case err := <-errCh:
+			log.Fatalf("server exited: %v", err)
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				if !reusePortSupported {
+					log.Print("SIGHUP ignored: handoff needs SO_REUSEPORT")
+					continue
+				}
+				pid, err := startHandoff()
+				if err != nil {
+					log.Printf("handoff failed, still serving: %v", err)
+					continue
+				}
+				log.Printf("handed off to pid %d", pid)
+				if err := sdNotify(fmt.Sprintf("MAINPID=%d", pid)); err != nil {
+					log.Printf("warning: sd_notify MAINPID failed: %v", err)
 				}
+			} else {
+				log.Printf("received %s", sig)
 			}
-		}()
+			drain(servers, activity, cfg.drainTimeout)
+			return
+		}
 	}
+}
 
-	err := <-errCh
-	cancel()
+// drain stops accepting connections, then waits up to timeout for in-flight
+// requests and proxied DevTools websocket sessions to finish. Hijacked
+// websocket connections are not tracked by http.Server.Shutdown, so the
+// activity tracker's session count is what keeps them alive.
+func drain(servers []*http.Server, activity *activityTracker, timeout time.Duration) {
+	log.Printf("draining (timeout %s, %d websocket sessions open)", timeout, activity.active())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			server.SetKeepAlivesEnabled(false)
+			_ = server.Shutdown(ctx)
+		}(server)
+	}
 	wg.Wait()
-	log.Fatalf("server exited: %v", err)
+
+	if remaining := activity.waitIdle(ctx); remaining > 0 {
+		log.Printf("drain timed out with %d websocket sessions open", remaining)
+		return
+	}
+	log.Print("drained")
 }
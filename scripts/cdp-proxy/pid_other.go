@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// listenerPID is only implemented on Linux, where Chrome runs in sandboxes.
+func listenerPID(port int) int {
+	return 0
+}
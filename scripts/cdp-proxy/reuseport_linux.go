@@ -0,0 +1,5 @@
+package main
+
+// soReusePort is SO_REUSEPORT, which the syscall package does not export on
+// Linux. 15 is its value on every architecture the proxy ships for.
+const soReusePort = 0xf
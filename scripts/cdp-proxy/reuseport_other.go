@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import "syscall"
+
+const reusePortSupported = false
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
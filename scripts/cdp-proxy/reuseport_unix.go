@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import "syscall"
+
+const reusePortSupported = true
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
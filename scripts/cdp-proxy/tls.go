@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	selfSignedCertFile = "tls-cert.pem"
+	selfSignedKeyFile  = "tls-key.pem"
+
+	// selfSignedValidity is how long a generated certificate is valid;
+	// it is regenerated at startup once less than selfSignedRenewBefore is
+	// left.
+	selfSignedValidity    = 365 * 24 * time.Hour
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// tlsConfig selects how the external listener serves TLS. The zero value
+// means plain HTTP.
+type tlsConfig struct {
+	certFile string
+	keyFile  string
+	auto     bool   // Generate a self-signed certificate under stateDir
+	stateDir string // Where the generated certificate is persisted
+}
+
+func (c tlsConfig) enabled() bool {
+	return c.auto || c.certFile != ""
+}
+
+// loadTLSConfig reads the TLS settings. Cert and key paths win over
+// CMUX_CDP_TLS=auto.
+func loadTLSConfig() (tlsConfig, error) {
+	cfg := tlsConfig{
+		certFile: getenv("CMUX_CDP_TLS_CERT", ""),
+		keyFile:  getenv("CMUX_CDP_TLS_KEY", ""),
+		stateDir: getenv("CMUX_CDP_STATE_DIR", "/var/lib/cmux/cdp-proxy"),
+	}
+	if (cfg.certFile == "") != (cfg.keyFile == "") {
+		return cfg, errors.New("CMUX_CDP_TLS_CERT and CMUX_CDP_TLS_KEY must be set together")
+	}
+
+	switch mode := strings.ToLower(getenv("CMUX_CDP_TLS", "")); mode {
+	case "", "off", "false", "0":
+	case "auto":
+		cfg.auto = cfg.certFile == ""
+	default:
+		return cfg, fmt.Errorf("invalid CMUX_CDP_TLS %q (expected auto or off)", mode)
+	}
+	return cfg, nil
+}
+
+// serverTLSConfig returns the tls.Config for the external listener, loading
+// the configured certificate or generating and persisting a self-signed one.
+func serverTLSConfig(cfg tlsConfig, now time.Time) (*tls.Config, error) {
+	certFile, keyFile := cfg.certFile, cfg.keyFile
+	if cfg.auto {
+		certFile = filepath.Join(cfg.stateDir, selfSignedCertFile)
+		keyFile = filepath.Join(cfg.stateDir, selfSignedKeyFile)
+		if err := ensureSelfSignedCert(certFile, keyFile, now); err != nil {
+			return nil, err
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	if len(cert.Certificate) > 0 {
+		sum := sha256.Sum256(cert.Certificate[0])
+		log.Printf("TLS certificate %s (SHA-256 %X)", certFile, sum)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// DevTools websockets upgrade over HTTP/1.1 only.
+		NextProtos: []string{"http/1.1"},
+	}, nil
+}
+
+// ensureSelfSignedCert keeps a usable self-signed certificate at certFile,
+// generating a new one when it is missing, unreadable, or close to expiry.
+// Reusing it across restarts lets clients pin or trust it once.
+func ensureSelfSignedCert(certFile, keyFile string, now time.Time) error {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			log.Printf("warning: existing TLS certificate unparsable, regenerating: %v", err)
+		} else if now.Add(selfSignedRenewBefore).Before(leaf.NotAfter) {
+			return nil
+		} else {
+			log.Printf("self-signed certificate %s expires %s, regenerating", certFile, leaf.NotAfter.Format(time.RFC3339))
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("warning: existing TLS certificate unusable, regenerating: %v", err)
+	}
+
+	certPEM, keyPEM, err := generateSelfSignedCert(selfSignedHosts(), now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0o700); err != nil {
+		return fmt.Errorf("create TLS state dir: %w", err)
+	}
+	// A crash between the writes leaves a mismatched pair, which the next
+	// start fails to load and regenerates.
+	if err := writeFileAtomic(keyFile, keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(certFile, certPEM, 0o644); err != nil {
+		return err
+	}
+	log.Printf("generated self-signed TLS certificate %s", certFile)
+	return nil
+}
+
+// selfSignedHosts lists the names and addresses clients may reach the
+// proxy by: the hostname, localhost, and every local interface address.
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	}
+	return hosts
+}
+
+// generateSelfSignedCert returns a PEM certificate and ECDSA P-256 key valid
+// for hosts (DNS names or IP addresses).
+func generateSelfSignedCert(hosts []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate certificate serial: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"cmux"}, CommonName: "cmux CDP proxy"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode TLS key: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
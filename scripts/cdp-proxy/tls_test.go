@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnsureSelfSignedCert_ReusesUntilNearExpiry(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "state", selfSignedCertFile)
+	keyFile := filepath.Join(dir, "state", selfSignedKeyFile)
+	now := time.Now()
+
+	if err := ensureSelfSignedCert(certFile, keyFile, now); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	first, _ := os.ReadFile(certFile)
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file mode: %v, %v", info, err)
+	}
+
+	if err := ensureSelfSignedCert(certFile, keyFile, now.Add(time.Hour)); err != nil {
+		t.Fatalf("reuse: %v", err)
+	}
+	if again, _ := os.ReadFile(certFile); !bytes.Equal(first, again) {
+		t.Error("expected the persisted certificate to be reused")
+	}
+
+	if err := ensureSelfSignedCert(certFile, keyFile, now.Add(selfSignedValidity-selfSignedRenewBefore)); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if renewed, _ := os.ReadFile(certFile); bytes.Equal(first, renewed) {
+		t.Error("expected a certificate near expiry to be regenerated")
+	}
+}
+
+func TestEnsureSelfSignedCert_ReplacesMismatchedPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, selfSignedCertFile)
+	keyFile := filepath.Join(dir, selfSignedKeyFile)
+	now := time.Now()
+
+	if err := ensureSelfSignedCert(certFile, keyFile, now); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	_, otherKey, err := generateSelfSignedCert([]string{"localhost"}, now)
+	if err != nil {
+		t.Fatalf("generate other: %v", err)
+	}
+	if err := os.WriteFile(keyFile, otherKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := serverTLSConfig(tlsConfig{auto: true, stateDir: dir}, now); err != nil {
+		t.Fatalf("expected mismatched pair to be regenerated, got %v", err)
+	}
+}
+
+func TestGenerateSelfSignedCert_SANs(t *testing.T) {
+	certPEM, _, err := generateSelfSignedCert([]string{"localhost", "sandbox", "127.0.0.1", "10.0.0.5", "localhost"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.DNSNames) != 2 || len(cert.IPAddresses) != 2 {
+		t.Errorf("unexpected SANs: dns=%v ip=%v", cert.DNSNames, cert.IPAddresses)
+	}
+	if err := cert.VerifyHostname("10.0.0.5"); err != nil {
+		t.Errorf("VerifyHostname: %v", err)
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		auto    bool
+		enabled bool
+		wantErr bool
+	}{
+		{name: "default off", env: map[string]string{}},
+		{name: "auto", env: map[string]string{"CMUX_CDP_TLS": "auto"}, auto: true, enabled: true},
+		{name: "paths", env: map[string]string{"CMUX_CDP_TLS_CERT": "/c.pem", "CMUX_CDP_TLS_KEY": "/k.pem"}, enabled: true},
+		{name: "paths win over auto", env: map[string]string{"CMUX_CDP_TLS": "auto", "CMUX_CDP_TLS_CERT": "/c.pem", "CMUX_CDP_TLS_KEY": "/k.pem"}, enabled: true},
+		{name: "cert without key", env: map[string]string{"CMUX_CDP_TLS_CERT": "/c.pem"}, wantErr: true},
+		{name: "bad mode", env: map[string]string{"CMUX_CDP_TLS": "yes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"CMUX_CDP_TLS", "CMUX_CDP_TLS_CERT", "CMUX_CDP_TLS_KEY"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg, err := loadTLSConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.auto != tt.auto || cfg.enabled() != tt.enabled) {
+				t.Errorf("got %+v", cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// adminPathPattern matches the admin API's action routes:
+// /admin/queue/<id>/cancel and /admin/templates/<vmid>/{pause,resume,flush}.
+var adminPathPattern = regexp.MustCompile(`^/admin/(queue|templates)/([^/]+)/(cancel|pause|resume|flush)$`)
+
+// queueEntry is one clone in the admin API's queue listing.
+type queueEntry struct {
+	ID        string `json:"id"`
+	Node      string `json:"node"`
+	NewID     int    `json:"newid"`
+	Caller    string `json:"caller"`
+	QueuedAt  string `json:"queued_at"`
+	AgeMs     int64  `json:"age_ms"`
+	RunningMs int64  `json:"running_ms,omitempty"`
+}
+
+type templateQueueSnapshot struct {
+	Paused      bool         `json:"paused"`
+	PausedSince string       `json:"paused_since,omitempty"`
+	Running     *queueEntry  `json:"running"`
+	Queued      []queueEntry `json:"queued"`
+}
+
+func newQueueEntry(req *cloneRequest, now time.Time) queueEntry {
+	e := queueEntry{
+		ID:       req.id,
+		Node:     req.node,
+		NewID:    req.newID,
+		Caller:   req.caller,
+		QueuedAt: req.queuedAt.UTC().Format(time.RFC3339),
+		AgeMs:    now.Sub(req.queuedAt).Milliseconds(),
+	}
+	if !req.startedAt.IsZero() {
+		e.RunningMs = now.Sub(req.startedAt).Milliseconds()
+	}
+	return e
+}
+
+// serveAdmin handles the admin API. Operators use it to see what each
+// template queue holds and to unwedge one without restarting the proxy.
+func (p *cloneProxy) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	if !p.adminAllowed(r) {
+		writeAdminError(w, http.StatusUnauthorized, "admin API requires the admin token or a loopback client")
+		return
+	}
+
+	if r.URL.Path == "/admin/queue" {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]any{"templates": p.queueSnapshot(time.Now())})
+		return
+	}
+
+	m := adminPathPattern.FindStringSubmatch(r.URL.Path)
+	if m == nil || (m[1] == "queue") != (m[3] == "cancel") {
+		writeAdminError(w, http.StatusNotFound, "unknown admin endpoint")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAdminError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	switch m[3] {
+	case "cancel":
+		p.adminCancel(w, m[2])
+	case "pause", "resume":
+		paused := m[3] == "pause"
+		p.setPaused(m[2], paused)
+		log.Printf("admin: template %s queue %sd", m[2], m[3])
+		writeAdminJSON(w, http.StatusOK, map[string]any{"template": m[2], "paused": paused})
+	case "flush":
+		n := p.flush(m[2], fmt.Sprintf("clone queue for template %s flushed by operator", m[2]))
+		log.Printf("admin: flushed %d queued clones of template %s", n, m[2])
+		writeAdminJSON(w, http.StatusOK, map[string]any{"template": m[2], "flushed": n})
+	}
+}
+
+func (p *cloneProxy) adminCancel(w http.ResponseWriter, id string) {
+	req, running := p.cancel(id, "clone request cancelled by operator")
+	switch {
+	case running:
+		writeAdminError(w, http.StatusConflict, fmt.Sprintf("clone %s is already running; only queued clones can be cancelled", id))
+	case req == nil:
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("no queued clone %s", id))
+	default:
+		log.Printf("admin: cancelled queued clone %s of template %s for %s", id, req.template, req.caller)
+		writeAdminJSON(w, http.StatusOK, map[string]any{"cancelled": id, "template": req.template})
+	}
+}
+
+// adminAllowed accepts the configured admin bearer token, or any loopback
+// client when no token is set.
+func (p *cloneProxy) adminAllowed(r *http.Request) bool {
+	if p.adminToken != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(got), []byte(p.adminToken)) == 1
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (p *cloneProxy) queueSnapshot(now time.Time) map[string]templateQueueSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make(map[string]templateQueueSnapshot, len(p.queues))
+	for template, q := range p.queues {
+		snap := templateQueueSnapshot{Paused: q.paused, Queued: make([]queueEntry, 0, len(q.waiting))}
+		if q.paused {
+			snap.PausedSince = q.pausedSince.UTC().Format(time.RFC3339)
+		}
+		if q.running != nil {
+			e := newQueueEntry(q.running, now)
+			snap.Running = &e
+		}
+		for _, req := range q.waiting {
+			snap.Queued = append(snap.Queued, newQueueEntry(req, now))
+		}
+		out[template] = snap
+	}
+	return out
+}
+
+// queueLocked returns template's queue, creating it and its worker on first
+// use. p.mu must be held.
+func (p *cloneProxy) queueLocked(template string) *templateQueue {
+	queue, ok := p.queues[template]
+	if !ok {
+		queue = &templateQueue{}
+		p.queues[template] = queue
+		p.workers.Add(1)
+		go p.worker(queue)
+	}
+	return queue
+}
+
+// setPaused stops or restarts template's worker picking up queued clones.
+// A clone already running is not affected.
+func (p *cloneProxy) setPaused(template string, paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		// The worker may have exited; pausing after close has no effect anyway.
+		if q, ok := p.queues[template]; ok {
+			q.paused = paused
+		}
+		return
+	}
+	q := p.queueLocked(template)
+	if paused && !q.paused {
+		q.pausedSince = time.Now()
+	}
+	q.paused = paused
+	p.wake.Broadcast()
+}
+
+// cancel removes queued clone id, answering its caller with a 503. It
+// returns the request, or running == true if id has already started.
+func (p *cloneProxy) cancel(id, reason string) (req *cloneRequest, running bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, q := range p.queues {
+		if q.running != nil && q.running.id == id {
+			return nil, true
+		}
+		for i, r := range q.waiting {
+			if r.id == id {
+				p.removeLocked(q, i, reason)
+				return r, false
+			}
+		}
+	}
+	return nil, false
+}
+
+// flush removes every queued clone of template, answering each caller with
+// a 503, and returns how many were removed. The running clone is left alone.
+func (p *cloneProxy) flush(template, reason string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	q, ok := p.queues[template]
+	if !ok {
+		return 0
+	}
+	n := len(q.waiting)
+	for len(q.waiting) > 0 {
+		p.removeLocked(q, len(q.waiting)-1, reason)
+	}
+	return n
+}
+
+// removeLocked drops q.waiting[i] and hands reason to its caller. p.mu must
+// be held.
+func (p *cloneProxy) removeLocked(q *templateQueue, i int, reason string) {
+	req := q.waiting[i]
+	q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	p.pending--
+	req.removed <- reason
+}
+
+// sortedTemplates returns the templates with queues, for stable output.
+func sortedTemplates(queues map[string]templateQueueSnapshot) []string {
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, code int, msg string) {
+	writeAdminJSON(w, code, map[string]string{"error": msg})
+}
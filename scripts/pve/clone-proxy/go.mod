@@ -1,3 +1,7 @@
 module github.com/karlorz/cmux/scripts/pve/clone-proxy
 
 go 1.22
+
+require github.com/karlorz/pve-go v0.0.0-00010101000000-000000000000
+
+replace github.com/karlorz/pve-go => ../../../packages/pve-go
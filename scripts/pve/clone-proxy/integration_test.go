@@ -0,0 +1,535 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karlorz/cmux/scripts/pve/clone-proxy/internal/testsupport"
+)
+
+const testToken = "PVEAPIToken=root@pam!ci=secret"
+
+// newTestProxy starts a clone proxy in front of fake with fast polling and
+// backoff. mutate adjusts the config before the proxy is built.
+func newTestProxy(t *testing.T, fake *testsupport.FakePVE, mutate func(*config)) (*cloneProxy, *httptest.Server) {
+	t.Helper()
+	cfg := config{
+		targetURLs:     []string{fake.URL()},
+		pollInterval:   2 * time.Millisecond,
+		pollTimeout:    5 * time.Second,
+		requestTimeout: 5 * time.Second,
+		queueSize:      100,
+		lockRetries:    3,
+		lockBackoff:    time.Millisecond,
+		retryWindow:    10 * time.Minute,
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	proxy, err := newCloneProxy(cfg)
+	if err != nil {
+		t.Fatalf("newCloneProxy: %v", err)
+	}
+	server := httptest.NewServer(proxy)
+	t.Cleanup(func() {
+		server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = proxy.close(ctx)
+	})
+	return proxy, server
+}
+
+type cloneResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func (r cloneResponse) taskResult(t *testing.T) taskResult {
+	t.Helper()
+	var envelope struct {
+		Data taskResult `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(r.body), &envelope); err != nil {
+		t.Fatalf("decode task result %q: %v", r.body, err)
+	}
+	return envelope.Data
+}
+
+// postClone clones template to newID through the proxy.
+func postClone(t *testing.T, server *httptest.Server, template string, newID int, headers map[string]string) cloneResponse {
+	t.Helper()
+	form := url.Values{"newid": {fmt.Sprint(newID)}, "hostname": {fmt.Sprintf("ct-%d", newID)}}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api2/json/nodes/pve/lxc/"+template+"/clone", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", testToken)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Errorf("clone %s -> %d: %v", template, newID, err)
+		return cloneResponse{}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return cloneResponse{status: resp.StatusCode, header: resp.Header, body: string(body)}
+}
+
+func TestIntegrationClonesSerializePerTemplate(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.SetTaskDuration(30 * time.Millisecond)
+	_, server := newTestProxy(t, fake, nil)
+
+	var wg sync.WaitGroup
+	for i, template := range []string{"9000", "9000", "9000", "9001", "9001"} {
+		wg.Add(1)
+		go func(i int, template string) {
+			defer wg.Done()
+			if resp := postClone(t, server, template, 200+i, nil); resp.status != http.StatusOK {
+				t.Errorf("clone %d: %d %s", i, resp.status, resp.body)
+			}
+		}(i, template)
+	}
+	wg.Wait()
+
+	if got := len(fake.CloneCalls()); got != 5 {
+		t.Errorf("clone calls = %d, want 5", got)
+	}
+	for _, template := range []string{"9000", "9001"} {
+		if got := fake.MaxConcurrentClones(template); got != 1 {
+			t.Errorf("template %s ran %d clones at once, want 1", template, got)
+		}
+	}
+	if got := fake.MaxConcurrentClones(""); got < 2 {
+		t.Errorf("different templates never cloned concurrently (peak %d)", got)
+	}
+	for _, call := range fake.CloneCalls() {
+		if call.Auth != testToken {
+			t.Errorf("clone forwarded Authorization %q, want the caller's token", call.Auth)
+		}
+	}
+}
+
+func TestIntegrationLockErrorsAreRetried(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailLocks("9000", 2)
+	proxy, server := newTestProxy(t, fake, nil)
+
+	resp := postClone(t, server, "9000", 200, map[string]string{resolveTaskHeader: "1"})
+	if resp.status != http.StatusOK {
+		t.Fatalf("clone: %d %s", resp.status, resp.body)
+	}
+	if result := resp.taskResult(t); result.Status != "stopped" || result.ExitStatus != "OK" || result.VMID != 200 {
+		t.Errorf("task result = %+v", result)
+	}
+	if got := len(fake.CloneCalls()); got != 3 {
+		t.Errorf("clone calls = %d, want 2 locked + 1 successful", got)
+	}
+	if got := proxy.lockRetriesTotal.Load(); got != 2 {
+		t.Errorf("lock_retries_total = %d, want 2", got)
+	}
+}
+
+func TestIntegrationLockRetriesExhausted(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailLocks("9000", 10)
+	proxy, server := newTestProxy(t, fake, func(cfg *config) { cfg.lockRetries = 2 })
+
+	resp := postClone(t, server, "9000", 200, nil)
+	if resp.status != http.StatusInternalServerError || !strings.Contains(resp.body, "can't lock") {
+		t.Fatalf("clone: %d %s, want PVE's lock error passed through", resp.status, resp.body)
+	}
+	if got := len(fake.CloneCalls()); got != 3 {
+		t.Errorf("clone calls = %d, want 1 + 2 retries", got)
+	}
+	if got := proxy.lockRetriesExhausted.Load(); got != 1 {
+		t.Errorf("lock_retries_exhausted = %d, want 1", got)
+	}
+}
+
+func TestIntegrationPollTimeoutHoldsTemplateQueue(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.SetTaskDuration(150 * time.Millisecond)
+	_, server := newTestProxy(t, fake, func(cfg *config) { cfg.pollTimeout = 20 * time.Millisecond })
+
+	first := postClone(t, server, "9000", 200, map[string]string{resolveTaskHeader: "1"})
+	if first.status != http.StatusGatewayTimeout {
+		t.Fatalf("first clone: %d %s, want 504", first.status, first.body)
+	}
+	result := first.taskResult(t)
+	if result.Status != "running" || result.UPID == "" {
+		t.Errorf("timed out task result = %+v", result)
+	}
+
+	// The task is still running on PVE, so the next clone of the template
+	// must wait for it.
+	if second := postClone(t, server, "9000", 201, nil); second.status != http.StatusGatewayTimeout && second.status != http.StatusOK {
+		t.Fatalf("second clone: %d %s", second.status, second.body)
+	}
+	calls := fake.CloneCalls()
+	if len(calls) != 2 {
+		t.Fatalf("clone calls = %d, want 2", len(calls))
+	}
+	if finished := fake.TaskFinishedAt(result.UPID); calls[1].At.Before(finished) {
+		t.Errorf("second clone started %s before the first task finished", finished.Sub(calls[1].At))
+	}
+}
+
+func TestIntegrationFailedTaskIsReported(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailTasks("9000", "clone failed: storage full")
+	proxy, server := newTestProxy(t, fake, nil)
+
+	resp := postClone(t, server, "9000", 200, map[string]string{resolveTaskHeader: "true"})
+	if resp.status != http.StatusOK {
+		t.Fatalf("clone: %d %s", resp.status, resp.body)
+	}
+	if result := resp.taskResult(t); result.ExitStatus != "clone failed: storage full" {
+		t.Errorf("task result = %+v", result)
+	}
+	if stats := proxy.stats.snapshot(time.Now())["9000"]; stats.Failed != 1 {
+		t.Errorf("template stats = %+v, want one failure", stats)
+	}
+}
+
+func TestIntegrationQueueFullRejects(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.SetTaskDuration(100 * time.Millisecond)
+	_, server := newTestProxy(t, fake, func(cfg *config) { cfg.queueSize = 1 })
+
+	done := make(chan cloneResponse)
+	go func() { done <- postClone(t, server, "9000", 200, nil) }()
+	fake.WaitForCalls(t, 1, 5*time.Second)
+
+	if resp := postClone(t, server, "9001", 201, nil); resp.status != http.StatusServiceUnavailable || !strings.Contains(resp.body, "queue full") {
+		t.Errorf("clone over queue size: %d %s, want 503 queue full", resp.status, resp.body)
+	}
+	if resp := <-done; resp.status != http.StatusOK {
+		t.Errorf("queued clone: %d %s", resp.status, resp.body)
+	}
+}
+
+func TestIntegrationGracefulShutdownDrainsQueue(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.SetTaskDuration(50 * time.Millisecond)
+	proxy, server := newTestProxy(t, fake, nil)
+
+	results := make(chan cloneResponse, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) { results <- postClone(t, server, "9000", 200+i, nil) }(i)
+	}
+	fake.WaitForCalls(t, 1, 5*time.Second)
+	time.Sleep(10 * time.Millisecond) // Let the second request queue up
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := proxy.close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// Every clone queued before close ran to completion before it returned.
+	closedAt := time.Now()
+	calls := fake.CloneCalls()
+	if len(calls) != 2 {
+		t.Fatalf("clone calls at close = %d, want 2", len(calls))
+	}
+	for _, call := range calls {
+		if finished := fake.TaskFinishedAt(call.UPID); finished.After(closedAt) {
+			t.Errorf("task %s still running when close returned", call.UPID)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case resp := <-results:
+			if resp.status != http.StatusOK {
+				t.Errorf("queued clone: %d %s", resp.status, resp.body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no response for queued clone %d", i)
+		}
+	}
+
+	if resp := postClone(t, server, "9000", 210, nil); resp.status != http.StatusServiceUnavailable || !strings.Contains(resp.body, "shutting down") {
+		t.Errorf("clone after close: %d %s, want 503 shutting down", resp.status, resp.body)
+	}
+}
+
+func TestIntegrationPostConfigAndStart(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	_, server := newTestProxy(t, fake, nil)
+
+	resp := postClone(t, server, "9000", 200, map[string]string{postConfigHeader: `{"config":{"memory":8192,"tags":"cmux"},"start":true}`})
+	if resp.status != http.StatusOK {
+		t.Fatalf("clone: %d %s", resp.status, resp.body)
+	}
+	post := resp.taskResult(t).PostConfig
+	if post == nil || post.Error != "" || !post.Started || strings.Join(post.Configured, ",") != "memory,tags" {
+		t.Fatalf("post_config = %+v", post)
+	}
+
+	var configured, started bool
+	for _, call := range fake.Calls() {
+		switch {
+		case call.Method == http.MethodPut && strings.HasSuffix(call.Path, "/lxc/200/config"):
+			configured = call.Form.Get("memory") == "8192" && call.Form.Get("tags") == "cmux"
+		case call.Method == http.MethodPost && strings.HasSuffix(call.Path, "/lxc/200/status/start"):
+			started = true
+		}
+	}
+	if !configured || !started {
+		t.Errorf("fake saw configured=%t started=%t: %+v", configured, started, fake.Calls())
+	}
+}
+
+func TestIntegrationRetryBudgetRejectsCaller(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailTasks("9000", "clone failed: storage full")
+	proxy, server := newTestProxy(t, fake, func(cfg *config) { cfg.retryBudget = 2 })
+
+	// A clone rejected by validation plus a failed clone task use the
+	// whole budget.
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api2/json/nodes/pve/lxc/9000/clone", strings.NewReader("newid=42"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", testToken)
+	invalid, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid clone: %d, want 400", invalid.StatusCode)
+	}
+	if resp := postClone(t, server, "9000", 200, map[string]string{resolveTaskHeader: "1"}); resp.status != http.StatusOK || resp.taskResult(t).ExitStatus == "OK" {
+		t.Fatalf("failing clone: %d %s", resp.status, resp.body)
+	}
+
+	resp := postClone(t, server, "9000", 201, nil)
+	if resp.status != http.StatusTooManyRequests || resp.header.Get("X-Cmux-Reject-Reason") != retryBudgetReason {
+		t.Fatalf("third clone: %d %s, want 429 %s", resp.status, resp.body, retryBudgetReason)
+	}
+	if got := len(fake.CloneCalls()); got != 1 {
+		t.Errorf("clone calls = %d, want 1 (invalid and rejected clones never reach PVE)", got)
+	}
+	if got := proxy.retries.snapshot(time.Now())[callerIdentity(req.Header)]; got.RetriesTotal != 2 || got.Rejected != 1 {
+		t.Errorf("caller accounting = %+v, want 2 retries and 1 rejection", got)
+	}
+}
+
+func TestIntegrationRetryBudgetIgnoresLockRetries(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailLocks("9000", 3)
+	proxy, server := newTestProxy(t, fake, func(cfg *config) {
+		cfg.lockRetries = 1
+		cfg.retryBudget = 1
+	})
+
+	// The first clone retries a lock error and gives up on a second one;
+	// the second clone retries one more. None of it is the caller's fault.
+	if resp := postClone(t, server, "9000", 200, nil); resp.status != http.StatusInternalServerError {
+		t.Fatalf("first clone: %d %s, want the lock error", resp.status, resp.body)
+	}
+	if resp := postClone(t, server, "9000", 201, nil); resp.status != http.StatusOK {
+		t.Fatalf("second clone: %d %s, want it admitted and cloned", resp.status, resp.body)
+	}
+	if got := proxy.lockRetriesTotal.Load(); got != 2 {
+		t.Errorf("lock_retries_total = %d, want 2", got)
+	}
+	for caller, got := range proxy.retries.snapshot(time.Now()) {
+		if got.RetriesTotal != 0 {
+			t.Errorf("caller %s charged %+v for lock retries", caller, got)
+		}
+	}
+}
+
+func TestIntegrationRetryBudgetIgnoresUnavailableUpstream(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	proxy, server := newTestProxy(t, fake, func(cfg *config) {
+		cfg.targetURLs = []string{down.URL}
+		cfg.retryBudget = 1
+	})
+
+	for i := 0; i < 2; i++ {
+		if resp := postClone(t, server, "9000", 200+i, nil); resp.status != http.StatusBadGateway {
+			t.Fatalf("clone %d: %d %s, want 502", i, resp.status, resp.body)
+		}
+	}
+	for caller, got := range proxy.retries.snapshot(time.Now()) {
+		if got.RetriesTotal != 0 {
+			t.Errorf("caller %s charged %+v for an unreachable upstream", caller, got)
+		}
+	}
+}
+
+// adminCall makes an admin API request and decodes its JSON response.
+func adminCall(t *testing.T, server *httptest.Server, method, path, token string, out any) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, server.URL+path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForQueued polls the admin API until template has n queued clones.
+func waitForQueued(t *testing.T, server *httptest.Server, template string, n int) templateQueueSnapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var listing struct {
+			Templates map[string]templateQueueSnapshot `json:"templates"`
+		}
+		adminCall(t, server, http.MethodGet, "/admin/queue", "", &listing)
+		if q := listing.Templates[template]; len(q.Queued) == n {
+			return q
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("template %s never had %d queued clones: %+v", template, n, listing.Templates)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIntegrationAdminPauseCancelFlush(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	proxy, server := newTestProxy(t, fake, nil)
+
+	var paused map[string]any
+	if code := adminCall(t, server, http.MethodPost, "/admin/templates/9000/pause", "", &paused); code != http.StatusOK || paused["paused"] != true {
+		t.Fatalf("pause: %d %v", code, paused)
+	}
+
+	results := make(chan cloneResponse, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) { results <- postClone(t, server, "9000", 200+i, nil) }(i)
+		waitForQueued(t, server, "9000", i+1)
+	}
+	q := waitForQueued(t, server, "9000", 3)
+	if !q.Paused || q.Running != nil || q.Queued[0].Caller != callerIdentity(http.Header{"Authorization": {testToken}}) {
+		t.Errorf("paused queue = %+v", q)
+	}
+	if got := len(fake.CloneCalls()); got != 0 {
+		t.Fatalf("paused template made %d clone calls", got)
+	}
+
+	// Cancel the middle clone, flush the rest.
+	cancelled := q.Queued[1]
+	if code := adminCall(t, server, http.MethodPost, "/admin/queue/"+cancelled.ID+"/cancel", "", nil); code != http.StatusOK {
+		t.Fatalf("cancel %s: %d", cancelled.ID, code)
+	}
+	if code := adminCall(t, server, http.MethodPost, "/admin/queue/"+cancelled.ID+"/cancel", "", nil); code != http.StatusNotFound {
+		t.Errorf("second cancel of %s: %d, want 404", cancelled.ID, code)
+	}
+	var flushed map[string]any
+	if code := adminCall(t, server, http.MethodPost, "/admin/templates/9000/flush", "", &flushed); code != http.StatusOK || flushed["flushed"] != float64(2) {
+		t.Fatalf("flush: %d %v", code, flushed)
+	}
+	for i := 0; i < 3; i++ {
+		resp := <-results
+		if resp.status != http.StatusServiceUnavailable || !strings.Contains(resp.body, "by operator") {
+			t.Errorf("removed clone: %d %s, want 503 by operator", resp.status, resp.body)
+		}
+	}
+	proxy.mu.Lock()
+	pending := proxy.pending
+	proxy.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("pending = %d after flush, want 0", pending)
+	}
+
+	if code := adminCall(t, server, http.MethodPost, "/admin/templates/9000/resume", "", nil); code != http.StatusOK {
+		t.Fatalf("resume: %d", code)
+	}
+	if resp := postClone(t, server, "9000", 210, nil); resp.status != http.StatusOK {
+		t.Errorf("clone after resume: %d %s", resp.status, resp.body)
+	}
+	if got := len(fake.CloneCalls()); got != 1 {
+		t.Errorf("clone calls = %d, want only the clone after resume", got)
+	}
+}
+
+func TestIntegrationAdminCannotCancelRunningClone(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.SetTaskDuration(200 * time.Millisecond)
+	_, server := newTestProxy(t, fake, nil)
+
+	done := make(chan cloneResponse, 1)
+	go func() { done <- postClone(t, server, "9000", 200, nil) }()
+	fake.WaitForCalls(t, 1, 5*time.Second)
+
+	q := waitForQueued(t, server, "9000", 0)
+	if q.Running == nil || q.Running.NewID != 200 {
+		t.Fatalf("running clone = %+v", q.Running)
+	}
+	if code := adminCall(t, server, http.MethodPost, "/admin/queue/"+q.Running.ID+"/cancel", "", nil); code != http.StatusConflict {
+		t.Errorf("cancel running clone: %d, want 409", code)
+	}
+	if resp := <-done; resp.status != http.StatusOK {
+		t.Errorf("running clone: %d %s", resp.status, resp.body)
+	}
+}
+
+func TestIntegrationAdminToken(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	_, server := newTestProxy(t, fake, func(cfg *config) { cfg.adminToken = "s3cret" })
+
+	if code := adminCall(t, server, http.MethodGet, "/admin/queue", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("without token: %d, want 401", code)
+	}
+	if code := adminCall(t, server, http.MethodGet, "/admin/queue", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: %d, want 401", code)
+	}
+	if code := adminCall(t, server, http.MethodGet, "/admin/queue", "s3cret", nil); code != http.StatusOK {
+		t.Errorf("with token: %d, want 200", code)
+	}
+	if code := adminCall(t, server, http.MethodPost, "/admin/templates/9000/cancel", "s3cret", nil); code != http.StatusNotFound {
+		t.Errorf("cancel on a template path: %d, want 404", code)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("admin requests reached PVE: %+v", fake.Calls())
+	}
+}
+
+func TestIntegrationNonClonePassesThrough(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	_, server := newTestProxy(t, fake, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api2/json/nodes/pve/lxc", nil)
+	req.Header.Set("Authorization", testToken)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].Path != "/api2/json/nodes/pve/lxc" || calls[0].Auth != testToken {
+		t.Errorf("fake calls = %+v", calls)
+	}
+}
@@ -0,0 +1,309 @@
+// Package testsupport provides a fake PVE API for clone proxy tests. It
+// serves just enough of PVE for the proxies: clone calls returning UPIDs,
+// task status that moves from running to stopped, container config and
+// start, with injectable lock errors, latencies and task failures.
+package testsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LockMessage is the error fake lock failures return, worded like PVE's.
+const LockMessage = "can't lock file '/run/lock/lxc/pve-config-%s.lock' - got timeout"
+
+var (
+	clonePath  = regexp.MustCompile(`^/api2/json/nodes/([^/]+)/lxc/(\d+)/clone/?$`)
+	configPath = regexp.MustCompile(`^/api2/json/nodes/([^/]+)/lxc/(\d+)/config/?$`)
+	startPath  = regexp.MustCompile(`^/api2/json/nodes/([^/]+)/lxc/(\d+)/status/start/?$`)
+	statusPath = regexp.MustCompile(`^/api2/json/nodes/([^/]+)/tasks/([^/]+)/status/?$`)
+)
+
+// Call is one request the fake received.
+type Call struct {
+	Method   string
+	Path     string
+	Node     string
+	VMID     string // Source template for clones, the container otherwise
+	NewID    string // Clones only
+	Form     url.Values
+	Auth     string // Authorization header
+	At       time.Time
+	UPID     string // Set when the call started a task
+	Rejected bool   // Failed with an injected lock error
+}
+
+type fakeTask struct {
+	vmid       string
+	started    time.Time
+	finishAt   time.Time
+	exitStatus string
+}
+
+// FakePVE is an httptest server speaking the PVE API subset the clone
+// proxies use. The zero configuration answers every clone immediately
+// with a task that stops OK on its first poll.
+type FakePVE struct {
+	Server *httptest.Server
+
+	mu           sync.Mutex
+	cloneLatency time.Duration
+	taskDuration time.Duration
+	lockFailures map[string]int    // VMID -> clone calls left to reject
+	taskExit     map[string]string // VMID -> exit status of its tasks
+	calls        []Call
+	tasks        map[string]*fakeTask
+	taskPolls    int
+	seq          int
+}
+
+// NewFakePVE starts a fake PVE that is closed when the test ends.
+func NewFakePVE(t testing.TB) *FakePVE {
+	f := &FakePVE{
+		lockFailures: make(map[string]int),
+		taskExit:     make(map[string]string),
+		tasks:        make(map[string]*fakeTask),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Server.Close)
+	return f
+}
+
+// URL is the fake's base URL.
+func (f *FakePVE) URL() string {
+	return f.Server.URL
+}
+
+// SetCloneLatency delays every clone call's response by d.
+func (f *FakePVE) SetCloneLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cloneLatency = d
+}
+
+// SetTaskDuration makes tasks report running for d after they start.
+func (f *FakePVE) SetTaskDuration(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.taskDuration = d
+}
+
+// FailLocks rejects the next n clone calls of template with a lock error.
+func (f *FakePVE) FailLocks(template string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lockFailures[template] = n
+}
+
+// FailTasks makes tasks for vmid stop with exitStatus instead of OK.
+func (f *FakePVE) FailTasks(vmid, exitStatus string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.taskExit[vmid] = exitStatus
+}
+
+// Calls returns the requests received so far, status polls excluded.
+func (f *FakePVE) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CloneCalls returns the clone calls received so far, including rejected
+// ones.
+func (f *FakePVE) CloneCalls() []Call {
+	var out []Call
+	for _, c := range f.Calls() {
+		if strings.HasSuffix(strings.TrimSuffix(c.Path, "/"), "/clone") {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// TaskPolls is the number of task status requests served.
+func (f *FakePVE) TaskPolls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.taskPolls
+}
+
+// WaitForCalls blocks until at least n clone calls arrived, failing the
+// test after timeout.
+func (f *FakePVE) WaitForCalls(t testing.TB, n int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for len(f.CloneCalls()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d clone calls after %s, want %d", len(f.CloneCalls()), timeout, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// MaxConcurrentClones is the most clone tasks of template that were ever
+// running at once. An empty template counts all templates together.
+func (f *FakePVE) MaxConcurrentClones(template string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	for _, task := range f.tasks {
+		if task.vmid == "" || (template != "" && task.vmid != template) {
+			continue
+		}
+		edges = append(edges, edge{task.started, 1}, edge{task.finishAt, -1})
+	}
+	// Ends sort before starts at the same instant, so back-to-back tasks
+	// don't count as overlapping.
+	sort.Slice(edges, func(i, j int) bool {
+		if !edges[i].at.Equal(edges[j].at) {
+			return edges[i].at.Before(edges[j].at)
+		}
+		return edges[i].delta < edges[j].delta
+	})
+	running, peak := 0, 0
+	for _, e := range edges {
+		running += e.delta
+		if running > peak {
+			peak = running
+		}
+	}
+	return peak
+}
+
+// TaskFinishedAt is when the task with upid stops, or the zero time for
+// unknown tasks.
+func (f *FakePVE) TaskFinishedAt(upid string) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if task, ok := f.tasks[upid]; ok {
+		return task.finishAt
+	}
+	return time.Time{}
+}
+
+func (f *FakePVE) serve(w http.ResponseWriter, r *http.Request) {
+	if m := statusPath.FindStringSubmatch(r.URL.Path); m != nil && r.Method == http.MethodGet {
+		f.serveTaskStatus(w, m[2])
+		return
+	}
+
+	_ = r.ParseForm()
+	call := Call{Method: r.Method, Path: r.URL.Path, Form: r.PostForm, Auth: r.Header.Get("Authorization"), At: time.Now()}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		call.Form = url.Values{}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for k, v := range body {
+			call.Form.Set(k, fmt.Sprint(v))
+		}
+	}
+
+	switch m := clonePath.FindStringSubmatch(r.URL.Path); {
+	case m != nil && r.Method == http.MethodPost:
+		call.Node, call.VMID, call.NewID = m[1], m[2], call.Form.Get("newid")
+		f.serveClone(w, r, call)
+	case configPath.MatchString(r.URL.Path) && r.Method == http.MethodPut:
+		m := configPath.FindStringSubmatch(r.URL.Path)
+		call.Node, call.VMID = m[1], m[2]
+		f.record(call)
+		writeData(w, http.StatusOK, nil)
+	case startPath.MatchString(r.URL.Path) && r.Method == http.MethodPost:
+		m := startPath.FindStringSubmatch(r.URL.Path)
+		call.Node, call.VMID = m[1], m[2]
+		call.UPID = f.startTask(call.Node, "", "vzstart", call.VMID)
+		f.record(call)
+		writeData(w, http.StatusOK, call.UPID)
+	default:
+		f.record(call)
+		writeData(w, http.StatusOK, []any{})
+	}
+}
+
+func (f *FakePVE) serveClone(w http.ResponseWriter, r *http.Request, call Call) {
+	f.mu.Lock()
+	latency := f.cloneLatency
+	f.mu.Unlock()
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+
+	f.mu.Lock()
+	if f.lockFailures[call.VMID] > 0 {
+		f.lockFailures[call.VMID]--
+		call.Rejected = true
+		f.calls = append(f.calls, call)
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": nil, "message": fmt.Sprintf(LockMessage, call.VMID)})
+		return
+	}
+	f.mu.Unlock()
+
+	call.UPID = f.startTask(call.Node, call.VMID, "vzclone", call.VMID)
+	f.record(call)
+	writeData(w, http.StatusOK, call.UPID)
+}
+
+// startTask registers a task. template is set for clones so concurrency
+// can be measured per template; exit statuses are looked up by vmid.
+func (f *FakePVE) startTask(node, template, kind, vmid string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	now := time.Now()
+	upid := fmt.Sprintf("UPID:%s:%08X:%08X:%08X:%s:%s:root@pam:", node, 1000+f.seq, f.seq, now.Unix(), kind, vmid)
+	exit := f.taskExit[vmid]
+	if exit == "" {
+		exit = "OK"
+	}
+	f.tasks[upid] = &fakeTask{vmid: template, started: now, finishAt: now.Add(f.taskDuration), exitStatus: exit}
+	return upid
+}
+
+func (f *FakePVE) serveTaskStatus(w http.ResponseWriter, upid string) {
+	f.mu.Lock()
+	f.taskPolls++
+	task, ok := f.tasks[upid]
+	f.mu.Unlock()
+	if !ok {
+		writeData(w, http.StatusInternalServerError, nil)
+		return
+	}
+	if time.Now().Before(task.finishAt) {
+		writeData(w, http.StatusOK, map[string]string{"status": "running"})
+		return
+	}
+	writeData(w, http.StatusOK, map[string]string{"status": "stopped", "exitstatus": task.exitStatus})
+}
+
+func (f *FakePVE) record(call Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func writeData(w http.ResponseWriter, code int, data any) {
+	body, _ := json.Marshal(map[string]any{"data": data})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
@@ -6,8 +6,10 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -17,8 +19,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	pve "github.com/karlorz/pve-go"
 )
 
 var (
@@ -36,26 +42,60 @@ var (
 	}
 )
 
+// resolveTaskHeader opts a clone caller into a normalized taskResult response
+// instead of the raw PVE UPID body.
+const resolveTaskHeader = "X-Cmux-Resolve-Task"
+
+// maxLockRetryBackoff caps the exponential backoff between lock retries.
+const maxLockRetryBackoff = 30 * time.Second
+
 // config holds runtime settings sourced from environment variables.
 type config struct {
 	listenAddr     string
-	targetURL      string
+	targetURLs     []string // Preferred first; the rest are failover upstreams
+	healthInterval time.Duration
 	pollInterval   time.Duration
 	pollTimeout    time.Duration
 	requestTimeout time.Duration
 	skipTLSVerify  bool
 	queueSize      int
+	lockRetries    int
+	lockBackoff    time.Duration
+	cooldown       time.Duration
+	retryBudget    int
+	retryWindow    time.Duration
+	adminToken     string           // Bearer token for /admin; empty allows loopback clients only
+	simulate       *simulatorConfig // Nil unless CLONE_PROXY_SIMULATE is true
 }
 
 func main() {
 	cfg := config{
 		listenAddr:     getenv("CLONE_PROXY_LISTEN", "127.0.0.1:8081"),
-		targetURL:      getenv("CLONE_PROXY_TARGET", getenv("PVE_API_URL", "https://127.0.0.1:8006")),
+		targetURLs:     splitUpstreams(getenv("CLONE_PROXY_TARGET", getenv("PVE_API_URL", "https://127.0.0.1:8006"))),
+		healthInterval: mustParseDuration(getenv("CLONE_PROXY_HEALTH_INTERVAL", "5s")),
 		pollInterval:   mustParseDuration(getenv("CLONE_PROXY_POLL_INTERVAL", "2s")),
 		pollTimeout:    mustParseDuration(getenv("CLONE_PROXY_POLL_TIMEOUT", "15m")),
 		requestTimeout: mustParseDuration(getenv("CLONE_PROXY_REQUEST_TIMEOUT", "30s")),
 		skipTLSVerify:  strings.EqualFold(getenv("CLONE_PROXY_SKIP_TLS_VERIFY", "false"), "true"),
 		queueSize:      mustParseInt(getenv("CLONE_PROXY_QUEUE_SIZE", "100")),
+		lockRetries:    mustParseInt(getenv("CLONE_PROXY_LOCK_RETRIES", "3")),
+		lockBackoff:    mustParseDuration(getenv("CLONE_PROXY_LOCK_RETRY_BACKOFF", "2s")),
+		cooldown:       mustParseDuration(getenv("CLONE_PROXY_TEMPLATE_COOLDOWN", "0s")),
+		retryBudget:    mustParseInt(getenv("CLONE_PROXY_RETRY_BUDGET", "0")),
+		retryWindow:    mustParseDuration(getenv("CLONE_PROXY_RETRY_WINDOW", "10m")),
+		adminToken:     os.Getenv("CLONE_PROXY_ADMIN_TOKEN"),
+	}
+	if strings.EqualFold(getenv("CLONE_PROXY_SIMULATE", "false"), "true") {
+		dist, err := parseDurationDist(getenv("CLONE_PROXY_SIMULATE_DURATION", "lognormal:40s,0.4"))
+		if err != nil {
+			log.Fatalf("invalid CLONE_PROXY_SIMULATE_DURATION: %v", err)
+		}
+		cfg.simulate = &simulatorConfig{
+			duration: dist,
+			lockRate: mustParseFloat(getenv("CLONE_PROXY_SIMULATE_LOCK_RATE", "0.1")),
+			failRate: mustParseFloat(getenv("CLONE_PROXY_SIMULATE_FAIL_RATE", "0.02")),
+			seed:     int64(mustParseInt(getenv("CLONE_PROXY_SIMULATE_SEED", "0"))),
+		}
 	}
 
 	proxy, err := newCloneProxy(cfg)
@@ -73,49 +113,122 @@ func main() {
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-shutdownCtx.Done()
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
 			log.Printf("graceful shutdown failed: %v", err)
 		}
+		if err := proxy.close(ctx); err != nil {
+			log.Printf("clone workers still busy at shutdown: %v", err)
+		}
+		if proxy.sim != nil {
+			report, _ := json.Marshal(proxy.sim.report())
+			log.Printf("simulation results: %s", report)
+		}
 	}()
 
-	log.Printf("pve clone proxy listening on %s -> %s (queue=%d, poll=%s, timeout=%s)", cfg.listenAddr, cfg.targetURL, cfg.queueSize, cfg.pollInterval, cfg.pollTimeout)
+	if cfg.simulate != nil {
+		log.Printf("SIMULATE MODE: PVE at %s will not be contacted (duration=%s, lock_rate=%g, fail_rate=%g)", strings.Join(cfg.targetURLs, ","), cfg.simulate.duration, cfg.simulate.lockRate, cfg.simulate.failRate)
+	}
+	if cfg.simulate == nil && len(cfg.targetURLs) > 1 {
+		go proxy.upstreams.runHealthChecks(shutdownCtx, proxy.healthClient, cfg.healthInterval)
+	}
+	log.Printf("pve clone proxy listening on %s -> %s (queue=%d, poll=%s, timeout=%s, lock_retries=%d, cooldown=%s, retry_budget=%d/%s)", cfg.listenAddr, strings.Join(cfg.targetURLs, ","), cfg.queueSize, cfg.pollInterval, cfg.pollTimeout, cfg.lockRetries, cfg.cooldown, cfg.retryBudget, cfg.retryWindow)
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("server exited with error: %v", err)
 	}
+	<-shutdownDone
 }
 
 // cloneProxy proxies requests to the PVE API while serializing clone operations
-// using a bounded in-memory queue.
+// per source template. Clones of different templates run concurrently; the
+// total number of pending clones across all templates is bounded.
 type cloneProxy struct {
-	target       *url.URL
+	target       *url.URL // Primary upstream; failoverTransport rewrites the host
+	upstreams    *upstreamPool
 	reverseProxy *httputil.ReverseProxy
 	httpClient   *http.Client
+	healthClient *http.Client // Bypasses failover so each upstream is probed directly
+	api          *pve.Client  // Unauthenticated; use WithAuth per request
 	pollInterval time.Duration
 	pollTimeout  time.Duration
-	queue        chan *cloneRequest
+	queueSize    int
+	lockRetries  int
+	lockBackoff  time.Duration
+	cooldown     time.Duration // Min interval between clone starts per template
+	adminToken   string
+
+	// Lock retry counters, reported in log lines and /stats.
+	lockRetriesTotal     atomic.Int64
+	lockRetriesExhausted atomic.Int64
+	stats                *cloneStats
+	retries              *retryBudget // Per-caller retry accounting
+	sim                  *simulator   // Non-nil in simulate mode
+
+	mu      sync.Mutex
+	wake    *sync.Cond                // Signalled on mu when a queue gains work, resumes or closes
+	queues  map[string]*templateQueue // Source template VMID -> queue
+	pending int                       // Queued or in-flight clones across all templates
+	nextID  int64
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// templateQueue is one template's clones, run in order by its worker.
+// Guarded by cloneProxy.mu.
+type templateQueue struct {
+	waiting     []*cloneRequest
+	running     *cloneRequest
+	paused      bool
+	pausedSince time.Time
 }
 
 type cloneRequest struct {
-	w    http.ResponseWriter
-	r    *http.Request
-	body []byte
-	node string
-	done chan struct{}
+	id         string // Admin API handle, unique per proxy run
+	w          http.ResponseWriter
+	r          *http.Request
+	body       []byte // Normalized form body
+	node       string
+	template   string
+	caller     string // callerIdentity of the Authorization header
+	newID      int
+	postConfig *postCloneConfig // Nil unless the caller asked for a post-clone step
+	queuedAt   time.Time
+	startedAt  time.Time     // Zero until the worker picks it up; guarded by cloneProxy.mu
+	done       chan struct{} // Closed by the worker once processClone returns
+	removed    chan string   // Receives the reason when the admin API drops it from the queue
+}
+
+// taskResult is the normalized response returned when the caller sets
+// resolveTaskHeader. It is wrapped in a PVE-style {"data": ...} envelope.
+type taskResult struct {
+	UPID        string `json:"upid"`
+	Node        string `json:"node"`
+	VMID        int    `json:"vmid,omitempty"`
+	Status      string `json:"status"`
+	ExitStatus  string `json:"exitstatus,omitempty"`
+	ElapsedMs   int64  `json:"elapsed_ms"`
+	QueueWaitMs int64  `json:"queue_wait_ms"`
+
+	PostConfig *postConfigResult `json:"post_config,omitempty"`
 }
 
 func newCloneProxy(cfg config) (*cloneProxy, error) {
-	target, err := url.Parse(cfg.targetURL)
+	targets, err := parseUpstreams(cfg.targetURLs)
 	if err != nil {
 		return nil, err
 	}
+	upstreams := newUpstreamPool(targets)
+	target := upstreams.primary()
 
-	transport := &http.Transport{
+	base := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.skipTLSVerify},
 	}
+	transport := &failoverTransport{pool: upstreams, base: base}
 
 	rp := httputil.NewSingleHostReverseProxy(target)
 	rp.Transport = transport
@@ -124,31 +237,74 @@ func newCloneProxy(cfg config) (*cloneProxy, error) {
 		http.Error(w, "upstream error", http.StatusBadGateway)
 	}
 
+	httpClient := &http.Client{Transport: transport, Timeout: cfg.requestTimeout}
+	api, err := pve.NewClient(pve.Config{BaseURL: target.String(), HTTPClient: httpClient})
+	if err != nil {
+		return nil, err
+	}
+
 	cp := &cloneProxy{
 		target:       target,
+		upstreams:    upstreams,
 		reverseProxy: rp,
-		httpClient:   &http.Client{Transport: transport, Timeout: cfg.requestTimeout},
+		httpClient:   httpClient,
+		healthClient: &http.Client{Transport: base, Timeout: 5 * time.Second},
+		api:          api,
 		pollInterval: cfg.pollInterval,
 		pollTimeout:  cfg.pollTimeout,
-		queue:        make(chan *cloneRequest, cfg.queueSize),
+		queueSize:    cfg.queueSize,
+		lockRetries:  cfg.lockRetries,
+		lockBackoff:  cfg.lockBackoff,
+		cooldown:     cfg.cooldown,
+		adminToken:   cfg.adminToken,
+		stats:        newCloneStats(),
+		retries:      newRetryBudget(cfg.retryBudget, cfg.retryWindow),
+		queues:       make(map[string]*templateQueue),
+	}
+	cp.wake = sync.NewCond(&cp.mu)
+	if cfg.simulate != nil {
+		cp.sim = newSimulator(*cfg.simulate)
 	}
-
-	go cp.worker()
 
 	return cp, nil
 }
 
 func (p *cloneProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/") {
+		p.serveAdmin(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		switch r.URL.Path {
+		case "/stats":
+			p.serveStats(w, r)
+			return
+		case "/metrics":
+			p.serveMetrics(w, r)
+			return
+		}
+	}
 	if r.Method == http.MethodPost && clonePathPattern.MatchString(r.URL.Path) {
 		p.enqueueClone(w, r)
 		return
 	}
+	if p.sim != nil {
+		p.serveSimulated(w, r)
+		return
+	}
 	p.reverseProxy.ServeHTTP(w, r)
 }
 
 func (p *cloneProxy) enqueueClone(w http.ResponseWriter, r *http.Request) {
 	matches := clonePathPattern.FindStringSubmatch(r.URL.Path)
-	node := matches[1]
+	node, template := matches[1], matches[2]
+	caller := callerIdentity(r.Header)
+
+	if ok, retryAfter := p.retries.admit(caller, time.Now()); !ok {
+		log.Printf("rejecting clone of template %s: caller %s is over its retry budget", template, caller)
+		writeRetryBudgetExceeded(w, caller, p.retries.limit, p.retries.window, retryAfter)
+		return
+	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -157,61 +313,189 @@ func (p *cloneProxy) enqueueClone(w http.ResponseWriter, r *http.Request) {
 	}
 	r.Body.Close()
 
-	req := &cloneRequest{
-		w:    w,
-		r:    r,
-		body: body,
-		node: node,
-		done: make(chan struct{}),
+	postConfig, body, errs := extractPostConfig(r, body)
+	if errs != nil {
+		log.Printf("rejecting invalid post-config for clone of template %s: %v", template, errs)
+		p.retries.charge(caller, time.Now())
+		writeParamErrors(w, errs)
+		return
+	}
+
+	values, newID, errs := normalizeCloneBody(r.Header.Get("Content-Type"), body)
+	if errs != nil {
+		log.Printf("rejecting invalid clone of template %s: %v", template, errs)
+		p.retries.charge(caller, time.Now())
+		writeParamErrors(w, errs)
+		return
 	}
 
+	req := &cloneRequest{
+		w:          w,
+		r:          r,
+		body:       []byte(values.Encode()),
+		node:       node,
+		template:   template,
+		caller:     caller,
+		newID:      newID,
+		postConfig: postConfig,
+		queuedAt:   time.Now(),
+		done:       make(chan struct{}),
+		removed:    make(chan string, 1),
+	}
+
+	if err := p.enqueue(req); err != nil {
+		log.Printf("rejecting clone of template %s: %v", template, err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	select {
-	case p.queue <- req:
-		<-req.done
-	default:
-		log.Printf("clone queue full (size=%d)", cap(p.queue))
-		http.Error(w, "clone queue full", http.StatusServiceUnavailable)
+	case <-req.done:
+	case reason := <-req.removed:
+		http.Error(w, reason, http.StatusServiceUnavailable)
 	}
 }
 
-func (p *cloneProxy) worker() {
-	for req := range p.queue {
+// enqueue adds req to its template's queue, starting that template's worker
+// on first use.
+func (p *cloneProxy) enqueue(req *cloneRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errors.New("clone proxy shutting down")
+	}
+	if p.pending >= p.queueSize {
+		return fmt.Errorf("clone queue full (size=%d)", p.queueSize)
+	}
+
+	queue := p.queueLocked(req.template)
+	p.nextID++
+	req.id = fmt.Sprintf("c%d", p.nextID)
+	p.pending++
+	queue.waiting = append(queue.waiting, req)
+	p.wake.Broadcast()
+	return nil
+}
+
+// next blocks until queue has a clone to run and is not paused, and marks
+// it running. It returns nil once the proxy is closed and the queue is
+// drained; a paused queue is still drained on close.
+func (p *cloneProxy) next(queue *templateQueue) *cloneRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(queue.waiting) == 0 || (queue.paused && !p.closed) {
+		if p.closed && len(queue.waiting) == 0 {
+			return nil
+		}
+		p.wake.Wait()
+	}
+	req := queue.waiting[0]
+	queue.waiting = queue.waiting[1:]
+	queue.running = req
+	req.startedAt = time.Now()
+	return req
+}
+
+// worker processes one template's clones in order.
+func (p *cloneProxy) worker(queue *templateQueue) {
+	defer p.workers.Done()
+	for {
+		req := p.next(queue)
+		if req == nil {
+			return
+		}
 		p.processClone(req)
 		close(req.done)
+
+		p.mu.Lock()
+		queue.running = nil
+		p.pending--
+		p.mu.Unlock()
 	}
 }
 
-func (p *cloneProxy) processClone(req *cloneRequest) {
-	start := time.Now()
+// close stops accepting clones and waits until the queued ones finish or
+// ctx is done.
+func (p *cloneProxy) close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.wake.Broadcast()
+	p.mu.Unlock()
 
-	upstreamURL := p.joinURL(req.r.URL)
-	upstreamReq, err := http.NewRequestWithContext(req.r.Context(), req.r.Method, upstreamURL.String(), bytes.NewReader(req.body))
-	if err != nil {
-		http.Error(req.w, "failed to build upstream request", http.StatusBadRequest)
-		return
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	upstreamReq.ContentLength = int64(len(req.body))
-	upstreamReq.Host = p.target.Host
-	copyHeaders(upstreamReq.Header, req.r.Header)
-	addForwardHeaders(upstreamReq, req.r)
+}
 
-	resp, err := p.httpClient.Do(upstreamReq)
-	if err != nil {
-		log.Printf("clone request failed: %v", err)
-		http.Error(req.w, "upstream unavailable", http.StatusBadGateway)
-		return
+func (p *cloneProxy) processClone(req *cloneRequest) {
+	if wait := p.cooldownWait(req.template); wait > 0 {
+		log.Printf("template %s cooling down, delaying clone by %s", req.template, wait.Round(time.Millisecond))
+		select {
+		case <-req.r.Context().Done():
+			log.Printf("client went away during cooldown for template %s: %v", req.template, req.r.Context().Err())
+			return
+		case <-time.After(wait):
+		}
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("failed reading upstream response: %v", err)
-		http.Error(req.w, "failed to read upstream response", http.StatusBadGateway)
-		return
+	start := time.Now()
+	p.stats.start(req.template, start)
+	failed := true
+	// Only failures the caller caused cost retry budget; see callerAtFault.
+	charge := false
+	defer func() {
+		p.stats.finish(req.template, time.Since(start), failed)
+		if charge {
+			p.retries.charge(req.caller, time.Now())
+		}
+		if p.sim != nil {
+			p.sim.record(start.Sub(req.queuedAt), time.Since(start), failed)
+		}
+	}()
+
+	var (
+		resp     *http.Response
+		respBody []byte
+	)
+	for attempt := 0; ; attempt++ {
+		var ok bool
+		if resp, respBody, ok = p.callClone(req); !ok {
+			return
+		}
+
+		if !errors.Is(cloneError(resp, respBody), pve.ErrLocked) {
+			break
+		}
+		if attempt >= p.lockRetries {
+			exhausted := p.lockRetriesExhausted.Add(1)
+			log.Printf("clone of template %s still locked after %d retries: %s (lock_retries_total=%d lock_retries_exhausted=%d)",
+				req.template, attempt, resp.Status, p.lockRetriesTotal.Load(), exhausted)
+			break
+		}
+
+		delay := p.lockRetryDelay(attempt)
+		total := p.lockRetriesTotal.Add(1)
+		log.Printf("clone of template %s hit lock error: %s; retry %d/%d in %s (lock_retries_total=%d)",
+			req.template, resp.Status, attempt+1, p.lockRetries, delay.Round(time.Millisecond), total)
+		select {
+		case <-req.r.Context().Done():
+			log.Printf("client went away while retrying clone of template %s: %v", req.template, req.r.Context().Err())
+			return
+		case <-time.After(delay):
+		}
 	}
 
 	// If the clone call failed, return immediately.
 	if resp.StatusCode >= 400 {
+		charge = callerAtFault(resp, respBody)
 		copyResponseHeaders(req.w.Header(), resp.Header)
 		req.w.WriteHeader(resp.StatusCode)
 		if _, err := req.w.Write(respBody); err != nil {
@@ -220,8 +504,12 @@ func (p *cloneProxy) processClone(req *cloneRequest) {
 		return
 	}
 
-	upid := extractUPID(respBody)
+	var upid string
+	if data, err := pve.DecodeEnvelope(respBody); err == nil {
+		upid = pve.ExtractUPID(data)
+	}
 	if upid == "" {
+		failed = false
 		copyResponseHeaders(req.w.Header(), resp.Header)
 		req.w.WriteHeader(resp.StatusCode)
 		if _, err := req.w.Write(respBody); err != nil {
@@ -230,28 +518,49 @@ func (p *cloneProxy) processClone(req *cloneRequest) {
 		return
 	}
 
-	authHeaders := cloneAuthHeaders(req.r.Header)
-	status, exitStatus, timedOut := p.waitForTask(req.node, upid, authHeaders)
+	api := p.api.WithAuth(pve.ForwardedAuth(req.r.Header))
+	task, err := p.waitForTask(api, req.node, upid, p.pollTimeout)
 	duration := time.Since(start)
 
-	if timedOut {
+	if err != nil {
 		// Clone task is still running on PVE. Return an error to the client
 		// but keep blocking until the task finishes to maintain serialization.
 		log.Printf("clone task %s poll timed out after %s, waiting indefinitely for task completion", upid, duration)
-		http.Error(req.w, "clone task poll timed out, task may still be running", http.StatusGatewayTimeout)
+		if wantsResolvedTask(req) {
+			p.writeTaskResult(req, http.StatusGatewayTimeout, upid, "running", "", start, nil)
+		} else {
+			http.Error(req.w, "clone task poll timed out, task may still be running", http.StatusGatewayTimeout)
+		}
 
 		// Continue polling without timeout to ensure we don't release the queue
 		// slot until the clone task actually finishes on PVE.
-		finalStatus, finalExitStatus := p.waitForTaskIndefinitely(req.node, upid, authHeaders)
+		final, err := p.waitForTask(api, req.node, upid, 0)
+		failed = err != nil || taskFailed(final)
+		charge = err == nil && taskFailedForCaller(upid, final)
 		finalDuration := time.Since(start)
-		log.Printf("clone task %s eventually finished status=%s exitstatus=%s (duration=%s)", upid, finalStatus, finalExitStatus, finalDuration)
+		log.Printf("clone task %s eventually finished status=%s exitstatus=%s (duration=%s)", upid, final.Status, final.ExitStatus, finalDuration)
 		return
 	}
 
-	if status != "" {
-		log.Printf("clone task %s finished status=%s exitstatus=%s (duration=%s)", upid, status, exitStatus, duration)
-	} else {
-		log.Printf("clone task %s finished (duration=%s)", upid, duration)
+	log.Printf("clone task %s finished status=%s exitstatus=%s (duration=%s)", upid, task.Status, task.ExitStatus, duration)
+	failed = taskFailed(task)
+	charge = taskFailedForCaller(upid, task)
+
+	// The post-clone step runs before responding so the template's queue
+	// slot stays held through config and start.
+	if req.postConfig != nil && !failed {
+		post := p.applyPostConfig(req)
+		code := resp.StatusCode
+		if post.Error != "" {
+			code = http.StatusBadGateway
+		}
+		p.writeTaskResult(req, code, upid, task.Status, task.ExitStatus, start, &post)
+		return
+	}
+
+	if wantsResolvedTask(req) {
+		p.writeTaskResult(req, resp.StatusCode, upid, task.Status, task.ExitStatus, start, nil)
+		return
 	}
 
 	copyResponseHeaders(req.w.Header(), resp.Header)
@@ -261,134 +570,160 @@ func (p *cloneProxy) processClone(req *cloneRequest) {
 	}
 }
 
-func (p *cloneProxy) waitForTask(node, upid string, authHeaders http.Header) (status string, exitStatus string, timedOut bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), p.pollTimeout)
-	defer cancel()
-
-	statusURL := p.taskStatusURL(node, upid)
+// callClone makes one clone call upstream, or to the simulator in simulate
+// mode. On failure it has already responded to the client and returns false.
+func (p *cloneProxy) callClone(req *cloneRequest) (*http.Response, []byte, bool) {
+	if p.sim != nil {
+		resp, body := p.sim.clone(req)
+		return resp, body, true
+	}
 
-	ticker := time.NewTicker(p.pollInterval)
-	defer ticker.Stop()
+	upstreamReq, err := p.newUpstreamCloneRequest(req)
+	if err != nil {
+		http.Error(req.w, "failed to build upstream request", http.StatusBadRequest)
+		return nil, nil, false
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("poll timeout for %s: %v", upid, ctx.Err())
-			return "", "", true
-		case <-ticker.C:
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
-			if err != nil {
-				log.Printf("failed to build status request: %v", err)
-				continue
-			}
-			copyHeaders(req.Header, authHeaders)
-
-			resp, err := p.httpClient.Do(req)
-			if err != nil {
-				log.Printf("status poll failed for %s: %v", upid, err)
-				continue
-			}
-
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				log.Printf("status response read failed for %s: %v", upid, err)
-				continue
-			}
-
-			if resp.StatusCode >= 300 {
-				log.Printf("status poll returned %d for %s: %s", resp.StatusCode, upid, strings.TrimSpace(string(body)))
-				continue
-			}
-
-			s, es := parseTaskStatus(body)
-			if s == "running" {
-				continue
-			}
-
-			if s == "" {
-				s = "unknown"
-			}
-			return s, es, false
-		}
+	resp, err := p.httpClient.Do(upstreamReq)
+	if err != nil {
+		log.Printf("clone request failed: %v", err)
+		http.Error(req.w, "upstream unavailable", http.StatusBadGateway)
+		return nil, nil, false
 	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		log.Printf("failed reading upstream response: %v", err)
+		http.Error(req.w, "failed to read upstream response", http.StatusBadGateway)
+		return nil, nil, false
+	}
+	return resp, body, true
 }
 
-// waitForTaskIndefinitely polls the task status without any timeout.
-// This is used after the initial poll timeout to ensure we don't release
-// the queue slot while the clone task is still running on PVE.
-func (p *cloneProxy) waitForTaskIndefinitely(node, upid string, authHeaders http.Header) (string, string) {
-	statusURL := p.taskStatusURL(node, upid)
-
-	ticker := time.NewTicker(p.pollInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), p.httpClient.Timeout)
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
-		if err != nil {
-			cancel()
-			log.Printf("failed to build status request: %v", err)
-			continue
-		}
-		copyHeaders(req.Header, authHeaders)
+// taskFailed reports whether a finished task ended with a failure exit status.
+func taskFailed(task pve.Task) bool {
+	return task.ExitStatus != "" && task.ExitStatus != "OK"
+}
 
-		resp, err := p.httpClient.Do(req)
-		if err != nil {
-			cancel()
-			log.Printf("indefinite poll failed for %s: %v", upid, err)
-			continue
-		}
+func (p *cloneProxy) newUpstreamCloneRequest(req *cloneRequest) (*http.Request, error) {
+	upstreamURL := p.joinURL(req.r.URL)
+	upstreamReq, err := http.NewRequestWithContext(req.r.Context(), req.r.Method, upstreamURL.String(), bytes.NewReader(req.body))
+	if err != nil {
+		return nil, err
+	}
+	upstreamReq.ContentLength = int64(len(req.body))
+	upstreamReq.Host = p.target.Host
+	copyHeaders(upstreamReq.Header, req.r.Header)
+	upstreamReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	upstreamReq.Header.Del(resolveTaskHeader)
+	upstreamReq.Header.Del(postConfigHeader)
+	addForwardHeaders(upstreamReq, req.r)
+	return upstreamReq, nil
+}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		cancel()
-		if err != nil {
-			log.Printf("indefinite poll response read failed for %s: %v", upid, err)
-			continue
-		}
+// wantsResolvedTask reports whether the caller wants a taskResult response.
+// A post-clone step implies it, since its outcome has no raw PVE equivalent.
+func wantsResolvedTask(req *cloneRequest) bool {
+	if req.postConfig != nil {
+		return true
+	}
+	v := strings.TrimSpace(req.r.Header.Get(resolveTaskHeader))
+	return v == "1" || strings.EqualFold(v, "true")
+}
 
-		if resp.StatusCode >= 300 {
-			log.Printf("indefinite poll returned %d for %s: %s", resp.StatusCode, upid, strings.TrimSpace(string(body)))
-			continue
-		}
+// writeTaskResult responds with the clone's final task state. start is when
+// the worker picked up the request, so elapsed excludes queue wait. post is
+// the post-clone step's outcome, if one ran.
+func (p *cloneProxy) writeTaskResult(req *cloneRequest, code int, upid, status, exitStatus string, start time.Time, post *postConfigResult) {
+	result := taskResult{
+		UPID:        upid,
+		Node:        req.node,
+		VMID:        req.newID,
+		Status:      status,
+		ExitStatus:  exitStatus,
+		ElapsedMs:   time.Since(start).Milliseconds(),
+		QueueWaitMs: start.Sub(req.queuedAt).Milliseconds(),
+		PostConfig:  post,
+	}
+	body, err := json.Marshal(map[string]taskResult{"data": result})
+	if err != nil {
+		http.Error(req.w, "failed to encode task result", http.StatusInternalServerError)
+		return
+	}
+	req.w.Header().Set("Content-Type", "application/json")
+	req.w.WriteHeader(code)
+	if _, err := req.w.Write(body); err != nil {
+		log.Printf("failed writing task result to client: %v", err)
+	}
+}
 
-		status, exitStatus := parseTaskStatus(body)
-		if status == "running" {
-			continue
-		}
+// lockRetryDelay returns the exponential backoff for the given attempt with
+// +/-50% jitter, so callers retrying the same template don't stay in lockstep.
+func (p *cloneProxy) lockRetryDelay(attempt int) time.Duration {
+	delay := p.lockBackoff
+	for i := 0; i < attempt && delay < maxLockRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxLockRetryBackoff {
+		delay = maxLockRetryBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)))
+}
 
-		if status == "" {
-			status = "unknown"
+// cloneError classifies PVE's error response to a clone call the way
+// pve-go does, or returns nil if the call succeeded. errors.Is(err,
+// pve.ErrLocked) holds when the source template or target config is locked,
+// e.g. "can't lock file '/run/lock/lxc/pve-config-100.lock' - got timeout" or
+// "CT is locked (clone)". PVE usually returns these as 500 with the message
+// only in the status line, so that is classified when the body says nothing.
+func cloneError(resp *http.Response, body []byte) error {
+	switch {
+	case resp.StatusCode < 400:
+		return nil
+	case resp.StatusCode == http.StatusConflict, resp.StatusCode == http.StatusLocked:
+		return fmt.Errorf("%s: %w", resp.Status, pve.ErrLocked)
+	}
+	err := pve.ParseAPIError(resp.StatusCode, body)
+	if errors.Unwrap(err) == nil {
+		if kind := pve.ClassifyMessage(resp.Status); kind != nil {
+			return fmt.Errorf("%s: %w", resp.Status, kind)
 		}
-		return status, exitStatus
 	}
-
-	// Unreachable, but needed for compilation
-	return "unknown", ""
+	return err
 }
 
-func (p *cloneProxy) taskStatusURL(node, upid string) string {
-	escapedNode := url.PathEscape(node)
-	escapedUpid := url.PathEscape(upid)
-
-	// Build an escaped path without letting url.URL double-encode percent sequences
-	path := singleJoiningSlash(p.target.Path, "/api2/json/nodes/"+escapedNode+"/tasks/"+escapedUpid+"/status")
-
-	u := *p.target
-	u.RawQuery = ""
+// waitForTask polls the clone task until it stops, giving up after timeout
+// (zero waits indefinitely). Each poll is still bounded by the HTTP client
+// timeout. A task that stopped with a failed exit status is not an error
+// here; the caller reports the exit status as-is.
+func (p *cloneProxy) waitForTask(api *pve.Client, node, upid string, timeout time.Duration) (pve.Task, error) {
+	if p.sim != nil {
+		return p.sim.waitForTask(upid, timeout)
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
-	// Keep the escaped form in RawPath and the unescaped form in Path so
-	// EscapedPath uses the provided encoding instead of re-encoding the `%`.
-	unescapedPath, err := url.PathUnescape(path)
+	task, err := api.WaitForTask(ctx, node, upid, pve.WaitOptions{
+		Interval: p.pollInterval,
+		OnPollError: func(err error) {
+			log.Printf("status poll failed for %s: %v", upid, err)
+		},
+	})
+	var taskErr *pve.ErrTaskFailed
+	if errors.As(err, &taskErr) {
+		return task, nil
+	}
 	if err != nil {
-		// Fallback to the escaped path if unescape ever fails (should not happen)
-		unescapedPath = path
+		log.Printf("poll timeout for %s: %v", upid, err)
 	}
-	u.Path = unescapedPath
-	u.RawPath = path
-
-	return u.String()
+	return task, err
 }
 
 func (p *cloneProxy) joinURL(reqURL *url.URL) *url.URL {
@@ -402,39 +737,6 @@ func (p *cloneProxy) joinURL(reqURL *url.URL) *url.URL {
 	return &target
 }
 
-func extractUPID(body []byte) string {
-	var payload struct {
-		Data any `json:"data"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return ""
-	}
-
-	switch v := payload.Data.(type) {
-	case string:
-		return v
-	case map[string]any:
-		if upid, ok := v["upid"].(string); ok {
-			return upid
-		}
-	}
-	return ""
-}
-
-func parseTaskStatus(body []byte) (string, string) {
-	var payload struct {
-		Data struct {
-			Status     string `json:"status"`
-			ExitStatus string `json:"exitstatus"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		return "", ""
-	}
-
-	return payload.Data.Status, payload.Data.ExitStatus
-}
-
 func copyHeaders(dst, src http.Header) {
 	for k, vs := range src {
 		if _, skip := hopByHopHeaders[k]; skip {
@@ -457,18 +759,6 @@ func copyResponseHeaders(dst, src http.Header) {
 	}
 }
 
-func cloneAuthHeaders(src http.Header) http.Header {
-	dst := http.Header{}
-	for _, key := range []string{"Authorization", "Cookie", "CSRFPreventionToken", "Ticket"} {
-		if values, ok := src[key]; ok {
-			for _, v := range values {
-				dst.Add(key, v)
-			}
-		}
-	}
-	return dst
-}
-
 func addForwardHeaders(outReq, inReq *http.Request) {
 	clientIP, _, err := net.SplitHostPort(inReq.RemoteAddr)
 	if err == nil {
@@ -516,6 +806,14 @@ func mustParseInt(v string) int {
 	return n
 }
 
+func mustParseFloat(v string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		log.Fatalf("invalid float %q: %v", v, err)
+	}
+	return f
+}
+
 func singleJoiningSlash(a, b string) string {
 	aslash := strings.HasSuffix(a, "/")
 	bslash := strings.HasPrefix(b, "/")
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	pve "github.com/karlorz/pve-go"
+)
+
+// postConfigHeader carries an optional post-clone step as JSON. A JSON clone
+// body may carry the same object under postConfigKey instead.
+const (
+	postConfigHeader = "X-Cmux-Post-Config"
+	postConfigKey    = "post_config"
+)
+
+// postCloneConfig is applied to the new container after its clone finishes,
+// while the template's queue slot is still held:
+//
+//	{"config": {"memory": 8192, "cores": 4, "tags": "cmux"}, "start": true}
+type postCloneConfig struct {
+	Config url.Values
+	Start  bool
+}
+
+// postConfigResult reports the post-clone step in the combined response.
+type postConfigResult struct {
+	Configured []string `json:"configured,omitempty"` // Config keys applied
+	Started    bool     `json:"started,omitempty"`
+	StartUPID  string   `json:"start_upid,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	ElapsedMs  int64    `json:"elapsed_ms"`
+}
+
+// extractPostConfig reads the post-clone step from the header or, for JSON
+// bodies, the post_config key, which it strips from the returned body so the
+// clone parameters validate as before. It returns nil when none was given.
+func extractPostConfig(r *http.Request, body []byte) (*postCloneConfig, []byte, paramErrors) {
+	var raw json.RawMessage
+	source := postConfigHeader
+	if h := strings.TrimSpace(r.Header.Get(postConfigHeader)); h != "" {
+		raw = json.RawMessage(h)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(r.Header.Get("Content-Type")), "application/json") {
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(body, &payload); err == nil {
+			if section, ok := payload[postConfigKey]; ok {
+				if raw != nil {
+					return nil, nil, paramErrors{postConfigKey: "set either the " + postConfigHeader + " header or the body section, not both"}
+				}
+				raw, source = section, postConfigKey
+				delete(payload, postConfigKey)
+				if body, err = json.Marshal(payload); err != nil {
+					return nil, nil, paramErrors{"body": err.Error()}
+				}
+			}
+		}
+	}
+	if raw == nil {
+		return nil, body, nil
+	}
+
+	cfg, err := parsePostConfig(raw)
+	if err != nil {
+		return nil, nil, paramErrors{source: err.Error()}
+	}
+	return cfg, body, nil
+}
+
+func parsePostConfig(raw json.RawMessage) (*postCloneConfig, error) {
+	var spec struct {
+		Config map[string]any `json:"config"`
+		Start  bool           `json:"start"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid post-config JSON: %v", err)
+	}
+
+	cfg := &postCloneConfig{Config: url.Values{}, Start: spec.Start}
+	for key, value := range spec.Config {
+		switch v := value.(type) {
+		case string:
+			cfg.Config.Set(key, v)
+		case json.Number:
+			cfg.Config.Set(key, v.String())
+		case bool:
+			cfg.Config.Set(key, map[bool]string{true: "1", false: "0"}[v])
+		default:
+			return nil, fmt.Errorf("config %q must be a string, number or boolean", key)
+		}
+	}
+	if len(cfg.Config) == 0 && !cfg.Start {
+		return nil, errors.New("post-config needs config values, start, or both")
+	}
+	return cfg, nil
+}
+
+// applyPostConfig PUTs the config and optionally starts the new container,
+// retrying lock errors like the clone itself. The clone task has already
+// succeeded, so failures here leave a stopped, possibly unconfigured
+// container behind; the result says which step failed.
+func (p *cloneProxy) applyPostConfig(req *cloneRequest) postConfigResult {
+	start := time.Now()
+	result := postConfigResult{}
+
+	finish := func(err error) postConfigResult {
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("post-config of CT %d (template %s) failed: %v", req.newID, req.template, err)
+		} else {
+			log.Printf("post-config of CT %d (template %s) done: configured=%v started=%t", req.newID, req.template, result.Configured, result.Started)
+		}
+		result.ElapsedMs = time.Since(start).Milliseconds()
+		return result
+	}
+
+	if p.sim != nil {
+		// Nothing to configure in simulate mode; report what would be done.
+		result.Configured = sortedKeys(req.postConfig.Config)
+		result.Started = req.postConfig.Start
+		return finish(nil)
+	}
+
+	api := p.api.WithAuth(pve.ForwardedAuth(req.r.Header))
+	ctx, cancel := context.WithTimeout(context.Background(), p.pollTimeout)
+	defer cancel()
+
+	if len(req.postConfig.Config) > 0 {
+		err := p.retryLocked(ctx, "config", req, func() error {
+			return api.UpdateLXCConfig(ctx, req.node, req.newID, req.postConfig.Config)
+		})
+		if err != nil {
+			return finish(fmt.Errorf("config update: %w", err))
+		}
+		result.Configured = sortedKeys(req.postConfig.Config)
+	}
+
+	if req.postConfig.Start {
+		var upid string
+		err := p.retryLocked(ctx, "start", req, func() error {
+			var err error
+			upid, err = api.StartLXC(ctx, req.node, req.newID)
+			return err
+		})
+		if err != nil {
+			return finish(fmt.Errorf("start: %w", err))
+		}
+		if upid != "" {
+			result.StartUPID = upid
+			task, err := p.waitForTask(api, req.node, upid, p.pollTimeout)
+			if err != nil {
+				return finish(fmt.Errorf("start: %w", err))
+			}
+			if taskFailed(task) {
+				return finish(fmt.Errorf("start: task exited with %s", task.ExitStatus))
+			}
+		}
+		result.Started = true
+	}
+	return finish(nil)
+}
+
+// retryLocked runs call, retrying with the clone's lock backoff while PVE
+// reports the new container as locked.
+func (p *cloneProxy) retryLocked(ctx context.Context, step string, req *cloneRequest, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || !errors.Is(err, pve.ErrLocked) || attempt >= p.lockRetries {
+			return err
+		}
+		delay := p.lockRetryDelay(attempt)
+		total := p.lockRetriesTotal.Add(1)
+		log.Printf("post-config %s of CT %d hit lock error: %v; retry %d/%d in %s (lock_retries_total=%d)",
+			step, req.newID, err, attempt+1, p.lockRetries, delay.Round(time.Millisecond), total)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
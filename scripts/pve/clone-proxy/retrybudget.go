@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pve "github.com/karlorz/pve-go"
+)
+
+// retryBudgetReason is set in X-Cmux-Reject-Reason on 429s from the retry
+// budget, so callers can tell them apart from PVE's own responses.
+const retryBudgetReason = "retry-budget-exceeded"
+
+// callerIdentity names the caller behind an Authorization header without
+// keeping its secret: the token ID for PVE API tokens, a short hash
+// otherwise.
+func callerIdentity(h http.Header) string {
+	auth := strings.TrimSpace(h.Get("Authorization"))
+	if auth == "" {
+		return "anonymous"
+	}
+	if rest, ok := strings.CutPrefix(auth, "PVEAPIToken="); ok {
+		if i := strings.LastIndex(rest, "="); i > 0 {
+			return rest[:i]
+		}
+	}
+	sum := sha256.Sum256([]byte(auth))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// callerRetries is one caller's retry accounting.
+type callerRetries struct {
+	total    int64
+	rejected int64
+	recent   []time.Time // Within the budget window, oldest first
+}
+
+type callerSnapshot struct {
+	RetriesTotal    int64 `json:"retries_total"`
+	RetriesInWindow int   `json:"retries_in_window"`
+	Rejected        int64 `json:"rejected"`
+}
+
+// retryBudget counts the retries each caller causes in a sliding window.
+// Only failures of the caller's own making cost one: clones rejected by
+// validation or by PVE, and clone tasks that fail. Lock retries and
+// unreachable upstreams are the proxy's or the cluster's problem and are
+// not charged. With a limit set, callers at the limit are rejected until
+// old retries age out of the window.
+type retryBudget struct {
+	limit  int // Zero only tracks
+	window time.Duration
+
+	mu      sync.Mutex
+	callers map[string]*callerRetries
+}
+
+func newRetryBudget(limit int, window time.Duration) *retryBudget {
+	return &retryBudget{limit: limit, window: window, callers: make(map[string]*callerRetries)}
+}
+
+func (b *retryBudget) get(caller string, now time.Time) *callerRetries {
+	c, ok := b.callers[caller]
+	if !ok {
+		c = &callerRetries{}
+		b.callers[caller] = c
+	}
+	c.recent = pruneBefore(c.recent, now.Add(-b.window))
+	return c
+}
+
+// charge records one retry by caller.
+func (b *retryBudget) charge(caller string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.get(caller, now)
+	c.total++
+	c.recent = append(c.recent, now)
+}
+
+// callerAtFault reports whether PVE's error response to a clone call is
+// down to the caller's request. Lock errors (retried or not) and gateway
+// errors on the way to PVE say nothing about the request.
+func callerAtFault(resp *http.Response, body []byte) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return false
+	}
+	err := cloneError(resp, body)
+	return err != nil && !errors.Is(err, pve.ErrLocked)
+}
+
+// taskFailedForCaller reports whether a finished clone task failed in a way
+// the caller is charged for. Tasks that fail on a config lock are
+// contention between callers, not a bad request.
+func taskFailedForCaller(upid string, task pve.Task) bool {
+	return taskFailed(task) && !errors.Is(pve.NewTaskFailedError(upid, task.ExitStatus), pve.ErrLocked)
+}
+
+// admit reports whether caller is within its budget, counting a rejection
+// if not. When rejected, retryAfter is when the oldest retry in the window
+// expires.
+func (b *retryBudget) admit(caller string, now time.Time) (ok bool, retryAfter time.Duration) {
+	if b.limit <= 0 {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.get(caller, now)
+	if len(c.recent) < b.limit {
+		return true, 0
+	}
+	c.rejected++
+	return false, c.recent[len(c.recent)-b.limit].Add(b.window).Sub(now)
+}
+
+func (b *retryBudget) snapshot(now time.Time) map[string]callerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[string]callerSnapshot, len(b.callers))
+	for caller := range b.callers {
+		c := b.get(caller, now)
+		out[caller] = callerSnapshot{RetriesTotal: c.total, RetriesInWindow: len(c.recent), Rejected: c.rejected}
+	}
+	return out
+}
+
+// writeRetryBudgetExceeded rejects a caller over its retry budget with a
+// PVE-style error body.
+func writeRetryBudgetExceeded(w http.ResponseWriter, caller string, limit int, window, retryAfter time.Duration) {
+	msg := fmt.Sprintf("caller %s used its retry budget (%d retries per %s); fix the failing clone requests or wait", caller, limit, window)
+	body, _ := json.Marshal(map[string]any{"data": nil, "errors": map[string]string{"retry_budget": msg}})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cmux-Reject-Reason", retryBudgetReason)
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pve "github.com/karlorz/pve-go"
+)
+
+// taskStatusPathPattern matches the PVE task status endpoint, which the
+// simulator answers for its own synthetic UPIDs.
+var taskStatusPathPattern = regexp.MustCompile(`^/api2/json/nodes/([^/]+)/tasks/([^/]+)/status/?$`)
+
+// simTaskRetention is how long finished synthetic tasks stay queryable.
+const simTaskRetention = time.Hour
+
+// durationDist is a distribution of synthetic clone task durations, parsed
+// from specs such as "fixed:30s", "uniform:20s-60s", "normal:40s,10s" or
+// "lognormal:40s,0.5" (median and sigma of the underlying normal).
+type durationDist struct {
+	spec string
+	kind string
+	a, b float64 // Seconds, except sigma for lognormal
+}
+
+func parseDurationDist(spec string) (durationDist, error) {
+	kind, args, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return durationDist{}, fmt.Errorf("invalid duration distribution %q (want kind:args)", spec)
+	}
+	d := durationDist{spec: spec, kind: kind}
+	seconds := func(v string) (float64, error) {
+		parsed, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || parsed < 0 {
+			return 0, fmt.Errorf("invalid duration %q in %q", v, spec)
+		}
+		return parsed.Seconds(), nil
+	}
+
+	var err error
+	switch kind {
+	case "fixed":
+		d.a, err = seconds(args)
+	case "uniform":
+		lo, hi, found := strings.Cut(args, "-")
+		if !found {
+			return d, fmt.Errorf("uniform distribution needs min-max, got %q", spec)
+		}
+		if d.a, err = seconds(lo); err == nil {
+			d.b, err = seconds(hi)
+		}
+		if err == nil && d.b < d.a {
+			err = fmt.Errorf("uniform max below min in %q", spec)
+		}
+	case "normal", "lognormal":
+		center, spread, found := strings.Cut(args, ",")
+		if !found {
+			return d, fmt.Errorf("%s distribution needs center,spread, got %q", kind, spec)
+		}
+		if d.a, err = seconds(center); err != nil {
+			break
+		}
+		if kind == "normal" {
+			d.b, err = seconds(spread)
+		} else if d.b, err = strconv.ParseFloat(strings.TrimSpace(spread), 64); err != nil || d.b < 0 {
+			err = fmt.Errorf("invalid sigma %q in %q", spread, spec)
+		}
+	default:
+		return d, fmt.Errorf("unknown duration distribution %q (use fixed, uniform, normal or lognormal)", kind)
+	}
+	return d, err
+}
+
+func (d durationDist) String() string { return d.spec }
+
+// sample draws one duration. Negative draws from the normal distribution are
+// clamped to zero.
+func (d durationDist) sample(rng *rand.Rand) time.Duration {
+	var secs float64
+	switch d.kind {
+	case "fixed":
+		secs = d.a
+	case "uniform":
+		secs = d.a + rng.Float64()*(d.b-d.a)
+	case "normal":
+		secs = d.a + rng.NormFloat64()*d.b
+	case "lognormal":
+		secs = d.a * math.Exp(rng.NormFloat64()*d.b)
+	}
+	if secs < 0 {
+		secs = 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// simulatorConfig holds the CLONE_PROXY_SIMULATE_* settings.
+type simulatorConfig struct {
+	duration durationDist
+	lockRate float64 // Probability a clone call is rejected with a lock error
+	failRate float64 // Probability a synthetic task exits with an error
+	seed     int64   // Zero seeds from the clock
+}
+
+// simulator stands in for PVE when CLONE_PROXY_SIMULATE is true. Clone
+// calls get synthetic lock errors or UPIDs whose tasks finish after a
+// sampled duration, so queueing, cooldown and lock retries behave as they
+// would against a real host without contacting one.
+type simulator struct {
+	cfg simulatorConfig
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	seq   int64
+	tasks map[string]simTask
+
+	// Results for the report.
+	clones      int64
+	failed      int64
+	lockErrors  int64
+	queueWaits  []time.Duration
+	elapsed     []time.Duration
+	firstCall   time.Time
+	lastFinish  time.Time
+	maxInFlight int
+	inFlight    int
+}
+
+type simTask struct {
+	node       string
+	end        time.Time
+	exitStatus string
+}
+
+func newSimulator(cfg simulatorConfig) *simulator {
+	seed := cfg.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &simulator{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewSource(seed)),
+		tasks: make(map[string]simTask),
+	}
+}
+
+// clone answers one clone call the way PVE would: either a lock error or a
+// {"data": "UPID:..."} body for a new synthetic task.
+func (s *simulator) clone(req *cloneRequest) (*http.Response, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.firstCall.IsZero() {
+		s.firstCall = time.Now()
+	}
+	if s.rng.Float64() < s.cfg.lockRate {
+		s.lockErrors++
+		status := fmt.Sprintf("can't lock file '/run/lock/lxc/pve-config-%s.lock' - got timeout", req.template)
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Status:     "500 " + status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+		}, []byte(`{"data":null}`)
+	}
+
+	now := time.Now()
+	s.seq++
+	upid := fmt.Sprintf("UPID:%s:%08X:%08X:%08X:vzclone:%s:root@pam:", req.node, s.seq, s.seq, now.Unix(), req.template)
+	task := simTask{node: req.node, end: now.Add(s.cfg.duration.sample(s.rng)), exitStatus: "OK"}
+	if s.rng.Float64() < s.cfg.failRate {
+		task.exitStatus = "clone failed: simulated storage error"
+	}
+	for id, t := range s.tasks {
+		if now.Sub(t.end) > simTaskRetention {
+			delete(s.tasks, id)
+		}
+	}
+	s.tasks[upid] = task
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+
+	body, _ := json.Marshal(map[string]string{"data": upid})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, body
+}
+
+// state returns the PVE view of a synthetic task at now.
+func (t simTask) state(now time.Time) pve.Task {
+	if now.Before(t.end) {
+		return pve.Task{Status: "running"}
+	}
+	return pve.Task{Status: "stopped", ExitStatus: t.exitStatus}
+}
+
+// waitForTask blocks until the synthetic task finishes, giving up after
+// timeout (zero waits indefinitely) like cloneProxy.waitForTask.
+func (s *simulator) waitForTask(upid string, timeout time.Duration) (pve.Task, error) {
+	s.mu.Lock()
+	task, ok := s.tasks[upid]
+	s.mu.Unlock()
+	if !ok {
+		return pve.Task{}, fmt.Errorf("unknown simulated task %s", upid)
+	}
+
+	wait := time.Until(task.end)
+	if timeout > 0 && wait > timeout {
+		time.Sleep(timeout)
+		log.Printf("poll timeout for %s: %v", upid, context.DeadlineExceeded)
+		return task.state(time.Now()), context.DeadlineExceeded
+	}
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	s.mu.Lock()
+	if s.inFlight > 0 {
+		s.inFlight--
+	}
+	s.mu.Unlock()
+	return task.state(task.end), nil
+}
+
+// record adds one finished clone to the report.
+func (s *simulator) record(queueWait, elapsed time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clones++
+	if failed {
+		s.failed++
+	}
+	s.queueWaits = append(s.queueWaits, queueWait)
+	s.elapsed = append(s.elapsed, elapsed)
+	s.lastFinish = time.Now()
+}
+
+type latencySummary struct {
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+	P99Ms int64 `json:"p99_ms"`
+	MaxMs int64 `json:"max_ms"`
+}
+
+func summarizeLatencies(values []time.Duration) latencySummary {
+	if len(values) == 0 {
+		return latencySummary{}
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(q float64) int64 {
+		return sorted[int(math.Ceil(q*float64(len(sorted))))-1].Milliseconds()
+	}
+	return latencySummary{P50Ms: at(0.50), P95Ms: at(0.95), P99Ms: at(0.99), MaxMs: sorted[len(sorted)-1].Milliseconds()}
+}
+
+type simulationReport struct {
+	Duration    string         `json:"duration_distribution"`
+	LockRate    float64        `json:"lock_rate"`
+	FailRate    float64        `json:"fail_rate"`
+	Clones      int64          `json:"clones"`
+	Failed      int64          `json:"failed"`
+	LockErrors  int64          `json:"lock_errors_injected"`
+	MaxInFlight int            `json:"max_in_flight"`
+	ClonesPerHr float64        `json:"clones_per_hour"`
+	QueueWait   latencySummary `json:"queue_wait"`
+	Elapsed     latencySummary `json:"elapsed"`
+}
+
+// report summarizes the clones finished so far. Throughput runs from the
+// first clone call to the last finished clone.
+func (s *simulator) report() simulationReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := simulationReport{
+		Duration:    s.cfg.duration.String(),
+		LockRate:    s.cfg.lockRate,
+		FailRate:    s.cfg.failRate,
+		Clones:      s.clones,
+		Failed:      s.failed,
+		LockErrors:  s.lockErrors,
+		MaxInFlight: s.maxInFlight,
+		QueueWait:   summarizeLatencies(s.queueWaits),
+		Elapsed:     summarizeLatencies(s.elapsed),
+	}
+	if span := s.lastFinish.Sub(s.firstCall); s.clones > 0 && span > 0 {
+		r.ClonesPerHr = float64(s.clones) / span.Hours()
+	}
+	return r
+}
+
+func (s *simulator) serveReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.report())
+}
+
+// serveTaskStatus answers GET .../tasks/<upid>/status for synthetic tasks so
+// clients that poll on their own keep working in simulate mode.
+func (s *simulator) serveTaskStatus(w http.ResponseWriter, r *http.Request, upid string) {
+	s.mu.Lock()
+	task, ok := s.tasks[pve.NormalizeUPID(upid)]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "no such task", http.StatusNotFound)
+		return
+	}
+	state := task.state(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+		"upid":       pve.NormalizeUPID(upid),
+		"node":       task.node,
+		"status":     state.Status,
+		"exitstatus": state.ExitStatus,
+	}})
+}
+
+// serveSimulated handles non-clone requests in simulate mode, which must
+// never reach PVE.
+func (p *cloneProxy) serveSimulated(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if r.URL.Path == "/simulate" {
+			p.sim.serveReport(w, r)
+			return
+		}
+		if matches := taskStatusPathPattern.FindStringSubmatch(r.URL.Path); matches != nil {
+			p.sim.serveTaskStatus(w, r, matches[2])
+			return
+		}
+	}
+	http.Error(w, "not available in simulate mode", http.StatusNotImplemented)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlorz/cmux/scripts/pve/clone-proxy/internal/testsupport"
+)
+
+func TestParseDurationDist(t *testing.T) {
+	for spec, wantErr := range map[string]bool{
+		"fixed:30s":         false,
+		"uniform:20s-60s":   false,
+		"normal:40s,10s":    false,
+		"lognormal:40s,0.4": false,
+		"fixed":             true,
+		"fixed:-1s":         true,
+		"uniform:60s-20s":   true,
+		"uniform:20s":       true,
+		"normal:40s":        true,
+		"lognormal:40s,-1":  true,
+		"lognormal:40s,abc": true,
+		"poisson:40s":       true,
+	} {
+		d, err := parseDurationDist(spec)
+		if (err != nil) != wantErr {
+			t.Errorf("parseDurationDist(%q) error = %v, want error %t", spec, err, wantErr)
+		}
+		if err == nil && d.String() != spec {
+			t.Errorf("String() = %q, want %q", d.String(), spec)
+		}
+	}
+}
+
+func TestDurationDistSample(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sample := func(spec string, n int) []time.Duration {
+		d, err := parseDurationDist(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]time.Duration, n)
+		for i := range out {
+			out[i] = d.sample(rng)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+		return out
+	}
+
+	if got := sample("fixed:30s", 1)[0]; got != 30*time.Second {
+		t.Errorf("fixed sample = %s", got)
+	}
+	if got := sample("uniform:20s-60s", 1000); got[0] < 20*time.Second || got[len(got)-1] > 60*time.Second {
+		t.Errorf("uniform samples span %s-%s, want within 20s-60s", got[0], got[len(got)-1])
+	}
+	if got := sample("normal:0s,10s", 100); got[0] != 0 {
+		t.Errorf("normal sample %s not clamped to zero", got[0])
+	}
+	if median := sample("lognormal:40s,0.4", 2001)[1000]; median < 36*time.Second || median > 44*time.Second {
+		t.Errorf("lognormal median = %s, want about 40s", median)
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	if got := summarizeLatencies(nil); got != (latencySummary{}) {
+		t.Errorf("empty summary = %+v", got)
+	}
+	var values []time.Duration
+	for i := 100; i >= 1; i-- {
+		values = append(values, time.Duration(i)*time.Millisecond)
+	}
+	want := latencySummary{P50Ms: 50, P95Ms: 95, P99Ms: 99, MaxMs: 100}
+	if got := summarizeLatencies(values); got != want {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+}
+
+// newSimulatedProxy starts a proxy in simulate mode whose target is fake, so
+// a test can check nothing reached it.
+func newSimulatedProxy(t *testing.T, fake *testsupport.FakePVE, sim simulatorConfig) (*cloneProxy, *httptest.Server) {
+	t.Helper()
+	sim.seed = 1
+	return newTestProxy(t, fake, func(cfg *config) { cfg.simulate = &sim })
+}
+
+func TestIntegrationSimulateMode(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	dist, _ := parseDurationDist("fixed:5ms")
+	proxy, server := newSimulatedProxy(t, fake, simulatorConfig{duration: dist})
+
+	var upid string
+	for i := 0; i < 3; i++ {
+		resp := postClone(t, server, "9000", 200+i, map[string]string{resolveTaskHeader: "1"})
+		if resp.status != http.StatusOK {
+			t.Fatalf("clone %d: %d %s", i, resp.status, resp.body)
+		}
+		result := resp.taskResult(t)
+		if result.Status != "stopped" || result.ExitStatus != "OK" || !strings.Contains(result.UPID, ":vzclone:9000:") {
+			t.Errorf("clone %d result = %+v", i, result)
+		}
+		upid = result.UPID
+	}
+
+	// Clients polling the task themselves get the simulated status.
+	var status struct {
+		Data map[string]string `json:"data"`
+	}
+	if code := adminCall(t, server, http.MethodGet, "/api2/json/nodes/pve/tasks/"+upid+"/status", "", &status); code != http.StatusOK || status.Data["exitstatus"] != "OK" {
+		t.Errorf("task status: %d %v", code, status)
+	}
+	if code := adminCall(t, server, http.MethodGet, "/api2/json/nodes/pve/tasks/UPID:pve:nope/status", "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown task status: %d, want 404", code)
+	}
+	if code := adminCall(t, server, http.MethodGet, "/api2/json/nodes/pve/lxc", "", nil); code != http.StatusNotImplemented {
+		t.Errorf("other request: %d, want 501", code)
+	}
+
+	waitForSimulatedClones(t, proxy, 3)
+	var report simulationReport
+	if code := adminCall(t, server, http.MethodGet, "/simulate", "", &report); code != http.StatusOK {
+		t.Fatalf("/simulate: %d", code)
+	}
+	if report.Clones != 3 || report.Failed != 0 || report.LockErrors != 0 || report.MaxInFlight != 1 || report.Duration != "fixed:5ms" {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Elapsed.P50Ms < 5 || report.ClonesPerHr <= 0 {
+		t.Errorf("report latencies = %+v, throughput %g", report.Elapsed, report.ClonesPerHr)
+	}
+
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("simulate mode contacted PVE: %+v", calls)
+	}
+}
+
+func TestIntegrationSimulateLockErrorsAndFailures(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	dist, _ := parseDurationDist("fixed:0s")
+
+	proxy, server := newSimulatedProxy(t, fake, simulatorConfig{duration: dist, lockRate: 1})
+	// Like PVE, the simulator reports the lock in the status line.
+	if resp := postClone(t, server, "9000", 200, nil); resp.status != http.StatusInternalServerError {
+		t.Errorf("locked clone: %d %s, want 500", resp.status, resp.body)
+	}
+	if report := proxy.sim.report(); report.LockErrors != 4 {
+		t.Errorf("lock errors = %d, want 1 + 3 retries", report.LockErrors)
+	}
+	if got := proxy.lockRetriesExhausted.Load(); got != 1 {
+		t.Errorf("lock_retries_exhausted = %d, want 1", got)
+	}
+
+	proxy, server = newSimulatedProxy(t, fake, simulatorConfig{duration: dist, failRate: 1})
+	resp := postClone(t, server, "9000", 201, map[string]string{resolveTaskHeader: "1"})
+	if resp.status != http.StatusOK || resp.taskResult(t).ExitStatus != "clone failed: simulated storage error" {
+		t.Errorf("failing clone: %d %s", resp.status, resp.body)
+	}
+	waitForSimulatedClones(t, proxy, 1)
+	if report := proxy.sim.report(); report.Failed != 1 {
+		t.Errorf("report = %+v, want one failure", report)
+	}
+
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("simulate mode contacted PVE: %+v", calls)
+	}
+}
+
+// waitForSimulatedClones waits until the simulator has recorded n clones;
+// they are recorded after the response is written.
+func waitForSimulatedClones(t *testing.T, proxy *cloneProxy, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for proxy.sim.report().Clones < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("simulator recorded %d clones, want %d", proxy.sim.report().Clones, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsWindow is how far back clone starts are kept for the per-template
+// recent clone count.
+const statsWindow = time.Hour
+
+// templateStats accumulates clone outcomes for one source template.
+type templateStats struct {
+	total        int64
+	failed       int64
+	completed    int64
+	durationSum  time.Duration
+	lastStart    time.Time
+	recentStarts []time.Time // Within statsWindow, oldest first
+}
+
+// cloneStats tracks per-template clone accounting for /stats and /metrics.
+type cloneStats struct {
+	mu        sync.Mutex
+	templates map[string]*templateStats
+}
+
+type templateSnapshot struct {
+	Total          int64  `json:"total"`
+	Failed         int64  `json:"failed"`
+	AvgDurationMs  int64  `json:"avg_duration_ms"`
+	ClonesLastHour int    `json:"clones_last_hour"`
+	LastStartAt    string `json:"last_start_at,omitempty"`
+}
+
+func newCloneStats() *cloneStats {
+	return &cloneStats{templates: make(map[string]*templateStats)}
+}
+
+func (s *cloneStats) get(template string) *templateStats {
+	ts, ok := s.templates[template]
+	if !ok {
+		ts = &templateStats{}
+		s.templates[template] = ts
+	}
+	return ts
+}
+
+// start records a clone of template starting at now.
+func (s *cloneStats) start(template string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts := s.get(template)
+	ts.total++
+	ts.lastStart = now
+	ts.recentStarts = append(pruneBefore(ts.recentStarts, now.Add(-statsWindow)), now)
+}
+
+// finish records the outcome of a clone started with start.
+func (s *cloneStats) finish(template string, duration time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts := s.get(template)
+	ts.completed++
+	ts.durationSum += duration
+	if failed {
+		ts.failed++
+	}
+}
+
+// lastStart returns when the most recent clone of template started.
+func (s *cloneStats) lastStart(template string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ts, ok := s.templates[template]; ok {
+		return ts.lastStart
+	}
+	return time.Time{}
+}
+
+func (s *cloneStats) snapshot(now time.Time) map[string]templateSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]templateSnapshot, len(s.templates))
+	for template, ts := range s.templates {
+		ts.recentStarts = pruneBefore(ts.recentStarts, now.Add(-statsWindow))
+		snap := templateSnapshot{
+			Total:          ts.total,
+			Failed:         ts.failed,
+			ClonesLastHour: len(ts.recentStarts),
+		}
+		if ts.completed > 0 {
+			snap.AvgDurationMs = (ts.durationSum / time.Duration(ts.completed)).Milliseconds()
+		}
+		if !ts.lastStart.IsZero() {
+			snap.LastStartAt = ts.lastStart.UTC().Format(time.RFC3339)
+		}
+		out[template] = snap
+	}
+	return out
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// cooldownWait returns how long a clone of template must wait so clone starts
+// on the same template are at least cooldown apart.
+func (p *cloneProxy) cooldownWait(template string) time.Duration {
+	if p.cooldown <= 0 {
+		return 0
+	}
+	last := p.stats.lastStart(template)
+	if last.IsZero() {
+		return 0
+	}
+	return time.Until(last.Add(p.cooldown))
+}
+
+func (p *cloneProxy) serveStats(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	pending := p.pending
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"pending":                pending,
+		"queue_size":             p.queueSize,
+		"cooldown_ms":            p.cooldown.Milliseconds(),
+		"lock_retries_total":     p.lockRetriesTotal.Load(),
+		"lock_retries_exhausted": p.lockRetriesExhausted.Load(),
+		"templates":              p.stats.snapshot(time.Now()),
+		"upstreams":              p.upstreams.snapshot(),
+		"retry_budget":           p.retries.limit,
+		"retry_window_ms":        p.retries.window.Milliseconds(),
+		"callers":                p.retries.snapshot(time.Now()),
+	})
+}
+
+// serveMetrics writes the same counters in the Prometheus text format.
+func (p *cloneProxy) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	pending := p.pending
+	p.mu.Unlock()
+	templates := p.stats.snapshot(time.Now())
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	writeMetric := func(name, kind, help string, value func(templateSnapshot) string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, template := range names {
+			fmt.Fprintf(&b, "%s{template=%q} %s\n", name, template, value(templates[template]))
+		}
+	}
+	writeMetric("pve_clone_proxy_clones_total", "counter", "Clones started per source template.",
+		func(s templateSnapshot) string { return fmt.Sprint(s.Total) })
+	writeMetric("pve_clone_proxy_clones_failed_total", "counter", "Clones that failed per source template.",
+		func(s templateSnapshot) string { return fmt.Sprint(s.Failed) })
+	writeMetric("pve_clone_proxy_clone_duration_avg_seconds", "gauge", "Average clone duration per source template.",
+		func(s templateSnapshot) string { return fmt.Sprintf("%.3f", float64(s.AvgDurationMs)/1000) })
+	writeMetric("pve_clone_proxy_clones_last_hour", "gauge", "Clones started in the last hour per source template.",
+		func(s templateSnapshot) string { return fmt.Sprint(s.ClonesLastHour) })
+
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_pending Queued or in-flight clones.\n# TYPE pve_clone_proxy_pending gauge\npve_clone_proxy_pending %d\n", pending)
+
+	queues := p.queueSnapshot(time.Now())
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_queued Clones waiting per source template.\n# TYPE pve_clone_proxy_queued gauge\n")
+	for _, template := range sortedTemplates(queues) {
+		fmt.Fprintf(&b, "pve_clone_proxy_queued{template=%q} %d\n", template, len(queues[template].Queued))
+	}
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_template_paused Whether an operator paused the template's queue.\n# TYPE pve_clone_proxy_template_paused gauge\n")
+	for _, template := range sortedTemplates(queues) {
+		paused := 0
+		if queues[template].Paused {
+			paused = 1
+		}
+		fmt.Fprintf(&b, "pve_clone_proxy_template_paused{template=%q} %d\n", template, paused)
+	}
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_lock_retries_total Clone retries after PVE lock errors.\n# TYPE pve_clone_proxy_lock_retries_total counter\npve_clone_proxy_lock_retries_total %d\n", p.lockRetriesTotal.Load())
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_lock_retries_exhausted_total Clones still locked after all retries.\n# TYPE pve_clone_proxy_lock_retries_exhausted_total counter\npve_clone_proxy_lock_retries_exhausted_total %d\n", p.lockRetriesExhausted.Load())
+
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_upstream_healthy Whether each PVE API upstream passed its last check.\n# TYPE pve_clone_proxy_upstream_healthy gauge\n")
+	for _, up := range p.upstreams.snapshot() {
+		healthy := 0
+		if up.Healthy {
+			healthy = 1
+		}
+		fmt.Fprintf(&b, "pve_clone_proxy_upstream_healthy{upstream=%q} %d\n", up.URL, healthy)
+	}
+
+	callers := p.retries.snapshot(time.Now())
+	callerNames := make([]string, 0, len(callers))
+	for name := range callers {
+		callerNames = append(callerNames, name)
+	}
+	sort.Strings(callerNames)
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_caller_retries_total Clone failures charged to each caller's retry budget.\n# TYPE pve_clone_proxy_caller_retries_total counter\n")
+	for _, name := range callerNames {
+		fmt.Fprintf(&b, "pve_clone_proxy_caller_retries_total{caller=%q} %d\n", name, callers[name].RetriesTotal)
+	}
+	fmt.Fprintf(&b, "# HELP pve_clone_proxy_caller_retry_budget_rejections_total Clones rejected because the caller was over its retry budget.\n# TYPE pve_clone_proxy_caller_retry_budget_rejections_total counter\n")
+	for _, name := range callerNames {
+		fmt.Fprintf(&b, "pve_clone_proxy_caller_retry_budget_rejections_total{caller=%q} %d\n", name, callers[name].Rejected)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlorz/cmux/scripts/pve/clone-proxy/internal/testsupport"
+)
+
+func TestCloneStatsSnapshot(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	s := newCloneStats()
+	s.start("9000", now.Add(-2*time.Hour))
+	s.finish("9000", 10*time.Second, false)
+	s.start("9000", now.Add(-time.Minute))
+	s.finish("9000", 30*time.Second, true)
+	s.start("9000", now) // Still running
+
+	snap := s.snapshot(now)["9000"]
+	want := templateSnapshot{
+		Total:          3,
+		Failed:         1,
+		AvgDurationMs:  20000,
+		ClonesLastHour: 2,
+		LastStartAt:    "2026-01-02T15:00:00Z",
+	}
+	if snap != want {
+		t.Errorf("snapshot = %+v, want %+v", snap, want)
+	}
+}
+
+func TestIntegrationCooldownSpacesClonesPerTemplate(t *testing.T) {
+	const cooldown = 200 * time.Millisecond
+	fake := testsupport.NewFakePVE(t)
+	_, server := newTestProxy(t, fake, func(cfg *config) { cfg.cooldown = cooldown })
+
+	for i, template := range []string{"9000", "9001", "9000"} {
+		if resp := postClone(t, server, template, 200+i, nil); resp.status != http.StatusOK {
+			t.Fatalf("clone %d: %d %s", i, resp.status, resp.body)
+		}
+	}
+
+	calls := fake.CloneCalls()
+	if len(calls) != 3 {
+		t.Fatalf("clone calls = %d, want 3", len(calls))
+	}
+	// Another template is not held back by 9000's cooldown.
+	if gap := calls[1].At.Sub(calls[0].At); gap >= cooldown {
+		t.Errorf("template 9001 waited %s behind 9000's cooldown", gap)
+	}
+	// The clone start is recorded just before the call, so allow a little
+	// slack for the first call's own latency.
+	if gap := calls[2].At.Sub(calls[0].At); gap < cooldown-10*time.Millisecond {
+		t.Errorf("second clone of 9000 came %s after the first, want at least %s", gap, cooldown)
+	}
+}
+
+// getStats fetches path from the proxy and returns the body once ready
+// reports true for it. Stats are finalised after the response is written,
+// so a clone's outcome can lag its response slightly.
+func getStats(t *testing.T, server *httptest.Server, path string, ready func(string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, resp.StatusCode, body)
+		}
+		if ready(string(body)) || time.Now().After(deadline) {
+			return string(body)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIntegrationStatsAndMetrics(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	fake.FailTasks("9001", "clone failed: storage full")
+	_, server := newTestProxy(t, fake, func(cfg *config) { cfg.cooldown = time.Millisecond })
+
+	for i, template := range []string{"9000", "9000", "9001"} {
+		if resp := postClone(t, server, template, 200+i, map[string]string{resolveTaskHeader: "1"}); resp.status != http.StatusOK {
+			t.Fatalf("clone %d: %d %s", i, resp.status, resp.body)
+		}
+	}
+
+	var stats struct {
+		Pending    int                         `json:"pending"`
+		CooldownMs int64                       `json:"cooldown_ms"`
+		Templates  map[string]templateSnapshot `json:"templates"`
+	}
+	body := getStats(t, server, "/stats", func(body string) bool {
+		return strings.Contains(body, `"failed":1`)
+	})
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatalf("decode /stats %q: %v", body, err)
+	}
+	if stats.Pending != 0 || stats.CooldownMs != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if s := stats.Templates["9000"]; s.Total != 2 || s.Failed != 0 || s.ClonesLastHour != 2 || s.LastStartAt == "" {
+		t.Errorf("template 9000 = %+v", s)
+	}
+	if s := stats.Templates["9001"]; s.Total != 1 || s.Failed != 1 || s.ClonesLastHour != 1 {
+		t.Errorf("template 9001 = %+v", s)
+	}
+
+	metrics := getStats(t, server, "/metrics", func(string) bool { return true })
+	for _, want := range []string{
+		"# TYPE pve_clone_proxy_clones_total counter\n" +
+			`pve_clone_proxy_clones_total{template="9000"} 2` + "\n" +
+			`pve_clone_proxy_clones_total{template="9001"} 1` + "\n",
+		`pve_clone_proxy_clones_failed_total{template="9000"} 0` + "\n",
+		`pve_clone_proxy_clones_failed_total{template="9001"} 1` + "\n",
+		`pve_clone_proxy_clones_last_hour{template="9000"} 2` + "\n",
+		"# TYPE pve_clone_proxy_clone_duration_avg_seconds gauge\n",
+		"pve_clone_proxy_pending 0\n",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("/metrics missing %q:\n%s", want, metrics)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthCheckPath is probed on each upstream without credentials. pveproxy
+// answers 401 then, which still proves the API is serving, so any non-5xx
+// response counts as healthy.
+const healthCheckPath = "/api2/json/version"
+
+// splitUpstreams splits a comma-separated upstream list, dropping blanks.
+func splitUpstreams(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// parseUpstreams parses upstream addresses, defaulting the scheme to https.
+// All upstreams must share a path, since requests are rewritten by host only.
+func parseUpstreams(addrs []string) ([]*url.URL, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no PVE API upstream configured")
+	}
+	urls := make([]*url.URL, 0, len(addrs))
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "://") {
+			addr = "https://" + addr
+		}
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream %q: %w", addr, err)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream %q: missing host", addr)
+		}
+		if len(urls) > 0 && strings.TrimSuffix(u.Path, "/") != strings.TrimSuffix(urls[0].Path, "/") {
+			return nil, fmt.Errorf("upstream %q has a different path than %q", addr, urls[0])
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// upstream is one PVE API address and its last known health.
+type upstream struct {
+	url *url.URL
+
+	mu      sync.Mutex
+	healthy bool
+	lastErr string
+	since   time.Time // When healthy last changed
+}
+
+type upstreamSnapshot struct {
+	URL       string `json:"url"`
+	Healthy   bool   `json:"healthy"`
+	LastError string `json:"last_error,omitempty"`
+	Since     string `json:"since"`
+}
+
+// upstreamPool picks which PVE API address requests go to. Upstreams are
+// preferred in configured order, so the first one (usually the local
+// pveproxy) takes all traffic while it is healthy.
+type upstreamPool struct {
+	upstreams []*upstream
+}
+
+func newUpstreamPool(urls []*url.URL) *upstreamPool {
+	pool := &upstreamPool{}
+	now := time.Now()
+	for _, u := range urls {
+		pool.upstreams = append(pool.upstreams, &upstream{url: u, healthy: true, since: now})
+	}
+	return pool
+}
+
+// primary is the first configured upstream; requests are built against it
+// and rewritten by the failover transport.
+func (p *upstreamPool) primary() *url.URL {
+	return p.upstreams[0].url
+}
+
+// candidates returns healthy upstreams in preference order followed by the
+// unhealthy ones, so a request is still attempted when every health check
+// is failing.
+func (p *upstreamPool) candidates() []*upstream {
+	var healthy, unhealthy []*upstream
+	for _, up := range p.upstreams {
+		up.mu.Lock()
+		ok := up.healthy
+		up.mu.Unlock()
+		if ok {
+			healthy = append(healthy, up)
+		} else {
+			unhealthy = append(unhealthy, up)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// setHealth records a health change, logging transitions only.
+func (p *upstreamPool) setHealth(up *upstream, healthy bool, err error) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if err != nil {
+		up.lastErr = err.Error()
+	}
+	if up.healthy == healthy {
+		return
+	}
+	up.healthy = healthy
+	up.since = time.Now()
+	if healthy {
+		log.Printf("upstream %s is healthy again", up.url.Host)
+	} else {
+		log.Printf("upstream %s marked unhealthy: %v", up.url.Host, err)
+	}
+}
+
+func (p *upstreamPool) snapshot() []upstreamSnapshot {
+	out := make([]upstreamSnapshot, 0, len(p.upstreams))
+	for _, up := range p.upstreams {
+		up.mu.Lock()
+		out = append(out, upstreamSnapshot{
+			URL:       up.url.String(),
+			Healthy:   up.healthy,
+			LastError: up.lastErr,
+			Since:     up.since.UTC().Format(time.RFC3339),
+		})
+		up.mu.Unlock()
+	}
+	return out
+}
+
+// checkHealth probes every upstream once.
+func (p *upstreamPool) checkHealth(ctx context.Context, client *http.Client) {
+	var wg sync.WaitGroup
+	for _, up := range p.upstreams {
+		wg.Add(1)
+		go func(up *upstream) {
+			defer wg.Done()
+			ok, err := probeUpstream(ctx, client, up.url)
+			p.setHealth(up, ok, err)
+		}(up)
+	}
+	wg.Wait()
+}
+
+// runHealthChecks probes upstreams every interval until ctx is done.
+func (p *upstreamPool) runHealthChecks(ctx context.Context, client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.checkHealth(ctx, client)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeUpstream reports whether u's API answers, and why not.
+func probeUpstream(ctx context.Context, client *http.Client, u *url.URL) (bool, error) {
+	target := *u
+	target.Path = singleJoiningSlash(u.Path, healthCheckPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return false, fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return true, nil
+}
+
+// failoverTransport sends each request to the most preferred healthy
+// upstream. If connecting fails, the upstream is marked unhealthy and the
+// request moves on to the next one; nothing was sent, so this is safe even
+// for clone POSTs. Requests whose body cannot be replayed are not retried.
+// Any response marks its upstream healthy again, which is how a lone
+// upstream (no health checks) recovers.
+type failoverTransport struct {
+	pool *upstreamPool
+	base http.RoundTripper
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	candidates := t.pool.candidates()
+	var lastErr error
+	for i, up := range candidates {
+		out := req.Clone(req.Context())
+		out.URL.Scheme = up.url.Scheme
+		out.URL.Host = up.url.Host
+		if req.Host == "" || req.Host == t.pool.primary().Host {
+			out.Host = up.url.Host
+		}
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, lastErr
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, lastErr
+			}
+			out.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(out)
+		if err == nil {
+			t.pool.setHealth(up, true, nil)
+			return resp, nil
+		}
+		if !isDialError(err) {
+			return nil, err
+		}
+		t.pool.setHealth(up, false, err)
+		lastErr = err
+		if len(candidates) > 1 && i < len(candidates)-1 {
+			log.Printf("upstream %s unreachable, failing over: %v", up.url.Host, err)
+		}
+	}
+	return nil, lastErr
+}
+
+// isDialError reports whether err happened while connecting, before any
+// part of the request was written.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
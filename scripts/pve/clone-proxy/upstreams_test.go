@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karlorz/cmux/scripts/pve/clone-proxy/internal/testsupport"
+)
+
+func TestSplitUpstreams(t *testing.T) {
+	if got := splitUpstreams("pve1:8006, pve2:8006,,https://pve3:8006,"); strings.Join(got, "|") != "pve1:8006|pve2:8006|https://pve3:8006" {
+		t.Errorf("upstreams = %q", got)
+	}
+}
+
+func TestParseUpstreams(t *testing.T) {
+	urls, err := parseUpstreams([]string{"pve1:8006", "http://10.0.0.2:8006"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls[0].String() != "https://pve1:8006" || urls[1].String() != "http://10.0.0.2:8006" {
+		t.Errorf("urls = %v", urls)
+	}
+
+	for _, addrs := range [][]string{
+		nil,
+		{"https://"},
+		{"https://pve1:8006/pve", "https://pve2:8006"},
+	} {
+		if _, err := parseUpstreams(addrs); err == nil {
+			t.Errorf("parseUpstreams(%q) succeeded, want error", addrs)
+		}
+	}
+	if _, err := parseUpstreams([]string{"https://pve1:8006/pve/", "https://pve2:8006/pve"}); err != nil {
+		t.Errorf("trailing slash difference rejected: %v", err)
+	}
+}
+
+func newTestPool(t *testing.T, addrs ...string) *upstreamPool {
+	t.Helper()
+	urls, err := parseUpstreams(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newUpstreamPool(urls)
+}
+
+func candidateHosts(p *upstreamPool) string {
+	var hosts []string
+	for _, up := range p.candidates() {
+		hosts = append(hosts, up.url.Host)
+	}
+	return strings.Join(hosts, ",")
+}
+
+func TestUpstreamPoolPrefersHealthyInOrder(t *testing.T) {
+	pool := newTestPool(t, "pve1", "pve2", "pve3")
+	if got := candidateHosts(pool); got != "pve1,pve2,pve3" {
+		t.Errorf("candidates = %s", got)
+	}
+
+	pool.setHealth(pool.upstreams[0], false, io.ErrUnexpectedEOF)
+	if got := candidateHosts(pool); got != "pve2,pve3,pve1" {
+		t.Errorf("candidates with pve1 down = %s", got)
+	}
+	snap := pool.snapshot()[0]
+	if snap.Healthy || snap.LastError != io.ErrUnexpectedEOF.Error() || snap.Since == "" {
+		t.Errorf("pve1 snapshot = %+v", snap)
+	}
+
+	pool.setHealth(pool.upstreams[0], true, nil)
+	if got := candidateHosts(pool); got != "pve1,pve2,pve3" {
+		t.Errorf("candidates after recovery = %s", got)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthCheckPath {
+			t.Errorf("health check requested %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized) // Any non-5xx answer counts
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	pool := newTestPool(t, ok.URL, failing.URL, down.URL)
+	pool.checkHealth(context.Background(), &http.Client{Timeout: 5 * time.Second})
+
+	snap := pool.snapshot()
+	if !snap[0].Healthy || snap[0].LastError != "" {
+		t.Errorf("ok upstream = %+v", snap[0])
+	}
+	if snap[1].Healthy || !strings.Contains(snap[1].LastError, "503") {
+		t.Errorf("failing upstream = %+v", snap[1])
+	}
+	if snap[2].Healthy || snap[2].LastError == "" {
+		t.Errorf("down upstream = %+v", snap[2])
+	}
+}
+
+func TestFailoverTransportDoesNotReplayUnreplayableBody(t *testing.T) {
+	var hits atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	pool := newTestPool(t, down.URL, up.URL)
+	transport := &failoverTransport{pool: pool, base: http.DefaultTransport}
+
+	req, _ := http.NewRequest(http.MethodPost, down.URL+"/api2/json/nodes/pve/lxc/9000/clone", nil)
+	req.Body = io.NopCloser(strings.NewReader("newid=200"))
+	req.GetBody = nil
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("request with an unreplayable body failed over")
+	}
+	if hits.Load() != 0 {
+		t.Errorf("second upstream received %d requests", hits.Load())
+	}
+
+	// A replayable body moves on to the next upstream.
+	req, _ = http.NewRequest(http.MethodPost, down.URL+"/api2/json/nodes/pve/lxc/9000/clone", strings.NewReader("newid=200"))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("failover: %v", err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 1 {
+		t.Errorf("second upstream received %d requests, want 1", hits.Load())
+	}
+}
+
+func TestIntegrationFailoverToSecondUpstream(t *testing.T) {
+	fake := testsupport.NewFakePVE(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	proxy, server := newTestProxy(t, fake, func(cfg *config) {
+		cfg.targetURLs = []string{down.URL, fake.URL()}
+	})
+
+	resp := postClone(t, server, "9000", 200, map[string]string{resolveTaskHeader: "1"})
+	if resp.status != http.StatusOK {
+		t.Fatalf("clone: %d %s", resp.status, resp.body)
+	}
+	if result := resp.taskResult(t); result.ExitStatus != "OK" {
+		t.Errorf("task result = %+v", result)
+	}
+	calls := fake.CloneCalls()
+	if len(calls) != 1 || calls[0].Form.Get("newid") != "200" {
+		t.Errorf("clone calls on the second upstream = %+v", calls)
+	}
+
+	// The dead primary is skipped from now on, including for passthrough.
+	snap := proxy.upstreams.snapshot()
+	if snap[0].Healthy || !snap[1].Healthy {
+		t.Errorf("upstreams = %+v", snap)
+	}
+	if code := adminCall(t, server, http.MethodGet, "/api2/json/nodes/pve/lxc", "", nil); code != http.StatusOK {
+		t.Errorf("passthrough: %d", code)
+	}
+	if got := len(fake.Calls()); got != 2 {
+		t.Errorf("fake calls = %d, want the clone and the passthrough", got)
+	}
+	fakeHost, _ := url.Parse(fake.URL())
+	if got := candidateHosts(proxy.upstreams); !strings.HasPrefix(got, fakeHost.Host) {
+		t.Errorf("candidates = %s, want the fake first", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PVE's accepted VMID range.
+const (
+	minVMID = 100
+	maxVMID = 999999999
+)
+
+var hostnameInvalidChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// paramErrors maps clone parameters to what is wrong with them, reported in
+// PVE's own "Parameter verification failed" shape.
+type paramErrors map[string]string
+
+// normalizeCloneBody parses a form or JSON clone body, validates it and
+// returns it as form values with defaults applied: full defaults to 0 and
+// hostname is reduced to a valid DNS name. Problems are returned per
+// parameter rather than forwarded to PVE, which would only reject them with
+// an opaque 500 after the clone waited in queue.
+func normalizeCloneBody(contentType string, body []byte) (url.Values, int, paramErrors) {
+	values, err := parseCloneBody(contentType, body)
+	if err != nil {
+		return nil, 0, paramErrors{"body": err.Error()}
+	}
+
+	errs := paramErrors{}
+	newID, err := strconv.Atoi(strings.TrimSpace(values.Get("newid")))
+	switch {
+	case values.Get("newid") == "":
+		errs["newid"] = "property is missing and it is not optional"
+	case err != nil:
+		errs["newid"] = fmt.Sprintf("value %q does not look like a valid VM ID", values.Get("newid"))
+	case newID < minVMID || newID > maxVMID:
+		errs["newid"] = fmt.Sprintf("value must be between %d and %d", minVMID, maxVMID)
+	default:
+		values.Set("newid", strconv.Itoa(newID))
+	}
+
+	full := "0"
+	if v := values.Get("full"); v != "" {
+		if full = normalizeBool(v); full == "" {
+			errs["full"] = fmt.Sprintf("type check ('boolean') failed - got %q", v)
+		}
+	}
+	values.Set("full", full)
+	if values.Get("storage") != "" && full == "0" {
+		errs["storage"] = "target storage is only supported for full clones (set full=1)"
+	}
+
+	if raw, ok := values["hostname"]; ok {
+		hostname := sanitizeHostname(strings.Join(raw, ""))
+		if hostname == "" {
+			errs["hostname"] = fmt.Sprintf("value %q has no valid hostname characters", strings.Join(raw, ""))
+		}
+		values.Set("hostname", hostname)
+	}
+
+	if len(errs) > 0 {
+		return nil, 0, errs
+	}
+	return values, newID, nil
+}
+
+// parseCloneBody reads a clone body as form values. JSON objects are
+// flattened to strings so both encodings validate the same way.
+func parseCloneBody(contentType string, body []byte) (url.Values, error) {
+	if !strings.HasPrefix(strings.TrimSpace(contentType), "application/json") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form body: %v", err)
+		}
+		return values, nil
+	}
+
+	var payload map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	values := url.Values{}
+	for key, value := range payload {
+		switch v := value.(type) {
+		case string:
+			values.Set(key, v)
+		case json.Number:
+			values.Set(key, v.String())
+		case bool:
+			values.Set(key, map[bool]string{true: "1", false: "0"}[v])
+		case nil:
+		default:
+			return nil, fmt.Errorf("parameter %q must be a string, number or boolean", key)
+		}
+	}
+	return values, nil
+}
+
+// normalizeBool maps PVE's accepted boolean spellings to 0/1, or "" if v is
+// not a boolean.
+func normalizeBool(v string) string {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return "1"
+	case "0", "false", "no", "off":
+		return "0"
+	}
+	return ""
+}
+
+// sanitizeHostname lowercases name, replaces runs of invalid characters with
+// '-', and trims each label to 63 characters without leading or trailing '-'.
+func sanitizeHostname(name string) string {
+	name = hostnameInvalidChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	var labels []string
+	for _, label := range strings.Split(name, ".") {
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		if label = strings.Trim(label, "-"); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	name = strings.Join(labels, ".")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+// writeParamErrors responds 400 with the same envelope PVE uses for
+// parameter verification failures.
+func writeParamErrors(w http.ResponseWriter, errs paramErrors) {
+	body, _ := json.Marshal(map[string]any{"data": nil, "errors": errs})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	formContentType = "application/x-www-form-urlencoded"
+	jsonContentType = "application/json"
+)
+
+func TestNormalizeCloneBodyRewrites(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		wantID      int
+		want        string
+	}{
+		{"defaults full to 0", formContentType, "newid=200", 200, "full=0&newid=200"},
+		{"trims newid", formContentType, "newid=+0200+", 200, "full=0&newid=200"},
+		{"boolean spellings", formContentType, "newid=200&full=yes", 200, "full=1&newid=200"},
+		{"storage with full clone", formContentType, "newid=200&full=true&storage=local-zfs", 200, "full=1&newid=200&storage=local-zfs"},
+		{"hostname sanitized", formContentType, "newid=200&hostname=My_Box..Dev-", 200, "full=0&hostname=my-box.dev&newid=200"},
+		{"long label trimmed", formContentType, "newid=200&hostname=" + strings.Repeat("a", 70), 200, "full=0&hostname=" + strings.Repeat("a", 63) + "&newid=200"},
+		{"other fields kept", formContentType, "newid=200&description=hi", 200, "description=hi&full=0&newid=200"},
+		{"JSON numbers and booleans", jsonContentType, `{"newid":201,"full":false,"pool":null}`, 201, "full=0&newid=201"},
+		{"JSON with charset", jsonContentType + "; charset=utf-8", `{"newid":"202","full":true}`, 202, "full=1&newid=202"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values, newID, errs := normalizeCloneBody(tc.contentType, []byte(tc.body))
+			if errs != nil {
+				t.Fatalf("errors = %v", errs)
+			}
+			if newID != tc.wantID {
+				t.Errorf("newID = %d, want %d", newID, tc.wantID)
+			}
+			if got := values.Encode(); got != tc.want {
+				t.Errorf("values = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCloneBodyRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		wantFields  []string
+	}{
+		{"missing newid", formContentType, "full=1", []string{"newid"}},
+		{"non-numeric newid", formContentType, "newid=abc", []string{"newid"}},
+		{"newid below range", formContentType, "newid=99", []string{"newid"}},
+		{"newid above range", formContentType, "newid=1000000000", []string{"newid"}},
+		{"bad boolean", formContentType, "newid=200&full=maybe", []string{"full"}},
+		{"storage on linked clone", formContentType, "newid=200&storage=local-zfs", []string{"storage"}},
+		{"empty hostname", formContentType, "newid=200&hostname=___", []string{"hostname"}},
+		{"every problem at once", formContentType, "full=2&hostname=!", []string{"newid", "full", "hostname"}},
+		{"bad form", formContentType, "newid=%zz", []string{"body"}},
+		{"bad JSON", jsonContentType, `{"newid":`, []string{"body"}},
+		{"JSON object value", jsonContentType, `{"newid":200,"net0":{"bridge":"vmbr0"}}`, []string{"body"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values, _, errs := normalizeCloneBody(tc.contentType, []byte(tc.body))
+			if values != nil {
+				t.Errorf("values = %v, want nil", values)
+			}
+			if len(errs) != len(tc.wantFields) {
+				t.Errorf("errors = %v, want fields %v", errs, tc.wantFields)
+			}
+			for _, field := range tc.wantFields {
+				if errs[field] == "" {
+					t.Errorf("no error for %s in %v", field, errs)
+				}
+			}
+		})
+	}
+}